@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/marianogappa/screpdb/internal/appdata"
+	"github.com/marianogappa/screpdb/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var aggregatesSQLitePath string
+
+var aggregatesCmd = &cobra.Command{
+	Use:   "aggregates",
+	Short: "Rebuild the player aggregate tables the players pages read",
+	Long: `Recompute the player aggregate tables (player_game_facts, player_aggregates, player_matchup_aggregates and player_marker_counts) from every stored replay.
+
+The dashboard's players list, player summary, APM histogram, production cadence and viewport multitasking views read these tables instead of aggregating every player's games on each request. A new database starts with them built, and ingest, reanalyze, merge and the PostgreSQL mirror keep them current in the same transaction as the rows they summarize. A database created before they existed, or built by an older screpdb whose aggregates were computed differently, needs one rebuild; until then the dashboard computes those views live.
+
+  screpdb aggregates`,
+	Args: cobra.NoArgs,
+	RunE: runAggregates,
+}
+
+func init() {
+	aggregatesCmd.Flags().StringVarP(&aggregatesSQLitePath, "sqlite-path", "s", "screp.db", "SQLite database file path")
+}
+
+func runAggregates(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	dbPath, err := appdata.ResolveDBPath(aggregatesSQLitePath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve database path: %w", err)
+	}
+	store, err := storage.NewSQLiteStorage(dbPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	if err := store.Initialize(ctx, false, false); err != nil {
+		return err
+	}
+
+	stats, err := store.RebuildPlayerAggregates(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Rebuilt player aggregates: %d player(s) across %d game(s)\n", stats.Players, stats.Games)
+	return nil
+}
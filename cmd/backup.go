@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/marianogappa/screpdb/internal/appdata"
+	"github.com/marianogappa/screpdb/internal/backup"
+	"github.com/spf13/cobra"
+)
+
+var (
+	backupSQLitePath  string
+	backupKeep        int
+	restoreSQLitePath string
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup [dest]",
+	Short: "Back up the database, even while the dashboard or an ingest is using it",
+	Long: `Copy the SQLite database to dest with SQLite's online backup API, so it is safe to run while the dashboard is open or an ingest is running. The copy is integrity-checked before it replaces dest.
+
+Without dest, the backup goes to the backups folder of the app-data root under a timestamped name, and only the newest --keep of those are kept. The dashboard takes the same rotating backups on a schedule (see --backup-interval).
+
+  screpdb backup ~/screp-backup.db
+  screpdb backup --keep 30`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runBackup,
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <backup>",
+	Short: "Replace the database with a backup",
+	Long: `Replace the SQLite database with a backup taken by ` + "`screpdb backup`" + ` or the dashboard. backup is a file path or the name of a rotating backup in the app-data backups folder.
+
+The backup is integrity-checked and its migrations are compared with this build's before anything is touched: a backup taken by a newer screpdb is refused, and an older one is migrated forward after the swap. The current database is first saved as a rotating backup, so a restore can itself be undone.
+
+  screpdb restore screp-20250102-030405.db`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRestore,
+}
+
+func init() {
+	backupCmd.Flags().StringVarP(&backupSQLitePath, "sqlite-path", "s", "screp.db", "SQLite database file path")
+	backupCmd.Flags().IntVar(&backupKeep, "keep", backup.DefaultKeep, "Rotating backups to keep when no dest is given (0 keeps all)")
+	restoreCmd.Flags().StringVarP(&restoreSQLitePath, "sqlite-path", "s", "screp.db", "SQLite database file path")
+}
+
+func runBackup(cmd *cobra.Command, args []string) error {
+	dbPath, err := appdata.ResolveDBPath(backupSQLitePath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve database path: %w", err)
+	}
+	var info backup.Info
+	if len(args) == 1 {
+		info, err = backup.Backup(context.Background(), dbPath, args[0])
+	} else {
+		dir, dirErr := backup.Dir()
+		if dirErr != nil {
+			return dirErr
+		}
+		info, err = backup.Rotate(context.Background(), dbPath, dir, backupKeep)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Backed up %s to %s (%s)\n", dbPath, info.Path, formatBackupSize(info.SizeBytes))
+	return nil
+}
+
+func runRestore(cmd *cobra.Command, args []string) error {
+	dbPath, err := appdata.ResolveDBPath(restoreSQLitePath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve database path: %w", err)
+	}
+	dir, err := backup.Dir()
+	if err != nil {
+		return err
+	}
+	src := args[0]
+	// A bare rotating backup name refers to the backups folder.
+	if !strings.ContainsAny(src, `/\`) {
+		if info, err := backup.Lookup(dir, src); err == nil {
+			src = info.Path
+		}
+	}
+	result, err := backup.Restore(context.Background(), src, dbPath, dir)
+	if err != nil {
+		return err
+	}
+	printRestoreResult(cmd.OutOrStdout(), src, dbPath, result)
+	return nil
+}
+
+func printRestoreResult(w io.Writer, src, dbPath string, result backup.RestoreResult) {
+	fmt.Fprintf(w, "Restored %s from %s\n", dbPath, src)
+	if result.SafetyBackup.Path != "" {
+		fmt.Fprintf(w, "  previous database saved as %s\n", result.SafetyBackup.Path)
+	}
+	if len(result.Migrated) > 0 {
+		fmt.Fprintf(w, "  applied %d migration(s) the backup predates: %s\n", len(result.Migrated), strings.Join(result.Migrated, ", "))
+	}
+}
+
+func formatBackupSize(n int64) string {
+	const mb = 1 << 20
+	if n < mb {
+		return fmt.Sprintf("%d KB", (n+1023)/1024)
+	}
+	return fmt.Sprintf("%.1f MB", float64(n)/mb)
+}
@@ -1,13 +1,15 @@
 package cmd
 
 import (
+	"database/sql"
+	"strings"
 	"testing"
 
 	"github.com/spf13/cobra"
 )
 
 func TestRootHasSubcommands(t *testing.T) {
-	want := map[string]bool{"ingest": false, "mcp": false, "dashboard": false}
+	want := map[string]bool{"ingest": false, "mcp": false, "query": false, "reanalyze": false, "inspect": false, "doctor": false, "merge": false, "failures": false, "backup": false, "restore": false, "compact": false, "aggregates": false, "migrate": false, "dashboard": false}
 	for _, c := range rootCmd.Commands() {
 		if _, ok := want[c.Name()]; ok {
 			want[c.Name()] = true
@@ -38,6 +40,10 @@ func TestIngestFlagDefaults(t *testing.T) {
 		{"skip-hotkeys", "false"},
 		{"clean", "false"},
 		{"clean-dashboard", "false"},
+		{"watch", "false"},
+		{"retry-failed", "false"},
+		{"database-url", ""},
+		{"compact-commands", "false"},
 	}
 	for _, tt := range tests {
 		f := ingestCmd.Flags().Lookup(tt.name)
@@ -88,6 +94,236 @@ func TestMCPFlagDefaults(t *testing.T) {
 	if sh := mcpCmd.Flags().ShorthandLookup("s"); sh == nil || sh.Name != "sqlite-path" {
 		t.Error("mcp shorthand -s should map to sqlite-path")
 	}
+	if f := mcpCmd.Flags().Lookup("database-url"); f == nil || f.DefValue != "" {
+		t.Errorf("mcp database-url default wrong: %+v", f)
+	}
+}
+
+func TestQueryFlagDefaults(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"sqlite-path", "screp.db"},
+		{"file", ""},
+		{"format", "table"},
+		{"param", "[]"},
+		{"max-rows", "0"},
+	}
+	for _, tt := range tests {
+		f := queryCmd.Flags().Lookup(tt.name)
+		if f == nil {
+			t.Errorf("query flag %q not registered", tt.name)
+			continue
+		}
+		if f.DefValue != tt.want {
+			t.Errorf("query flag %q default = %q, want %q", tt.name, f.DefValue, tt.want)
+		}
+	}
+}
+
+func TestReanalyzeFlagDefaults(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"sqlite-path", "screp.db"},
+		{"replay-id", "[]"},
+		{"feature-key", "[]"},
+		{"concurrency", "0"},
+		{"dry-run", "false"},
+		{"from-db", "false"},
+	}
+	for _, tt := range tests {
+		f := reanalyzeCmd.Flags().Lookup(tt.name)
+		if f == nil {
+			t.Errorf("reanalyze flag %q not registered", tt.name)
+			continue
+		}
+		if f.DefValue != tt.want {
+			t.Errorf("reanalyze flag %q default = %q, want %q", tt.name, f.DefValue, tt.want)
+		}
+	}
+}
+
+func TestInspectFlagDefaults(t *testing.T) {
+	f := inspectCmd.Flags().Lookup("format")
+	if f == nil {
+		t.Fatal("inspect flag \"format\" not registered")
+	}
+	if f.DefValue != "text" {
+		t.Errorf("inspect flag \"format\" default = %q, want %q", f.DefValue, "text")
+	}
+	if inspectCmd.Args(inspectCmd, nil) == nil {
+		t.Error("inspect must require a replay path")
+	}
+}
+
+func TestDoctorFlagDefaults(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"sqlite-path", "screp.db"},
+		{"fix", "false"},
+	}
+	for _, tt := range tests {
+		f := doctorCmd.Flags().Lookup(tt.name)
+		if f == nil {
+			t.Errorf("doctor flag %q not registered", tt.name)
+			continue
+		}
+		if f.DefValue != tt.want {
+			t.Errorf("doctor flag %q default = %q, want %q", tt.name, f.DefValue, tt.want)
+		}
+	}
+	if doctorCmd.Flags().Lookup("input-dir") == nil {
+		t.Error("doctor flag \"input-dir\" not registered")
+	}
+}
+
+func TestMergeFlags(t *testing.T) {
+	for _, name := range []string{"from", "into"} {
+		f := mergeCmd.Flags().Lookup(name)
+		if f == nil {
+			t.Errorf("merge flag %q not registered", name)
+			continue
+		}
+		if f.Annotations[cobra.BashCompOneRequiredFlag] == nil {
+			t.Errorf("merge flag %q should be required", name)
+		}
+	}
+	if got := mergeCmd.Flags().Lookup("from").Value.Type(); got != "stringArray" {
+		t.Errorf("merge flag \"from\" type = %q, want stringArray", got)
+	}
+}
+
+func TestFailuresFlagDefaults(t *testing.T) {
+	f := failuresCmd.Flags().Lookup("sqlite-path")
+	if f == nil {
+		t.Fatal("failures flag \"sqlite-path\" not registered")
+	}
+	if f.DefValue != "screp.db" || f.Shorthand != "s" {
+		t.Errorf("failures flag \"sqlite-path\" = %q (-%s), want \"screp.db\" (-s)", f.DefValue, f.Shorthand)
+	}
+}
+
+func TestBackupFlagDefaults(t *testing.T) {
+	for _, cmd := range []*cobra.Command{backupCmd, restoreCmd} {
+		f := cmd.Flags().Lookup("sqlite-path")
+		if f == nil {
+			t.Errorf("%s flag \"sqlite-path\" not registered", cmd.Name())
+			continue
+		}
+		if f.DefValue != "screp.db" || f.Shorthand != "s" {
+			t.Errorf("%s flag \"sqlite-path\" = %q (-%s), want \"screp.db\" (-s)", cmd.Name(), f.DefValue, f.Shorthand)
+		}
+	}
+	if f := backupCmd.Flags().Lookup("keep"); f == nil || f.DefValue != "7" {
+		t.Errorf("backup flag \"keep\" default wrong: %+v", f)
+	}
+}
+
+func TestCompactFlagDefaults(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"sqlite-path", "screp.db"},
+		{"expand", "false"},
+		{"no-vacuum", "false"},
+	}
+	for _, tt := range tests {
+		f := compactCmd.Flags().Lookup(tt.name)
+		if f == nil {
+			t.Errorf("compact flag %q not registered", tt.name)
+			continue
+		}
+		if f.DefValue != tt.want {
+			t.Errorf("compact flag %q default = %q, want %q", tt.name, f.DefValue, tt.want)
+		}
+	}
+}
+
+func TestAggregatesFlagDefaults(t *testing.T) {
+	f := aggregatesCmd.Flags().Lookup("sqlite-path")
+	if f == nil {
+		t.Fatal("aggregates flag \"sqlite-path\" not registered")
+	}
+	if f.DefValue != "screp.db" || f.Shorthand != "s" {
+		t.Errorf("aggregates flag \"sqlite-path\" = %q (-%s), want \"screp.db\" (-s)", f.DefValue, f.Shorthand)
+	}
+}
+
+func TestMigrateSubcommandsAndFlags(t *testing.T) {
+	want := map[string]bool{"status": false, "up": false, "down": false, "to": false}
+	for _, c := range migrateCmd.Commands() {
+		if _, ok := want[c.Name()]; ok {
+			want[c.Name()] = true
+		}
+	}
+	for name, found := range want {
+		if !found {
+			t.Errorf("migrate subcommand %q not registered", name)
+		}
+	}
+	tests := []struct {
+		name, want string
+	}{
+		{"sqlite-path", "screp.db"},
+		{"set", ""},
+		{"no-backup", "false"},
+	}
+	for _, tt := range tests {
+		f := migrateCmd.PersistentFlags().Lookup(tt.name)
+		if f == nil {
+			t.Errorf("migrate flag %q not registered", tt.name)
+			continue
+		}
+		if f.DefValue != tt.want {
+			t.Errorf("migrate flag %q default = %q, want %q", tt.name, f.DefValue, tt.want)
+		}
+	}
+	if f := migrateDownCmd.Flags().Lookup("steps"); f == nil || f.DefValue != "1" {
+		t.Errorf("migrate down flag \"steps\" default wrong: %+v", f)
+	}
+}
+
+func TestParseQueryParams(t *testing.T) {
+	args, err := parseQueryParams([]string{"42", "1.5", "Soma", "NaN"})
+	if err != nil {
+		t.Fatalf("parseQueryParams positional: %v", err)
+	}
+	if args[0] != int64(42) || args[1] != 1.5 || args[2] != "Soma" || args[3] != "NaN" {
+		t.Fatalf("positional args = %#v", args)
+	}
+
+	args, err = parseQueryParams([]string{"player=Soma", "n=5"})
+	if err != nil {
+		t.Fatalf("parseQueryParams named: %v", err)
+	}
+	if args[0] != sql.Named("player", "Soma") || args[1] != sql.Named("n", int64(5)) {
+		t.Fatalf("named args = %#v", args)
+	}
+
+	if _, err := parseQueryParams([]string{"player=Soma", "5"}); err == nil {
+		t.Fatal("expected mixing named and positional params to fail")
+	}
+}
+
+func TestReadQuerySQLFromStdin(t *testing.T) {
+	queryFile = ""
+	queryCmd.SetIn(strings.NewReader("SELECT 1"))
+	t.Cleanup(func() { queryCmd.SetIn(nil) })
+
+	got, err := readQuerySQL(queryCmd, nil)
+	if err != nil {
+		t.Fatalf("readQuerySQL: %v", err)
+	}
+	if got != "SELECT 1" {
+		t.Fatalf("readQuerySQL = %q, want stdin contents", got)
+	}
+
+	queryCmd.SetIn(strings.NewReader("  \n"))
+	if _, err := readQuerySQL(queryCmd, nil); err == nil {
+		t.Fatal("expected an error for empty SQL")
+	}
 }
 
 func TestDashboardFlagDefaults(t *testing.T) {
@@ -104,6 +340,15 @@ func TestDashboardFlagDefaults(t *testing.T) {
 		if sqlite == nil || sqlite.DefValue != "screp.db" {
 			t.Errorf("%s sqlite-path default wrong: %+v", cmd.Name(), sqlite)
 		}
+		if dbURL := cmd.Flags().Lookup("database-url"); dbURL == nil || dbURL.DefValue != "" {
+			t.Errorf("%s database-url default wrong: %+v", cmd.Name(), dbURL)
+		}
+		if interval := cmd.Flags().Lookup("backup-interval"); interval == nil || interval.DefValue != "24h0m0s" {
+			t.Errorf("%s backup-interval default wrong: %+v", cmd.Name(), interval)
+		}
+		if keep := cmd.Flags().Lookup("backup-keep"); keep == nil || keep.DefValue != "7" {
+			t.Errorf("%s backup-keep default wrong: %+v", cmd.Name(), keep)
+		}
 	}
 }
 
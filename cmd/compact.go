@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/marianogappa/screpdb/internal/appdata"
+	"github.com/marianogappa/screpdb/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	compactSQLitePath string
+	compactExpand     bool
+	compactNoVacuum   bool
+)
+
+var compactCmd = &cobra.Command{
+	Use:   "compact",
+	Short: "Compact Right Click, Hotkey and Minimap Ping rows into blobs, or back",
+	Long: `Move every replay's Right Click, Hotkey and Minimap Ping commands out of commands_low_value into one compressed, columnar blob per replay (the command_blobs table), which is what ingest --compact-commands does for new replays. These commands are most of a database that keeps right-clicks, and the blobs take a fraction of the space. Only these three command types are compacted: the commands table and the other commands_low_value rows stay as rows.
+
+Replays already compacted are skipped, so compacting is safe to repeat. Analysis reads blobs transparently. For SQL, the commands_low_value_all view returns every low-value command however it is stored, and command_blob_rows decodes one replay's blob:
+
+  SELECT * FROM command_blobs b, command_blob_rows(b.payload, b.format_version) r WHERE b.replay_id = 42
+
+--expand turns blobs back into rows. The database is vacuumed afterwards to return the freed space to the filesystem.
+
+  screpdb compact
+  screpdb compact --expand`,
+	Args: cobra.NoArgs,
+	RunE: runCompact,
+}
+
+func init() {
+	compactCmd.Flags().StringVarP(&compactSQLitePath, "sqlite-path", "s", "screp.db", "SQLite database file path")
+	compactCmd.Flags().BoolVar(&compactExpand, "expand", false, "Convert compacted replays back to commands_low_value rows")
+	compactCmd.Flags().BoolVar(&compactNoVacuum, "no-vacuum", false, "Skip vacuuming the database afterwards")
+}
+
+func runCompact(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	dbPath, err := appdata.ResolveDBPath(compactSQLitePath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve database path: %w", err)
+	}
+	store, err := storage.NewSQLiteStorage(dbPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	if err := store.Initialize(ctx, false, false); err != nil {
+		return err
+	}
+
+	w := cmd.OutOrStdout()
+	if compactExpand {
+		stats, err := store.ExpandCommands(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Expanded %d replay(s): %d commands back in commands_low_value\n", stats.Replays, stats.Rows)
+	} else {
+		stats, err := store.CompactCommands(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Compacted %d replay(s): %d commands moved into command_blobs\n", stats.Replays, stats.Rows)
+	}
+	if compactNoVacuum {
+		return nil
+	}
+	before, after, err := store.Vacuum(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Vacuumed %s: %s -> %s\n", dbPath, formatBackupSize(before), formatBackupSize(after))
+	return nil
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/marianogappa/screpdb/internal/dashboardrun"
 	"github.com/marianogappa/screpdb/internal/netfacade"
 	"github.com/marianogappa/screpdb/internal/selfupdate"
+	"github.com/marianogappa/screpdb/internal/storage"
 	"github.com/marianogappa/screpdb/internal/winsandbox"
 	"github.com/pkg/browser"
 	"github.com/spf13/cobra"
@@ -82,12 +83,27 @@ func RunDashboardWithContext(ctx context.Context, opts dashboardrun.Options) err
 		return fmt.Errorf("failed to resolve database path: %w", err)
 	}
 	log.Printf("Using SQLite database at %s", dbPath)
+	if opts.DatabaseURL != "" {
+		log.Printf("Mirroring PostgreSQL database at %s...", storage.RedactDatabaseURL(opts.DatabaseURL))
+		stats, err := storage.RefreshMirror(ctx, dbPath, opts.DatabaseURL)
+		if err != nil {
+			return fmt.Errorf("failed to mirror PostgreSQL database: %w", err)
+		}
+		log.Printf("Mirrored %d new replays (%d removed, %d regrouped)", stats.Replays, stats.RemovedReplays, stats.Regrouped)
+	}
 
 	dash, err := dashboard.New(ctx, dbPath, opts.Headless)
 	if err != nil {
 		return err
 	}
 	dash.SetShutdownFunc(cancel)
+	dash.SetDatabaseURL(opts.DatabaseURL)
+	dash.StartScheduledBackups(opts.BackupInterval, opts.BackupKeep)
+	if opts.PersistResultCache {
+		if err := dash.PersistResultCache(); err != nil {
+			log.Printf("Warning: failed to load persisted result cache: %v", err)
+		}
+	}
 
 	// Start backend server asynchronously
 	serverURL := fmt.Sprintf("http://localhost:%d", opts.Port)
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/marianogappa/screpdb/internal/appdata"
+	"github.com/marianogappa/screpdb/internal/doctor"
+	"github.com/marianogappa/screpdb/internal/fileops"
+	"github.com/spf13/cobra"
+)
+
+var (
+	doctorSQLitePath string
+	doctorInputDir   string
+	doctorFix        bool
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Audit the database for missing files, orphaned rows and other inconsistencies",
+	Long: `Audit a screpdb database: replays whose file is missing or changed since ingest, rows orphaned from their replay, the analyzer algorithm version distribution, migration state, enum values that fell back to UNKNOWN, and SQLite's integrity_check / foreign_key_check.
+
+With --fix, repairs what is safely repairable: applies pending migrations, re-points moved replays found by checksum under --input-dir, prunes orphaned rows and re-queues replays whose analysis is missing (run ` + "`screpdb reanalyze`" + ` afterwards).
+Exits with an error when unresolved problems remain.`,
+	RunE: runDoctor,
+}
+
+func init() {
+	doctorCmd.Flags().StringVarP(&doctorSQLitePath, "sqlite-path", "s", "screp.db", "SQLite database file path")
+	doctorCmd.Flags().StringVarP(&doctorInputDir, "input-dir", "i", fileops.GetDefaultReplayDir(), "Directory searched for moved replay files")
+	doctorCmd.Flags().BoolVar(&doctorFix, "fix", false, "Repair what is safely repairable")
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	dbPath, err := appdata.ResolveDBPath(doctorSQLitePath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve database path: %w", err)
+	}
+	report, err := doctor.Run(context.Background(), doctor.Config{
+		SQLitePath: dbPath,
+		InputDir:   doctorInputDir,
+		Fix:        doctorFix,
+	})
+	if err != nil {
+		return err
+	}
+	printDoctorReport(cmd.OutOrStdout(), report, doctorFix)
+	if n := report.Problems(); n > 0 {
+		cmd.SilenceUsage = true
+		if doctorFix {
+			return fmt.Errorf("%d problem(s) could not be fixed automatically", n)
+		}
+		return fmt.Errorf("%d problem(s) found; run with --fix to repair what is repairable", n)
+	}
+	return nil
+}
+
+func printDoctorReport(w io.Writer, r *doctor.Report, fix bool) {
+	fmt.Fprintf(w, "Replays: %d\n", r.Replays)
+
+	fmt.Fprintln(w, "\nReplay files:")
+	if len(r.Files) == 0 {
+		fmt.Fprintln(w, "  ok")
+	}
+	for _, f := range r.Files {
+		what := "checksum changed"
+		if f.Missing {
+			what = "missing"
+		}
+		line := fmt.Sprintf("  replay %d: %s (%s)", f.ReplayID, f.Path, what)
+		switch {
+		case f.Fixed:
+			line += " -> re-pointed to " + f.FoundAt
+		case f.FoundAt != "":
+			line += " -> found at " + f.FoundAt
+		}
+		fmt.Fprintln(w, line)
+	}
+	if len(r.Files) > 0 && r.SearchSkipped != "" {
+		fmt.Fprintf(w, "  (input dir not searched: %s)\n", r.SearchSkipped)
+	}
+
+	fmt.Fprintln(w, "\nOrphaned rows:")
+	for _, o := range r.Orphans {
+		fmt.Fprintf(w, "  %-48s %d\n", o.Check, o.Rows)
+	}
+	if r.OrphansPruned > 0 {
+		fmt.Fprintf(w, "  pruned %d row(s)\n", r.OrphansPruned)
+	}
+
+	fmt.Fprintf(w, "\nAnalyzer algorithm versions (current %d):\n", r.CurrentVersion)
+	for _, v := range r.Versions {
+		note := ""
+		switch {
+		case v.Version == 0:
+			note = "  (never analyzed)"
+		case v.Version < r.CurrentVersion:
+			note = "  (stale)"
+		case v.Version > r.CurrentVersion:
+			note = "  (newer than this build)"
+		}
+		fmt.Fprintf(w, "  v%-4d %d%s\n", v.Version, v.Replays, note)
+	}
+	if stale := r.StaleReplays(); stale > 0 {
+		fmt.Fprintf(w, "  %d stale replay(s): run `screpdb reanalyze`\n", stale)
+	}
+	if len(r.MissingAnalysis) > 0 {
+		fmt.Fprintf(w, "  %d replay(s) stamped analyzed but without detections: %s\n", len(r.MissingAnalysis), joinIDs(r.MissingAnalysis))
+		if r.Requeued {
+			fmt.Fprintln(w, "  re-queued: run `screpdb reanalyze` to analyze them again")
+		}
+	}
+
+	fmt.Fprintln(w, "\nMigrations:")
+	for _, m := range r.Migrations {
+		line := fmt.Sprintf("  %-10s %d applied", m.Set, len(m.Applied))
+		if len(m.Pending) > 0 {
+			verb := "pending"
+			if r.MigrationsApplied {
+				verb = "now applied"
+			}
+			line += fmt.Sprintf(", %s: %s", verb, strings.Join(m.Pending, ", "))
+		}
+		if len(m.Unknown) > 0 {
+			line += fmt.Sprintf(", unknown to this build: %s", strings.Join(m.Unknown, ", "))
+		}
+		fmt.Fprintln(w, line)
+	}
+
+	fmt.Fprintln(w, "\nUNKNOWN enum fallbacks:")
+	if len(r.UnknownEnums) == 0 {
+		fmt.Fprintln(w, "  none")
+	}
+	for _, e := range r.UnknownEnums {
+		fmt.Fprintf(w, "  %-32s %d row(s) in %d replay(s)\n", e.Table+"."+e.Column, e.Rows, e.Replays)
+	}
+
+	fmt.Fprintln(w, "\nSQLite checks:")
+	fmt.Fprintf(w, "  integrity_check:   %s\n", strings.Join(r.Integrity, "; "))
+	if len(r.ForeignKeys) == 0 {
+		fmt.Fprintln(w, "  foreign_key_check: ok")
+	}
+	for _, v := range r.ForeignKeys {
+		fmt.Fprintf(w, "  foreign_key_check: %d row(s) in %s reference missing %s\n", v.Rows, v.Table, v.Parent)
+	}
+
+	if n := r.Problems(); n == 0 {
+		fmt.Fprintln(w, "\nNo problems found.")
+	} else if !fix {
+		fmt.Fprintf(w, "\n%d problem(s) found.\n", n)
+	}
+}
+
+func joinIDs(ids []int64) string {
+	const limit = 20
+	parts := make([]string, 0, limit+1)
+	for i, id := range ids {
+		if i == limit {
+			parts = append(parts, fmt.Sprintf("… (%d more)", len(ids)-limit))
+			break
+		}
+		parts = append(parts, fmt.Sprint(id))
+	}
+	return strings.Join(parts, ", ")
+}
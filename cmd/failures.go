@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/marianogappa/screpdb/internal/appdata"
+	"github.com/marianogappa/screpdb/internal/ingest"
+	"github.com/marianogappa/screpdb/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var failuresSQLitePath string
+
+var failuresCmd = &cobra.Command{
+	Use:   "failures",
+	Short: "List replay files that failed to ingest or were skipped",
+	Long: `List the replay files recorded in the ingest failure ledger: files that failed to parse, crashed the parser (panic), or were skipped as UMS, with the screpdb version and number of attempts.
+
+Ingest skips a recorded file while the screpdb version and the file's content are unchanged, and re-attempts it automatically after an upgrade. Run ` + "`screpdb ingest --retry-failed`" + ` to re-attempt them now; files that then ingest are removed from the ledger.`,
+	RunE: runFailures,
+}
+
+func init() {
+	failuresCmd.Flags().StringVarP(&failuresSQLitePath, "sqlite-path", "s", "screp.db", "SQLite database file path")
+}
+
+func runFailures(cmd *cobra.Command, args []string) error {
+	dbPath, err := appdata.ResolveDBPath(failuresSQLitePath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve database path: %w", err)
+	}
+	failures, err := ingest.ListFailures(context.Background(), dbPath)
+	if err != nil {
+		return err
+	}
+	printFailures(cmd.OutOrStdout(), failures)
+	return nil
+}
+
+func printFailures(w io.Writer, failures []storage.IngestFailure) {
+	if len(failures) == 0 {
+		fmt.Fprintln(w, "No failed or skipped replays recorded.")
+		return
+	}
+	fmt.Fprintf(w, "%d failed or skipped replay(s):\n", len(failures))
+	for _, f := range failures {
+		fmt.Fprintf(w, "\n%s\n", f.FilePath)
+		line := fmt.Sprintf("  %s, %d attempt(s), last %s with screpdb %s", f.ErrorClass, f.Attempts, f.LastFailedAt, f.ScrepdbVersion)
+		if f.StackHash != "" {
+			line += ", stack " + f.StackHash
+		}
+		fmt.Fprintln(w, line)
+		// Parse errors can be long and multi-line; the first line identifies them.
+		message, _, _ := strings.Cut(f.Message, "\n")
+		fmt.Fprintf(w, "  %s\n", message)
+	}
+}
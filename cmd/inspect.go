@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/marianogappa/screpdb/internal/fileops"
+	"github.com/marianogappa/screpdb/internal/inspect"
+	"github.com/marianogappa/screpdb/internal/iofacade"
+	"github.com/marianogappa/screpdb/internal/parser"
+	"github.com/spf13/cobra"
+)
+
+var inspectFormat string
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect <file.rep>",
+	Short: "Analyze a single replay in memory and print what screpdb detects",
+	Long: `Analyze a single replay without touching any database: players and winners, detected openers and markers, the narrative event timeline and, for multi-player melee, the alliance timeline.
+
+Useful to check a replay before ingesting it, and to attach reproducible output to misdetection reports. A replay inside a .zip archive is addressed as archive.zip!/inner/path.rep.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runInspect,
+}
+
+func init() {
+	inspectCmd.Flags().StringVarP(&inspectFormat, "format", "o", inspect.FormatText, "Output format: text or json")
+}
+
+func runInspect(cmd *cobra.Command, args []string) error {
+	if inspectFormat != inspect.FormatText && inspectFormat != inspect.FormatJSON {
+		return fmt.Errorf("unknown --format %q (want %s or %s)", inspectFormat, inspect.FormatText, inspect.FormatJSON)
+	}
+	// A replay inside an archive is addressed as archive.zip!/inner/path.rep;
+	// only the archive part is a filesystem path.
+	container, entry, inArchive := fileops.SplitArchivePath(args[0])
+	if !inArchive {
+		container = args[0]
+	}
+	path, err := filepath.Abs(container)
+	if err != nil {
+		return fmt.Errorf("failed to resolve replay path: %w", err)
+	}
+	if inArchive {
+		path = fileops.ArchiveEntryPath(path, entry)
+	}
+	if err := iofacade.AllowDir(fileops.ReplayDir(path)); err != nil {
+		return err
+	}
+
+	report, err := inspect.File(path, parser.Options{EarlyFilterDebugDir: os.Getenv("SCREPDB_EARLY_FILTER_DEBUG_DIR")})
+	if err != nil {
+		return fmt.Errorf("failed to inspect %s: %w", args[0], err)
+	}
+	return inspect.Write(cmd.OutOrStdout(), inspectFormat, report)
+}
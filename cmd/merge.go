@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/marianogappa/screpdb/internal/appdata"
+	"github.com/marianogappa/screpdb/internal/merge"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mergeFrom []string
+	mergeInto string
+)
+
+var mergeCmd = &cobra.Command{
+	Use:   "merge",
+	Short: "Merge replays from other screpdb databases into one",
+	Long: `Copy every replay of one or more screpdb databases (players, commands, low-value commands and detections included) into another, for example to pool a team's local databases into a shared corpus.
+
+Replays already present (same file checksum) are skipped, so merging is safe to repeat. Player aliases are merged too: when two databases map a battle tag to different aliases, manual entries win over imported ones, then the most recently updated. A source's own "you" aliases are kept as manual.
+The --into database is created if it does not exist; sources are only read.
+
+  screpdb merge --from alice.db --from bob.db --into team.db`,
+	RunE: runMerge,
+}
+
+func init() {
+	mergeCmd.Flags().StringArrayVar(&mergeFrom, "from", nil, "Source database file path (repeatable)")
+	mergeCmd.Flags().StringVar(&mergeInto, "into", "", "Target database file path")
+	_ = mergeCmd.MarkFlagRequired("from")
+	_ = mergeCmd.MarkFlagRequired("into")
+}
+
+func runMerge(cmd *cobra.Command, args []string) error {
+	into, err := appdata.ResolveDBPath(mergeInto)
+	if err != nil {
+		return fmt.Errorf("failed to resolve database path: %w", err)
+	}
+	sources := make([]string, 0, len(mergeFrom))
+	for _, src := range mergeFrom {
+		path, err := appdata.ResolveDBPath(src)
+		if err != nil {
+			return fmt.Errorf("failed to resolve database path: %w", err)
+		}
+		sources = append(sources, path)
+	}
+	results, err := merge.Run(context.Background(), merge.Config{Sources: sources, Into: into})
+	w := cmd.OutOrStdout()
+	for _, r := range results {
+		printMergeResult(w, r)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Merged %d database(s) into %s\n", len(results), into)
+	return nil
+}
+
+func printMergeResult(w io.Writer, r merge.SourceResult) {
+	s := r.Stats
+	fmt.Fprintf(w, "%s:\n", r.Path)
+	fmt.Fprintf(w, "  replays:  %d merged, %d already present", s.Replays, s.DuplicateReplays)
+	if s.PathConflicts > 0 {
+		fmt.Fprintf(w, ", %d skipped (file path already used by another replay)", s.PathConflicts)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "  rows:     %d players, %d commands, %d low-value commands, %d command blobs, %d events, %d analysis inputs, %d economy samples, %d production busy spans, %d larva samples, %d hotkey usage rows\n",
+		s.Players, s.Commands, s.CommandsLowValue, s.CommandBlobs, s.ReplayEvents, s.AnalysisInputs, s.EconomySamples, s.ProductionBusySpans, s.LarvaSamples, s.HotkeyUsage)
+	fmt.Fprintf(w, "  aliases:  %d added, %d updated, %d conflicts resolved\n",
+		s.AliasesAdded, s.AliasesUpdated, s.AliasConflicts)
+}
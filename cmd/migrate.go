@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/marianogappa/screpdb/internal/appdata"
+	"github.com/marianogappa/screpdb/internal/backup"
+	"github.com/marianogappa/screpdb/internal/iofacade"
+	"github.com/marianogappa/screpdb/internal/migrations"
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateSQLitePath string
+	migrateSet        string
+	migrateNoBackup   bool
+	migrateSteps      int
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Show, apply or revert the database's schema migrations",
+	Long: `Inspect and move the schema of the replay, dashboard and settings migration sets. Every screpdb command already applies pending migrations when it opens the database; this command shows where each set stands and takes a set down to an older version, so the database can be handed to an older screpdb and brought forward again without a re-ingest.
+
+Down migrations keep data wherever the older schema can hold it: compacted commands are expanded back into rows, for instance. Reverting 000001 of a set drops its tables, and with the settings set that includes aliases and the global replay filter.
+
+Before changing anything the database is saved as a rotating backup in the app-data backups folder (skip with --no-backup); ` + "`screpdb restore`" + ` undoes the change. screpdb takes the same backup before applying pending migrations on its own.
+
+  screpdb migrate status
+  screpdb migrate up
+  screpdb migrate down --set replay --steps 2
+  screpdb migrate to 6 --set replay`,
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show each migration set's version and pending migrations",
+	Args:  cobra.NoArgs,
+	RunE:  runMigrateStatus,
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply every pending migration",
+	Args:  cobra.NoArgs,
+	RunE:  runMigrateUp,
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Revert a set's newest migrations",
+	Args:  cobra.NoArgs,
+	RunE:  runMigrateDown,
+}
+
+var migrateToCmd = &cobra.Command{
+	Use:   "to <version>",
+	Short: "Apply or revert a set's migrations until it is at version",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runMigrateTo,
+}
+
+func init() {
+	migrateCmd.PersistentFlags().StringVarP(&migrateSQLitePath, "sqlite-path", "s", "screp.db", "SQLite database file path")
+	migrateCmd.PersistentFlags().StringVar(&migrateSet, "set", "", "Migration set: replay, dashboard or settings (status and up default to all; down and to require one)")
+	migrateCmd.PersistentFlags().BoolVar(&migrateNoBackup, "no-backup", false, "Don't back the database up before changing its schema")
+	migrateDownCmd.Flags().IntVar(&migrateSteps, "steps", 1, "Migrations to revert")
+	migrateCmd.AddCommand(migrateStatusCmd, migrateUpCmd, migrateDownCmd, migrateToCmd)
+}
+
+func runMigrateStatus(cmd *cobra.Command, args []string) error {
+	dbPath, sets, err := migrateStatus()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "%s\n", dbPath)
+	return printMigrationStatus(cmd.OutOrStdout(), sets)
+}
+
+func runMigrateUp(cmd *cobra.Command, args []string) error {
+	dbPath, sets, err := migrateStatus()
+	if err != nil {
+		return err
+	}
+	targets := map[migrations.MigrationSet]int{}
+	for _, st := range sets {
+		if targets[st.Set], err = migrations.LatestVersion(st.Set); err != nil {
+			return err
+		}
+	}
+	return migrateSets(cmd.OutOrStdout(), dbPath, sets, targets)
+}
+
+func runMigrateDown(cmd *cobra.Command, args []string) error {
+	if migrateSteps < 1 {
+		return fmt.Errorf("--steps must be at least 1, got %d", migrateSteps)
+	}
+	dbPath, sets, err := migrateStatus()
+	if err != nil {
+		return err
+	}
+	if len(sets) != 1 {
+		return errors.New("migrate down needs --set: replay, dashboard or settings")
+	}
+	target := max(0, sets[0].Version()-migrateSteps)
+	return migrateSets(cmd.OutOrStdout(), dbPath, sets, map[migrations.MigrationSet]int{sets[0].Set: target})
+}
+
+func runMigrateTo(cmd *cobra.Command, args []string) error {
+	target, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid version %q: %w", args[0], err)
+	}
+	dbPath, sets, err := migrateStatus()
+	if err != nil {
+		return err
+	}
+	if len(sets) != 1 {
+		return errors.New("migrate to needs --set: replay, dashboard or settings")
+	}
+	latest, err := migrations.LatestVersion(sets[0].Set)
+	if err != nil {
+		return err
+	}
+	if target < 0 || target > latest {
+		return fmt.Errorf("%s migrations go from 0 to %d, not %d", sets[0].Set, latest, target)
+	}
+	return migrateSets(cmd.OutOrStdout(), dbPath, sets, map[migrations.MigrationSet]int{sets[0].Set: target})
+}
+
+// migrateStatus resolves --sqlite-path, which must exist, and returns the
+// status of the sets --set selects.
+func migrateStatus() (string, []migrations.SetStatus, error) {
+	dbPath, err := appdata.ResolveDBPath(migrateSQLitePath)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to resolve database path: %w", err)
+	}
+	if err := iofacade.AllowDir(filepath.Dir(dbPath)); err != nil {
+		return "", nil, fmt.Errorf("failed to register database folder: %w", err)
+	}
+	if _, err := iofacade.Stat(dbPath); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil, fmt.Errorf("database %s does not exist", dbPath)
+		}
+		return "", nil, fmt.Errorf("failed to stat database: %w", err)
+	}
+	all, err := migrations.Status(dbPath)
+	if err != nil {
+		return "", nil, err
+	}
+	if migrateSet == "" {
+		return dbPath, all, nil
+	}
+	for _, st := range all {
+		if string(st.Set) == migrateSet {
+			return dbPath, []migrations.SetStatus{st}, nil
+		}
+	}
+	return "", nil, fmt.Errorf("unknown migration set %q: want replay, dashboard or settings", migrateSet)
+}
+
+// migrateSets moves each of sets of the database at dbPath to its version in
+// targets, backing the database up first unless they are all there already.
+func migrateSets(w io.Writer, dbPath string, sets []migrations.SetStatus, targets map[migrations.MigrationSet]int) error {
+	changing := false
+	for _, st := range sets {
+		changing = changing || st.Changes(targets[st.Set])
+	}
+	if !changing {
+		fmt.Fprintln(w, "Nothing to migrate.")
+		return nil
+	}
+	if !migrateNoBackup {
+		info, ok, err := backup.BeforeMigrating(context.Background(), dbPath)
+		if err != nil {
+			return err
+		}
+		if ok {
+			fmt.Fprintf(w, "Backed up %s to %s (%s)\n", dbPath, info.Path, formatBackupSize(info.SizeBytes))
+		}
+	}
+	for _, st := range sets {
+		ran, err := migrations.MigrateTo(dbPath, st.Set, targets[st.Set])
+		for _, name := range ran {
+			fmt.Fprintf(w, "  %s/%s\n", st.Set, name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(ran) > 0 {
+			fmt.Fprintf(w, "Migrated %s to version %d\n", st.Set, targets[st.Set])
+		}
+	}
+	return nil
+}
+
+func printMigrationStatus(w io.Writer, sets []migrations.SetStatus) error {
+	for _, st := range sets {
+		latest, err := migrations.LatestVersion(st.Set)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%-10s version %d of %d\n", st.Set, st.Version(), latest)
+		if len(st.Pending) > 0 {
+			fmt.Fprintf(w, "  pending: %s\n", strings.Join(st.Pending, ", "))
+		}
+		if len(st.Unknown) > 0 {
+			fmt.Fprintf(w, "  unknown to this build (applied by a newer screpdb): %s\n", strings.Join(st.Unknown, ", "))
+		}
+	}
+	return nil
+}
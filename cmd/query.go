@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/marianogappa/screpdb/internal/appdata"
+	"github.com/marianogappa/screpdb/internal/iofacade"
+	"github.com/marianogappa/screpdb/internal/mcp"
+	"github.com/marianogappa/screpdb/internal/queryout"
+	"github.com/marianogappa/screpdb/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	querySQLitePath string
+	queryFile       string
+	queryFormat     string
+	queryParams     []string
+	queryMaxRows    int
+)
+
+var queryCmd = &cobra.Command{
+	Use:   "query [sql]",
+	Short: "Run a read-only SQL query against the database",
+	Long: `Run a single read-only SQL statement (SELECT, WITH, EXPLAIN or PRAGMA) against the replay database and print the results.
+
+The statement is taken from the argument, from --file, or from stdin when neither is given.
+Bind values are passed positionally (--param 42) or by name (--param player=Foo, referenced as :player).`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runQuery,
+}
+
+func init() {
+	queryCmd.Flags().StringVarP(&querySQLitePath, "sqlite-path", "s", "screp.db", "SQLite database file path")
+	queryCmd.Flags().StringVarP(&queryFile, "file", "f", "", "Read the SQL statement from this file")
+	queryCmd.Flags().StringVarP(&queryFormat, "format", "o", queryout.FormatTable, "Output format: "+strings.Join(queryout.Formats, ", "))
+	queryCmd.Flags().StringArrayVarP(&queryParams, "param", "p", nil, "Bind value, positional (VALUE) or named (NAME=VALUE); repeatable")
+	queryCmd.Flags().IntVar(&queryMaxRows, "max-rows", 0, "Stop after this many rows (0 = no limit)")
+}
+
+func runQuery(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	if queryMaxRows < 0 {
+		return fmt.Errorf("--max-rows must be >= 0")
+	}
+
+	query, err := readQuerySQL(cmd, args)
+	if err != nil {
+		return err
+	}
+	if err := mcp.EnsureReadOnly(query); err != nil {
+		return err
+	}
+	bindArgs, err := parseQueryParams(queryParams)
+	if err != nil {
+		return err
+	}
+	// Validate the format before touching the database so a typo fails fast.
+	if err := queryout.Write(io.Discard, queryFormat, nil, nil); err != nil {
+		return err
+	}
+
+	dbPath, err := appdata.ResolveDBPath(querySQLitePath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve database path: %w", err)
+	}
+	// Opening a missing path would make SQLite create an empty database;
+	// report it instead of leaving a stray file behind.
+	if err := iofacade.AllowDir(filepath.Dir(dbPath)); err != nil {
+		return fmt.Errorf("failed to register database folder: %w", err)
+	}
+	if _, err := iofacade.Stat(dbPath); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("database %s does not exist; run `screpdb ingest` first", dbPath)
+		}
+		return fmt.Errorf("failed to stat database: %w", err)
+	}
+
+	store, err := storage.NewSQLiteStorage(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to create SQLite storage: %w", err)
+	}
+	defer store.Close()
+
+	columns, rows, truncated, err := store.QueryColumns(ctx, queryMaxRows, query, bindArgs...)
+	if err != nil {
+		return fmt.Errorf("query execution failed: %w", err)
+	}
+	if err := queryout.Write(cmd.OutOrStdout(), queryFormat, columns, rows); err != nil {
+		return err
+	}
+	if truncated {
+		// stderr, so machine-readable stdout stays parseable.
+		fmt.Fprintf(cmd.ErrOrStderr(), "warning: output truncated to %d rows (--max-rows)\n", queryMaxRows)
+	}
+	return nil
+}
+
+// readQuerySQL returns the statement from the positional argument, --file, or
+// stdin, in that order. Supplying both an argument and --file is an error.
+func readQuerySQL(cmd *cobra.Command, args []string) (string, error) {
+	var query string
+	switch {
+	case len(args) == 1 && queryFile != "":
+		return "", fmt.Errorf("pass the SQL either as an argument or with --file, not both")
+	case len(args) == 1:
+		query = args[0]
+	case queryFile != "":
+		// The SQL file may live anywhere the user points at (e.g. a reports
+		// repo), so register its folder as a read root first.
+		if err := iofacade.AllowDir(filepath.Dir(queryFile)); err != nil {
+			return "", fmt.Errorf("failed to register SQL file folder: %w", err)
+		}
+		data, err := iofacade.ReadFile(queryFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read SQL file: %w", err)
+		}
+		query = string(data)
+	default:
+		data, err := io.ReadAll(cmd.InOrStdin())
+		if err != nil {
+			return "", fmt.Errorf("failed to read SQL from stdin: %w", err)
+		}
+		query = string(data)
+	}
+	if strings.TrimSpace(query) == "" {
+		return "", fmt.Errorf("no SQL given; pass it as an argument, with --file, or on stdin")
+	}
+	return query, nil
+}
+
+// namedParamPattern matches NAME=VALUE bind values. Anything else is bound
+// positionally, so a literal value containing '=' still works as long as it
+// doesn't start with an identifier.
+var namedParamPattern = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)=(.*)$`)
+
+// parseQueryParams turns --param values into driver bind arguments. Values
+// that parse as integers or floats are bound as numbers so they work with
+// LIMIT and numeric comparisons; everything else is bound as text. Mixing
+// named and positional values is rejected to keep binding unambiguous.
+func parseQueryParams(params []string) ([]any, error) {
+	args := make([]any, 0, len(params))
+	named, positional := 0, 0
+	for _, p := range params {
+		if m := namedParamPattern.FindStringSubmatch(p); m != nil {
+			args = append(args, sql.Named(m[1], queryParamValue(m[2])))
+			named++
+			continue
+		}
+		args = append(args, queryParamValue(p))
+		positional++
+	}
+	if named > 0 && positional > 0 {
+		return nil, fmt.Errorf("--param values must be all positional or all NAME=VALUE, not a mix")
+	}
+	return args, nil
+}
+
+func queryParamValue(s string) any {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i
+	}
+	// ParseFloat also accepts "Inf"/"NaN", which are far more likely to be
+	// player names than numbers here.
+	if f, err := strconv.ParseFloat(s, 64); err == nil && strings.ContainsAny(s, "0123456789") {
+		return f
+	}
+	return s
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/marianogappa/screpdb/internal/appdata"
+	"github.com/marianogappa/screpdb/internal/ingest"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reanalyzeSQLitePath  string
+	reanalyzeReplayIDs   []int64
+	reanalyzeFeatureKeys []string
+	reanalyzeConcurrency int
+	reanalyzeDryRun      bool
+	reanalyzeFromDB      bool
+)
+
+var reanalyzeCmd = &cobra.Command{
+	Use:   "reanalyze",
+	Short: "Re-run pattern detection on already-ingested replays",
+	Long: `Re-run pattern detection (markers, openers and game events) on replays that are already in the database, reading each replay's original .rep file. Replays whose file was moved, deleted or replaced since ingest are rebuilt from their stored commands instead; --from-db does that for every replay.
+
+By default every replay analyzed by an older algorithm version is selected. --replay-id and --feature-key select specific replays instead (their union), whatever version they were analyzed with.
+Use --dry-run to see how many marker rows would change, and which openers would be reclassified, without writing anything.`,
+	RunE: runReanalyze,
+}
+
+func init() {
+	reanalyzeCmd.Flags().StringVarP(&reanalyzeSQLitePath, "sqlite-path", "s", "screp.db", "SQLite database file path")
+	reanalyzeCmd.Flags().Int64SliceVar(&reanalyzeReplayIDs, "replay-id", nil, "Re-analyze these replay IDs (repeatable or comma-separated)")
+	reanalyzeCmd.Flags().StringSliceVar(&reanalyzeFeatureKeys, "feature-key", nil, "Re-analyze replays carrying these marker feature keys, e.g. bo_9_pool (repeatable or comma-separated)")
+	reanalyzeCmd.Flags().IntVarP(&reanalyzeConcurrency, "concurrency", "j", 0, "Replays to parse in parallel (0 = number of CPUs)")
+	reanalyzeCmd.Flags().BoolVar(&reanalyzeDryRun, "dry-run", false, "Report what would change without writing to the database")
+	reanalyzeCmd.Flags().BoolVar(&reanalyzeFromDB, "from-db", false, "Rebuild replays from the database even when their .rep file is available")
+}
+
+func runReanalyze(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	dbPath, err := appdata.ResolveDBPath(reanalyzeSQLitePath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve database path: %w", err)
+	}
+
+	summary, err := ingest.Reanalyze(ctx, ingest.ReanalyzeConfig{
+		SQLitePath:          dbPath,
+		ReplayIDs:           reanalyzeReplayIDs,
+		FeatureKeys:         reanalyzeFeatureKeys,
+		Concurrency:         reanalyzeConcurrency,
+		DryRun:              reanalyzeDryRun,
+		FromDatabase:        reanalyzeFromDB,
+		UseColor:            true,
+		EarlyFilterDebugDir: os.Getenv("SCREPDB_EARLY_FILTER_DEBUG_DIR"),
+	})
+	if err != nil {
+		return fmt.Errorf("re-analysis failed: %w", err)
+	}
+
+	printReanalyzeSummary(cmd.OutOrStdout(), summary, reanalyzeDryRun)
+	return nil
+}
+
+func printReanalyzeSummary(w io.Writer, s *ingest.ReanalyzeSummary, dryRun bool) {
+	if s.Reanalyzed == 0 {
+		return
+	}
+	heading := "Marker rows changed"
+	if dryRun {
+		heading = "Marker rows that would change (dry run, nothing written)"
+	}
+	fmt.Fprintf(w, "\n%s:\n", heading)
+	if len(s.Markers) == 0 {
+		fmt.Fprintln(w, "  none")
+	} else {
+		fmt.Fprintf(w, "  %-40s %7s %7s %7s\n", "feature_key", "added", "removed", "changed")
+		for _, key := range s.SortedMarkerKeys() {
+			d := s.Markers[key]
+			fmt.Fprintf(w, "  %-40s %7d %7d %7d\n", key, d.Added, d.Removed, d.Changed)
+		}
+	}
+
+	fmt.Fprintln(w, "\nOpener reclassifications (per player):")
+	if len(s.OpenerChanges) == 0 {
+		fmt.Fprintln(w, "  none")
+		return
+	}
+	for _, change := range s.SortedOpenerChanges() {
+		fmt.Fprintf(w, "  %5d  %s\n", s.OpenerChanges[change], change)
+	}
+}
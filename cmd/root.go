@@ -27,6 +27,17 @@ func Execute() error {
 func init() {
 	rootCmd.AddCommand(ingestCmd)
 	rootCmd.AddCommand(mcpCmd)
+	rootCmd.AddCommand(queryCmd)
+	rootCmd.AddCommand(reanalyzeCmd)
+	rootCmd.AddCommand(inspectCmd)
+	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(mergeCmd)
+	rootCmd.AddCommand(failuresCmd)
+	rootCmd.AddCommand(backupCmd)
+	rootCmd.AddCommand(restoreCmd)
+	rootCmd.AddCommand(compactCmd)
+	rootCmd.AddCommand(aggregatesCmd)
+	rootCmd.AddCommand(migrateCmd)
 	rootCmd.AddCommand(dashboardCmd)
 	addDashboardFlags(rootCmd)
 }
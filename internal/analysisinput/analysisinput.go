@@ -0,0 +1,211 @@
+// Package analysisinput persists what pattern detection reads from a replay
+// beyond its replays, players, commands and commands_low_value rows, so a
+// replay can be re-analyzed after its .rep file is moved, deleted or left
+// behind on a teammate's machine.
+//
+// The stored command tables hold the stream detection ran on (after the
+// early-game filter and dedup passes), but not all of it: Right Click and
+// Hotkey commands may be skipped by ingest options, and a few fields are
+// transient (target unit tags, larva morph multiplicity, order IDs). The rest
+// is derived from the raw .rep — map geometry, selection-state evidence from
+// internal/unittags, Mutalisk harass episodes, player activity for alliance
+// analysis — and is captured here at ingest.
+//
+// A replay rebuilt from an Input runs detection on the same stream it ran on
+// at ingest, so re-analysis picks up detector changes (core.AlgorithmVersion)
+// but keeps the ingest-time early filter.
+package analysisinput
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/marianogappa/screpdb/internal/models"
+	"github.com/marianogappa/screpdb/internal/unittags"
+)
+
+// FormatVersion is stored next to every encoded Input. Bump it when a change
+// to Input can't be read by the previous Decode, or when older inputs decode
+// but lack evidence the detectors now rely on; stored inputs of another
+// version are treated as missing.
+//
+// 2: player evidence records larva morphs.
+// 3: player evidence records hotkey bindings.
+// 4: producers are keyed by the tank's mode name, so older inputs would still
+// count a Siege Tank as a separate Factory.
+const FormatVersion = 4
+
+// ErrUnsupportedFormat is returned by Decode for inputs of another
+// FormatVersion.
+var ErrUnsupportedFormat = errors.New("unsupported analysis input format")
+
+// Where a command of the analysis stream is stored.
+const (
+	SourceCommands byte = iota
+	SourceLowValue
+	SourceOmitted
+)
+
+// Input is one replay's persisted detection input. The parser fills the
+// replay-derived fields; storage records where each command went with Record.
+type Input struct {
+	// PlayerIDs maps each player's slot to its in-replay player ID, which the
+	// players table doesn't keep.
+	PlayerIDs  map[uint16]byte              `json:"player_ids"`
+	MapContext MapContext                   `json:"map_context"`
+	Evidence   *unittags.Evidence           `json:"evidence,omitempty"`
+	MutaHarass []unittags.MutaHarassEpisode `json:"muta_harass,omitempty"`
+	// Activity is set for multi-player melee, where alliances are analyzed.
+	// It is computed from the unfiltered stream, which isn't stored.
+	Activity *Activity `json:"activity,omitempty"`
+
+	// Sources holds one Source* value per command of the analysis stream, in
+	// stream order. Commands stored in a table appear there in the same
+	// relative order.
+	Sources []byte `json:"sources"`
+	// Omitted are the stream's commands not stored in either table.
+	Omitted []OmittedCommand `json:"omitted,omitempty"`
+	// Extras are the transient fields of stream commands that carry any.
+	Extras []Extra `json:"extras,omitempty"`
+}
+
+// Activity mirrors parser.Activity.
+type Activity struct {
+	StoppedSecByPID map[byte]int `json:"stopped_sec_by_pid,omitempty"`
+	LeaveSecByPID   map[byte]int `json:"leave_sec_by_pid,omitempty"`
+}
+
+// OmittedCommand is a stream command that storage skipped.
+type OmittedCommand struct {
+	PlayerID byte            `json:"player_id"` // in-replay player ID
+	Command  *models.Command `json:"command"`
+}
+
+// Extra holds the fields of the stream command at Index that no table keeps.
+type Extra struct {
+	Index          int     `json:"i"`
+	OrderID        *byte   `json:"order_id,omitempty"`
+	TargetUnitTag  *uint16 `json:"target_unit_tag,omitempty"`
+	TargetUnitType *string `json:"target_unit_type,omitempty"`
+	SelectedUnits  int     `json:"selected_units,omitempty"`
+	MorphUnitCount int     `json:"morph_unit_count,omitempty"`
+}
+
+// Record appends the next stream command, stored per source.
+func (in *Input) Record(cmd *models.Command, source byte) {
+	index := len(in.Sources)
+	in.Sources = append(in.Sources, source)
+	if source == SourceOmitted {
+		var pid byte
+		if cmd.Player != nil {
+			pid = cmd.Player.PlayerID
+		}
+		in.Omitted = append(in.Omitted, OmittedCommand{PlayerID: pid, Command: cmd})
+	}
+	if cmd.OrderID != nil || cmd.TargetUnitTag != nil || cmd.TargetUnitType != nil || cmd.SelectedUnits != 0 || cmd.MorphUnitCount != 0 {
+		in.Extras = append(in.Extras, Extra{
+			Index:          index,
+			OrderID:        cmd.OrderID,
+			TargetUnitTag:  cmd.TargetUnitTag,
+			TargetUnitType: cmd.TargetUnitType,
+			SelectedUnits:  cmd.SelectedUnits,
+			MorphUnitCount: cmd.MorphUnitCount,
+		})
+	}
+}
+
+// Rebuild interleaves the commands read back from the commands and
+// commands_low_value tables (each in insertion order) with the omitted ones
+// into the analysis stream, and restores their transient fields. Omitted
+// commands are attributed through playersByID, keyed by in-replay player ID,
+// and inherit replay from the stored ones.
+func (in *Input) Rebuild(commands, lowValue []*models.Command, playersByID map[byte]*models.Player, replay *models.Replay) ([]*models.Command, error) {
+	stream := make([]*models.Command, 0, len(in.Sources))
+	var nextCommand, nextLowValue, nextOmitted int
+	for _, source := range in.Sources {
+		switch source {
+		case SourceCommands:
+			if nextCommand >= len(commands) {
+				return nil, fmt.Errorf("analysis input expects more than %d commands rows", len(commands))
+			}
+			stream = append(stream, commands[nextCommand])
+			nextCommand++
+		case SourceLowValue:
+			if nextLowValue >= len(lowValue) {
+				return nil, fmt.Errorf("analysis input expects more than %d commands_low_value rows", len(lowValue))
+			}
+			stream = append(stream, lowValue[nextLowValue])
+			nextLowValue++
+		case SourceOmitted:
+			if nextOmitted >= len(in.Omitted) {
+				return nil, fmt.Errorf("analysis input is missing omitted command %d", nextOmitted)
+			}
+			omitted := in.Omitted[nextOmitted]
+			nextOmitted++
+			cmd := omitted.Command
+			cmd.Player = playersByID[omitted.PlayerID]
+			if cmd.Player == nil {
+				return nil, fmt.Errorf("omitted command references unknown player %d", omitted.PlayerID)
+			}
+			cmd.PlayerID = cmd.Player.ID
+			cmd.Replay = replay
+			stream = append(stream, cmd)
+		default:
+			return nil, fmt.Errorf("unknown command source %d", source)
+		}
+	}
+	if nextCommand != len(commands) || nextLowValue != len(lowValue) {
+		return nil, fmt.Errorf("analysis input covers %d commands and %d commands_low_value rows, found %d and %d",
+			nextCommand, nextLowValue, len(commands), len(lowValue))
+	}
+	for _, extra := range in.Extras {
+		if extra.Index < 0 || extra.Index >= len(stream) {
+			return nil, fmt.Errorf("analysis input extra for command %d is out of range", extra.Index)
+		}
+		cmd := stream[extra.Index]
+		cmd.OrderID = extra.OrderID
+		cmd.TargetUnitTag = extra.TargetUnitTag
+		cmd.TargetUnitType = extra.TargetUnitType
+		cmd.SelectedUnits = extra.SelectedUnits
+		cmd.MorphUnitCount = extra.MorphUnitCount
+	}
+	return stream, nil
+}
+
+// Encode serializes in as gzip-compressed JSON.
+func Encode(in *Input) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(in); err != nil {
+		return nil, fmt.Errorf("failed to encode analysis input: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress analysis input: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode reverses Encode for an input stored with formatVersion.
+func Decode(formatVersion int, payload []byte) (*Input, error) {
+	if formatVersion != FormatVersion {
+		return nil, fmt.Errorf("%w: version %d, want %d", ErrUnsupportedFormat, formatVersion, FormatVersion)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress analysis input: %w", err)
+	}
+	defer zr.Close()
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress analysis input: %w", err)
+	}
+	var in Input
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return nil, fmt.Errorf("failed to decode analysis input: %w", err)
+	}
+	return &in, nil
+}
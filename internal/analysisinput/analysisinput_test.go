@@ -0,0 +1,97 @@
+package analysisinput
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/marianogappa/screpdb/internal/models"
+)
+
+func TestRecordAndRebuildRoundTrip(t *testing.T) {
+	player := &models.Player{ID: 7, PlayerID: 1}
+	replay := &models.Replay{ID: 3}
+	tag := uint16(42)
+	transport := "Dropship"
+	stream := []*models.Command{
+		{Frame: 1, ActionType: "Train", Player: player, MorphUnitCount: 2},
+		{Frame: 2, ActionType: "Right Click", Player: player, TargetUnitTag: &tag, TargetUnitType: &transport},
+		{Frame: 3, ActionType: "Hotkey", Player: player},
+		{Frame: 4, ActionType: "Build", Player: player},
+	}
+
+	in := &Input{}
+	in.Record(stream[0], SourceCommands)
+	in.Record(stream[1], SourceOmitted)
+	in.Record(stream[2], SourceLowValue)
+	in.Record(stream[3], SourceCommands)
+
+	payload, err := Encode(in)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	decoded, err := Decode(FormatVersion, payload)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	// What the command tables give back: no transient fields.
+	commands := []*models.Command{
+		{Frame: 1, ActionType: "Train", Player: player},
+		{Frame: 4, ActionType: "Build", Player: player},
+	}
+	lowValue := []*models.Command{{Frame: 3, ActionType: "Hotkey", Player: player}}
+	got, err := decoded.Rebuild(commands, lowValue, map[byte]*models.Player{1: player}, replay)
+	if err != nil {
+		t.Fatalf("Rebuild: %v", err)
+	}
+	if len(got) != len(stream) {
+		t.Fatalf("Rebuild returned %d commands, want %d", len(got), len(stream))
+	}
+	for i, cmd := range got {
+		if cmd.Frame != stream[i].Frame || cmd.ActionType != stream[i].ActionType {
+			t.Errorf("command %d = %d %s, want %d %s", i, cmd.Frame, cmd.ActionType, stream[i].Frame, stream[i].ActionType)
+		}
+	}
+	if got[0].MorphUnitCount != 2 {
+		t.Errorf("MorphUnitCount not restored: %d", got[0].MorphUnitCount)
+	}
+	rightClick := got[1]
+	if rightClick.Player != player || rightClick.PlayerID != player.ID || rightClick.Replay != replay {
+		t.Errorf("omitted command not attributed: %+v", rightClick)
+	}
+	if rightClick.TargetUnitTag == nil || *rightClick.TargetUnitTag != tag || rightClick.TargetUnitType == nil || *rightClick.TargetUnitType != transport {
+		t.Errorf("target unit not restored: %+v", rightClick)
+	}
+
+	if _, err := decoded.Rebuild(commands[:1], lowValue, map[byte]*models.Player{1: player}, replay); err == nil {
+		t.Error("Rebuild should reject missing table rows")
+	}
+	if _, err := decoded.Rebuild(append(commands, commands[0]), lowValue, map[byte]*models.Player{1: player}, replay); err == nil {
+		t.Error("Rebuild should reject rows it doesn't account for")
+	}
+}
+
+func TestDecodeRejectsOtherFormats(t *testing.T) {
+	payload, err := Encode(&Input{})
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if _, err := Decode(FormatVersion+1, payload); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("Decode of another format = %v, want ErrUnsupportedFormat", err)
+	}
+}
+
+func TestMapContextRoundTrip(t *testing.T) {
+	mc := &models.ReplayMapContext{
+		MineralFields: []models.MapResourcePosition{{X: 1, Y: 2}},
+		Layout: &models.MapContextLayout{
+			Bases:      []models.MapContextBase{{Name: "main", Clock: 12, Polygon: []models.MapPolygonPoint{{X: 3, Y: 4}}}},
+			WidthTiles: 128,
+		},
+	}
+	got := NewMapContext(mc).ReplayMapContext()
+	if len(got.MineralFields) != 1 || got.Layout == nil || got.Layout.WidthTiles != 128 ||
+		len(got.Layout.Bases) != 1 || got.Layout.Bases[0].Name != "main" || got.Layout.Bases[0].Polygon[0].Y != 4 {
+		t.Errorf("map context not restored: %+v", got)
+	}
+}
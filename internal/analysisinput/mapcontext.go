@@ -0,0 +1,73 @@
+package analysisinput
+
+import "github.com/marianogappa/screpdb/internal/models"
+
+// MapContext is the serialized form of models.ReplayMapContext, whose layout
+// fields are runtime-only.
+type MapContext struct {
+	MineralFields  []models.MapResourcePosition `json:"mineral_fields,omitempty"`
+	Geysers        []models.MapResourcePosition `json:"geysers,omitempty"`
+	StartLocations []models.MapStartLocation    `json:"start_locations,omitempty"`
+	Layout         *MapLayout                   `json:"layout,omitempty"`
+}
+
+// MapLayout is the serialized form of models.MapContextLayout.
+type MapLayout struct {
+	Bases       []MapBase `json:"bases"`
+	WidthTiles  int       `json:"width_tiles"`
+	HeightTiles int       `json:"height_tiles"`
+}
+
+// MapBase is the serialized form of models.MapContextBase.
+type MapBase struct {
+	Name             string                     `json:"name"`
+	Kind             string                     `json:"kind"`
+	Clock            int                        `json:"clock"`
+	Center           models.MapResourcePosition `json:"center"`
+	Polygon          []models.MapPolygonPoint   `json:"polygon,omitempty"`
+	MineralOnly      bool                       `json:"mineral_only,omitempty"`
+	NaturalExpansion string                     `json:"natural_expansion,omitempty"`
+}
+
+// NewMapContext captures mc; a nil mc yields an empty MapContext.
+func NewMapContext(mc *models.ReplayMapContext) MapContext {
+	if mc == nil {
+		return MapContext{}
+	}
+	out := MapContext{
+		MineralFields:  mc.MineralFields,
+		Geysers:        mc.Geysers,
+		StartLocations: mc.StartLocations,
+	}
+	if mc.Layout != nil {
+		out.Layout = &MapLayout{
+			Bases:       make([]MapBase, 0, len(mc.Layout.Bases)),
+			WidthTiles:  mc.Layout.WidthTiles,
+			HeightTiles: mc.Layout.HeightTiles,
+		}
+		for _, b := range mc.Layout.Bases {
+			out.Layout.Bases = append(out.Layout.Bases, MapBase(b))
+		}
+	}
+	return out
+}
+
+// ReplayMapContext restores the models.ReplayMapContext m was captured from.
+func (m MapContext) ReplayMapContext() *models.ReplayMapContext {
+	out := &models.ReplayMapContext{
+		MineralFields:  m.MineralFields,
+		Geysers:        m.Geysers,
+		StartLocations: m.StartLocations,
+	}
+	if m.Layout != nil {
+		out.Layout = &models.MapContextLayout{
+			Bases:       make([]models.MapContextBase, 0, len(m.Layout.Bases)),
+			WidthTiles:  m.Layout.WidthTiles,
+			HeightTiles: m.Layout.HeightTiles,
+		}
+		for _, b := range m.Layout.Bases {
+			out.Layout.Bases = append(out.Layout.Bases, models.MapContextBase(b))
+		}
+	}
+	return out
+}
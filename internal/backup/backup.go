@@ -0,0 +1,566 @@
+// Package backup copies the SQLite database with SQLite's online backup API,
+// so it can be backed up while the dashboard or an ingest is using it, and
+// restores such copies. It serves `screpdb backup`, `screpdb restore`, the
+// dashboard's scheduled backups and its backup endpoints.
+//
+// Scheduled and dashboard-triggered backups rotate in the backups folder of
+// the app-data root; see Rotate. Backups taken before a migration rotate
+// there too, under their own prefix and keep; see BeforeMigrating.
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"modernc.org/sqlite"
+
+	"github.com/marianogappa/screpdb/internal/appdata"
+	"github.com/marianogappa/screpdb/internal/crashreport"
+	"github.com/marianogappa/screpdb/internal/iofacade"
+	"github.com/marianogappa/screpdb/internal/migrations"
+)
+
+// DirName is the app-data subfolder rotating backups are kept in.
+const DirName = "backups"
+
+// DefaultKeep is how many rotating backups are kept when the caller doesn't
+// say.
+const DefaultKeep = 7
+
+// PreMigrationKeep is how many pre-migration backups are kept. They are
+// pruned apart from the other rotating backups, so neither kind pushes the
+// other out.
+const PreMigrationKeep = 3
+
+// stepPages is how many pages a backup copies per step. Between steps the
+// source is unlocked, so writers are never held up for the whole copy.
+const stepPages = 1024
+
+// rotatingPrefix and rotatingLayout name rotating backups: the UTC time they
+// were taken, down to the microsecond so two backups in the same second
+// don't overwrite each other. Backups taken before this build carry
+// legacyRotatingLayout. preMigrationPrefix marks the ones taken before a
+// migration.
+const (
+	rotatingPrefix       = "screp-"
+	preMigrationPrefix   = "screp-premigration-"
+	rotatingLayout       = "20060102-150405.000000"
+	legacyRotatingLayout = "20060102-150405"
+	rotatingSuffix       = ".db"
+)
+
+// Info describes a backup file. PreMigration is set on rotating backups taken
+// before a migration.
+type Info struct {
+	Name         string    `json:"name"`
+	Path         string    `json:"path"`
+	SizeBytes    int64     `json:"size_bytes"`
+	CreatedAt    time.Time `json:"created_at"`
+	PreMigration bool      `json:"pre_migration,omitempty"`
+}
+
+// backuper is implemented by modernc.org/sqlite's driver connections.
+type backuper interface {
+	NewBackup(dstURI string) (*sqlite.Backup, error)
+	NewRestore(srcURI string) (*sqlite.Backup, error)
+}
+
+// Dir returns the rotating backups folder, creating it if needed.
+func Dir() (string, error) {
+	dir, err := appdata.Path(DirName)
+	if err != nil {
+		return "", err
+	}
+	if err := iofacade.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create backups folder: %w", err)
+	}
+	return dir, nil
+}
+
+// Backup copies the database at sqlitePath to dest while it stays in use. The
+// copy is written next to dest and renamed into place once it passes an
+// integrity check, so dest never holds a partial backup. An existing dest is
+// replaced.
+func Backup(ctx context.Context, sqlitePath, dest string) (Info, error) {
+	dest, err := filepath.Abs(dest)
+	if err != nil {
+		return Info{}, fmt.Errorf("failed to resolve %s: %w", dest, err)
+	}
+	if err := iofacade.AllowDir(filepath.Dir(dest)); err != nil {
+		return Info{}, fmt.Errorf("failed to register backup folder: %w", err)
+	}
+	// SQLite URIs (in-memory databases included) are handed to the driver
+	// as-is; only file paths are checked up front.
+	src := sqlitePath
+	if sqlitePath != ":memory:" && !strings.HasPrefix(sqlitePath, "file:") {
+		if src, err = filepath.Abs(sqlitePath); err != nil {
+			return Info{}, fmt.Errorf("failed to resolve %s: %w", sqlitePath, err)
+		}
+		if dest == src {
+			return Info{}, errors.New("backup destination is the database itself")
+		}
+		if err := iofacade.AllowDir(filepath.Dir(src)); err != nil {
+			return Info{}, fmt.Errorf("failed to register database folder: %w", err)
+		}
+		if info, err := iofacade.Stat(src); err != nil || info.IsDir() {
+			return Info{}, fmt.Errorf("database %s does not exist", sqlitePath)
+		}
+	}
+	if info, err := iofacade.Stat(dest); err == nil && info.IsDir() {
+		return Info{}, fmt.Errorf("backup destination %s is a directory", dest)
+	}
+
+	tmp := dest + ".partial"
+	_ = iofacade.Remove(tmp)
+	err = withDriverConn(ctx, src, func(c backuper) error {
+		b, err := c.NewBackup(tmp)
+		if err != nil {
+			return err
+		}
+		return runSteps(ctx, b, stepPages)
+	})
+	if err == nil {
+		err = checkIntegrity(ctx, tmp)
+	}
+	if err != nil {
+		_ = iofacade.Remove(tmp)
+		return Info{}, fmt.Errorf("failed to back up %s: %w", sqlitePath, err)
+	}
+	if err := iofacade.Rename(tmp, dest); err != nil {
+		_ = iofacade.Remove(tmp)
+		return Info{}, fmt.Errorf("failed to move backup into place: %w", err)
+	}
+	return stat(dest)
+}
+
+// rotateMu serializes rotating backups, so a scheduled one and one triggered
+// from the dashboard neither share a name nor prune each other's files.
+var rotateMu sync.Mutex
+
+// Rotate backs the database at sqlitePath up into dir under a timestamped
+// name, then deletes the oldest rotating backups beyond keep (keep <= 0 keeps
+// them all). Pre-migration backups are neither counted nor deleted.
+func Rotate(ctx context.Context, sqlitePath, dir string, keep int) (Info, error) {
+	return rotate(ctx, sqlitePath, dir, false, keep)
+}
+
+func rotate(ctx context.Context, sqlitePath, dir string, preMigration bool, keep int) (Info, error) {
+	rotateMu.Lock()
+	defer rotateMu.Unlock()
+	prefix := rotatingPrefix
+	if preMigration {
+		prefix = preMigrationPrefix
+	}
+	taken := time.Now().UTC()
+	name := prefix + taken.Format(rotatingLayout) + rotatingSuffix
+	// Another process may have taken one in the same microsecond.
+	for {
+		if _, err := iofacade.Stat(filepath.Join(dir, name)); err != nil {
+			break
+		}
+		taken = taken.Add(time.Microsecond)
+		name = prefix + taken.Format(rotatingLayout) + rotatingSuffix
+	}
+	info, err := Backup(ctx, sqlitePath, filepath.Join(dir, name))
+	if err != nil {
+		return Info{}, err
+	}
+	if keep > 0 {
+		if err := prune(dir, preMigration, keep); err != nil {
+			return info, err
+		}
+	}
+	return info, nil
+}
+
+// Schedule takes a rotating backup of the database at sqlitePath into Dir
+// every interval, keeping keep, until ctx is done. The first one is due an
+// interval after the newest existing backup, so restarting the dashboard
+// doesn't reset the clock; with no backups yet it is taken right away.
+func Schedule(ctx context.Context, sqlitePath string, interval time.Duration, keep int) {
+	defer crashreport.GuardNonFatal(nil)
+	dir, err := Dir()
+	if err != nil {
+		log.Printf("Scheduled backups disabled: %v", err)
+		return
+	}
+	wait := time.Duration(0)
+	if backups, err := List(dir); err == nil {
+		for _, b := range backups {
+			if !b.PreMigration {
+				wait = max(0, time.Until(b.CreatedAt.Add(interval)))
+				break
+			}
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		info, err := Rotate(ctx, sqlitePath, dir, keep)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Scheduled backup failed: %v", err)
+			}
+		} else {
+			log.Printf("Backed up the database to %s", info.Path)
+		}
+		wait = interval
+	}
+}
+
+// List returns the rotating backups in dir, pre-migration ones included,
+// newest first.
+func List(dir string) ([]Info, error) {
+	out := []Info{}
+	err := iofacade.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if path == dir && errors.Is(err, os.ErrNotExist) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if fi.IsDir() {
+			if path != dir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		created, preMigration, ok := parseRotatingName(fi.Name())
+		if !ok {
+			return nil
+		}
+		out = append(out, Info{Name: fi.Name(), Path: path, SizeBytes: fi.Size(), CreatedAt: created, PreMigration: preMigration})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.After(out[j].CreatedAt)
+		}
+		return out[i].Name > out[j].Name
+	})
+	return out, nil
+}
+
+// Lookup returns the rotating backup called name in dir. Names that aren't a
+// rotating backup's, including ones with path separators, are rejected.
+func Lookup(dir, name string) (Info, error) {
+	if _, _, ok := parseRotatingName(name); !ok || filepath.Base(name) != name {
+		return Info{}, fmt.Errorf("%q is not a backup name", name)
+	}
+	return stat(filepath.Join(dir, name))
+}
+
+// prune deletes all but the keep newest rotating backups of one kind in dir.
+func prune(dir string, preMigration bool, keep int) error {
+	backups, err := List(dir)
+	if err != nil {
+		return err
+	}
+	kept := 0
+	for _, b := range backups {
+		if b.PreMigration != preMigration {
+			continue
+		}
+		if kept < keep {
+			kept++
+			continue
+		}
+		if err := iofacade.Remove(b.Path); err != nil {
+			return fmt.Errorf("failed to delete old backup %s: %w", b.Name, err)
+		}
+	}
+	return nil
+}
+
+// parseRotatingName returns when a rotating backup called name was taken and
+// whether it is a pre-migration one, and false when name isn't a rotating
+// backup's.
+func parseRotatingName(name string) (time.Time, bool, bool) {
+	stamp, preMigration := strings.CutPrefix(name, preMigrationPrefix)
+	if !preMigration {
+		var ok bool
+		if stamp, ok = strings.CutPrefix(name, rotatingPrefix); !ok {
+			return time.Time{}, false, false
+		}
+	}
+	stamp, ok := strings.CutSuffix(stamp, rotatingSuffix)
+	if !ok {
+		return time.Time{}, false, false
+	}
+	for _, layout := range []string{rotatingLayout, legacyRotatingLayout} {
+		if created, err := time.Parse(layout, stamp); err == nil {
+			return created, preMigration, true
+		}
+	}
+	return time.Time{}, false, false
+}
+
+func stat(path string) (Info, error) {
+	fi, err := iofacade.Stat(path)
+	if err != nil {
+		return Info{}, err
+	}
+	created, preMigration, ok := parseRotatingName(fi.Name())
+	if !ok {
+		created = fi.ModTime()
+	}
+	return Info{Name: fi.Name(), Path: path, SizeBytes: fi.Size(), CreatedAt: created, PreMigration: preMigration}, nil
+}
+
+// withDriverConn runs fn on a driver connection to the database at path.
+func withDriverConn(ctx context.Context, path string, fn func(backuper) error) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer conn.Close()
+	return conn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(backuper)
+		if !ok {
+			return fmt.Errorf("unexpected sqlite connection type %T", driverConn)
+		}
+		return fn(c)
+	})
+}
+
+// runSteps copies b's pages n at a time until done, then finishes it.
+func runSteps(ctx context.Context, b *sqlite.Backup, n int32) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			_ = b.Finish()
+			return err
+		}
+		more, err := b.Step(n)
+		if err != nil {
+			_ = b.Finish()
+			return err
+		}
+		if !more {
+			return b.Finish()
+		}
+	}
+}
+
+// checkIntegrity runs PRAGMA quick_check on the database at path.
+func checkIntegrity(ctx context.Context, path string) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	var result string
+	if err := db.QueryRowContext(ctx, "PRAGMA quick_check").Scan(&result); err != nil {
+		return fmt.Errorf("%s is not a readable SQLite database: %w", filepath.Base(path), err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("%s failed its integrity check: %s", filepath.Base(path), result)
+	}
+	return nil
+}
+
+// BeforeMigrating saves the database at sqlitePath as a pre-migration backup
+// in Dir ahead of a schema change, so a migration that goes wrong, or a
+// downgrade that turns out to be a mistake, is undone with Restore instead of
+// a re-ingest. Only the PreMigrationKeep newest are kept. It takes none,
+// returning false, when there is no screpdb schema to lose: the database is
+// in memory, doesn't exist yet, or was never migrated.
+func BeforeMigrating(ctx context.Context, sqlitePath string) (Info, bool, error) {
+	sets, ok, err := migrationStatus(sqlitePath)
+	if err != nil || !ok {
+		return Info{}, false, err
+	}
+	migrated := false
+	for _, m := range sets {
+		migrated = migrated || len(m.Applied) > 0
+	}
+	if !migrated {
+		return Info{}, false, nil
+	}
+	return rotateBeforeMigrating(ctx, sqlitePath)
+}
+
+// BeforePendingMigrations is BeforeMigrating for the migrations screpdb
+// applies by itself when it opens a database: it only takes a backup when an
+// already migrated database has some pending in the sets the caller is about
+// to run, so opening an up-to-date one costs nothing, and neither does one
+// that never runs a set (an ingest-only database has no settings). The
+// backup is logged, since nobody asked for it.
+func BeforePendingMigrations(ctx context.Context, sqlitePath string, sets ...migrations.MigrationSet) (Info, bool, error) {
+	status, ok, err := migrationStatus(sqlitePath)
+	if err != nil || !ok {
+		return Info{}, false, err
+	}
+	migrated, pending := false, false
+	for _, m := range status {
+		migrated = migrated || len(m.Applied) > 0
+		if slices.Contains(sets, m.Set) {
+			pending = pending || len(m.Pending) > 0
+		}
+	}
+	if !migrated || !pending {
+		return Info{}, false, nil
+	}
+	info, ok, err := rotateBeforeMigrating(ctx, sqlitePath)
+	if ok {
+		log.Printf("Backed up the database to %s before migrating it", info.Path)
+	}
+	return info, ok, err
+}
+
+// migrationStatus is migrations.Status for an existing database file, and
+// false for anything else; Status would create the file.
+func migrationStatus(sqlitePath string) ([]migrations.SetStatus, bool, error) {
+	if sqlitePath == ":memory:" || strings.HasPrefix(sqlitePath, "file:") {
+		return nil, false, nil
+	}
+	path, err := filepath.Abs(sqlitePath)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to resolve %s: %w", sqlitePath, err)
+	}
+	if err := iofacade.AllowDir(filepath.Dir(path)); err != nil {
+		return nil, false, fmt.Errorf("failed to register database folder: %w", err)
+	}
+	if info, err := iofacade.Stat(path); err != nil || info.IsDir() {
+		return nil, false, nil
+	}
+	sets, err := migrations.Status(path)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read migrations of %s: %w", sqlitePath, err)
+	}
+	return sets, true, nil
+}
+
+func rotateBeforeMigrating(ctx context.Context, sqlitePath string) (Info, bool, error) {
+	dir, err := Dir()
+	if err != nil {
+		return Info{}, false, err
+	}
+	info, err := rotate(ctx, sqlitePath, dir, true, PreMigrationKeep)
+	if err != nil {
+		return Info{}, false, fmt.Errorf("failed to back up the database before migrating: %w", err)
+	}
+	return info, true, nil
+}
+
+// RestoreResult describes a completed Restore.
+type RestoreResult struct {
+	// SafetyBackup is the rotating backup of the database as it was just
+	// before the restore, empty when there was no database yet.
+	SafetyBackup Info
+	// Migrated lists the migrations the restored copy was missing and that
+	// were applied after it was swapped in.
+	Migrated []string
+}
+
+// Restore replaces the database at sqlitePath with the backup at src. The
+// backup must pass an integrity check and must not carry migrations unknown
+// to this build (it was taken by a newer screpdb); older backups are migrated
+// forward after the swap. The current database is first saved as a rotating
+// backup in backupDir. The swap goes through SQLite's backup API, so it is
+// safe while other connections have the database open; they see the restored
+// data on their next query.
+func Restore(ctx context.Context, src, sqlitePath, backupDir string) (RestoreResult, error) {
+	var result RestoreResult
+	source, err := filepath.Abs(src)
+	if err != nil {
+		return result, fmt.Errorf("failed to resolve %s: %w", src, err)
+	}
+	target, err := filepath.Abs(sqlitePath)
+	if err != nil {
+		return result, fmt.Errorf("failed to resolve %s: %w", sqlitePath, err)
+	}
+	if source == target {
+		return result, errors.New("the backup is the database itself")
+	}
+	for _, dir := range []string{filepath.Dir(source), filepath.Dir(target)} {
+		if err := iofacade.AllowDir(dir); err != nil {
+			return result, fmt.Errorf("failed to register %s: %w", dir, err)
+		}
+	}
+	pending, err := Validate(ctx, source)
+	if err != nil {
+		return result, err
+	}
+
+	if _, err := iofacade.Stat(target); err == nil {
+		if result.SafetyBackup, err = Rotate(ctx, target, backupDir, 0); err != nil {
+			return result, fmt.Errorf("failed to save the current database before restoring: %w", err)
+		}
+	}
+	err = withDriverConn(ctx, target, func(c backuper) error {
+		b, err := c.NewRestore(source)
+		if err != nil {
+			return err
+		}
+		// One step: a restore copied piecemeal would let readers see a mix
+		// of both databases.
+		return runSteps(ctx, b, -1)
+	})
+	if err != nil {
+		return result, fmt.Errorf("failed to restore %s: %w", src, err)
+	}
+	if len(pending) > 0 {
+		if err := migrations.RunMigrations(target); err != nil {
+			return result, fmt.Errorf("restored, but failed to migrate the restored database: %w", err)
+		}
+		result.Migrated = pending
+	}
+	return result, nil
+}
+
+// Validate checks that the file at path is a screpdb database this build can
+// restore: readable, intact, and without migrations newer than this build's.
+// It returns the migrations the database is missing, as set/name.
+func Validate(ctx context.Context, path string) ([]string, error) {
+	info, err := iofacade.Stat(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("backup %s does not exist", path)
+		}
+		return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("backup %s is a directory", path)
+	}
+	if err := checkIntegrity(ctx, path); err != nil {
+		return nil, err
+	}
+	sets, err := migrations.Status(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations of %s: %w", path, err)
+	}
+	var pending []string
+	for _, m := range sets {
+		if len(m.Unknown) > 0 {
+			return nil, fmt.Errorf("backup %s has %s migrations unknown to this build (%v); upgrade screpdb first", path, m.Set, m.Unknown)
+		}
+		if m.Set == migrations.MigrationSetReplay && len(m.Applied) == 0 {
+			return nil, fmt.Errorf("%s is not a screpdb database", path)
+		}
+		for _, name := range m.Pending {
+			pending = append(pending, string(m.Set)+"/"+name)
+		}
+	}
+	return pending, nil
+}
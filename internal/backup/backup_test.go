@@ -0,0 +1,302 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/marianogappa/screpdb/internal/migrations"
+)
+
+// newDB returns a migrated database holding the given aliases.
+func newDB(t *testing.T, aliases ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "screp.db")
+	if err := migrations.RunMigrations(path); err != nil {
+		t.Fatalf("RunMigrations: %v", err)
+	}
+	for _, alias := range aliases {
+		exec(t, path, `INSERT INTO player_aliases (canonical_alias, battle_tag_normalized, battle_tag_raw, source) VALUES (?, ?, ?, 'manual')`, alias, alias, alias)
+	}
+	return path
+}
+
+func exec(t *testing.T, path, query string, args ...any) {
+	t.Helper()
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(query, args...); err != nil {
+		t.Fatalf("exec %q: %v", query, err)
+	}
+}
+
+func aliases(t *testing.T, path string) []string {
+	t.Helper()
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	defer db.Close()
+	rows, err := db.Query(`SELECT canonical_alias FROM player_aliases ORDER BY canonical_alias`)
+	if err != nil {
+		t.Fatalf("query aliases: %v", err)
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var alias string
+		if err := rows.Scan(&alias); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		out = append(out, alias)
+	}
+	return out
+}
+
+func TestBackupWhileOpen(t *testing.T) {
+	ctx := context.Background()
+	src := newDB(t, "flash", "jaedong")
+
+	// A second connection holding a read transaction must not block the copy.
+	db, err := sql.Open("sqlite", src)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	defer tx.Rollback()
+	var n int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM player_aliases`).Scan(&n); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+
+	dest := filepath.Join(t.TempDir(), "nested", "copy.db")
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	info, err := Backup(ctx, src, dest)
+	if err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	if info.Path != dest || info.SizeBytes == 0 {
+		t.Errorf("unexpected info: %+v", info)
+	}
+	if got := aliases(t, dest); strings.Join(got, ",") != "flash,jaedong" {
+		t.Errorf("backup aliases = %v", got)
+	}
+	if _, err := os.Stat(dest + ".partial"); !os.IsNotExist(err) {
+		t.Errorf("partial file left behind: %v", err)
+	}
+	if _, err := Backup(ctx, src, src); err == nil {
+		t.Error("Backup onto the database itself should fail")
+	}
+	if _, err := Backup(ctx, filepath.Join(t.TempDir(), "missing.db"), dest); err == nil {
+		t.Error("Backup of a missing database should fail")
+	}
+}
+
+func TestRotateKeepsNewest(t *testing.T) {
+	ctx := context.Background()
+	src := newDB(t)
+	dir := t.TempDir()
+	for _, name := range []string{"screp-20240101-000000.db", "screp-20240102-000000.db", "screp-20240103-000000.db"} {
+		if _, err := Backup(ctx, src, filepath.Join(dir, name)); err != nil {
+			t.Fatalf("Backup %s: %v", name, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("keep me"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	latest, err := Rotate(ctx, src, dir, 2)
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	backups, err := List(dir)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(backups) != 2 || backups[0].Name != latest.Name || backups[1].Name != "screp-20240103-000000.db" {
+		t.Fatalf("after rotation: %+v", backups)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.txt")); err != nil {
+		t.Errorf("rotation touched an unrelated file: %v", err)
+	}
+
+	if got, err := Lookup(dir, latest.Name); err != nil || got.Path != latest.Path {
+		t.Errorf("Lookup(%s) = %+v, %v", latest.Name, got, err)
+	}
+	for _, name := range []string{"notes.txt", "../screp-20240103-000000.db", ""} {
+		if _, err := Lookup(dir, name); err == nil {
+			t.Errorf("Lookup(%q) should fail", name)
+		}
+	}
+}
+
+func TestRestore(t *testing.T) {
+	ctx := context.Background()
+	live := newDB(t, "flash")
+	saved := filepath.Join(t.TempDir(), "saved.db")
+	if _, err := Backup(ctx, live, saved); err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	// Make the backup look like it was taken before the chat index existed.
+	exec(t, saved, `DROP TABLE chat_messages_fts`)
+	exec(t, saved, `DELETE FROM schema_migrations_replay WHERE name = '000006_chat_search.up.sql'`)
+	exec(t, live, `UPDATE player_aliases SET canonical_alias = 'changed'`)
+
+	backupDir := t.TempDir()
+	result, err := Restore(ctx, saved, live, backupDir)
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if got := aliases(t, live); strings.Join(got, ",") != "flash" {
+		t.Errorf("restored aliases = %v", got)
+	}
+	if got := aliases(t, result.SafetyBackup.Path); strings.Join(got, ",") != "changed" {
+		t.Errorf("safety backup aliases = %v", got)
+	}
+	if strings.Join(result.Migrated, ",") != "replay/000006_chat_search.up.sql" {
+		t.Errorf("Migrated = %v", result.Migrated)
+	}
+	if sets, err := migrations.Status(live); err != nil {
+		t.Fatalf("Status: %v", err)
+	} else {
+		for _, s := range sets {
+			if len(s.Pending) > 0 {
+				t.Errorf("%s migrations still pending after restore: %v", s.Set, s.Pending)
+			}
+		}
+	}
+}
+
+func TestRestoreRejects(t *testing.T) {
+	ctx := context.Background()
+	live := newDB(t, "flash")
+
+	newer := filepath.Join(t.TempDir(), "newer.db")
+	if _, err := Backup(ctx, live, newer); err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	exec(t, newer, `INSERT INTO schema_migrations_replay (name) VALUES ('999999_future.up.sql')`)
+
+	foreign := filepath.Join(t.TempDir(), "foreign.db")
+	exec(t, foreign, `CREATE TABLE notes (body TEXT)`)
+
+	garbage := filepath.Join(t.TempDir(), "garbage.db")
+	if err := os.WriteFile(garbage, []byte(strings.Repeat("not a database ", 512)), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	for name, src := range map[string]string{
+		"newer build":  newer,
+		"not screpdb":  foreign,
+		"not sqlite":   garbage,
+		"missing file": filepath.Join(t.TempDir(), "missing.db"),
+		"itself":       live,
+	} {
+		if _, err := Restore(ctx, src, live, t.TempDir()); err == nil {
+			t.Errorf("%s: Restore should fail", name)
+		}
+	}
+	if got := aliases(t, live); strings.Join(got, ",") != "flash" {
+		t.Errorf("a rejected restore changed the database: %v", got)
+	}
+}
+
+func TestBeforeMigrating(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("SCREPDB_APPDATA_DIR", t.TempDir())
+	live := newDB(t, "flash")
+	all := []migrations.MigrationSet{migrations.MigrationSetReplay, migrations.MigrationSetDashboard, migrations.MigrationSetSettings}
+
+	if _, ok, err := BeforePendingMigrations(ctx, live, all...); err != nil || ok {
+		t.Errorf("up-to-date database: BeforePendingMigrations = %v, %v; want no backup", ok, err)
+	}
+	missing := filepath.Join(t.TempDir(), "missing.db")
+	if _, ok, err := BeforeMigrating(ctx, missing); err != nil || ok {
+		t.Errorf("missing database: BeforeMigrating = %v, %v; want no backup", ok, err)
+	}
+	if _, err := os.Stat(missing); !os.IsNotExist(err) {
+		t.Errorf("BeforeMigrating created the missing database: %v", err)
+	}
+
+	if _, ok, err := BeforeMigrating(ctx, live); err != nil || !ok {
+		t.Errorf("BeforeMigrating = %v, %v; want a backup", ok, err)
+	}
+	// An ingest-only database never runs the settings set, so its pending
+	// settings migrations don't count for the sets ingest runs.
+	exec(t, live, `DELETE FROM schema_migrations_settings`)
+	if _, ok, err := BeforePendingMigrations(ctx, live, migrations.MigrationSetReplay, migrations.MigrationSetDashboard); err != nil || ok {
+		t.Errorf("pending settings only: BeforePendingMigrations = %v, %v; want no backup", ok, err)
+	}
+	exec(t, live, `DELETE FROM schema_migrations_replay WHERE name = '000008_player_aggregates.up.sql'`)
+	info, ok, err := BeforePendingMigrations(ctx, live, migrations.MigrationSetReplay, migrations.MigrationSetDashboard)
+	if err != nil || !ok {
+		t.Fatalf("pending migration: BeforePendingMigrations = %v, %v; want a backup", ok, err)
+	}
+	if got := aliases(t, info.Path); strings.Join(got, ",") != "flash" {
+		t.Errorf("backup aliases = %v", got)
+	}
+	dir, err := Dir()
+	if err != nil {
+		t.Fatalf("Dir: %v", err)
+	}
+	if filepath.Dir(info.Path) != dir || !info.PreMigration {
+		t.Errorf("backup taken into %s (%+v), want a pre-migration backup in %s", info.Path, info, dir)
+	}
+}
+
+func TestPreMigrationBackupsRotateApart(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("SCREPDB_APPDATA_DIR", t.TempDir())
+	live := newDB(t, "flash")
+	dir, err := Dir()
+	if err != nil {
+		t.Fatalf("Dir: %v", err)
+	}
+
+	// Taken back to back, well within a second: every name must be new.
+	for range PreMigrationKeep + 2 {
+		if _, ok, err := BeforeMigrating(ctx, live); err != nil || !ok {
+			t.Fatalf("BeforeMigrating = %v, %v", ok, err)
+		}
+	}
+	scheduled, err := Rotate(ctx, live, dir, 1)
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if _, err := Rotate(ctx, live, dir, 1); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	backups, err := List(dir)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	var preMigration, rotating int
+	for _, b := range backups {
+		if b.PreMigration {
+			preMigration++
+		} else {
+			rotating++
+			if b.Name == scheduled.Name {
+				t.Errorf("second Rotate in the same second kept %s instead of replacing the rotation", b.Name)
+			}
+		}
+	}
+	if preMigration != PreMigrationKeep || rotating != 1 {
+		t.Errorf("kept %d pre-migration and %d rotating backups; want %d and 1: %+v", preMigration, rotating, PreMigrationKeep, backups)
+	}
+	if got, err := Lookup(dir, backups[len(backups)-1].Name); err != nil || got.Path == "" {
+		t.Errorf("Lookup of a pre-migration backup = %+v, %v", got, err)
+	}
+}
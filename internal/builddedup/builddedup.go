@@ -38,6 +38,18 @@ var productionBuildings = map[string]bool{
 	"Command Center": true, "Barracks": true, "Factory": true, "Starport": true,
 }
 
+// tierABuildings are the buildings Tier A applies to: the early-game ones it
+// was validated on. The canonical build-time table covers the whole game, but
+// extending the rule to later Zerg tech dropped real buildings in the golden
+// corpus, so those are left alone.
+var tierABuildings = map[string]bool{
+	"Hatchery": true, "Spawning Pool": true, "Extractor": true, "Evolution Chamber": true,
+	"Creep Colony": true, "Sunken Colony": true, "Spire": true,
+	"Command Center": true, "Supply Depot": true, "Barracks": true, "Refinery": true,
+	"Engineering Bay": true, "Factory": true, "Starport": true, "Machine Shop": true,
+	"Academy": true, "Bunker": true, "Missile Turret": true,
+}
+
 // startInstances are buildings every player starts with one of (melee); the
 // starting one produces but has no Build command, so it is seeded.
 var startInstances = map[string]int{"Nexus": 1, "Command Center": 1}
@@ -114,7 +126,7 @@ func (pl *Plan) tierAWorkerOneAtATime(pid byte, pe *unittags.PlayerEvidence, rac
 		sort.Slice(list, func(i, j int) bool { return list[i].Sec < list[j].Sec })
 		for i := 0; i+1 < len(list); i++ {
 			bt, ok := models.BuildTimeOf(list[i].Building)
-			if !ok {
+			if !ok || !tierABuildings[list[i].Building] {
 				continue
 			}
 			// Only a redirect to a DIFFERENT tile proves the earlier build was
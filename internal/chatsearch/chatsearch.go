@@ -0,0 +1,302 @@
+// Package chatsearch finds in-game chat messages by their words.
+//
+// On SQLite the chat_messages_fts FTS5 index (replay migration 000006) holds
+// every commands row with action_type 'Chat'; triggers on commands keep it in
+// step with ingest, merge and deletes. On PostgreSQL the same search runs on
+// a GIN index over to_tsvector('simple', chat_message).
+//
+// The words of a search all have to appear in a message, in any order; a
+// word ending in * matches as a prefix. Punctuation is ignored, so "gg!" and
+// "gg" search for the same thing.
+package chatsearch
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Dialects Search can generate SQL for.
+const (
+	DialectSQLite   = "sqlite"
+	DialectPostgres = "postgres"
+)
+
+// Defaults and bounds for Options.Limit and Options.Context.
+const (
+	DefaultLimit   = 50
+	MaxLimit       = 200
+	DefaultContext = 2
+	MaxContext     = 10
+)
+
+// ErrNoWords is returned for a search text without a single word in it.
+var ErrNoWords = errors.New("search text has no words")
+
+// Options describes one search. Zero values mean no filter.
+type Options struct {
+	Text     string
+	Players  []string // speaker names, matched case-insensitively
+	Matchups []string // replays.matchup values like "PvZ", case-insensitive
+	From     string   // YYYY-MM-DD, inclusive
+	To       string   // YYYY-MM-DD, inclusive
+	Limit    int      // messages to return; 0 means DefaultLimit
+	Context  int      // messages to include before and after each hit; 0 means DefaultContext, <0 none
+}
+
+// Message is one chat line.
+type Message struct {
+	Player string `json:"player"`
+	Second int64  `json:"second"`
+	Text   string `json:"message"`
+}
+
+// Hit is a matching message with the replay it was said in and the chat
+// around it.
+type Hit struct {
+	Message
+	ReplayID   int64     `json:"replay_id"`
+	ReplayDate string    `json:"replay_date"`
+	MapName    string    `json:"map_name"`
+	Matchup    string    `json:"matchup"`
+	Race       string    `json:"race"`
+	Before     []Message `json:"before"`
+	After      []Message `json:"after"`
+}
+
+// Querier runs a query; *sql.DB, *sql.Conn and *sql.Tx satisfy it.
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// term is one search word.
+type term struct {
+	word   string
+	prefix bool
+}
+
+// parseTerms splits text into words: runs of letters and digits, the way
+// both FTS5's unicode61 tokenizer and PostgreSQL's simple parser see them. A
+// * right after a word makes it a prefix.
+func parseTerms(text string) []term {
+	var terms []term
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		if !isWordRune(runes[i]) {
+			i++
+			continue
+		}
+		start := i
+		for i < len(runes) && isWordRune(runes[i]) {
+			i++
+		}
+		t := term{word: strings.ToLower(string(runes[start:i]))}
+		if i < len(runes) && runes[i] == '*' {
+			t.prefix = true
+		}
+		terms = append(terms, t)
+	}
+	return terms
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// ftsQuery renders terms as an FTS5 MATCH expression: quoted strings, so no
+// word is read as an operator, implicitly ANDed.
+func ftsQuery(terms []term) string {
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		parts[i] = `"` + t.word + `"`
+		if t.prefix {
+			parts[i] += "*"
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// tsQuery renders terms as a PostgreSQL tsquery.
+func tsQuery(terms []term) string {
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		parts[i] = t.word
+		if t.prefix {
+			parts[i] += ":*"
+		}
+	}
+	return strings.Join(parts, " & ")
+}
+
+// buildQuery returns the SQL and arguments listing the hits of opts, without
+// their context. A message recorded by several POVs of one game is listed
+// once, from the game's canonical replay when that replay has it.
+func buildQuery(dialect string, opts Options, terms []term, limit int) (string, []any, error) {
+	var match, date string
+	var args []any
+	switch dialect {
+	case DialectSQLite:
+		match = "c.id IN (SELECT rowid FROM chat_messages_fts WHERE chat_messages_fts MATCH ?)"
+		args = append(args, ftsQuery(terms))
+		date = "substr(r.replay_date, 1, 10)"
+	case DialectPostgres:
+		match = "to_tsvector('simple', c.chat_message) @@ to_tsquery('simple', ?)"
+		args = append(args, tsQuery(terms))
+		date = "to_char(r.replay_date, 'YYYY-MM-DD')"
+	default:
+		return "", nil, fmt.Errorf("unsupported dialect %q", dialect)
+	}
+
+	clauses := []string{match, "c.action_type = 'Chat'", "c.chat_message IS NOT NULL"}
+	if len(opts.Players) > 0 {
+		clauses = append(clauses, "lower(trim(p.name)) IN ("+placeholders(len(opts.Players))+")")
+		for _, name := range opts.Players {
+			args = append(args, strings.ToLower(strings.TrimSpace(name)))
+		}
+	}
+	if len(opts.Matchups) > 0 {
+		clauses = append(clauses, "lower(r.matchup) IN ("+placeholders(len(opts.Matchups))+")")
+		for _, m := range opts.Matchups {
+			args = append(args, strings.ToLower(strings.TrimSpace(m)))
+		}
+	}
+	if opts.From != "" {
+		clauses = append(clauses, date+" >= ?")
+		args = append(args, opts.From)
+	}
+	if opts.To != "" {
+		clauses = append(clauses, date+" <= ?")
+		args = append(args, opts.To)
+	}
+	args = append(args, limit)
+
+	query := `
+		WITH hits AS (
+			SELECT c.id, c.replay_id, r.replay_date, r.map_name, r.matchup,
+				p.name, p.race, c.seconds_from_game_start, c.chat_message,
+				ROW_NUMBER() OVER (
+					PARTITION BY COALESCE(r.game_group_id, r.id), lower(trim(p.name)), c.seconds_from_game_start, c.chat_message
+					ORDER BY CASE WHEN r.id = COALESCE(r.game_group_id, r.id) THEN 0 ELSE 1 END, r.id
+				) AS pov_rank
+			FROM commands c
+			JOIN replays r ON r.id = c.replay_id
+			JOIN players p ON p.id = c.player_id
+			WHERE ` + strings.Join(clauses, "\n\t\t\t\tAND ") + `
+		)
+		SELECT id, replay_id, replay_date, map_name, matchup, name, race, seconds_from_game_start, chat_message
+		FROM hits
+		WHERE pov_rank = 1
+		ORDER BY replay_date DESC, replay_id DESC, id
+		LIMIT ?`
+	return query, args, nil
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// Search returns the chat messages matching opts, newest game first and in
+// game order within a game.
+func Search(ctx context.Context, db Querier, dialect string, opts Options) ([]Hit, error) {
+	terms := parseTerms(opts.Text)
+	if len(terms) == 0 {
+		return nil, ErrNoWords
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	limit = min(limit, MaxLimit)
+	around := opts.Context
+	if around == 0 {
+		around = DefaultContext
+	}
+	around = max(0, min(around, MaxContext))
+
+	query, args, err := buildQuery(dialect, opts, terms, limit)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search chat: %w", err)
+	}
+	defer rows.Close()
+
+	hits := []Hit{}
+	var commandIDs []int64
+	for rows.Next() {
+		var h Hit
+		var commandID int64
+		if err := rows.Scan(&commandID, &h.ReplayID, &h.ReplayDate, &h.MapName, &h.Matchup,
+			&h.Player, &h.Race, &h.Second, &h.Text); err != nil {
+			return nil, fmt.Errorf("failed to scan chat hit: %w", err)
+		}
+		hits = append(hits, h)
+		commandIDs = append(commandIDs, commandID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search chat: %w", err)
+	}
+	if around == 0 || len(hits) == 0 {
+		return hits, nil
+	}
+	if err := addContext(ctx, db, hits, commandIDs, around); err != nil {
+		return nil, err
+	}
+	return hits, nil
+}
+
+// addContext fills the Before and After messages of hits, whose command IDs
+// are commandIDs, with up to around chat lines each.
+func addContext(ctx context.Context, db Querier, hits []Hit, commandIDs []int64, around int) error {
+	replayIDs := []any{}
+	seen := map[int64]bool{}
+	for _, h := range hits {
+		if !seen[h.ReplayID] {
+			seen[h.ReplayID] = true
+			replayIDs = append(replayIDs, h.ReplayID)
+		}
+	}
+	rows, err := db.QueryContext(ctx, `
+		SELECT c.id, c.replay_id, p.name, c.seconds_from_game_start, c.chat_message
+		FROM commands c
+		JOIN players p ON p.id = c.player_id
+		WHERE c.replay_id IN (`+placeholders(len(replayIDs))+`)
+			AND c.action_type = 'Chat'
+			AND c.chat_message IS NOT NULL
+		ORDER BY c.replay_id, c.seconds_from_game_start, c.id`, replayIDs...)
+	if err != nil {
+		return fmt.Errorf("failed to load chat context: %w", err)
+	}
+	defer rows.Close()
+
+	chat := map[int64][]Message{}
+	position := map[int64]int{} // command ID -> index in its replay's chat
+	for rows.Next() {
+		var id, replayID int64
+		var m Message
+		if err := rows.Scan(&id, &replayID, &m.Player, &m.Second, &m.Text); err != nil {
+			return fmt.Errorf("failed to scan chat context: %w", err)
+		}
+		position[id] = len(chat[replayID])
+		chat[replayID] = append(chat[replayID], m)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to load chat context: %w", err)
+	}
+
+	for i := range hits {
+		lines := chat[hits[i].ReplayID]
+		at, ok := position[commandIDs[i]]
+		if !ok {
+			continue
+		}
+		hits[i].Before = append([]Message{}, lines[max(0, at-around):at]...)
+		hits[i].After = append([]Message{}, lines[at+1:min(len(lines), at+1+around)]...)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package chatsearch
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseTerms(t *testing.T) {
+	terms := parseTerms(`GG! "attack" 6, alli* -- 하네요`)
+	if got := ftsQuery(terms); got != `"gg" "attack" "6" "alli"* "하네요"` {
+		t.Errorf("ftsQuery = %s", got)
+	}
+	if got := tsQuery(terms); got != `gg & attack & 6 & alli:* & 하네요` {
+		t.Errorf("tsQuery = %s", got)
+	}
+	if terms := parseTerms(" ^^ ... * "); len(terms) != 0 {
+		t.Errorf("parseTerms of punctuation = %v, want none", terms)
+	}
+}
+
+func TestBuildQuery(t *testing.T) {
+	opts := Options{Players: []string{"A", "b"}, Matchups: []string{"PvZ"}, From: "2024-01-01", To: "2024-12-31"}
+	terms := parseTerms("gg")
+	for _, tc := range []struct {
+		dialect, match, date string
+	}{
+		{DialectSQLite, "chat_messages_fts MATCH ?", "substr(r.replay_date, 1, 10) >= ?"},
+		{DialectPostgres, "@@ to_tsquery('simple', ?)", "to_char(r.replay_date, 'YYYY-MM-DD') <= ?"},
+	} {
+		query, args, err := buildQuery(tc.dialect, opts, terms, 10)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.dialect, err)
+		}
+		if !strings.Contains(query, tc.match) || !strings.Contains(query, tc.date) {
+			t.Errorf("%s query lacks %q or %q:\n%s", tc.dialect, tc.match, tc.date, query)
+		}
+		if n := strings.Count(query, "?"); n != len(args) {
+			t.Errorf("%s query has %d placeholders for %d args", tc.dialect, n, len(args))
+		}
+		if args[1] != "a" || args[3] != "pvz" || args[len(args)-1] != 10 {
+			t.Errorf("%s args = %v", tc.dialect, args)
+		}
+	}
+	if _, _, err := buildQuery("mysql", opts, terms, 10); err == nil {
+		t.Error("buildQuery should reject an unknown dialect")
+	}
+}
+
+func TestSearchRejectsTextWithoutWords(t *testing.T) {
+	if _, err := Search(context.Background(), nil, DialectSQLite, Options{Text: "?!"}); !errors.Is(err, ErrNoWords) {
+		t.Errorf("Search = %v, want ErrNoWords", err)
+	}
+}
@@ -0,0 +1,437 @@
+// Package cmdblob encodes a replay's command rows as one compressed,
+// columnar blob: each column is stored contiguously, strings (the enum
+// columns above all) are interned into a per-blob table, players into a
+// per-blob list, and frames, seconds, coordinates and row ordinals are delta
+// encoded before the whole thing is gzipped. A replay's Right Click and
+// Hotkey rows shrink to a few bytes each this way.
+//
+// Rows keep the commands_low_value column shapes (database player IDs,
+// normalized enum names, JSON player-ID arrays), so a decoded blob reads like
+// that table. The command_blob_rows virtual table (see Module) decodes blobs
+// inside SQL queries.
+package cmdblob
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// FormatVersion is stored next to every encoded blob. Bump it when a change
+// to the encoding can't be read by the previous Decode.
+const FormatVersion = 1
+
+// ErrUnsupportedFormat is returned by Decode for blobs of another
+// FormatVersion.
+var ErrUnsupportedFormat = errors.New("unsupported command blob format")
+
+// Row is one command, shaped like a commands_low_value row without its id and
+// replay_id.
+type Row struct {
+	// Ordinal is the row's position in the replay's commands_low_value
+	// stream, which interleaves rows kept in the table with rows kept in the
+	// blob. Ordinals must increase from row to row.
+	Ordinal           int64
+	PlayerID          int64
+	Frame             int64
+	Second            int64
+	ActionType        string
+	X                 *int64
+	Y                 *int64
+	IsQueued          *bool
+	OrderName         *string
+	UnitType          *string
+	UnitTypes         *string
+	TechName          *string
+	UpgradeName       *string
+	HotkeyType        *string
+	HotkeyGroup       *int64
+	GameSpeed         *string
+	VisionPlayerIDs   *string
+	AlliancePlayerIDs *string
+	IsAlliedVictory   *bool
+	GeneralData       *string
+	ChatMessage       *string
+	LeaveReason       *string
+}
+
+// stringColumns returns pointers to r's nullable string columns, in encoding
+// order.
+func (r *Row) stringColumns() []**string {
+	return []**string{
+		&r.OrderName, &r.UnitType, &r.UnitTypes, &r.TechName, &r.UpgradeName,
+		&r.HotkeyType, &r.GameSpeed, &r.VisionPlayerIDs, &r.AlliancePlayerIDs,
+		&r.GeneralData, &r.ChatMessage, &r.LeaveReason,
+	}
+}
+
+// stringColumnCount is len(Row.stringColumns()).
+const stringColumnCount = 12
+
+// Encode returns rows as a FormatVersion blob.
+func Encode(rows []Row) ([]byte, error) {
+	e := encoder{strings: map[string]uint64{}, players: map[int64]uint64{}}
+	prevOrdinal := int64(-1)
+	for i := range rows {
+		if rows[i].Ordinal <= prevOrdinal {
+			return nil, fmt.Errorf("row %d has ordinal %d, not after %d", i, rows[i].Ordinal, prevOrdinal)
+		}
+		prevOrdinal = rows[i].Ordinal
+		e.intern(rows[i].ActionType)
+		for _, col := range rows[i].stringColumns() {
+			if *col != nil {
+				e.intern(**col)
+			}
+		}
+		e.player(rows[i].PlayerID)
+	}
+
+	var body bytes.Buffer
+	e.buf = &body
+	e.uvarint(uint64(len(rows)))
+	e.uvarint(uint64(len(e.stringList)))
+	for _, s := range e.stringList {
+		e.uvarint(uint64(len(s)))
+		body.WriteString(s)
+	}
+	e.uvarint(uint64(len(e.playerList)))
+	var prev int64
+	for _, id := range e.playerList {
+		e.varint(id - prev)
+		prev = id
+	}
+
+	prev = -1
+	for i := range rows {
+		e.uvarint(uint64(rows[i].Ordinal - prev - 1))
+		prev = rows[i].Ordinal
+	}
+	for i := range rows {
+		e.uvarint(e.players[rows[i].PlayerID])
+	}
+	e.deltas(rows, func(r *Row) int64 { return r.Frame })
+	e.deltas(rows, func(r *Row) int64 { return r.Second })
+	for i := range rows {
+		e.uvarint(e.strings[rows[i].ActionType])
+	}
+	e.nullableDeltas(rows, func(r *Row) *int64 { return r.X })
+	e.nullableDeltas(rows, func(r *Row) *int64 { return r.Y })
+	for i := range rows {
+		e.nullableInt(rows[i].HotkeyGroup)
+	}
+	for i := range rows {
+		e.nullableBool(rows[i].IsQueued)
+	}
+	for i := range rows {
+		e.nullableBool(rows[i].IsAlliedVictory)
+	}
+	for c := 0; c < stringColumnCount; c++ {
+		for i := range rows {
+			e.nullableString(*rows[i].stringColumns()[c])
+		}
+	}
+
+	var out bytes.Buffer
+	zw := gzip.NewWriter(&out)
+	if _, err := zw.Write(body.Bytes()); err != nil {
+		return nil, fmt.Errorf("failed to compress command blob: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress command blob: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// Decode reverses Encode for a blob stored with formatVersion.
+func Decode(formatVersion int, blob []byte) ([]Row, error) {
+	if formatVersion != FormatVersion {
+		return nil, fmt.Errorf("%w: version %d", ErrUnsupportedFormat, formatVersion)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(blob))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress command blob: %w", err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress command blob: %w", err)
+	}
+	d := decoder{r: bufio.NewReader(bytes.NewReader(body)), size: uint64(len(body))}
+
+	// Every row takes at least one byte per column, which bounds n before
+	// anything is allocated from it.
+	n := d.count()
+	strs := make([]string, d.count())
+	for i := range strs {
+		b := make([]byte, d.count())
+		d.read(b)
+		strs[i] = string(b)
+	}
+	players := make([]int64, d.count())
+	var prev int64
+	for i := range players {
+		prev += d.varint()
+		players[i] = prev
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	rows := make([]Row, n)
+	prev = -1
+	for i := range rows {
+		prev += int64(d.uvarint()) + 1
+		rows[i].Ordinal = prev
+	}
+	for i := range rows {
+		rows[i].PlayerID = players[d.index(len(players))]
+	}
+	d.deltas(rows, func(r *Row) *int64 { return &r.Frame })
+	d.deltas(rows, func(r *Row) *int64 { return &r.Second })
+	for i := range rows {
+		rows[i].ActionType = strs[d.index(len(strs))]
+	}
+	d.nullableDeltas(rows, func(r *Row) **int64 { return &r.X })
+	d.nullableDeltas(rows, func(r *Row) **int64 { return &r.Y })
+	for i := range rows {
+		rows[i].HotkeyGroup = d.nullableInt()
+	}
+	for i := range rows {
+		rows[i].IsQueued = d.nullableBool()
+	}
+	for i := range rows {
+		rows[i].IsAlliedVictory = d.nullableBool()
+	}
+	for c := 0; c < stringColumnCount; c++ {
+		for i := range rows {
+			*rows[i].stringColumns()[c] = d.nullableString(strs)
+		}
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	if _, err := d.r.ReadByte(); err != io.EOF {
+		return nil, errors.New("corrupt command blob: trailing data")
+	}
+	return rows, nil
+}
+
+type encoder struct {
+	buf        *bytes.Buffer
+	scratch    [binary.MaxVarintLen64]byte
+	strings    map[string]uint64
+	stringList []string
+	players    map[int64]uint64
+	playerList []int64
+}
+
+func (e *encoder) intern(s string) {
+	if _, ok := e.strings[s]; !ok {
+		e.strings[s] = uint64(len(e.stringList))
+		e.stringList = append(e.stringList, s)
+	}
+}
+
+func (e *encoder) player(id int64) {
+	if _, ok := e.players[id]; !ok {
+		e.players[id] = uint64(len(e.playerList))
+		e.playerList = append(e.playerList, id)
+	}
+}
+
+func (e *encoder) uvarint(v uint64) {
+	e.buf.Write(e.scratch[:binary.PutUvarint(e.scratch[:], v)])
+}
+
+func (e *encoder) varint(v int64) {
+	e.buf.Write(e.scratch[:binary.PutVarint(e.scratch[:], v)])
+}
+
+func (e *encoder) deltas(rows []Row, get func(*Row) int64) {
+	var prev int64
+	for i := range rows {
+		v := get(&rows[i])
+		e.varint(v - prev)
+		prev = v
+	}
+}
+
+// nullableDeltas writes 0 for NULL, otherwise the zigzag delta from the
+// previous non-NULL value plus one.
+func (e *encoder) nullableDeltas(rows []Row, get func(*Row) *int64) {
+	var prev int64
+	for i := range rows {
+		v := get(&rows[i])
+		if v == nil {
+			e.uvarint(0)
+			continue
+		}
+		e.uvarint(zigzag(*v-prev) + 1)
+		prev = *v
+	}
+}
+
+func (e *encoder) nullableInt(v *int64) {
+	if v == nil {
+		e.uvarint(0)
+		return
+	}
+	e.uvarint(zigzag(*v) + 1)
+}
+
+func (e *encoder) nullableBool(v *bool) {
+	switch {
+	case v == nil:
+		e.buf.WriteByte(0)
+	case *v:
+		e.buf.WriteByte(2)
+	default:
+		e.buf.WriteByte(1)
+	}
+}
+
+func (e *encoder) nullableString(s *string) {
+	if s == nil {
+		e.uvarint(0)
+		return
+	}
+	e.uvarint(e.strings[*s] + 1)
+}
+
+// decoder reads an Encode body, remembering the first error; reads after it
+// return zero values.
+type decoder struct {
+	r    *bufio.Reader
+	size uint64
+	err  error
+}
+
+func (d *decoder) fail(err error) {
+	if d.err == nil {
+		d.err = fmt.Errorf("corrupt command blob: %w", err)
+	}
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		d.fail(err)
+	}
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadVarint(d.r)
+	if err != nil {
+		d.fail(err)
+	}
+	return v
+}
+
+// count reads a length, which can't exceed the body size.
+func (d *decoder) count() int {
+	n := d.uvarint()
+	if n > d.size {
+		d.fail(fmt.Errorf("length %d exceeds blob size", n))
+		return 0
+	}
+	return int(n)
+}
+
+func (d *decoder) index(n int) int {
+	i := d.uvarint()
+	if i >= uint64(n) {
+		d.fail(fmt.Errorf("index %d out of range [0, %d)", i, n))
+		return 0
+	}
+	return int(i)
+}
+
+func (d *decoder) read(b []byte) {
+	if d.err != nil {
+		return
+	}
+	if _, err := io.ReadFull(d.r, b); err != nil {
+		d.fail(err)
+	}
+}
+
+func (d *decoder) deltas(rows []Row, field func(*Row) *int64) {
+	var prev int64
+	for i := range rows {
+		prev += d.varint()
+		*field(&rows[i]) = prev
+	}
+}
+
+func (d *decoder) nullableDeltas(rows []Row, field func(*Row) **int64) {
+	var prev int64
+	for i := range rows {
+		v := d.uvarint()
+		if v == 0 {
+			continue
+		}
+		prev += unzigzag(v - 1)
+		value := prev
+		*field(&rows[i]) = &value
+	}
+}
+
+func (d *decoder) nullableInt() *int64 {
+	v := d.uvarint()
+	if v == 0 {
+		return nil
+	}
+	value := unzigzag(v - 1)
+	return &value
+}
+
+func (d *decoder) nullableBool() *bool {
+	if d.err != nil {
+		return nil
+	}
+	b, err := d.r.ReadByte()
+	if err != nil {
+		d.fail(err)
+		return nil
+	}
+	switch b {
+	case 0:
+		return nil
+	case 1, 2:
+		value := b == 2
+		return &value
+	default:
+		d.fail(fmt.Errorf("invalid boolean %d", b))
+		return nil
+	}
+}
+
+func (d *decoder) nullableString(strs []string) *string {
+	v := d.uvarint()
+	if v == 0 {
+		return nil
+	}
+	if v > uint64(len(strs)) {
+		d.fail(fmt.Errorf("string index %d out of range", v-1))
+		return nil
+	}
+	return &strs[v-1]
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+func unzigzag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
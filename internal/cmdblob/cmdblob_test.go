@@ -0,0 +1,115 @@
+package cmdblob
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func testRows() []Row {
+	return []Row{
+		{Ordinal: 0, PlayerID: 11, Frame: 10, Second: 0, ActionType: "Hotkey", HotkeyType: ptr("Assign"), HotkeyGroup: ptr(int64(1))},
+		{Ordinal: 2, PlayerID: 12, Frame: 12, Second: 0, ActionType: "Right Click", X: ptr(int64(2048)), Y: ptr(int64(1900)), IsQueued: ptr(false), OrderName: ptr("Move")},
+		{Ordinal: 3, PlayerID: 11, Frame: 40, Second: 1, ActionType: "Right Click", X: ptr(int64(1000)), Y: ptr(int64(3000)), IsQueued: ptr(true), UnitType: ptr("Probe")},
+		{Ordinal: 9, PlayerID: 11, Frame: 39, Second: 1, ActionType: "Minimap Ping", X: ptr(int64(0)), Y: ptr(int64(-5))},
+		{Ordinal: 10, PlayerID: 12, Frame: 5000, Second: 209, ActionType: "Vision", VisionPlayerIDs: ptr("[11,12]"), IsAlliedVictory: ptr(true), ChatMessage: ptr("")},
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, rows := range [][]Row{testRows(), {}} {
+		blob, err := Encode(rows)
+		if err != nil {
+			t.Fatalf("Encode: %v", err)
+		}
+		got, err := Decode(FormatVersion, blob)
+		if err != nil {
+			t.Fatalf("Decode: %v", err)
+		}
+		if len(rows) == 0 && len(got) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, rows) {
+			t.Errorf("round trip:\n got %+v\nwant %+v", got, rows)
+		}
+	}
+}
+
+func TestEncodeRejectsUnorderedOrdinals(t *testing.T) {
+	rows := testRows()
+	rows[1].Ordinal = 0
+	if _, err := Encode(rows); err == nil {
+		t.Fatal("expected an error for a repeated ordinal")
+	}
+}
+
+func TestDecodeRejectsBadInput(t *testing.T) {
+	blob, err := Encode(testRows())
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if _, err := Decode(FormatVersion+1, blob); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("Decode of another version = %v, want ErrUnsupportedFormat", err)
+	}
+	if _, err := Decode(FormatVersion, blob[:len(blob)/2]); err == nil {
+		t.Error("expected an error for a truncated blob")
+	}
+	if _, err := Decode(FormatVersion, []byte{0x1f, 0x8b, 0, 1, 2}); err == nil {
+		t.Error("expected an error for a corrupt gzip stream")
+	}
+}
+
+func TestModule(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	blob, err := Encode(testRows())
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	for _, stmt := range []string{
+		"CREATE VIRTUAL TABLE command_blob_rows USING " + ModuleName,
+		"CREATE TABLE blobs (replay_id INTEGER, format_version INTEGER, payload BLOB)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	if _, err := db.Exec("INSERT INTO blobs VALUES (1, ?, ?), (2, ?, NULL)", FormatVersion, blob, FormatVersion); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	var count, rightClicks, queued int
+	var maxX int64
+	err = db.QueryRow(`
+		SELECT COUNT(*), SUM(r.action_type = 'Right Click'), SUM(r.is_queued), MAX(r.x)
+		FROM blobs b, command_blob_rows(b.payload, b.format_version) r`).Scan(&count, &rightClicks, &queued, &maxX)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if count != 5 || rightClicks != 2 || queued != 1 || maxX != 2048 {
+		t.Errorf("got count=%d rightClicks=%d queued=%d maxX=%d", count, rightClicks, queued, maxX)
+	}
+
+	var ordinal, hotkeyGroup int64
+	var hotkeyType string
+	var x sql.NullInt64
+	err = db.QueryRow("SELECT ordinal, hotkey_type, hotkey_group, x FROM command_blob_rows(?) WHERE action_type = 'Hotkey'", blob).
+		Scan(&ordinal, &hotkeyType, &hotkeyGroup, &x)
+	if err != nil {
+		t.Fatalf("query without format_version: %v", err)
+	}
+	if ordinal != 0 || hotkeyType != "Assign" || hotkeyGroup != 1 || x.Valid {
+		t.Errorf("hotkey row = %d %q %d %v", ordinal, hotkeyType, hotkeyGroup, x)
+	}
+
+	if _, err := db.Exec("SELECT * FROM command_blob_rows(?, 99)", blob); err == nil {
+		t.Error("expected an error for an unsupported format_version")
+	}
+}
@@ -0,0 +1,239 @@
+package cmdblob
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	// The driver wires up vtab.RegisterModule in its init, which must run
+	// before ours.
+	_ "modernc.org/sqlite"
+	"modernc.org/sqlite/vtab"
+)
+
+// ModuleName is the SQLite virtual table module that decodes blobs. The
+// replay schema creates one table with it, command_blob_rows, and uses it as a
+// table-valued function:
+//
+//	SELECT r.* FROM command_blobs b, command_blob_rows(b.payload, b.format_version) r
+//
+// The module is registered for every connection this process opens once the
+// package is imported.
+const ModuleName = "screp_command_blob"
+
+// columns lists the virtual table's visible columns, in Row field order.
+var columns = []string{
+	"ordinal", "player_id", "frame", "seconds_from_game_start", "action_type",
+	"x", "y", "is_queued", "order_name", "unit_type", "unit_types",
+	"tech_name", "upgrade_name", "hotkey_type", "hotkey_group", "game_speed",
+	"vision_player_ids", "alliance_player_ids", "is_allied_victory",
+	"general_data", "chat_message", "leave_reason",
+}
+
+// Hidden columns, bound by the table-valued function's arguments.
+var (
+	payloadColumn       = len(columns)
+	formatVersionColumn = len(columns) + 1
+)
+
+const (
+	planPayload       = 1 << iota // payload is bound
+	planFormatVersion             // format_version is bound
+)
+
+func init() {
+	if err := vtab.RegisterModule(nil, ModuleName, module{}); err != nil {
+		panic(fmt.Sprintf("register %s module: %v", ModuleName, err))
+	}
+}
+
+type module struct{}
+
+func (module) Create(ctx vtab.Context, _ []string) (vtab.Table, error) { return declare(ctx) }
+
+func (module) Connect(ctx vtab.Context, _ []string) (vtab.Table, error) { return declare(ctx) }
+
+func declare(ctx vtab.Context) (vtab.Table, error) {
+	schema := "CREATE TABLE x(" + strings.Join(columns, ", ") + ", payload HIDDEN, format_version HIDDEN)"
+	if err := ctx.Declare(schema); err != nil {
+		return nil, err
+	}
+	return table{}, nil
+}
+
+type table struct{}
+
+// BestIndex only offers plans that bind payload: without a blob there are no
+// rows to produce, so any other plan gets a prohibitive cost.
+func (table) BestIndex(info *vtab.IndexInfo) error {
+	var plan int64
+	argc := 0
+	for i, c := range info.Constraints {
+		if !c.Usable || c.Op != vtab.OpEQ {
+			continue
+		}
+		var bit int64
+		switch c.Column {
+		case payloadColumn:
+			bit = planPayload
+		case formatVersionColumn:
+			bit = planFormatVersion
+		default:
+			continue
+		}
+		if plan&bit != 0 {
+			continue
+		}
+		plan |= bit
+		info.Constraints[i].ArgIndex = argc
+		info.Constraints[i].Omit = true
+		argc++
+	}
+	info.IdxNum = plan
+	if plan&planPayload == 0 {
+		info.EstimatedCost = math.MaxFloat64 / 4
+		info.EstimatedRows = math.MaxInt32
+		return nil
+	}
+	info.EstimatedCost = 1000
+	info.EstimatedRows = 1000
+	return nil
+}
+
+func (table) Open() (vtab.Cursor, error) { return &cursor{}, nil }
+
+func (table) Disconnect() error { return nil }
+
+func (table) Destroy() error { return nil }
+
+type cursor struct {
+	rows []Row
+	pos  int
+}
+
+// Filter decodes the bound blob. Arguments arrive in the order BestIndex
+// assigned them, which follows the constraints it accepted.
+func (c *cursor) Filter(idxNum int, _ string, vals []vtab.Value) error {
+	c.rows, c.pos = nil, 0
+	if idxNum&planPayload == 0 {
+		return errors.New("command_blob_rows requires a payload argument")
+	}
+	var payload []byte
+	formatVersion := FormatVersion
+	for i := 0; i < len(vals); i++ {
+		switch v := vals[i].(type) {
+		case nil:
+			// A NULL payload or version decodes to no rows.
+			return nil
+		case []byte:
+			payload = v
+		case int64:
+			formatVersion = int(v)
+		default:
+			return fmt.Errorf("command_blob_rows: unexpected %T argument", v)
+		}
+	}
+	if payload == nil {
+		return errors.New("command_blob_rows: payload must be a blob")
+	}
+	rows, err := Decode(formatVersion, payload)
+	if err != nil {
+		return err
+	}
+	c.rows = rows
+	return nil
+}
+
+func (c *cursor) Next() error {
+	c.pos++
+	return nil
+}
+
+func (c *cursor) Eof() bool { return c.pos >= len(c.rows) }
+
+func (c *cursor) Rowid() (int64, error) { return int64(c.pos), nil }
+
+func (c *cursor) Close() error {
+	c.rows = nil
+	return nil
+}
+
+func (c *cursor) Column(col int) (vtab.Value, error) {
+	r := &c.rows[c.pos]
+	switch col {
+	case 0:
+		return r.Ordinal, nil
+	case 1:
+		return r.PlayerID, nil
+	case 2:
+		return r.Frame, nil
+	case 3:
+		return r.Second, nil
+	case 4:
+		return r.ActionType, nil
+	case 5:
+		return nullableInt(r.X), nil
+	case 6:
+		return nullableInt(r.Y), nil
+	case 7:
+		return nullableBool(r.IsQueued), nil
+	case 8:
+		return nullableString(r.OrderName), nil
+	case 9:
+		return nullableString(r.UnitType), nil
+	case 10:
+		return nullableString(r.UnitTypes), nil
+	case 11:
+		return nullableString(r.TechName), nil
+	case 12:
+		return nullableString(r.UpgradeName), nil
+	case 13:
+		return nullableString(r.HotkeyType), nil
+	case 14:
+		return nullableInt(r.HotkeyGroup), nil
+	case 15:
+		return nullableString(r.GameSpeed), nil
+	case 16:
+		return nullableString(r.VisionPlayerIDs), nil
+	case 17:
+		return nullableString(r.AlliancePlayerIDs), nil
+	case 18:
+		return nullableBool(r.IsAlliedVictory), nil
+	case 19:
+		return nullableString(r.GeneralData), nil
+	case 20:
+		return nullableString(r.ChatMessage), nil
+	case 21:
+		return nullableString(r.LeaveReason), nil
+	default:
+		// Hidden columns read back as NULL; their values were consumed by
+		// Filter.
+		return nil, nil
+	}
+}
+
+func nullableInt(v *int64) vtab.Value {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+// nullableBool returns v as the 0/1 integer SQLite stores booleans as.
+func nullableBool(v *bool) vtab.Value {
+	if v == nil {
+		return nil
+	}
+	if *v {
+		return int64(1)
+	}
+	return int64(0)
+}
+
+func nullableString(v *string) vtab.Value {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
@@ -15,19 +15,19 @@ import (
 // EnrichedCommand.Subject.
 type UnitEcon struct {
 	Minerals    int     // mineral cost
-	Gas         int     // gas cost (0 for the early-game Tier-1 set we cover)
+	Gas         int     // gas cost (0 for the early-game Tier-1 set)
 	BuildTimeS  float64 // build/train time in seconds at Fastest game speed
 	SupplyDelta int     // +N for cap-increasers (Pylon/Depot/Overlord = +8); 0 otherwise
 	SupplyCost  int     // population consumed when produced (1 for workers + most Tier-1 units; 0 for buildings)
 }
 
-// econTable is the source of truth for early-game economy. Extend as the
-// filter's coverage grows beyond Tier-1.
+// earlyEconTable is the source of truth for early-game economy: the Tier-1
+// set the early filter simulates.
 //
 // BuildTimeS references the canonical models.BuildTime* consts so the build
 // times here can never drift from the build-order / expert-timing values in
 // internal/models/build_times.go (enforced by a cross-consistency test).
-var econTable = map[string]UnitEcon{
+var earlyEconTable = map[string]UnitEcon{
 	// Protoss
 	models.GeneralUnitPylon:           {Minerals: 100, BuildTimeS: models.BuildTimePylon, SupplyDelta: 8},
 	models.GeneralUnitGateway:         {Minerals: 150, BuildTimeS: models.BuildTimeGateway},
@@ -38,7 +38,6 @@ var econTable = map[string]UnitEcon{
 	models.GeneralUnitCyberneticsCore: {Minerals: 200, BuildTimeS: models.BuildTimeCyberneticsCore},
 	models.GeneralUnitProbe:           {Minerals: 50, BuildTimeS: models.BuildTimeProbe, SupplyCost: 1},
 	models.GeneralUnitZealot:          {Minerals: 100, BuildTimeS: models.BuildTimeZealot, SupplyCost: 2},
-
 	// Terran
 	models.GeneralUnitSupplyDepot:    {Minerals: 100, BuildTimeS: models.BuildTimeSupplyDepot, SupplyDelta: 8},
 	models.GeneralUnitBarracks:       {Minerals: 150, BuildTimeS: models.BuildTimeBarracks},
@@ -52,7 +51,6 @@ var econTable = map[string]UnitEcon{
 	models.GeneralUnitBunker:         {Minerals: 100, BuildTimeS: models.BuildTimeBunker},
 	models.GeneralUnitSCV:            {Minerals: 50, BuildTimeS: models.BuildTimeSCV, SupplyCost: 1},
 	models.GeneralUnitMarine:         {Minerals: 50, BuildTimeS: models.BuildTimeMarine, SupplyCost: 1},
-
 	// Zerg
 	models.GeneralUnitOverlord:         {Minerals: 100, BuildTimeS: models.BuildTimeOverlord, SupplyDelta: 8},
 	models.GeneralUnitSpawningPool:     {Minerals: 200, BuildTimeS: models.BuildTimeSpawningPool},
@@ -68,6 +66,90 @@ var econTable = map[string]UnitEcon{
 	models.GeneralUnitZergling: {Minerals: 50, BuildTimeS: models.BuildTimeZergling, SupplyCost: 2},
 }
 
+// lateEconTable extends earlyEconTable to every other unit and building, so
+// the full-game economy model (internal/earlyfilter.SimulateEconomy) can
+// charge them. The early filter keeps to earlyEconTable: it only tracks
+// minerals, and its keep/drop verdicts are tuned to the Tier-1 set.
+var lateEconTable = map[string]UnitEcon{
+	// Protoss
+	models.GeneralUnitRoboticsFacility:   {Minerals: 200, Gas: 200, BuildTimeS: models.BuildTimeRoboticsFacility},
+	models.GeneralUnitStargate:           {Minerals: 150, Gas: 150, BuildTimeS: models.BuildTimeStargate},
+	models.GeneralUnitCitadelOfAdun:      {Minerals: 150, Gas: 100, BuildTimeS: models.BuildTimeCitadelOfAdun},
+	models.GeneralUnitTemplarArchives:    {Minerals: 150, Gas: 200, BuildTimeS: models.BuildTimeTemplarArchives},
+	models.GeneralUnitObservatory:        {Minerals: 50, Gas: 100, BuildTimeS: models.BuildTimeObservatory},
+	models.GeneralUnitRoboticsSupportBay: {Minerals: 150, Gas: 100, BuildTimeS: models.BuildTimeRoboticsSupportBay},
+	models.GeneralUnitFleetBeacon:        {Minerals: 300, Gas: 200, BuildTimeS: models.BuildTimeFleetBeacon},
+	models.GeneralUnitArbiterTribunal:    {Minerals: 200, Gas: 150, BuildTimeS: models.BuildTimeArbiterTribunal},
+	models.GeneralUnitShieldBattery:      {Minerals: 100, BuildTimeS: models.BuildTimeShieldBattery},
+	models.GeneralUnitDragoon:            {Minerals: 125, Gas: 50, BuildTimeS: models.BuildTimeDragoon, SupplyCost: 2},
+	models.GeneralUnitHighTemplar:        {Minerals: 50, Gas: 150, BuildTimeS: models.BuildTimeHighTemplar, SupplyCost: 2},
+	models.GeneralUnitDarkTemplar:        {Minerals: 125, Gas: 100, BuildTimeS: models.BuildTimeDarkTemplar, SupplyCost: 2},
+	models.GeneralUnitShuttle:            {Minerals: 200, BuildTimeS: models.BuildTimeShuttle, SupplyCost: 2},
+	models.GeneralUnitReaver:             {Minerals: 200, Gas: 100, BuildTimeS: models.BuildTimeReaver, SupplyCost: 4},
+	models.GeneralUnitObserver:           {Minerals: 25, Gas: 75, BuildTimeS: models.BuildTimeObserver, SupplyCost: 1},
+	models.GeneralUnitScout:              {Minerals: 275, Gas: 125, BuildTimeS: models.BuildTimeScout, SupplyCost: 3},
+	models.GeneralUnitCorsair:            {Minerals: 150, Gas: 100, BuildTimeS: models.BuildTimeCorsair, SupplyCost: 2},
+	models.GeneralUnitCarrier:            {Minerals: 350, Gas: 250, BuildTimeS: models.BuildTimeCarrier, SupplyCost: 6},
+	models.GeneralUnitArbiter:            {Minerals: 100, Gas: 350, BuildTimeS: models.BuildTimeArbiter, SupplyCost: 4},
+
+	// Terran
+	models.GeneralUnitComSat:            {Minerals: 50, Gas: 50, BuildTimeS: models.BuildTimeComSat},
+	models.GeneralUnitNuclearSilo:       {Minerals: 100, Gas: 100, BuildTimeS: models.BuildTimeNuclearSilo},
+	models.GeneralUnitControlTower:      {Minerals: 50, Gas: 50, BuildTimeS: models.BuildTimeControlTower},
+	models.GeneralUnitScienceFacility:   {Minerals: 100, Gas: 150, BuildTimeS: models.BuildTimeScienceFacility},
+	models.GeneralUnitCovertOps:         {Minerals: 50, Gas: 50, BuildTimeS: models.BuildTimeCovertOps},
+	models.GeneralUnitPhysicsLab:        {Minerals: 50, Gas: 50, BuildTimeS: models.BuildTimePhysicsLab},
+	models.GeneralUnitMissileTurret:     {Minerals: 75, BuildTimeS: models.BuildTimeMissileTurret},
+	models.GeneralUnitArmory:            {Minerals: 100, Gas: 50, BuildTimeS: models.BuildTimeArmory},
+	models.GeneralUnitFirebat:           {Minerals: 50, Gas: 25, BuildTimeS: models.BuildTimeFirebat, SupplyCost: 1},
+	models.GeneralUnitMedic:             {Minerals: 50, Gas: 25, BuildTimeS: models.BuildTimeMedic, SupplyCost: 1},
+	models.GeneralUnitGhost:             {Minerals: 25, Gas: 75, BuildTimeS: models.BuildTimeGhost, SupplyCost: 1},
+	models.GeneralUnitVulture:           {Minerals: 75, BuildTimeS: models.BuildTimeVulture, SupplyCost: 2},
+	models.GeneralUnitSiegeTankTankMode: {Minerals: 150, Gas: 100, BuildTimeS: models.BuildTimeSiegeTank, SupplyCost: 2},
+	models.GeneralUnitGoliath:           {Minerals: 100, Gas: 50, BuildTimeS: models.BuildTimeGoliath, SupplyCost: 2},
+	models.GeneralUnitWraith:            {Minerals: 150, Gas: 100, BuildTimeS: models.BuildTimeWraith, SupplyCost: 2},
+	models.GeneralUnitDropship:          {Minerals: 100, Gas: 100, BuildTimeS: models.BuildTimeDropship, SupplyCost: 2},
+	models.GeneralUnitScienceVessel:     {Minerals: 100, Gas: 225, BuildTimeS: models.BuildTimeScienceVessel, SupplyCost: 2},
+	models.GeneralUnitValkyrie:          {Minerals: 250, Gas: 125, BuildTimeS: models.BuildTimeValkyrie, SupplyCost: 3},
+	models.GeneralUnitBattlecruiser:     {Minerals: 400, Gas: 300, BuildTimeS: models.BuildTimeBattlecruiser, SupplyCost: 6},
+
+	// Zerg
+	models.GeneralUnitMutalisk:        {Minerals: 100, Gas: 100, BuildTimeS: models.BuildTimeMutalisk, SupplyCost: 2},
+	models.GeneralUnitLair:            {Minerals: 150, Gas: 100, BuildTimeS: models.BuildTimeLair},
+	models.GeneralUnitHive:            {Minerals: 200, Gas: 150, BuildTimeS: models.BuildTimeHive},
+	models.GeneralUnitHydraliskDen:    {Minerals: 100, Gas: 50, BuildTimeS: models.BuildTimeHydraliskDen},
+	models.GeneralUnitSpire:           {Minerals: 200, Gas: 150, BuildTimeS: models.BuildTimeSpire},
+	models.GeneralUnitGreaterSpire:    {Minerals: 100, Gas: 150, BuildTimeS: models.BuildTimeGreaterSpire},
+	models.GeneralUnitQueensNest:      {Minerals: 150, Gas: 100, BuildTimeS: models.BuildTimeQueensNest},
+	models.GeneralUnitUltraliskCavern: {Minerals: 150, Gas: 200, BuildTimeS: models.BuildTimeUltraliskCavern},
+	models.GeneralUnitDefilerMound:    {Minerals: 100, Gas: 100, BuildTimeS: models.BuildTimeDefilerMound},
+	models.GeneralUnitNydusCanal:      {Minerals: 150, BuildTimeS: models.BuildTimeNydusCanal},
+	models.GeneralUnitSporeColony:     {Minerals: 50, BuildTimeS: models.BuildTimeSporeColony},
+	models.GeneralUnitHydralisk:       {Minerals: 75, Gas: 25, BuildTimeS: models.BuildTimeHydralisk, SupplyCost: 1},
+	models.GeneralUnitUltralisk:       {Minerals: 200, Gas: 200, BuildTimeS: models.BuildTimeUltralisk, SupplyCost: 4},
+	models.GeneralUnitQueen:           {Minerals: 100, Gas: 100, BuildTimeS: models.BuildTimeQueen, SupplyCost: 2},
+	models.GeneralUnitDefiler:         {Minerals: 50, Gas: 150, BuildTimeS: models.BuildTimeDefiler, SupplyCost: 2},
+	models.GeneralUnitScourge:         {Minerals: 25, Gas: 75, BuildTimeS: models.BuildTimeScourge, SupplyCost: 1},
+	models.GeneralUnitInfestedTerran:  {Minerals: 100, Gas: 50, BuildTimeS: models.BuildTimeInfestedTerran, SupplyCost: 1},
+	// Lurker, Guardian and Devourer morph from an existing Hydralisk or
+	// Mutalisk, so their supply cost is only what they add on top of it.
+	models.GeneralUnitLurker:   {Minerals: 50, Gas: 100, BuildTimeS: models.BuildTimeLurker, SupplyCost: 1},
+	models.GeneralUnitGuardian: {Minerals: 50, Gas: 100, BuildTimeS: models.BuildTimeGuardian},
+	models.GeneralUnitDevourer: {Minerals: 150, Gas: 50, BuildTimeS: models.BuildTimeDevourer},
+}
+
+// econTable is earlyEconTable and lateEconTable together: what EconOf answers.
+var econTable = func() map[string]UnitEcon {
+	out := make(map[string]UnitEcon, len(earlyEconTable)+len(lateEconTable))
+	for subject, econ := range earlyEconTable {
+		out[subject] = econ
+	}
+	for subject, econ := range lateEconTable {
+		out[subject] = econ
+	}
+	return out
+}()
+
 // EconOf returns the economic footprint for a Subject. Returns (zero, false)
 // if the Subject is not in the table — callers should treat that as
 // "unknown / pass-through" rather than free.
@@ -76,6 +158,13 @@ func EconOf(subject string) (UnitEcon, bool) {
 	return e, ok
 }
 
+// EarlyEconOf is EconOf restricted to the early-game Tier-1 set the early
+// filter covers.
+func EarlyEconOf(subject string) (UnitEcon, bool) {
+	e, ok := earlyEconTable[subject]
+	return e, ok
+}
+
 // gatherRates is the steady-state per-worker per-minute mineral income at a
 // near base, sourced from Liquipedia. Values match the user spec.
 var gatherRates = map[string]float64{
@@ -90,13 +179,13 @@ func GatherRatePerMinute(workerSubject string) float64 {
 	return gatherRates[workerSubject]
 }
 
-// EconEntry is one row of the early-game economy table (Subject + footprint).
+// EconEntry is one row of the economy table (Subject + footprint).
 type EconEntry struct {
 	Subject string
 	Econ    UnitEcon
 }
 
-// AllEcon returns every early-game economy entry, sorted by Subject. Used by
+// AllEcon returns every economy entry, sorted by Subject. Used by
 // the SPECIFICATION.md generator and cross-consistency tests.
 func AllEcon() []EconEntry {
 	out := make([]EconEntry, 0, len(econTable))
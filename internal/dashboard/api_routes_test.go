@@ -25,6 +25,7 @@ func TestSetupRouter_JSONEndpoints(t *testing.T) {
 	}{
 		{"health", http.MethodGet, "/api/health", nil},
 		{"games list", http.MethodGet, "/api/games", nil},
+		{"chat search", http.MethodGet, "/api/chat/search?q=gg", nil},
 		{"players list", http.MethodGet, "/api/players", nil},
 		{"player colors", http.MethodGet, "/api/player-colors", nil},
 		{"screp colors", http.MethodGet, "/api/screp-colors", nil},
@@ -35,6 +36,7 @@ func TestSetupRouter_JSONEndpoints(t *testing.T) {
 		{"global replay filter options", http.MethodGet, "/api/custom/global-replay-filter/options", nil},
 		{"ingest settings get", http.MethodGet, "/api/custom/ingest/settings", nil},
 		{"stale replays count", http.MethodGet, "/api/custom/replays/stale-count", nil},
+		{"ingest failures list", http.MethodGet, "/api/custom/ingest/failures", nil},
 		{"aliases list", http.MethodGet, "/api/custom/aliases", nil},
 	}
 
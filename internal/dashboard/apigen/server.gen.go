@@ -1,6 +1,6 @@
 // Package apigen provides primitives to interact with the openapi HTTP API.
 //
-// Code generated by github.com/oapi-codegen/oapi-codegen/v2 version v2.7.1 DO NOT EDIT.
+// Code generated by github.com/oapi-codegen/oapi-codegen/v2 version (devel) DO NOT EDIT.
 package apigen
 
 import (
@@ -185,6 +185,7 @@ type ImportAliasesRequest struct {
 type IngestRequest struct {
 	Clean            *bool   `json:"clean,omitempty"`
 	CleanDashboard   *bool   `json:"clean_dashboard,omitempty"`
+	CompactCommands  *bool   `json:"compact_commands,omitempty"`
 	InputDir         *string `json:"input_dir,omitempty"`
 	SkipHotkeys      *bool   `json:"skip_hotkeys,omitempty"`
 	SqlitePath       *string `json:"sqlite_path,omitempty"`
@@ -234,6 +235,17 @@ type PlayerKey = string
 // ReplayID defines model for replayID.
 type ReplayID = int64
 
+// ChatSearchParams defines parameters for ChatSearch.
+type ChatSearchParams struct {
+	Q       *string   `form:"q,omitempty" json:"q,omitempty"`
+	Player  *[]string `form:"player,omitempty" json:"player,omitempty"`
+	Matchup *[]string `form:"matchup,omitempty" json:"matchup,omitempty"`
+	From    *string   `form:"from,omitempty" json:"from,omitempty"`
+	To      *string   `form:"to,omitempty" json:"to,omitempty"`
+	Limit   *int64    `form:"limit,omitempty" json:"limit,omitempty"`
+	Context *int64    `form:"context,omitempty" json:"context,omitempty"`
+}
+
 // GamesListParams defines parameters for GamesList.
 type GamesListParams struct {
 	Limit     *int64    `form:"limit,omitempty" json:"limit,omitempty"`
@@ -608,6 +620,9 @@ func (t *GenericValue_1_Item) UnmarshalJSON(b []byte) error {
 
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
+	// Full-text search over in-game chat, with the messages around each hit.
+	// (GET /api/chat/search)
+	ChatSearch(w http.ResponseWriter, r *http.Request, params ChatSearchParams)
 
 	// (GET /api/custom/aliases)
 	ListAliases(w http.ResponseWriter, r *http.Request)
@@ -621,6 +636,12 @@ type ServerInterface interface {
 	// (DELETE /api/custom/aliases/{id})
 	DeleteAliasEntry(w http.ResponseWriter, r *http.Request, id int64)
 
+	// (GET /api/custom/backups)
+	ListBackups(w http.ResponseWriter, r *http.Request)
+
+	// (POST /api/custom/backups)
+	CreateBackup(w http.ResponseWriter, r *http.Request)
+
 	// (GET /api/custom/global-replay-filter)
 	GetGlobalReplayFilterConfig(w http.ResponseWriter, r *http.Request)
 
@@ -633,6 +654,12 @@ type ServerInterface interface {
 	// (POST /api/custom/ingest)
 	Ingest(w http.ResponseWriter, r *http.Request)
 
+	// (GET /api/custom/ingest/failures)
+	ListIngestFailures(w http.ResponseWriter, r *http.Request)
+
+	// (POST /api/custom/ingest/failures/retry)
+	RetryIngestFailures(w http.ResponseWriter, r *http.Request)
+
 	// (GET /api/custom/ingest/logs)
 	IngestLogs(w http.ResponseWriter, r *http.Request)
 
@@ -715,6 +742,117 @@ type ServerInterfaceWrapper struct {
 
 type MiddlewareFunc func(http.Handler) http.Handler
 
+// ChatSearch operation middleware
+func (siw *ServerInterfaceWrapper) ChatSearch(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+	_ = err
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ChatSearchParams
+
+	// ------------- Optional query parameter "q" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "q", r.URL.Query(), &params.Q, runtime.BindQueryParameterOptions{Type: "string", Format: ""})
+	if err != nil {
+		var requiredError *runtime.RequiredParameterError
+		if errors.As(err, &requiredError) {
+			siw.ErrorHandlerFunc(w, r, &RequiredParamError{ParamName: "q"})
+		} else {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "q", Err: err})
+		}
+		return
+	}
+
+	// ------------- Optional query parameter "player" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "player", r.URL.Query(), &params.Player, runtime.BindQueryParameterOptions{Type: "array", Format: ""})
+	if err != nil {
+		var requiredError *runtime.RequiredParameterError
+		if errors.As(err, &requiredError) {
+			siw.ErrorHandlerFunc(w, r, &RequiredParamError{ParamName: "player"})
+		} else {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "player", Err: err})
+		}
+		return
+	}
+
+	// ------------- Optional query parameter "matchup" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "matchup", r.URL.Query(), &params.Matchup, runtime.BindQueryParameterOptions{Type: "array", Format: ""})
+	if err != nil {
+		var requiredError *runtime.RequiredParameterError
+		if errors.As(err, &requiredError) {
+			siw.ErrorHandlerFunc(w, r, &RequiredParamError{ParamName: "matchup"})
+		} else {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "matchup", Err: err})
+		}
+		return
+	}
+
+	// ------------- Optional query parameter "from" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "from", r.URL.Query(), &params.From, runtime.BindQueryParameterOptions{Type: "string", Format: ""})
+	if err != nil {
+		var requiredError *runtime.RequiredParameterError
+		if errors.As(err, &requiredError) {
+			siw.ErrorHandlerFunc(w, r, &RequiredParamError{ParamName: "from"})
+		} else {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "from", Err: err})
+		}
+		return
+	}
+
+	// ------------- Optional query parameter "to" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "to", r.URL.Query(), &params.To, runtime.BindQueryParameterOptions{Type: "string", Format: ""})
+	if err != nil {
+		var requiredError *runtime.RequiredParameterError
+		if errors.As(err, &requiredError) {
+			siw.ErrorHandlerFunc(w, r, &RequiredParamError{ParamName: "to"})
+		} else {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "to", Err: err})
+		}
+		return
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "limit", r.URL.Query(), &params.Limit, runtime.BindQueryParameterOptions{Type: "integer", Format: "int64"})
+	if err != nil {
+		var requiredError *runtime.RequiredParameterError
+		if errors.As(err, &requiredError) {
+			siw.ErrorHandlerFunc(w, r, &RequiredParamError{ParamName: "limit"})
+		} else {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "limit", Err: err})
+		}
+		return
+	}
+
+	// ------------- Optional query parameter "context" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "context", r.URL.Query(), &params.Context, runtime.BindQueryParameterOptions{Type: "integer", Format: "int64"})
+	if err != nil {
+		var requiredError *runtime.RequiredParameterError
+		if errors.As(err, &requiredError) {
+			siw.ErrorHandlerFunc(w, r, &RequiredParamError{ParamName: "context"})
+		} else {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "context", Err: err})
+		}
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ChatSearch(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
 // ListAliases operation middleware
 func (siw *ServerInterfaceWrapper) ListAliases(w http.ResponseWriter, r *http.Request) {
 
@@ -783,6 +921,34 @@ func (siw *ServerInterfaceWrapper) DeleteAliasEntry(w http.ResponseWriter, r *ht
 	handler.ServeHTTP(w, r)
 }
 
+// ListBackups operation middleware
+func (siw *ServerInterfaceWrapper) ListBackups(w http.ResponseWriter, r *http.Request) {
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ListBackups(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// CreateBackup operation middleware
+func (siw *ServerInterfaceWrapper) CreateBackup(w http.ResponseWriter, r *http.Request) {
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.CreateBackup(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
 // GetGlobalReplayFilterConfig operation middleware
 func (siw *ServerInterfaceWrapper) GetGlobalReplayFilterConfig(w http.ResponseWriter, r *http.Request) {
 
@@ -839,6 +1005,34 @@ func (siw *ServerInterfaceWrapper) Ingest(w http.ResponseWriter, r *http.Request
 	handler.ServeHTTP(w, r)
 }
 
+// ListIngestFailures operation middleware
+func (siw *ServerInterfaceWrapper) ListIngestFailures(w http.ResponseWriter, r *http.Request) {
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ListIngestFailures(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// RetryIngestFailures operation middleware
+func (siw *ServerInterfaceWrapper) RetryIngestFailures(w http.ResponseWriter, r *http.Request) {
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.RetryIngestFailures(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
 // IngestLogs operation middleware
 func (siw *ServerInterfaceWrapper) IngestLogs(w http.ResponseWriter, r *http.Request) {
 
@@ -1732,6 +1926,8 @@ func HandlerWithOptions(si ServerInterface, options GorillaServerOptions) http.H
 		ErrorHandlerFunc:   options.ErrorHandlerFunc,
 	}
 
+	r.HandleFunc(options.BaseURL+"/api/chat/search", wrapper.ChatSearch).Methods(http.MethodGet)
+
 	r.HandleFunc(options.BaseURL+"/api/custom/aliases", wrapper.ListAliases).Methods(http.MethodGet)
 
 	r.HandleFunc(options.BaseURL+"/api/custom/aliases", wrapper.ImportAliases).Methods(http.MethodPut)
@@ -1740,6 +1936,10 @@ func HandlerWithOptions(si ServerInterface, options GorillaServerOptions) http.H
 
 	r.HandleFunc(options.BaseURL+"/api/custom/aliases/{id}", wrapper.DeleteAliasEntry).Methods(http.MethodDelete)
 
+	r.HandleFunc(options.BaseURL+"/api/custom/backups", wrapper.ListBackups).Methods(http.MethodGet)
+
+	r.HandleFunc(options.BaseURL+"/api/custom/backups", wrapper.CreateBackup).Methods(http.MethodPost)
+
 	r.HandleFunc(options.BaseURL+"/api/custom/global-replay-filter", wrapper.GetGlobalReplayFilterConfig).Methods(http.MethodGet)
 
 	r.HandleFunc(options.BaseURL+"/api/custom/global-replay-filter", wrapper.UpdateGlobalReplayFilterConfig).Methods(http.MethodPut)
@@ -1748,6 +1948,10 @@ func HandlerWithOptions(si ServerInterface, options GorillaServerOptions) http.H
 
 	r.HandleFunc(options.BaseURL+"/api/custom/ingest", wrapper.Ingest).Methods(http.MethodPost)
 
+	r.HandleFunc(options.BaseURL+"/api/custom/ingest/failures", wrapper.ListIngestFailures).Methods(http.MethodGet)
+
+	r.HandleFunc(options.BaseURL+"/api/custom/ingest/failures/retry", wrapper.RetryIngestFailures).Methods(http.MethodPost)
+
 	r.HandleFunc(options.BaseURL+"/api/custom/ingest/logs", wrapper.IngestLogs).Methods(http.MethodGet)
 
 	r.HandleFunc(options.BaseURL+"/api/custom/ingest/settings", wrapper.GetIngestSettings).Methods(http.MethodGet)
@@ -1799,6 +2003,36 @@ func HandlerWithOptions(si ServerInterface, options GorillaServerOptions) http.H
 	return r
 }
 
+type ChatSearchRequestObject struct {
+	Params ChatSearchParams
+}
+
+type ChatSearchResponseObject interface {
+	VisitChatSearchResponse(w http.ResponseWriter) error
+}
+
+type ChatSearch200JSONResponse GenericValue
+
+func (t ChatSearch200JSONResponse) MarshalJSON() ([]byte, error) {
+	return GenericValue(t).MarshalJSON()
+}
+
+func (t *ChatSearch200JSONResponse) UnmarshalJSON(b []byte) error {
+	return (*GenericValue)(t).UnmarshalJSON(b)
+}
+
+func (response ChatSearch200JSONResponse) VisitChatSearchResponse(w http.ResponseWriter) error {
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(response); err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	_, err := buf.WriteTo(w)
+	return err
+}
+
 type ListAliasesRequestObject struct {
 }
 
@@ -1918,6 +2152,64 @@ func (response DeleteAliasEntry200JSONResponse) VisitDeleteAliasEntryResponse(w
 	return err
 }
 
+type ListBackupsRequestObject struct {
+}
+
+type ListBackupsResponseObject interface {
+	VisitListBackupsResponse(w http.ResponseWriter) error
+}
+
+type ListBackups200JSONResponse GenericValue
+
+func (t ListBackups200JSONResponse) MarshalJSON() ([]byte, error) {
+	return GenericValue(t).MarshalJSON()
+}
+
+func (t *ListBackups200JSONResponse) UnmarshalJSON(b []byte) error {
+	return (*GenericValue)(t).UnmarshalJSON(b)
+}
+
+func (response ListBackups200JSONResponse) VisitListBackupsResponse(w http.ResponseWriter) error {
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(response); err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+type CreateBackupRequestObject struct {
+}
+
+type CreateBackupResponseObject interface {
+	VisitCreateBackupResponse(w http.ResponseWriter) error
+}
+
+type CreateBackup200JSONResponse GenericValue
+
+func (t CreateBackup200JSONResponse) MarshalJSON() ([]byte, error) {
+	return GenericValue(t).MarshalJSON()
+}
+
+func (t *CreateBackup200JSONResponse) UnmarshalJSON(b []byte) error {
+	return (*GenericValue)(t).UnmarshalJSON(b)
+}
+
+func (response CreateBackup200JSONResponse) VisitCreateBackupResponse(w http.ResponseWriter) error {
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(response); err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	_, err := buf.WriteTo(w)
+	return err
+}
+
 type GetGlobalReplayFilterConfigRequestObject struct {
 }
 
@@ -2036,6 +2328,64 @@ func (response Ingest200JSONResponse) VisitIngestResponse(w http.ResponseWriter)
 	return err
 }
 
+type ListIngestFailuresRequestObject struct {
+}
+
+type ListIngestFailuresResponseObject interface {
+	VisitListIngestFailuresResponse(w http.ResponseWriter) error
+}
+
+type ListIngestFailures200JSONResponse GenericValue
+
+func (t ListIngestFailures200JSONResponse) MarshalJSON() ([]byte, error) {
+	return GenericValue(t).MarshalJSON()
+}
+
+func (t *ListIngestFailures200JSONResponse) UnmarshalJSON(b []byte) error {
+	return (*GenericValue)(t).UnmarshalJSON(b)
+}
+
+func (response ListIngestFailures200JSONResponse) VisitListIngestFailuresResponse(w http.ResponseWriter) error {
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(response); err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+type RetryIngestFailuresRequestObject struct {
+}
+
+type RetryIngestFailuresResponseObject interface {
+	VisitRetryIngestFailuresResponse(w http.ResponseWriter) error
+}
+
+type RetryIngestFailures200JSONResponse GenericValue
+
+func (t RetryIngestFailures200JSONResponse) MarshalJSON() ([]byte, error) {
+	return GenericValue(t).MarshalJSON()
+}
+
+func (t *RetryIngestFailures200JSONResponse) UnmarshalJSON(b []byte) error {
+	return (*GenericValue)(t).UnmarshalJSON(b)
+}
+
+func (response RetryIngestFailures200JSONResponse) VisitRetryIngestFailuresResponse(w http.ResponseWriter) error {
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(response); err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	_, err := buf.WriteTo(w)
+	return err
+}
+
 type IngestLogsRequestObject struct {
 }
 
@@ -2739,6 +3089,9 @@ func (response ScrepColors200JSONResponse) VisitScrepColorsResponse(w http.Respo
 
 // StrictServerInterface represents all server handlers.
 type StrictServerInterface interface {
+	// Full-text search over in-game chat, with the messages around each hit.
+	// (GET /api/chat/search)
+	ChatSearch(ctx context.Context, request ChatSearchRequestObject) (ChatSearchResponseObject, error)
 
 	// (GET /api/custom/aliases)
 	ListAliases(ctx context.Context, request ListAliasesRequestObject) (ListAliasesResponseObject, error)
@@ -2752,6 +3105,12 @@ type StrictServerInterface interface {
 	// (DELETE /api/custom/aliases/{id})
 	DeleteAliasEntry(ctx context.Context, request DeleteAliasEntryRequestObject) (DeleteAliasEntryResponseObject, error)
 
+	// (GET /api/custom/backups)
+	ListBackups(ctx context.Context, request ListBackupsRequestObject) (ListBackupsResponseObject, error)
+
+	// (POST /api/custom/backups)
+	CreateBackup(ctx context.Context, request CreateBackupRequestObject) (CreateBackupResponseObject, error)
+
 	// (GET /api/custom/global-replay-filter)
 	GetGlobalReplayFilterConfig(ctx context.Context, request GetGlobalReplayFilterConfigRequestObject) (GetGlobalReplayFilterConfigResponseObject, error)
 
@@ -2764,6 +3123,12 @@ type StrictServerInterface interface {
 	// (POST /api/custom/ingest)
 	Ingest(ctx context.Context, request IngestRequestObject) (IngestResponseObject, error)
 
+	// (GET /api/custom/ingest/failures)
+	ListIngestFailures(ctx context.Context, request ListIngestFailuresRequestObject) (ListIngestFailuresResponseObject, error)
+
+	// (POST /api/custom/ingest/failures/retry)
+	RetryIngestFailures(ctx context.Context, request RetryIngestFailuresRequestObject) (RetryIngestFailuresResponseObject, error)
+
 	// (GET /api/custom/ingest/logs)
 	IngestLogs(ctx context.Context, request IngestLogsRequestObject) (IngestLogsResponseObject, error)
 
@@ -2866,6 +3231,32 @@ type strictHandler struct {
 	options     StrictHTTPServerOptions
 }
 
+// ChatSearch operation middleware
+func (sh *strictHandler) ChatSearch(w http.ResponseWriter, r *http.Request, params ChatSearchParams) {
+	var request ChatSearchRequestObject
+
+	request.Params = params
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.ChatSearch(ctx, request.(ChatSearchRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "ChatSearch")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(ChatSearchResponseObject); ok {
+		if err := validResponse.VisitChatSearchResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
 // ListAliases operation middleware
 func (sh *strictHandler) ListAliases(w http.ResponseWriter, r *http.Request) {
 	var request ListAliasesRequestObject
@@ -2978,6 +3369,54 @@ func (sh *strictHandler) DeleteAliasEntry(w http.ResponseWriter, r *http.Request
 	}
 }
 
+// ListBackups operation middleware
+func (sh *strictHandler) ListBackups(w http.ResponseWriter, r *http.Request) {
+	var request ListBackupsRequestObject
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.ListBackups(ctx, request.(ListBackupsRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "ListBackups")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(ListBackupsResponseObject); ok {
+		if err := validResponse.VisitListBackupsResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// CreateBackup operation middleware
+func (sh *strictHandler) CreateBackup(w http.ResponseWriter, r *http.Request) {
+	var request CreateBackupRequestObject
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.CreateBackup(ctx, request.(CreateBackupRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "CreateBackup")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(CreateBackupResponseObject); ok {
+		if err := validResponse.VisitCreateBackupResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
 // GetGlobalReplayFilterConfig operation middleware
 func (sh *strictHandler) GetGlobalReplayFilterConfig(w http.ResponseWriter, r *http.Request) {
 	var request GetGlobalReplayFilterConfigRequestObject
@@ -3091,6 +3530,54 @@ func (sh *strictHandler) Ingest(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// ListIngestFailures operation middleware
+func (sh *strictHandler) ListIngestFailures(w http.ResponseWriter, r *http.Request) {
+	var request ListIngestFailuresRequestObject
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.ListIngestFailures(ctx, request.(ListIngestFailuresRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "ListIngestFailures")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(ListIngestFailuresResponseObject); ok {
+		if err := validResponse.VisitListIngestFailuresResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// RetryIngestFailures operation middleware
+func (sh *strictHandler) RetryIngestFailures(w http.ResponseWriter, r *http.Request) {
+	var request RetryIngestFailuresRequestObject
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.RetryIngestFailures(ctx, request.(RetryIngestFailuresRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "RetryIngestFailures")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(RetryIngestFailuresResponseObject); ok {
+		if err := validResponse.VisitRetryIngestFailuresResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
 // IngestLogs operation middleware
 func (sh *strictHandler) IngestLogs(w http.ResponseWriter, r *http.Request) {
 	var request IngestLogsRequestObject
@@ -3715,36 +4202,38 @@ func (sh *strictHandler) ScrepColors(w http.ResponseWriter, r *http.Request) {
 // const string: with thousands of chunks the chained `+` fold is several
 // times slower for the Go compiler than parsing a slice literal.
 var swaggerSpec = []string{
-	"3Frdb9vIEf9XiG2BawHKdHptH/SWJm3OuAscxMi95AJiRI6oPe+Xd2fjCoL+92J3KVkfpCzrZJT2Sxxx",
-	"Z2dnfvO5Qy5YpaXRChU5Nl4wAxYkEtr0S8Ac7c84Dz+4YmNmgGYsZwokhl/r9ZxZvPPcYs3GZD3mzFUz",
-	"lBA20twEYkeWq4Ytl4E27Lx638N2vXyI61RbCcTGjCv6599ZvjqGK8IGLVuGgxJ51OWt4OCupNGW/q3I",
-	"RpWgrjlxrUB8stqgJY6OjacgHObMbDxaMPBWWyh5fdTZOZsAkcCSoOmDYKXY103ab2teevI7VhRYfUCF",
-	"llfX6UGv2Amgvt2/gvAYNmuF11M2/rpgf7Y4ZWP2p+LBBYoWsGL7zGW+YJxQuj/CYAeDhyfKy0kEbbGP",
-	"4/rRRGuBoNjy23KtJFgL83PzTi4SvQXdZ7zz6OipvpI29+/aQPMQhnsuu6/6rr13PGslSZdbXakGHZ2m",
-	"YRXxGu8jmKelsgY3m2iwdTcRV8ZTWXPbERw5c7fclDNNtzh33fvdneCEZUwbnRxImxKmhLZUpUWzyWYj",
-	"SB1pi6XlzYzKSvDqtuc4b0rSpSqlVjTr4ZVo5vP5vJSyrOvusN+zwhdTA+EHoScgPse89x8uCO07raa8",
-	"OdE6WhousC5TInXlNLIs3Z0Iy8oLAROBOynjAT38byV8jWXg41e1YB+VFZmbaUtlAxJ7CMNSGR5vuz4q",
-	"L4OTBlt9d+VEE2nJciZRILI8JJpSq1Kr8GNqEcuptiUIseHOD0LvBoYEU95yVcejanSV5Sbgx8bsI5gs",
-	"ipORzriKWlxkXz7eZC1gGVjMQNyH/7Za1lkTbSTmv6m/gCc9qrmrwIYVoIzHYPprnjmd/eCl+yHjLlOa",
-	"Msi+g+B1+NdjNkOLF78plu+jYLHxAmzQXyucH6HjTqxvoNxtmy7DbuL0rdc9U6q4QSKumhOT4qGQ7w4M",
-	"h20ejsnvxFR8zrKdswqUVrwCUcbM2p17tLcVbppWgvIggtFjLsea5WyufYeJd0y6e1x+uFVYxsw61VEs",
-	"TiJyriyaepK9XyXk7O2nK5az72hdioY3F5cXl0FwbVCB4WzMfry4vPiR5bEti0oWYHhReUdaFhvVrcFo",
-	"jwA4BINc1WzMfuFuVT5jA+eMVi393y4vU35ShCqZ0hjBq7i5+N3pWFMeerwjGozU10TVt4P8+uf01PgO",
-	"GbeKfNtmoqN/6Xp+NgE7G4nltolDAl7+30Fa5l0GLnDVJndCuBufz4RiXxp4SUAueL1MVUgg4T6U7+Pz",
-	"LSg372FfOy9JvP6D16NvQ4OrxolvCglmJGCuPRWL1UUwoLdDHEraCJxDcsXEc1GH/HmQSoI5TOAVpw6K",
-	"WPZHSZRR6qR6c98HpL5WbrC58HAH+mxhfUzb+xKCvMs/Ch23uKf5yXW7aWgapt42FgLtuoppWn+mKrp1",
-	"S122HjE8eAqhm357Jy1+CSQ74r+5fLN/Qbm551TNuGoyYzXpSguXTbXN7nHidHWLlHnTWKixXxzXNuuH",
-	"XHC7rR94guoQ9rnSUvd1Z4i+J8HeonVFjVOu+Crn7BC1l9rCEQgcVdonUfu84iaQpbzk3kXioWntQBqB",
-	"I4ch6qDe19hHMxZBrHnvqiMgvwnXeoLRjUxYDXebnu7szmPs3Nr2THDJA+lTOrK8m5WeTh2eiVea2G/x",
-	"Wk8iHh03dLMMfdU5+dU+oX5WplME8nHLeVWnaubNueGM85jTmA6nqY/RtN3C9wfWeyTgYhAVaDu0u1g+",
-	"kBTrN1Xx1UGn3oVD3H2z9iTGeU/bFXC7iYPSgVh8hiDSPL7Tzj/F5WqG1e1wZE75cFRpoW1/7v8Uqd4l",
-	"ooHJ/pjUL79mxT8HXyj3yKDEvPxHaYR3p2x32lI5mW9tXU10W5EsVOHParwORrKcCXBURtvUnRPeA6fV",
-	"3HYeB65iyReewHFTjhdeS1pPL7hyvJmRK8DI0Yw70o0F+VgAvDXypzXtcHXyitPIWF37KmwYVVCjSi8S",
-	"Dmn3RXF615IeFeXtCOuEiJBcrV8lnSGuT0k3A/bJ7xzvjbY0kl4QJ3C3aSR50Hq/tps+bu4ZnI6L9Yc+",
-	"y0cUeqmN3MOXTA+dXIf2RTUDGjkvJaRXI4fahRnQTUv5avFovf8RKK5aqpcMQ08SixnqUC49Br6nFbRh",
-	"1bNn9qxT6+J2WXx1bndEET+IrvYk+OOXh+sV2at1M4sVKhodnv0lMD5H0g9tA/RK8Whr27EO0ha4V+En",
-	"PaFWAWGj7dO+cT4KY4N2tBohHgXzJ7Qf1yPHV+6AzmDFQRwHzE1L/CpQiZ9sPTaSuglEg5lILZf/CwAA",
-	"//8=",
+	"3Fvdb9tGEv9XiL0DegdQpnO9uwe9pcklNdrAQYz0JTWIETkSt94v787aJQz974ddkrI+SFlWbYT2S11x",
+	"Z4czv/nc4eaOFVoarVCRY9M7ZsCCRELb/BJQo/0F6/CDKzZlBqhiKVMgkU3X1lNm8dpziyWbkvWYMldU",
+	"KCFspNoEYkeWqwVbLgNt2Hn2foDtankf17m2EohNGVf033+ztHsNV4QLtGy5XHbkUZe3goM7k0Zb+p8i",
+	"G1WCsuTEtQLx2WqDljg6Np2DcJgys/bojoG32kLOy4PenbIZEAnMCRZDEHSKfVunvVzx0rM/sKDA6iMq",
+	"tLw4bx4Mit0ANLT7NxAew2at8HzOpt/u2N8tztmU/S27d4GsBSzbfOcyvWOcULq/wmALg/snyssZ2vUn",
+	"9ziuHs20FgiKLS+XKyXBWqifmnfjItFb0H3Ba4+OHusrzebhXWto7sNwx2V3Vd+295ZndZL0udWZWqCj",
+	"4zQsIl7TXQTTZikvwVUzDbYcINLSQEF5oaUEVbp+Kq6Mp7zktieEUuauuMkrTVdYD+x314IT5jG59HIg",
+	"bXKYE9pc5RbNOpu1UHakLeaWLyrKC8GLq4HXeZOTzlUutaJqgFdDU9d1nUuZl2V/ctix1VdTAuFHoWcg",
+	"vsTs+IELQvtOqzlfHGlDLQ0XWOZNunX5PLLM3bUIy8oLATOBW4nlHj38sxC+xGBD47uKsYtKR+YqbSlf",
+	"gMQBwrCUh8ebAYLKy+DKwVY3Lp9pIi1ZyiQKRJYyrTDXKtcq/JhbxHyubQ5CsMseobfDR4LJr3jrgSW6",
+	"wnIT8GNT9glMEsVJSCdcRS1Okq+fLpIWsAQsJiBuw/+2WpbJItpI1L+rf4AnPSm5K8CGFaCEx5D7Z5o4",
+	"nfzgpfsh4S5RmhJIbkDwMvzXY1KhxZPfFUt3UbC48AJs0F8rrA/QcSsjrKHcb5s+w67jdDnonk1CuUAi",
+	"rhZHps59Id8fGA7bbB1T5HFvfdLinrIClFa8AJHH/NtL47S3Ba6bVoLyIILRY8bHkqWs1r7HxFsm3X5d",
+	"ur+hWMbMOtdRLE4ici4smnKWvO/SdvL28xlL2Q1a10TDm5PTk9MguDaowHA2ZT+enJ78yNLYvEUlMzA8",
+	"KyqgzCHYIibdBUZjBLQhWOOsZFP2rgK6aEjSjZbzW9sPXnu09X1DeM329pP9m5rmdGPnKpwejJl+lhKo",
+	"qLx5Up5zq+UGwwPVI33MLsElJ/a4PnqAVaEV4Z+PZXYZfNcZrdoG6V+np00xUoSqiVtjBC+is2R/OB3b",
+	"jPs3HNBzNq1u9PPNjH7+S4wd56UEW7Mp++CFmAQlksZhE32DNuFqElJhEjw5TW45VQlVmEh0DhYY0r72",
+	"qkwQiiqpOJ1Epo3ve0daZmv9X6/7/8pd12Cy7w3HMmXG98i40Qa3BzF09JMu6ycTsLfVXm6mt9B8LL87",
+	"SP0GzrA7SPZCuF2bngnFoRL4koC84+Wy6cAEEu5C+T4+34Cyr2xsjhF4+RcHCJdjg2sGxZU3+xPLTy3N",
+	"GBKLdn3F3yIQNmKykSKc3QUfCi65tV7izC8yCWYioNaesrtuWNVDHKrIBJxDctnMc1GG0ryXSoLZT+AV",
+	"px6KeOiYNKJMmnPcoJN8RBo6SI62Gu0//z5bYj3k0P0S0myff2Q6bnGP85PzdtPYNGxO1rEU9yad5mz6",
+	"XH3MxiRt2XrE+ODJ5sCFtw90po02HzrSkauSWex6sF7DfwnLL0MloRfDlmlU+FUvdsR/c/pmd3h1ccup",
+	"qLhaJMZq0oUWLplrm9zizOniCinxZmGhxGFxXDvI2ZcgNkc+Iy8fPcI+V9HoH4WNMTNIsFdoXVbinCve",
+	"VYQtonbgmTkCgZNCe0X7vOIikDVVw72LxGPT2oE0AicOQ9RBuauxj2bMglj14KojIL8O12q63Y9MWA0Z",
+	"9rCh1xOOavR87vCJeD3LWO1pR2qlb1B/2jkdAvm4ZewTxW5WfxzT8Rx6YzRtHrCGA+s9EnAxigq0Gdp9",
+	"LO9Jsk675uNzr96ZQ9y+m/EoxkMn8YDbBeL3B63TvEIQNPzZ4Oe4XFRYXI1H5iYfTgottB3O/Z8j1buG",
+	"aGSyPyT1y69Z8c8RX0u0EnX+n9wI747Z7sJX1Vm9sbX72teKZKEIf7pPr2AkS5kAR3m0Tdn79W/P20pu",
+	"e18HrmCNLzyC47ocL7yWtJ6eceXCPQ6XgZGTijvSCwvyoQB4a+TPK9rx6hSGgxNjdemLsGFSQImqwIe0",
+	"+6o4vWtJD4rydsB4RERIrlbXDJ4gro9JNyP2yRuOt0ZbmkgviBO4q2ZgvNd6v7WbPq3vGZ2Od6urossH",
+	"FHqpjdxKwbVOrkf7eD9isvoEvb9dCNckWspXi0fr/Q9AcdZSvWQYhi5yhAy1L5ceAt/jCtq46tkze9ax",
+	"dXGzLL46tzugiO9FV3sS/OHDw3lH9mrdzGKBiib7Z38NGF8i6ce2AXqleLS17VAHaQvcq/CToZtyQLjQ",
+	"9nH/SuYgjA3aSTdCPAjmz2g/rUaOr9wBncGCgzgMmIuW+FWgEq/zPjSSughEo5lILZf/HwA=",
 }
 
 // decodeSpec returns the embedded OpenAPI spec as raw JSON bytes,
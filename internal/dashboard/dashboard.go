@@ -53,6 +53,12 @@ type Dashboard struct {
 	pendingSampleIngest bool
 	headless            bool
 	shutdown            func()
+	backupKeep          int
+	resultCache         *resultCache
+
+	// databaseURL, when set, is the shared PostgreSQL corpus sqlitePath
+	// mirrors: ingests write there and are then mirrored back.
+	databaseURL string
 }
 
 // SetShutdownFunc registers the callback the /api/custom/quit endpoint invokes to
@@ -62,6 +68,13 @@ func (d *Dashboard) SetShutdownFunc(fn func()) {
 	d.shutdown = fn
 }
 
+// SetDatabaseURL points the dashboard's ingests at the PostgreSQL database
+// at databaseURL, whose corpus the SQLite database is a mirror of (see
+// storage.RefreshMirror). The caller refreshes the mirror before New.
+func (d *Dashboard) SetDatabaseURL(databaseURL string) {
+	d.databaseURL = databaseURL
+}
+
 func New(ctx context.Context, sqlitePath string, headless bool) (*Dashboard, error) {
 	if err := runMigrations(sqlitePath); err != nil {
 		return nil, fmt.Errorf("failed to run migration routine: %w", err)
@@ -86,6 +99,7 @@ func New(ctx context.Context, sqlitePath string, headless bool) (*Dashboard, err
 		ingestStatus: "idle",
 		sqlitePath:   sqlitePath,
 		headless:     headless,
+		resultCache:  newResultCache(resultCacheMaxBytes),
 	}
 	dashboard.dbStore = dashboarddb.NewStore(dashboard.db, dashboard.currentReplayScopedDB, dashboard.withFilteredConnection)
 	if err := dashboard.initializeIngestSettings(ctx); err != nil {
@@ -138,6 +152,7 @@ func newMethodlessPathMatcher(swagger *openapi3.T) *mux.Router {
 func (d *Dashboard) setupRouter() *mux.Router {
 	r := mux.NewRouter()
 	r.Use(recoveryMiddleware)
+	r.Use(d.purgeResultCacheOnWrite)
 	swagger, err := apigen.GetSwagger()
 	if err != nil {
 		panic(fmt.Errorf("failed to load embedded OpenAPI spec: %w", err))
@@ -214,6 +229,7 @@ func (d *Dashboard) setupRouter() *mux.Router {
 	r.HandleFunc("/api/custom/sample-set/load", d.handlerLoadSampleSet).Methods(http.MethodPost)
 	r.HandleFunc("/api/custom/update/status", d.handlerUpdateStatus).Methods(http.MethodGet)
 	r.HandleFunc("/api/custom/update/apply", d.handlerUpdateApply).Methods(http.MethodPost)
+	r.HandleFunc("/api/custom/backups/{name}", d.handlerDownloadBackup).Methods(http.MethodGet)
 	r.HandleFunc("/api/custom/quit", d.handlerQuit).Methods(http.MethodPost)
 	apigen.HandlerFromMux(strictHandler, r)
 	r.PathPrefix("/api/").Methods(http.MethodOptions).HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
@@ -421,6 +437,7 @@ func qualifyReplayFilterSQL(filterSQL string) string {
 		"players",
 		"commands",
 		"commands_low_value",
+		"commands_low_value_all",
 		"replay_events",
 	}
 	for _, table := range tables {
@@ -436,6 +453,7 @@ func hasUnqualifiedReplays(filterSQL string) bool {
 		"players",
 		"commands",
 		"commands_low_value",
+		"commands_low_value_all",
 		"replay_events",
 	}
 	for _, table := range tables {
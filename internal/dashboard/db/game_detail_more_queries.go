@@ -126,6 +126,36 @@ func (s *Store) ListGameUnitProductionAndCasts(ctx context.Context, replayID int
 	return out, nil
 }
 
+// EconomySampleRow is one point of a player's estimated economy timeline.
+type EconomySampleRow struct {
+	PlayerID      int64
+	Second        int64
+	Minerals      int64
+	Gas           int64
+	MineralIncome int64
+	GasIncome     int64
+	SupplyUsed    int64
+	SupplyMax     int64
+	Workers       int64
+	Bases         int64
+}
+
+// ListReplayEconomySamples returns every player's estimated economy timeline
+// for one replay, ordered by player then second. Source: the
+// player_economy_samples rows written at ingest by
+// earlyfilter.SimulateEconomy.
+func (s *Store) ListReplayEconomySamples(ctx context.Context, replayID int64) ([]EconomySampleRow, error) {
+	rows, err := sqlcgen.New(Trace(s.replayScoped())).ListReplayEconomySamples(ctx, replayID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]EconomySampleRow, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, EconomySampleRow(row))
+	}
+	return out, nil
+}
+
 type PlayerMatchupRow struct {
 	OwnRace string
 	OppRace string
@@ -18,6 +18,11 @@ func BuildGlobalReplayFilterSQL(
 	// rest of the app never sees them. Hardcoded so it survives any user
 	// filter combination.
 	clauses = append(clauses, "r.map_kind != 'UseMapSettings'")
+	// Replays saved by several players of one game are grouped under the
+	// canonical replay's id (see internal/gameid); only the canonical one is
+	// visible so every game counts once. Rows ingested before grouping
+	// existed have no group and stand for themselves.
+	clauses = append(clauses, "COALESCE(r.game_group_id, r.id) = r.id")
 	if excludeShortGames {
 		clauses = append(clauses, fmt.Sprintf("r.duration_seconds >= %d", shortGameSeconds))
 	}
@@ -45,6 +50,54 @@ func BuildGlobalReplayFilterSQL(
 	return query + " WHERE " + strings.Join(clauses, " AND ")
 }
 
+// BuildPlayerAggregateClassSQL is BuildGlobalReplayFilterSQL as a predicate
+// over the replay class columns of the player aggregate tables (see
+// internal/playeragg), which a filter setting selects rows of without
+// touching replays.
+func BuildPlayerAggregateClassSQL(
+	excludeShortGames bool,
+	excludeComputers bool,
+	gameTypes []string,
+	mapKinds []string,
+) string {
+	clauses := []string{"map_kind != 'UseMapSettings'", "is_canonical = 1"}
+	if excludeShortGames {
+		clauses = append(clauses, "is_short = 0")
+	}
+	if excludeComputers {
+		clauses = append(clauses, "has_computers = 0")
+	}
+	gamePredicates := []string{}
+	for _, value := range gameTypes {
+		switch value {
+		case "top_vs_bottom":
+			gamePredicates = append(gamePredicates, "game_type = 'top vs bottom'")
+		case "melee":
+			gamePredicates = append(gamePredicates, "game_type = 'melee'")
+		case "one_on_one":
+			gamePredicates = append(gamePredicates, "is_one_on_one = 1")
+		case "free_for_all":
+			gamePredicates = append(gamePredicates, "game_type = 'free for all'")
+		}
+	}
+	if len(gamePredicates) > 0 {
+		clauses = append(clauses, "("+strings.Join(gamePredicates, " OR ")+")")
+	}
+	mapPredicates := []string{}
+	for _, value := range mapKinds {
+		switch value {
+		case "regular":
+			mapPredicates = append(mapPredicates, "map_kind = 'Regular'")
+		case "money":
+			mapPredicates = append(mapPredicates, "map_kind = 'Money'")
+		}
+	}
+	if len(mapPredicates) > 0 {
+		clauses = append(clauses, "("+strings.Join(mapPredicates, " OR ")+")")
+	}
+	return strings.Join(clauses, " AND ")
+}
+
 func ComposeReplayFilterSQL(globalFilterSQL string, localFilterSQL string) string {
 	globalNormalized := normalizeSQL(globalFilterSQL)
 	localNormalized := normalizeSQL(localFilterSQL)
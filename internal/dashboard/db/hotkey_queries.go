@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+
+	"github.com/marianogappa/screpdb/internal/dashboard/db/sqlcgen"
+)
+
+// HotkeyUsageRow is one second of a player's use of one hotkey group. Role is
+// the group's binding role at that second ("building", "army" or empty when
+// unknown).
+type HotkeyUsageRow struct {
+	PlayerID        int64
+	HotkeyGroup     int64
+	WindowEndSecond int64
+	Second          int64
+	Role            string
+	Assigns         int64
+	Selects         int64
+	Adds            int64
+	CameraJumps     int64
+}
+
+// ListReplayHotkeyUsage returns one replay's hotkey usage, ordered by player,
+// group and second.
+func (s *Store) ListReplayHotkeyUsage(ctx context.Context, replayID int64) ([]HotkeyUsageRow, error) {
+	sqlcRows, err := sqlcgen.New(Trace(s.replayScoped())).ListReplayHotkeyUsage(ctx, replayID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]HotkeyUsageRow, 0, len(sqlcRows))
+	for _, row := range sqlcRows {
+		out = append(out, HotkeyUsageRow(row))
+	}
+	return out, nil
+}
+
+// HotkeyGroupTotalRow is one player's use of one hotkey group in one phase of
+// one game (0 early, 1 mid, 2 late) while bound to Role. OppRace is empty
+// outside 1v1 games.
+type HotkeyGroupTotalRow struct {
+	ReplayID        int64
+	OwnRace         string
+	OppRace         string
+	MidStartsAt     int64
+	LateStartsAt    int64
+	WindowEndSecond int64
+	HotkeyGroup     int64
+	Phase           int64
+	Role            string
+	Assigns         int64
+	Selects         int64
+	Adds            int64
+	CameraJumps     int64
+}
+
+// ListPlayerHotkeyGroupTotals returns playerKey's hotkey totals per game,
+// group, phase and role, ordered by replay, group and phase.
+func (s *Store) ListPlayerHotkeyGroupTotals(ctx context.Context, playerKey string) ([]HotkeyGroupTotalRow, error) {
+	sqlcRows, err := sqlcgen.New(Trace(s.replayScoped())).ListPlayerHotkeyGroupTotals(ctx, playerKey)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]HotkeyGroupTotalRow, 0, len(sqlcRows))
+	for _, row := range sqlcRows {
+		out = append(out, HotkeyGroupTotalRow(row))
+	}
+	return out, nil
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"context"
+
+	"github.com/marianogappa/screpdb/internal/dashboard/db/sqlcgen"
+)
+
+// LarvaSampleRow is one second of a Zerg hatchery's estimated larva timeline
+// in which larvae spawned, were used or a spawn was missed. A hatchery's
+// first row is its ready second; Larvae is the count after the second.
+type LarvaSampleRow struct {
+	PlayerID        int64
+	HatcheryIndex   int64
+	WindowEndSecond int64
+	Second          int64
+	Larvae          int64
+	Spawned         int64
+	Used            int64
+	Missed          int64
+}
+
+// ListReplayLarvaSamples returns one replay's larva samples, ordered by
+// player, hatchery and second.
+func (s *Store) ListReplayLarvaSamples(ctx context.Context, replayID int64) ([]LarvaSampleRow, error) {
+	sqlcRows, err := sqlcgen.New(Trace(s.replayScoped())).ListReplayLarvaSamples(ctx, replayID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]LarvaSampleRow, 0, len(sqlcRows))
+	for _, row := range sqlcRows {
+		out = append(out, LarvaSampleRow{
+			PlayerID:        row.PlayerID,
+			HatcheryIndex:   row.HatcheryIndex,
+			WindowEndSecond: row.WindowEndSecond,
+			Second:          row.Second,
+			Larvae:          row.Larvae,
+			Spawned:         row.Spawned,
+			Used:            row.Used,
+			Missed:          row.Missed,
+		})
+	}
+	return out, nil
+}
+
+// LarvaPhaseTotalRow is one player's larvae spawned, used and missed in one
+// phase of one game (0 early, 1 mid, 2 late).
+type LarvaPhaseTotalRow struct {
+	PlayerKey  string
+	PlayerName string
+	ReplayID   int64
+	Phase      int64
+	Spawned    int64
+	Used       int64
+	Missed     int64
+}
+
+// ListLarvaPlayerPhaseTotals returns every human player's larva totals per
+// game and phase, ordered by player, replay and phase.
+func (s *Store) ListLarvaPlayerPhaseTotals(ctx context.Context) ([]LarvaPhaseTotalRow, error) {
+	sqlcRows, err := sqlcgen.New(Trace(s.replayScoped())).ListLarvaPlayerPhaseTotals(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]LarvaPhaseTotalRow, 0, len(sqlcRows))
+	for _, row := range sqlcRows {
+		out = append(out, LarvaPhaseTotalRow(row))
+	}
+	return out, nil
+}
@@ -0,0 +1,284 @@
+package db
+
+import (
+	"context"
+
+	"github.com/marianogappa/screpdb/internal/playeragg"
+)
+
+// The queries below read the materialized player aggregates (see
+// internal/playeragg) instead of players, commands and replay_events.
+// classSQL is a BuildPlayerAggregateClassSQL predicate standing in for the
+// global replay filter; each returns what its live counterpart returns under
+// that filter.
+
+// PlayerAggregatesCurrent reports whether the player aggregate tables exist
+// and are built under the current playeragg.Version.
+func (s *Store) PlayerAggregatesCurrent(ctx context.Context) (bool, error) {
+	var tables int64
+	if err := s.ReplayQueryRowContext(ctx, `
+		SELECT COUNT(*) FROM sqlite_master
+		WHERE type = 'table' AND name = 'player_aggregates_state'
+	`).Scan(&tables); err != nil {
+		return false, err
+	}
+	if tables == 0 {
+		return false, nil
+	}
+	var version int64
+	if err := s.ReplayQueryRowContext(ctx, `
+		SELECT COALESCE(MAX(version), 0) FROM player_aggregates_state WHERE id = 1
+	`).Scan(&version); err != nil {
+		return false, err
+	}
+	return version == playeragg.Version, nil
+}
+
+// ListPlayerApmAggregatesMaterialized is ListPlayerApmAggregates.
+func (s *Store) ListPlayerApmAggregatesMaterialized(ctx context.Context, classSQL string, minGames int64) ([]PlayerApmAggregateRow, error) {
+	rows, err := s.ReplayQueryContext(ctx, `
+		SELECT
+			player_key,
+			MIN(player_name) AS player_name,
+			COALESCE(SUM(apm_sum) * 1.0 / NULLIF(SUM(apm_games), 0), 0) AS average_apm,
+			SUM(games) AS games_played
+		FROM player_aggregates
+		WHERE `+classSQL+`
+		GROUP BY player_key
+		HAVING SUM(games) >= ?
+			AND COALESCE(SUM(apm_sum) * 1.0 / NULLIF(SUM(apm_games), 0), 0) > 0
+	`, minGames)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []PlayerApmAggregateRow{}
+	for rows.Next() {
+		var row PlayerApmAggregateRow
+		if err := rows.Scan(&row.PlayerKey, &row.PlayerName, &row.AverageAPM, &row.GamesPlayed); err != nil {
+			return nil, err
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+// ListPlayerMatchupAggregatesMaterialized is ListPlayerMatchupAggregates.
+func (s *Store) ListPlayerMatchupAggregatesMaterialized(ctx context.Context, classSQL string, playerKey string) ([]PlayerMatchupAggregateRow, error) {
+	rows, err := s.ReplayQueryContext(ctx, `
+		SELECT
+			race AS own_race,
+			opp_race,
+			SUM(games) AS games,
+			SUM(wins) AS wins,
+			COALESCE(SUM(apm_sum) * 1.0 / NULLIF(SUM(apm_games), 0), 0) AS avg_apm,
+			COALESCE(SUM(eapm_sum) * 1.0 / NULLIF(SUM(eapm_games), 0), 0) AS avg_eapm
+		FROM player_matchup_aggregates
+		WHERE `+classSQL+`
+			AND player_key = ?
+			AND opp_race != ''
+		GROUP BY race, opp_race
+		ORDER BY games DESC, own_race, opp_race
+	`, playerKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []PlayerMatchupAggregateRow{}
+	for rows.Next() {
+		var row PlayerMatchupAggregateRow
+		if err := rows.Scan(&row.OwnRace, &row.OppRace, &row.Games, &row.Wins, &row.AvgAPM, &row.AvgEAPM); err != nil {
+			return nil, err
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+// ListPlayerMatchupMarkerCountsMaterialized is ListPlayerMatchupMarkerCounts.
+func (s *Store) ListPlayerMatchupMarkerCountsMaterialized(ctx context.Context, classSQL string, playerKey string) ([]PlayerMatchupMarkerCountRow, error) {
+	rows, err := s.ReplayQueryContext(ctx, `
+		SELECT
+			race AS own_race,
+			opp_race,
+			event_type AS pattern_name,
+			SUM(replays) AS replay_count
+		FROM player_marker_counts
+		WHERE `+classSQL+`
+			AND player_key = ?
+			AND opp_race != ''
+		GROUP BY race, opp_race, event_type
+	`, playerKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []PlayerMatchupMarkerCountRow{}
+	for rows.Next() {
+		var row PlayerMatchupMarkerCountRow
+		if err := rows.Scan(&row.OwnRace, &row.OppRace, &row.PatternName, &row.ReplayCount); err != nil {
+			return nil, err
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+// playerByFormatSQL restricts ListPlayerByFormat*Materialized to the team
+// games ListPlayerByFormatAggregates covers.
+const playerByFormatSQL = `map_kind IN ('Regular', 'Money')
+			AND team_format LIKE '%v%'
+			AND team_format != '1v1'`
+
+// ListPlayerByFormatAggregatesMaterialized is ListPlayerByFormatAggregates.
+func (s *Store) ListPlayerByFormatAggregatesMaterialized(ctx context.Context, classSQL string, playerKey string) ([]PlayerByFormatAggregateRow, error) {
+	rows, err := s.ReplayQueryContext(ctx, `
+		SELECT
+			race AS own_race,
+			team_format,
+			map_kind,
+			SUM(games) AS games,
+			SUM(wins) AS wins,
+			COALESCE(SUM(apm_sum) * 1.0 / NULLIF(SUM(apm_games), 0), 0) AS avg_apm,
+			COALESCE(SUM(eapm_sum) * 1.0 / NULLIF(SUM(eapm_games), 0), 0) AS avg_eapm
+		FROM player_matchup_aggregates
+		WHERE `+classSQL+`
+			AND player_key = ?
+			AND `+playerByFormatSQL+`
+		GROUP BY race, team_format, map_kind
+	`, playerKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []PlayerByFormatAggregateRow{}
+	for rows.Next() {
+		var row PlayerByFormatAggregateRow
+		if err := rows.Scan(&row.OwnRace, &row.TeamFormat, &row.MapKind, &row.Games, &row.Wins, &row.AvgAPM, &row.AvgEAPM); err != nil {
+			return nil, err
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+// ListPlayerByFormatMarkerCountsMaterialized is
+// ListPlayerByFormatMarkerCounts.
+func (s *Store) ListPlayerByFormatMarkerCountsMaterialized(ctx context.Context, classSQL string, playerKey string) ([]PlayerByFormatMarkerCountRow, error) {
+	rows, err := s.ReplayQueryContext(ctx, `
+		SELECT
+			race AS own_race,
+			team_format,
+			map_kind,
+			event_type AS pattern_name,
+			SUM(replays) AS replay_count
+		FROM player_marker_counts
+		WHERE `+classSQL+`
+			AND player_key = ?
+			AND `+playerByFormatSQL+`
+		GROUP BY race, team_format, map_kind, event_type
+	`, playerKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []PlayerByFormatMarkerCountRow{}
+	for rows.Next() {
+		var row PlayerByFormatMarkerCountRow
+		if err := rows.Scan(&row.OwnRace, &row.TeamFormat, &row.MapKind, &row.PatternName, &row.ReplayCount); err != nil {
+			return nil, err
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+// UnitCadencePlayerAggregateRow sums a player's per-replay production
+// cadence metrics (see ListUnitCadenceReplayMetrics) over Games replays.
+type UnitCadencePlayerAggregateRow struct {
+	PlayerKey     string
+	PlayerName    string
+	Games         int64
+	SumRate       float64
+	SumCV         float64
+	SumBurstiness float64
+	SumIdle       float64
+	SumCadence    float64
+}
+
+// ListUnitCadencePlayerAggregates returns every player's summed cadence
+// metrics under the strict or, with broad, the broad unit exclusions.
+func (s *Store) ListUnitCadencePlayerAggregates(ctx context.Context, classSQL string, broad bool) ([]UnitCadencePlayerAggregateRow, error) {
+	mode := "strict"
+	if broad {
+		mode = "broad"
+	}
+	rows, err := s.ReplayQueryContext(ctx, `
+		SELECT
+			player_key,
+			MIN(player_name) AS player_name,
+			SUM(cadence_`+mode+`_games) AS games,
+			SUM(cadence_`+mode+`_rate_sum),
+			SUM(cadence_`+mode+`_cv_sum),
+			SUM(cadence_`+mode+`_burstiness_sum),
+			SUM(cadence_`+mode+`_idle_sum),
+			SUM(cadence_`+mode+`_score_sum)
+		FROM player_aggregates
+		WHERE `+classSQL+`
+		GROUP BY player_key
+		HAVING SUM(cadence_`+mode+`_games) > 0
+		ORDER BY player_key
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []UnitCadencePlayerAggregateRow{}
+	for rows.Next() {
+		var row UnitCadencePlayerAggregateRow
+		if err := rows.Scan(&row.PlayerKey, &row.PlayerName, &row.Games, &row.SumRate, &row.SumCV, &row.SumBurstiness, &row.SumIdle, &row.SumCadence); err != nil {
+			return nil, err
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+// ViewportPlayerAggregateRow sums a player's viewport switches per minute
+// over the Games replays that recorded it.
+type ViewportPlayerAggregateRow struct {
+	PlayerKey  string
+	PlayerName string
+	Games      int64
+	SumRate    float64
+}
+
+// ListViewportPlayerAggregates returns every player's summed viewport
+// switch rate.
+func (s *Store) ListViewportPlayerAggregates(ctx context.Context, classSQL string) ([]ViewportPlayerAggregateRow, error) {
+	rows, err := s.ReplayQueryContext(ctx, `
+		SELECT
+			player_key,
+			MIN(player_name) AS player_name,
+			SUM(viewport_games) AS games,
+			SUM(viewport_rate_sum) AS rate_sum
+		FROM player_aggregates
+		WHERE `+classSQL+`
+		GROUP BY player_key
+		HAVING SUM(viewport_games) > 0
+		ORDER BY player_key
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []ViewportPlayerAggregateRow{}
+	for rows.Next() {
+		var row ViewportPlayerAggregateRow
+		if err := rows.Scan(&row.PlayerKey, &row.PlayerName, &row.Games, &row.SumRate); err != nil {
+			return nil, err
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+
+	"github.com/marianogappa/screpdb/internal/dashboard/db/sqlcgen"
+)
+
+// ProductionBusySpanRow is one stretch a production building spent training.
+// Building and BuildingIndex name the building within its player; ReadySecond
+// and WindowEndSecond bound when it could have trained.
+type ProductionBusySpanRow struct {
+	PlayerID        int64
+	Building        string
+	BuildingIndex   int64
+	ReadySecond     int64
+	WindowEndSecond int64
+	StartSecond     int64
+	EndSecond       int64
+}
+
+// ListReplayProductionBusySpans returns one replay's production busy spans,
+// ordered by player, building and start.
+func (s *Store) ListReplayProductionBusySpans(ctx context.Context, replayID int64) ([]ProductionBusySpanRow, error) {
+	sqlcRows, err := sqlcgen.New(Trace(s.replayScoped())).ListReplayProductionBusySpans(ctx, replayID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]ProductionBusySpanRow, 0, len(sqlcRows))
+	for _, row := range sqlcRows {
+		out = append(out, ProductionBusySpanRow{
+			PlayerID:        row.PlayerID,
+			Building:        row.Building,
+			BuildingIndex:   row.BuildingIndex,
+			ReadySecond:     row.ReadySecond,
+			WindowEndSecond: row.WindowEndSecond,
+			StartSecond:     row.StartSecond,
+			EndSecond:       row.EndSecond,
+		})
+	}
+	return out, nil
+}
+
+// ProductionIdlePlayerRow is one busy span of the production-idle population,
+// with its replay's phase boundaries (0 when not reached).
+type ProductionIdlePlayerRow struct {
+	PlayerKey         string
+	PlayerName        string
+	ReplayID          int64
+	Span              ProductionBusySpanRow
+	EarlyEndsAtSecond int64
+	MidEndsAtSecond   int64
+}
+
+// ListProductionIdlePlayerRows returns every human player's production busy
+// spans, ordered by player, replay, building and start.
+func (s *Store) ListProductionIdlePlayerRows(ctx context.Context) ([]ProductionIdlePlayerRow, error) {
+	sqlcRows, err := sqlcgen.New(Trace(s.replayScoped())).ListProductionIdlePlayerRows(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]ProductionIdlePlayerRow, 0, len(sqlcRows))
+	for _, row := range sqlcRows {
+		out = append(out, ProductionIdlePlayerRow{
+			PlayerKey:  row.PlayerKey,
+			PlayerName: row.PlayerName,
+			ReplayID:   row.ReplayID,
+			Span: ProductionBusySpanRow{
+				Building:        row.Building,
+				BuildingIndex:   row.BuildingIndex,
+				ReadySecond:     row.ReadySecond,
+				WindowEndSecond: row.WindowEndSecond,
+				StartSecond:     row.StartSecond,
+				EndSecond:       row.EndSecond,
+			},
+			EarlyEndsAtSecond: row.EarlyEndsAtSecond,
+			MidEndsAtSecond:   row.MidEndsAtSecond,
+		})
+	}
+	return out, nil
+}
@@ -277,6 +277,180 @@ func TestGetPhaseBoundariesForReplay(t *testing.T) {
 	}
 }
 
+func TestListReplayEconomySamples(t *testing.T) {
+	s, conn := newTestStore(t)
+	ctx := context.Background()
+	replayID, boxerID, nadaID := fixtureBasic1v1(t, conn)
+
+	for _, row := range [][3]int64{{nadaID, 0, 50}, {boxerID, 10, 80}, {boxerID, 0, 50}} {
+		mustExec(t, conn, `
+			INSERT INTO player_economy_samples (replay_id, player_id, second, minerals, gas, mineral_income,
+				gas_income, supply_used, supply_max, workers, bases)
+			VALUES (?, ?, ?, ?, 0, 400, 0, 8, 10, 4, 1)`, replayID, row[0], row[1], row[2])
+	}
+
+	rows, err := s.ListReplayEconomySamples(ctx, replayID)
+	if err != nil {
+		t.Fatalf("ListReplayEconomySamples: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("economy samples = %+v", rows)
+	}
+	first, second := rows[0], rows[1]
+	if first.PlayerID > second.PlayerID || (first.PlayerID == second.PlayerID && first.Second > second.Second) {
+		t.Errorf("samples not ordered by player then second: %+v", rows)
+	}
+	if first.MineralIncome != 400 || first.SupplyMax != 10 || first.Bases != 1 {
+		t.Errorf("sample = %+v", first)
+	}
+}
+
+func TestListSupplyBlocks(t *testing.T) {
+	s, conn := newTestStore(t)
+	ctx := context.Background()
+	replayID, boxerID, nadaID := fixtureBasic1v1(t, conn)
+
+	// Only BoxeR was simulated; NaDa's block must not count for the population.
+	mustExec(t, conn, `
+		INSERT INTO player_economy_samples (replay_id, player_id, second, minerals, gas, mineral_income,
+			gas_income, supply_used, supply_max, workers, bases)
+		VALUES (?, ?, 0, 50, 0, 400, 0, 8, 10, 4, 1)`, replayID, boxerID)
+	for _, row := range [][2]int64{{boxerID, 700}, {boxerID, 100}, {nadaID, 50}} {
+		mustExec(t, conn, `
+			INSERT INTO replay_events (replay_id, seconds_from_game_start, event_kind, event_type, source_player_id, payload)
+			VALUES (?, ?, 'game_event', 'supply_block', ?, '{"duration_seconds":20,"supply":18}')`, replayID, row[1], row[0])
+	}
+
+	blocks, err := s.ListReplaySupplyBlocks(ctx, replayID)
+	if err != nil {
+		t.Fatalf("ListReplaySupplyBlocks: %v", err)
+	}
+	if len(blocks) != 3 || blocks[0].Second != 50 || blocks[0].PlayerID != nadaID || blocks[2].Second != 700 {
+		t.Fatalf("replay supply blocks = %+v", blocks)
+	}
+
+	rows, err := s.ListSupplyBlockPlayerRows(ctx, 600)
+	if err != nil {
+		t.Fatalf("ListSupplyBlockPlayerRows: %v", err)
+	}
+	if len(rows) != 1 || rows[0].PlayerName != "BoxeR" || rows[0].BlockSecond == nil || *rows[0].BlockSecond != 100 {
+		t.Fatalf("supply block player rows = %+v", rows)
+	}
+	if rows[0].BlockPayload == "" {
+		t.Errorf("block payload missing: %+v", rows[0])
+	}
+}
+
+func TestListProductionBusySpans(t *testing.T) {
+	s, conn := newTestStore(t)
+	ctx := context.Background()
+	replayID, boxerID, _ := fixtureBasic1v1(t, conn)
+
+	for _, span := range [][2]int64{{400, 450}, {100, 150}} {
+		mustExec(t, conn, `
+			INSERT INTO production_busy_spans (replay_id, player_id, building, building_index, ready_second,
+				window_end_second, start_second, end_second)
+			VALUES (?, ?, 'Barracks', 1, 90, 800, ?, ?)`, replayID, boxerID, span[0], span[1])
+	}
+	seedMarker(t, conn, replayID, nil, "mid_game_starts", 300, nil)
+
+	spans, err := s.ListReplayProductionBusySpans(ctx, replayID)
+	if err != nil {
+		t.Fatalf("ListReplayProductionBusySpans: %v", err)
+	}
+	if len(spans) != 2 || spans[0].StartSecond != 100 || spans[0].PlayerID != boxerID || spans[1].WindowEndSecond != 800 {
+		t.Fatalf("replay busy spans = %+v", spans)
+	}
+
+	rows, err := s.ListProductionIdlePlayerRows(ctx)
+	if err != nil {
+		t.Fatalf("ListProductionIdlePlayerRows: %v", err)
+	}
+	if len(rows) != 2 || rows[0].PlayerName != "BoxeR" || rows[0].Span.ReadySecond != 90 {
+		t.Fatalf("production idle rows = %+v", rows)
+	}
+	if rows[0].EarlyEndsAtSecond != 300 || rows[0].MidEndsAtSecond != 0 {
+		t.Errorf("phase boundaries = %d/%d; want 300/0", rows[0].EarlyEndsAtSecond, rows[0].MidEndsAtSecond)
+	}
+}
+
+func TestListLarvaSamples(t *testing.T) {
+	s, conn := newTestStore(t)
+	ctx := context.Background()
+	replayID, boxerID, _ := fixtureBasic1v1(t, conn)
+
+	for _, sample := range [][5]int64{{0, 3, 3, 0, 0}, {100, 1, 0, 2, 0}, {400, 3, 0, 0, 1}} {
+		mustExec(t, conn, `
+			INSERT INTO larva_samples (replay_id, player_id, hatchery_index, window_end_second, second,
+				larvae, spawned, used, missed)
+			VALUES (?, ?, 1, 800, ?, ?, ?, ?, ?)`, replayID, boxerID, sample[0], sample[1], sample[2], sample[3], sample[4])
+	}
+	seedMarker(t, conn, replayID, nil, "mid_game_starts", 300, nil)
+
+	samples, err := s.ListReplayLarvaSamples(ctx, replayID)
+	if err != nil {
+		t.Fatalf("ListReplayLarvaSamples: %v", err)
+	}
+	if len(samples) != 3 || samples[1].Second != 100 || samples[1].Used != 2 || samples[0].PlayerID != boxerID {
+		t.Fatalf("replay larva samples = %+v", samples)
+	}
+
+	rows, err := s.ListLarvaPlayerPhaseTotals(ctx)
+	if err != nil {
+		t.Fatalf("ListLarvaPlayerPhaseTotals: %v", err)
+	}
+	if len(rows) != 2 || rows[0].PlayerKey != "boxer" || rows[0].ReplayID != replayID {
+		t.Fatalf("larva phase totals = %+v", rows)
+	}
+	if rows[0].Phase != 0 || rows[0].Spawned != 3 || rows[0].Used != 2 || rows[1].Phase != 1 || rows[1].Missed != 1 {
+		t.Errorf("larva phase totals = %+v; want early 3 spawned 2 used, mid 1 missed", rows)
+	}
+}
+
+func TestListHotkeyUsage(t *testing.T) {
+	s, conn := newTestStore(t)
+	ctx := context.Background()
+	replayID, boxerID, _ := fixtureBasic1v1(t, conn)
+
+	for _, use := range []struct {
+		group, second int64
+		role          string
+		assigns       int64
+		selects       int64
+		jumps         int64
+	}{
+		{1, 20, "building", 1, 0, 0},
+		{1, 100, "building", 0, 3, 0},
+		{1, 400, "building", 0, 5, 1},
+		{2, 350, "army", 1, 2, 0},
+	} {
+		mustExec(t, conn, `
+			INSERT INTO hotkey_usage (replay_id, player_id, hotkey_group, window_end_second, second,
+				role, assigns, selects, adds, camera_jumps)
+			VALUES (?, ?, ?, 800, ?, ?, ?, ?, 0, ?)`, replayID, boxerID, use.group, use.second, use.role, use.assigns, use.selects, use.jumps)
+	}
+	seedMarker(t, conn, replayID, nil, "mid_game_starts", 300, nil)
+
+	usage, err := s.ListReplayHotkeyUsage(ctx, replayID)
+	if err != nil {
+		t.Fatalf("ListReplayHotkeyUsage: %v", err)
+	}
+	if len(usage) != 4 || usage[0].Second != 20 || usage[0].Role != "building" || usage[3].HotkeyGroup != 2 || usage[0].PlayerID != boxerID {
+		t.Fatalf("replay hotkey usage = %+v", usage)
+	}
+
+	rows, err := s.ListPlayerHotkeyGroupTotals(ctx, "boxer")
+	if err != nil {
+		t.Fatalf("ListPlayerHotkeyGroupTotals: %v", err)
+	}
+	if len(rows) != 3 || rows[0].ReplayID != replayID || rows[0].OppRace == "" || rows[0].WindowEndSecond != 800 {
+		t.Fatalf("hotkey group totals = %+v", rows)
+	}
+	if rows[0].Phase != 0 || rows[0].Selects != 3 || rows[0].Assigns != 1 || rows[1].Phase != 1 || rows[1].Selects != 5 || rows[1].CameraJumps != 1 || rows[2].Role != "army" {
+		t.Errorf("hotkey group totals = %+v; want group 1 early 1+3, mid 5 with a jump, then group 2 army", rows)
+	}
+}
+
 func TestListViewportGameRowsFiltersEmptyPayload(t *testing.T) {
 	s, conn := newTestStore(t)
 	ctx := context.Background()
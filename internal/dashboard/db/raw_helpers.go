@@ -24,6 +24,9 @@ func ApplyReplayTempViews(db *sql.DB, qualifiedFilterSQL string) error {
 	if _, err := db.Exec(`CREATE TEMP VIEW commands_low_value AS SELECT * FROM main.commands_low_value WHERE replay_id IN (SELECT id FROM replays)`); err != nil {
 		return err
 	}
+	if _, err := db.Exec(`CREATE TEMP VIEW commands_low_value_all AS SELECT * FROM main.commands_low_value_all WHERE replay_id IN (SELECT id FROM replays)`); err != nil {
+		return err
+	}
 	if _, err := db.Exec(`CREATE TEMP VIEW replay_events AS SELECT * FROM main.replay_events WHERE replay_id IN (SELECT id FROM replays)`); err != nil {
 		return err
 	}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"fmt"
 	"strings"
 
 	"github.com/marianogappa/screpdb/internal/dashboard/db/sqlcgen"
@@ -26,10 +27,28 @@ func (s *Store) CountReplays(ctx context.Context) (int64, error) {
 	return sqlcgen.New(Trace(s.defaultDB)).CountReplays(ctx)
 }
 
+// ReplayGeneration returns the replay corpus's generation (see
+// replay/000011_replay_generation) as "epoch:counter". It changes with every
+// write to replays.
+func (s *Store) ReplayGeneration(ctx context.Context) (string, error) {
+	row, err := sqlcgen.New(Trace(s.defaultDB)).GetReplayGeneration(ctx)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%d", row.Epoch, row.Generation), nil
+}
+
 func (s *Store) GetReplayFilePathByID(ctx context.Context, replayID int64) (string, error) {
 	return sqlcgen.New(Trace(s.replayScoped())).GetReplayFilePathByID(ctx, replayID)
 }
 
+// GetReplayGameGroupID returns the id of the canonical replay of the game
+// replayID recorded. It reads the unfiltered database so that every POV of a
+// game resolves, not only the canonical one the replay filter lets through.
+func (s *Store) GetReplayGameGroupID(ctx context.Context, replayID int64) (int64, error) {
+	return sqlcgen.New(Trace(s.defaultDB)).GetReplayGameGroupID(ctx, replayID)
+}
+
 type PlayerColorRow struct {
 	PlayerKey string
 	Games     int64
@@ -17,9 +17,12 @@ func TestBuildGlobalReplayFilterSQL(t *testing.T) {
 		wantMissing       []string
 	}{
 		{
-			name:         "no filters keeps only the hardcoded UMS exclusion",
-			wantContains: []string{"SELECT r.* FROM replays r", "WHERE r.map_kind != 'UseMapSettings'"},
-			wantMissing:  []string{"duration_seconds", "computer", "game_type", "AND"},
+			name: "no filters keeps only the hardcoded UMS and duplicate-POV exclusions",
+			wantContains: []string{
+				"SELECT r.* FROM replays r",
+				"WHERE r.map_kind != 'UseMapSettings' AND COALESCE(r.game_group_id, r.id) = r.id",
+			},
+			wantMissing: []string{"duration_seconds", "computer", "game_type", " OR "},
 		},
 		{
 			name:              "short games and computers",
@@ -121,6 +124,59 @@ func TestBuildWorkflowPlayersListBaseSQL(t *testing.T) {
 	}
 }
 
+func TestBuildPlayerAggregateClassSQL(t *testing.T) {
+	tests := []struct {
+		name              string
+		excludeShortGames bool
+		excludeComputers  bool
+		gameTypes         []string
+		mapKinds          []string
+		want              string
+	}{
+		{
+			name: "no filters keeps only the hardcoded UMS and duplicate-POV exclusions",
+			want: "map_kind != 'UseMapSettings' AND is_canonical = 1",
+		},
+		{
+			name:              "short games and computers",
+			excludeShortGames: true,
+			excludeComputers:  true,
+			want:              "map_kind != 'UseMapSettings' AND is_canonical = 1 AND is_short = 0 AND has_computers = 0",
+		},
+		{
+			name:      "game types and map kinds",
+			gameTypes: []string{"melee", "one_on_one"},
+			mapKinds:  []string{"money"},
+			want:      "map_kind != 'UseMapSettings' AND is_canonical = 1 AND (game_type = 'melee' OR is_one_on_one = 1) AND (map_kind = 'Money')",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BuildPlayerAggregateClassSQL(tt.excludeShortGames, tt.excludeComputers, tt.gameTypes, tt.mapKinds); got != tt.want {
+				t.Errorf("BuildPlayerAggregateClassSQL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildWorkflowPlayersListAggregateBaseSQL(t *testing.T) {
+	sqlText, args := BuildWorkflowPlayersListAggregateBaseSQL("", "is_canonical = 1")
+	if len(args) != 0 {
+		t.Errorf("expected no args for empty filter, got %v", args)
+	}
+	if !strings.Contains(sqlText, "FROM player_aggregates") || !strings.Contains(sqlText, "WHERE is_canonical = 1") {
+		t.Errorf("aggregate base SQL should read player_aggregates under the class predicate: %s", sqlText)
+	}
+
+	sqlText, args = BuildWorkflowPlayersListAggregateBaseSQL("boxer", "is_canonical = 1")
+	if len(args) != 1 || args[0] != "%boxer%" {
+		t.Errorf("expected LIKE arg %q, got %v", "%boxer%", args)
+	}
+	if !strings.Contains(sqlText, "player_key LIKE ?") {
+		t.Errorf("name filter should add a LIKE clause: %s", sqlText)
+	}
+}
+
 func TestBuildWorkflowPlayersListWhere(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -23,6 +23,25 @@ WHERE lower(trim(p.name)) = ?
   AND re.event_type NOT IN ('used_hotkey_groups', 'viewport_multitasking')
 GROUP BY p.race, re.event_type;
 
+-- name: ListReplayEconomySamples :many
+-- The estimated economy timeline of each player of one replay, persisted at
+-- ingest by earlyfilter.SimulateEconomy. Replays ingested before the table
+-- existed have no rows until re-analyzed; the game page hides the chart then.
+SELECT
+  es.player_id,
+  es.second,
+  es.minerals,
+  es.gas,
+  es.mineral_income,
+  es.gas_income,
+  es.supply_used,
+  es.supply_max,
+  es.workers,
+  es.bases
+FROM player_economy_samples es
+WHERE es.replay_id = ?
+ORDER BY es.player_id, es.second;
+
 -- name: ListTopActionTypes :many
 SELECT c.action_type, COUNT(*) AS n
 FROM commands c
@@ -0,0 +1,74 @@
+-- name: ListReplayHotkeyUsage :many
+-- One replay's hotkey group use per player, group and second, written at
+-- ingest from the hotkey commands and selection tags (see
+-- parser.BuildHotkeyUsage).
+SELECT
+  h.player_id,
+  h.hotkey_group,
+  h.window_end_second,
+  h.second,
+  h.role,
+  h.assigns,
+  h.selects,
+  h.adds,
+  h.camera_jumps
+FROM hotkey_usage h
+WHERE h.replay_id = ?
+ORDER BY h.player_id ASC, h.hotkey_group ASC, h.second ASC;
+
+-- name: ListPlayerHotkeyGroupTotals :many
+-- One player's hotkey use per game, group, phase and role (phase 0 early,
+-- 1 mid, 2 late, as cut by the replay's mid_game_starts and late_game_starts
+-- markers; a game without them is all early). opp_race is the opponent's race
+-- in 1v1 games and empty otherwise, so only 1v1 games have a matchup.
+SELECT
+  h.replay_id,
+  self.race AS own_race,
+  CAST(COALESCE((
+    SELECT opp.race
+    FROM players opp
+    WHERE opp.replay_id = self.replay_id
+      AND opp.id != self.id
+      AND opp.is_observer = 0
+      AND lower(trim(coalesce(opp.type, ''))) = 'human'
+      AND 2 = (
+        SELECT COUNT(*) FROM players p2
+        WHERE p2.replay_id = self.replay_id
+          AND p2.is_observer = 0
+          AND lower(trim(coalesce(p2.type, ''))) = 'human'
+      )
+    LIMIT 1
+  ), '') AS TEXT) AS opp_race,
+  CAST(COALESCE(b.mid_starts_at, 0) AS INTEGER) AS mid_starts_at,
+  CAST(COALESCE(b.late_starts_at, 0) AS INTEGER) AS late_starts_at,
+  CAST(MAX(h.window_end_second) AS INTEGER) AS window_end_second,
+  h.hotkey_group,
+  CAST(CASE
+    WHEN COALESCE(b.late_starts_at, 0) > 0 AND h.second >= b.late_starts_at THEN 2
+    WHEN COALESCE(b.mid_starts_at, 0) > 0 AND h.second >= b.mid_starts_at THEN 1
+    ELSE 0
+  END AS INTEGER) AS phase,
+  h.role,
+  CAST(SUM(h.assigns) AS INTEGER) AS assigns,
+  CAST(SUM(h.selects) AS INTEGER) AS selects,
+  CAST(SUM(h.adds) AS INTEGER) AS adds,
+  CAST(SUM(h.camera_jumps) AS INTEGER) AS camera_jumps
+FROM hotkey_usage h
+JOIN players self
+  ON self.id = h.player_id
+LEFT JOIN (
+  SELECT
+    m.replay_id,
+    MIN(CASE WHEN m.event_type = 'mid_game_starts' THEN m.seconds_from_game_start END) AS mid_starts_at,
+    MIN(CASE WHEN m.event_type = 'late_game_starts' THEN m.seconds_from_game_start END) AS late_starts_at
+  FROM replay_events m
+  WHERE m.event_kind = 'marker'
+    AND m.event_type IN ('mid_game_starts', 'late_game_starts')
+  GROUP BY m.replay_id
+) b
+  ON b.replay_id = h.replay_id
+WHERE lower(trim(self.name)) = ?
+  AND self.is_observer = 0
+  AND lower(trim(coalesce(self.type, ''))) = 'human'
+GROUP BY h.replay_id, self.id, self.race, b.mid_starts_at, b.late_starts_at, h.hotkey_group, phase, h.role
+ORDER BY h.replay_id ASC, h.hotkey_group ASC, phase ASC, h.role ASC;
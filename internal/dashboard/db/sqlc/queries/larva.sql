@@ -0,0 +1,50 @@
+-- name: ListReplayLarvaSamples :many
+-- The estimated larva timelines of one replay's Zerg hatcheries, written at
+-- ingest from selection tags and the larva timer (see unittags.HatcheryLarvae).
+SELECT
+  l.player_id,
+  l.hatchery_index,
+  l.window_end_second,
+  l.second,
+  l.larvae,
+  l.spawned,
+  l.used,
+  l.missed
+FROM larva_samples l
+WHERE l.replay_id = ?
+ORDER BY l.player_id ASC, l.hatchery_index ASC, l.second ASC;
+
+-- name: ListLarvaPlayerPhaseTotals :many
+-- Every human player's larvae spawned, used and missed per game and phase
+-- (0 early, 1 mid, 2 late, as cut by the replay's mid_game_starts and
+-- late_game_starts markers; a game without them is all early).
+SELECT
+  lower(trim(p.name)) AS player_key,
+  p.name AS player_name,
+  l.replay_id,
+  CAST(CASE
+    WHEN COALESCE(b.late_starts_at, 0) > 0 AND l.second >= b.late_starts_at THEN 2
+    WHEN COALESCE(b.mid_starts_at, 0) > 0 AND l.second >= b.mid_starts_at THEN 1
+    ELSE 0
+  END AS INTEGER) AS phase,
+  CAST(SUM(l.spawned) AS INTEGER) AS spawned,
+  CAST(SUM(l.used) AS INTEGER) AS used,
+  CAST(SUM(l.missed) AS INTEGER) AS missed
+FROM larva_samples l
+JOIN players p
+  ON p.id = l.player_id
+LEFT JOIN (
+  SELECT
+    m.replay_id,
+    MIN(CASE WHEN m.event_type = 'mid_game_starts' THEN m.seconds_from_game_start END) AS mid_starts_at,
+    MIN(CASE WHEN m.event_type = 'late_game_starts' THEN m.seconds_from_game_start END) AS late_starts_at
+  FROM replay_events m
+  WHERE m.event_kind = 'marker'
+    AND m.event_type IN ('mid_game_starts', 'late_game_starts')
+  GROUP BY m.replay_id
+) b
+  ON b.replay_id = l.replay_id
+WHERE p.is_observer = 0
+  AND lower(trim(coalesce(p.type, ''))) = 'human'
+GROUP BY player_key, p.name, l.replay_id, phase
+ORDER BY player_key ASC, l.replay_id ASC, phase ASC;
@@ -0,0 +1,48 @@
+-- name: ListReplayProductionBusySpans :many
+-- The estimated busy spans of one replay's Gateways, Barracks and Factories,
+-- written at ingest from selection tags (see unittags.ProducerTimelines).
+SELECT
+  s.player_id,
+  s.building,
+  s.building_index,
+  s.ready_second,
+  s.window_end_second,
+  s.start_second,
+  s.end_second
+FROM production_busy_spans s
+WHERE s.replay_id = ?
+ORDER BY s.player_id ASC, s.building ASC, s.building_index ASC, s.start_second ASC;
+
+-- name: ListProductionIdlePlayerRows :many
+-- Every busy span of every human player, with the replay's phase boundaries
+-- (0 when the game never reached them) so idle time can be split by phase.
+SELECT
+  lower(trim(p.name)) AS player_key,
+  p.name AS player_name,
+  s.replay_id,
+  s.building,
+  s.building_index,
+  s.ready_second,
+  s.window_end_second,
+  s.start_second,
+  s.end_second,
+  CAST(COALESCE((
+    SELECT MIN(m.seconds_from_game_start)
+    FROM replay_events m
+    WHERE m.replay_id = s.replay_id
+      AND m.event_kind = 'marker'
+      AND m.event_type = 'mid_game_starts'
+  ), 0) AS INTEGER) AS early_ends_at_second,
+  CAST(COALESCE((
+    SELECT MIN(m.seconds_from_game_start)
+    FROM replay_events m
+    WHERE m.replay_id = s.replay_id
+      AND m.event_kind = 'marker'
+      AND m.event_type = 'late_game_starts'
+  ), 0) AS INTEGER) AS mid_ends_at_second
+FROM production_busy_spans s
+JOIN players p
+  ON p.id = s.player_id
+WHERE p.is_observer = 0
+  AND lower(trim(coalesce(p.type, ''))) = 'human'
+ORDER BY player_key ASC, s.replay_id ASC, s.building ASC, s.building_index ASC, s.start_second ASC;
@@ -17,6 +17,16 @@ SELECT file_path
 FROM replays
 WHERE id = ?;
 
+-- name: GetReplayGameGroupID :one
+SELECT COALESCE(game_group_id, id) AS game_group_id
+FROM replays
+WHERE id = ?;
+
+-- name: GetReplayGeneration :one
+SELECT epoch, generation
+FROM replay_generation
+WHERE id = 1;
+
 -- name: ListTopPlayerColorRows :many
 SELECT lower(trim(name)) AS player_key, COUNT(*) AS games
 FROM players
@@ -0,0 +1,43 @@
+-- name: ListReplaySupplyBlocks :many
+-- The supply_block game events of one replay, written at ingest from the
+-- economy simulation's supply blocks. payload is {"duration_seconds": N,
+-- "supply": M}.
+SELECT
+  re.source_player_id AS player_id,
+  re.seconds_from_game_start AS second,
+  COALESCE(re.payload, '') AS payload
+FROM replay_events re
+WHERE re.replay_id = ?
+  AND re.event_type = 'supply_block'
+  AND re.source_player_id IS NOT NULL
+ORDER BY re.seconds_from_game_start ASC, re.id ASC;
+
+-- name: ListSupplyBlockPlayerRows :many
+-- One row per supply_block event starting before the window for every human
+-- player of a game at least as long, or one row with a NULL block when the
+-- player had none. Only players with economy samples count: they were
+-- analyzed by the simulation that finds blocks.
+SELECT
+  lower(trim(p.name)) AS player_key,
+  p.name AS player_name,
+  p.replay_id,
+  re.seconds_from_game_start AS block_second,
+  re.payload AS block_payload
+FROM players p
+JOIN replays r
+  ON r.id = p.replay_id
+LEFT JOIN replay_events re
+  ON re.replay_id = p.replay_id
+  AND re.source_player_id = p.id
+  AND re.event_type = 'supply_block'
+  AND re.seconds_from_game_start < ?
+WHERE p.is_observer = 0
+  AND lower(trim(coalesce(p.type, ''))) = 'human'
+  AND r.duration_seconds >= ?
+  AND EXISTS (
+    SELECT 1
+    FROM player_economy_samples es
+    WHERE es.replay_id = p.replay_id
+      AND es.player_id = p.id
+  )
+ORDER BY player_key ASC, p.replay_id ASC, block_second ASC;
@@ -34,7 +34,15 @@ CREATE TABLE replays (
   team_format TEXT NOT NULL DEFAULT '',
   team_stacking BOOLEAN NOT NULL DEFAULT 0,
   team_info_incomplete BOOLEAN NOT NULL DEFAULT 0,
-  analyzer_algorithm_version INTEGER NOT NULL DEFAULT 0
+  analyzer_algorithm_version INTEGER NOT NULL DEFAULT 0,
+  game_fingerprint TEXT NOT NULL DEFAULT '',
+  game_group_id INTEGER
+);
+
+CREATE TABLE replay_generation (
+  id INTEGER PRIMARY KEY CHECK (id = 1),
+  epoch TEXT NOT NULL,
+  generation INTEGER NOT NULL DEFAULT 0
 );
 
 CREATE TABLE players (
@@ -53,6 +61,60 @@ CREATE TABLE players (
   slot_id INTEGER NOT NULL DEFAULT 0
 );
 
+CREATE TABLE player_economy_samples (
+  replay_id INTEGER NOT NULL,
+  player_id INTEGER NOT NULL,
+  second INTEGER NOT NULL,
+  minerals INTEGER NOT NULL,
+  gas INTEGER NOT NULL,
+  mineral_income INTEGER NOT NULL,
+  gas_income INTEGER NOT NULL,
+  supply_used INTEGER NOT NULL,
+  supply_max INTEGER NOT NULL,
+  workers INTEGER NOT NULL,
+  bases INTEGER NOT NULL,
+  PRIMARY KEY (replay_id, player_id, second)
+);
+
+CREATE TABLE production_busy_spans (
+  replay_id INTEGER NOT NULL,
+  player_id INTEGER NOT NULL,
+  building TEXT NOT NULL,
+  building_index INTEGER NOT NULL,
+  ready_second INTEGER NOT NULL,
+  window_end_second INTEGER NOT NULL,
+  start_second INTEGER NOT NULL,
+  end_second INTEGER NOT NULL,
+  PRIMARY KEY (replay_id, player_id, building, building_index, start_second)
+);
+
+CREATE TABLE larva_samples (
+  replay_id INTEGER NOT NULL,
+  player_id INTEGER NOT NULL,
+  hatchery_index INTEGER NOT NULL,
+  window_end_second INTEGER NOT NULL,
+  second INTEGER NOT NULL,
+  larvae INTEGER NOT NULL,
+  spawned INTEGER NOT NULL,
+  used INTEGER NOT NULL,
+  missed INTEGER NOT NULL,
+  PRIMARY KEY (replay_id, player_id, hatchery_index, second)
+);
+
+CREATE TABLE hotkey_usage (
+  replay_id INTEGER NOT NULL,
+  player_id INTEGER NOT NULL,
+  hotkey_group INTEGER NOT NULL,
+  window_end_second INTEGER NOT NULL,
+  second INTEGER NOT NULL,
+  role TEXT NOT NULL,
+  assigns INTEGER NOT NULL,
+  selects INTEGER NOT NULL,
+  adds INTEGER NOT NULL,
+  camera_jumps INTEGER NOT NULL,
+  PRIMARY KEY (replay_id, player_id, hotkey_group, second)
+);
+
 CREATE TABLE player_aliases (
   id INTEGER PRIMARY KEY AUTOINCREMENT,
   canonical_alias TEXT NOT NULL,
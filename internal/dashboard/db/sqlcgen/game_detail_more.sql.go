@@ -394,6 +394,73 @@ func (q *Queries) ListRaceSections(ctx context.Context, name string) ([]ListRace
 	return items, nil
 }
 
+const ListReplayEconomySamples = `-- name: ListReplayEconomySamples :many
+SELECT
+  es.player_id,
+  es.second,
+  es.minerals,
+  es.gas,
+  es.mineral_income,
+  es.gas_income,
+  es.supply_used,
+  es.supply_max,
+  es.workers,
+  es.bases
+FROM player_economy_samples es
+WHERE es.replay_id = ?
+ORDER BY es.player_id, es.second
+`
+
+type ListReplayEconomySamplesRow struct {
+	PlayerID      int64
+	Second        int64
+	Minerals      int64
+	Gas           int64
+	MineralIncome int64
+	GasIncome     int64
+	SupplyUsed    int64
+	SupplyMax     int64
+	Workers       int64
+	Bases         int64
+}
+
+// The estimated economy timeline of each player of one replay, persisted at
+// ingest by earlyfilter.SimulateEconomy. Replays ingested before the table
+// existed have no rows until re-analyzed; the game page hides the chart then.
+func (q *Queries) ListReplayEconomySamples(ctx context.Context, replayID int64) ([]ListReplayEconomySamplesRow, error) {
+	rows, err := q.db.QueryContext(ctx, ListReplayEconomySamples, replayID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListReplayEconomySamplesRow{}
+	for rows.Next() {
+		var i ListReplayEconomySamplesRow
+		if err := rows.Scan(
+			&i.PlayerID,
+			&i.Second,
+			&i.Minerals,
+			&i.Gas,
+			&i.MineralIncome,
+			&i.GasIncome,
+			&i.SupplyUsed,
+			&i.SupplyMax,
+			&i.Workers,
+			&i.Bases,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const ListTopActionTypes = `-- name: ListTopActionTypes :many
 SELECT c.action_type, COUNT(*) AS n
 FROM commands c
@@ -37,6 +37,31 @@ type CommandsLowValue struct {
 	AlliancePlayerIds    *string
 }
 
+type HotkeyUsage struct {
+	ReplayID        int64
+	PlayerID        int64
+	HotkeyGroup     int64
+	WindowEndSecond int64
+	Second          int64
+	Role            string
+	Assigns         int64
+	Selects         int64
+	Adds            int64
+	CameraJumps     int64
+}
+
+type LarvaSample struct {
+	ReplayID        int64
+	PlayerID        int64
+	HatcheryIndex   int64
+	WindowEndSecond int64
+	Second          int64
+	Larvae          int64
+	Spawned         int64
+	Used            int64
+	Missed          int64
+}
+
 type Player struct {
 	ID                  int64
 	ReplayID            int64
@@ -63,6 +88,31 @@ type PlayerAlias struct {
 	UpdatedAt           string
 }
 
+type PlayerEconomySample struct {
+	ReplayID      int64
+	PlayerID      int64
+	Second        int64
+	Minerals      int64
+	Gas           int64
+	MineralIncome int64
+	GasIncome     int64
+	SupplyUsed    int64
+	SupplyMax     int64
+	Workers       int64
+	Bases         int64
+}
+
+type ProductionBusySpan struct {
+	ReplayID        int64
+	PlayerID        int64
+	Building        string
+	BuildingIndex   int64
+	ReadySecond     int64
+	WindowEndSecond int64
+	StartSecond     int64
+	EndSecond       int64
+}
+
 type Replay struct {
 	ID                       int64
 	FilePath                 string
@@ -78,6 +128,8 @@ type Replay struct {
 	TeamStacking             bool
 	TeamInfoIncomplete       bool
 	AnalyzerAlgorithmVersion int64
+	GameFingerprint          string
+	GameGroupID              *int64
 }
 
 type ReplayEvent struct {
@@ -97,6 +149,12 @@ type ReplayEvent struct {
 	Payload                 *string
 }
 
+type ReplayGeneration struct {
+	ID         int64
+	Epoch      string
+	Generation int64
+}
+
 type Setting struct {
 	ConfigKey                string
 	GameType                 string
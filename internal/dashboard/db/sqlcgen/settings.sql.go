@@ -47,6 +47,37 @@ func (q *Queries) GetReplayFilePathByID(ctx context.Context, id int64) (string,
 	return file_path, err
 }
 
+const GetReplayGameGroupID = `-- name: GetReplayGameGroupID :one
+SELECT COALESCE(game_group_id, id) AS game_group_id
+FROM replays
+WHERE id = ?
+`
+
+func (q *Queries) GetReplayGameGroupID(ctx context.Context, id int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, GetReplayGameGroupID, id)
+	var game_group_id int64
+	err := row.Scan(&game_group_id)
+	return game_group_id, err
+}
+
+const GetReplayGeneration = `-- name: GetReplayGeneration :one
+SELECT epoch, generation
+FROM replay_generation
+WHERE id = 1
+`
+
+type GetReplayGenerationRow struct {
+	Epoch      string
+	Generation int64
+}
+
+func (q *Queries) GetReplayGeneration(ctx context.Context) (GetReplayGenerationRow, error) {
+	row := q.db.QueryRowContext(ctx, GetReplayGeneration)
+	var i GetReplayGenerationRow
+	err := row.Scan(&i.Epoch, &i.Generation)
+	return i, err
+}
+
 const ListTopPlayerColorRows = `-- name: ListTopPlayerColorRows :many
 SELECT lower(trim(name)) AS player_key, COUNT(*) AS games
 FROM players
@@ -120,6 +120,12 @@ func (s *Store) ReplayQueryRowContext(ctx context.Context, query string, args ..
 	return &Row{row: row}
 }
 
+// ReplayDB returns the replay-scoped database, for packages that run their own
+// queries against it (internal/chatsearch).
+func (s *Store) ReplayDB() *sql.DB {
+	return s.replayScoped()
+}
+
 func (s *Store) WithFilteredConnection(replaysFilterSQL *string, fn func(*sql.DB) error) error {
 	return s.withFiltered(replaysFilterSQL, fn)
 }
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+
+	"github.com/marianogappa/screpdb/internal/dashboard/db/sqlcgen"
+)
+
+// SupplyBlockRow is one supply_block game event: the blocked player, the
+// second the block began and its raw JSON payload.
+type SupplyBlockRow struct {
+	PlayerID int64
+	Second   int64
+	Payload  string
+}
+
+// ListReplaySupplyBlocks returns the supply blocks of one replay, ordered by
+// start.
+func (s *Store) ListReplaySupplyBlocks(ctx context.Context, replayID int64) ([]SupplyBlockRow, error) {
+	sqlcRows, err := sqlcgen.New(Trace(s.replayScoped())).ListReplaySupplyBlocks(ctx, replayID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]SupplyBlockRow, 0, len(sqlcRows))
+	for _, row := range sqlcRows {
+		var playerID int64
+		if row.PlayerID != nil {
+			playerID = *row.PlayerID
+		}
+		out = append(out, SupplyBlockRow{
+			PlayerID: playerID,
+			Second:   row.Second,
+			Payload:  row.Payload,
+		})
+	}
+	return out, nil
+}
+
+// SupplyBlockPlayerRow is one player-game of the supply-block population,
+// with one of its blocks. BlockSecond is nil when the player had none in the
+// window.
+type SupplyBlockPlayerRow struct {
+	PlayerKey    string
+	PlayerName   string
+	ReplayID     int64
+	BlockSecond  *int64
+	BlockPayload string
+}
+
+// ListSupplyBlockPlayerRows returns, for every human player with economy
+// samples in a game lasting at least windowSeconds, the supply blocks that
+// began before windowSeconds (or a single block-less row).
+func (s *Store) ListSupplyBlockPlayerRows(ctx context.Context, windowSeconds int64) ([]SupplyBlockPlayerRow, error) {
+	sqlcRows, err := sqlcgen.New(Trace(s.replayScoped())).ListSupplyBlockPlayerRows(ctx, sqlcgen.ListSupplyBlockPlayerRowsParams{
+		SecondsFromGameStart: windowSeconds,
+		DurationSeconds:      windowSeconds,
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]SupplyBlockPlayerRow, 0, len(sqlcRows))
+	for _, row := range sqlcRows {
+		entry := SupplyBlockPlayerRow{
+			PlayerKey:   row.PlayerKey,
+			PlayerName:  row.PlayerName,
+			ReplayID:    row.ReplayID,
+			BlockSecond: row.BlockSecond,
+		}
+		if row.BlockPayload != nil {
+			entry.BlockPayload = *row.BlockPayload
+		}
+		out = append(out, entry)
+	}
+	return out, nil
+}
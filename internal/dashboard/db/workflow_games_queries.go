@@ -63,6 +63,15 @@ type WorkflowCurrentPlayerPatternRow struct {
 	Payload        string
 }
 
+// GamePOVRow is one replay file recording a game, keyed by the game's
+// canonical replay id.
+type GamePOVRow struct {
+	GameGroupID int64
+	ReplayID    int64
+	FileName    string
+	FilePath    string
+}
+
 type WorkflowFilterOptionRow struct {
 	Key   string
 	Label string
@@ -239,6 +248,42 @@ func (s *Store) ListReplayPlayers(ctx context.Context, replayIDs []int64) ([]Wor
 	return result, nil
 }
 
+// ListGamePOVs returns every replay recording the games whose canonical
+// replay ids are given, canonical replays first. It reads the unfiltered
+// database: the replay filter hides every POV but the canonical one.
+func (s *Store) ListGamePOVs(ctx context.Context, gameGroupIDs []int64) ([]GamePOVRow, error) {
+	if len(gameGroupIDs) == 0 {
+		return []GamePOVRow{}, nil
+	}
+	placeholders := strings.TrimRight(strings.Repeat("?,", len(gameGroupIDs)), ",")
+	args := make([]any, 0, len(gameGroupIDs))
+	for _, id := range gameGroupIDs {
+		args = append(args, id)
+	}
+	rows, err := s.DefaultQueryContext(ctx, `
+		SELECT COALESCE(game_group_id, id) AS group_id, id, file_name, file_path
+		FROM replays
+		WHERE COALESCE(game_group_id, id) IN (`+placeholders+`)
+		ORDER BY group_id ASC, id <> COALESCE(game_group_id, id), id ASC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	result := []GamePOVRow{}
+	for rows.Next() {
+		var row GamePOVRow
+		if err := rows.Scan(&row.GameGroupID, &row.ReplayID, &row.FileName, &row.FilePath); err != nil {
+			return nil, err
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 func (s *Store) ListFeaturingPlayerPatternRows(ctx context.Context, replayIDs []int64) ([]WorkflowPlayerPatternRow, error) {
 	if len(replayIDs) == 0 {
 		return []WorkflowPlayerPatternRow{}, nil
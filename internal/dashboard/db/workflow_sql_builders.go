@@ -26,7 +26,53 @@ func BuildWorkflowPlayersListBaseSQL(nameContainsNormalized string) (string, []a
 		baseWhere = append(baseWhere, "lower(trim(p.name)) LIKE ?")
 		args = append(args, "%"+nameContainsNormalized+"%")
 	}
-	sqlText := `
+	return workflowPlayersListSQL(`
+			SELECT
+				lower(trim(p.name)) AS player_key,
+				MIN(p.name) AS player_name,
+				COUNT(*) AS games_played,
+				COALESCE(AVG(CASE WHEN p.apm > 0 THEN p.apm END), 0) AS average_apm,
+				MAX(r.replay_date) AS last_played,
+				SUM(CASE WHEN lower(trim(p.race)) = 'protoss' THEN 1 ELSE 0 END) AS protoss_games,
+				SUM(CASE WHEN lower(trim(p.race)) = 'terran' THEN 1 ELSE 0 END) AS terran_games,
+				SUM(CASE WHEN lower(trim(p.race)) = 'zerg' THEN 1 ELSE 0 END) AS zerg_games
+			FROM players p
+			JOIN replays r ON r.id = p.replay_id
+			WHERE ` + strings.Join(baseWhere, " AND ") + `
+			GROUP BY lower(trim(p.name))
+	`), args
+}
+
+// BuildWorkflowPlayersListAggregateBaseSQL is BuildWorkflowPlayersListBaseSQL
+// over the player_aggregates rows matching classSQL (see
+// BuildPlayerAggregateClassSQL).
+func BuildWorkflowPlayersListAggregateBaseSQL(nameContainsNormalized string, classSQL string) (string, []any) {
+	baseWhere := []string{classSQL}
+	args := []any{}
+	if nameContainsNormalized != "" {
+		baseWhere = append(baseWhere, "player_key LIKE ?")
+		args = append(args, "%"+nameContainsNormalized+"%")
+	}
+	return workflowPlayersListSQL(`
+			SELECT
+				player_key,
+				MIN(player_name) AS player_name,
+				SUM(games) AS games_played,
+				COALESCE(SUM(apm_sum) * 1.0 / NULLIF(SUM(apm_games), 0), 0) AS average_apm,
+				MAX(last_played) AS last_played,
+				SUM(protoss_games) AS protoss_games,
+				SUM(terran_games) AS terran_games,
+				SUM(zerg_games) AS zerg_games
+			FROM player_aggregates
+			WHERE ` + strings.Join(baseWhere, " AND ") + `
+			GROUP BY player_key
+	`), args
+}
+
+// workflowPlayersListSQL derives the players list columns from grouped, one
+// row per player with its games, race mix, average APM and last replay date.
+func workflowPlayersListSQL(grouped string) string {
+	return `
 		SELECT
 			player_key,
 			player_name,
@@ -41,23 +87,8 @@ func BuildWorkflowPlayersListBaseSQL(nameContainsNormalized string) (string, []a
 				ELSE 'Random'
 			END AS race,
 			COALESCE(CAST(julianday('now') - julianday(substr(last_played, 1, 19)) AS INTEGER), 0) AS last_played_days_ago
-		FROM (
-			SELECT
-				lower(trim(p.name)) AS player_key,
-				MIN(p.name) AS player_name,
-				COUNT(*) AS games_played,
-				COALESCE(AVG(CASE WHEN p.apm > 0 THEN p.apm END), 0) AS average_apm,
-				MAX(r.replay_date) AS last_played,
-				SUM(CASE WHEN lower(trim(p.race)) = 'protoss' THEN 1 ELSE 0 END) AS protoss_games,
-				SUM(CASE WHEN lower(trim(p.race)) = 'terran' THEN 1 ELSE 0 END) AS terran_games,
-				SUM(CASE WHEN lower(trim(p.race)) = 'zerg' THEN 1 ELSE 0 END) AS zerg_games
-			FROM players p
-			JOIN replays r ON r.id = p.replay_id
-			WHERE ` + strings.Join(baseWhere, " AND ") + `
-			GROUP BY lower(trim(p.name))
-		) grouped
+		FROM (` + grouped + `) grouped
 	`
-	return sqlText, args
 }
 
 func BuildWorkflowPlayersListWhere(onlyFivePlus bool, lastPlayedBuckets []string) (string, []any) {
@@ -0,0 +1,95 @@
+package dashboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/marianogappa/screpdb/internal/backup"
+	"github.com/marianogappa/screpdb/internal/dashboard/apigen"
+	dashboardservice "github.com/marianogappa/screpdb/internal/dashboard/service"
+	"github.com/marianogappa/screpdb/internal/iofacade"
+)
+
+// StartScheduledBackups takes a rotating backup of the database every
+// interval while the dashboard runs, keeping the newest keep. keep also bounds
+// backups triggered from the dashboard; interval <= 0 only disables the
+// schedule.
+func (d *Dashboard) StartScheduledBackups(interval time.Duration, keep int) {
+	d.backupKeep = keep
+	if interval <= 0 {
+		return
+	}
+	go backup.Schedule(d.ctx, d.sqlitePath, interval, keep)
+}
+
+// ListBackups returns the rotating backups in the app-data backups folder,
+// newest first.
+func (d *Dashboard) ListBackups(_ context.Context, _ apigen.ListBackupsRequestObject) (any, error) {
+	dir, err := backup.Dir()
+	if err != nil {
+		return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
+	}
+	backups, err := backup.List(dir)
+	if err != nil {
+		return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
+	}
+	return map[string]any{
+		"dir":     dir,
+		"count":   len(backups),
+		"backups": backups,
+	}, nil
+}
+
+// CreateBackup takes a rotating backup now. The dashboard keeps serving (and
+// ingesting) while it runs; download it from /api/custom/backups/{name}.
+func (d *Dashboard) CreateBackup(ctx context.Context, _ apigen.CreateBackupRequestObject) (any, error) {
+	dir, err := backup.Dir()
+	if err != nil {
+		return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
+	}
+	keep := d.backupKeep
+	if keep == 0 {
+		keep = backup.DefaultKeep
+	}
+	info, err := backup.Rotate(ctx, d.sqlitePath, dir, keep)
+	if err != nil {
+		return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
+	}
+	return map[string]any{"ok": true, "backup": info}, nil
+}
+
+// handlerDownloadBackup serves a rotating backup as an attachment. Registered
+// as a manual route because the response is a file, not JSON.
+func (d *Dashboard) handlerDownloadBackup(w http.ResponseWriter, r *http.Request) {
+	dir, err := backup.Dir()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	info, err := backup.Lookup(dir, mux.Vars(r)["name"])
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f, err := iofacade.Open(info.Path)
+	if err != nil {
+		log.Printf("backup download: %v", err)
+		http.Error(w, "failed to open backup", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", info.Name))
+	http.ServeContent(w, r, info.Name, info.CreatedAt, f)
+}
@@ -2,6 +2,7 @@ package dashboard
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 )
 
@@ -10,7 +11,11 @@ import (
 // OpenAPI spec) so the request-validation middleware falls through to it.
 func (d *Dashboard) handlerLoadSampleSet(w http.ResponseWriter, r *http.Request) {
 	if err := d.loadSampleSet(r.Context()); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		status := http.StatusInternalServerError
+		if errors.Is(err, errSampleSetWithDatabaseURL) {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
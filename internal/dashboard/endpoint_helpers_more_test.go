@@ -5,6 +5,8 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/marianogappa/screpdb/internal/dashboard/db"
 )
 
 func TestOutlierIconKey(t *testing.T) {
@@ -233,6 +235,125 @@ func TestParseViewportSwitchRate(t *testing.T) {
 	}
 }
 
+func TestParseSupplyBlockPayloadAndWindow(t *testing.T) {
+	block, ok := parseSupplyBlockPayload(`{"duration_seconds":30,"supply":9}`)
+	if !ok || block.DurationSeconds != 30 || block.Supply != 9 {
+		t.Errorf("payload = %+v ok=%v, want 30s at 9 supply", block, ok)
+	}
+	for _, raw := range []string{"   ", "not-json", `{"duration_seconds":0,"supply":9}`} {
+		if _, ok := parseSupplyBlockPayload(raw); ok {
+			t.Errorf("%q should be ok=false", raw)
+		}
+	}
+	for _, tc := range []struct{ second, duration, want int64 }{
+		{60, 30, 30},
+		{580, 40, 20},
+		{600, 30, 0},
+	} {
+		got := supplyBlockedSecondsInWindow(workflowSupplyBlockPeriod{Second: tc.second, DurationSeconds: tc.duration})
+		if got != tc.want {
+			t.Errorf("%ds block at %ds: %d in window, want %d", tc.duration, tc.second, got, tc.want)
+		}
+	}
+}
+
+func TestTallyProductionSpansSplitsByPhase(t *testing.T) {
+	// One Barracks ready 100-700, busy 100-200 and 350-450; mid game starts
+	// at 300 and late game is never reached.
+	spans := []db.ProductionBusySpanRow{
+		{Building: "Barracks", BuildingIndex: 1, ReadySecond: 100, WindowEndSecond: 700, StartSecond: 100, EndSecond: 200},
+		{Building: "Barracks", BuildingIndex: 1, ReadySecond: 100, WindowEndSecond: 700, StartSecond: 350, EndSecond: 450},
+	}
+	tally := tallyProductionSpans(spans, productionPhaseBounds(300, 0))
+	if tally.available != [3]int64{200, 400, 0} || tally.busy != [3]int64{100, 100, 0} {
+		t.Fatalf("tally = %+v", tally)
+	}
+	if idle, ok := tally.idlePercent(-1); !ok || math.Abs(idle-100*400.0/600) > 1e-9 {
+		t.Errorf("overall idle = %v ok=%v; want 66.7", idle, ok)
+	}
+	if idle, ok := tally.idlePercent(0); !ok || idle != 50 {
+		t.Errorf("early idle = %v ok=%v; want 50", idle, ok)
+	}
+	if _, ok := tally.idlePercent(2); ok {
+		t.Error("late game was never reached; want ok=false")
+	}
+}
+
+func TestBuildLarvaHatcheriesAndTally(t *testing.T) {
+	// One Hatchery ready at 0 with 3 larvae, all used at 10, back to 3 at 43,
+	// a spawn missed at 58 and banked until the 100s window end; mid game
+	// starts at 50.
+	samples := []db.LarvaSampleRow{
+		{HatcheryIndex: 1, WindowEndSecond: 100, Second: 0, Larvae: 3, Spawned: 3},
+		{HatcheryIndex: 1, WindowEndSecond: 100, Second: 10, Larvae: 0, Used: 3},
+		{HatcheryIndex: 1, WindowEndSecond: 100, Second: 14, Larvae: 1, Spawned: 1},
+		{HatcheryIndex: 1, WindowEndSecond: 100, Second: 29, Larvae: 2, Spawned: 1},
+		{HatcheryIndex: 1, WindowEndSecond: 100, Second: 43, Larvae: 3, Spawned: 1},
+		{HatcheryIndex: 1, WindowEndSecond: 100, Second: 58, Larvae: 3, Missed: 1},
+	}
+	hatcheries := buildLarvaHatcheries(samples)
+	if len(hatcheries) != 1 {
+		t.Fatalf("hatcheries = %+v; want one", hatcheries)
+	}
+	h := hatcheries[0]
+	wantBanked := []workflowLarvaBankedSpan{{StartSecond: 0, EndSecond: 10}, {StartSecond: 43, EndSecond: 100}}
+	if !reflect.DeepEqual(h.Banked, wantBanked) || h.BankedSeconds != 67 {
+		t.Errorf("banked = %+v (%ds); want %+v (67s)", h.Banked, h.BankedSeconds, wantBanked)
+	}
+	if h.Spawned != 6 || h.Used != 3 || h.Missed != 1 || len(h.Counts) != len(samples) {
+		t.Errorf("hatchery = %+v", h)
+	}
+
+	tally := tallyLarvaSamples(samples, productionPhaseBounds(50, 0))
+	if tally.spawned != [3]int64{6, 0, 0} || tally.missed != [3]int64{0, 1, 0} {
+		t.Fatalf("tally = %+v", tally)
+	}
+	if missed, ok := tally.missedPercent(-1); !ok || math.Abs(missed-100.0/7) > 1e-9 {
+		t.Errorf("overall missed = %v ok=%v; want 14.3", missed, ok)
+	}
+	if missed, ok := tally.missedPercent(1); !ok || missed != 100 {
+		t.Errorf("mid missed = %v ok=%v; want 100", missed, ok)
+	}
+}
+
+func TestBuildHotkeyGamePlayerAndLayouts(t *testing.T) {
+	// Group 1 is a building bound at 5 and rebound at 70 with a camera jump;
+	// group 4 is army recalled once in the mid game (from 60) of a 120s game.
+	usage := []db.HotkeyUsageRow{
+		{HotkeyGroup: 1, WindowEndSecond: 120, Second: 5, Role: "building", Assigns: 1},
+		{HotkeyGroup: 1, WindowEndSecond: 120, Second: 30, Role: "building", Selects: 8},
+		{HotkeyGroup: 1, WindowEndSecond: 120, Second: 70, Role: "building", Assigns: 1, Selects: 2, CameraJumps: 1},
+		{HotkeyGroup: 4, WindowEndSecond: 120, Second: 90, Role: "army", Assigns: 1, Selects: 1},
+	}
+	entry := buildHotkeyGamePlayer(usage, productionPhaseBounds(60, 0))
+	if entry.Assigns != 3 || entry.Rebinds != 1 || entry.Selects != 11 || entry.CameraJumps != 1 {
+		t.Fatalf("entry = %+v", entry)
+	}
+	if math.Abs(entry.SelectsPerMinute-5.5) > 1e-9 || len(entry.Phases) != 2 || entry.Phases[0].SelectsPerMinute != 8 || entry.Phases[1].SelectsPerMinute != 3 {
+		t.Errorf("rates = %v %+v; want 5.5 overall, 8 early, 3 mid", entry.SelectsPerMinute, entry.Phases)
+	}
+	if entry.Layout != "1B 4A" || len(entry.Groups) != 2 || entry.Groups[0].FirstBoundSecond == nil || *entry.Groups[0].FirstBoundSecond != 5 || len(entry.Groups[1].Uses) != 1 {
+		t.Errorf("groups = %+v layout %q; want 1B 4A", entry.Groups, entry.Layout)
+	}
+
+	totals := []db.HotkeyGroupTotalRow{
+		{ReplayID: 1, OwnRace: "Terran", OppRace: "Zerg", WindowEndSecond: 60, HotkeyGroup: 0, Role: "army", Selects: 6},
+		{ReplayID: 1, OwnRace: "Terran", OppRace: "Zerg", WindowEndSecond: 60, HotkeyGroup: 4, Role: "building", Assigns: 2, Selects: 6},
+		{ReplayID: 2, OwnRace: "Terran", OppRace: "Zerg", WindowEndSecond: 60, HotkeyGroup: 4, Role: "building", Selects: 12},
+		{ReplayID: 3, OwnRace: "Terran", WindowEndSecond: 60, HotkeyGroup: 1, Role: "army", Selects: 6},
+	}
+	layouts := buildHotkeyLayouts(totals)
+	if len(layouts) != 2 || layouts[0].Games != 3 || layouts[0].OppRace != "" || layouts[1].OppRace != "Zerg" || layouts[1].Games != 2 {
+		t.Fatalf("layouts = %+v; want all games then TvZ", layouts)
+	}
+	if layouts[0].Layout != "1A 4B 0A" || layouts[1].Layout != "4B 0A" || layouts[1].SameAsOverall {
+		t.Errorf("fingerprints = %q / %q", layouts[0].Layout, layouts[1].Layout)
+	}
+	if layouts[1].RebindsPerGame != 0.5 || layouts[1].SelectsPerMinute != 12 {
+		t.Errorf("TvZ = %+v; want 0.5 rebinds and 12 selects per game minute", layouts[1])
+	}
+}
+
 func TestFormatQueryResults(t *testing.T) {
 	if got := formatQueryResults(nil); got != "No results found." {
 		t.Errorf("empty = %q, want \"No results found.\"", got)
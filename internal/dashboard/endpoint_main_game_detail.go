@@ -14,6 +14,7 @@ import (
 	db "github.com/marianogappa/screpdb/internal/dashboard/db"
 	"github.com/marianogappa/screpdb/internal/models"
 	"github.com/marianogappa/screpdb/internal/patterns/markers"
+	"github.com/marianogappa/screpdb/internal/playeragg"
 	"github.com/samber/lo"
 )
 
@@ -94,6 +95,9 @@ func (d *Dashboard) buildWorkflowGameDetail(replayID int64) (workflowGameDetail,
 	if err := d.populateTimingsForGameDetail(&detail); err != nil {
 		return detail, err
 	}
+	if err := d.populateEconomyTimelineForGameDetail(&detail); err != nil {
+		return detail, err
+	}
 	if err := d.populateFirstUnitEfficiencyForGameDetail(&detail); err != nil {
 		return detail, err
 	}
@@ -103,6 +107,18 @@ func (d *Dashboard) buildWorkflowGameDetail(replayID int64) (workflowGameDetail,
 	if err := d.populateViewportMultitaskingForGameDetail(&detail); err != nil {
 		return detail, err
 	}
+	if err := d.populateSupplyBlocksForGameDetail(&detail); err != nil {
+		return detail, err
+	}
+	if err := d.populateProductionIdleForGameDetail(&detail); err != nil {
+		return detail, err
+	}
+	if err := d.populateLarvaForGameDetail(&detail); err != nil {
+		return detail, err
+	}
+	if err := d.populateHotkeysForGameDetail(&detail); err != nil {
+		return detail, err
+	}
 	if err := d.populateMarkersForGameDetail(&detail); err != nil {
 		return detail, err
 	}
@@ -346,7 +362,16 @@ func (d *Dashboard) buildWorkflowPlayerApmHistogram(playerKey string) (workflowP
 		PlayerEligible: false,
 	}
 
-	rows, err := d.dbStore.ListPlayerApmAggregates(d.ctx, minGames)
+	classSQL, materialized, err := d.playerAggregateClassSQL()
+	if err != nil {
+		return result, err
+	}
+	var rows []db.PlayerApmAggregateRow
+	if materialized {
+		rows, err = d.dbStore.ListPlayerApmAggregatesMaterialized(d.ctx, classSQL, minGames)
+	} else {
+		rows, err = d.dbStore.ListPlayerApmAggregates(d.ctx, minGames)
+	}
 	if err != nil {
 		return result, err
 	}
@@ -478,23 +503,7 @@ type workflowPlayerUnitCadenceReplayMetric struct {
 }
 
 func workflowUnitCadenceExcludedUnits(filterMode workflowUnitCadenceFilterMode) []string {
-	if filterMode == workflowUnitCadenceFilterBroad {
-		return []string{"SCV", "Probe", "Drone", "Overlord"}
-	}
-	return []string{
-		"SCV",
-		"Probe",
-		"Drone",
-		"Overlord",
-		"Observer",
-		"Shuttle",
-		"Science Vessel",
-		"Medic",
-		"Dropship",
-		"Defiler",
-		"Queen",
-		"Nuclear Missile",
-	}
+	return playeragg.CadenceExcludedUnits(filterMode == workflowUnitCadenceFilterBroad)
 }
 
 func (d *Dashboard) queryWorkflowUnitCadenceReplayMetrics(filterMode workflowUnitCadenceFilterMode, onlyPlayerKey string) ([]workflowPlayerUnitCadenceReplayMetric, error) {
@@ -558,10 +567,6 @@ func (d *Dashboard) buildWorkflowPlayerUnitCadenceLeaderboard(filterMode workflo
 	if limit > workflowUnitCadenceMaxLimit {
 		limit = workflowUnitCadenceMaxLimit
 	}
-	replays, err := d.queryWorkflowUnitCadenceReplayMetrics(filterMode, "")
-	if err != nil {
-		return result, err
-	}
 	type agg struct {
 		name       string
 		games      int64
@@ -572,20 +577,46 @@ func (d *Dashboard) buildWorkflowPlayerUnitCadenceLeaderboard(filterMode workflo
 		sumCadence float64
 	}
 	byPlayer := map[string]*agg{}
-	for _, replay := range replays {
-		entry, ok := byPlayer[replay.PlayerKey]
-		if !ok {
-			entry = &agg{name: replay.PlayerName}
-			byPlayer[replay.PlayerKey] = entry
+	classSQL, materialized, err := d.playerAggregateClassSQL()
+	if err != nil {
+		return result, err
+	}
+	if materialized {
+		rows, err := d.dbStore.ListUnitCadencePlayerAggregates(d.ctx, classSQL, filterMode == workflowUnitCadenceFilterBroad)
+		if err != nil {
+			return result, err
+		}
+		for _, row := range rows {
+			byPlayer[row.PlayerKey] = &agg{
+				name:       row.PlayerName,
+				games:      row.Games,
+				sumRate:    row.SumRate,
+				sumCV:      row.SumCV,
+				sumBurst:   row.SumBurstiness,
+				sumIdle:    row.SumIdle,
+				sumCadence: row.SumCadence,
+			}
+		}
+	} else {
+		replays, err := d.queryWorkflowUnitCadenceReplayMetrics(filterMode, "")
+		if err != nil {
+			return result, err
 		}
-		entry.games++
-		entry.sumRate += replay.RatePerMinute
-		entry.sumCV += replay.CVGap
-		entry.sumBurst += replay.Burstiness
-		entry.sumIdle += replay.Idle20Ratio
-		entry.sumCadence += replay.CadenceScore
-		if strings.TrimSpace(entry.name) == "" {
-			entry.name = replay.PlayerName
+		for _, replay := range replays {
+			entry, ok := byPlayer[replay.PlayerKey]
+			if !ok {
+				entry = &agg{name: replay.PlayerName}
+				byPlayer[replay.PlayerKey] = entry
+			}
+			entry.games++
+			entry.sumRate += replay.RatePerMinute
+			entry.sumCV += replay.CVGap
+			entry.sumBurst += replay.Burstiness
+			entry.sumIdle += replay.Idle20Ratio
+			entry.sumCadence += replay.CadenceScore
+			if strings.TrimSpace(entry.name) == "" {
+				entry.name = replay.PlayerName
+			}
 		}
 	}
 	for playerKey, entry := range byPlayer {
@@ -757,6 +788,12 @@ func (d *Dashboard) buildWorkflowPlayerAsyncInsight(playerKey string, insightTyp
 		return d.buildWorkflowPlayerCadenceAsyncInsight(playerKey)
 	case workflowPlayerInsightTypeViewportSwitchRate:
 		return d.buildWorkflowPlayerViewportAsyncInsight(playerKey)
+	case workflowPlayerInsightTypeSupplyBlocked:
+		return d.buildWorkflowPlayerSupplyBlockAsyncInsight(playerKey)
+	case workflowPlayerInsightTypeProductionIdle:
+		return d.buildWorkflowPlayerProductionIdleAsyncInsight(playerKey)
+	case workflowPlayerInsightTypeLarvaMissed:
+		return d.buildWorkflowPlayerLarvaAsyncInsight(playerKey)
 	default:
 		return workflowPlayerAsyncInsight{}, errUnsupportedWorkflowPlayerInsightType
 	}
@@ -1018,6 +1055,12 @@ func replayEventsFromRows(rows []db.ReplayEventRow, mapLayout *models.MapContext
 		if event.Type == "late_alliance" && row.Payload != nil && *row.Payload != "" {
 			applyAlliancePayload(&event, *row.Payload)
 		}
+		if event.Type == "supply_block" && row.Payload != nil {
+			if block, ok := parseSupplyBlockPayload(*row.Payload); ok {
+				event.DurationSeconds = block.DurationSeconds
+				event.SupplyCap = block.Supply
+			}
+		}
 		events = append(events, event)
 	}
 	return events
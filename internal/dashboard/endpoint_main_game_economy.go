@@ -0,0 +1,45 @@
+package dashboard
+
+import "fmt"
+
+// populateEconomyTimelineForGameDetail attaches each player's estimated
+// economy timeline, in detail.Players order. Observers and players without
+// samples (replays ingested before they were stored) are left out, so an
+// empty EconomyTimeline hides the game page's Economy tab.
+func (d *Dashboard) populateEconomyTimelineForGameDetail(detail *workflowGameDetail) error {
+	detail.EconomyTimeline = []workflowEconomyTimelinePlayer{}
+	rows, err := d.dbStore.ListReplayEconomySamples(d.ctx, detail.ReplayID)
+	if err != nil {
+		return fmt.Errorf("failed to load economy samples: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	samplesByPlayer := map[int64][]workflowEconomySample{}
+	for _, row := range rows {
+		samplesByPlayer[row.PlayerID] = append(samplesByPlayer[row.PlayerID], workflowEconomySample{
+			Second:        row.Second,
+			Minerals:      row.Minerals,
+			Gas:           row.Gas,
+			MineralIncome: row.MineralIncome,
+			GasIncome:     row.GasIncome,
+			SupplyUsed:    row.SupplyUsed,
+			SupplyMax:     row.SupplyMax,
+			Workers:       row.Workers,
+			Bases:         row.Bases,
+		})
+	}
+	for _, p := range detail.Players {
+		samples := samplesByPlayer[p.PlayerID]
+		if len(samples) == 0 {
+			continue
+		}
+		detail.EconomyTimeline = append(detail.EconomyTimeline, workflowEconomyTimelinePlayer{
+			PlayerID:  p.PlayerID,
+			PlayerKey: p.PlayerKey,
+			Name:      p.Name,
+			Samples:   samples,
+		})
+	}
+	return nil
+}
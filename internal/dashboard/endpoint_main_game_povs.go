@@ -0,0 +1,79 @@
+package dashboard
+
+import (
+	"context"
+	"database/sql"
+
+	db "github.com/marianogappa/screpdb/internal/dashboard/db"
+)
+
+// populateWorkflowGameListPOVs sets how many replay files recorded each
+// listed game. Listed replays are canonical, so their ids are group ids.
+func (d *Dashboard) populateWorkflowGameListPOVs(items []workflowGameListItem) error {
+	groupIDs := make([]int64, 0, len(items))
+	itemIndexByGroupID := map[int64]int{}
+	for i, item := range items {
+		groupIDs = append(groupIDs, item.ReplayID)
+		itemIndexByGroupID[item.ReplayID] = i
+	}
+	if len(groupIDs) == 0 {
+		return nil
+	}
+	rows, err := d.dbStore.ListGamePOVs(d.ctx, groupIDs)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if idx, ok := itemIndexByGroupID[row.GameGroupID]; ok {
+			items[idx].POVCount++
+		}
+	}
+	return nil
+}
+
+// resolveGamePOV maps any replay id, canonical or not, to its game: the
+// canonical replay id the game's analysis is read from, every POV of the
+// game, and the POV that replayID names. It returns sql.ErrNoRows when no
+// such replay exists.
+func (d *Dashboard) resolveGamePOV(ctx context.Context, replayID int64) (int64, []db.GamePOVRow, db.GamePOVRow, error) {
+	groupID, err := d.dbStore.GetReplayGameGroupID(ctx, replayID)
+	if err != nil {
+		return 0, nil, db.GamePOVRow{}, err
+	}
+	povs, err := d.dbStore.ListGamePOVs(ctx, []int64{groupID})
+	if err != nil {
+		return 0, nil, db.GamePOVRow{}, err
+	}
+	for _, pov := range povs {
+		if pov.ReplayID == replayID {
+			return groupID, povs, pov, nil
+		}
+	}
+	return 0, nil, db.GamePOVRow{}, sql.ErrNoRows
+}
+
+// buildWorkflowGameDetailForPOV builds the detail of the game replayID
+// recorded from its canonical replay, then presents it as replayID's POV.
+func (d *Dashboard) buildWorkflowGameDetailForPOV(ctx context.Context, replayID int64) (workflowGameDetail, error) {
+	groupID, povs, requested, err := d.resolveGamePOV(ctx, replayID)
+	if err != nil {
+		return workflowGameDetail{SummaryVersion: workflowSummaryVersion}, err
+	}
+	detail, err := d.buildWorkflowGameDetail(groupID)
+	if err != nil {
+		return detail, err
+	}
+	detail.ReplayID = requested.ReplayID
+	detail.FileName = requested.FileName
+	detail.FilePath = requested.FilePath
+	detail.POVs = make([]workflowGamePOV, 0, len(povs))
+	for _, pov := range povs {
+		detail.POVs = append(detail.POVs, workflowGamePOV{
+			ReplayID:    pov.ReplayID,
+			FileName:    pov.FileName,
+			FilePath:    pov.FilePath,
+			IsCanonical: pov.ReplayID == groupID,
+		})
+	}
+	return detail, nil
+}
@@ -13,7 +13,10 @@ import (
 )
 
 func (d *Dashboard) listWorkflowPlayers(limit, offset int, filters workflowPlayersListFilters, sortSpec workflowPlayersListSort) ([]workflowPlayersListItem, int64, workflowPlayersListFilterOptions, error) {
-	baseSQL, baseArgs := buildWorkflowPlayersListBaseSQL(filters)
+	baseSQL, baseArgs, err := d.workflowPlayersListBaseSQL(filters)
+	if err != nil {
+		return []workflowPlayersListItem{}, 0, workflowPlayersListFilterOptions{}, err
+	}
 	whereSQL, whereArgs := buildWorkflowPlayersListWhere(filters)
 	allArgs := append(append([]any{}, baseArgs...), whereArgs...)
 
@@ -67,6 +70,21 @@ func (d *Dashboard) listWorkflowPlayers(limit, offset int, filters workflowPlaye
 	return items, total, filterOptions, nil
 }
 
+// workflowPlayersListBaseSQL reads the players list from the player
+// aggregates when they're built, and from players otherwise.
+func (d *Dashboard) workflowPlayersListBaseSQL(filters workflowPlayersListFilters) (string, []any, error) {
+	classSQL, ok, err := d.playerAggregateClassSQL()
+	if err != nil {
+		return "", nil, err
+	}
+	if ok {
+		baseSQL, baseArgs := dashboarddb.BuildWorkflowPlayersListAggregateBaseSQL(normalizePlayerKey(filters.NameContains), classSQL)
+		return baseSQL, baseArgs, nil
+	}
+	baseSQL, baseArgs := buildWorkflowPlayersListBaseSQL(filters)
+	return baseSQL, baseArgs, nil
+}
+
 func buildWorkflowPlayersListBaseSQL(filters workflowPlayersListFilters) (string, []any) {
 	return dashboarddb.BuildWorkflowPlayersListBaseSQL(normalizePlayerKey(filters.NameContains))
 }
@@ -0,0 +1,449 @@
+package dashboard
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/marianogappa/screpdb/internal/dashboard/db"
+	"github.com/marianogappa/screpdb/internal/unittags"
+)
+
+// Control-group analytics come from the hotkey usage ingest writes per
+// player, group and second (see parser.BuildHotkeyUsage): binds (Assign),
+// recalls (Select), adds to the selection (Add) and camera jumps, which are
+// recalls double-tapped to center the screen on the group. A group's role is
+// whether its binding held a building or army (see unittags.HotkeyBinding).
+
+// workflowHotkeyLayoutMinShare is the share of a player's recalls, in
+// percent, a group needs to make it into their layout fingerprint, so a
+// group bound once by accident doesn't change it.
+const workflowHotkeyLayoutMinShare = 5.0
+
+// workflowHotkeyLayoutMinGames is how many games a matchup needs for its
+// own layout on the player page.
+const workflowHotkeyLayoutMinGames int64 = 2
+
+type workflowHotkeyUse struct {
+	Second      int64  `json:"second"`
+	Role        string `json:"role"`
+	Assigns     int64  `json:"assigns"`
+	Selects     int64  `json:"selects"`
+	CameraJumps int64  `json:"camera_jumps"`
+}
+
+type workflowHotkeyGroup struct {
+	Group            int64               `json:"group"`
+	Role             string              `json:"role"`
+	Games            int64               `json:"games,omitempty"`
+	Assigns          int64               `json:"assigns"`
+	Selects          int64               `json:"selects"`
+	Adds             int64               `json:"adds"`
+	CameraJumps      int64               `json:"camera_jumps"`
+	UsePercent       float64             `json:"use_percent"`
+	FirstBoundSecond *int64              `json:"first_bound_second,omitempty"`
+	Uses             []workflowHotkeyUse `json:"uses,omitempty"`
+}
+
+type workflowHotkeyPhase struct {
+	Phase            string  `json:"phase"`
+	Label            string  `json:"label"`
+	Selects          int64   `json:"selects"`
+	SelectsPerMinute float64 `json:"selects_per_minute"`
+}
+
+type workflowGameHotkeyPlayer struct {
+	PlayerID          int64                 `json:"player_id"`
+	PlayerKey         string                `json:"player_key"`
+	PlayerName        string                `json:"player_name"`
+	Team              int64                 `json:"team"`
+	IsWinner          bool                  `json:"is_winner"`
+	WindowEndSecond   int64                 `json:"window_end_second"`
+	Assigns           int64                 `json:"assigns"`
+	Rebinds           int64                 `json:"rebinds"`
+	Selects           int64                 `json:"selects"`
+	Adds              int64                 `json:"adds"`
+	CameraJumps       int64                 `json:"camera_jumps"`
+	CameraJumpPercent float64               `json:"camera_jump_percent"`
+	SelectsPerMinute  float64               `json:"selects_per_minute"`
+	Layout            string                `json:"layout"`
+	Phases            []workflowHotkeyPhase `json:"phases"`
+	Groups            []workflowHotkeyGroup `json:"groups"`
+}
+
+// workflowPlayerHotkeyLayout is a player's control-group habits over all
+// their games (empty races) or over one 1v1 matchup. SameAsOverall is set
+// on a matchup whose layout matches the all-games one.
+type workflowPlayerHotkeyLayout struct {
+	OwnRace           string                `json:"own_race"`
+	OppRace           string                `json:"opp_race"`
+	Games             int64                 `json:"games"`
+	SelectsPerMinute  float64               `json:"selects_per_minute"`
+	CameraJumpPercent float64               `json:"camera_jump_percent"`
+	RebindsPerGame    float64               `json:"rebinds_per_game"`
+	Layout            string                `json:"layout"`
+	SameAsOverall     bool                  `json:"same_as_overall"`
+	Phases            []workflowHotkeyPhase `json:"phases"`
+	Groups            []workflowHotkeyGroup `json:"groups"`
+}
+
+// hotkeyGroupTally sums one group's use; roleUses counts its binds, recalls
+// and adds per binding role.
+type hotkeyGroupTally struct {
+	games       int64
+	assigns     int64
+	selects     int64
+	adds        int64
+	cameraJumps int64
+	roleUses    map[string]int64
+}
+
+// hotkeyTally sums a player's hotkey use over one or more games. seconds is
+// the time played per phase, rebinds the binds to a group already bound in
+// the same game.
+type hotkeyTally struct {
+	games   int64
+	rebinds int64
+	selects [3]int64
+	seconds [3]int64
+	groups  map[int64]*hotkeyGroupTally
+}
+
+func newHotkeyTally() *hotkeyTally {
+	return &hotkeyTally{groups: map[int64]*hotkeyGroupTally{}}
+}
+
+func (t *hotkeyTally) add(group int64, phase int, role string, assigns, selects, adds, cameraJumps int64) {
+	g := t.groups[group]
+	if g == nil {
+		g = &hotkeyGroupTally{roleUses: map[string]int64{}}
+		t.groups[group] = g
+	}
+	g.assigns += assigns
+	g.selects += selects
+	g.adds += adds
+	g.cameraJumps += cameraJumps
+	g.roleUses[role] += assigns + selects + adds
+	t.selects[phase] += selects
+}
+
+// addGame closes one game's tally: it counts the player's window in each
+// phase and the game's rebinds.
+func (t *hotkeyTally) addGame(windowEnd int64, bounds [3][2]int64) {
+	t.games++
+	for i, b := range bounds {
+		t.seconds[i] += overlapSeconds(0, windowEnd, b[0], b[1])
+	}
+	for _, g := range t.groups {
+		g.games = 1
+		t.rebinds += max(0, g.assigns-1)
+	}
+}
+
+// merge adds a closed game tally into t.
+func (t *hotkeyTally) merge(game *hotkeyTally) {
+	t.games += game.games
+	t.rebinds += game.rebinds
+	for i := range t.selects {
+		t.selects[i] += game.selects[i]
+		t.seconds[i] += game.seconds[i]
+	}
+	for group, g := range game.groups {
+		into := t.groups[group]
+		if into == nil {
+			into = &hotkeyGroupTally{roleUses: map[string]int64{}}
+			t.groups[group] = into
+		}
+		into.games += g.games
+		into.assigns += g.assigns
+		into.selects += g.selects
+		into.adds += g.adds
+		into.cameraJumps += g.cameraJumps
+		for role, uses := range g.roleUses {
+			into.roleUses[role] += uses
+		}
+	}
+}
+
+func (t *hotkeyTally) totals() (assigns, selects, adds, cameraJumps int64) {
+	for _, g := range t.groups {
+		assigns += g.assigns
+		selects += g.selects
+		adds += g.adds
+		cameraJumps += g.cameraJumps
+	}
+	return assigns, selects, adds, cameraJumps
+}
+
+// selectsPerMinute is the recall rate in phase i, or over the whole game
+// when i < 0, and false when no time was played then.
+func (t *hotkeyTally) selectsPerMinute(i int) (float64, bool) {
+	var selects, seconds int64
+	for j := range t.selects {
+		if i < 0 || i == j {
+			selects += t.selects[j]
+			seconds += t.seconds[j]
+		}
+	}
+	if seconds <= 0 {
+		return 0, false
+	}
+	return 60 * float64(selects) / float64(seconds), true
+}
+
+func (t *hotkeyTally) cameraJumpPercent() float64 {
+	_, selects, _, cameraJumps := t.totals()
+	if selects <= 0 {
+		return 0
+	}
+	return 100 * float64(cameraJumps) / float64(selects)
+}
+
+func (t *hotkeyTally) phases() []workflowHotkeyPhase {
+	out := []workflowHotkeyPhase{}
+	for i, phase := range workflowProductionPhases {
+		perMinute, ok := t.selectsPerMinute(i)
+		if !ok {
+			continue
+		}
+		out = append(out, workflowHotkeyPhase{
+			Phase:            phase.Key,
+			Label:            phase.Label,
+			Selects:          t.selects[i],
+			SelectsPerMinute: perMinute,
+		})
+	}
+	return out
+}
+
+// groupList returns the groups in keyboard order (1 to 9, then 0) with
+// their dominant role and share of the recalls.
+func (t *hotkeyTally) groupList() []workflowHotkeyGroup {
+	_, selects, _, _ := t.totals()
+	out := make([]workflowHotkeyGroup, 0, len(t.groups))
+	for group, g := range t.groups {
+		entry := workflowHotkeyGroup{
+			Group:       group,
+			Role:        dominantHotkeyRole(g.roleUses),
+			Games:       g.games,
+			Assigns:     g.assigns,
+			Selects:     g.selects,
+			Adds:        g.adds,
+			CameraJumps: g.cameraJumps,
+		}
+		if selects > 0 {
+			entry.UsePercent = 100 * float64(g.selects) / float64(selects)
+		}
+		out = append(out, entry)
+	}
+	sort.Slice(out, func(i, j int) bool { return hotkeyKeyboardOrder(out[i].Group) < hotkeyKeyboardOrder(out[j].Group) })
+	return out
+}
+
+// hotkeyKeyboardOrder places group 0 after 9, as on the keyboard.
+func hotkeyKeyboardOrder(group int64) int64 {
+	if group == 0 {
+		return 10
+	}
+	return group
+}
+
+// dominantHotkeyRole is the known role a group was used under most, or
+// empty when none is known. Ties go to building.
+func dominantHotkeyRole(roleUses map[string]int64) string {
+	best := ""
+	for _, role := range []string{unittags.HotkeyRoleBuilding, unittags.HotkeyRoleArmy} {
+		if roleUses[role] > 0 && (best == "" || roleUses[role] > roleUses[best]) {
+			best = role
+		}
+	}
+	return best
+}
+
+// hotkeyLayoutFingerprint writes the groups holding at least
+// workflowHotkeyLayoutMinShare of the recalls as key and role letter, e.g.
+// "1B 2B 4A 5A" (B building, A army, ? unknown).
+func hotkeyLayoutFingerprint(groups []workflowHotkeyGroup) string {
+	parts := []string{}
+	for _, g := range groups {
+		if g.UsePercent < workflowHotkeyLayoutMinShare {
+			continue
+		}
+		letter := "?"
+		switch g.Role {
+		case unittags.HotkeyRoleBuilding:
+			letter = "B"
+		case unittags.HotkeyRoleArmy:
+			letter = "A"
+		}
+		parts = append(parts, fmt.Sprintf("%d%s", g.Group, letter))
+	}
+	return strings.Join(parts, " ")
+}
+
+// populateHotkeysForGameDetail attaches each player's control groups, with
+// their roles, first bind and uses over time, and their recall rate overall
+// and per phase, rebinds, camera jumps and layout fingerprint. Players who
+// never pressed a hotkey are left out, so an empty list hides the game
+// page's tab.
+func (d *Dashboard) populateHotkeysForGameDetail(detail *workflowGameDetail) error {
+	detail.Hotkeys = []workflowGameHotkeyPlayer{}
+	rows, err := d.dbStore.ListReplayHotkeyUsage(d.ctx, detail.ReplayID)
+	if err != nil {
+		return fmt.Errorf("failed to load hotkey usage: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	boundaries, err := d.dbStore.GetPhaseBoundariesForReplay(d.ctx, detail.ReplayID)
+	if err != nil {
+		return fmt.Errorf("failed to load phase boundaries: %w", err)
+	}
+	bounds := productionPhaseBounds(boundaries.EarlyEndsAtSecond, boundaries.MidEndsAtSecond)
+	rowsByPlayer := map[int64][]db.HotkeyUsageRow{}
+	for _, row := range rows {
+		rowsByPlayer[row.PlayerID] = append(rowsByPlayer[row.PlayerID], row)
+	}
+
+	for _, player := range detail.Players {
+		usage := rowsByPlayer[player.PlayerID]
+		if len(usage) == 0 {
+			continue
+		}
+		entry := buildHotkeyGamePlayer(usage, bounds)
+		entry.PlayerID = player.PlayerID
+		entry.PlayerKey = player.PlayerKey
+		entry.PlayerName = player.Name
+		entry.Team = player.Team
+		entry.IsWinner = player.IsWinner
+		detail.Hotkeys = append(detail.Hotkeys, entry)
+	}
+	return nil
+}
+
+// buildHotkeyGamePlayer tallies one player's usage rows, which come ordered
+// by group and second.
+func buildHotkeyGamePlayer(usage []db.HotkeyUsageRow, bounds [3][2]int64) workflowGameHotkeyPlayer {
+	tally := newHotkeyTally()
+	uses := map[int64][]workflowHotkeyUse{}
+	firstBound := map[int64]int64{}
+	var windowEnd int64
+	for _, row := range usage {
+		tally.add(row.HotkeyGroup, phaseIndexAt(row.Second, bounds), row.Role, row.Assigns, row.Selects, row.Adds, row.CameraJumps)
+		uses[row.HotkeyGroup] = append(uses[row.HotkeyGroup], workflowHotkeyUse{
+			Second:      row.Second,
+			Role:        row.Role,
+			Assigns:     row.Assigns,
+			Selects:     row.Selects,
+			CameraJumps: row.CameraJumps,
+		})
+		if _, ok := firstBound[row.HotkeyGroup]; !ok && row.Assigns > 0 {
+			firstBound[row.HotkeyGroup] = row.Second
+		}
+		windowEnd = max(windowEnd, row.WindowEndSecond)
+	}
+	tally.addGame(windowEnd, bounds)
+
+	entry := workflowGameHotkeyPlayer{
+		WindowEndSecond:   windowEnd,
+		Rebinds:           tally.rebinds,
+		CameraJumpPercent: tally.cameraJumpPercent(),
+		Phases:            tally.phases(),
+		Groups:            tally.groupList(),
+	}
+	entry.Assigns, entry.Selects, entry.Adds, entry.CameraJumps = tally.totals()
+	entry.SelectsPerMinute, _ = tally.selectsPerMinute(-1)
+	entry.Layout = hotkeyLayoutFingerprint(entry.Groups)
+	for i := range entry.Groups {
+		group := &entry.Groups[i]
+		group.Games = 0
+		group.Uses = uses[group.Group]
+		if second, ok := firstBound[group.Group]; ok {
+			group.FirstBoundSecond = &second
+		}
+	}
+	return entry
+}
+
+// hotkeyLayoutsForPlayer builds the player's control-group layout over all
+// their games, then per 1v1 matchup with at least
+// workflowHotkeyLayoutMinGames games, so the player page can show whether
+// the layout changes with the opponent.
+func (d *Dashboard) hotkeyLayoutsForPlayer(playerKey string) ([]workflowPlayerHotkeyLayout, error) {
+	rows, err := d.dbStore.ListPlayerHotkeyGroupTotals(d.ctx, playerKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load hotkey group totals: %w", err)
+	}
+	return buildHotkeyLayouts(rows), nil
+}
+
+// buildHotkeyLayouts aggregates group totals, which come ordered by replay,
+// into the all-games layout followed by one per qualifying matchup.
+func buildHotkeyLayouts(rows []db.HotkeyGroupTotalRow) []workflowPlayerHotkeyLayout {
+	type matchupKey struct {
+		own string
+		opp string
+	}
+	overall := newHotkeyTally()
+	byMatchup := map[matchupKey]*hotkeyTally{}
+	for start := 0; start < len(rows); {
+		end := start
+		for end < len(rows) && rows[end].ReplayID == rows[start].ReplayID {
+			end++
+		}
+		game := newHotkeyTally()
+		var windowEnd int64
+		for _, row := range rows[start:end] {
+			game.add(row.HotkeyGroup, int(row.Phase), row.Role, row.Assigns, row.Selects, row.Adds, row.CameraJumps)
+			windowEnd = max(windowEnd, row.WindowEndSecond)
+		}
+		game.addGame(windowEnd, productionPhaseBounds(rows[start].MidStartsAt, rows[start].LateStartsAt))
+		overall.merge(game)
+		if rows[start].OppRace != "" {
+			key := matchupKey{own: rows[start].OwnRace, opp: rows[start].OppRace}
+			if byMatchup[key] == nil {
+				byMatchup[key] = newHotkeyTally()
+			}
+			byMatchup[key].merge(game)
+		}
+		start = end
+	}
+	if overall.games == 0 {
+		return []workflowPlayerHotkeyLayout{}
+	}
+
+	out := []workflowPlayerHotkeyLayout{hotkeyLayoutFromTally(overall)}
+	keys := make([]matchupKey, 0, len(byMatchup))
+	for key, tally := range byMatchup {
+		if tally.games >= workflowHotkeyLayoutMinGames {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].own == keys[j].own {
+			return keys[i].opp < keys[j].opp
+		}
+		return keys[i].own < keys[j].own
+	})
+	for _, key := range keys {
+		layout := hotkeyLayoutFromTally(byMatchup[key])
+		layout.OwnRace = key.own
+		layout.OppRace = key.opp
+		layout.SameAsOverall = layout.Layout == out[0].Layout
+		out = append(out, layout)
+	}
+	return out
+}
+
+func hotkeyLayoutFromTally(t *hotkeyTally) workflowPlayerHotkeyLayout {
+	layout := workflowPlayerHotkeyLayout{
+		Games:             t.games,
+		CameraJumpPercent: t.cameraJumpPercent(),
+		RebindsPerGame:    float64(t.rebinds) / float64(t.games),
+		Phases:            t.phases(),
+		Groups:            t.groupList(),
+	}
+	layout.SelectsPerMinute, _ = t.selectsPerMinute(-1)
+	layout.Layout = hotkeyLayoutFingerprint(layout.Groups)
+	return layout
+}
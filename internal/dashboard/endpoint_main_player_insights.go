@@ -225,6 +225,12 @@ func (d *Dashboard) populateAdvancedPlayerOverview(playerKey string, result *wor
 	}
 	result.EarlyTimings = earlyTimings
 
+	hotkeyLayouts, err := d.hotkeyLayoutsForPlayer(playerKey)
+	if err != nil {
+		return err
+	}
+	result.HotkeyLayouts = hotkeyLayouts
+
 	return nil
 }
 
@@ -8,6 +8,7 @@ import (
 	"sort"
 	"strings"
 
+	dashboarddb "github.com/marianogappa/screpdb/internal/dashboard/db"
 	"github.com/marianogappa/screpdb/internal/models"
 )
 
@@ -263,13 +264,28 @@ func (d *Dashboard) buildWorkflowPlayerSummaryPerMatchup(playerKey string) (work
 
 	cards := []workflowPlayerSummaryCard{}
 
+	classSQL, materialized, err := d.playerAggregateClassSQL()
+	if err != nil {
+		return result, err
+	}
+
 	// 1v1 matchup cards
-	aggRows, err := d.dbStore.ListPlayerMatchupAggregates(d.ctx, playerKey)
+	var aggRows []dashboarddb.PlayerMatchupAggregateRow
+	if materialized {
+		aggRows, err = d.dbStore.ListPlayerMatchupAggregatesMaterialized(d.ctx, classSQL, playerKey)
+	} else {
+		aggRows, err = d.dbStore.ListPlayerMatchupAggregates(d.ctx, playerKey)
+	}
 	if err != nil {
 		return result, fmt.Errorf("failed to load matchup aggregates: %w", err)
 	}
 	if len(aggRows) > 0 {
-		markerRows, err := d.dbStore.ListPlayerMatchupMarkerCounts(d.ctx, playerKey)
+		var markerRows []dashboarddb.PlayerMatchupMarkerCountRow
+		if materialized {
+			markerRows, err = d.dbStore.ListPlayerMatchupMarkerCountsMaterialized(d.ctx, classSQL, playerKey)
+		} else {
+			markerRows, err = d.dbStore.ListPlayerMatchupMarkerCounts(d.ctx, playerKey)
+		}
 		if err != nil {
 			return result, fmt.Errorf("failed to load matchup marker counts: %w", err)
 		}
@@ -317,12 +333,22 @@ func (d *Dashboard) buildWorkflowPlayerSummaryPerMatchup(playerKey string) (work
 	// team_formats may roll up to a single bucket (any team_format with
 	// 2+ 'v's becomes "multi-team"), in which case we re-average APM/EAPM
 	// weighted by games and sum game/win counts.
-	byFormatAgg, err := d.dbStore.ListPlayerByFormatAggregates(d.ctx, playerKey)
+	var byFormatAgg []dashboarddb.PlayerByFormatAggregateRow
+	if materialized {
+		byFormatAgg, err = d.dbStore.ListPlayerByFormatAggregatesMaterialized(d.ctx, classSQL, playerKey)
+	} else {
+		byFormatAgg, err = d.dbStore.ListPlayerByFormatAggregates(d.ctx, playerKey)
+	}
 	if err != nil {
 		return result, fmt.Errorf("failed to load by-format aggregates: %w", err)
 	}
 	if len(byFormatAgg) > 0 {
-		byFormatMarkers, err := d.dbStore.ListPlayerByFormatMarkerCounts(d.ctx, playerKey)
+		var byFormatMarkers []dashboarddb.PlayerByFormatMarkerCountRow
+		if materialized {
+			byFormatMarkers, err = d.dbStore.ListPlayerByFormatMarkerCountsMaterialized(d.ctx, classSQL, playerKey)
+		} else {
+			byFormatMarkers, err = d.dbStore.ListPlayerByFormatMarkerCounts(d.ctx, playerKey)
+		}
 		if err != nil {
 			return result, fmt.Errorf("failed to load by-format marker counts: %w", err)
 		}
@@ -1,6 +1,10 @@
 package dashboard
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	"github.com/marianogappa/screpdb/internal/playeragg"
+)
 
 const workflowSummaryVersion = "v2"
 
@@ -23,11 +27,11 @@ var topPlayerPalette = []string{
 }
 
 const firstUnitEfficiencyMaxGapSeconds int64 = 60
-const workflowUnitCadenceStartSeconds int64 = 7 * 60
-const workflowUnitCadenceEndFraction float64 = 0.8
-const workflowUnitCadenceIdleGapSeconds int64 = 20
-const workflowUnitCadenceMinUnitsPerReplay int64 = 12
-const workflowUnitCadenceMinGapsPerReplay int64 = 8
+const workflowUnitCadenceStartSeconds = playeragg.CadenceStartSeconds
+const workflowUnitCadenceEndFraction = playeragg.CadenceEndFraction
+const workflowUnitCadenceIdleGapSeconds = playeragg.CadenceIdleGapSeconds
+const workflowUnitCadenceMinUnitsPerReplay = playeragg.CadenceMinUnitsPerReplay
+const workflowUnitCadenceMinGapsPerReplay = playeragg.CadenceMinGapsPerReplay
 const workflowUnitCadenceMinGames int64 = 4
 const workflowUnitCadenceDefaultLimit int64 = 50
 const workflowUnitCadenceMaxLimit int64 = 200
@@ -122,6 +126,9 @@ type workflowGameListItem struct {
 	Players            []workflowGameListPlayer  `json:"players"`
 	Featuring          []string                  `json:"featuring"`
 	CurrentPlayer      *workflowRecentGamePlayer `json:"current_player,omitempty"`
+	// POVCount is how many replay files recorded this game (one per player
+	// who saved it); the game is listed once, under its canonical replay.
+	POVCount int64 `json:"pov_count,omitempty"`
 }
 
 type workflowGameListPlayer struct {
@@ -372,10 +379,15 @@ type workflowGameDetail struct {
 	UnitsBySlice                     []workflowUnitSlice                      `json:"units_by_slice"`
 	UnitsEarlyEvents                 []workflowUnitEarlyEventPlayer           `json:"units_early_events"`
 	ProductionTimeline               []workflowProductionTimelinePlayer       `json:"production_timeline"`
+	EconomyTimeline                  []workflowEconomyTimelinePlayer          `json:"economy_timeline"`
 	Timings                          workflowReplayTimings                    `json:"timings"`
 	FirstUnitEfficiency              []workflowFirstUnitEfficiencyPlayer      `json:"first_unit_efficiency"`
 	UnitCadence                      []workflowGameUnitCadencePlayer          `json:"unit_production_cadence"`
 	ViewportMultitasking             []workflowGameViewportMultitaskingPlayer `json:"viewport_multitasking"`
+	SupplyBlocks                     []workflowGameSupplyBlockPlayer          `json:"supply_blocks"`
+	ProductionIdle                   []workflowGameProductionIdlePlayer       `json:"production_idle"`
+	Larva                            []workflowGameLarvaPlayer                `json:"larva"`
+	Hotkeys                          []workflowGameHotkeyPlayer               `json:"hotkeys"`
 	Markers                          []workflowMarkerPlayer                   `json:"build_orders"`
 	MutaliskTiming                   []workflowMarkerPlayer                   `json:"mutalisk_timing_chart,omitempty"`
 	MutaliskTimingSummary            *workflowMutaliskTimingSummary           `json:"mutalisk_timing_summary,omitempty"`
@@ -408,6 +420,19 @@ type workflowGameDetail struct {
 	// build/morph duration (Fastest game speed). The frontend pre-indexes
 	// per-player and binary-searches per event click.
 	TrainedUnitsTimeline []workflowTrainedUnitSample `json:"trained_units_timeline,omitempty"`
+
+	// POVs lists every replay file that recorded this game, canonical first.
+	// The analysis above always comes from the canonical replay; ReplayID,
+	// FileName and FilePath describe the POV that was requested.
+	POVs []workflowGamePOV `json:"povs"`
+}
+
+// workflowGamePOV is one replay file recording a game.
+type workflowGamePOV struct {
+	ReplayID    int64  `json:"replay_id"`
+	FileName    string `json:"file_name"`
+	FilePath    string `json:"file_path"`
+	IsCanonical bool   `json:"is_canonical"`
 }
 
 // workflowTrainedUnitSample is one "unit alive at second" entry on the
@@ -553,6 +578,11 @@ type workflowGameEvent struct {
 	// size ≥2 are included (solos filtered for clarity). Source is the
 	// {"teams":[["A","B"],...]} payload written by parser.BuildAllianceDerivedEvents.
 	AllianceTeams [][]workflowGameEventPlayer `json:"alliance_teams,omitempty"`
+	// Supply-block fields. Populated only for supply_block events, from the
+	// {"duration_seconds":N,"supply":M} payload written by
+	// parser.BuildSupplyBlockEvents.
+	DurationSeconds int64 `json:"duration_seconds,omitempty"`
+	SupplyCap       int64 `json:"supply_cap,omitempty"`
 	// BuildOrders: populated only for the consolidated "bo_openers" event at
 	// second 0 — one entry per (player × detected opener BO). The FE groups
 	// these by player to render one line per player and to label each starting
@@ -663,6 +693,30 @@ type workflowProductionEvent struct {
 	Count      int64  `json:"count"`
 }
 
+// workflowEconomyTimelinePlayer carries one player's estimated economy,
+// sampled every 10 seconds by the ingest-time simulation (see
+// earlyfilter.SimulateEconomy). It is a model of the command stream, not a
+// readout: the frontend labels it as an estimate. Empty for replays ingested
+// before the samples were stored, until they are re-analyzed.
+type workflowEconomyTimelinePlayer struct {
+	PlayerID  int64                   `json:"player_id"`
+	PlayerKey string                  `json:"player_key"`
+	Name      string                  `json:"name"`
+	Samples   []workflowEconomySample `json:"samples"`
+}
+
+type workflowEconomySample struct {
+	Second        int64 `json:"second"`
+	Minerals      int64 `json:"minerals"`
+	Gas           int64 `json:"gas"`
+	MineralIncome int64 `json:"mineral_income"`
+	GasIncome     int64 `json:"gas_income"`
+	SupplyUsed    int64 `json:"supply_used"`
+	SupplyMax     int64 `json:"supply_max"`
+	Workers       int64 `json:"workers"`
+	Bases         int64 `json:"bases"`
+}
+
 type workflowReplayTimings struct {
 	Gas       []workflowPlayerTimingSeries `json:"gas"`
 	Expansion []workflowPlayerTimingSeries `json:"expansion"`
@@ -770,6 +824,7 @@ type workflowPlayerOverview struct {
 	RaceOrders          []workflowRaceOrderSummary    `json:"race_orders"`
 	MatchupOrders       []workflowMatchupOrderSummary `json:"matchup_orders"`
 	EarlyTimings        []workflowPlayerEarlyTiming   `json:"early_timings"`
+	HotkeyLayouts       []workflowPlayerHotkeyLayout  `json:"hotkey_layouts"`
 }
 
 // workflowUnitCompositionUnit is one entry in the (player, phase)
@@ -971,6 +1026,9 @@ const (
 	workflowPlayerInsightTypeAPM                workflowPlayerInsightType = "apm"
 	workflowPlayerInsightTypeUnitCadence        workflowPlayerInsightType = "unit-production-cadence"
 	workflowPlayerInsightTypeViewportSwitchRate workflowPlayerInsightType = "viewport-switch-rate"
+	workflowPlayerInsightTypeSupplyBlocked      workflowPlayerInsightType = "supply-blocked"
+	workflowPlayerInsightTypeProductionIdle     workflowPlayerInsightType = "production-idle"
+	workflowPlayerInsightTypeLarvaMissed        workflowPlayerInsightType = "larva-missed"
 )
 
 type workflowPlayerInsightDetail struct {
@@ -1,11 +1,15 @@
 package dashboard
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"testing"
+
+	"github.com/marianogappa/screpdb/internal/storage"
 )
 
 func firstPlayerKey(t *testing.T, dash *Dashboard) string {
@@ -93,7 +97,7 @@ func TestPlayerInsightEndpoint_TypesAndErrors(t *testing.T) {
 	router := dash.setupRouter()
 	key := firstPlayerKey(t, dash)
 
-	for _, insightType := range []string{"apm", "unit-production-cadence", "viewport-switch-rate"} {
+	for _, insightType := range []string{"apm", "unit-production-cadence", "viewport-switch-rate", "supply-blocked", "production-idle", "larva-missed"} {
 		rec := performDashboardRequest(router, http.MethodGet, "/api/players/"+key+"/insight?type="+insightType, nil)
 		if rec.Code != http.StatusOK {
 			t.Fatalf("type %q status %d: %s", insightType, rec.Code, rec.Body.String())
@@ -399,6 +403,172 @@ func TestStaleReplaysCountEndpoint(t *testing.T) {
 	}
 }
 
+func TestIngestFailuresEndpoint(t *testing.T) {
+	dash := newTestDashboard(t)
+	store, err := storage.NewSQLiteStorage(dash.sqlitePath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage: %v", err)
+	}
+	defer store.Close()
+	if err := store.RecordIngestFailures(context.Background(), []storage.IngestFailure{{
+		FilePath:       "/replays/broken.rep",
+		ErrorClass:     storage.IngestFailureParseError,
+		Message:        "failed to parse replay: unexpected EOF",
+		ScrepdbVersion: "v1.0.0",
+	}}); err != nil {
+		t.Fatalf("RecordIngestFailures: %v", err)
+	}
+	router := dash.setupRouter()
+
+	rec := performDashboardRequest(router, http.MethodGet, "/api/custom/ingest/failures", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Count    int `json:"count"`
+		Failures []struct {
+			FilePath   string `json:"file_path"`
+			ErrorClass string `json:"error_class"`
+			Attempts   int    `json:"attempts"`
+		} `json:"failures"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.Count != 1 || len(resp.Failures) != 1 {
+		t.Fatalf("expected one failure, got %s", rec.Body.String())
+	}
+	if f := resp.Failures[0]; f.FilePath != "/replays/broken.rep" || f.ErrorClass != "parse_error" || f.Attempts != 1 {
+		t.Fatalf("unexpected failure: %+v", f)
+	}
+}
+
+func TestGamesListAndDetailCollapseDuplicatePOVs(t *testing.T) {
+	dash := newTestDashboard(t)
+	router := dash.setupRouter()
+
+	type listResp struct {
+		Total int64 `json:"total"`
+		Items []struct {
+			ReplayID int64 `json:"replay_id"`
+			POVCount int64 `json:"pov_count"`
+		} `json:"items"`
+	}
+	list := func() listResp {
+		t.Helper()
+		rec := performDashboardRequest(router, http.MethodGet, "/api/games?limit=200", nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("games status %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp listResp
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("unmarshal games: %v", err)
+		}
+		return resp
+	}
+
+	before := list()
+	if len(before.Items) < 2 {
+		t.Skip("need two visible games")
+	}
+	canonical, pov := before.Items[0].ReplayID, before.Items[1].ReplayID
+	// Pretend the second game is another player's recording of the first.
+	if _, err := dash.dbStore.DefaultExecContext(context.Background(),
+		"UPDATE replays SET game_group_id = ? WHERE id = ?", canonical, pov); err != nil {
+		t.Fatalf("group replays: %v", err)
+	}
+
+	after := list()
+	if after.Total != before.Total-1 {
+		t.Fatalf("expected total %d once the POVs are grouped, got %d", before.Total-1, after.Total)
+	}
+	for _, item := range after.Items {
+		if item.ReplayID == pov {
+			t.Fatalf("non-canonical POV %d should not be listed", pov)
+		}
+		if item.ReplayID == canonical && item.POVCount != 2 {
+			t.Fatalf("expected pov_count 2 for game %d, got %d", canonical, item.POVCount)
+		}
+	}
+
+	rec := performDashboardRequest(router, http.MethodGet, "/api/games/"+strconv.FormatInt(pov, 10), nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("detail of non-canonical POV: status %d: %s", rec.Code, rec.Body.String())
+	}
+	var detail struct {
+		ReplayID int64 `json:"replay_id"`
+		POVs     []struct {
+			ReplayID    int64 `json:"replay_id"`
+			IsCanonical bool  `json:"is_canonical"`
+		} `json:"povs"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &detail); err != nil {
+		t.Fatalf("unmarshal detail: %v", err)
+	}
+	if detail.ReplayID != pov {
+		t.Fatalf("expected detail for POV %d, got %d", pov, detail.ReplayID)
+	}
+	if len(detail.POVs) != 2 || detail.POVs[0].ReplayID != canonical || !detail.POVs[0].IsCanonical || detail.POVs[1].ReplayID != pov {
+		t.Fatalf("unexpected povs: %+v", detail.POVs)
+	}
+}
+
+func TestChatSearchEndpoint(t *testing.T) {
+	dash := newTestDashboard(t)
+	router := dash.setupRouter()
+
+	rec := performDashboardRequest(router, http.MethodGet, "/api/chat/search?q=GG!&context=1", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Count int `json:"count"`
+		Hits  []struct {
+			ReplayID int64  `json:"replay_id"`
+			Player   string `json:"player"`
+			Message  string `json:"message"`
+			Before   []struct {
+				Message string `json:"message"`
+			} `json:"before"`
+		} `json:"hits"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.Count == 0 || resp.Count != len(resp.Hits) {
+		t.Fatalf("expected gg hits, got %s", rec.Body.String())
+	}
+	for _, h := range resp.Hits {
+		if !strings.EqualFold(h.Message, "gg") || h.ReplayID == 0 || h.Player == "" {
+			t.Fatalf("unexpected hit: %+v", h)
+		}
+		if len(h.Before) > 1 {
+			t.Fatalf("context=1 returned %d earlier messages", len(h.Before))
+		}
+	}
+
+	player := url.QueryEscape(resp.Hits[0].Player)
+	rec = performDashboardRequest(router, http.MethodGet, "/api/chat/search?q=gg&context=0&player="+player, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	for _, h := range resp.Hits {
+		if !strings.EqualFold(h.Player, resp.Hits[0].Player) || len(h.Before) != 0 {
+			t.Fatalf("player-filtered hit without context expected, got %+v", h)
+		}
+	}
+
+	for _, query := range []string{"", "?q=%5E%5E", "?q=gg&from=yesterday"} {
+		rec = performDashboardRequest(router, http.MethodGet, "/api/chat/search"+query, nil)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: status %d, want 400: %s", query, rec.Code, rec.Body.String())
+		}
+	}
+}
+
 func TestGlobalReplayFilterEndpoints(t *testing.T) {
 	dash := newTestDashboard(t)
 	router := dash.setupRouter()
@@ -427,3 +597,61 @@ func TestGlobalReplayFilterEndpoints(t *testing.T) {
 		t.Fatal("expected exclude_short_games true to round-trip")
 	}
 }
+
+func TestBackupEndpoints(t *testing.T) {
+	t.Setenv("SCREPDB_APPDATA_DIR", t.TempDir())
+	dash := newTestDashboard(t)
+	router := dash.setupRouter()
+
+	rec := performDashboardRequest(router, http.MethodPost, "/api/custom/backups", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create status %d: %s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Backup struct {
+			Name      string `json:"name"`
+			SizeBytes int64  `json:"size_bytes"`
+		} `json:"backup"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if created.Backup.Name == "" || created.Backup.SizeBytes == 0 {
+		t.Fatalf("unexpected create response: %s", rec.Body.String())
+	}
+
+	rec = performDashboardRequest(router, http.MethodGet, "/api/custom/backups", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list status %d: %s", rec.Code, rec.Body.String())
+	}
+	var listed struct {
+		Count   int `json:"count"`
+		Backups []struct {
+			Name string `json:"name"`
+		} `json:"backups"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &listed); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if listed.Count != 1 || listed.Backups[0].Name != created.Backup.Name {
+		t.Fatalf("unexpected list response: %s", rec.Body.String())
+	}
+
+	rec = performDashboardRequest(router, http.MethodGet, "/api/custom/backups/"+created.Backup.Name, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("download status %d: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Header().Get("Content-Disposition"), created.Backup.Name) {
+		t.Errorf("Content-Disposition = %q", rec.Header().Get("Content-Disposition"))
+	}
+	if int64(rec.Body.Len()) != created.Backup.SizeBytes || !strings.HasPrefix(rec.Body.String(), "SQLite format 3") {
+		t.Errorf("downloaded %d bytes, want a %d-byte SQLite file", rec.Body.Len(), created.Backup.SizeBytes)
+	}
+
+	if rec := performDashboardRequest(router, http.MethodGet, "/api/custom/backups/screp-20000101-000000.db", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("missing backup status %d, want 404", rec.Code)
+	}
+	if rec := performDashboardRequest(router, http.MethodGet, "/api/custom/backups/screp.db", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("non-backup name status %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,371 @@
+package dashboard
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/marianogappa/screpdb/internal/dashboard/db"
+)
+
+// The missed-larvae proxy is the share of a Zerg player's larva spawns lost
+// because the hatchery already held three, from the larva timelines ingest
+// estimates from selection tags and the larva timer (see
+// unittags.HatcheryLarvae). Every hatchery's spawns count the same, so a
+// player with more hatcheries weighs their banking more. Lower is better.
+const workflowLarvaMinGames int64 = 4
+
+// workflowLarvaCap is how many larvae a hatchery holds; at the cap its
+// spawns are missed.
+const workflowLarvaCap = 3
+
+type workflowLarvaCount struct {
+	Second int64 `json:"second"`
+	Larvae int64 `json:"larvae"`
+}
+
+type workflowLarvaBankedSpan struct {
+	StartSecond int64 `json:"start_second"`
+	EndSecond   int64 `json:"end_second"`
+}
+
+type workflowLarvaHatchery struct {
+	Index           int64                     `json:"index"`
+	ReadySecond     int64                     `json:"ready_second"`
+	WindowEndSecond int64                     `json:"window_end_second"`
+	Spawned         int64                     `json:"spawned"`
+	Used            int64                     `json:"used"`
+	Missed          int64                     `json:"missed"`
+	BankedSeconds   int64                     `json:"banked_seconds"`
+	Counts          []workflowLarvaCount      `json:"counts"`
+	Banked          []workflowLarvaBankedSpan `json:"banked"`
+}
+
+type workflowHatcheryCount struct {
+	Second     int64 `json:"second"`
+	Hatcheries int64 `json:"hatcheries"`
+}
+
+type workflowLarvaPhase struct {
+	Phase         string  `json:"phase"`
+	Label         string  `json:"label"`
+	Spawned       int64   `json:"spawned"`
+	Used          int64   `json:"used"`
+	Missed        int64   `json:"missed"`
+	MissedPercent float64 `json:"missed_percent"`
+}
+
+type workflowGameLarvaPlayer struct {
+	PlayerID       int64                   `json:"player_id"`
+	PlayerKey      string                  `json:"player_key"`
+	PlayerName     string                  `json:"player_name"`
+	Team           int64                   `json:"team"`
+	IsWinner       bool                    `json:"is_winner"`
+	Spawned        int64                   `json:"spawned"`
+	Used           int64                   `json:"used"`
+	Missed         int64                   `json:"missed"`
+	MissedPercent  float64                 `json:"missed_percent"`
+	Phases         []workflowLarvaPhase    `json:"phases"`
+	HatcheryCounts []workflowHatcheryCount `json:"hatchery_counts"`
+	Hatcheries     []workflowLarvaHatchery `json:"hatcheries"`
+}
+
+// larvaTally sums larvae spawned, used and missed per phase.
+type larvaTally struct {
+	spawned [3]int64
+	used    [3]int64
+	missed  [3]int64
+}
+
+// totals sums phase i, or the whole game when i < 0.
+func (t larvaTally) totals(i int) (spawned, used, missed int64) {
+	for j := range t.spawned {
+		if i < 0 || i == j {
+			spawned += t.spawned[j]
+			used += t.used[j]
+			missed += t.missed[j]
+		}
+	}
+	return spawned, used, missed
+}
+
+// missedPercent is the missed share of the spawns of phase i, or of the
+// whole game when i < 0, and false when no hatchery stood then.
+func (t larvaTally) missedPercent(i int) (float64, bool) {
+	spawned, _, missed := t.totals(i)
+	if spawned+missed <= 0 {
+		return 0, false
+	}
+	return 100 * float64(missed) / float64(spawned+missed), true
+}
+
+// phaseIndexAt returns the phase second falls in (see productionPhaseBounds).
+func phaseIndexAt(second int64, bounds [3][2]int64) int {
+	for i, b := range bounds {
+		if second >= b[0] && second < b[1] {
+			return i
+		}
+	}
+	return 0
+}
+
+// tallyLarvaSamples sums one player-game's samples by phase.
+func tallyLarvaSamples(samples []db.LarvaSampleRow, bounds [3][2]int64) larvaTally {
+	var tally larvaTally
+	for _, sample := range samples {
+		phase := phaseIndexAt(sample.Second, bounds)
+		tally.spawned[phase] += sample.Spawned
+		tally.used[phase] += sample.Used
+		tally.missed[phase] += sample.Missed
+	}
+	return tally
+}
+
+// buildLarvaHatcheries groups one player's samples, which come ordered by
+// hatchery and second, into hatcheries with their banked spans: the
+// stretches spent at the cap, up to the player's window end.
+func buildLarvaHatcheries(samples []db.LarvaSampleRow) []workflowLarvaHatchery {
+	var out []workflowLarvaHatchery
+	for i, sample := range samples {
+		if i == 0 || sample.HatcheryIndex != samples[i-1].HatcheryIndex {
+			out = append(out, workflowLarvaHatchery{
+				Index:           sample.HatcheryIndex,
+				ReadySecond:     sample.Second,
+				WindowEndSecond: sample.WindowEndSecond,
+				Counts:          []workflowLarvaCount{},
+				Banked:          []workflowLarvaBankedSpan{},
+			})
+		}
+		hatchery := &out[len(out)-1]
+		hatchery.Spawned += sample.Spawned
+		hatchery.Used += sample.Used
+		hatchery.Missed += sample.Missed
+		hatchery.Counts = append(hatchery.Counts, workflowLarvaCount{Second: sample.Second, Larvae: sample.Larvae})
+		n := len(hatchery.Banked)
+		open := n > 0 && hatchery.Banked[n-1].EndSecond < 0
+		switch {
+		case sample.Larvae >= workflowLarvaCap && !open:
+			hatchery.Banked = append(hatchery.Banked, workflowLarvaBankedSpan{StartSecond: sample.Second, EndSecond: -1})
+		case sample.Larvae < workflowLarvaCap && open:
+			hatchery.Banked[n-1].EndSecond = sample.Second
+		}
+	}
+	for i := range out {
+		hatchery := &out[i]
+		if n := len(hatchery.Banked); n > 0 && hatchery.Banked[n-1].EndSecond < 0 {
+			hatchery.Banked[n-1].EndSecond = max(hatchery.Banked[n-1].StartSecond, hatchery.WindowEndSecond)
+		}
+		for _, span := range hatchery.Banked {
+			hatchery.BankedSeconds += span.EndSecond - span.StartSecond
+		}
+	}
+	return out
+}
+
+// populateLarvaForGameDetail attaches each Zerg player's hatcheries, their
+// larva counts and banked spans, the hatchery count over time, and larvae
+// spawned, used and missed overall and per phase. Players without a larva
+// estimate are left out, so an empty list hides the game page's tab.
+func (d *Dashboard) populateLarvaForGameDetail(detail *workflowGameDetail) error {
+	detail.Larva = []workflowGameLarvaPlayer{}
+	rows, err := d.dbStore.ListReplayLarvaSamples(d.ctx, detail.ReplayID)
+	if err != nil {
+		return fmt.Errorf("failed to load larva samples: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	boundaries, err := d.dbStore.GetPhaseBoundariesForReplay(d.ctx, detail.ReplayID)
+	if err != nil {
+		return fmt.Errorf("failed to load phase boundaries: %w", err)
+	}
+	bounds := productionPhaseBounds(boundaries.EarlyEndsAtSecond, boundaries.MidEndsAtSecond)
+	samplesByPlayer := map[int64][]db.LarvaSampleRow{}
+	for _, row := range rows {
+		samplesByPlayer[row.PlayerID] = append(samplesByPlayer[row.PlayerID], row)
+	}
+
+	for _, player := range detail.Players {
+		samples := samplesByPlayer[player.PlayerID]
+		if len(samples) == 0 {
+			continue
+		}
+		tally := tallyLarvaSamples(samples, bounds)
+		entry := workflowGameLarvaPlayer{
+			PlayerID:       player.PlayerID,
+			PlayerKey:      player.PlayerKey,
+			PlayerName:     player.Name,
+			Team:           player.Team,
+			IsWinner:       player.IsWinner,
+			Phases:         []workflowLarvaPhase{},
+			HatcheryCounts: []workflowHatcheryCount{},
+			Hatcheries:     buildLarvaHatcheries(samples),
+		}
+		entry.Spawned, entry.Used, entry.Missed = tally.totals(-1)
+		entry.MissedPercent, _ = tally.missedPercent(-1)
+		for i, phase := range workflowProductionPhases {
+			missedPercent, ok := tally.missedPercent(i)
+			if !ok {
+				continue
+			}
+			spawned, used, missed := tally.totals(i)
+			entry.Phases = append(entry.Phases, workflowLarvaPhase{
+				Phase:         phase.Key,
+				Label:         phase.Label,
+				Spawned:       spawned,
+				Used:          used,
+				Missed:        missed,
+				MissedPercent: missedPercent,
+			})
+		}
+		readySeconds := make([]int64, 0, len(entry.Hatcheries))
+		for _, hatchery := range entry.Hatcheries {
+			readySeconds = append(readySeconds, hatchery.ReadySecond)
+		}
+		sort.Slice(readySeconds, func(i, j int) bool { return readySeconds[i] < readySeconds[j] })
+		for i, second := range readySeconds {
+			entry.HatcheryCounts = append(entry.HatcheryCounts, workflowHatcheryCount{Second: second, Hatcheries: int64(i + 1)})
+		}
+		detail.Larva = append(detail.Larva, entry)
+	}
+	return nil
+}
+
+type workflowLarvaAggregate struct {
+	PlayerKey   string
+	PlayerName  string
+	GamesPlayed int64
+	tally       larvaTally
+}
+
+func (d *Dashboard) buildWorkflowPlayerLarvaAsyncInsight(playerKey string) (workflowPlayerAsyncInsight, error) {
+	allPlayers, err := d.loadWorkflowLarvaAggregates()
+	if err != nil {
+		return workflowPlayerAsyncInsight{}, err
+	}
+	eligible := make([]workflowLarvaAggregate, 0, len(allPlayers))
+	for _, player := range allPlayers {
+		if player.GamesPlayed >= workflowLarvaMinGames {
+			eligible = append(eligible, player)
+		}
+	}
+	playerName, err := d.playerNameForKey(playerKey)
+	if err != nil {
+		return workflowPlayerAsyncInsight{}, err
+	}
+	result := workflowPlayerAsyncInsight{
+		SummaryVersion:  workflowSummaryVersion,
+		PlayerKey:       playerKey,
+		PlayerName:      playerName,
+		InsightType:     workflowPlayerInsightTypeLarvaMissed,
+		Title:           "Missed larvae",
+		BetterDirection: "lower",
+		PopulationSize:  int64(len(eligible)),
+		Description:     "Share of larva spawns a Zerg player's hatcheries lost because they already held three larvae, over all their Zerg games, overall and per phase. Larvae are estimated from which hatchery each morph's larvae came from and the 14.4-second larva timer, so a destroyed or cancelled Hatchery keeps spawning, and larvae banked while maxed out count too. Lower is better.",
+	}
+
+	values := make([]float64, 0, len(eligible))
+	for _, player := range eligible {
+		missed, _ := player.tally.missedPercent(-1)
+		values = append(values, missed)
+	}
+	sort.Float64s(values)
+	populationMean := meanFloatSlice(values)
+	result.Details = append(result.Details,
+		workflowPlayerInsightDetail{Label: "Eligible players", Value: fmt.Sprintf("%d (minimum %d Zerg games)", len(eligible), workflowLarvaMinGames)},
+		workflowPlayerInsightDetail{Label: "Population mean", Value: fmt.Sprintf("%.1f%% missed", populationMean)},
+		workflowPlayerInsightDetail{Label: "Population stddev", Value: fmt.Sprintf("%.1f", stddevFloatSlice(values, populationMean))},
+	)
+
+	var playerSummary *workflowLarvaAggregate
+	for i := range allPlayers {
+		if allPlayers[i].PlayerKey == playerKey {
+			playerSummary = &allPlayers[i]
+			break
+		}
+	}
+	if playerSummary == nil {
+		result.IneligibleReason = "No Zerg games with a larva estimate were found for this player yet."
+		return result, nil
+	}
+	spawned, used, _ := playerSummary.tally.totals(-1)
+	result.Details = append(result.Details,
+		workflowPlayerInsightDetail{Label: "Player Zerg games", Value: strconv.FormatInt(playerSummary.GamesPlayed, 10)},
+		workflowPlayerInsightDetail{Label: "Larvae per game", Value: fmt.Sprintf("%.1f spawned, %.1f used", float64(spawned)/float64(playerSummary.GamesPlayed), float64(used)/float64(playerSummary.GamesPlayed))},
+	)
+	if playerSummary.GamesPlayed < workflowLarvaMinGames {
+		result.IneligibleReason = fmt.Sprintf("Not enough Zerg games yet. This view currently requires at least %d games.", workflowLarvaMinGames)
+		return result, nil
+	}
+
+	for i, phase := range workflowProductionPhases {
+		missed, ok := playerSummary.tally.missedPercent(i)
+		if !ok {
+			continue
+		}
+		phaseValues := make([]float64, 0, len(eligible))
+		for _, player := range eligible {
+			if v, ok := player.tally.missedPercent(i); ok {
+				phaseValues = append(phaseValues, v)
+			}
+		}
+		result.Details = append(result.Details, workflowPlayerInsightDetail{
+			Label: phase.Label,
+			Value: fmt.Sprintf("%.1f%% missed (population mean %.1f%%)", missed, meanFloatSlice(phaseValues)),
+		})
+	}
+
+	value, _ := playerSummary.tally.missedPercent(-1)
+	percentile := performancePercentileFromSortedValues(values, value, true)
+	result.Eligible = true
+	result.PerformancePercentile = &percentile
+	result.PlayerValue = &value
+	result.PlayerValueLabel = fmt.Sprintf("%.1f%% missed", value)
+	return result, nil
+}
+
+// loadWorkflowLarvaAggregates sums every Zerg player's larvae over their
+// games, fewest missed first.
+func (d *Dashboard) loadWorkflowLarvaAggregates() ([]workflowLarvaAggregate, error) {
+	rows, err := d.dbStore.ListLarvaPlayerPhaseTotals(d.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load larva totals: %w", err)
+	}
+	aggregates := map[string]*workflowLarvaAggregate{}
+	var (
+		gameKey    string
+		gameReplay int64
+	)
+	for _, row := range rows {
+		aggregate := aggregates[row.PlayerKey]
+		if aggregate == nil {
+			aggregate = &workflowLarvaAggregate{PlayerKey: row.PlayerKey, PlayerName: row.PlayerName}
+			aggregates[row.PlayerKey] = aggregate
+		}
+		if row.PlayerKey != gameKey || row.ReplayID != gameReplay {
+			gameKey, gameReplay = row.PlayerKey, row.ReplayID
+			aggregate.GamesPlayed++
+		}
+		phase := int(row.Phase)
+		if phase < 0 || phase >= len(aggregate.tally.spawned) {
+			continue
+		}
+		aggregate.tally.spawned[phase] += row.Spawned
+		aggregate.tally.used[phase] += row.Used
+		aggregate.tally.missed[phase] += row.Missed
+	}
+
+	out := make([]workflowLarvaAggregate, 0, len(aggregates))
+	for _, aggregate := range aggregates {
+		out = append(out, *aggregate)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, _ := out[i].tally.missedPercent(-1)
+		b, _ := out[j].tally.missedPercent(-1)
+		if a == b {
+			return out[i].PlayerKey < out[j].PlayerKey
+		}
+		return a < b
+	})
+	return out, nil
+}
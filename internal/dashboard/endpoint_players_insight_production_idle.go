@@ -0,0 +1,357 @@
+package dashboard
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/marianogappa/screpdb/internal/dashboard/db"
+)
+
+// The production-idle proxy is the share of time a player's Gateways,
+// Barracks and Factories stood ready but trained nothing, from the busy spans
+// ingest estimates from selection tags (see unittags.ProducerTimelines). It
+// is time-weighted: every building-second counts the same, so a player with
+// more buildings weighs their idle time more. Lower is better.
+const workflowProductionIdleMinGames int64 = 4
+
+// workflowProductionPhases are the phases idle time is split by, in order,
+// as cut by the replay's mid_game_starts / late_game_starts markers.
+var workflowProductionPhases = []struct {
+	Key   string
+	Label string
+}{
+	{Key: "early", Label: "Early game"},
+	{Key: "mid", Label: "Mid game"},
+	{Key: "late", Label: "Late game"},
+}
+
+type workflowProductionBusySpan struct {
+	StartSecond int64 `json:"start_second"`
+	EndSecond   int64 `json:"end_second"`
+}
+
+type workflowProductionBuilding struct {
+	Building        string                       `json:"building"`
+	Index           int64                        `json:"index"`
+	ReadySecond     int64                        `json:"ready_second"`
+	WindowEndSecond int64                        `json:"window_end_second"`
+	BusySeconds     int64                        `json:"busy_seconds"`
+	IdlePercent     float64                      `json:"idle_percent"`
+	Busy            []workflowProductionBusySpan `json:"busy"`
+}
+
+type workflowProductionPhaseIdle struct {
+	Phase            string  `json:"phase"`
+	Label            string  `json:"label"`
+	AvailableSeconds int64   `json:"available_seconds"`
+	IdlePercent      float64 `json:"idle_percent"`
+}
+
+type workflowGameProductionIdlePlayer struct {
+	PlayerID    int64                         `json:"player_id"`
+	PlayerKey   string                        `json:"player_key"`
+	PlayerName  string                        `json:"player_name"`
+	Team        int64                         `json:"team"`
+	IsWinner    bool                          `json:"is_winner"`
+	IdlePercent float64                       `json:"idle_percent"`
+	Phases      []workflowProductionPhaseIdle `json:"phases"`
+	Buildings   []workflowProductionBuilding  `json:"buildings"`
+}
+
+// productionIdleTally sums building-seconds a building stood ready
+// (available) and spent training (busy), per phase.
+type productionIdleTally struct {
+	available [3]int64
+	busy      [3]int64
+}
+
+// productionPhaseBounds returns each phase's [start, end) second; a phase the
+// game never reached is empty, and the last reached one is open-ended.
+func productionPhaseBounds(earlyEndsAt, midEndsAt int64) [3][2]int64 {
+	const open = math.MaxInt64
+	bounds := [3][2]int64{{0, open}, {open, open}, {open, open}}
+	if earlyEndsAt > 0 {
+		bounds[0][1] = earlyEndsAt
+		bounds[1] = [2]int64{earlyEndsAt, open}
+		if midEndsAt > earlyEndsAt {
+			bounds[1][1] = midEndsAt
+			bounds[2] = [2]int64{midEndsAt, open}
+		}
+	}
+	return bounds
+}
+
+func overlapSeconds(start, end, from, to int64) int64 {
+	return max(0, min(end, to)-max(start, from))
+}
+
+// addWindow counts a building's ready window, addBusy one of its spans.
+func (t *productionIdleTally) addWindow(ready, windowEnd int64, bounds [3][2]int64) {
+	for i, b := range bounds {
+		t.available[i] += overlapSeconds(ready, windowEnd, b[0], b[1])
+	}
+}
+
+func (t *productionIdleTally) addBusy(start, end int64, bounds [3][2]int64) {
+	for i, b := range bounds {
+		t.busy[i] += overlapSeconds(start, end, b[0], b[1])
+	}
+}
+
+func (t *productionIdleTally) add(other productionIdleTally) {
+	for i := range t.available {
+		t.available[i] += other.available[i]
+		t.busy[i] += other.busy[i]
+	}
+}
+
+// idlePercent is the idle share of phase i, or of the whole game when i < 0,
+// and false when no building stood ready then.
+func (t productionIdleTally) idlePercent(i int) (float64, bool) {
+	var available, busy int64
+	for j := range t.available {
+		if i < 0 || i == j {
+			available += t.available[j]
+			busy += t.busy[j]
+		}
+	}
+	if available <= 0 {
+		return 0, false
+	}
+	return 100 * float64(available-busy) / float64(available), true
+}
+
+// tallyProductionSpans sums the spans of one player-game. Spans come ordered
+// by building, so a building's window is counted once, on its first span.
+func tallyProductionSpans(spans []db.ProductionBusySpanRow, bounds [3][2]int64) productionIdleTally {
+	var tally productionIdleTally
+	for i, span := range spans {
+		if i == 0 || span.Building != spans[i-1].Building || span.BuildingIndex != spans[i-1].BuildingIndex {
+			tally.addWindow(span.ReadySecond, span.WindowEndSecond, bounds)
+		}
+		tally.addBusy(span.StartSecond, span.EndSecond, bounds)
+	}
+	return tally
+}
+
+// populateProductionIdleForGameDetail attaches each player's production
+// buildings, their busy spans and idle share overall and per phase. Players
+// without a Gateway, Barracks or Factory the estimate could follow are left
+// out, so an empty list hides the game page's tab.
+func (d *Dashboard) populateProductionIdleForGameDetail(detail *workflowGameDetail) error {
+	detail.ProductionIdle = []workflowGameProductionIdlePlayer{}
+	rows, err := d.dbStore.ListReplayProductionBusySpans(d.ctx, detail.ReplayID)
+	if err != nil {
+		return fmt.Errorf("failed to load production busy spans: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	boundaries, err := d.dbStore.GetPhaseBoundariesForReplay(d.ctx, detail.ReplayID)
+	if err != nil {
+		return fmt.Errorf("failed to load phase boundaries: %w", err)
+	}
+	bounds := productionPhaseBounds(boundaries.EarlyEndsAtSecond, boundaries.MidEndsAtSecond)
+	spansByPlayer := map[int64][]db.ProductionBusySpanRow{}
+	for _, row := range rows {
+		spansByPlayer[row.PlayerID] = append(spansByPlayer[row.PlayerID], row)
+	}
+
+	for _, player := range detail.Players {
+		spans := spansByPlayer[player.PlayerID]
+		if len(spans) == 0 {
+			continue
+		}
+		tally := tallyProductionSpans(spans, bounds)
+		entry := workflowGameProductionIdlePlayer{
+			PlayerID:   player.PlayerID,
+			PlayerKey:  player.PlayerKey,
+			PlayerName: player.Name,
+			Team:       player.Team,
+			IsWinner:   player.IsWinner,
+			Phases:     []workflowProductionPhaseIdle{},
+			Buildings:  []workflowProductionBuilding{},
+		}
+		entry.IdlePercent, _ = tally.idlePercent(-1)
+		for i, phase := range workflowProductionPhases {
+			idle, ok := tally.idlePercent(i)
+			if !ok {
+				continue
+			}
+			entry.Phases = append(entry.Phases, workflowProductionPhaseIdle{
+				Phase:            phase.Key,
+				Label:            phase.Label,
+				AvailableSeconds: tally.available[i],
+				IdlePercent:      idle,
+			})
+		}
+		for _, span := range spans {
+			n := len(entry.Buildings)
+			if n == 0 || entry.Buildings[n-1].Building != span.Building || entry.Buildings[n-1].Index != span.BuildingIndex {
+				entry.Buildings = append(entry.Buildings, workflowProductionBuilding{
+					Building:        span.Building,
+					Index:           span.BuildingIndex,
+					ReadySecond:     span.ReadySecond,
+					WindowEndSecond: span.WindowEndSecond,
+					Busy:            []workflowProductionBusySpan{},
+				})
+				n++
+			}
+			building := &entry.Buildings[n-1]
+			building.Busy = append(building.Busy, workflowProductionBusySpan{StartSecond: span.StartSecond, EndSecond: span.EndSecond})
+			building.BusySeconds += span.EndSecond - span.StartSecond
+		}
+		for i := range entry.Buildings {
+			building := &entry.Buildings[i]
+			if window := building.WindowEndSecond - building.ReadySecond; window > 0 {
+				building.IdlePercent = 100 * float64(window-building.BusySeconds) / float64(window)
+			}
+		}
+		sort.SliceStable(entry.Buildings, func(i, j int) bool {
+			return entry.Buildings[i].ReadySecond < entry.Buildings[j].ReadySecond
+		})
+		detail.ProductionIdle = append(detail.ProductionIdle, entry)
+	}
+	return nil
+}
+
+type workflowProductionIdleAggregate struct {
+	PlayerKey   string
+	PlayerName  string
+	GamesPlayed int64
+	tally       productionIdleTally
+}
+
+func (d *Dashboard) buildWorkflowPlayerProductionIdleAsyncInsight(playerKey string) (workflowPlayerAsyncInsight, error) {
+	allPlayers, err := d.loadWorkflowProductionIdleAggregates()
+	if err != nil {
+		return workflowPlayerAsyncInsight{}, err
+	}
+	eligible := make([]workflowProductionIdleAggregate, 0, len(allPlayers))
+	for _, player := range allPlayers {
+		if player.GamesPlayed >= workflowProductionIdleMinGames {
+			eligible = append(eligible, player)
+		}
+	}
+	playerName, err := d.playerNameForKey(playerKey)
+	if err != nil {
+		return workflowPlayerAsyncInsight{}, err
+	}
+	result := workflowPlayerAsyncInsight{
+		SummaryVersion:  workflowSummaryVersion,
+		PlayerKey:       playerKey,
+		PlayerName:      playerName,
+		InsightType:     workflowPlayerInsightTypeProductionIdle,
+		Title:           "Production idle time",
+		BetterDirection: "lower",
+		PopulationSize:  int64(len(eligible)),
+		Description:     "Share of the time a player's Gateways, Barracks and Factories stood ready but trained nothing, over all their games, overall and per phase. Busy time is estimated from which building each Train command selected and the unit's build time, so refused or cancelled units still count as busy. Lower is better.",
+	}
+
+	values := make([]float64, 0, len(eligible))
+	for _, player := range eligible {
+		idle, _ := player.tally.idlePercent(-1)
+		values = append(values, idle)
+	}
+	sort.Float64s(values)
+	populationMean := meanFloatSlice(values)
+	result.Details = append(result.Details,
+		workflowPlayerInsightDetail{Label: "Eligible players", Value: fmt.Sprintf("%d (minimum %d games)", len(eligible), workflowProductionIdleMinGames)},
+		workflowPlayerInsightDetail{Label: "Population mean", Value: fmt.Sprintf("%.1f%% idle", populationMean)},
+		workflowPlayerInsightDetail{Label: "Population stddev", Value: fmt.Sprintf("%.1f", stddevFloatSlice(values, populationMean))},
+	)
+
+	var playerSummary *workflowProductionIdleAggregate
+	for i := range allPlayers {
+		if allPlayers[i].PlayerKey == playerKey {
+			playerSummary = &allPlayers[i]
+			break
+		}
+	}
+	if playerSummary == nil {
+		result.IneligibleReason = "No games with a Gateway, Barracks or Factory production estimate were found for this player yet."
+		return result, nil
+	}
+	result.Details = append(result.Details, workflowPlayerInsightDetail{Label: "Player games", Value: strconv.FormatInt(playerSummary.GamesPlayed, 10)})
+	if playerSummary.GamesPlayed < workflowProductionIdleMinGames {
+		result.IneligibleReason = fmt.Sprintf("Not enough games with Gateways, Barracks or Factories yet. This view currently requires at least %d games.", workflowProductionIdleMinGames)
+		return result, nil
+	}
+
+	for i, phase := range workflowProductionPhases {
+		idle, ok := playerSummary.tally.idlePercent(i)
+		if !ok {
+			continue
+		}
+		phaseValues := make([]float64, 0, len(eligible))
+		for _, player := range eligible {
+			if v, ok := player.tally.idlePercent(i); ok {
+				phaseValues = append(phaseValues, v)
+			}
+		}
+		result.Details = append(result.Details, workflowPlayerInsightDetail{
+			Label: phase.Label,
+			Value: fmt.Sprintf("%.1f%% idle (population mean %.1f%%)", idle, meanFloatSlice(phaseValues)),
+		})
+	}
+
+	value, _ := playerSummary.tally.idlePercent(-1)
+	percentile := performancePercentileFromSortedValues(values, value, true)
+	result.Eligible = true
+	result.PerformancePercentile = &percentile
+	result.PlayerValue = &value
+	result.PlayerValueLabel = fmt.Sprintf("%.1f%% idle", value)
+	return result, nil
+}
+
+// loadWorkflowProductionIdleAggregates sums every player's production
+// building-seconds over their games, least idle first.
+func (d *Dashboard) loadWorkflowProductionIdleAggregates() ([]workflowProductionIdleAggregate, error) {
+	rows, err := d.dbStore.ListProductionIdlePlayerRows(d.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load production busy rows: %w", err)
+	}
+	aggregates := map[string]*workflowProductionIdleAggregate{}
+	flush := func(key string, spans []db.ProductionBusySpanRow, bounds [3][2]int64) {
+		if len(spans) == 0 {
+			return
+		}
+		aggregate := aggregates[key]
+		aggregate.GamesPlayed++
+		aggregate.tally.add(tallyProductionSpans(spans, bounds))
+	}
+	var (
+		gameKey    string
+		gameReplay int64
+		gameBounds [3][2]int64
+		gameSpans  []db.ProductionBusySpanRow
+	)
+	for _, row := range rows {
+		if aggregates[row.PlayerKey] == nil {
+			aggregates[row.PlayerKey] = &workflowProductionIdleAggregate{PlayerKey: row.PlayerKey, PlayerName: row.PlayerName}
+		}
+		if row.PlayerKey != gameKey || row.ReplayID != gameReplay {
+			flush(gameKey, gameSpans, gameBounds)
+			gameKey, gameReplay, gameSpans = row.PlayerKey, row.ReplayID, nil
+			gameBounds = productionPhaseBounds(row.EarlyEndsAtSecond, row.MidEndsAtSecond)
+		}
+		gameSpans = append(gameSpans, row.Span)
+	}
+	flush(gameKey, gameSpans, gameBounds)
+
+	out := make([]workflowProductionIdleAggregate, 0, len(aggregates))
+	for _, aggregate := range aggregates {
+		out = append(out, *aggregate)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, _ := out[i].tally.idlePercent(-1)
+		b, _ := out[j].tally.idlePercent(-1)
+		if a == b {
+			return out[i].PlayerKey < out[j].PlayerKey
+		}
+		return a < b
+	})
+	return out, nil
+}
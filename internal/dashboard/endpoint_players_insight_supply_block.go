@@ -0,0 +1,250 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The supply-block proxy counts the seconds a player sat supply blocked
+// before 10:00, from the supply_block game events the ingest-time economy
+// simulation writes (see earlyfilter.SimulateEconomy). Lower is better. The
+// population comparison only counts games that reached 10:00, so every game
+// weighs the same window.
+const workflowSupplyBlockWindowSeconds int64 = 600
+const workflowSupplyBlockMinGames int64 = 4
+
+type workflowSupplyBlockPeriod struct {
+	Second          int64 `json:"second"`
+	DurationSeconds int64 `json:"duration_seconds"`
+	Supply          int64 `json:"supply"`
+}
+
+type workflowGameSupplyBlockPlayer struct {
+	PlayerID                int64                       `json:"player_id"`
+	PlayerKey               string                      `json:"player_key"`
+	PlayerName              string                      `json:"player_name"`
+	Team                    int64                       `json:"team"`
+	IsWinner                bool                        `json:"is_winner"`
+	Eligible                bool                        `json:"eligible"`
+	IneligibleReason        string                      `json:"ineligible_reason,omitempty"`
+	SecondsBlockedBefore10m int64                       `json:"seconds_blocked_before_10m"`
+	BlocksBefore10m         int64                       `json:"blocks_before_10m"`
+	TotalSecondsBlocked     int64                       `json:"total_seconds_blocked"`
+	Blocks                  []workflowSupplyBlockPeriod `json:"blocks"`
+}
+
+type workflowSupplyBlockAggregate struct {
+	PlayerKey      string
+	PlayerName     string
+	GamesPlayed    int64
+	averageSeconds float64
+}
+
+// parseSupplyBlockPayload reads a supply_block event's
+// {"duration_seconds": N, "supply": M} payload.
+func parseSupplyBlockPayload(raw string) (workflowSupplyBlockPeriod, bool) {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" {
+		return workflowSupplyBlockPeriod{}, false
+	}
+	var payload struct {
+		DurationSeconds int64 `json:"duration_seconds"`
+		Supply          int64 `json:"supply"`
+	}
+	if err := json.Unmarshal([]byte(trimmed), &payload); err != nil || payload.DurationSeconds <= 0 {
+		return workflowSupplyBlockPeriod{}, false
+	}
+	return workflowSupplyBlockPeriod{DurationSeconds: payload.DurationSeconds, Supply: payload.Supply}, true
+}
+
+// supplyBlockedSecondsInWindow is the part of a block that falls before
+// workflowSupplyBlockWindowSeconds.
+func supplyBlockedSecondsInWindow(block workflowSupplyBlockPeriod) int64 {
+	if block.Second >= workflowSupplyBlockWindowSeconds {
+		return 0
+	}
+	return min(block.Second+block.DurationSeconds, workflowSupplyBlockWindowSeconds) - block.Second
+}
+
+// populateSupplyBlocksForGameDetail attaches each player's supply blocks and
+// seconds blocked before 10:00. It runs after
+// populateEconomyTimelineForGameDetail: a player without economy samples
+// wasn't analyzed by the simulation, so no blocks doesn't mean none happened.
+func (d *Dashboard) populateSupplyBlocksForGameDetail(detail *workflowGameDetail) error {
+	if detail == nil {
+		return nil
+	}
+	simulated := map[int64]bool{}
+	for _, entry := range detail.EconomyTimeline {
+		simulated[entry.PlayerID] = true
+	}
+	detail.SupplyBlocks = []workflowGameSupplyBlockPlayer{}
+	indexByPlayerID := map[int64]int{}
+	for _, player := range detail.Players {
+		entry := workflowGameSupplyBlockPlayer{
+			PlayerID:   player.PlayerID,
+			PlayerKey:  player.PlayerKey,
+			PlayerName: player.Name,
+			Team:       player.Team,
+			IsWinner:   player.IsWinner,
+			Eligible:   simulated[player.PlayerID],
+			Blocks:     []workflowSupplyBlockPeriod{},
+		}
+		if !entry.Eligible {
+			entry.IneligibleReason = "no economy simulation for this replay yet; re-analyze it"
+		}
+		indexByPlayerID[player.PlayerID] = len(detail.SupplyBlocks)
+		detail.SupplyBlocks = append(detail.SupplyBlocks, entry)
+	}
+	if len(simulated) == 0 {
+		return nil
+	}
+
+	rows, err := d.dbStore.ListReplaySupplyBlocks(d.ctx, detail.ReplayID)
+	if err != nil {
+		return fmt.Errorf("failed to load game supply blocks: %w", err)
+	}
+	for _, row := range rows {
+		idx, ok := indexByPlayerID[row.PlayerID]
+		if !ok {
+			continue
+		}
+		block, ok := parseSupplyBlockPayload(row.Payload)
+		if !ok {
+			continue
+		}
+		block.Second = row.Second
+		entry := &detail.SupplyBlocks[idx]
+		entry.Blocks = append(entry.Blocks, block)
+		entry.TotalSecondsBlocked += block.DurationSeconds
+		if inWindow := supplyBlockedSecondsInWindow(block); inWindow > 0 {
+			entry.SecondsBlockedBefore10m += inWindow
+			entry.BlocksBefore10m++
+		}
+	}
+	sort.SliceStable(detail.SupplyBlocks, func(i, j int) bool {
+		a := detail.SupplyBlocks[i]
+		b := detail.SupplyBlocks[j]
+		if a.Eligible != b.Eligible {
+			return a.Eligible
+		}
+		if a.SecondsBlockedBefore10m == b.SecondsBlockedBefore10m {
+			return a.PlayerName < b.PlayerName
+		}
+		return a.SecondsBlockedBefore10m < b.SecondsBlockedBefore10m
+	})
+	return nil
+}
+
+func (d *Dashboard) buildWorkflowPlayerSupplyBlockAsyncInsight(playerKey string) (workflowPlayerAsyncInsight, error) {
+	allPlayers, err := d.loadWorkflowSupplyBlockAggregates()
+	if err != nil {
+		return workflowPlayerAsyncInsight{}, err
+	}
+	eligible := make([]workflowSupplyBlockAggregate, 0, len(allPlayers))
+	for _, player := range allPlayers {
+		if player.GamesPlayed >= workflowSupplyBlockMinGames {
+			eligible = append(eligible, player)
+		}
+	}
+	playerName, err := d.playerNameForKey(playerKey)
+	if err != nil {
+		return workflowPlayerAsyncInsight{}, err
+	}
+	result := workflowPlayerAsyncInsight{
+		SummaryVersion:  workflowSummaryVersion,
+		PlayerKey:       playerKey,
+		PlayerName:      playerName,
+		InsightType:     workflowPlayerInsightTypeSupplyBlocked,
+		Title:           "Supply blocked before 10:00",
+		BetterDirection: "lower",
+		PopulationSize:  int64(len(eligible)),
+		Description:     "Average seconds per game spent at the supply cap with no Pylon, Supply Depot, Overlord or town hall in progress, before 10:00, in games that reached 10:00. It comes from a simulation of each player's commands, so it is an estimate. Lower is better.",
+	}
+
+	values := make([]float64, 0, len(eligible))
+	for _, player := range eligible {
+		values = append(values, player.averageSeconds)
+	}
+	sort.Float64s(values)
+	populationMean := meanFloatSlice(values)
+	result.Details = append(result.Details,
+		workflowPlayerInsightDetail{Label: "Eligible players", Value: fmt.Sprintf("%d (minimum %d games)", len(eligible), workflowSupplyBlockMinGames)},
+		workflowPlayerInsightDetail{Label: "Population mean", Value: fmt.Sprintf("%.1fs blocked", populationMean)},
+		workflowPlayerInsightDetail{Label: "Population stddev", Value: fmt.Sprintf("%.1f", stddevFloatSlice(values, populationMean))},
+	)
+
+	var playerSummary *workflowSupplyBlockAggregate
+	for i := range allPlayers {
+		if allPlayers[i].PlayerKey == playerKey {
+			playerSummary = &allPlayers[i]
+			break
+		}
+	}
+	if playerSummary == nil {
+		result.IneligibleReason = "No simulated games of 10:00 or longer were found for this player yet."
+		return result, nil
+	}
+	result.Details = append(result.Details, workflowPlayerInsightDetail{Label: "Player games", Value: strconv.FormatInt(playerSummary.GamesPlayed, 10)})
+	if playerSummary.GamesPlayed < workflowSupplyBlockMinGames {
+		result.IneligibleReason = fmt.Sprintf("Not enough games of 10:00 or longer yet. This view currently requires at least %d games.", workflowSupplyBlockMinGames)
+		return result, nil
+	}
+
+	value := playerSummary.averageSeconds
+	percentile := performancePercentileFromSortedValues(values, value, true)
+	result.Eligible = true
+	result.PerformancePercentile = &percentile
+	result.PlayerValue = &value
+	result.PlayerValueLabel = fmt.Sprintf("%.1fs blocked per game", value)
+	return result, nil
+}
+
+// loadWorkflowSupplyBlockAggregates averages every player's seconds blocked
+// before 10:00 over their games that reached it, fewest first.
+func (d *Dashboard) loadWorkflowSupplyBlockAggregates() ([]workflowSupplyBlockAggregate, error) {
+	rows, err := d.dbStore.ListSupplyBlockPlayerRows(d.ctx, workflowSupplyBlockWindowSeconds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load supply block rows: %w", err)
+	}
+	aggregates := map[string]*workflowSupplyBlockAggregate{}
+	lastReplayByPlayer := map[string]int64{}
+	for _, row := range rows {
+		aggregate := aggregates[row.PlayerKey]
+		if aggregate == nil {
+			aggregate = &workflowSupplyBlockAggregate{PlayerKey: row.PlayerKey, PlayerName: row.PlayerName}
+			aggregates[row.PlayerKey] = aggregate
+		}
+		if last, seen := lastReplayByPlayer[row.PlayerKey]; !seen || last != row.ReplayID {
+			lastReplayByPlayer[row.PlayerKey] = row.ReplayID
+			aggregate.GamesPlayed++
+		}
+		if row.BlockSecond == nil {
+			continue
+		}
+		block, ok := parseSupplyBlockPayload(row.BlockPayload)
+		if !ok {
+			continue
+		}
+		block.Second = *row.BlockSecond
+		aggregate.averageSeconds += float64(supplyBlockedSecondsInWindow(block))
+	}
+
+	out := make([]workflowSupplyBlockAggregate, 0, len(aggregates))
+	for _, aggregate := range aggregates {
+		if aggregate.GamesPlayed > 0 {
+			aggregate.averageSeconds /= float64(aggregate.GamesPlayed)
+		}
+		out = append(out, *aggregate)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].averageSeconds == out[j].averageSeconds {
+			return out[i].PlayerKey < out[j].PlayerKey
+		}
+		return out[i].averageSeconds < out[j].averageSeconds
+	})
+	return out, nil
+}
@@ -130,12 +130,31 @@ func (d *Dashboard) buildWorkflowPlayerViewportAsyncInsight(playerKey string) (w
 }
 
 func (d *Dashboard) loadWorkflowViewportMultitaskingAggregates() ([]workflowViewportMultitaskingAggregate, error) {
+	aggregates := map[string]*workflowViewportMultitaskingAggregate{}
+	classSQL, materialized, err := d.playerAggregateClassSQL()
+	if err != nil {
+		return nil, err
+	}
+	if materialized {
+		rows, err := d.dbStore.ListViewportPlayerAggregates(d.ctx, classSQL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load viewport multitasking aggregates: %w", err)
+		}
+		for _, row := range rows {
+			aggregates[row.PlayerKey] = &workflowViewportMultitaskingAggregate{
+				PlayerKey:                 row.PlayerKey,
+				PlayerName:                row.PlayerName,
+				GamesPlayed:               row.Games,
+				averageViewportSwitchRate: row.SumRate,
+			}
+		}
+		return sortWorkflowViewportMultitaskingAggregates(aggregates), nil
+	}
+
 	rows, err := d.dbStore.ListViewportAggregateRows(d.ctx, viewportMultitaskingFeatureKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load viewport multitasking patterns: %w", err)
 	}
-
-	aggregates := map[string]*workflowViewportMultitaskingAggregate{}
 	for _, row := range rows {
 		playerKey := row.PlayerKey
 		playerName := row.PlayerName
@@ -155,6 +174,12 @@ func (d *Dashboard) loadWorkflowViewportMultitaskingAggregates() ([]workflowView
 		aggregate.GamesPlayed++
 		aggregate.averageViewportSwitchRate += rate
 	}
+	return sortWorkflowViewportMultitaskingAggregates(aggregates), nil
+}
+
+// sortWorkflowViewportMultitaskingAggregates turns the summed switch rates of
+// aggregates into averages, highest first.
+func sortWorkflowViewportMultitaskingAggregates(aggregates map[string]*workflowViewportMultitaskingAggregate) []workflowViewportMultitaskingAggregate {
 	out := make([]workflowViewportMultitaskingAggregate, 0, len(aggregates))
 	for _, aggregate := range aggregates {
 		if aggregate.GamesPlayed > 0 {
@@ -171,7 +196,7 @@ func (d *Dashboard) loadWorkflowViewportMultitaskingAggregates() ([]workflowView
 		}
 		return out[i].averageViewportSwitchRate > out[j].averageViewportSwitchRate
 	})
-	return out, nil
+	return out
 }
 
 func filterWorkflowViewportMultitaskingAggregates(all []workflowViewportMultitaskingAggregate) []workflowViewportMultitaskingAggregate {
@@ -9,6 +9,11 @@ import BuildOrderTimelineRows from './components/charts/BuildOrderTimelineRows';
 import MutaliskTimingChart from './components/charts/MutaliskTimingChart';
 import UnitProductionEarlyTimeline from './components/charts/UnitProductionEarlyTimeline';
 import SupplyTimeline from './components/charts/SupplyTimeline';
+import EconomyTimeline from './components/charts/EconomyTimeline';
+import ProductionBusyTimeline from './components/charts/ProductionBusyTimeline';
+import LarvaTimeline from './components/charts/LarvaTimeline';
+import HotkeyTimeline from './components/charts/HotkeyTimeline';
+import HotkeyLayoutKeys from './components/charts/HotkeyLayoutKeys';
 import AllianceTimeline from './components/charts/AllianceTimeline';
 import { getUnitIcon, getWorkerIconForRace, normalizeUnitName } from './lib/gameAssets';
 import {
@@ -144,7 +149,7 @@ const normalizeEventType = (eventType) => String(eventType || '').trim().toLower
 /** Aligns with NeverUsedHotkeysPlayerDetector (7+ minute replays). */
 const GAME_SUMMARY_NEGATION_MIN_SECONDS = 7 * 60;
 
-const MAIN_GAME_SKILL_PROXY_TABS = ['first-unit-efficiency', 'unit-production-cadence', 'viewport-multitasking'];
+const MAIN_GAME_SKILL_PROXY_TABS = ['first-unit-efficiency', 'unit-production-cadence', 'viewport-multitasking', 'supply-blocks', 'production-idle', 'larva-missed', 'hotkeys'];
 
 const isMainGameSkillProxyTab = (tab) => MAIN_GAME_SKILL_PROXY_TABS.includes(tab);
 
@@ -152,12 +157,23 @@ const SKILL_PROXY_CADENCE_INFO_TEXT = 'ℹ️ How smoothly you keep adding army
 
 const SKILL_PROXY_VIEWPORT_INFO_TEXT = 'ℹ️ How many times a player switches between places on average per minute.';
 
+const SKILL_PROXY_PRODUCTION_IDLE_INFO_TEXT = 'ℹ️ Share of the time each Gateway, Barracks and Factory stood ready but trained nothing, overall and per phase. Estimated from the Train commands each building received; lower is better.';
+
+const SKILL_PROXY_LARVA_INFO_TEXT = 'ℹ️ Share of the larvae each Zerg\'s hatcheries would have spawned but lost because three were already waiting, overall and per phase. Estimated from a simulation of the larva timer and the morph commands; lower is better.';
+
+const SKILL_PROXY_HOTKEY_INFO_TEXT = 'ℹ️ Which control groups each player bound to buildings or army, how often they recalled them per minute by phase, how often they rebound a group, and how many recalls were double taps to jump the screen to the group.';
+
+const SKILL_PROXY_SUPPLY_BLOCK_INFO_TEXT = 'ℹ️ Seconds spent at the supply cap with no Pylon, Depot, Overlord or town hall in progress before 10:00. Estimated from a simulation of each player\'s commands; lower is better.';
+
 // Per-insight short descriptions for the player Skill proxies > Summary cards.
 // APM omitted intentionally (number is self-explanatory in that view).
 const PLAYER_INSIGHT_DESCRIPTION_OVERRIDES = {
   apm: '',
   'unit-production-cadence': 'How smoothly you keep adding army from the mid game on—not just how much, but how evenly you queue it. Formula: units/min ÷ (1 + gap CV).',
   'viewport-switch-rate': 'How many times a player switches between places on average per minute.',
+  'supply-blocked': 'Average seconds per game stuck at the supply cap with nothing in progress, before 10:00 (estimated). Lower is better.',
+  'production-idle': 'Share of the time your Gateways, Barracks and Factories stood ready but trained nothing (estimated). Lower is better.',
+  'larva-missed': 'Share of your hatcheries\' larvae lost because three were already waiting (estimated, Zerg only). Lower is better.',
 };
 
 const DROP_ACTOR_EVENT_TYPES = ['drop', 'cliff_drop'];
@@ -443,6 +459,10 @@ const gameEventDescription = (event, registry) => {
   if (eventType === 'first_corsair') return actor ? `${actor} trains their first Corsair` : 'First Corsair';
   if (eventType === 'speedlot') return actor ? `${actor} starts Zealot Speed research` : 'Zealot Speed';
   if (eventType === 'location_inactive') return location ? `Location inactive: ${location}` : 'Location inactive';
+  if (eventType === 'supply_block') {
+    const blocked = `supply blocked at ${Number(event?.supply_cap || 0)} for ${formatDuration(event?.duration_seconds)}`;
+    return actor ? `${actor} is ${blocked}` : `Supply blocked for ${formatDuration(event?.duration_seconds)}`;
+  }
   if (eventType === 'expansion') {
     if (actor && isActorAtOwnNaturalBase(event)) return `${actor} expands to their natural`;
     return actor && location ? `${actor} expands to ${location}` : 'Expansion';
@@ -626,6 +646,10 @@ const renderGameEventDescription = (event, registry, playerRaceByID) => {
   if (eventType === 'first_corsair') return actorName ? <>{actorSpan} trains their first Corsair</> : 'First Corsair';
   if (eventType === 'speedlot') return actorName ? <>{actorSpan} starts Zealot Speed research</> : 'Zealot Speed';
   if (eventType === 'location_inactive') return location ? `Location inactive: ${location}` : 'Location inactive';
+  if (eventType === 'supply_block') {
+    const blocked = `supply blocked at ${Number(event?.supply_cap || 0)} for ${formatDuration(event?.duration_seconds)}`;
+    return actorName ? <>{actorSpan} is {blocked}</> : `Supply blocked for ${formatDuration(event?.duration_seconds)}`;
+  }
   if (eventType === 'expansion') {
     if (actorName && isActorAtOwnNaturalBase(event)) return <>{actorSpan} expands to their natural</>;
     return actorName && location ? <>{actorSpan} expands to {location}</> : 'Expansion';
@@ -1273,6 +1297,9 @@ const gameEventRowIconEntries = (event, playerRaceByID, registry) => {
   if (normalized === 'team_stacking_detected') {
     return [{ emoji: '😈', alt: 'team stacking', title: 'Stacking topology held >5 min' }];
   }
+  if (normalized === 'supply_block') {
+    return [{ emoji: '🚧', alt: 'supply blocked', title: 'At the supply cap with no supply in progress (estimated)' }];
+  }
   if (normalized === 'expansion' || normalized === 'takeover') {
     const icon = getExpansionMarkerIconForRace(actorRace);
     if (!icon) return [];
@@ -1763,6 +1790,9 @@ const PLAYER_INSIGHT_TYPES = {
   apm: 'apm',
   unitProductionCadence: 'unit-production-cadence',
   viewportSwitchRate: 'viewport-switch-rate',
+  supplyBlocked: 'supply-blocked',
+  productionIdle: 'production-idle',
+  larvaMissed: 'larva-missed',
 };
 
 // PLAYER_SUMMARY_OUTLIER_CATEGORIES is the canonical list the FE iterates
@@ -1825,6 +1855,10 @@ const playerInsightDestinationTab = (insightType) => {
       return 'unit-production-cadence';
     case PLAYER_INSIGHT_TYPES.viewportSwitchRate:
       return 'viewport-multitasking';
+    case PLAYER_INSIGHT_TYPES.supplyBlocked:
+    case PLAYER_INSIGHT_TYPES.productionIdle:
+    case PLAYER_INSIGHT_TYPES.larvaMissed:
+      return '';
     default:
       return 'summary';
   }
@@ -2163,6 +2197,15 @@ function App() {
   const [mainPlayerViewportInsight, setMainPlayerViewportInsight] = useState(null);
   const [mainPlayerViewportInsightLoading, setMainPlayerViewportInsightLoading] = useState(false);
   const [mainPlayerViewportInsightError, setMainPlayerViewportInsightError] = useState('');
+  const [mainPlayerSupplyBlockInsight, setMainPlayerSupplyBlockInsight] = useState(null);
+  const [mainPlayerSupplyBlockInsightLoading, setMainPlayerSupplyBlockInsightLoading] = useState(false);
+  const [mainPlayerSupplyBlockInsightError, setMainPlayerSupplyBlockInsightError] = useState('');
+  const [mainPlayerProductionIdleInsight, setMainPlayerProductionIdleInsight] = useState(null);
+  const [mainPlayerProductionIdleInsightLoading, setMainPlayerProductionIdleInsightLoading] = useState(false);
+  const [mainPlayerProductionIdleInsightError, setMainPlayerProductionIdleInsightError] = useState('');
+  const [mainPlayerLarvaInsight, setMainPlayerLarvaInsight] = useState(null);
+  const [mainPlayerLarvaInsightLoading, setMainPlayerLarvaInsightLoading] = useState(false);
+  const [mainPlayerLarvaInsightError, setMainPlayerLarvaInsightError] = useState('');
   const [topPlayerColors, setTopPlayerColors] = useState({});
   // Used purely as a re-render trigger after the screp engine color map loads;
   // the actual map lives at module scope (see scPlayerColorMap above) so the
@@ -2323,8 +2366,9 @@ function App() {
       let nextTab = wantTab && MAIN_GAME_TABS.includes(String(wantTab).trim().toLowerCase())
         ? String(wantTab).trim().toLowerCase()
         : 'summary';
-      // Build Orders / Mutalisk Timing tabs are hidden when no data was
-      // detected; don't leave the user stranded on an invisible tab.
+      // Build Orders / Mutalisk Timing / Economy / Production idle / Larva / Hotkeys tabs are
+      // hidden when no data was detected; don't leave the user stranded on an
+      // invisible tab.
       const hasBuildOrders = Array.isArray(data?.build_orders) && data.build_orders.length > 0;
       if (nextTab === 'build-orders' && !hasBuildOrders) {
         nextTab = 'summary';
@@ -2333,6 +2377,22 @@ function App() {
       if (nextTab === 'mutalisk-timing' && !hasMutaliskTiming) {
         nextTab = 'summary';
       }
+      const hasEconomyTimeline = Array.isArray(data?.economy_timeline) && data.economy_timeline.length > 0;
+      if (nextTab === 'economy-timeline' && !hasEconomyTimeline) {
+        nextTab = 'summary';
+      }
+      const hasProductionIdle = Array.isArray(data?.production_idle) && data.production_idle.length > 0;
+      if (nextTab === 'production-idle' && !hasProductionIdle) {
+        nextTab = 'summary';
+      }
+      const hasLarva = Array.isArray(data?.larva) && data.larva.length > 0;
+      if (nextTab === 'larva-missed' && !hasLarva) {
+        nextTab = 'summary';
+      }
+      const hasHotkeys = Array.isArray(data?.hotkeys) && data.hotkeys.length > 0;
+      if (nextTab === 'hotkeys' && !hasHotkeys) {
+        nextTab = 'summary';
+      }
       setMainGameTab(nextTab);
       setMainEventsPlayerEnabledById(
         Object.fromEntries((data.players || []).map((p) => [String(p.player_id), true])),
@@ -2520,6 +2580,54 @@ function App() {
     }
   };
 
+  const loadMainPlayerSupplyBlockInsight = async (playerKey) => {
+    const normalizedPlayerKey = String(playerKey || '').trim().toLowerCase();
+    if (!normalizedPlayerKey) return;
+    try {
+      setMainPlayerSupplyBlockInsightLoading(true);
+      setMainPlayerSupplyBlockInsightError('');
+      const supplyBlockData = await api.getPlayerInsight(normalizedPlayerKey, PLAYER_INSIGHT_TYPES.supplyBlocked);
+      setMainPlayerSupplyBlockInsight(supplyBlockData);
+    } catch (err) {
+      setMainPlayerSupplyBlockInsightError(err.message || 'Failed to load supply block insight');
+      setMainPlayerSupplyBlockInsight(null);
+    } finally {
+      setMainPlayerSupplyBlockInsightLoading(false);
+    }
+  };
+
+  const loadMainPlayerProductionIdleInsight = async (playerKey) => {
+    const normalizedPlayerKey = String(playerKey || '').trim().toLowerCase();
+    if (!normalizedPlayerKey) return;
+    try {
+      setMainPlayerProductionIdleInsightLoading(true);
+      setMainPlayerProductionIdleInsightError('');
+      const productionIdleData = await api.getPlayerInsight(normalizedPlayerKey, PLAYER_INSIGHT_TYPES.productionIdle);
+      setMainPlayerProductionIdleInsight(productionIdleData);
+    } catch (err) {
+      setMainPlayerProductionIdleInsightError(err.message || 'Failed to load production idle insight');
+      setMainPlayerProductionIdleInsight(null);
+    } finally {
+      setMainPlayerProductionIdleInsightLoading(false);
+    }
+  };
+
+  const loadMainPlayerLarvaInsight = async (playerKey) => {
+    const normalizedPlayerKey = String(playerKey || '').trim().toLowerCase();
+    if (!normalizedPlayerKey) return;
+    try {
+      setMainPlayerLarvaInsightLoading(true);
+      setMainPlayerLarvaInsightError('');
+      const larvaData = await api.getPlayerInsight(normalizedPlayerKey, PLAYER_INSIGHT_TYPES.larvaMissed);
+      setMainPlayerLarvaInsight(larvaData);
+    } catch (err) {
+      setMainPlayerLarvaInsightError(err.message || 'Failed to load missed larvae insight');
+      setMainPlayerLarvaInsight(null);
+    } finally {
+      setMainPlayerLarvaInsightLoading(false);
+    }
+  };
+
   const openMainPlayer = async (playerKey, options = {}) => {
     const normalizedPlayerKey = String(playerKey || '').trim().toLowerCase();
     // Navigate first, fetch second. Previously the player overview fetch
@@ -2555,6 +2663,15 @@ function App() {
     setMainPlayerViewportInsight(null);
     setMainPlayerViewportInsightError('');
     setMainPlayerViewportInsightLoading(false);
+    setMainPlayerSupplyBlockInsight(null);
+    setMainPlayerSupplyBlockInsightError('');
+    setMainPlayerSupplyBlockInsightLoading(false);
+    setMainPlayerProductionIdleInsight(null);
+    setMainPlayerProductionIdleInsightError('');
+    setMainPlayerProductionIdleInsightLoading(false);
+    setMainPlayerLarvaInsight(null);
+    setMainPlayerLarvaInsightError('');
+    setMainPlayerLarvaInsightLoading(false);
     setSelectedPlayerKey(normalizedPlayerKey);
     const wantTab = options.initialPlayerTab;
     const nextTab = wantTab && MAIN_PLAYER_TABS.includes(String(wantTab).trim().toLowerCase())
@@ -3092,11 +3209,23 @@ function App() {
     if (!mainPlayerViewportInsight && !mainPlayerViewportInsightLoading && !mainPlayerViewportInsightError) {
       loadMainPlayerViewportInsight(selectedPlayerKey);
     }
+    if (!mainPlayerSupplyBlockInsight && !mainPlayerSupplyBlockInsightLoading && !mainPlayerSupplyBlockInsightError) {
+      loadMainPlayerSupplyBlockInsight(selectedPlayerKey);
+    }
+    if (!mainPlayerProductionIdleInsight && !mainPlayerProductionIdleInsightLoading && !mainPlayerProductionIdleInsightError) {
+      loadMainPlayerProductionIdleInsight(selectedPlayerKey);
+    }
+    if (!mainPlayerLarvaInsight && !mainPlayerLarvaInsightLoading && !mainPlayerLarvaInsightError) {
+      loadMainPlayerLarvaInsight(selectedPlayerKey);
+    }
   }, [
     activeView, selectedPlayerKey, mainPlayerTab,
     mainPlayerApmInsight, mainPlayerApmInsightLoading, mainPlayerApmInsightError,
     mainPlayerCadenceInsight, mainPlayerCadenceInsightLoading, mainPlayerCadenceInsightError,
     mainPlayerViewportInsight, mainPlayerViewportInsightLoading, mainPlayerViewportInsightError,
+    mainPlayerSupplyBlockInsight, mainPlayerSupplyBlockInsightLoading, mainPlayerSupplyBlockInsightError,
+    mainPlayerProductionIdleInsight, mainPlayerProductionIdleInsightLoading, mainPlayerProductionIdleInsightError,
+    mainPlayerLarvaInsight, mainPlayerLarvaInsightLoading, mainPlayerLarvaInsightError,
   ]);
 
   useEffect(() => {
@@ -4741,12 +4870,20 @@ function App() {
     mainPlayerApmInsight,
     mainPlayerViewportInsight,
     mainPlayerCadenceInsight,
+    mainPlayerSupplyBlockInsight,
+    mainPlayerProductionIdleInsight,
+    mainPlayerLarvaInsight,
   ].filter(Boolean);
-  const mainPlayerInsightLoading = mainPlayerApmInsightLoading || mainPlayerCadenceInsightLoading || mainPlayerViewportInsightLoading;
+  const mainPlayerInsightLoading = mainPlayerApmInsightLoading || mainPlayerCadenceInsightLoading
+    || mainPlayerViewportInsightLoading || mainPlayerSupplyBlockInsightLoading || mainPlayerProductionIdleInsightLoading
+    || mainPlayerLarvaInsightLoading;
   const mainPlayerInsightErrors = [
     mainPlayerApmInsightError,
     mainPlayerCadenceInsightError,
     mainPlayerViewportInsightError,
+    mainPlayerSupplyBlockInsightError,
+    mainPlayerProductionIdleInsightError,
+    mainPlayerLarvaInsightError,
   ].filter(Boolean);
   const mainPlayerNameWidthCh = useMemo(() => {
     const longestNameLength = mainGamePlayers.reduce((longest, player) => {
@@ -5959,6 +6096,17 @@ function App() {
                     >
                       Supply
                     </button>
+                    {Array.isArray(mainGame?.economy_timeline) && mainGame.economy_timeline.length > 0 ? (
+                      <button
+                        type="button"
+                        role="tab"
+                        aria-selected={mainGameTab === 'economy-timeline'}
+                        className={`workflow-production-tab ${mainGameTab === 'economy-timeline' ? 'workflow-production-tab-active' : ''}`}
+                        onClick={() => setMainGameTab('economy-timeline')}
+                      >
+                        Economy
+                      </button>
+                    ) : null}
                     <button
                       type="button"
                       role="tab"
@@ -6002,6 +6150,48 @@ function App() {
                         >
                           Viewport multitasking
                         </button>
+                        <button
+                          type="button"
+                          role="tab"
+                          aria-selected={mainGameTab === 'supply-blocks'}
+                          className={`workflow-production-tab ${mainGameTab === 'supply-blocks' ? 'workflow-production-tab-active' : ''}`}
+                          onClick={() => setMainGameTab('supply-blocks')}
+                        >
+                          Supply blocks
+                        </button>
+                        {(mainGame?.production_idle || []).length > 0 ? (
+                          <button
+                            type="button"
+                            role="tab"
+                            aria-selected={mainGameTab === 'production-idle'}
+                            className={`workflow-production-tab ${mainGameTab === 'production-idle' ? 'workflow-production-tab-active' : ''}`}
+                            onClick={() => setMainGameTab('production-idle')}
+                          >
+                            Production idle
+                          </button>
+                        ) : null}
+                        {(mainGame?.larva || []).length > 0 ? (
+                          <button
+                            type="button"
+                            role="tab"
+                            aria-selected={mainGameTab === 'larva-missed'}
+                            className={`workflow-production-tab ${mainGameTab === 'larva-missed' ? 'workflow-production-tab-active' : ''}`}
+                            onClick={() => setMainGameTab('larva-missed')}
+                          >
+                            Missed larvae
+                          </button>
+                        ) : null}
+                        {(mainGame?.hotkeys || []).length > 0 ? (
+                          <button
+                            type="button"
+                            role="tab"
+                            aria-selected={mainGameTab === 'hotkeys'}
+                            className={`workflow-production-tab ${mainGameTab === 'hotkeys' ? 'workflow-production-tab-active' : ''}`}
+                            onClick={() => setMainGameTab('hotkeys')}
+                          >
+                            Control groups
+                          </button>
+                        ) : null}
                       </div>
                       {mainGameTab === 'unit-production-cadence' ? (
                         <div className="workflow-section-info workflow-skill-proxy-tab-info" role="note">
@@ -6013,6 +6203,26 @@ function App() {
                           {SKILL_PROXY_VIEWPORT_INFO_TEXT}
                         </div>
                       ) : null}
+                      {mainGameTab === 'supply-blocks' ? (
+                        <div className="workflow-section-info workflow-skill-proxy-tab-info" role="note">
+                          {SKILL_PROXY_SUPPLY_BLOCK_INFO_TEXT}
+                        </div>
+                      ) : null}
+                      {mainGameTab === 'production-idle' ? (
+                        <div className="workflow-section-info workflow-skill-proxy-tab-info" role="note">
+                          {SKILL_PROXY_PRODUCTION_IDLE_INFO_TEXT}
+                        </div>
+                      ) : null}
+                      {mainGameTab === 'larva-missed' ? (
+                        <div className="workflow-section-info workflow-skill-proxy-tab-info" role="note">
+                          {SKILL_PROXY_LARVA_INFO_TEXT}
+                        </div>
+                      ) : null}
+                      {mainGameTab === 'hotkeys' ? (
+                        <div className="workflow-section-info workflow-skill-proxy-tab-info" role="note">
+                          {SKILL_PROXY_HOTKEY_INFO_TEXT}
+                        </div>
+                      ) : null}
                     </div>
                   ) : null}
                 </div>
@@ -6829,6 +7039,15 @@ function App() {
                   />
                 )}
 
+                {mainGameTab === 'economy-timeline' && (
+                  <EconomyTimeline
+                    players={mainGamePlayers}
+                    timeline={mainGame.economy_timeline || []}
+                    durationSeconds={mainGame.duration_seconds || 0}
+                    playerColor={playerColorToCss}
+                  />
+                )}
+
                 {mainGameTab === 'timings' && (
                   <div className="workflow-timing-charts">
                     <div className="workflow-section-top-row">
@@ -7099,6 +7318,59 @@ function App() {
                     </div>
                   </div>
                 )}
+                {mainGameTab === 'supply-blocks' && (
+                  <div className="workflow-timing-charts">
+                    <div className="workflow-card workflow-card-fingerprints">
+                      <div className="workflow-card-subtitle"><span>Per-player breakdown</span></div>
+                      {(mainGame?.supply_blocks || []).map((entry) => (
+                        <div key={`game-supply-block-${entry.player_id}`} className="workflow-pattern-row">
+                          <span style={playerAccentColor(entry.player_key) ? { color: playerAccentColor(entry.player_key), fontWeight: 600 } : undefined}>
+                            {entry.is_winner ? '👑 ' : ''}{entry.player_name}
+                          </span>
+                          <span
+                            title={entry.eligible
+                              ? (entry.blocks || []).map((block) => `${formatDuration(block.second)}: blocked at ${Number(block.supply || 0)} for ${formatDuration(block.duration_seconds)}`).join('\n')
+                              : String(entry.ineligible_reason || '')}
+                          >
+                            {entry.eligible
+                              ? `${formatDuration(Number(entry.seconds_blocked_before_10m || 0))} blocked before 10:00 (${Number(entry.blocks_before_10m || 0)} blocks; ${formatDuration(Number(entry.total_seconds_blocked || 0))} all game)`
+                              : `N/A (${entry.ineligible_reason || 'insufficient data'})`}
+                          </span>
+                        </div>
+                      ))}
+                    </div>
+                  </div>
+                )}
+                {mainGameTab === 'production-idle' && (
+                  <ProductionBusyTimeline
+                    players={mainGamePlayers}
+                    entries={mainGame?.production_idle || []}
+                    durationSeconds={mainGame?.duration_seconds || 0}
+                    earlyEndsAt={mainGame?.early_game_ends_at_second || 0}
+                    midEndsAt={mainGame?.mid_game_ends_at_second || 0}
+                    playerColor={playerColorToCss}
+                  />
+                )}
+                {mainGameTab === 'larva-missed' && (
+                  <LarvaTimeline
+                    players={mainGamePlayers}
+                    entries={mainGame?.larva || []}
+                    durationSeconds={mainGame?.duration_seconds || 0}
+                    earlyEndsAt={mainGame?.early_game_ends_at_second || 0}
+                    midEndsAt={mainGame?.mid_game_ends_at_second || 0}
+                    playerColor={playerColorToCss}
+                  />
+                )}
+                {mainGameTab === 'hotkeys' && (
+                  <HotkeyTimeline
+                    players={mainGamePlayers}
+                    entries={mainGame?.hotkeys || []}
+                    durationSeconds={mainGame?.duration_seconds || 0}
+                    earlyEndsAt={mainGame?.early_game_ends_at_second || 0}
+                    midEndsAt={mainGame?.mid_game_ends_at_second || 0}
+                    playerColor={playerColorToCss}
+                  />
+                )}
               </>
             ) : (
               <div className="chart-empty">Select a game from the Games tab.</div>
@@ -7322,6 +7594,47 @@ function App() {
                           </div>
                         );
                       })() : null}
+
+                      {(mainPlayer?.hotkey_layouts || []).length > 0 ? (
+                        <div className="workflow-card workflow-card-player-matchups">
+                          <div className="workflow-card-title"><span>Control-group layout</span></div>
+                          <div className="workflow-subtle-note">
+                            Keys are tinted by what the group was bound to (amber buildings, red army, grey unknown) and filled by their share of group recalls. Matchups need 2+ games.
+                          </div>
+                          <div className="workflow-player-matchup-grid">
+                            {mainPlayer.hotkey_layouts.map((layout) => {
+                              const isAll = !layout.opp_race;
+                              const label = isAll
+                                ? 'All games'
+                                : `${String(layout.own_race || '').charAt(0).toUpperCase() || '?'}v${String(layout.opp_race || '').charAt(0).toUpperCase() || '?'}`;
+                              const phases = (layout.phases || [])
+                                .map((p) => `${p.label.toLowerCase()} ${(Number(p.selects_per_minute) || 0).toFixed(1)}`)
+                                .join(', ');
+                              return (
+                                <div key={isAll ? 'all' : `${layout.own_race}-${layout.opp_race}`} className="workflow-player-matchup-card">
+                                  <div className="workflow-player-matchup-card-header">
+                                    <span className="workflow-player-matchup-card-label">
+                                      <strong>{label}</strong>
+                                      {!isAll && layout.same_as_overall ? <span className="workflow-subtle-note">same layout</span> : null}
+                                    </span>
+                                    <span className="workflow-player-matchup-card-meta">
+                                      <span><strong>{layout.games}</strong> games</span>
+                                      <span><strong>{(Number(layout.selects_per_minute) || 0).toFixed(1)}</strong> recalls/min</span>
+                                      <span><strong>{(Number(layout.rebinds_per_game) || 0).toFixed(1)}</strong> rebinds/game</span>
+                                      <span><strong>{(Number(layout.camera_jump_percent) || 0).toFixed(0)}%</strong> camera jumps</span>
+                                    </span>
+                                  </div>
+                                  <HotkeyLayoutKeys groups={layout.groups} />
+                                  <div className="workflow-subtle-note">
+                                    {layout.layout || 'No group holds 5% of recalls'}
+                                    {phases ? ` · recalls/min ${phases}` : ''}
+                                  </div>
+                                </div>
+                              );
+                            })}
+                          </div>
+                        </div>
+                      ) : null}
                     </>
                   )}
 
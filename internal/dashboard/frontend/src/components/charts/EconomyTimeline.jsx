@@ -0,0 +1,229 @@
+import React, { useMemo, useState } from 'react';
+
+// EconomyTimeline charts each player's estimated economy over the game, from
+// the economy_timeline samples the ingest-time simulation stores every 10
+// seconds (see earlyfilter.SimulateEconomy). One metric is shown at a time:
+// bank (minerals solid, gas dashed), income per minute (same split) or supply
+// (used solid, max dashed).
+//
+// The simulation assumes perfect mining and never sees units die, so values
+// are an estimate of pace, not a readout of the game. Lines stop where a
+// player left / stopped playing (left_second), as in SupplyTimeline.
+
+const METRICS = [
+  {
+    id: 'bank',
+    label: 'Bank',
+    solid: { key: 'minerals', label: 'Minerals' },
+    dashed: { key: 'gas', label: 'Gas' },
+  },
+  {
+    id: 'income',
+    label: 'Income',
+    solid: { key: 'mineral_income', label: 'Minerals/min' },
+    dashed: { key: 'gas_income', label: 'Gas/min' },
+  },
+  {
+    id: 'supply',
+    label: 'Supply',
+    solid: { key: 'supply_used', label: 'Supply used' },
+    dashed: { key: 'supply_max', label: 'Supply max' },
+  },
+];
+
+const FALLBACK_COLORS = ['#60a5fa', '#f87171', '#34d399', '#fbbf24', '#a78bfa', '#f472b6', '#22d3ee', '#fb923c'];
+
+const W = 1000;
+const H = 380;
+const M = { left: 52, right: 16, top: 14, bottom: 32 };
+const PLOT_W = W - M.left - M.right;
+const PLOT_H = H - M.top - M.bottom;
+
+const formatTime = (seconds) => {
+  const value = Math.max(0, Math.floor(Number(seconds) || 0));
+  return `${Math.floor(value / 60)}:${String(value % 60).padStart(2, '0')}`;
+};
+
+const num = (v) => Number(v) || 0;
+
+// niceCeil rounds a maximum up to a round axis top: 20s for supply-sized
+// values, then 100s, then 500s.
+const niceCeil = (v) => {
+  if (v <= 200) return Math.max(20, Math.ceil(v / 20) * 20);
+  if (v <= 2000) return Math.ceil(v / 100) * 100;
+  return Math.ceil(v / 500) * 500;
+};
+
+function EconomyTimeline({ players, timeline, durationSeconds, playerColor }) {
+  const duration = Math.max(1, Math.floor(Number(durationSeconds) || 0));
+  const [metricId, setMetricId] = useState('bank');
+  const [hoveredId, setHoveredId] = useState(null);
+  const [hoverSec, setHoverSec] = useState(null);
+  const metric = METRICS.find((m) => m.id === metricId) || METRICS[0];
+
+  const series = useMemo(() => {
+    const playerByID = new Map((players || []).map((p, idx) => [p.player_id, { player: p, idx }]));
+    return (timeline || [])
+      .map((entry, i) => {
+        const known = playerByID.get(entry.player_id);
+        const player = known?.player || { player_id: entry.player_id, name: entry.name };
+        const idx = known ? known.idx : i;
+        const color = playerColor && player.color ? playerColor(player.color) : FALLBACK_COLORS[idx % FALLBACK_COLORS.length];
+        const left = player.left_second != null ? Math.floor(Number(player.left_second)) : null;
+        const endSec = left != null && left > 0 ? Math.min(left, duration) : duration;
+        const samples = (entry.samples || []).filter((s) => num(s.second) <= endSec);
+        return { player, color, samples };
+      })
+      .filter((s) => s.samples.length > 0);
+  }, [players, timeline, playerColor, duration]);
+
+  if (series.length === 0) return null;
+
+  const yMax = niceCeil(Math.max(
+    1,
+    ...series.flatMap((s) => s.samples.map((p) => Math.max(num(p[metric.solid.key]), num(p[metric.dashed.key])))),
+  ));
+  const xAt = (sec) => M.left + (Math.max(0, Math.min(duration, sec)) / duration) * PLOT_W;
+  const yAt = (v) => M.top + PLOT_H - (Math.max(0, Math.min(yMax, v)) / yMax) * PLOT_H;
+
+  const xStep = duration <= 600 ? 60 : duration <= 1800 ? 120 : 300;
+  const xTicks = [];
+  for (let t = 0; t <= duration; t += xStep) xTicks.push(t);
+  const yTicks = [0, 0.25, 0.5, 0.75, 1].map((f) => Math.round(yMax * f));
+
+  const linePath = (samples, key) => samples
+    .map((p, i) => `${i === 0 ? 'M' : 'L'} ${xAt(num(p.second))} ${yAt(num(p[key]))}`)
+    .join(' ');
+
+  const dim = (id) => hoveredId != null && hoveredId !== id;
+
+  // Hovering the plot picks the nearest sample second; the tooltip lists
+  // every player's values there.
+  const onMouseMove = (event) => {
+    const rect = event.currentTarget.getBoundingClientRect();
+    if (!rect.width) return;
+    const x = ((event.clientX - rect.left) / rect.width) * W;
+    const sec = ((x - M.left) / PLOT_W) * duration;
+    if (sec < 0 || sec > duration) {
+      setHoverSec(null);
+      return;
+    }
+    setHoverSec(Math.round(sec / 10) * 10);
+  };
+
+  const tipRows = hoverSec == null ? [] : series
+    .map((s) => {
+      const sample = s.samples.find((p) => num(p.second) === hoverSec);
+      return sample ? { s, sample } : null;
+    })
+    .filter(Boolean);
+
+  return (
+    <div className="workflow-card workflow-card-chat-summary">
+      <div className="workflow-section-warning">
+        ⚠️ Estimated, not measured: a simulation of each player&apos;s commands
+        that assumes perfect mining and can&apos;t see units die, so banks run
+        high late in the game. Read it for pace, not exact numbers.
+      </div>
+
+      <div className="workflow-production-tabs" role="tablist" aria-label="Economy metric tabs">
+        {METRICS.map((m) => (
+          <button
+            key={m.id}
+            type="button"
+            role="tab"
+            aria-selected={metricId === m.id}
+            className={`workflow-production-tab ${metricId === m.id ? 'workflow-production-tab-active' : ''}`}
+            onClick={() => setMetricId(m.id)}
+          >
+            {m.label}
+          </button>
+        ))}
+      </div>
+
+      <svg
+        width="100%"
+        viewBox={`0 0 ${W} ${H}`}
+        preserveAspectRatio="xMidYMid meet"
+        style={{ display: 'block' }}
+        onMouseMove={onMouseMove}
+        onMouseLeave={() => setHoverSec(null)}
+      >
+        {yTicks.map((v) => (
+          <g key={`y-${v}`}>
+            <line x1={M.left} y1={yAt(v)} x2={M.left + PLOT_W} y2={yAt(v)} stroke="rgba(255,255,255,0.10)" strokeWidth="1" />
+            <text x={M.left - 6} y={yAt(v) + 3} textAnchor="end" fill="rgba(255,255,255,0.55)" fontSize="11">{v}</text>
+          </g>
+        ))}
+        {xTicks.map((t) => (
+          <g key={`x-${t}`}>
+            <line x1={xAt(t)} y1={M.top} x2={xAt(t)} y2={M.top + PLOT_H} stroke="rgba(255,255,255,0.06)" strokeWidth="1" />
+            <text x={xAt(t)} y={H - 12} textAnchor="middle" fill="rgba(255,255,255,0.55)" fontSize="11">{formatTime(t)}</text>
+          </g>
+        ))}
+
+        {series.map((s) => {
+          const id = s.player.player_id;
+          const isHover = hoveredId === id;
+          return (
+            <g
+              key={`line-${id}`}
+              opacity={dim(id) ? 0.12 : 1}
+              onMouseEnter={() => setHoveredId(id)}
+              onMouseLeave={() => setHoveredId(null)}
+              style={{ cursor: 'pointer' }}
+            >
+              <path d={linePath(s.samples, metric.dashed.key)} fill="none" stroke={s.color} strokeWidth={isHover ? 2 : 1.2} strokeDasharray="5 4" opacity="0.8" />
+              <path d={linePath(s.samples, metric.solid.key)} fill="none" stroke={s.color} strokeWidth={isHover ? 2.5 : 1.6} strokeLinejoin="round" />
+            </g>
+          );
+        })}
+
+        {hoverSec != null && tipRows.length > 0 ? (() => {
+          const lines = [
+            formatTime(hoverSec),
+            ...tipRows.map(({ s, sample }) => (
+              `${s.player.name}: ${num(sample[metric.solid.key])} / ${num(sample[metric.dashed.key])}  (${num(sample.workers)} workers, ${num(sample.bases)} bases)`
+            )),
+          ];
+          const w = Math.max(...lines.map((ln) => ln.length)) * 6.1 + 16;
+          const h = lines.length * 14 + 8;
+          const x = xAt(hoverSec);
+          let bx = x + 10;
+          if (bx + w > W) bx = x - w - 10;
+          const by = M.top + 4;
+          return (
+            <g pointerEvents="none">
+              <line x1={x} y1={M.top} x2={x} y2={M.top + PLOT_H} stroke="rgba(255,255,255,0.35)" strokeWidth="1" />
+              <rect x={bx} y={by} width={w} height={h} rx={4} fill="rgba(12,15,24,0.96)" stroke="rgba(255,255,255,0.3)" strokeWidth="1" />
+              {lines.map((ln, i) => (
+                <text
+                  key={i}
+                  x={bx + 8}
+                  y={by + 16 + i * 14}
+                  fill={i === 0 ? 'rgba(255,255,255,0.9)' : tipRows[i - 1].s.color}
+                  fontSize="11"
+                  fontWeight={i === 0 ? 700 : 400}
+                >
+                  {ln}
+                </text>
+              ))}
+            </g>
+          );
+        })() : null}
+      </svg>
+
+      <div className="workflow-card-subtitle">
+        Solid: {metric.solid.label}. Dashed: {metric.dashed.label}.
+        {' '}
+        {series.map((s, i) => (
+          <span key={`legend-${s.player.player_id}`} style={{ color: s.color }}>
+            {i > 0 ? ' · ' : ''}{s.player.is_winner ? '👑 ' : ''}{s.player.name}
+          </span>
+        ))}
+      </div>
+    </div>
+  );
+}
+
+export default EconomyTimeline;
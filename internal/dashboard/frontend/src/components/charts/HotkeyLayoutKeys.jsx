@@ -0,0 +1,56 @@
+import React from 'react';
+
+// HotkeyLayoutKeys draws a control-group layout as the keyboard's number row,
+// 1 to 9 then 0: each key is tinted by the role its group was bound to
+// (building or army, grey when unknown) and filled by its share of the
+// player's group recalls, so two layouts compare at a glance.
+
+export const HOTKEY_ROLE_COLORS = {
+  building: '#fbbf24',
+  army: '#f87171',
+  '': '#94a3b8',
+};
+
+const KEYS = [1, 2, 3, 4, 5, 6, 7, 8, 9, 0];
+const KEY_W = 28;
+const KEY_H = 28;
+const KEY_GAP = 4;
+
+const num = (v) => Number(v) || 0;
+
+export const hotkeyRoleLabel = (role) => (role === 'building' ? 'buildings' : role === 'army' ? 'army' : 'unknown');
+
+function HotkeyLayoutKeys({ groups }) {
+  const byKey = new Map((groups || []).map((g) => [num(g.group), g]));
+  const peak = Math.max(1, ...(groups || []).map((g) => num(g.use_percent)));
+  const W = KEYS.length * (KEY_W + KEY_GAP) - KEY_GAP;
+
+  return (
+    <svg width={W} height={KEY_H} viewBox={`0 0 ${W} ${KEY_H}`} style={{ display: 'block' }}>
+      {KEYS.map((key, i) => {
+        const g = byKey.get(key);
+        const x = i * (KEY_W + KEY_GAP);
+        const color = HOTKEY_ROLE_COLORS[g?.role || ''] || HOTKEY_ROLE_COLORS[''];
+        const fill = g ? (num(g.use_percent) / peak) * KEY_H : 0;
+        return (
+          <g key={`key-${key}`}>
+            <title>
+              {g
+                ? `Group ${key}: ${hotkeyRoleLabel(g.role)}, ${num(g.use_percent).toFixed(0)}% of recalls (${num(g.selects)} recalls, ${num(g.assigns)} binds)`
+                : `Group ${key}: unused`}
+            </title>
+            <rect x={x} y={0} width={KEY_W} height={KEY_H} rx={4} fill="rgba(255,255,255,0.04)" stroke={g ? color : 'rgba(255,255,255,0.15)'} strokeWidth="1" />
+            {fill > 0 ? (
+              <rect x={x + 1} y={KEY_H - fill} width={KEY_W - 2} height={Math.max(0, fill - 1)} rx={3} fill={color} opacity={0.45} />
+            ) : null}
+            <text x={x + KEY_W / 2} y={KEY_H / 2 + 4} textAnchor="middle" fill={g ? '#fff' : 'rgba(255,255,255,0.35)'} fontSize="12" fontWeight={g ? 700 : 400}>
+              {key}
+            </text>
+          </g>
+        );
+      })}
+    </svg>
+  );
+}
+
+export default HotkeyLayoutKeys;
@@ -0,0 +1,188 @@
+import React, { useState } from 'react';
+import HotkeyLayoutKeys, { HOTKEY_ROLE_COLORS, hotkeyRoleLabel } from './HotkeyLayoutKeys';
+
+// HotkeyTimeline draws, for each player in the hotkeys entries of the game
+// detail, their control-group layout and one row per group: a tick for every
+// second the group was recalled (taller for more recalls), a diamond where it
+// was bound and a dot where a recall was double-tapped to jump the screen
+// to the group.
+//
+// Roles come from the units each bind selected (see unittags.HotkeyBinding):
+// a single building, or army when more than one unit was selected. Replays
+// don't record the F2-F4 screen hotkeys, so double taps are the only camera
+// jumps shown.
+
+const FALLBACK_COLORS = ['#60a5fa', '#f87171', '#34d399', '#fbbf24', '#a78bfa', '#f472b6', '#22d3ee', '#fb923c'];
+
+const W = 1000;
+const ROW_H = 16;
+const ROW_GAP = 4;
+const HEADER_H = 20;
+const KEYS_H = 36;
+const M = { left: 150, right: 16, top: 8, bottom: 28 };
+const PLOT_W = W - M.left - M.right;
+
+const formatTime = (seconds) => {
+  const value = Math.max(0, Math.floor(Number(seconds) || 0));
+  return `${Math.floor(value / 60)}:${String(value % 60).padStart(2, '0')}`;
+};
+
+const num = (v) => Number(v) || 0;
+
+function HotkeyTimeline({ players, entries, durationSeconds, earlyEndsAt, midEndsAt, playerColor }) {
+  const duration = Math.max(1, Math.floor(Number(durationSeconds) || 0));
+  const [hovered, setHovered] = useState(null);
+
+  const playerByID = new Map((players || []).map((p, idx) => [p.player_id, { player: p, idx }]));
+  const groups = (entries || [])
+    .map((entry, i) => {
+      const known = playerByID.get(entry.player_id);
+      const player = known?.player || { player_id: entry.player_id, name: entry.player_name };
+      const idx = known ? known.idx : i;
+      const color = playerColor && player.color ? playerColor(player.color) : FALLBACK_COLORS[idx % FALLBACK_COLORS.length];
+      return { entry, color };
+    })
+    .filter((g) => (g.entry.groups || []).length > 0);
+
+  if (groups.length === 0) return null;
+
+  const xAt = (sec) => M.left + (Math.max(0, Math.min(duration, num(sec))) / duration) * PLOT_W;
+  let y = M.top;
+  const layout = groups.map((g) => {
+    const headerY = y;
+    y += HEADER_H + ROW_GAP;
+    const rows = g.entry.groups.map((group) => {
+      const rowY = y;
+      y += ROW_H + ROW_GAP;
+      return { group, rowY };
+    });
+    y += ROW_GAP;
+    return { ...g, headerY, rows };
+  });
+  const plotBottom = y;
+  const H = plotBottom + M.bottom;
+
+  const xStep = duration <= 600 ? 60 : duration <= 1800 ? 120 : 300;
+  const xTicks = [];
+  for (let t = 0; t <= duration; t += xStep) xTicks.push(t);
+  const phaseLines = [
+    { sec: num(earlyEndsAt), label: 'Mid game' },
+    { sec: num(midEndsAt), label: 'Late game' },
+  ].filter((p) => p.sec > 0 && p.sec < duration);
+
+  return (
+    <div className="workflow-card workflow-card-chat-summary">
+      <div style={{ display: 'flex', flexWrap: 'wrap', gap: 24, marginBottom: 8 }}>
+        {layout.map(({ entry, color }) => (
+          <div key={`keys-${entry.player_id}`} style={{ minHeight: KEYS_H }}>
+            <div style={{ color, fontSize: 12, fontWeight: 700, marginBottom: 4 }}>
+              {entry.is_winner ? '👑 ' : ''}{entry.player_name}{entry.layout ? ` · ${entry.layout}` : ''}
+            </div>
+            <HotkeyLayoutKeys groups={entry.groups} />
+          </div>
+        ))}
+      </div>
+
+      <svg width="100%" viewBox={`0 0 ${W} ${H}`} preserveAspectRatio="xMidYMid meet" style={{ display: 'block' }}>
+        {xTicks.map((t) => (
+          <g key={`x-${t}`}>
+            <line x1={xAt(t)} y1={M.top} x2={xAt(t)} y2={plotBottom} stroke="rgba(255,255,255,0.06)" strokeWidth="1" />
+            <text x={xAt(t)} y={H - 10} textAnchor="middle" fill="rgba(255,255,255,0.55)" fontSize="11">{formatTime(t)}</text>
+          </g>
+        ))}
+        {phaseLines.map((p) => (
+          <g key={`phase-${p.label}`}>
+            <line x1={xAt(p.sec)} y1={M.top} x2={xAt(p.sec)} y2={plotBottom} stroke="rgba(255,255,255,0.35)" strokeWidth="1" strokeDasharray="4 4" />
+            <text x={xAt(p.sec) + 4} y={plotBottom - 4} fill="rgba(255,255,255,0.55)" fontSize="10">{p.label}</text>
+          </g>
+        ))}
+
+        {layout.map(({ entry, color, headerY, rows }) => {
+          const peak = Math.max(1, ...rows.flatMap(({ group }) => (group.uses || []).map((u) => num(u.selects))));
+          return (
+            <g key={`player-${entry.player_id}`}>
+              <text x={8} y={headerY + 14} fill={color} fontSize="12" fontWeight={700}>
+                {entry.is_winner ? '👑 ' : ''}{entry.player_name} · {num(entry.selects_per_minute).toFixed(1)} recalls/min · {num(entry.rebinds)} rebinds · {num(entry.camera_jump_percent).toFixed(0)}% camera jumps
+              </text>
+              {rows.map(({ group, rowY }) => {
+                const key = `${entry.player_id}-${group.group}`;
+                const isHover = hovered === key;
+                const roleColor = HOTKEY_ROLE_COLORS[group.role || ''] || HOTKEY_ROLE_COLORS[''];
+                return (
+                  <g
+                    key={key}
+                    onMouseEnter={() => setHovered(key)}
+                    onMouseLeave={() => setHovered(null)}
+                  >
+                    <title>
+                      {`Group ${group.group} (${hotkeyRoleLabel(group.role)}): ${num(group.selects)} recalls, ${num(group.assigns)} binds, ${num(group.adds)} adds, ${num(group.camera_jumps)} camera jumps${group.first_bound_second != null ? `, first bound ${formatTime(group.first_bound_second)}` : ''}`}
+                    </title>
+                    <text x={M.left - 8} y={rowY + ROW_H - 4} textAnchor="end" fill={roleColor} fontSize="11">
+                      Group {group.group} · {hotkeyRoleLabel(group.role)}
+                    </text>
+                    <rect
+                      x={xAt(0)}
+                      y={rowY}
+                      width={Math.max(0, xAt(entry.window_end_second) - xAt(0))}
+                      height={ROW_H}
+                      fill="rgba(255,255,255,0.04)"
+                      stroke={isHover ? 'rgba(255,255,255,0.5)' : 'none'}
+                    />
+                    {(group.uses || []).filter((u) => num(u.selects) > 0).map((u) => {
+                      const h = Math.max(3, ((ROW_H - 2) * num(u.selects)) / peak);
+                      return (
+                        <rect
+                          key={`${key}-use-${u.second}`}
+                          x={xAt(u.second)}
+                          y={rowY + ROW_H - 1 - h}
+                          width={Math.max(1, PLOT_W / duration)}
+                          height={h}
+                          fill={color}
+                          opacity={isHover ? 0.9 : 0.65}
+                        />
+                      );
+                    })}
+                    {(group.uses || []).filter((u) => num(u.assigns) > 0).map((u) => (
+                      <path
+                        key={`${key}-bind-${u.second}`}
+                        d={`M ${xAt(u.second)} ${rowY + 1} l 4 ${ROW_H / 2 - 1} l -4 ${ROW_H / 2 - 1} l -4 ${-(ROW_H / 2 - 1)} Z`}
+                        fill={roleColor}
+                        stroke="rgba(0,0,0,0.5)"
+                        strokeWidth="0.5"
+                      />
+                    ))}
+                    {(group.uses || []).filter((u) => num(u.camera_jumps) > 0).map((u) => (
+                      <circle
+                        key={`${key}-jump-${u.second}`}
+                        cx={xAt(u.second)}
+                        cy={rowY + 3}
+                        r={2.5}
+                        fill="#fff"
+                      />
+                    ))}
+                  </g>
+                );
+              })}
+            </g>
+          );
+        })}
+      </svg>
+
+      <div className="workflow-card-subtitle">
+        Ticks: recalls. Diamonds: binds. Dots: camera jumps (double taps).
+        {' '}
+        {layout.map(({ entry, color }, i) => (
+          <span key={`legend-${entry.player_id}`} style={{ color }}>
+            {i > 0 ? ' · ' : ''}
+            {entry.player_name}
+            {(entry.phases || []).length > 0
+              ? ` (${entry.phases.map((p) => `${p.label.toLowerCase()} ${num(p.selects_per_minute).toFixed(1)}`).join(', ')} recalls/min)`
+              : ''}
+          </span>
+        ))}
+      </div>
+    </div>
+  );
+}
+
+export default HotkeyTimeline;
@@ -0,0 +1,205 @@
+import React, { useState } from 'react';
+
+// LarvaTimeline draws, for each Zerg in the larva entries of the game detail,
+// a step line of how many hatcheries stood over time and one row per
+// hatchery: a band shaded by how many larvae were waiting, from when the
+// hatchery was ready to when its owner stopped playing, with the banked spans
+// (three larvae waiting, so spawns are lost) outlined.
+//
+// Larva counts come from a simulation of the 14.4s larva timer against the
+// morph commands each hatchery received (see unittags.HatcheryLarvae), so
+// morphs refused for money or supply still use larvae.
+
+const FALLBACK_COLORS = ['#60a5fa', '#f87171', '#34d399', '#fbbf24', '#a78bfa', '#f472b6', '#22d3ee', '#fb923c'];
+
+const W = 1000;
+const ROW_H = 16;
+const ROW_GAP = 4;
+const HEADER_H = 20;
+const COUNT_H = 28;
+const M = { left: 150, right: 16, top: 8, bottom: 28 };
+const PLOT_W = W - M.left - M.right;
+const LARVA_CAP = 3;
+
+const formatTime = (seconds) => {
+  const value = Math.max(0, Math.floor(Number(seconds) || 0));
+  return `${Math.floor(value / 60)}:${String(value % 60).padStart(2, '0')}`;
+};
+
+const num = (v) => Number(v) || 0;
+
+function LarvaTimeline({ players, entries, durationSeconds, earlyEndsAt, midEndsAt, playerColor }) {
+  const duration = Math.max(1, Math.floor(Number(durationSeconds) || 0));
+  const [hovered, setHovered] = useState(null);
+
+  const playerByID = new Map((players || []).map((p, idx) => [p.player_id, { player: p, idx }]));
+  const groups = (entries || [])
+    .map((entry, i) => {
+      const known = playerByID.get(entry.player_id);
+      const player = known?.player || { player_id: entry.player_id, name: entry.player_name };
+      const idx = known ? known.idx : i;
+      const color = playerColor && player.color ? playerColor(player.color) : FALLBACK_COLORS[idx % FALLBACK_COLORS.length];
+      return { entry, color };
+    })
+    .filter((g) => (g.entry.hatcheries || []).length > 0);
+
+  if (groups.length === 0) return null;
+
+  const xAt = (sec) => M.left + (Math.max(0, Math.min(duration, num(sec))) / duration) * PLOT_W;
+  let y = M.top;
+  const layout = groups.map((g) => {
+    const headerY = y;
+    y += HEADER_H;
+    const countY = y;
+    y += COUNT_H + ROW_GAP;
+    const rows = g.entry.hatcheries.map((h) => {
+      const rowY = y;
+      y += ROW_H + ROW_GAP;
+      return { h, rowY };
+    });
+    y += ROW_GAP;
+    return { ...g, headerY, countY, rows };
+  });
+  const plotBottom = y;
+  const H = plotBottom + M.bottom;
+
+  const xStep = duration <= 600 ? 60 : duration <= 1800 ? 120 : 300;
+  const xTicks = [];
+  for (let t = 0; t <= duration; t += xStep) xTicks.push(t);
+  const phaseLines = [
+    { sec: num(earlyEndsAt), label: 'Mid game' },
+    { sec: num(midEndsAt), label: 'Late game' },
+  ].filter((p) => p.sec > 0 && p.sec < duration);
+
+  // countPath steps through the hatchery count from the first hatchery to the
+  // latest window end, scaled so the most hatcheries fill the strip.
+  const countPath = (entry, countY) => {
+    const counts = entry.hatchery_counts || [];
+    if (counts.length === 0) return { d: '', peak: 0 };
+    const peak = Math.max(...counts.map((c) => num(c.hatcheries)), 1);
+    const end = Math.max(...(entry.hatcheries || []).map((h) => num(h.window_end_second)), num(counts[counts.length - 1].second));
+    const yAt = (n) => countY + COUNT_H - (num(n) / peak) * COUNT_H;
+    let d = `M ${xAt(counts[0].second)} ${yAt(counts[0].hatcheries)}`;
+    for (let i = 1; i < counts.length; i += 1) {
+      d += ` H ${xAt(counts[i].second)} V ${yAt(counts[i].hatcheries)}`;
+    }
+    d += ` H ${xAt(end)}`;
+    return { d, peak };
+  };
+
+  // larvaSegments turns a hatchery's count samples into spans of constant
+  // larva count, each closed by the next sample or the window end.
+  const larvaSegments = (h) => {
+    const counts = h.counts || [];
+    return counts.map((c, i) => ({
+      start: num(c.second),
+      end: i + 1 < counts.length ? num(counts[i + 1].second) : Math.max(num(c.second), num(h.window_end_second)),
+      larvae: num(c.larvae),
+    })).filter((s) => s.end > s.start && s.larvae > 0);
+  };
+
+  return (
+    <div className="workflow-card workflow-card-chat-summary">
+      <div className="workflow-section-warning">
+        ⚠️ Estimated, not measured: larvae follow the morph commands each
+        hatchery received, so refused morphs still use larvae and a destroyed
+        hatchery keeps spawning.
+      </div>
+
+      <svg width="100%" viewBox={`0 0 ${W} ${H}`} preserveAspectRatio="xMidYMid meet" style={{ display: 'block' }}>
+        {xTicks.map((t) => (
+          <g key={`x-${t}`}>
+            <line x1={xAt(t)} y1={M.top} x2={xAt(t)} y2={plotBottom} stroke="rgba(255,255,255,0.06)" strokeWidth="1" />
+            <text x={xAt(t)} y={H - 10} textAnchor="middle" fill="rgba(255,255,255,0.55)" fontSize="11">{formatTime(t)}</text>
+          </g>
+        ))}
+        {phaseLines.map((p) => (
+          <g key={`phase-${p.label}`}>
+            <line x1={xAt(p.sec)} y1={M.top} x2={xAt(p.sec)} y2={plotBottom} stroke="rgba(255,255,255,0.35)" strokeWidth="1" strokeDasharray="4 4" />
+            <text x={xAt(p.sec) + 4} y={plotBottom - 4} fill="rgba(255,255,255,0.55)" fontSize="10">{p.label}</text>
+          </g>
+        ))}
+
+        {layout.map(({ entry, color, headerY, countY, rows }) => {
+          const { d, peak } = countPath(entry, countY);
+          return (
+            <g key={`player-${entry.player_id}`}>
+              <text x={8} y={headerY + 14} fill={color} fontSize="12" fontWeight={700}>
+                {entry.is_winner ? '👑 ' : ''}{entry.player_name} · {num(entry.missed_percent).toFixed(0)}% larvae missed
+              </text>
+              <text x={M.left - 8} y={countY + COUNT_H - 4} textAnchor="end" fill="rgba(255,255,255,0.7)" fontSize="11">
+                Hatcheries (max {peak})
+              </text>
+              <path d={d} fill="none" stroke={color} strokeWidth="2" />
+              {rows.map(({ h, rowY }) => {
+                const key = `${entry.player_id}-${h.index}`;
+                const isHover = hovered === key;
+                return (
+                  <g
+                    key={key}
+                    onMouseEnter={() => setHovered(key)}
+                    onMouseLeave={() => setHovered(null)}
+                  >
+                    <title>
+                      {`Hatchery #${h.index}: ready ${formatTime(h.ready_second)}, ${num(h.spawned)} spawned, ${num(h.used)} used, ${num(h.missed)} missed, banked ${formatTime(h.banked_seconds)}`}
+                    </title>
+                    <text x={M.left - 8} y={rowY + ROW_H - 4} textAnchor="end" fill="rgba(255,255,255,0.7)" fontSize="11">
+                      Hatchery #{h.index}
+                    </text>
+                    <rect
+                      x={xAt(h.ready_second)}
+                      y={rowY}
+                      width={Math.max(0, xAt(h.window_end_second) - xAt(h.ready_second))}
+                      height={ROW_H}
+                      fill="rgba(255,255,255,0.06)"
+                      stroke={isHover ? 'rgba(255,255,255,0.5)' : 'none'}
+                    />
+                    {larvaSegments(h).map((s) => (
+                      <rect
+                        key={`${key}-count-${s.start}`}
+                        x={xAt(s.start)}
+                        y={rowY + 2}
+                        width={Math.max(1, xAt(s.end) - xAt(s.start))}
+                        height={ROW_H - 4}
+                        fill={color}
+                        opacity={(isHover ? 0.35 : 0.25) * s.larvae}
+                      />
+                    ))}
+                    {(h.banked || []).map((span) => (
+                      <rect
+                        key={`${key}-banked-${span.start_second}`}
+                        x={xAt(span.start_second)}
+                        y={rowY + 1}
+                        width={Math.max(1, xAt(span.end_second) - xAt(span.start_second))}
+                        height={ROW_H - 2}
+                        fill="none"
+                        stroke="#fbbf24"
+                        strokeWidth="1.5"
+                      />
+                    ))}
+                  </g>
+                );
+              })}
+            </g>
+          );
+        })}
+      </svg>
+
+      <div className="workflow-card-subtitle">
+        Darker: more larvae waiting (up to {LARVA_CAP}). Outlined: banked at {LARVA_CAP}, spawns lost.
+        {' '}
+        {layout.map(({ entry, color }, i) => (
+          <span key={`legend-${entry.player_id}`} style={{ color }}>
+            {i > 0 ? ' · ' : ''}
+            {entry.player_name}
+            {(entry.phases || []).length > 0
+              ? ` (${entry.phases.map((p) => `${p.label.toLowerCase()} ${num(p.missed_percent).toFixed(0)}%`).join(', ')} missed)`
+              : ''}
+          </span>
+        ))}
+      </div>
+    </div>
+  );
+}
+
+export default LarvaTimeline;
@@ -0,0 +1,154 @@
+import React, { useState } from 'react';
+
+// ProductionBusyTimeline draws one row per Gateway, Barracks and Factory from
+// the production_idle entries of the game detail: a faint bar from when the
+// building was ready to when its owner stopped playing, with the estimated
+// busy spans filled in the player's color. The gaps are idle production time.
+//
+// Busy time comes from which building each Train command selected (see
+// unittags.ProducerTimelines), so units refused for money or supply, and
+// cancels, still show as busy, and destroyed buildings keep their row.
+
+const FALLBACK_COLORS = ['#60a5fa', '#f87171', '#34d399', '#fbbf24', '#a78bfa', '#f472b6', '#22d3ee', '#fb923c'];
+
+const W = 1000;
+const ROW_H = 16;
+const ROW_GAP = 4;
+const HEADER_H = 20;
+const M = { left: 150, right: 16, top: 8, bottom: 28 };
+const PLOT_W = W - M.left - M.right;
+
+const formatTime = (seconds) => {
+  const value = Math.max(0, Math.floor(Number(seconds) || 0));
+  return `${Math.floor(value / 60)}:${String(value % 60).padStart(2, '0')}`;
+};
+
+const num = (v) => Number(v) || 0;
+
+function ProductionBusyTimeline({ players, entries, durationSeconds, earlyEndsAt, midEndsAt, playerColor }) {
+  const duration = Math.max(1, Math.floor(Number(durationSeconds) || 0));
+  const [hovered, setHovered] = useState(null);
+
+  const playerByID = new Map((players || []).map((p, idx) => [p.player_id, { player: p, idx }]));
+  const groups = (entries || [])
+    .map((entry, i) => {
+      const known = playerByID.get(entry.player_id);
+      const player = known?.player || { player_id: entry.player_id, name: entry.player_name };
+      const idx = known ? known.idx : i;
+      const color = playerColor && player.color ? playerColor(player.color) : FALLBACK_COLORS[idx % FALLBACK_COLORS.length];
+      return { entry, color };
+    })
+    .filter((g) => (g.entry.buildings || []).length > 0);
+
+  if (groups.length === 0) return null;
+
+  const xAt = (sec) => M.left + (Math.max(0, Math.min(duration, num(sec))) / duration) * PLOT_W;
+  let y = M.top;
+  const layout = groups.map((g) => {
+    const headerY = y;
+    y += HEADER_H;
+    const rows = g.entry.buildings.map((b) => {
+      const rowY = y;
+      y += ROW_H + ROW_GAP;
+      return { b, rowY };
+    });
+    y += ROW_GAP;
+    return { ...g, headerY, rows };
+  });
+  const plotBottom = y;
+  const H = plotBottom + M.bottom;
+
+  const xStep = duration <= 600 ? 60 : duration <= 1800 ? 120 : 300;
+  const xTicks = [];
+  for (let t = 0; t <= duration; t += xStep) xTicks.push(t);
+  const phaseLines = [
+    { sec: num(earlyEndsAt), label: 'Mid game' },
+    { sec: num(midEndsAt), label: 'Late game' },
+  ].filter((p) => p.sec > 0 && p.sec < duration);
+
+  return (
+    <div className="workflow-card workflow-card-chat-summary">
+      <div className="workflow-section-warning">
+        ⚠️ Estimated, not measured: busy time follows the Train commands each
+        building received, so refused or cancelled units still count as busy
+        and a destroyed building keeps its row.
+      </div>
+
+      <svg width="100%" viewBox={`0 0 ${W} ${H}`} preserveAspectRatio="xMidYMid meet" style={{ display: 'block' }}>
+        {xTicks.map((t) => (
+          <g key={`x-${t}`}>
+            <line x1={xAt(t)} y1={M.top} x2={xAt(t)} y2={plotBottom} stroke="rgba(255,255,255,0.06)" strokeWidth="1" />
+            <text x={xAt(t)} y={H - 10} textAnchor="middle" fill="rgba(255,255,255,0.55)" fontSize="11">{formatTime(t)}</text>
+          </g>
+        ))}
+        {phaseLines.map((p) => (
+          <g key={`phase-${p.label}`}>
+            <line x1={xAt(p.sec)} y1={M.top} x2={xAt(p.sec)} y2={plotBottom} stroke="rgba(255,255,255,0.35)" strokeWidth="1" strokeDasharray="4 4" />
+            <text x={xAt(p.sec) + 4} y={plotBottom - 4} fill="rgba(255,255,255,0.55)" fontSize="10">{p.label}</text>
+          </g>
+        ))}
+
+        {layout.map(({ entry, color, headerY, rows }) => (
+          <g key={`player-${entry.player_id}`}>
+            <text x={8} y={headerY + 14} fill={color} fontSize="12" fontWeight={700}>
+              {entry.is_winner ? '👑 ' : ''}{entry.player_name} · {num(entry.idle_percent).toFixed(0)}% idle
+            </text>
+            {rows.map(({ b, rowY }) => {
+              const key = `${entry.player_id}-${b.building}-${b.index}`;
+              const isHover = hovered === key;
+              return (
+                <g
+                  key={key}
+                  onMouseEnter={() => setHovered(key)}
+                  onMouseLeave={() => setHovered(null)}
+                >
+                  <title>
+                    {`${b.building} #${b.index}: ready ${formatTime(b.ready_second)}, busy ${formatTime(b.busy_seconds)}, ${num(b.idle_percent).toFixed(0)}% idle`}
+                  </title>
+                  <text x={M.left - 8} y={rowY + ROW_H - 4} textAnchor="end" fill="rgba(255,255,255,0.7)" fontSize="11">
+                    {b.building} #{b.index}
+                  </text>
+                  <rect
+                    x={xAt(b.ready_second)}
+                    y={rowY}
+                    width={Math.max(0, xAt(b.window_end_second) - xAt(b.ready_second))}
+                    height={ROW_H}
+                    fill="rgba(255,255,255,0.06)"
+                    stroke={isHover ? 'rgba(255,255,255,0.5)' : 'none'}
+                  />
+                  {(b.busy || []).map((span) => (
+                    <rect
+                      key={`${key}-${span.start_second}`}
+                      x={xAt(span.start_second)}
+                      y={rowY + 2}
+                      width={Math.max(1, xAt(span.end_second) - xAt(span.start_second))}
+                      height={ROW_H - 4}
+                      fill={color}
+                      opacity={isHover ? 1 : 0.8}
+                    />
+                  ))}
+                </g>
+              );
+            })}
+          </g>
+        ))}
+      </svg>
+
+      <div className="workflow-card-subtitle">
+        Filled: training. Faint: ready but idle.
+        {' '}
+        {layout.map(({ entry, color }, i) => (
+          <span key={`legend-${entry.player_id}`} style={{ color }}>
+            {i > 0 ? ' · ' : ''}
+            {entry.player_name}
+            {(entry.phases || []).length > 0
+              ? ` (${entry.phases.map((p) => `${p.label.toLowerCase()} ${num(p.idle_percent).toFixed(0)}%`).join(', ')} idle)`
+              : ''}
+          </span>
+        ))}
+      </div>
+    </div>
+  );
+}
+
+export default ProductionBusyTimeline;
@@ -7,6 +7,7 @@ export const MAIN_GAME_TABS = [
   'events',
   'units',
   'supply-timeline',
+  'economy-timeline',
   'timings',
   'build-orders',
   'mutalisk-timing',
@@ -14,6 +15,10 @@ export const MAIN_GAME_TABS = [
   'first-unit-efficiency',
   'unit-production-cadence',
   'viewport-multitasking',
+  'supply-blocks',
+  'production-idle',
+  'larva-missed',
+  'hotkeys',
 ];
 
 export const MAIN_PLAYERS_TABS = [
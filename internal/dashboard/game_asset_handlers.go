@@ -11,7 +11,9 @@ import (
 
 	"github.com/marianogappa/scmapanalyzer/lib/scmapanalyzer"
 	"github.com/marianogappa/screpdb/internal/appdata"
+	"github.com/marianogappa/screpdb/internal/fileops"
 	"github.com/marianogappa/screpdb/internal/iofacade"
+	"github.com/marianogappa/screpdb/internal/screp"
 	"golang.org/x/sync/singleflight"
 )
 
@@ -151,7 +153,7 @@ func (d *Dashboard) handlerGameAssetMap(w http.ResponseWriter, r *http.Request)
 		if data, readErr := iofacade.ReadFile(cachePath); readErr == nil && len(data) > 0 {
 			return data, nil
 		}
-		pngBytes, genErr := scmapanalyzer.MapImagePNGFromReplayFile(replayPath)
+		pngBytes, genErr := mapImagePNG(replayPath)
 		if genErr != nil {
 			return nil, genErr
 		}
@@ -172,3 +174,17 @@ func (d *Dashboard) handlerGameAssetMap(w http.ResponseWriter, r *http.Request)
 	w.Header().Set("Expires", "0")
 	_, _ = w.Write(pngBytes)
 }
+
+// mapImagePNG renders the map of the replay at replayPath. scmapanalyzer only
+// reads replays from disk, so archive entries are parsed here and rendered
+// from memory.
+func mapImagePNG(replayPath string) ([]byte, error) {
+	if _, _, ok := fileops.SplitArchivePath(replayPath); !ok {
+		return scmapanalyzer.MapImagePNGFromReplayFile(replayPath)
+	}
+	rep, err := screp.ParseFile(replayPath)
+	if err != nil {
+		return nil, err
+	}
+	return scmapanalyzer.MapImagePNGFromScrepReplay(rep)
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	dashboarddb "github.com/marianogappa/screpdb/internal/dashboard/db"
+	"github.com/marianogappa/screpdb/internal/playeragg"
 )
 
 const (
@@ -18,7 +19,7 @@ const (
 	globalReplayFilterGameTypeFreeForAll  = "free_for_all"
 	globalReplayFilterMapKindRegular      = "regular"
 	globalReplayFilterMapKindMoney        = "money"
-	globalReplayFilterShortGameSeconds    = 120
+	globalReplayFilterShortGameSeconds    = playeragg.ShortGameSeconds
 )
 
 type globalReplayFilterConfig struct {
@@ -1,8 +1,10 @@
 package dashboard
 
 import (
+	"github.com/icza/screp/rep"
 	"github.com/marianogappa/scmapanalyzer/lib/scmapanalyzer"
 	"github.com/marianogappa/scmapanalyzer/replaymap"
+	"github.com/marianogappa/screpdb/internal/fileops"
 	"github.com/marianogappa/screpdb/internal/models"
 	"github.com/marianogappa/screpdb/internal/screp"
 )
@@ -13,7 +15,17 @@ func buildDashboardMapContextLayoutFromReplay(replayPath string) (*models.MapCon
 		return nil, err
 	}
 
-	result, err := client.Analyze(replayPath)
+	// scmapanalyzer only reads replays from disk; for an archive entry the map
+	// name lets it answer from its embedded cache of ladder maps instead.
+	var opts []scmapanalyzer.Option
+	var parsed *rep.Replay
+	if _, _, ok := fileops.SplitArchivePath(replayPath); ok {
+		if parsed, err = screp.ParseFile(replayPath); err != nil {
+			return nil, err
+		}
+		opts = append(opts, scmapanalyzer.WithMapName(parsed.Header.Map))
+	}
+	result, err := client.Analyze(replayPath, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -29,9 +41,12 @@ func buildDashboardMapContextLayoutFromReplay(replayPath string) (*models.MapCon
 		return nil, nil
 	}
 	layout := &models.MapContextLayout{Bases: bases}
-	if rep, repErr := screp.ParseFile(replayPath); repErr == nil && rep != nil {
-		layout.WidthTiles = int(rep.Header.MapWidth)
-		layout.HeightTiles = int(rep.Header.MapHeight)
+	if parsed == nil {
+		parsed, _ = screp.ParseFile(replayPath)
+	}
+	if parsed != nil {
+		layout.WidthTiles = int(parsed.Header.MapWidth)
+		layout.HeightTiles = int(parsed.Header.MapHeight)
 	}
 	return layout, nil
 }
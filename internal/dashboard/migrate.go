@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 
+	"github.com/marianogappa/screpdb/internal/backup"
 	"github.com/marianogappa/screpdb/internal/migrations"
 )
 
@@ -12,6 +13,9 @@ import (
 // Runtime dashboard query/scan paths should go through internal/dashboard/db.
 
 func runMigrations(sqlitePath string) error {
+	if _, _, err := backup.BeforePendingMigrations(context.Background(), sqlitePath, migrations.MigrationSetReplay, migrations.MigrationSetDashboard, migrations.MigrationSetSettings); err != nil {
+		return err
+	}
 	if err := migrations.RunMigrations(sqlitePath); err != nil {
 		return err
 	}
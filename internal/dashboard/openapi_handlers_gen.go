@@ -10,6 +10,21 @@ import (
 )
 
 // Code generated by gen_openapi_bridge. DO NOT EDIT.
+type ChatSearchJSONResponse struct {
+	Payload any
+}
+
+func (response ChatSearchJSONResponse) VisitChatSearchResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	return json.NewEncoder(w).Encode(response.Payload)
+}
+
+func (a *openAPIStrictAdapter) ChatSearch(ctx context.Context, request apigen.ChatSearchRequestObject) (apigen.ChatSearchResponseObject, error) {
+	return responseFromPayload(ctx, request, a.service.ChatSearch, func(value any) apigen.ChatSearchResponseObject { return ChatSearchJSONResponse{Payload: value} })
+}
+
 type ListAliasesJSONResponse struct {
 	Payload any
 }
@@ -74,6 +89,36 @@ func (a *openAPIStrictAdapter) DeleteAliasEntry(ctx context.Context, request api
 	})
 }
 
+type ListBackupsJSONResponse struct {
+	Payload any
+}
+
+func (response ListBackupsJSONResponse) VisitListBackupsResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	return json.NewEncoder(w).Encode(response.Payload)
+}
+
+func (a *openAPIStrictAdapter) ListBackups(ctx context.Context, request apigen.ListBackupsRequestObject) (apigen.ListBackupsResponseObject, error) {
+	return responseFromPayload(ctx, request, a.service.ListBackups, func(value any) apigen.ListBackupsResponseObject { return ListBackupsJSONResponse{Payload: value} })
+}
+
+type CreateBackupJSONResponse struct {
+	Payload any
+}
+
+func (response CreateBackupJSONResponse) VisitCreateBackupResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	return json.NewEncoder(w).Encode(response.Payload)
+}
+
+func (a *openAPIStrictAdapter) CreateBackup(ctx context.Context, request apigen.CreateBackupRequestObject) (apigen.CreateBackupResponseObject, error) {
+	return responseFromPayload(ctx, request, a.service.CreateBackup, func(value any) apigen.CreateBackupResponseObject { return CreateBackupJSONResponse{Payload: value} })
+}
+
 type GetGlobalReplayFilterConfigJSONResponse struct {
 	Payload any
 }
@@ -140,6 +185,40 @@ func (a *openAPIStrictAdapter) Ingest(ctx context.Context, request apigen.Ingest
 	return responseFromPayload(ctx, request, a.service.Ingest, func(value any) apigen.IngestResponseObject { return IngestJSONResponse{Payload: value} })
 }
 
+type ListIngestFailuresJSONResponse struct {
+	Payload any
+}
+
+func (response ListIngestFailuresJSONResponse) VisitListIngestFailuresResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	return json.NewEncoder(w).Encode(response.Payload)
+}
+
+func (a *openAPIStrictAdapter) ListIngestFailures(ctx context.Context, request apigen.ListIngestFailuresRequestObject) (apigen.ListIngestFailuresResponseObject, error) {
+	return responseFromPayload(ctx, request, a.service.ListIngestFailures, func(value any) apigen.ListIngestFailuresResponseObject {
+		return ListIngestFailuresJSONResponse{Payload: value}
+	})
+}
+
+type RetryIngestFailuresJSONResponse struct {
+	Payload any
+}
+
+func (response RetryIngestFailuresJSONResponse) VisitRetryIngestFailuresResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	return json.NewEncoder(w).Encode(response.Payload)
+}
+
+func (a *openAPIStrictAdapter) RetryIngestFailures(ctx context.Context, request apigen.RetryIngestFailuresRequestObject) (apigen.RetryIngestFailuresResponseObject, error) {
+	return responseFromPayload(ctx, request, a.service.RetryIngestFailures, func(value any) apigen.RetryIngestFailuresResponseObject {
+		return RetryIngestFailuresJSONResponse{Payload: value}
+	})
+}
+
 func (a *openAPIStrictAdapter) IngestLogs(ctx context.Context, request apigen.IngestLogsRequestObject) (apigen.IngestLogsResponseObject, error) {
 	return responseFromPayload(ctx, request, a.service.IngestLogs, func(_ any) apigen.IngestLogsResponseObject { return apigen.IngestLogs101Response{} })
 }
@@ -7,11 +7,14 @@ import (
 	"fmt"
 	"net/http"
 	"path"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/icza/screp/rep/repcore"
 	"github.com/marianogappa/screpdb/internal/appdata"
 	"github.com/marianogappa/screpdb/internal/buildinfo"
+	"github.com/marianogappa/screpdb/internal/chatsearch"
 	"github.com/marianogappa/screpdb/internal/dashboard/apigen"
 	dashboardservice "github.com/marianogappa/screpdb/internal/dashboard/service"
 	"github.com/marianogappa/screpdb/internal/fileops"
@@ -95,6 +98,7 @@ func (d *Dashboard) Ingest(ctx context.Context, request apigen.IngestRequestObje
 		SQLitePath:       strings.TrimSpace(nullableStringValue(body.SqlitePath)),
 		StoreRightClicks: nullableBoolValue(body.StoreRightClicks),
 		SkipHotkeys:      nullableBoolValue(body.SkipHotkeys),
+		CompactCommands:  nullableBoolValue(body.CompactCommands),
 		StopAfterN:       nullableIntValue(body.StopAfterNReps),
 		UpToDate:         strings.TrimSpace(nullableStringValue(body.UpToYyyyMmDd)),
 		UpToMonths:       nullableIntValue(body.UpToNMonths),
@@ -123,6 +127,54 @@ func (d *Dashboard) Ingest(ctx context.Context, request apigen.IngestRequestObje
 	}, nil
 }
 
+// ListIngestFailures returns the ingest failure ledger: replay files that failed
+// to parse, panicked, or were skipped as UMS, most recently failed first.
+func (d *Dashboard) ListIngestFailures(ctx context.Context, _ apigen.ListIngestFailuresRequestObject) (any, error) {
+	failures, err := ingest.ListFailures(ctx, d.sqlitePath)
+	if err != nil {
+		return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
+	}
+	items := make([]map[string]any, 0, len(failures))
+	for _, f := range failures {
+		items = append(items, map[string]any{
+			"file_path":       f.FilePath,
+			"file_checksum":   f.FileChecksum,
+			"error_class":     f.ErrorClass,
+			"message":         f.Message,
+			"stack_hash":      f.StackHash,
+			"screpdb_version": f.ScrepdbVersion,
+			"attempts":        f.Attempts,
+			"first_failed_at": f.FirstFailedAt,
+			"last_failed_at":  f.LastFailedAt,
+		})
+	}
+	return map[string]any{
+		"count":           len(items),
+		"current_version": buildinfo.Version,
+		"failures":        items,
+	}, nil
+}
+
+// RetryIngestFailures starts an ingest of the configured replay folder that
+// re-attempts files recorded in the failure ledger, even ones that already
+// failed under the running version.
+func (d *Dashboard) RetryIngestFailures(ctx context.Context, _ apigen.RetryIngestFailuresRequestObject) (any, error) {
+	inputDir, err := d.getIngestInputDir(ctx)
+	if err != nil {
+		return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
+	}
+	if inputDir == "" {
+		return nil, dashboardservice.WithStatus(http.StatusBadRequest, errors.New("replay folder is not configured"))
+	}
+	started := d.startIngestAsync(ingest.Config{InputDir: inputDir, RetryFailed: true})
+	return map[string]any{
+		"ok":          true,
+		"started":     started,
+		"in_progress": !started,
+		"input_dir":   inputDir,
+	}, nil
+}
+
 func (d *Dashboard) IngestLogs(_ context.Context, _ apigen.IngestLogsRequestObject) (any, error) {
 	return map[string]any{"upgraded": true}, nil
 }
@@ -245,6 +297,9 @@ func (d *Dashboard) GamesList(ctx context.Context, request apigen.GamesListReque
 	if err := d.populateWorkflowGameListFeaturing(items); err != nil {
 		return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
 	}
+	if err := d.populateWorkflowGameListPOVs(items); err != nil {
+		return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
+	}
 	filterOptions, err := d.workflowGamesListFilterOptions()
 	if err != nil {
 		return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
@@ -259,15 +314,17 @@ func (d *Dashboard) GamesList(ctx context.Context, request apigen.GamesListReque
 	}, nil
 }
 
-func (d *Dashboard) GameDetail(_ context.Context, request apigen.GameDetailRequestObject) (any, error) {
-	detail, err := d.buildWorkflowGameDetail(request.ReplayID)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, dashboardservice.WithStatus(http.StatusNotFound, err)
+func (d *Dashboard) GameDetail(ctx context.Context, request apigen.GameDetailRequestObject) (any, error) {
+	return d.cachedResult(ctx, "GameDetail", request, func() (any, error) {
+		detail, err := d.buildWorkflowGameDetailForPOV(ctx, request.ReplayID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, dashboardservice.WithStatus(http.StatusNotFound, err)
+			}
+			return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
 		}
-		return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
-	}
-	return detail, nil
+		return detail, nil
+	})
 }
 
 func (d *Dashboard) GameSee(ctx context.Context, request apigen.GameSeeRequestObject) (any, error) {
@@ -277,10 +334,16 @@ func (d *Dashboard) GameSee(ctx context.Context, request apigen.GameSeeRequestOb
 	// the screpdb prefix.
 	const seeReplayFolderName = "000_screpdb_watch_me"
 	const seeReplayFilename = "watch_me.rep"
-	sourceFilePath, err := d.dbStore.GetReplayFilePathByID(ctx, request.ReplayID)
+	// Any POV of a game can be staged, as long as the game itself (its
+	// canonical replay) passes the replay filter.
+	groupID, _, pov, err := d.resolveGamePOV(ctx, request.ReplayID)
 	if err != nil {
 		return nil, dashboardservice.WithStatus(http.StatusNotFound, err)
 	}
+	if _, err := d.dbStore.GetReplayFilePathByID(ctx, groupID); err != nil {
+		return nil, dashboardservice.WithStatus(http.StatusNotFound, err)
+	}
+	sourceFilePath := pov.FilePath
 	ingestDirPath, err := d.getIngestInputDir(ctx)
 	if err != nil {
 		return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
@@ -297,7 +360,20 @@ func (d *Dashboard) GameSee(ctx context.Context, request apigen.GameSeeRequestOb
 		if err != nil {
 			return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
 		}
-		dest, err := winsandbox.BrokerSeeReplay(appDir, sourceFilePath, ingestDirPath)
+		// The launcher copies plain files only, so a replay inside an archive
+		// is first extracted to app-data and staged from there.
+		brokerSource := sourceFilePath
+		if _, _, ok := fileops.SplitArchivePath(sourceFilePath); ok {
+			input, err := fileops.ReadReplayFile(sourceFilePath)
+			if err != nil {
+				return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
+			}
+			brokerSource = filepath.Join(appDir, seeReplayFilename)
+			if err := iofacade.WriteFile(brokerSource, input, 0644); err != nil {
+				return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
+			}
+		}
+		dest, err := winsandbox.BrokerSeeReplay(appDir, brokerSource, ingestDirPath)
 		if err != nil {
 			return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
 		}
@@ -306,7 +382,7 @@ func (d *Dashboard) GameSee(ctx context.Context, request apigen.GameSeeRequestOb
 		if err := iofacade.MkdirAll(destinationDirPath, 0755); err != nil {
 			return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
 		}
-		input, err := iofacade.ReadFile(sourceFilePath)
+		input, err := fileops.ReadReplayFile(sourceFilePath)
 		if err != nil {
 			return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
 		}
@@ -334,6 +410,7 @@ func (d *Dashboard) Healthcheck(ctx context.Context, _ apigen.HealthcheckRequest
 		"total_replays": totalReplays,
 		"version":       buildinfo.Version,
 		"commit":        buildinfo.Commit,
+		"result_cache":  d.resultCache.stats(),
 	}, nil
 }
 
@@ -419,60 +496,68 @@ func (d *Dashboard) PlayersList(_ context.Context, request apigen.PlayersListReq
 	}, nil
 }
 
-func (d *Dashboard) PlayersApmHistogram(_ context.Context, _ apigen.PlayersApmHistogramRequestObject) (any, error) {
-	histogram, err := d.buildWorkflowPlayerApmHistogram("")
-	if err != nil {
-		return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
-	}
-	return histogram, nil
+func (d *Dashboard) PlayersApmHistogram(ctx context.Context, request apigen.PlayersApmHistogramRequestObject) (any, error) {
+	return d.cachedResult(ctx, "PlayersApmHistogram", request, func() (any, error) {
+		histogram, err := d.buildWorkflowPlayerApmHistogram("")
+		if err != nil {
+			return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
+		}
+		return histogram, nil
+	})
 }
 
-func (d *Dashboard) PlayersUnitCadence(_ context.Context, request apigen.PlayersUnitCadenceRequestObject) (any, error) {
-	filterMode, err := parseWorkflowUnitCadenceFilterMode(nullableStringValue(request.Params.Filter))
-	if err != nil {
-		return nil, dashboardservice.WithStatus(http.StatusBadRequest, err)
-	}
-	minGames := workflowUnitCadenceMinGames
-	if request.Params.MinGames != nil && *request.Params.MinGames > 0 {
-		minGames = *request.Params.MinGames
-	}
-	limit := workflowUnitCadenceDefaultLimit
-	if request.Params.Limit != nil {
-		if *request.Params.Limit < 0 {
-			return nil, dashboardservice.WithStatus(http.StatusBadRequest, errors.New("limit must be >= 0"))
+func (d *Dashboard) PlayersUnitCadence(ctx context.Context, request apigen.PlayersUnitCadenceRequestObject) (any, error) {
+	return d.cachedResult(ctx, "PlayersUnitCadence", request, func() (any, error) {
+		filterMode, err := parseWorkflowUnitCadenceFilterMode(nullableStringValue(request.Params.Filter))
+		if err != nil {
+			return nil, dashboardservice.WithStatus(http.StatusBadRequest, err)
 		}
-		limit = *request.Params.Limit
-	}
-	if limit > workflowUnitCadenceMaxLimit {
-		limit = workflowUnitCadenceMaxLimit
-	}
-	result, err := d.buildWorkflowPlayerUnitCadenceLeaderboard(filterMode, minGames, limit)
-	if err != nil {
-		return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
-	}
-	return result, nil
+		minGames := workflowUnitCadenceMinGames
+		if request.Params.MinGames != nil && *request.Params.MinGames > 0 {
+			minGames = *request.Params.MinGames
+		}
+		limit := workflowUnitCadenceDefaultLimit
+		if request.Params.Limit != nil {
+			if *request.Params.Limit < 0 {
+				return nil, dashboardservice.WithStatus(http.StatusBadRequest, errors.New("limit must be >= 0"))
+			}
+			limit = *request.Params.Limit
+		}
+		if limit > workflowUnitCadenceMaxLimit {
+			limit = workflowUnitCadenceMaxLimit
+		}
+		result, err := d.buildWorkflowPlayerUnitCadenceLeaderboard(filterMode, minGames, limit)
+		if err != nil {
+			return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
+		}
+		return result, nil
+	})
 }
 
-func (d *Dashboard) PlayersViewportMultitasking(_ context.Context, _ apigen.PlayersViewportMultitaskingRequestObject) (any, error) {
-	result, err := d.buildWorkflowPlayerViewportMultitaskingDistribution()
-	if err != nil {
-		return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
-	}
-	return result, nil
+func (d *Dashboard) PlayersViewportMultitasking(ctx context.Context, request apigen.PlayersViewportMultitaskingRequestObject) (any, error) {
+	return d.cachedResult(ctx, "PlayersViewportMultitasking", request, func() (any, error) {
+		result, err := d.buildWorkflowPlayerViewportMultitaskingDistribution()
+		if err != nil {
+			return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
+		}
+		return result, nil
+	})
 }
 
-func (d *Dashboard) PlayerDetail(_ context.Context, request apigen.PlayerDetailRequestObject) (any, error) {
-	if strings.TrimSpace(request.PlayerKey) == "" {
-		return nil, dashboardservice.WithStatus(http.StatusBadRequest, errors.New("player key missing"))
-	}
-	player, err := d.buildWorkflowPlayerOverview(normalizePlayerKey(request.PlayerKey))
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, dashboardservice.WithStatus(http.StatusNotFound, err)
+func (d *Dashboard) PlayerDetail(ctx context.Context, request apigen.PlayerDetailRequestObject) (any, error) {
+	return d.cachedResult(ctx, "PlayerDetail", request, func() (any, error) {
+		if strings.TrimSpace(request.PlayerKey) == "" {
+			return nil, dashboardservice.WithStatus(http.StatusBadRequest, errors.New("player key missing"))
 		}
-		return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
-	}
-	return player, nil
+		player, err := d.buildWorkflowPlayerOverview(normalizePlayerKey(request.PlayerKey))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, dashboardservice.WithStatus(http.StatusNotFound, err)
+			}
+			return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
+		}
+		return player, nil
+	})
 }
 
 func (d *Dashboard) PlayerChatSummary(_ context.Context, request apigen.PlayerChatSummaryRequestObject) (any, error) {
@@ -494,121 +579,189 @@ func (d *Dashboard) PlayerChatSummary(_ context.Context, request apigen.PlayerCh
 	}, nil
 }
 
-func (d *Dashboard) PlayerInsight(_ context.Context, request apigen.PlayerInsightRequestObject) (any, error) {
-	playerKey := normalizePlayerKey(request.PlayerKey)
-	if playerKey == "" {
-		return nil, dashboardservice.WithStatus(http.StatusBadRequest, errors.New("player key missing"))
-	}
-	insightType := workflowPlayerInsightType(nullableStringValue(request.Params.Type))
-	result, err := d.buildWorkflowPlayerAsyncInsight(playerKey, insightType)
-	if err != nil {
-		if errors.Is(err, errUnsupportedWorkflowPlayerInsightType) {
-			return nil, dashboardservice.WithStatus(http.StatusBadRequest, err)
+// ChatSearch finds in-game chat messages containing every word of q, within
+// the global replay filter. player and matchup take the same keys as the games
+// list; from and to are inclusive YYYY-MM-DD dates.
+func (d *Dashboard) ChatSearch(ctx context.Context, request apigen.ChatSearchRequestObject) (any, error) {
+	params := request.Params
+	opts := chatsearch.Options{}
+	if params.Q != nil {
+		opts.Text = *params.Q
+	}
+	if params.Player != nil {
+		opts.Players = parseCSVQueryValues(*params.Player, true)
+	}
+	if params.Matchup != nil {
+		opts.Matchups = parseCSVQueryValues(*params.Matchup, true)
+	}
+	for _, date := range []struct {
+		name  string
+		value *string
+		into  *string
+	}{{"from", params.From, &opts.From}, {"to", params.To, &opts.To}} {
+		if date.value == nil || strings.TrimSpace(*date.value) == "" {
+			continue
 		}
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, dashboardservice.WithStatus(http.StatusNotFound, err)
+		value := strings.TrimSpace(*date.value)
+		if _, err := time.Parse(time.DateOnly, value); err != nil {
+			return nil, dashboardservice.WithStatus(http.StatusBadRequest, fmt.Errorf("%s must be a YYYY-MM-DD date", date.name))
 		}
-		return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
+		*date.into = value
 	}
-	return result, nil
-}
-
-func (d *Dashboard) PlayerApmHistogram(_ context.Context, request apigen.PlayerApmHistogramRequestObject) (any, error) {
-	playerKey := normalizePlayerKey(request.PlayerKey)
-	if playerKey == "" {
-		return nil, dashboardservice.WithStatus(http.StatusBadRequest, errors.New("player key missing"))
+	if params.Limit != nil && *params.Limit > 0 {
+		opts.Limit = int(*params.Limit)
 	}
-	histogram, err := d.buildWorkflowPlayerApmHistogram(playerKey)
-	if err != nil {
-		return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
+	if params.Context != nil {
+		// An explicit context=0 asks for no surrounding messages.
+		opts.Context = int(*params.Context)
+		if opts.Context <= 0 {
+			opts.Context = -1
+		}
 	}
-	return histogram, nil
-}
 
-func (d *Dashboard) PlayerUnitCadence(_ context.Context, request apigen.PlayerUnitCadenceRequestObject) (any, error) {
-	playerKey := normalizePlayerKey(request.PlayerKey)
-	if playerKey == "" {
-		return nil, dashboardservice.WithStatus(http.StatusBadRequest, errors.New("player key missing"))
-	}
-	filterMode, err := parseWorkflowUnitCadenceFilterMode(nullableStringValue(request.Params.Filter))
+	hits, err := chatsearch.Search(ctx, d.dbStore.ReplayDB(), chatsearch.DialectSQLite, opts)
 	if err != nil {
-		return nil, dashboardservice.WithStatus(http.StatusBadRequest, err)
-	}
-	result, err := d.buildWorkflowPlayerUnitCadenceInsight(playerKey, filterMode)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, dashboardservice.WithStatus(http.StatusNotFound, err)
+		if errors.Is(err, chatsearch.ErrNoWords) {
+			return nil, dashboardservice.WithStatus(http.StatusBadRequest, errors.New("q must contain at least one word"))
 		}
 		return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
 	}
-	return result, nil
+	return map[string]any{
+		"query": opts.Text,
+		"count": len(hits),
+		"hits":  hits,
+	}, nil
 }
 
-func (d *Dashboard) PlayerOutliers(_ context.Context, request apigen.PlayerOutliersRequestObject) (any, error) {
-	playerKey := normalizePlayerKey(request.PlayerKey)
-	if playerKey == "" {
-		return nil, dashboardservice.WithStatus(http.StatusBadRequest, errors.New("player key missing"))
-	}
-	outliers, err := d.buildWorkflowPlayerOutliers(playerKey)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, dashboardservice.WithStatus(http.StatusNotFound, err)
+func (d *Dashboard) PlayerInsight(ctx context.Context, request apigen.PlayerInsightRequestObject) (any, error) {
+	return d.cachedResult(ctx, "PlayerInsight", request, func() (any, error) {
+		playerKey := normalizePlayerKey(request.PlayerKey)
+		if playerKey == "" {
+			return nil, dashboardservice.WithStatus(http.StatusBadRequest, errors.New("player key missing"))
 		}
-		return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
-	}
-	return outliers, nil
+		insightType := workflowPlayerInsightType(nullableStringValue(request.Params.Type))
+		result, err := d.buildWorkflowPlayerAsyncInsight(playerKey, insightType)
+		if err != nil {
+			if errors.Is(err, errUnsupportedWorkflowPlayerInsightType) {
+				return nil, dashboardservice.WithStatus(http.StatusBadRequest, err)
+			}
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, dashboardservice.WithStatus(http.StatusNotFound, err)
+			}
+			return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
+		}
+		return result, nil
+	})
 }
 
-func (d *Dashboard) PlayerSummaryPerMatchup(_ context.Context, request apigen.PlayerSummaryPerMatchupRequestObject) (any, error) {
-	playerKey := normalizePlayerKey(request.PlayerKey)
-	if playerKey == "" {
-		return nil, dashboardservice.WithStatus(http.StatusBadRequest, errors.New("player key missing"))
-	}
-	result, err := d.buildWorkflowPlayerSummaryPerMatchup(playerKey)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, dashboardservice.WithStatus(http.StatusNotFound, err)
+func (d *Dashboard) PlayerApmHistogram(ctx context.Context, request apigen.PlayerApmHistogramRequestObject) (any, error) {
+	return d.cachedResult(ctx, "PlayerApmHistogram", request, func() (any, error) {
+		playerKey := normalizePlayerKey(request.PlayerKey)
+		if playerKey == "" {
+			return nil, dashboardservice.WithStatus(http.StatusBadRequest, errors.New("player key missing"))
 		}
-		return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
-	}
-	return result, nil
+		histogram, err := d.buildWorkflowPlayerApmHistogram(playerKey)
+		if err != nil {
+			return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
+		}
+		return histogram, nil
+	})
 }
 
-func (d *Dashboard) PlayerSummarySpecial(_ context.Context, request apigen.PlayerSummarySpecialRequestObject) (any, error) {
-	playerKey := normalizePlayerKey(request.PlayerKey)
-	if playerKey == "" {
-		return nil, dashboardservice.WithStatus(http.StatusBadRequest, errors.New("player key missing"))
-	}
-	result, err := d.buildWorkflowPlayerSummarySpecial(playerKey)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, dashboardservice.WithStatus(http.StatusNotFound, err)
+func (d *Dashboard) PlayerUnitCadence(ctx context.Context, request apigen.PlayerUnitCadenceRequestObject) (any, error) {
+	return d.cachedResult(ctx, "PlayerUnitCadence", request, func() (any, error) {
+		playerKey := normalizePlayerKey(request.PlayerKey)
+		if playerKey == "" {
+			return nil, dashboardservice.WithStatus(http.StatusBadRequest, errors.New("player key missing"))
 		}
-		return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
-	}
-	return result, nil
+		filterMode, err := parseWorkflowUnitCadenceFilterMode(nullableStringValue(request.Params.Filter))
+		if err != nil {
+			return nil, dashboardservice.WithStatus(http.StatusBadRequest, err)
+		}
+		result, err := d.buildWorkflowPlayerUnitCadenceInsight(playerKey, filterMode)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, dashboardservice.WithStatus(http.StatusNotFound, err)
+			}
+			return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
+		}
+		return result, nil
+	})
 }
 
-func (d *Dashboard) PlayerSummaryOutliers(_ context.Context, request apigen.PlayerSummaryOutliersRequestObject) (any, error) {
-	playerKey := normalizePlayerKey(request.PlayerKey)
-	if playerKey == "" {
-		return nil, dashboardservice.WithStatus(http.StatusBadRequest, errors.New("player key missing"))
-	}
-	category := strings.TrimSpace(request.Params.Category)
-	if category == "" {
-		return nil, dashboardservice.WithStatus(http.StatusBadRequest, errors.New("category is required"))
-	}
-	result, err := d.buildWorkflowPlayerSummaryOutliersForCategory(playerKey, category)
-	if err != nil {
-		if errors.Is(err, errUnknownOutlierCategory) {
-			return nil, dashboardservice.WithStatus(http.StatusBadRequest, err)
+func (d *Dashboard) PlayerOutliers(ctx context.Context, request apigen.PlayerOutliersRequestObject) (any, error) {
+	return d.cachedResult(ctx, "PlayerOutliers", request, func() (any, error) {
+		playerKey := normalizePlayerKey(request.PlayerKey)
+		if playerKey == "" {
+			return nil, dashboardservice.WithStatus(http.StatusBadRequest, errors.New("player key missing"))
 		}
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, dashboardservice.WithStatus(http.StatusNotFound, err)
+		outliers, err := d.buildWorkflowPlayerOutliers(playerKey)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, dashboardservice.WithStatus(http.StatusNotFound, err)
+			}
+			return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
 		}
-		return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
-	}
-	return result, nil
+		return outliers, nil
+	})
+}
+
+func (d *Dashboard) PlayerSummaryPerMatchup(ctx context.Context, request apigen.PlayerSummaryPerMatchupRequestObject) (any, error) {
+	return d.cachedResult(ctx, "PlayerSummaryPerMatchup", request, func() (any, error) {
+		playerKey := normalizePlayerKey(request.PlayerKey)
+		if playerKey == "" {
+			return nil, dashboardservice.WithStatus(http.StatusBadRequest, errors.New("player key missing"))
+		}
+		result, err := d.buildWorkflowPlayerSummaryPerMatchup(playerKey)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, dashboardservice.WithStatus(http.StatusNotFound, err)
+			}
+			return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
+		}
+		return result, nil
+	})
+}
+
+func (d *Dashboard) PlayerSummarySpecial(ctx context.Context, request apigen.PlayerSummarySpecialRequestObject) (any, error) {
+	return d.cachedResult(ctx, "PlayerSummarySpecial", request, func() (any, error) {
+		playerKey := normalizePlayerKey(request.PlayerKey)
+		if playerKey == "" {
+			return nil, dashboardservice.WithStatus(http.StatusBadRequest, errors.New("player key missing"))
+		}
+		result, err := d.buildWorkflowPlayerSummarySpecial(playerKey)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, dashboardservice.WithStatus(http.StatusNotFound, err)
+			}
+			return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
+		}
+		return result, nil
+	})
+}
+
+func (d *Dashboard) PlayerSummaryOutliers(ctx context.Context, request apigen.PlayerSummaryOutliersRequestObject) (any, error) {
+	return d.cachedResult(ctx, "PlayerSummaryOutliers", request, func() (any, error) {
+		playerKey := normalizePlayerKey(request.PlayerKey)
+		if playerKey == "" {
+			return nil, dashboardservice.WithStatus(http.StatusBadRequest, errors.New("player key missing"))
+		}
+		category := strings.TrimSpace(request.Params.Category)
+		if category == "" {
+			return nil, dashboardservice.WithStatus(http.StatusBadRequest, errors.New("category is required"))
+		}
+		result, err := d.buildWorkflowPlayerSummaryOutliersForCategory(playerKey, category)
+		if err != nil {
+			if errors.Is(err, errUnknownOutlierCategory) {
+				return nil, dashboardservice.WithStatus(http.StatusBadRequest, err)
+			}
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, dashboardservice.WithStatus(http.StatusNotFound, err)
+			}
+			return nil, dashboardservice.WithStatus(http.StatusInternalServerError, err)
+		}
+		return result, nil
+	})
 }
 
 func (d *Dashboard) PlayerRecentGames(_ context.Context, request apigen.PlayerRecentGamesRequestObject) (any, error) {
@@ -0,0 +1,39 @@
+package dashboard
+
+import (
+	dashboarddb "github.com/marianogappa/screpdb/internal/dashboard/db"
+)
+
+// playerAggregateClassSQL returns the player aggregate class predicate (see
+// dashboarddb.BuildPlayerAggregateClassSQL) equivalent to the current global
+// replay filter, and false when the players pages must be computed live:
+// the aggregates aren't built (`screpdb aggregates`), or the replay-scoped
+// connection runs a filter other than the one the config compiles to.
+func (d *Dashboard) playerAggregateClassSQL() (string, bool, error) {
+	d.replayScopedMu.RLock()
+	scoped := d.replayScopedDB != nil
+	config := d.globalReplayFilter
+	d.replayScopedMu.RUnlock()
+	if !scoped || config.CompiledReplaysFilterSQL == nil {
+		return "", false, nil
+	}
+	compiled, err := compileGlobalReplayFilterSQL(config)
+	if err != nil || normalizeSQLWhitespace(compiled) != normalizeSQLWhitespace(*config.CompiledReplaysFilterSQL) {
+		return "", false, nil
+	}
+	normalized, err := normalizeGlobalReplayFilterConfigWithoutSQL(config)
+	if err != nil {
+		return "", false, nil
+	}
+
+	current, err := d.dbStore.PlayerAggregatesCurrent(d.ctx)
+	if err != nil || !current {
+		return "", false, err
+	}
+	return dashboarddb.BuildPlayerAggregateClassSQL(
+		normalized.ExcludeShortGames,
+		normalized.ExcludeComputers,
+		normalized.GameTypes,
+		normalized.MapKinds,
+	), true, nil
+}
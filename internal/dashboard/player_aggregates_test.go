@@ -0,0 +1,137 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/marianogappa/screpdb/internal/playeragg"
+)
+
+// playerPagesSnapshot is everything the players pages compute from the
+// player aggregates, as JSON with floats rounded so sums taken in a
+// different order compare.
+func playerPagesSnapshot(t *testing.T, dash *Dashboard, playerKey string) map[string]any {
+	t.Helper()
+	players, total, _, err := dash.listWorkflowPlayers(1000, 0, workflowPlayersListFilters{}, workflowPlayersListSort{Column: "player_key"})
+	if err != nil {
+		t.Fatalf("listWorkflowPlayers: %v", err)
+	}
+	histogram, err := dash.buildWorkflowPlayerApmHistogram(playerKey)
+	if err != nil {
+		t.Fatalf("buildWorkflowPlayerApmHistogram: %v", err)
+	}
+	strict, err := dash.buildWorkflowPlayerUnitCadenceLeaderboard(workflowUnitCadenceFilterStrict, 1, 0)
+	if err != nil {
+		t.Fatalf("buildWorkflowPlayerUnitCadenceLeaderboard(strict): %v", err)
+	}
+	broad, err := dash.buildWorkflowPlayerUnitCadenceLeaderboard(workflowUnitCadenceFilterBroad, 1, 0)
+	if err != nil {
+		t.Fatalf("buildWorkflowPlayerUnitCadenceLeaderboard(broad): %v", err)
+	}
+	viewport, err := dash.loadWorkflowViewportMultitaskingAggregates()
+	if err != nil {
+		t.Fatalf("loadWorkflowViewportMultitaskingAggregates: %v", err)
+	}
+	summary, err := dash.buildWorkflowPlayerSummaryPerMatchup(playerKey)
+	if err != nil {
+		t.Fatalf("buildWorkflowPlayerSummaryPerMatchup: %v", err)
+	}
+	viewportRates := map[string]float64{}
+	for _, aggregate := range viewport {
+		viewportRates[aggregate.PlayerKey] = aggregate.averageViewportSwitchRate
+	}
+
+	bs, err := json.Marshal(map[string]any{
+		"players":        players,
+		"total":          total,
+		"histogram":      histogram,
+		"strict":         strict,
+		"broad":          broad,
+		"viewport":       viewport,
+		"viewport_rates": viewportRates,
+		"summary":        summary,
+	})
+	if err != nil {
+		t.Fatalf("marshal snapshot: %v", err)
+	}
+	var snapshot map[string]any
+	if err := json.Unmarshal(bs, &snapshot); err != nil {
+		t.Fatalf("unmarshal snapshot: %v", err)
+	}
+	roundSnapshotFloats(snapshot)
+	return snapshot
+}
+
+func roundSnapshotFloats(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			v[key] = roundSnapshotFloats(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = roundSnapshotFloats(item)
+		}
+	case float64:
+		return math.Round(v*1e6) / 1e6
+	}
+	return value
+}
+
+func TestPlayerAggregatesMatchLiveQueries(t *testing.T) {
+	dash := newTestDashboard(t)
+
+	var playerKey string
+	if err := dash.db.QueryRowContext(dash.ctx, `
+		SELECT lower(trim(name)) FROM players
+		WHERE is_observer = 0 AND lower(trim(coalesce(type, ''))) = 'human'
+		GROUP BY lower(trim(name))
+		ORDER BY COUNT(*) DESC, lower(trim(name))
+		LIMIT 1`).Scan(&playerKey); err != nil {
+		t.Fatalf("pick player: %v", err)
+	}
+
+	filters := []globalReplayFilterConfig{
+		defaultGlobalReplayFilterConfig(),
+		{
+			GameTypes: []string{globalReplayFilterGameTypeOneOnOne, globalReplayFilterGameTypeTopVsBottom},
+			MapKinds:  []string{globalReplayFilterMapKindRegular},
+		},
+		{ExcludeShortGames: true, ExcludeComputers: true},
+	}
+	for _, filter := range filters {
+		if _, err := dash.updateGlobalReplayFilterConfig(dash.ctx, filter); err != nil {
+			t.Fatalf("updateGlobalReplayFilterConfig: %v", err)
+		}
+		if err := dash.refreshReplayScopedDB(); err != nil {
+			t.Fatalf("refreshReplayScopedDB: %v", err)
+		}
+		if _, materialized, err := dash.playerAggregateClassSQL(); err != nil || !materialized {
+			t.Fatalf("playerAggregateClassSQL = %v, %v; want the aggregates built by ingest", materialized, err)
+		}
+		fromAggregates := playerPagesSnapshot(t, dash, playerKey)
+
+		if _, err := dash.db.ExecContext(dash.ctx, "DELETE FROM player_aggregates_state"); err != nil {
+			t.Fatalf("unbuild aggregates: %v", err)
+		}
+		if _, materialized, _ := dash.playerAggregateClassSQL(); materialized {
+			t.Fatal("playerAggregateClassSQL still reads unbuilt aggregates")
+		}
+		live := playerPagesSnapshot(t, dash, playerKey)
+		if players, _ := live["players"].([]any); len(players) == 0 {
+			t.Fatalf("filter %+v: no players to compare", filter)
+		}
+		if _, err := dash.db.ExecContext(dash.ctx, "INSERT INTO player_aggregates_state (id, version) VALUES (1, ?)", playeragg.Version); err != nil {
+			t.Fatalf("rebuild aggregates: %v", err)
+		}
+
+		for key, want := range live {
+			gotJSON, _ := json.Marshal(fromAggregates[key])
+			wantJSON, _ := json.Marshal(want)
+			if string(gotJSON) != string(wantJSON) {
+				t.Errorf("filter %+v: %s from aggregates differs from live\n got: %s\nwant: %s", filter, key, gotJSON, wantJSON)
+			}
+		}
+	}
+}
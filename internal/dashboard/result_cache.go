@@ -0,0 +1,315 @@
+package dashboard
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/marianogappa/screpdb/internal/appdata"
+	"github.com/marianogappa/screpdb/internal/buildinfo"
+	"github.com/marianogappa/screpdb/internal/iofacade"
+)
+
+const (
+	// resultCacheMaxBytes bounds the JSON the result cache holds; the least
+	// recently used responses are dropped first.
+	resultCacheMaxBytes = 64 << 20
+
+	// resultCacheFlushDelay is how long a persisted cache waits after a
+	// change before writing itself out, so a burst of misses makes one write.
+	resultCacheFlushDelay = 5 * time.Second
+
+	// resultCacheDirName is the app-data folder persisted caches live in.
+	resultCacheDirName = "cache"
+)
+
+// resultCache keeps the JSON of heavy dashboard responses, keyed by endpoint,
+// request parameters and global replay filter, for one replay generation (see
+// replay/000011_replay_generation). Every write to replays moves the
+// generation, which empties the cache, so ingests and re-analyses, from this
+// process or any other, invalidate it with no code of their own. Writes
+// through the dashboard API (aliases, settings, the filter) empty it too, see
+// purgeResultCacheOnWrite. Safe for concurrent use.
+type resultCache struct {
+	mu         sync.Mutex
+	generation string
+	// writes counts purges, so that a response computed across a purge
+	// isn't stored afterwards.
+	writes   int64
+	entries  map[string]*list.Element
+	lru      *list.List // of *resultCacheEntry, most recently used first
+	bytes    int
+	maxBytes int
+
+	hits, misses, invalidations int64
+
+	// persistPath, when set, is the file the cache is written to.
+	persistPath string
+	flushTimer  *time.Timer
+}
+
+type resultCacheEntry struct {
+	Key     string          `json:"key"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// resultCacheFile is a persisted cache. Version ties it to the build that
+// computed the responses.
+type resultCacheFile struct {
+	Version    string             `json:"version"`
+	Generation string             `json:"generation"`
+	Entries    []resultCacheEntry `json:"entries"` // most recently used first
+}
+
+func newResultCache(maxBytes int) *resultCache {
+	return &resultCache{
+		entries:  map[string]*list.Element{},
+		lru:      list.New(),
+		maxBytes: maxBytes,
+	}
+}
+
+// begin returns the purge count to hand back to put, and drops every entry
+// if generation isn't the one the cache holds.
+func (c *resultCache) begin(generation string) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		if c.lru.Len() > 0 {
+			c.invalidations++
+		}
+		c.resetLocked()
+		c.generation = generation
+	}
+	return c.writes
+}
+
+func (c *resultCache) get(key string) (json.RawMessage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*resultCacheEntry).Payload, true
+}
+
+// put stores payload under key unless the cache was purged or moved to
+// another generation since begin returned writes.
+func (c *resultCache) put(generation string, writes int64, key string, payload json.RawMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation || writes != c.writes || len(payload) > c.maxBytes {
+		return
+	}
+	if elem, ok := c.entries[key]; ok {
+		c.removeLocked(elem)
+	}
+	c.entries[key] = c.lru.PushFront(&resultCacheEntry{Key: key, Payload: payload})
+	c.bytes += len(payload)
+	for c.bytes > c.maxBytes {
+		c.removeLocked(c.lru.Back())
+	}
+	c.scheduleFlushLocked()
+}
+
+// purge drops every entry, and the persisted file with them.
+func (c *resultCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writes++
+	if c.lru.Len() > 0 {
+		c.invalidations++
+	}
+	c.resetLocked()
+	if c.persistPath != "" {
+		if err := iofacade.Remove(c.persistPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Warning: failed to remove result cache %s: %v", c.persistPath, err)
+		}
+	}
+}
+
+func (c *resultCache) resetLocked() {
+	c.entries = map[string]*list.Element{}
+	c.lru.Init()
+	c.bytes = 0
+}
+
+func (c *resultCache) removeLocked(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*resultCacheEntry)
+	delete(c.entries, entry.Key)
+	c.bytes -= len(entry.Payload)
+}
+
+// stats returns the cache's size and hit counters for the health endpoint.
+func (c *resultCache) stats() map[string]any {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	hitRate := 0.0
+	if lookups := c.hits + c.misses; lookups > 0 {
+		hitRate = float64(c.hits) / float64(lookups)
+	}
+	return map[string]any{
+		"entries":       c.lru.Len(),
+		"bytes":         c.bytes,
+		"hits":          c.hits,
+		"misses":        c.misses,
+		"hit_rate":      hitRate,
+		"invalidations": c.invalidations,
+		"generation":    c.generation,
+		"persisted":     c.persistPath != "",
+	}
+}
+
+// persistTo loads the cache saved at path if this build saved it for
+// generation, then keeps path up to date.
+func (c *resultCache) persistTo(path, generation string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.persistPath = path
+	raw, err := iofacade.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read result cache: %w", err)
+	}
+	var file resultCacheFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return fmt.Errorf("failed to parse result cache: %w", err)
+	}
+	if file.Version != resultCacheVersion() || file.Generation != generation {
+		return nil
+	}
+	c.resetLocked()
+	c.generation = generation
+	for _, entry := range file.Entries {
+		if c.bytes+len(entry.Payload) > c.maxBytes {
+			break
+		}
+		c.entries[entry.Key] = c.lru.PushBack(&resultCacheEntry{Key: entry.Key, Payload: entry.Payload})
+		c.bytes += len(entry.Payload)
+	}
+	return nil
+}
+
+func (c *resultCache) scheduleFlushLocked() {
+	if c.persistPath == "" || c.flushTimer != nil {
+		return
+	}
+	c.flushTimer = time.AfterFunc(resultCacheFlushDelay, c.flush)
+}
+
+// flush writes the cache to persistPath, next to it first so the file is
+// never half-written.
+func (c *resultCache) flush() {
+	c.mu.Lock()
+	c.flushTimer = nil
+	path := c.persistPath
+	file := resultCacheFile{Version: resultCacheVersion(), Generation: c.generation}
+	for elem := c.lru.Front(); elem != nil; elem = elem.Next() {
+		file.Entries = append(file.Entries, *elem.Value.(*resultCacheEntry))
+	}
+	c.mu.Unlock()
+	if len(file.Entries) == 0 {
+		return
+	}
+
+	raw, err := json.Marshal(file)
+	if err == nil {
+		tmp := path + ".tmp"
+		if err = iofacade.WriteFile(tmp, raw, 0o644); err == nil {
+			err = iofacade.Rename(tmp, path)
+		}
+	}
+	if err != nil {
+		log.Printf("Warning: failed to write result cache %s: %v", path, err)
+	}
+}
+
+func resultCacheVersion() string {
+	return buildinfo.Version + "+" + buildinfo.Commit
+}
+
+// PersistResultCache keeps the dashboard's result cache in the app-data cache
+// folder, so a restarted dashboard serves the responses it already computed
+// for the same database, build and replay generation.
+func (d *Dashboard) PersistResultCache() error {
+	dir, err := appdata.Path(resultCacheDirName)
+	if err != nil {
+		return err
+	}
+	if err := iofacade.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create cache folder: %w", err)
+	}
+	generation, err := d.dbStore.ReplayGeneration(d.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read replay generation: %w", err)
+	}
+	// One file per database, named after its path.
+	absPath, err := filepath.Abs(d.sqlitePath)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256([]byte(absPath))
+	return d.resultCache.persistTo(filepath.Join(dir, "results-"+hex.EncodeToString(sum[:8])+".json"), generation)
+}
+
+// cachedResult returns the payload fn computes for endpoint and params,
+// from the result cache when it has it. Errors aren't cached, and the cache
+// is bypassed when the replay generation can't be read.
+func (d *Dashboard) cachedResult(ctx context.Context, endpoint string, params any, fn func() (any, error)) (any, error) {
+	generation, err := d.dbStore.ReplayGeneration(ctx)
+	if err != nil {
+		return fn()
+	}
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return fn()
+	}
+	key := endpoint + "\x1f" + string(rawParams) + "\x1f" + nullableStringValue(d.currentGlobalReplayFilterSQL())
+
+	writes := d.resultCache.begin(generation)
+	if payload, ok := d.resultCache.get(key); ok {
+		return payload, nil
+	}
+	payload, err := fn()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	d.resultCache.put(generation, writes, key, raw)
+	return json.RawMessage(raw), nil
+}
+
+// purgeResultCacheOnWrite empties the result cache around every API request
+// that may write, before and after it runs, so no response computed from
+// the data it changes outlives it.
+func (d *Dashboard) purgeResultCacheOnWrite(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		d.resultCache.purge()
+		defer d.resultCache.purge()
+		next.ServeHTTP(w, r)
+	})
+}
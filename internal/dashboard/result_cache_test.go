@@ -0,0 +1,162 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/marianogappa/screpdb/internal/iofacade"
+)
+
+func TestResultCache_NewGenerationEmptiesIt(t *testing.T) {
+	c := newResultCache(1 << 10)
+	writes := c.begin("a:1")
+	c.put("a:1", writes, "k", json.RawMessage(`{"v":1}`))
+	if got, ok := c.get("k"); !ok || string(got) != `{"v":1}` {
+		t.Fatalf("get = %s, %v; want the stored payload", got, ok)
+	}
+
+	writes = c.begin("a:2")
+	if _, ok := c.get("k"); ok {
+		t.Fatal("entry survived a generation change")
+	}
+	// A response computed for the old generation mustn't land in the new one.
+	c.put("a:1", writes, "k", json.RawMessage(`{"v":1}`))
+	if _, ok := c.get("k"); ok {
+		t.Fatal("stored a response for a stale generation")
+	}
+
+	stats := c.stats()
+	if stats["hits"] != int64(1) || stats["misses"] != int64(2) || stats["invalidations"] != int64(1) {
+		t.Fatalf("stats = %v, want 1 hit, 2 misses, 1 invalidation", stats)
+	}
+}
+
+func TestResultCache_PurgeDropsInFlightResponses(t *testing.T) {
+	c := newResultCache(1 << 10)
+	writes := c.begin("a:1")
+	c.purge()
+	c.put("a:1", writes, "k", json.RawMessage(`1`))
+	if _, ok := c.get("k"); ok {
+		t.Fatal("stored a response computed across a purge")
+	}
+}
+
+func TestResultCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newResultCache(10)
+	writes := c.begin("a:1")
+	c.put("a:1", writes, "a", json.RawMessage(`"aaa"`))
+	c.put("a:1", writes, "b", json.RawMessage(`"bbb"`))
+	c.get("a")
+	c.put("a:1", writes, "c", json.RawMessage(`"ccc"`))
+	if _, ok := c.get("b"); ok {
+		t.Fatal("kept b, the least recently used entry")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.get(key); !ok {
+			t.Fatalf("evicted %s", key)
+		}
+	}
+	c.put("a:1", writes, "big", json.RawMessage(`"more than ten bytes"`))
+	if _, ok := c.get("big"); ok || c.stats()["bytes"] != 10 {
+		t.Fatalf("stored a payload over the budget; stats = %v", c.stats())
+	}
+}
+
+func TestResultCache_PersistRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	if err := iofacade.AllowDir(dir); err != nil {
+		t.Fatalf("AllowDir: %v", err)
+	}
+	path := filepath.Join(dir, "results.json")
+
+	c := newResultCache(1 << 10)
+	if err := c.persistTo(path, "a:1"); err != nil {
+		t.Fatalf("persistTo: %v", err)
+	}
+	writes := c.begin("a:1")
+	c.put("a:1", writes, "k", json.RawMessage(`{"v":1}`))
+	c.flush()
+
+	restored := newResultCache(1 << 10)
+	if err := restored.persistTo(path, "a:1"); err != nil {
+		t.Fatalf("persistTo: %v", err)
+	}
+	if got, ok := restored.get("k"); !ok || string(got) != `{"v":1}` {
+		t.Fatalf("get = %s, %v; want the persisted payload", got, ok)
+	}
+
+	stale := newResultCache(1 << 10)
+	if err := stale.persistTo(path, "a:2"); err != nil {
+		t.Fatalf("persistTo: %v", err)
+	}
+	if _, ok := stale.get("k"); ok {
+		t.Fatal("loaded a cache persisted for another generation")
+	}
+}
+
+func TestDashboardAPI_ResultCacheHitsAndInvalidation(t *testing.T) {
+	dash := newTestDashboard(t)
+	router := dash.setupRouter()
+
+	cacheStats := func() map[string]any {
+		t.Helper()
+		rec := performDashboardRequest(router, http.MethodGet, "/api/health", nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("health status %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp struct {
+			ResultCache map[string]any `json:"result_cache"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode health: %v", err)
+		}
+		return resp.ResultCache
+	}
+	histogram := func() string {
+		t.Helper()
+		rec := performDashboardRequest(router, http.MethodGet, "/api/players/insights/apm-histogram", nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("apm histogram status %d: %s", rec.Code, rec.Body.String())
+		}
+		return rec.Body.String()
+	}
+
+	first := histogram()
+	if second := histogram(); second != first {
+		t.Fatalf("cached response differs:\n%s\nvs\n%s", second, first)
+	}
+	stats := cacheStats()
+	if stats["hits"] != float64(1) || stats["entries"] != float64(1) {
+		t.Fatalf("result_cache = %v, want 1 hit and 1 entry", stats)
+	}
+
+	// Refreshing file stamps changes nothing shown, so the cache survives it.
+	if _, err := dash.db.ExecContext(context.Background(), `UPDATE replays SET file_size = COALESCE(file_size, 0) + 1, file_mod_time = COALESCE(file_mod_time, 0) + 1`); err != nil {
+		t.Fatalf("refresh file stamps: %v", err)
+	}
+	histogram()
+	if stats := cacheStats(); stats["hits"] != float64(2) || stats["invalidations"] != float64(0) {
+		t.Fatalf("result_cache = %v, want a file stamp refresh to keep it", stats)
+	}
+
+	// Any other write to replays moves the generation.
+	if _, err := dash.db.ExecContext(context.Background(), `UPDATE replays SET analyzer_algorithm_version = analyzer_algorithm_version`); err != nil {
+		t.Fatalf("touch replays: %v", err)
+	}
+	histogram()
+	if stats := cacheStats(); stats["hits"] != float64(2) || stats["invalidations"] != float64(1) {
+		t.Fatalf("result_cache = %v, want the write to invalidate it", stats)
+	}
+
+	// So does any write through the API.
+	rec := performDashboardRequest(router, http.MethodPut, "/api/custom/aliases/entry", []byte(`{"canonical_alias":"CacheAlias","battle_tag":"CacheTag","source":"manual"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("upsert alias status %d: %s", rec.Code, rec.Body.String())
+	}
+	if stats := cacheStats(); stats["entries"] != float64(0) {
+		t.Fatalf("result_cache = %v, want an API write to empty it", stats)
+	}
+}
@@ -2,13 +2,16 @@ package dashboard
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"path/filepath"
 	"strings"
 
 	"github.com/marianogappa/screpdb/internal/crashreport"
 	"github.com/marianogappa/screpdb/internal/ingest"
 	"github.com/marianogappa/screpdb/internal/sampledata"
+	"github.com/marianogappa/screpdb/internal/storage"
 )
 
 const sampleSetDirName = "sample_replays"
@@ -66,6 +69,9 @@ func (d *Dashboard) prepareSampleSet(ctx context.Context) error {
 // Call only after the dashboard is fully constructed (an ingest opens its own
 // DB connection, which races DB setup if done during New).
 func (d *Dashboard) loadSampleSet(ctx context.Context) error {
+	if d.databaseURL != "" {
+		return errSampleSetWithDatabaseURL
+	}
 	if err := d.prepareSampleSet(ctx); err != nil {
 		return err
 	}
@@ -82,15 +88,31 @@ func (d *Dashboard) StartPendingSampleIngest() {
 		return
 	}
 	d.pendingSampleIngest = false
+	if d.databaseURL != "" {
+		d.sampleSetAutoLoaded = false
+		log.Printf("Not ingesting the sample set: %v", errSampleSetWithDatabaseURL)
+		return
+	}
 	d.startIngestAsync(ingest.Config{InputDir: d.sampleSetDir(), Clean: true})
 }
 
+// errSampleSetWithDatabaseURL refuses the sample set when the dashboard
+// mirrors a shared PostgreSQL corpus: its ingest would wipe the team's data.
+var errSampleSetWithDatabaseURL = errors.New("the sample set is not available while the dashboard uses a PostgreSQL database")
+
 // startIngestAsync runs ingest.Run in the background using the dashboard's
 // ingest session machinery. It returns false if an ingest is already running.
+// With a database URL set, the ingest writes to PostgreSQL, never wipes it,
+// and is mirrored back into the SQLite database once it succeeds.
 func (d *Dashboard) startIngestAsync(cfg ingest.Config) bool {
 	if strings.TrimSpace(cfg.SQLitePath) == "" {
 		cfg.SQLitePath = d.sqlitePath
 	}
+	if d.databaseURL != "" {
+		cfg.DatabaseURL = d.databaseURL
+		cfg.Clean = false
+		cfg.CleanDashboard = false
+	}
 	if cfg.Logger == nil {
 		cfg.Logger = d.newIngestLogger()
 	}
@@ -104,6 +126,9 @@ func (d *Dashboard) startIngestAsync(cfg ingest.Config) bool {
 		// guard's cleanup on panic, never both.
 		defer crashreport.GuardNonFatal(func() { d.finishIngest(errIngestPanicked) })
 		runErr := ingest.Run(d.ctx, cfg)
+		if runErr == nil && cfg.DatabaseURL != "" {
+			runErr = d.refreshMirror(cfg.Logger)
+		}
 		if runErr != nil {
 			cfg.Logger.Errorf("Ingestion failed: %v", runErr)
 		}
@@ -111,3 +136,14 @@ func (d *Dashboard) startIngestAsync(cfg ingest.Config) bool {
 	}()
 	return true
 }
+
+// refreshMirror brings the SQLite database up to date with the PostgreSQL
+// corpus after an ingest wrote to it.
+func (d *Dashboard) refreshMirror(logger *ingest.Logger) error {
+	stats, err := storage.RefreshMirror(d.ctx, d.sqlitePath, d.databaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to refresh the local mirror: %w", err)
+	}
+	logger.Infof("Mirrored %d new replays from PostgreSQL (%d removed, %d regrouped)", stats.Replays, stats.RemovedReplays, stats.Regrouped)
+	return nil
+}
@@ -8,14 +8,19 @@ import (
 
 // DashboardService is generated from apigen.StrictServerInterface.
 type DashboardService interface {
+	ChatSearch(ctx context.Context, request apigen.ChatSearchRequestObject) (HandlerResult, error)
 	ListAliases(ctx context.Context, request apigen.ListAliasesRequestObject) (HandlerResult, error)
 	ImportAliases(ctx context.Context, request apigen.ImportAliasesRequestObject) (HandlerResult, error)
 	UpsertAliasEntry(ctx context.Context, request apigen.UpsertAliasEntryRequestObject) (HandlerResult, error)
 	DeleteAliasEntry(ctx context.Context, request apigen.DeleteAliasEntryRequestObject) (HandlerResult, error)
+	ListBackups(ctx context.Context, request apigen.ListBackupsRequestObject) (HandlerResult, error)
+	CreateBackup(ctx context.Context, request apigen.CreateBackupRequestObject) (HandlerResult, error)
 	GetGlobalReplayFilterConfig(ctx context.Context, request apigen.GetGlobalReplayFilterConfigRequestObject) (HandlerResult, error)
 	UpdateGlobalReplayFilterConfig(ctx context.Context, request apigen.UpdateGlobalReplayFilterConfigRequestObject) (HandlerResult, error)
 	GetGlobalReplayFilterOptions(ctx context.Context, request apigen.GetGlobalReplayFilterOptionsRequestObject) (HandlerResult, error)
 	Ingest(ctx context.Context, request apigen.IngestRequestObject) (HandlerResult, error)
+	ListIngestFailures(ctx context.Context, request apigen.ListIngestFailuresRequestObject) (HandlerResult, error)
+	RetryIngestFailures(ctx context.Context, request apigen.RetryIngestFailuresRequestObject) (HandlerResult, error)
 	IngestLogs(ctx context.Context, request apigen.IngestLogsRequestObject) (HandlerResult, error)
 	GetIngestSettings(ctx context.Context, request apigen.GetIngestSettingsRequestObject) (HandlerResult, error)
 	UpdateIngestSettings(ctx context.Context, request apigen.UpdateIngestSettingsRequestObject) (HandlerResult, error)
@@ -49,8 +49,13 @@ func TestSQLCSchemaMatchesRuntimeSchemaForDeclaredColumns(t *testing.T) {
 	tables := []string{
 		"settings",
 		"replays",
+		"replay_generation",
 		"players",
 		"player_aliases",
+		"player_economy_samples",
+		"production_busy_spans",
+		"larva_samples",
+		"hotkey_usage",
 		"replay_events",
 		"commands",
 		"commands_low_value",
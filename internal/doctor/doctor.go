@@ -0,0 +1,323 @@
+// Package doctor audits a screpdb database for the `screpdb doctor`
+// subcommand: replay files that moved or changed since ingest, rows orphaned
+// from their replay, analysis that is stale or missing, migration state,
+// enum values that fell back to UNKNOWN, and SQLite's own integrity checks.
+// With Fix set it repairs what can be repaired without losing information.
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/marianogappa/screpdb/internal/backup"
+	"github.com/marianogappa/screpdb/internal/fileops"
+	"github.com/marianogappa/screpdb/internal/iofacade"
+	"github.com/marianogappa/screpdb/internal/migrations"
+	"github.com/marianogappa/screpdb/internal/patterns/core"
+	"github.com/marianogappa/screpdb/internal/storage"
+)
+
+// Config controls a doctor run.
+type Config struct {
+	SQLitePath string
+	// InputDir is searched (recursively) for replays whose stored file_path no
+	// longer holds them. Empty disables the search.
+	InputDir string
+	// Fix repairs what is safely repairable: applies pending migrations,
+	// re-points moved files, prunes orphaned rows and re-queues replays whose
+	// analysis is missing.
+	Fix bool
+}
+
+// FileProblem is a replay whose stored file_path no longer holds the file it
+// was ingested from.
+type FileProblem struct {
+	ReplayID int64
+	Path     string
+	Missing  bool   // the path does not exist (otherwise its checksum changed)
+	FoundAt  string // where the original file was found by checksum, if anywhere
+	Fixed    bool   // file_path was re-pointed at FoundAt
+}
+
+// Report is the outcome of a doctor run. Counts describe the database as found;
+// the Fixed/Pruned/Requeued fields say what Fix changed.
+type Report struct {
+	Replays int
+
+	Files         []FileProblem
+	SearchSkipped string // why the input dir was not searched, if it was not
+
+	Orphans       []storage.OrphanCount
+	OrphansPruned int64
+
+	CurrentVersion  int
+	Versions        []storage.VersionCount
+	MissingAnalysis []int64
+	Requeued        bool
+
+	Migrations        []migrations.SetStatus
+	MigrationsApplied bool // pending migrations were applied by Fix
+	UnknownEnums      []storage.EnumFallbackCount
+
+	Integrity   []string
+	ForeignKeys []storage.ForeignKeyViolation
+}
+
+// StaleReplays counts replays analyzed by an older algorithm version.
+func (r *Report) StaleReplays() int64 {
+	var n int64
+	for _, v := range r.Versions {
+		if v.Version < r.CurrentVersion {
+			n += v.Replays
+		}
+	}
+	return n
+}
+
+// Problems counts the findings still unresolved after the run. Stale analysis,
+// pending migrations and UNKNOWN enum fallbacks are informational: the first
+// has its own command (`screpdb reanalyze`), the second is applied by the next
+// ingest or dashboard start, the last reflects what the replay contained.
+func (r *Report) Problems() int {
+	n := 0
+	for _, f := range r.Files {
+		if !f.Fixed {
+			n++
+		}
+	}
+	if r.OrphansPruned == 0 {
+		for _, o := range r.Orphans {
+			if o.Rows > 0 {
+				n++
+			}
+		}
+	}
+	if !r.Requeued {
+		n += len(r.MissingAnalysis)
+	}
+	for _, m := range r.Migrations {
+		n += len(m.Unknown)
+	}
+	if len(r.Integrity) != 1 || r.Integrity[0] != "ok" {
+		n++
+	}
+	return n + len(r.ForeignKeys)
+}
+
+// Run audits the database at cfg.SQLitePath and, with cfg.Fix, repairs it.
+// The database must exist: doctor never creates one.
+func Run(ctx context.Context, cfg Config) (*Report, error) {
+	if err := iofacade.AllowDir(filepath.Dir(cfg.SQLitePath)); err != nil {
+		return nil, fmt.Errorf("failed to register database folder: %w", err)
+	}
+	if _, err := iofacade.Stat(cfg.SQLitePath); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("database %s does not exist", cfg.SQLitePath)
+		}
+		return nil, fmt.Errorf("failed to stat database: %w", err)
+	}
+
+	// Migration state is read before opening storage so it reflects the file
+	// as found. Pending migrations are only applied under Fix, and never on
+	// top of migrations this build does not know about.
+	report := &Report{CurrentVersion: core.AlgorithmVersion}
+	var err error
+	if report.Migrations, err = migrations.Status(cfg.SQLitePath); err != nil {
+		return nil, err
+	}
+	if cfg.Fix && anyPending(report.Migrations) && !anyUnknown(report.Migrations) {
+		if _, _, err := backup.BeforePendingMigrations(ctx, cfg.SQLitePath, migrations.MigrationSetReplay, migrations.MigrationSetDashboard, migrations.MigrationSetSettings); err != nil {
+			return nil, err
+		}
+		if err := migrations.RunMigrations(cfg.SQLitePath); err != nil {
+			return nil, err
+		}
+		report.MigrationsApplied = true
+	}
+
+	store, err := storage.NewSQLiteStorage(cfg.SQLitePath)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	refs, err := store.ListAllReplayRefs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report.Replays = len(refs)
+	if err := checkFiles(ctx, store, cfg, refs, report); err != nil {
+		return nil, err
+	}
+
+	if report.Orphans, err = store.CountOrphans(ctx); err != nil {
+		return nil, err
+	}
+	if cfg.Fix && anyOrphans(report.Orphans) {
+		if report.OrphansPruned, err = store.PruneOrphans(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if report.Versions, err = store.AlgorithmVersionCounts(ctx); err != nil {
+		return nil, err
+	}
+	if report.MissingAnalysis, err = store.ReplaysMissingAnalysis(ctx); err != nil {
+		return nil, err
+	}
+	if cfg.Fix && len(report.MissingAnalysis) > 0 {
+		if err := store.RequeueReplayAnalysis(ctx, report.MissingAnalysis); err != nil {
+			return nil, err
+		}
+		report.Requeued = true
+	}
+
+	if report.UnknownEnums, err = store.UnknownEnumCounts(ctx); err != nil {
+		return nil, err
+	}
+
+	// SQLite's own checks run last so they describe the database after fixes.
+	if report.Integrity, err = store.IntegrityCheck(ctx); err != nil {
+		return nil, err
+	}
+	if report.ForeignKeys, err = store.ForeignKeyCheck(ctx); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func anyPending(sets []migrations.SetStatus) bool {
+	for _, m := range sets {
+		if len(m.Pending) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func anyUnknown(sets []migrations.SetStatus) bool {
+	for _, m := range sets {
+		if len(m.Unknown) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func anyOrphans(counts []storage.OrphanCount) bool {
+	for _, c := range counts {
+		if c.Rows > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// checkFiles verifies every replay's file against its stored checksum, then
+// looks for the missing or changed ones under the input dir by checksum.
+func checkFiles(ctx context.Context, store *storage.SQLiteStorage, cfg Config, refs []storage.ReplayRef, report *Report) error {
+	present := make([]fileops.FileInfo, 0, len(refs))
+	refByPath := make(map[string]storage.ReplayRef, len(refs))
+	for _, ref := range refs {
+		refByPath[ref.FilePath] = ref
+		// Replays may have been ingested from any folder, not only the
+		// current input dir, so register each one as a read root.
+		if err := iofacade.AllowDir(fileops.ReplayDir(ref.FilePath)); err != nil {
+			return err
+		}
+		info, err := fileops.StatReplayFile(ref.FilePath)
+		if err != nil || info.IsDir() {
+			report.Files = append(report.Files, FileProblem{ReplayID: ref.ID, Path: ref.FilePath, Missing: true})
+			continue
+		}
+		present = append(present, fileops.FileInfo{Path: ref.FilePath, Name: info.Name(), Size: info.Size(), ModTime: info.ModTime()})
+	}
+	hashed, err := fileops.HashFiles(ctx, present)
+	if err != nil {
+		return err
+	}
+	verified := make(map[string]bool, len(hashed))
+	for _, f := range hashed {
+		ref := refByPath[f.Path]
+		if f.Checksum == ref.FileChecksum {
+			verified[f.Path] = true
+			continue
+		}
+		report.Files = append(report.Files, FileProblem{ReplayID: ref.ID, Path: ref.FilePath})
+	}
+	if len(report.Files) == 0 {
+		return nil
+	}
+
+	found, skipped, err := findByChecksum(ctx, cfg.InputDir, verified)
+	if err != nil {
+		return err
+	}
+	report.SearchSkipped = skipped
+
+	checksumByID := make(map[int64]string, len(refs))
+	for _, ref := range refs {
+		checksumByID[ref.ID] = ref.FileChecksum
+	}
+	for i := range report.Files {
+		p := &report.Files[i]
+		path, ok := found[checksumByID[p.ReplayID]]
+		if !ok {
+			continue
+		}
+		// file_path is UNIQUE: a path another replay row still claims cannot
+		// be taken over, even if that row's own file is gone.
+		if other, taken := refByPath[path]; taken && other.ID != p.ReplayID {
+			continue
+		}
+		p.FoundAt = path
+		if cfg.Fix {
+			if err := store.UpdateReplayFilePath(ctx, p.ReplayID, path); err != nil {
+				return err
+			}
+			p.Fixed = true
+		}
+	}
+	return nil
+}
+
+// findByChecksum hashes every replay under inputDir except the already
+// verified ones and indexes them by checksum. It returns a reason instead of
+// an error when the folder cannot be searched, since the rest of the audit is
+// still useful.
+func findByChecksum(ctx context.Context, inputDir string, verified map[string]bool) (map[string]string, string, error) {
+	if strings.TrimSpace(inputDir) == "" {
+		return nil, "no input dir given", nil
+	}
+	if err := iofacade.AllowDir(inputDir); err != nil {
+		return nil, "", err
+	}
+	if err := fileops.ValidateReplayDir(inputDir); err != nil {
+		return nil, err.Error(), nil
+	}
+	files, err := fileops.WalkReplayFiles(inputDir)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to walk %s: %w", inputDir, err)
+	}
+	candidates := files[:0]
+	for _, f := range files {
+		if !verified[f.Path] {
+			candidates = append(candidates, f)
+		}
+	}
+	hashed, err := fileops.HashFiles(ctx, candidates)
+	if err != nil {
+		return nil, "", err
+	}
+	byChecksum := make(map[string]string, len(hashed))
+	for _, f := range hashed {
+		if _, dup := byChecksum[f.Checksum]; !dup {
+			byChecksum[f.Checksum] = f.Path
+		}
+	}
+	return byChecksum, "", nil
+}
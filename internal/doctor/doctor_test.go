@@ -0,0 +1,217 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/marianogappa/screpdb/internal/ingest"
+	"github.com/marianogappa/screpdb/internal/storage"
+)
+
+var testReplays = []string{
+	"bo_bbs_tvp_standordie.rep",
+	"bo_8pool_zvt_loveaddio.rep",
+}
+
+// ingestTestReplays ingests testReplays from a fresh folder and returns the
+// folder and database paths.
+func ingestTestReplays(t *testing.T) (string, string) {
+	t.Helper()
+	_, thisFile, _, _ := runtime.Caller(0)
+	src := filepath.Join(filepath.Dir(thisFile), "..", "patterns", "markers", "testdata", "replays")
+	inputDir := t.TempDir()
+	for _, name := range testReplays {
+		data, err := os.ReadFile(filepath.Join(src, name))
+		if err != nil {
+			t.Fatalf("read replay: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(inputDir, name), data, 0o644); err != nil {
+			t.Fatalf("write replay: %v", err)
+		}
+	}
+	dbPath := filepath.Join(t.TempDir(), "x.db")
+	err := ingest.Run(context.Background(), ingest.Config{
+		InputDir:   inputDir,
+		SQLitePath: dbPath,
+		Logger:     ingest.NewLogger(&bytes.Buffer{}, false, nil),
+	})
+	if err != nil {
+		t.Fatalf("ingest: %v", err)
+	}
+	return inputDir, dbPath
+}
+
+// rawExec writes with foreign keys off, the way an older build or an external
+// tool could leave the database.
+func rawExec(t *testing.T, dbPath string, queries ...string) {
+	t.Helper()
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+	for _, q := range queries {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatalf("exec %q: %v", q, err)
+		}
+	}
+}
+
+func TestRun_HealthyDatabase(t *testing.T) {
+	inputDir, dbPath := ingestTestReplays(t)
+	r, err := Run(context.Background(), Config{SQLitePath: dbPath, InputDir: inputDir})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if r.Replays != len(testReplays) || len(r.Files) != 0 {
+		t.Fatalf("replays=%d files=%+v", r.Replays, r.Files)
+	}
+	if n := r.Problems(); n != 0 {
+		t.Fatalf("fresh ingest should have no problems, got %d: %+v", n, r)
+	}
+	if r.StaleReplays() != 0 || len(r.Versions) != 1 || r.Versions[0].Version != r.CurrentVersion {
+		t.Fatalf("versions = %+v, want everything at %d", r.Versions, r.CurrentVersion)
+	}
+}
+
+func TestRun_FixRepairsDamage(t *testing.T) {
+	// --fix applies the settings migrations an ingest-only database lacks,
+	// which takes a pre-migration backup under the app-data dir.
+	t.Setenv("SCREPDB_APPDATA_DIR", t.TempDir())
+	inputDir, dbPath := ingestTestReplays(t)
+
+	// Replay 1's file moves to another folder; replay 2 loses its detections;
+	// a player row is left behind by a replay that no longer exists.
+	movedDir := t.TempDir()
+	oldPath := queryStrings(t, dbPath, "SELECT file_path FROM replays WHERE id = 1")[0]
+	if err := os.Rename(oldPath, filepath.Join(movedDir, filepath.Base(oldPath))); err != nil {
+		t.Fatalf("move replay: %v", err)
+	}
+	rawExec(t, dbPath,
+		"DELETE FROM replay_events WHERE replay_id = 2",
+		"INSERT INTO players (replay_id, slot_id, name, race, type, color, team, is_observer, apm, eapm, is_winner) VALUES (999, 0, 'ghost', 'Zerg', 'Human', 'Red', 1, 0, 0, 0, 0)",
+	)
+
+	r, err := Run(context.Background(), Config{SQLitePath: dbPath, InputDir: movedDir})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(r.Files) != 1 || !r.Files[0].Missing || r.Files[0].FoundAt == "" || r.Files[0].Fixed {
+		t.Fatalf("files = %+v, want replay 1 missing and found (not fixed)", r.Files)
+	}
+	if r.Orphans[0].Rows != 1 || len(r.ForeignKeys) != 1 {
+		t.Fatalf("orphans=%+v fk=%+v, want the ghost player", r.Orphans, r.ForeignKeys)
+	}
+	if len(r.MissingAnalysis) != 1 || r.MissingAnalysis[0] != 2 {
+		t.Fatalf("missing analysis = %v, want [2]", r.MissingAnalysis)
+	}
+	if n := r.Problems(); n != 4 {
+		t.Fatalf("problems = %d, want 4", n)
+	}
+
+	r, err = Run(context.Background(), Config{SQLitePath: dbPath, InputDir: movedDir, Fix: true})
+	if err != nil {
+		t.Fatalf("Run --fix: %v", err)
+	}
+	if !r.Files[0].Fixed || r.OrphansPruned != 1 || !r.Requeued {
+		t.Fatalf("fix incomplete: files=%+v pruned=%d requeued=%v", r.Files, r.OrphansPruned, r.Requeued)
+	}
+	if n := r.Problems(); n != 0 {
+		t.Fatalf("problems after fix = %d: %+v", n, r)
+	}
+
+	r, err = Run(context.Background(), Config{SQLitePath: dbPath, InputDir: inputDir})
+	if err != nil {
+		t.Fatalf("Run after fix: %v", err)
+	}
+	if n := r.Problems(); n != 0 || r.StaleReplays() != 1 {
+		t.Fatalf("after fix: problems=%d stale=%d, want 0 and the re-queued replay", n, r.StaleReplays())
+	}
+	if got := queryStrings(t, dbPath, "SELECT file_path FROM replays WHERE id = 1")[0]; filepath.Dir(got) != movedDir {
+		t.Fatalf("replay 1 file_path = %s, want it under %s", got, movedDir)
+	}
+}
+
+func TestRun_FixPrunesOrphansOfEveryReplayTable(t *testing.T) {
+	t.Setenv("SCREPDB_APPDATA_DIR", t.TempDir())
+	inputDir, dbPath := ingestTestReplays(t)
+
+	// A compact blob and a row of a table no check lists outlive their
+	// replay, and the chat index holds an entry for a command that's gone.
+	rawExec(t, dbPath,
+		"INSERT INTO command_blobs (replay_id, format_version, row_count, payload) VALUES (999, 1, 0, x'')",
+		"CREATE TABLE later_samples (replay_id INTEGER NOT NULL, second INTEGER NOT NULL)",
+		"INSERT INTO later_samples (replay_id, second) VALUES (1, 0), (999, 0)",
+		"INSERT INTO chat_messages_fts (rowid, chat_message) VALUES (999999, 'gg')",
+	)
+
+	r, err := Run(context.Background(), Config{SQLitePath: dbPath, InputDir: inputDir})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	found := map[string]int64{}
+	for _, o := range r.Orphans {
+		if o.Rows > 0 {
+			found[o.Check] = o.Rows
+		}
+	}
+	want := map[string]int64{
+		"command_blobs without replay":                1,
+		"later_samples without replay":                1,
+		"chat_messages_fts out of step with commands": 1,
+	}
+	if !maps.Equal(found, want) {
+		t.Fatalf("orphans = %v, want %v", found, want)
+	}
+
+	r, err = Run(context.Background(), Config{SQLitePath: dbPath, InputDir: inputDir, Fix: true})
+	if err != nil {
+		t.Fatalf("Run --fix: %v", err)
+	}
+	if r.OrphansPruned != 3 {
+		t.Fatalf("pruned = %d, want 3", r.OrphansPruned)
+	}
+	r, err = Run(context.Background(), Config{SQLitePath: dbPath, InputDir: inputDir})
+	if err != nil {
+		t.Fatalf("Run after fix: %v", err)
+	}
+	if n := r.Problems(); n != 0 {
+		t.Fatalf("problems after fix = %d: %+v", n, r)
+	}
+}
+
+func TestRun_MissingDatabase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "nope.db")
+	if _, err := Run(context.Background(), Config{SQLitePath: dbPath}); err == nil {
+		t.Fatal("expected an error for a missing database")
+	}
+	if _, err := os.Stat(dbPath); !os.IsNotExist(err) {
+		t.Fatal("doctor must not create the database")
+	}
+}
+
+func queryStrings(t *testing.T, dbPath, query string) []string {
+	t.Helper()
+	store, err := storage.NewSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer store.Close()
+	rows, err := store.Query(context.Background(), query)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	var out []string
+	for _, row := range rows {
+		for _, v := range row {
+			out = append(out, v.(string))
+		}
+	}
+	return out
+}
@@ -36,7 +36,7 @@ func findViolations(commands []*models.Command, verdicts map[int]Verdict) []viol
 		if !ok || en.Kind != cmdenrich.KindMakeBuilding {
 			continue
 		}
-		econ, ok := cmdenrich.EconOf(en.Subject)
+		econ, ok := cmdenrich.EarlyEconOf(en.Subject)
 		if !ok {
 			continue
 		}
@@ -185,7 +185,7 @@ func backtrackOverdraws(commands []*models.Command, idx int, verdicts map[int]Ve
 	if !ok {
 		return false
 	}
-	econ, ok := cmdenrich.EconOf(en.Subject)
+	econ, ok := cmdenrich.EarlyEconOf(en.Subject)
 	if !ok {
 		return false
 	}
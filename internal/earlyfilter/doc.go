@@ -21,7 +21,11 @@
 //   - It errs on admitting commands. False keeps (kept spam) are cheaper
 //     for downstream consumers than false drops (real builds removed).
 //
-// Apply is the public entry point. It is pure: it does not mutate the
+// SimulateEconomy reuses the simulation over the whole game, with gas,
+// per-base income saturation and the full cost table, to estimate each
+// player's bank, income and supply for the game page (see economy.go).
+//
+// Apply is the filter's entry point. It is pure: it does not mutate the
 // input command slice and has no I/O outside the optional JSON debug
 // trace written to Options.DebugDir.
 package earlyfilter
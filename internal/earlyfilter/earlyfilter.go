@@ -154,7 +154,7 @@ func runForward(
 			mineralsAfter[i] = int(sim.minerals)
 			continue
 		}
-		econ, hasEcon := cmdenrich.EconOf(enriched.Subject)
+		econ, hasEcon := cmdenrich.EarlyEconOf(enriched.Subject)
 		if !hasEcon {
 			verdicts[i] = VerdictKept
 			mineralsAfter[i] = int(sim.minerals)
@@ -0,0 +1,379 @@
+package earlyfilter
+
+import (
+	"math"
+	"sort"
+
+	"github.com/marianogappa/screpdb/internal/cmdenrich"
+	"github.com/marianogappa/screpdb/internal/models"
+)
+
+// EconomySampleSeconds is the spacing of SimulateEconomy's samples.
+const EconomySampleSeconds = 10
+
+// MinSupplyBlockSeconds is the shortest supply block SimulateEconomy reports:
+// a supply building ordered a moment after reaching the cap isn't a block.
+const MinSupplyBlockSeconds = 3
+
+// Full-game income model. A base's mineral line takes two workers per patch
+// (8 patches) at the full gather rate; a third per patch adds about a third
+// of a worker, and past that extra workers gather nothing. Each completed gas
+// building is staffed with 3 workers at the saturation rate the early filter
+// uses, never more than a third of the player's workers.
+const (
+	workersPerBaseFullRate    = 16
+	workersPerBaseOversat     = 8
+	oversaturatedGatherFactor = 1.0 / 3
+	gasPerWorkerPerMinute     = 46.0
+	maxSupply                 = 200
+)
+
+// townHallSupply is the supply a town hall provides. The early-game table
+// leaves it out (the filter's supply checks are tuned without it); the
+// full-game model adds it at completion.
+var townHallSupply = map[string]int{
+	models.GeneralUnitNexus:         9,
+	models.GeneralUnitCommandCenter: 10,
+	models.GeneralUnitHatchery:      1,
+}
+
+// Economy is SimulateEconomy's output.
+type Economy struct {
+	// Samples is each player's estimated economy every EconomySampleSeconds,
+	// from 0 to the replay's duration, ordered by second then by players'
+	// order.
+	Samples []models.EconomySample
+	// SupplyBlocks are the periods a player sat at the supply cap with no
+	// supply building in progress, ordered by start then by players' order.
+	SupplyBlocks []SupplyBlock
+}
+
+// SupplyBlock is one period a player was supply blocked, in whole seconds.
+// EndSecond is when supply was freed, a supply building was ordered, or the
+// player's last command, whichever came first.
+type SupplyBlock struct {
+	PlayerID    byte
+	StartSecond int
+	EndSecond   int
+	// Supply is the cap the player was blocked at.
+	Supply int
+}
+
+// DurationSeconds is the block's length.
+func (b SupplyBlock) DurationSeconds() int {
+	return b.EndSecond - b.StartSecond
+}
+
+// SimulateEconomy runs the resource simulation over the whole game and
+// returns each player's estimated economy and supply blocks.
+//
+// commands should be the filtered stream (Apply's Result.Commands, after
+// research dedup), since the simulation charges every Build / Train / Morph /
+// Tech / Upgrade it can afford. Unlike Apply it tracks gas, income grows with
+// bases, and it covers every unit, building, research and upgrade. It is an
+// estimate in the filter's spirit: workers mine perfectly and a command the
+// player can't afford in minerals at that moment is assumed never to have
+// happened. Gas doesn't gate commands (the model can't see workers moved on
+// and off gas, so it runs short), it bottoms out at zero. Deaths aren't in the
+// command stream: a unit made past the supply cap proves some died, so supply
+// used is held at the cap, but dead workers keep mining.
+//
+// A player is supply blocked while supply used is at the cap (below 200)
+// and no supply building or unit is in progress, whether they then try to
+// produce or stall. Blocks shorter than MinSupplyBlockSeconds are dropped.
+// Since deaths aren't seen, supply used only drifts up, so late-game blocks
+// can be the model's and not the player's.
+func SimulateEconomy(replay *models.Replay, players []*models.Player, commands []*models.Command) Economy {
+	if replay == nil {
+		return Economy{}
+	}
+	type simPlayer struct {
+		id  byte
+		sim *playerSim
+	}
+	var order []simPlayer
+	sims := map[int64]*playerSim{}
+	lastCommandFrame := map[int64]int32{}
+	for _, p := range players {
+		if p == nil || p.IsObserver {
+			continue
+		}
+		if _, dup := sims[int64(p.PlayerID)]; dup {
+			continue
+		}
+		sim := newPlayerSim(p.Race, nil)
+		sim.fullGame = true
+		sims[int64(p.PlayerID)] = sim
+		order = append(order, simPlayer{id: p.PlayerID, sim: sim})
+	}
+	if len(order) == 0 {
+		return Economy{}
+	}
+
+	var out Economy
+	emit := func(second int) {
+		for _, sp := range order {
+			sp.sim.advanceTo(secondsToFrame(float64(second)))
+			out.Samples = append(out.Samples, sp.sim.sample(sp.id, second))
+		}
+	}
+
+	next := 0
+	for _, cmd := range commands {
+		if cmd == nil || cmd.Player == nil {
+			continue
+		}
+		for next <= cmd.SecondsFromGameStart && next <= replay.DurationSeconds {
+			emit(next)
+			next += EconomySampleSeconds
+		}
+		sim := sims[int64(cmd.Player.PlayerID)]
+		if sim == nil {
+			continue
+		}
+		sim.advanceTo(cmd.Frame)
+		sim.applyFullGame(cmd)
+		sim.trackSupplyBlock(cmd.Frame)
+		lastCommandFrame[int64(cmd.Player.PlayerID)] = cmd.Frame
+	}
+	for next <= replay.DurationSeconds {
+		emit(next)
+		next += EconomySampleSeconds
+	}
+
+	end := secondsToFrame(float64(replay.DurationSeconds))
+	for _, sp := range order {
+		sp.sim.closeSupplyBlock(min(lastCommandFrame[int64(sp.id)], end))
+		for _, span := range sp.sim.supplyBlocks {
+			block := SupplyBlock{
+				PlayerID:    sp.id,
+				StartSecond: int(math.Round(frameToSeconds(span.start))),
+				EndSecond:   int(math.Round(frameToSeconds(span.end))),
+				Supply:      span.supply,
+			}
+			if block.DurationSeconds() >= MinSupplyBlockSeconds {
+				out.SupplyBlocks = append(out.SupplyBlocks, block)
+			}
+		}
+	}
+	sort.SliceStable(out.SupplyBlocks, func(i, j int) bool {
+		return out.SupplyBlocks[i].StartSecond < out.SupplyBlocks[j].StartSecond
+	})
+	return out
+}
+
+// trackSupplyBlock opens or closes the full-game sim's supply block after
+// its supply changed at frame. The early filter doesn't track blocks.
+func (p *playerSim) trackSupplyBlock(frame int32) {
+	if !p.fullGame {
+		return
+	}
+	blocked := p.supplyUsed < maxSupply && p.supplyUsed >= p.supplyMax && !p.supplyInProgress()
+	switch {
+	case blocked && !p.supplyBlocked:
+		p.supplyBlocked = true
+		p.openSupplyBlock = supplyBlockSpan{start: frame, supply: p.supplyMax}
+	case !blocked && p.supplyBlocked:
+		p.closeSupplyBlock(frame)
+	}
+}
+
+// closeSupplyBlock ends an open supply block at frame. No-op if none is
+// open, or if it opened after frame (the player had stopped playing).
+func (p *playerSim) closeSupplyBlock(frame int32) {
+	if !p.supplyBlocked {
+		return
+	}
+	p.supplyBlocked = false
+	if frame > p.openSupplyBlock.start {
+		span := p.openSupplyBlock
+		span.end = frame
+		p.supplyBlocks = append(p.supplyBlocks, span)
+	}
+}
+
+// supplyBlockSpan is one of the full-game sim's supply blocks, in frames.
+type supplyBlockSpan struct {
+	start, end int32
+	supply     int
+}
+
+// supplyInProgress reports whether a supply provider (Pylon, Supply Depot,
+// Overlord, town hall) is scheduled to complete.
+func (p *playerSim) supplyInProgress() bool {
+	for _, ev := range p.pending {
+		if ev.supplyMaxDelta > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// applyFullGame charges one command to the full-game sim, if the player could
+// afford it.
+func (p *playerSim) applyFullGame(cmd *models.Command) {
+	switch cmd.ActionType {
+	case models.ActionTypeCancelBuild:
+		p.cancelLastBuild()
+		return
+	case models.ActionTypeBuildingMorph:
+		if cmd.UnitType == nil {
+			return
+		}
+		econ, ok := cmdenrich.EconOf(*cmd.UnitType)
+		if !ok || !p.canAfford(econ.Minerals) {
+			return
+		}
+		p.spend(econ.Minerals, econ.Gas)
+		p.schedulePending(pendingEvent{
+			completionFrame:   cmd.Frame + secondsToFrame(econ.BuildTimeS),
+			completedBuilding: *cmd.UnitType,
+		})
+		return
+	}
+
+	enriched, ok := cmdenrich.Classify(cmd)
+	if !ok {
+		return
+	}
+	switch enriched.Kind {
+	case cmdenrich.KindMakeBuilding:
+		econ, ok := cmdenrich.EconOf(enriched.Subject)
+		if !ok || !p.canAfford(econ.Minerals) {
+			return
+		}
+		econ.SupplyDelta += townHallSupply[enriched.Subject]
+		var pos *[2]int
+		if cmd.X != nil && cmd.Y != nil {
+			pos = &[2]int{*cmd.X, *cmd.Y}
+		}
+		p.acceptBuild(enriched.Subject, econ, cmd.Frame, pos)
+		p.gas = max(p.gas, 0)
+	case cmdenrich.KindMakeUnit:
+		econ, ok := cmdenrich.EconOf(enriched.Subject)
+		if !ok {
+			return
+		}
+		p.acceptFullGameUnit(enriched.Subject, econ, cmd)
+	case cmdenrich.KindTech:
+		meta, ok := models.LookupTech(enriched.Subject)
+		if ok && p.canAfford(meta.Minerals) {
+			p.spend(meta.Minerals, meta.Gas)
+		}
+	case cmdenrich.KindUpgrade:
+		meta, ok := models.LookupUpgrade(enriched.Subject)
+		if !ok {
+			return
+		}
+		if p.upgradeLevels == nil {
+			p.upgradeLevels = map[string]int{}
+		}
+		level := p.upgradeLevels[enriched.Subject]
+		if level >= meta.MaxLevel {
+			return
+		}
+		cost := meta.Levels[level]
+		if p.canAfford(cost.Minerals) {
+			p.spend(cost.Minerals, cost.Gas)
+			p.upgradeLevels[enriched.Subject]++
+		}
+	}
+}
+
+// acceptFullGameUnit charges a Train / Morph for every unit it produced: one,
+// or for a Zerg larva morph, as many of the selected larvae as there were
+// larvae and resources for.
+func (p *playerSim) acceptFullGameUnit(subject string, econ cmdenrich.UnitEcon, cmd *models.Command) {
+	if !p.canAfford(econ.Minerals) {
+		return
+	}
+	count := 1
+	larvaMorph := isLarvaUnit(subject)
+	if larvaMorph {
+		if !p.canConsumeLarva(p.lastFrame) {
+			return
+		}
+		count = max(1, min(cmd.SelectedUnits, p.availableLarvaCount(), int(p.minerals)/max(econ.Minerals, 1)))
+	}
+	completion := cmd.Frame + secondsToFrame(econ.BuildTimeS)
+	for range count {
+		p.spend(econ.Minerals, econ.Gas)
+		p.supplyUsed = min(p.supplyUsed+econ.SupplyCost, max(p.supplyUsed, min(p.supplyMax, maxSupply)))
+		if larvaMorph {
+			p.consumeLarva()
+		}
+		ev := pendingEvent{
+			completionFrame: completion,
+			supplyMaxDelta:  econ.SupplyDelta,
+		}
+		if cmdenrich.IsWorker(subject) {
+			ev.workersDelta = 1
+		}
+		p.schedulePending(ev)
+	}
+}
+
+// isLarvaUnit reports whether a Zerg unit morphs from a larva: the early
+// filter's set (isLarvaConsumingMorph) plus the later larva units.
+func isLarvaUnit(subject string) bool {
+	switch subject {
+	case models.GeneralUnitHydralisk, models.GeneralUnitMutalisk, models.GeneralUnitScourge,
+		models.GeneralUnitQueen, models.GeneralUnitUltralisk, models.GeneralUnitDefiler:
+		return true
+	}
+	return isLarvaConsumingMorph(subject)
+}
+
+func (p *playerSim) canAfford(minerals int) bool {
+	return p.minerals >= float64(minerals)
+}
+
+// spend charges a cost; gas bottoms out at zero (see SimulateEconomy).
+func (p *playerSim) spend(minerals, gas int) {
+	p.minerals -= float64(minerals)
+	p.gas = max(p.gas-float64(gas), 0)
+}
+
+// bases counts the player's completed town halls. Lair and Hive morph in
+// place, so a Hatchery keeps counting once upgraded.
+func (p *playerSim) bases() int {
+	return p.completed[models.GeneralUnitNexus] + p.completed[models.GeneralUnitCommandCenter] + p.completed[models.GeneralUnitHatchery]
+}
+
+// fullGameGasWorkers is how many workers the full-game model puts on gas.
+func (p *playerSim) fullGameGasWorkers() int {
+	geysers := p.completed[models.GeneralUnitRefinery] + p.completed[models.GeneralUnitExtractor] + p.completed[models.GeneralUnitAssimilator]
+	return max(0, min(geysers*gasWorkersPerGather, p.workers/3))
+}
+
+// mineralIncomePerMinute is the full-game mineral income at the sim's
+// current state, saturating per base.
+func (p *playerSim) mineralIncomePerMinute() float64 {
+	onMinerals := p.workers - p.fullGameGasWorkers()
+	bases := p.bases()
+	if onMinerals <= 0 || bases <= 0 {
+		return 0
+	}
+	full := min(onMinerals, bases*workersPerBaseFullRate)
+	oversat := min(onMinerals-full, bases*workersPerBaseOversat)
+	return (float64(full) + float64(oversat)*oversaturatedGatherFactor) * p.gatherRate
+}
+
+func (p *playerSim) gasIncomePerMinute() float64 {
+	return float64(p.fullGameGasWorkers()) * gasPerWorkerPerMinute
+}
+
+func (p *playerSim) sample(playerID byte, second int) models.EconomySample {
+	return models.EconomySample{
+		PlayerID:      playerID,
+		Second:        second,
+		Minerals:      max(0, int(p.minerals)),
+		Gas:           int(p.gas),
+		MineralIncome: int(p.mineralIncomePerMinute()),
+		GasIncome:     int(p.gasIncomePerMinute()),
+		SupplyUsed:    p.supplyUsed,
+		SupplyMax:     min(p.supplyMax, maxSupply),
+		Workers:       p.workers,
+		Bases:         p.bases(),
+	}
+}
@@ -0,0 +1,100 @@
+package earlyfilter
+
+import (
+	"testing"
+
+	"github.com/marianogappa/screpdb/internal/models"
+)
+
+// TestSimulateEconomySamplesEveryPlayer — one sample per non-observer player
+// every EconomySampleSeconds through the game's end, in player order.
+func TestSimulateEconomySamplesEveryPlayer(t *testing.T) {
+	p, tr := protossPlayer(), terranPlayer()
+	obs := &models.Player{PlayerID: 3, Race: "Zerg", IsObserver: true}
+	samples := SimulateEconomy(&models.Replay{DurationSeconds: 35}, []*models.Player{p, tr, obs}, nil).Samples
+
+	if len(samples) != 8 {
+		t.Fatalf("expected 4 seconds x 2 players = 8 samples, got %d", len(samples))
+	}
+	for i, s := range samples {
+		wantSecond := (i / 2) * EconomySampleSeconds
+		wantPlayer := []byte{p.PlayerID, tr.PlayerID}[i%2]
+		if s.Second != wantSecond || s.PlayerID != wantPlayer {
+			t.Errorf("sample %d = player %d @%ds; want player %d @%ds", i, s.PlayerID, s.Second, wantPlayer, wantSecond)
+		}
+	}
+	start := samples[0]
+	if start.Minerals != 50 || start.Workers != 4 || start.Bases != 1 || start.SupplyMax != 9 {
+		t.Errorf("Protoss start = %+v; want 50 minerals, 4 workers, 1 base, 9 supply", start)
+	}
+	if last := samples[6]; last.Minerals <= start.Minerals || last.MineralIncome == 0 {
+		t.Errorf("expected the idle bank to grow with income, got %+v", last)
+	}
+}
+
+// TestSimulateEconomyFindsSupplyBlocks — the fifth Probe reaches 9/9 with
+// no Pylon in progress, which blocks until the Pylon is ordered; an idle
+// player who never reaches the cap is never blocked.
+func TestSimulateEconomyFindsSupplyBlocks(t *testing.T) {
+	p, tr := protossPlayer(), terranPlayer()
+	var cmds []*models.Command
+	for _, second := range []int{0, 15, 30, 45, 60} {
+		cmds = append(cmds, makeCmd("Train", models.GeneralUnitProbe, second, p))
+	}
+	cmds = append(cmds,
+		makeCmd("Build", models.GeneralUnitPylon, 90, p),
+		makeCmd("Train", models.GeneralUnitProbe, 200, p),
+	)
+	economy := SimulateEconomy(&models.Replay{DurationSeconds: 240}, []*models.Player{p, tr}, cmds)
+
+	want := []SupplyBlock{{PlayerID: p.PlayerID, StartSecond: 60, EndSecond: 90, Supply: 9}}
+	if len(economy.SupplyBlocks) != len(want) || economy.SupplyBlocks[0] != want[0] {
+		t.Fatalf("supply blocks = %+v; want %+v", economy.SupplyBlocks, want)
+	}
+	if got := economy.SupplyBlocks[0].DurationSeconds(); got != 30 {
+		t.Errorf("block duration = %d; want 30", got)
+	}
+}
+
+// TestSimulateEconomyChargesGasAndTownHallSupply — a completed Nexus adds a
+// base and its 9 supply, and a Dragoon costs gas, which bottoms out at zero
+// instead of refusing the unit.
+func TestSimulateEconomyChargesGasAndTownHallSupply(t *testing.T) {
+	p := protossPlayer()
+	sim := newPlayerSim(p.Race, nil)
+	sim.fullGame = true
+	sim.minerals = 1000
+
+	sim.applyFullGame(makeCmd("Build", models.GeneralUnitNexus, 0, p))
+	sim.applyFullGame(makeCmd("Train", models.GeneralUnitDragoon, 0, p))
+	if sim.minerals != 1000-400-125 || sim.gas != 0 {
+		t.Fatalf("after Nexus + Dragoon: minerals=%v gas=%v; want 475 and 0", sim.minerals, sim.gas)
+	}
+	sim.advanceTo(secondFrame(200))
+	got := sim.sample(p.PlayerID, 200)
+	if got.Bases != 2 || got.SupplyMax != 18 || got.SupplyUsed != 6 {
+		t.Errorf("after completion = %+v; want 2 bases, 18 supply max, 6 used", got)
+	}
+}
+
+// TestMineralIncomeSaturatesPerBase — past 16 workers a base's mineral line
+// adds a third of a worker each, and past 24 nothing; a second base lifts the
+// cap.
+func TestMineralIncomeSaturatesPerBase(t *testing.T) {
+	sim := newPlayerSim("Terran", nil)
+	sim.fullGame = true
+	perWorker := sim.gatherRate
+
+	sim.workers = 16
+	if got := sim.mineralIncomePerMinute(); got != 16*perWorker {
+		t.Errorf("16 workers on 1 base: got %v, want %v", got, 16*perWorker)
+	}
+	sim.workers = 40
+	if got, want := sim.mineralIncomePerMinute(), (16+8.0/3)*perWorker; got != want {
+		t.Errorf("40 workers on 1 base: got %v, want %v", got, want)
+	}
+	sim.completed[models.GeneralUnitCommandCenter] = 2
+	if got, want := sim.mineralIncomePerMinute(), (32+8.0/3)*perWorker; got != want {
+		t.Errorf("40 workers on 2 bases: got %v, want %v", got, want)
+	}
+}
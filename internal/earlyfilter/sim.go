@@ -128,6 +128,7 @@ type pendingEvent struct {
 type reversibleBuild struct {
 	id              int
 	minerals        int
+	gas             int
 	workersDelta    int // what acceptBuild applied (Zerg: -1; else 0)
 	supplyUsedDelta int // what acceptBuild applied (Zerg: -1; else 0)
 }
@@ -141,7 +142,13 @@ type reversibleBuild struct {
 type playerSim struct {
 	race string
 
+	// fullGame switches the sim to the full-game economy model (see
+	// economy.go): gas income, per-base mineral saturation and gas staffed
+	// from completed gas buildings. The early filter leaves it off.
+	fullGame bool
+
 	minerals   float64
+	gas        float64
 	supplyUsed int
 	supplyMax  int
 	workers    int
@@ -175,6 +182,17 @@ type playerSim struct {
 	// ends. Subsequent Harvest1 orders inside the window are no-ops.
 	gasActiveUntilFrame map[int]int32
 
+	// upgradeLevels counts the levels of each upgrade the full-game model
+	// has charged, since tiered upgrades share one name.
+	upgradeLevels map[string]int
+
+	// supplyBlocks are the full-game model's finished supply blocks;
+	// supplyBlocked / openSupplyBlock track the open one (see
+	// trackSupplyBlock).
+	supplyBlocks    []supplyBlockSpan
+	supplyBlocked   bool
+	openSupplyBlock supplyBlockSpan
+
 	// hatcheries tracks per-hatchery larva state for Zerg. Empty for
 	// other races. The starting hatchery is added at sim init; built
 	// hatcheries are appended on completion via the pending-event hook.
@@ -239,6 +257,7 @@ func (p *playerSim) advanceTo(targetFrame int32) {
 		if ev.addHatchery {
 			p.hatcheries = append(p.hatcheries, newBuiltHatchery(ev.completionFrame))
 		}
+		p.trackSupplyBlock(ev.completionFrame)
 		cursor = ev.completionFrame
 	}
 	// Advance larva spawn timers across all hatcheries.
@@ -253,6 +272,12 @@ func (p *playerSim) accumulateIncome(fromFrame, toFrame int32) {
 	if toFrame <= fromFrame || p.gatherRate == 0 {
 		return
 	}
+	if p.fullGame {
+		dtMin := frameToSeconds(toFrame-fromFrame) / 60.0
+		p.minerals += p.mineralIncomePerMinute() * dtMin
+		p.gas += p.gasIncomePerMinute() * dtMin
+		return
+	}
 	mineralWorkers := p.workers - p.gasWorkers
 	if mineralWorkers <= 0 {
 		return
@@ -286,6 +311,7 @@ func (p *playerSim) cancelLastBuild() {
 	b := p.openBuilds[len(p.openBuilds)-1]
 	p.openBuilds = p.openBuilds[:len(p.openBuilds)-1]
 	p.minerals += float64(b.minerals)
+	p.gas += float64(b.gas)
 	p.workers -= b.workersDelta
 	p.supplyUsed -= b.supplyUsedDelta
 	for i := range p.pending {
@@ -322,6 +348,7 @@ type dropDecision struct {
 // nil if no position is available.
 func (p *playerSim) acceptBuild(subject string, econ cmdenrich.UnitEcon, orderFrame int32, posBuildTilesXY *[2]int) {
 	p.minerals -= float64(econ.Minerals)
+	p.gas -= float64(econ.Gas)
 	workersDelta, supplyUsedDelta := 0, 0
 	if p.race == "Zerg" {
 		p.workers--
@@ -333,6 +360,7 @@ func (p *playerSim) acceptBuild(subject string, econ cmdenrich.UnitEcon, orderFr
 	p.openBuilds = append(p.openBuilds, reversibleBuild{
 		id:              buildID,
 		minerals:        econ.Minerals,
+		gas:             econ.Gas,
 		workersDelta:    workersDelta,
 		supplyUsedDelta: supplyUsedDelta,
 	})
@@ -107,7 +107,7 @@ func buildTrace(
 		// reason about (Build/Train/Morph with known econ). Trains of
 		// units we don't know costs for would record as "kept" with no
 		// useful detail — skip those.
-		if _, hasEcon := cmdenrich.EconOf(en.Subject); !hasEcon {
+		if _, hasEcon := cmdenrich.EarlyEconOf(en.Subject); !hasEcon {
 			continue
 		}
 		pid := int64(cmd.Player.PlayerID)
@@ -200,7 +200,7 @@ func captureTicks(
 		if !ok || !kindFiltered(en.Kind) {
 			continue
 		}
-		econ, hasEcon := cmdenrich.EconOf(en.Subject)
+		econ, hasEcon := cmdenrich.EarlyEconOf(en.Subject)
 		if !hasEcon {
 			continue
 		}
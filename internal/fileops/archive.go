@@ -0,0 +1,310 @@
+package fileops
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marianogappa/screpdb/internal/iofacade"
+)
+
+// ArchiveSeparator joins a .zip archive's path and the path of a replay
+// inside it, e.g. "packs/ASL15.zip!/Ro16/Game1.rep". Replays found in
+// archives are recorded under such paths, so path dedup, re-analysis and
+// staging address the archive entry without it ever being extracted to disk.
+const ArchiveSeparator = "!/"
+
+const archiveExt = ".zip"
+
+// IsArchive reports whether path names a replay archive (a .zip file).
+func IsArchive(path string) bool {
+	return strings.HasSuffix(strings.ToLower(path), archiveExt)
+}
+
+// SplitArchivePath splits an archive entry path into the archive's path and
+// the entry's name inside it. ok is false for plain file paths.
+func SplitArchivePath(p string) (archive, entry string, ok bool) {
+	i := strings.Index(strings.ToLower(p), archiveExt+ArchiveSeparator)
+	if i < 0 {
+		return "", "", false
+	}
+	archive = p[:i+len(archiveExt)]
+	entry = p[i+len(archiveExt)+len(ArchiveSeparator):]
+	return archive, entry, entry != ""
+}
+
+// ArchiveEntryPath builds the path recorded for entry inside archive.
+func ArchiveEntryPath(archive, entry string) string {
+	return archive + ArchiveSeparator + entry
+}
+
+// ContainerPath returns the on-disk file holding the replay at p: the archive
+// for archive entries, p itself otherwise.
+func ContainerPath(p string) string {
+	if archive, _, ok := SplitArchivePath(p); ok {
+		return archive
+	}
+	return p
+}
+
+// isReplayName reports whether name is a replay file that should be ingested.
+func isReplayName(name string) bool {
+	return strings.HasSuffix(strings.ToLower(name), ".rep") && !shouldIgnoreReplayFilePath(name)
+}
+
+// openArchive opens the zip at archive for reading. The returned closer closes
+// the underlying file.
+func openArchive(archive string) (*zip.Reader, io.Closer, os.FileInfo, error) {
+	f, err := iofacade.Open(archive)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, nil, err
+	}
+	r, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		f.Close()
+		return nil, nil, nil, fmt.Errorf("open archive %s: %w", archive, err)
+	}
+	return r, f, info, nil
+}
+
+// archiveIdleClose is how long an archive stays open after its last read.
+const archiveIdleClose = 2 * time.Second
+
+// openArchives shares each open archive between the reads of its replays.
+// Ingest lists, hashes and parses every entry of an archive in turn, and
+// reopening it for each would read its whole directory and scan it for the
+// entry every time.
+var openArchives = archiveCache{archives: map[string]*sharedArchive{}}
+
+// sharedArchive is an open archive with its entries indexed by name. refs
+// counts the reads using it; stale ones are closed when the last ends.
+type sharedArchive struct {
+	path    string
+	reader  *zip.Reader
+	file    io.Closer
+	size    int64
+	modTime time.Time
+	entries map[string]*zip.File
+	refs    int
+	stale   bool
+	idle    *time.Timer
+}
+
+// entry finds the entry named entry, reporting a missing one as
+// fs.ErrNotExist so callers treat it like a missing file.
+func (a *sharedArchive) entry(p, entry string) (*zip.File, error) {
+	if f, ok := a.entries[entry]; ok {
+		return f, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: p, Err: fs.ErrNotExist}
+}
+
+type archiveCache struct {
+	mu       sync.Mutex
+	archives map[string]*sharedArchive
+}
+
+// acquire returns the open archive at path, opening it unless it is already
+// open and unchanged on disk. Every acquire must be paired with a release.
+func (c *archiveCache) acquire(path string) (*sharedArchive, error) {
+	info, err := iofacade.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if a, ok := c.archives[path]; ok {
+		if a.size == info.Size() && a.modTime.Equal(info.ModTime()) {
+			a.refs++
+			if a.idle != nil {
+				a.idle.Stop()
+				a.idle = nil
+			}
+			return a, nil
+		}
+		c.dropLocked(a)
+	}
+
+	r, file, info, err := openArchive(path)
+	if err != nil {
+		return nil, err
+	}
+	a := &sharedArchive{
+		path:    path,
+		reader:  r,
+		file:    file,
+		size:    info.Size(),
+		modTime: info.ModTime(),
+		entries: make(map[string]*zip.File, len(r.File)),
+		refs:    1,
+	}
+	for _, f := range r.File {
+		// The first of several entries with one name wins, as in a scan.
+		if _, ok := a.entries[f.Name]; !ok {
+			a.entries[f.Name] = f
+		}
+	}
+	c.archives[path] = a
+	return a, nil
+}
+
+// release ends a read of a. The archive is closed once it has gone unused for
+// archiveIdleClose, or right away if it changed on disk meanwhile.
+func (c *archiveCache) release(a *sharedArchive) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	a.refs--
+	if a.refs > 0 {
+		return
+	}
+	if a.stale {
+		a.file.Close()
+		return
+	}
+	a.idle = time.AfterFunc(archiveIdleClose, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if a.refs == 0 && c.archives[a.path] == a {
+			c.dropLocked(a)
+		}
+	})
+}
+
+// dropLocked forgets a and closes it, or leaves that to the release of its
+// last read. c.mu must be held.
+func (c *archiveCache) dropLocked(a *sharedArchive) {
+	delete(c.archives, a.path)
+	if a.idle != nil {
+		a.idle.Stop()
+		a.idle = nil
+	}
+	if a.refs == 0 {
+		a.file.Close()
+		return
+	}
+	a.stale = true
+}
+
+// isBadArchive reports whether err means the file is not a readable zip, as
+// opposed to an I/O error. An archive still being copied looks like this too.
+func isBadArchive(err error) bool {
+	return errors.Is(err, zip.ErrFormat) || errors.Is(err, zip.ErrAlgorithm) || errors.Is(err, zip.ErrChecksum)
+}
+
+// walkArchive lists the replays inside archive. Entries are named by their
+// path in the archive; nested archives are not descended into.
+func walkArchive(archive string, archiveInfo os.FileInfo) ([]FileInfo, error) {
+	a, err := openArchives.acquire(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer openArchives.release(a)
+
+	var files []FileInfo
+	for _, f := range a.reader.File {
+		if f.FileInfo().IsDir() || !isReplayName(f.Name) {
+			continue
+		}
+		modTime := f.Modified
+		if modTime.IsZero() {
+			modTime = archiveInfo.ModTime()
+		}
+		files = append(files, FileInfo{
+			Path:    ArchiveEntryPath(archive, f.Name),
+			Name:    path.Base(f.Name),
+			Size:    int64(f.UncompressedSize64),
+			ModTime: modTime,
+		})
+	}
+	return files, nil
+}
+
+// OpenReplayFile opens the replay at p for reading, whether it is a plain
+// file or an archive entry.
+func OpenReplayFile(p string) (io.ReadCloser, error) {
+	archive, entry, ok := SplitArchivePath(p)
+	if !ok {
+		return iofacade.Open(p)
+	}
+	a, err := openArchives.acquire(archive)
+	if err != nil {
+		return nil, err
+	}
+	f, err := a.entry(p, entry)
+	if err != nil {
+		openArchives.release(a)
+		return nil, err
+	}
+	rc, err := f.Open()
+	if err != nil {
+		openArchives.release(a)
+		return nil, err
+	}
+	return &entryReadCloser{ReadCloser: rc, archive: a}, nil
+}
+
+// entryReadCloser closes the entry and releases the archive it was read from.
+type entryReadCloser struct {
+	io.ReadCloser
+	archive *sharedArchive
+	once    sync.Once
+}
+
+func (e *entryReadCloser) Close() error {
+	err := e.ReadCloser.Close()
+	e.once.Do(func() { openArchives.release(e.archive) })
+	return err
+}
+
+// ReadReplayFile reads the whole replay at p, whether it is a plain file or
+// an archive entry.
+func ReadReplayFile(p string) ([]byte, error) {
+	if _, _, ok := SplitArchivePath(p); !ok {
+		return iofacade.ReadFile(p)
+	}
+	rc, err := OpenReplayFile(p)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// StatReplayFile stats the replay at p. For archive entries the result
+// describes the entry (uncompressed size, modification time recorded in the
+// archive); a missing archive or entry satisfies errors.Is(err, os.ErrNotExist).
+func StatReplayFile(p string) (os.FileInfo, error) {
+	archive, entry, ok := SplitArchivePath(p)
+	if !ok {
+		return iofacade.Stat(p)
+	}
+	a, err := openArchives.acquire(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer openArchives.release(a)
+	f, err := a.entry(p, entry)
+	if err != nil {
+		return nil, err
+	}
+	return f.FileInfo(), nil
+}
+
+// ReplayDir returns the folder to register with iofacade.AllowDir before
+// reading the replay at p.
+func ReplayDir(p string) string {
+	return filepath.Dir(ContainerPath(p))
+}
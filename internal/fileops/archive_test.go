@@ -0,0 +1,192 @@
+package fileops
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeZip writes a zip at path with the given entry name → content pairs.
+func writeZip(t *testing.T, path string, entries map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create zip: %v", err)
+	}
+	zw := zip.NewWriter(f)
+	for name, content := range entries {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("create entry %s: %v", name, err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("write entry %s: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("close file: %v", err)
+	}
+}
+
+func TestSplitArchivePath(t *testing.T) {
+	tests := []struct {
+		in, archive, entry string
+		ok                 bool
+	}{
+		{"/packs/ASL.zip!/Ro16/g1.rep", "/packs/ASL.zip", "Ro16/g1.rep", true},
+		{"/packs/ASL.ZIP!/g1.rep", "/packs/ASL.ZIP", "g1.rep", true},
+		{"/replays/g1.rep", "", "", false},
+		{"/replays/wow!/g1.rep", "", "", false},
+		{"/packs/ASL.zip!/", "/packs/ASL.zip", "", false},
+	}
+	for _, tt := range tests {
+		archive, entry, ok := SplitArchivePath(tt.in)
+		if archive != tt.archive || entry != tt.entry || ok != tt.ok {
+			t.Errorf("SplitArchivePath(%q) = (%q, %q, %v), want (%q, %q, %v)", tt.in, archive, entry, ok, tt.archive, tt.entry, tt.ok)
+		}
+	}
+	if got := ArchiveEntryPath("/packs/ASL.zip", "Ro16/g1.rep"); got != "/packs/ASL.zip!/Ro16/g1.rep" {
+		t.Errorf("ArchiveEntryPath = %q", got)
+	}
+	if got := ReplayDir("/packs/ASL.zip!/Ro16/g1.rep"); got != filepath.Dir("/packs/ASL.zip") {
+		t.Errorf("ReplayDir = %q", got)
+	}
+}
+
+func TestWalkReplayFiles_ListsArchiveEntries(t *testing.T) {
+	rootDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(rootDir, "plain.rep"), []byte("plain"), 0o644); err != nil {
+		t.Fatalf("write plain.rep: %v", err)
+	}
+	archive := filepath.Join(rootDir, "pack.zip")
+	writeZip(t, archive, map[string]string{
+		"Ro16/game1.rep":      "one",
+		"Ro16/Ro8/game2.REP":  "two",
+		"Ro16/LastReplay.rep": "last",
+		"readme.txt":          "not a replay",
+	})
+	// Not a zip (e.g. still being copied): skipped, not fatal.
+	if err := os.WriteFile(filepath.Join(rootDir, "partial.zip"), []byte("PK garbage"), 0o644); err != nil {
+		t.Fatalf("write partial.zip: %v", err)
+	}
+
+	files, err := GetReplayFiles(rootDir)
+	if err != nil {
+		t.Fatalf("GetReplayFiles: %v", err)
+	}
+	want := map[string]string{
+		filepath.Join(rootDir, "plain.rep"):             "plain",
+		ArchiveEntryPath(archive, "Ro16/game1.rep"):     "one",
+		ArchiveEntryPath(archive, "Ro16/Ro8/game2.REP"): "two",
+	}
+	if len(files) != len(want) {
+		t.Fatalf("got %d files, want %d: %+v", len(files), len(want), files)
+	}
+	for _, f := range files {
+		content, ok := want[f.Path]
+		if !ok {
+			t.Fatalf("unexpected file %s", f.Path)
+		}
+		if sum := fmt.Sprintf("%x", sha256.Sum256([]byte(content))); f.Checksum != sum {
+			t.Errorf("%s checksum = %s, want %s", f.Path, f.Checksum, sum)
+		}
+		if f.Size != int64(len(content)) || f.Name != filepath.Base(f.Path) {
+			t.Errorf("%s: size=%d name=%s", f.Path, f.Size, f.Name)
+		}
+	}
+
+	if ok, err := HasReplayFiles(rootDir); err != nil || !ok {
+		t.Fatalf("HasReplayFiles = %v, %v", ok, err)
+	}
+}
+
+func TestHasReplayFiles_ArchiveOnlyFolder(t *testing.T) {
+	rootDir := t.TempDir()
+	writeZip(t, filepath.Join(rootDir, "pack.zip"), map[string]string{"g.rep": "g"})
+	if err := ValidateReplayDir(rootDir); err != nil {
+		t.Fatalf("ValidateReplayDir: %v", err)
+	}
+
+	emptyDir := t.TempDir()
+	writeZip(t, filepath.Join(emptyDir, "docs.zip"), map[string]string{"readme.txt": "x"})
+	if ok, err := HasReplayFiles(emptyDir); err != nil || ok {
+		t.Fatalf("HasReplayFiles on archive without replays = %v, %v", ok, err)
+	}
+}
+
+func TestArchiveEntryReadAndStat(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "pack.zip")
+	writeZip(t, archive, map[string]string{"a/g.rep": "hello"})
+	entryPath := ArchiveEntryPath(archive, "a/g.rep")
+
+	data, err := ReadReplayFile(entryPath)
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("ReadReplayFile = %q, %v", data, err)
+	}
+	info, err := NewFileInfoFromPath(entryPath)
+	if err != nil {
+		t.Fatalf("NewFileInfoFromPath: %v", err)
+	}
+	if info.Name != "g.rep" || info.Size != 5 || info.Checksum != fmt.Sprintf("%x", sha256.Sum256([]byte("hello"))) {
+		t.Fatalf("NewFileInfoFromPath = %+v", info)
+	}
+
+	for _, missing := range []string{
+		ArchiveEntryPath(archive, "a/nope.rep"),
+		ArchiveEntryPath(filepath.Join(filepath.Dir(archive), "gone.zip"), "g.rep"),
+	} {
+		if _, err := StatReplayFile(missing); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("StatReplayFile(%s) error = %v, want os.ErrNotExist", missing, err)
+		}
+	}
+	if _, err := HashFiles(context.Background(), []FileInfo{{Path: ArchiveEntryPath(archive, "a/nope.rep")}}); err == nil {
+		t.Fatal("HashFiles should fail for a missing entry")
+	}
+}
+
+func TestArchiveReadsShareOneOpenArchive(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "pack.zip")
+	writeZip(t, archive, map[string]string{"g1.rep": "one", "g2.rep": "two"})
+
+	rc, err := OpenReplayFile(ArchiveEntryPath(archive, "g1.rep"))
+	if err != nil {
+		t.Fatalf("OpenReplayFile: %v", err)
+	}
+	if data, err := ReadReplayFile(ArchiveEntryPath(archive, "g2.rep")); err != nil || string(data) != "two" {
+		t.Fatalf("ReadReplayFile = %q, %v", data, err)
+	}
+	openArchives.mu.Lock()
+	a := openArchives.archives[archive]
+	refs := 0
+	if a != nil {
+		refs = a.refs
+	}
+	openArchives.mu.Unlock()
+	if refs != 1 {
+		t.Fatalf("open archive refs = %d, want the open entry's read to keep it while the other reused it", refs)
+	}
+	if err := rc.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// An archive rewritten on disk is opened again instead of read stale.
+	writeZip(t, archive, map[string]string{"g1.rep": "rewritten", "g3.rep": "three"})
+	if err := os.Chtimes(archive, time.Now().Add(time.Hour), time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Chtimes: %v", err)
+	}
+	if data, err := ReadReplayFile(ArchiveEntryPath(archive, "g1.rep")); err != nil || string(data) != "rewritten" {
+		t.Fatalf("ReadReplayFile after rewrite = %q, %v", data, err)
+	}
+	if _, err := StatReplayFile(ArchiveEntryPath(archive, "g2.rep")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("StatReplayFile of a removed entry error = %v, want os.ErrNotExist", err)
+	}
+}
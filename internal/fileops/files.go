@@ -74,9 +74,18 @@ func HasReplayFiles(rootDir string) (bool, error) {
 		if info.IsDir() {
 			return nil
 		}
-		if strings.HasSuffix(strings.ToLower(path), ".rep") && !shouldIgnoreReplayFilePath(path) {
+		if isReplayName(path) {
 			return errReplayFound
 		}
+		if IsArchive(path) {
+			entries, err := walkArchive(path, info)
+			if err != nil && !isBadArchive(err) {
+				return err
+			}
+			if len(entries) > 0 {
+				return errReplayFound
+			}
+		}
 		return nil
 	})
 	if err == nil {
@@ -92,30 +101,58 @@ func HasReplayFiles(rootDir string) (bool, error) {
 // returns FileInfo entries with Path/Name/Size/ModTime populated. Checksum is
 // left empty — callers that need it should use HashFiles to populate it for
 // the subset that survives a cheaper dedup step (e.g. path-based prefilter).
+//
+// Replays inside .zip archives are listed too, under archive entry paths (see
+// ArchiveSeparator). A file that is not a readable zip is skipped rather than
+// failing the walk: it may still be being copied, and watch mode will see it
+// again on its next poll.
 func WalkReplayFiles(rootDir string) ([]FileInfo, error) {
 	var files []FileInfo
+	err := StreamReplayFiles(context.Background(), rootDir, func(file FileInfo) error {
+		files = append(files, file)
+		return nil
+	})
+	return files, err
+}
 
-	err := iofacade.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
+// StreamReplayFiles walks rootDir like WalkReplayFiles but hands each replay
+// to fn as soon as it's found instead of collecting them, so a caller can
+// start working before the walk ends and without holding the whole listing.
+// The walk stops with fn's error, or ctx's once it's cancelled.
+func StreamReplayFiles(ctx context.Context, rootDir string, fn func(FileInfo) error) error {
+	return iofacade.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
 
-		if !info.IsDir() && strings.HasSuffix(strings.ToLower(path), ".rep") {
-			if shouldIgnoreReplayFilePath(path) {
-				return nil
-			}
-			files = append(files, FileInfo{
+		if isReplayName(path) {
+			return fn(FileInfo{
 				Path:    path,
 				Name:    info.Name(),
 				Size:    info.Size(),
 				ModTime: info.ModTime(),
 			})
 		}
+		if IsArchive(path) {
+			entries, err := walkArchive(path, info)
+			if err != nil && !isBadArchive(err) {
+				return err
+			}
+			for _, entry := range entries {
+				if err := fn(entry); err != nil {
+					return err
+				}
+			}
+		}
 
 		return nil
 	})
-
-	return files, err
 }
 
 // HashFiles computes SHA256 for every entry whose Checksum is empty, fanning
@@ -213,21 +250,9 @@ func SortFilesByModTime(files []FileInfo) {
 func FilterFilesByDate(files []FileInfo, upToDate *time.Time, upToMonths *int) []FileInfo {
 	var filtered []FileInfo
 
+	include := DateFilter(upToDate, upToMonths)
 	for _, file := range files {
-		include := true
-
-		if upToDate != nil && file.ModTime.After(*upToDate) {
-			include = false
-		}
-
-		if upToMonths != nil {
-			cutoff := time.Now().AddDate(0, -*upToMonths, 0)
-			if file.ModTime.Before(cutoff) {
-				include = false
-			}
-		}
-
-		if include {
+		if include(file) {
 			filtered = append(filtered, file)
 		}
 	}
@@ -235,6 +260,24 @@ func FilterFilesByDate(files []FileInfo, upToDate *time.Time, upToMonths *int) [
 	return filtered
 }
 
+// DateFilter returns FilterFilesByDate's constraints as a predicate over one
+// file, for callers that see files one at a time (see StreamReplayFiles).
+func DateFilter(upToDate *time.Time, upToMonths *int) func(FileInfo) bool {
+	var cutoff time.Time
+	if upToMonths != nil {
+		cutoff = time.Now().AddDate(0, -*upToMonths, 0)
+	}
+	return func(file FileInfo) bool {
+		if upToDate != nil && file.ModTime.After(*upToDate) {
+			return false
+		}
+		if upToMonths != nil && file.ModTime.Before(cutoff) {
+			return false
+		}
+		return true
+	}
+}
+
 // LimitFiles limits the number of files returned
 func LimitFiles(files []FileInfo, limit int) []FileInfo {
 	if limit <= 0 || len(files) <= limit {
@@ -243,9 +286,9 @@ func LimitFiles(files []FileInfo, limit int) []FileInfo {
 	return files[:limit]
 }
 
-// calculateChecksum calculates SHA256 checksum of a file
+// calculateChecksum calculates SHA256 checksum of a file or archive entry
 func calculateChecksum(filePath string) (string, error) {
-	file, err := iofacade.Open(filePath)
+	file, err := OpenReplayFile(filePath)
 	if err != nil {
 		return "", err
 	}
@@ -262,8 +305,9 @@ func calculateChecksum(filePath string) (string, error) {
 // NewFileInfoFromPath stats the path and computes its checksum, returning a FileInfo
 // shaped like one produced by GetReplayFiles. Used by paths that already know which
 // .rep file to ingest (e.g. bulk re-analyze) and don't want to walk a directory.
+// filePath may be an archive entry path.
 func NewFileInfoFromPath(filePath string) (*FileInfo, error) {
-	info, err := iofacade.Stat(filePath)
+	info, err := StatReplayFile(filePath)
 	if err != nil {
 		return nil, err
 	}
@@ -3,6 +3,7 @@ package fileops
 import (
 	"context"
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -33,6 +34,39 @@ func TestGetReplayFiles_IgnoresLastReplay(t *testing.T) {
 	}
 }
 
+func TestStreamReplayFiles_StopsEarly(t *testing.T) {
+	rootDir := t.TempDir()
+	for _, name := range []string{"a.rep", "b.rep", "c.rep"} {
+		if err := os.WriteFile(filepath.Join(rootDir, name), []byte(name), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	errStop := errors.New("stop")
+	var seen []string
+	err := StreamReplayFiles(context.Background(), rootDir, func(f FileInfo) error {
+		seen = append(seen, f.Name)
+		if len(seen) == 2 {
+			return errStop
+		}
+		return nil
+	})
+	if !errors.Is(err, errStop) || len(seen) != 2 {
+		t.Fatalf("callback error should stop the walk: err=%v seen=%v", err, seen)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	seen = nil
+	err = StreamReplayFiles(ctx, rootDir, func(f FileInfo) error {
+		seen = append(seen, f.Name)
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) || len(seen) != 1 {
+		t.Fatalf("cancellation should stop the walk: err=%v seen=%v", err, seen)
+	}
+}
+
 func TestWalkReplayFiles_NoChecksum(t *testing.T) {
 	rootDir := t.TempDir()
 	if err := os.WriteFile(filepath.Join(rootDir, "a.rep"), []byte("a"), 0o644); err != nil {
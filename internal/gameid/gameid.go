@@ -0,0 +1,112 @@
+// Package gameid recognizes replays that record the same game.
+//
+// When several players of one game each save a replay, the files differ
+// byte-for-byte (each POV has its own header quirks and stops recording when
+// its owner leaves), so their checksums never match. What they do share is
+// everything the lockstep simulation shares: the map, the lobby (slots,
+// names, races, teams) and the command stream every client executed.
+//
+// Fingerprint hashes exactly that, restricted to the first EarlyWindowFrames
+// frames of unit commands. The frame count is deliberately left out — it
+// differs per POV — and so is the start time, which can drift between
+// machines; storage compares start times with a tolerance instead and uses
+// the frame count to pick the group's canonical (longest) recording.
+package gameid
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"sort"
+
+	"github.com/icza/screp/rep"
+	"github.com/icza/screp/rep/repcmd"
+)
+
+// version is mixed into every fingerprint. Bump it whenever the hashed input
+// changes so fingerprints from different algorithms never collide.
+const version = "gameid/v1"
+
+// EarlyWindowFrames bounds the command prefix that is hashed: about two
+// minutes on Fastest. Every POV of a game that lasted this long recorded the
+// same commands up to here.
+const EarlyWindowFrames = 2880
+
+// fingerprintedCommands are the command types hashed into the fingerprint.
+// They are issued by players and replicated verbatim to every client; chat,
+// keep-alives, sync and latency commands are left out because POVs may
+// record them differently.
+var fingerprintedCommands = map[byte]bool{
+	repcmd.TypeIDSelect:           true,
+	repcmd.TypeIDSelectAdd:        true,
+	repcmd.TypeIDSelectRemove:     true,
+	repcmd.TypeIDSelect121:        true,
+	repcmd.TypeIDBuild:            true,
+	repcmd.TypeIDHotkey:           true,
+	repcmd.TypeIDRightClick:       true,
+	repcmd.TypeIDRightClick121:    true,
+	repcmd.TypeIDTargetedOrder:    true,
+	repcmd.TypeIDTargetedOrder121: true,
+	repcmd.TypeIDTrain:            true,
+	repcmd.TypeIDUnitMorph:        true,
+	repcmd.TypeIDBuildingMorph:    true,
+}
+
+// Fingerprint returns a hex digest identifying the game r recorded, or "" if
+// r lacks the header needed to identify it. Replays of the same game from
+// different POVs get the same fingerprint.
+func Fingerprint(r *rep.Replay) string {
+	if r == nil || r.Header == nil {
+		return ""
+	}
+	h := sha256.New()
+	writeString := func(s string) {
+		var n [4]byte
+		binary.LittleEndian.PutUint32(n[:], uint32(len(s)))
+		h.Write(n[:])
+		h.Write([]byte(s))
+	}
+
+	writeString(version)
+	writeString(r.Header.Map)
+
+	players := make([]*rep.Player, 0, len(r.Header.Players))
+	for _, p := range r.Header.Players {
+		if p != nil {
+			players = append(players, p)
+		}
+	}
+	sort.Slice(players, func(i, j int) bool { return players[i].SlotID < players[j].SlotID })
+	for _, p := range players {
+		race := ""
+		if p.Race != nil {
+			race = p.Race.Name
+		}
+		observer := byte(0)
+		if p.Observer {
+			observer = 1
+		}
+		h.Write([]byte{byte(p.SlotID), byte(p.SlotID >> 8), p.ID, p.Team, observer})
+		writeString(p.Name)
+		writeString(race)
+	}
+
+	if r.Commands != nil {
+		var buf [6]byte
+		for _, cmd := range r.Commands.Cmds {
+			base := cmd.BaseCmd()
+			if base.Frame >= EarlyWindowFrames {
+				break
+			}
+			if base.Type == nil || !fingerprintedCommands[base.Type.ID] {
+				continue
+			}
+			binary.LittleEndian.PutUint32(buf[:4], uint32(base.Frame))
+			buf[4] = base.PlayerID
+			buf[5] = base.Type.ID
+			h.Write(buf[:])
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
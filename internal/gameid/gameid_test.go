@@ -0,0 +1,88 @@
+package gameid
+
+import (
+	"testing"
+	"time"
+
+	"github.com/icza/screp/rep"
+	"github.com/icza/screp/rep/repcmd"
+	"github.com/icza/screp/rep/repcore"
+)
+
+// pov builds a minimal two-player replay as one player's client would have
+// recorded it.
+func pov(start time.Time, frames repcore.Frame, cmds ...repcmd.Cmd) *rep.Replay {
+	return &rep.Replay{
+		Header: &rep.Header{
+			StartTime: start,
+			Frames:    frames,
+			Map:       "Fighting Spirit",
+			Players: []*rep.Player{
+				{SlotID: 1, ID: 1, Name: "Flash", Race: repcore.RaceTerran, Team: 1},
+				{SlotID: 0, ID: 0, Name: "Jaedong", Race: repcore.RaceZerg, Team: 2},
+			},
+		},
+		Commands: &rep.Commands{Cmds: cmds},
+	}
+}
+
+func cmd(frame repcore.Frame, player byte, typeID byte) repcmd.Cmd {
+	return &repcmd.Base{Frame: frame, PlayerID: player, Type: repcmd.TypeByID(typeID)}
+}
+
+func TestFingerprintMatchesAcrossPOVs(t *testing.T) {
+	start := time.Date(2026, 4, 17, 11, 52, 20, 0, time.UTC)
+	shared := []repcmd.Cmd{
+		cmd(10, 0, repcmd.TypeIDSelect),
+		cmd(12, 0, repcmd.TypeIDTrain),
+		cmd(40, 1, repcmd.TypeIDBuild),
+	}
+
+	// The other POV saw its clock a few seconds off, recorded its owner's
+	// chat differently, kept recording long after the first one stopped, and
+	// carries commands past the early window.
+	first := pov(start, 5000, shared...)
+	second := pov(start.Add(3*time.Second), 9000, append(append([]repcmd.Cmd{}, shared...),
+		cmd(41, 1, repcmd.TypeIDChat),
+		cmd(EarlyWindowFrames+1, 1, repcmd.TypeIDTrain),
+	)...)
+
+	a, b := Fingerprint(first), Fingerprint(second)
+	if a == "" {
+		t.Fatal("Fingerprint returned empty for a valid replay")
+	}
+	if a != b {
+		t.Fatalf("POVs of the same game fingerprint differently: %s vs %s", a, b)
+	}
+}
+
+func TestFingerprintDistinguishesGames(t *testing.T) {
+	start := time.Date(2026, 4, 17, 11, 52, 20, 0, time.UTC)
+	base := Fingerprint(pov(start, 5000, cmd(10, 0, repcmd.TypeIDTrain)))
+
+	otherOpening := Fingerprint(pov(start, 5000, cmd(11, 0, repcmd.TypeIDTrain)))
+	if otherOpening == base {
+		t.Error("different early command streams should fingerprint differently")
+	}
+
+	renamed := pov(start, 5000, cmd(10, 0, repcmd.TypeIDTrain))
+	renamed.Header.Players[0].Name = "Bisu"
+	if Fingerprint(renamed) == base {
+		t.Error("different players should fingerprint differently")
+	}
+
+	otherMap := pov(start, 5000, cmd(10, 0, repcmd.TypeIDTrain))
+	otherMap.Header.Map = "Circuit Breaker"
+	if Fingerprint(otherMap) == base {
+		t.Error("different maps should fingerprint differently")
+	}
+}
+
+func TestFingerprintWithoutHeader(t *testing.T) {
+	if got := Fingerprint(nil); got != "" {
+		t.Errorf("Fingerprint(nil) = %q, want empty", got)
+	}
+	if got := Fingerprint(&rep.Replay{}); got != "" {
+		t.Errorf("Fingerprint(no header) = %q, want empty", got)
+	}
+}
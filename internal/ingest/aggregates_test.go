@@ -0,0 +1,101 @@
+package ingest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/marianogappa/screpdb/internal/storage"
+)
+
+// aggregateRows returns every row of table in dbPath as a string, floats
+// rounded so sums taken in a different order compare.
+func aggregateRows(t *testing.T, dbPath, table string) []string {
+	t.Helper()
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+	rows, err := db.Query("SELECT * FROM " + table)
+	if err != nil {
+		t.Fatalf("query %s: %v", table, err)
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		t.Fatalf("columns %s: %v", table, err)
+	}
+	var out []string
+	for rows.Next() {
+		values := make([]any, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			t.Fatalf("scan %s: %v", table, err)
+		}
+		fields := make([]string, len(values))
+		for i, v := range values {
+			if f, ok := v.(float64); ok {
+				fields[i] = fmt.Sprintf("%.6f", f)
+			} else {
+				fields[i] = fmt.Sprint(v)
+			}
+		}
+		out = append(out, strings.Join(fields, "|"))
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("read %s: %v", table, err)
+	}
+	slices.Sort(out)
+	return out
+}
+
+var playerAggregateTables = []string{"player_game_facts", "player_aggregates", "player_matchup_aggregates", "player_marker_counts"}
+
+func TestRun_MaintainsPlayerAggregates(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "screp.db")
+	if err := Run(ctx, Config{InputDir: seedReplayDir(t, smallTestReplays...), SQLitePath: dbPath, Logger: quietLogger()}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	incremental := map[string][]string{}
+	for _, table := range playerAggregateTables {
+		incremental[table] = aggregateRows(t, dbPath, table)
+	}
+	if len(incremental["player_aggregates"]) == 0 {
+		t.Fatal("ingest left player_aggregates empty")
+	}
+	humans := countRows(t, dbPath, "players WHERE is_observer = 0 AND lower(trim(coalesce(type, ''))) = 'human'")
+	if got := int64(len(incremental["player_game_facts"])); got != humans {
+		t.Errorf("player_game_facts has %d rows, want one per human player (%d)", got, humans)
+	}
+
+	store, err := storage.NewSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer store.Close()
+	if current, err := store.PlayerAggregatesCurrent(ctx); err != nil || !current {
+		t.Fatalf("PlayerAggregatesCurrent = %v, %v; want true", current, err)
+	}
+	stats, err := store.RebuildPlayerAggregates(ctx)
+	if err != nil {
+		t.Fatalf("RebuildPlayerAggregates: %v", err)
+	}
+	if stats.Games != humans || stats.Players == 0 {
+		t.Errorf("rebuild stats = %+v, want %d games", stats, humans)
+	}
+	for _, table := range playerAggregateTables {
+		if rebuilt := aggregateRows(t, dbPath, table); !slices.Equal(rebuilt, incremental[table]) {
+			t.Errorf("%s: incremental maintenance diverges from a rebuild (%d rows vs %d)", table, len(incremental[table]), len(rebuilt))
+		}
+	}
+}
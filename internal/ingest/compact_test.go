@@ -0,0 +1,166 @@
+package ingest
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/marianogappa/screpdb/internal/storage"
+)
+
+// lowValueRows returns the low-value commands of table (or view) in dbPath,
+// keyed by replay checksum and player slot so databases ingested separately
+// compare, ordered by orderBy.
+func lowValueRows(t *testing.T, dbPath, table, orderBy string) []string {
+	t.Helper()
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+	rows, err := db.Query(`
+		SELECT json_array(r.file_checksum, p.slot_id, c.frame, c.seconds_from_game_start, c.action_type, c.x, c.y,
+			c.is_queued, c.order_name, c.unit_type, c.unit_types, c.hotkey_type, c.hotkey_group, c.vision_player_ids, c.alliance_player_ids)
+		FROM ` + table + ` c
+		JOIN replays r ON r.id = c.replay_id
+		JOIN players p ON p.id = c.player_id
+		ORDER BY ` + orderBy)
+	if err != nil {
+		t.Fatalf("query %s: %v", table, err)
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var row string
+		if err := rows.Scan(&row); err != nil {
+			t.Fatalf("scan %s: %v", table, err)
+		}
+		out = append(out, row)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("read %s: %v", table, err)
+	}
+	return out
+}
+
+// byContent orders lowValueRows by everything but storage order.
+const byContent = "r.file_checksum, p.slot_id, c.frame, c.action_type, c.x, c.y, c.hotkey_type, c.hotkey_group, c.unit_types"
+
+func TestRun_CompactCommands(t *testing.T) {
+	ctx := context.Background()
+	rowsDB := filepath.Join(t.TempDir(), "rows.db")
+	if err := Run(ctx, Config{InputDir: seedReplayDir(t, smallTestReplays...), SQLitePath: rowsDB, StoreRightClicks: true, Logger: quietLogger()}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	inputDir := seedReplayDir(t, smallTestReplays...)
+	compactDB := filepath.Join(t.TempDir(), "compact.db")
+	if err := Run(ctx, Config{InputDir: inputDir, SQLitePath: compactDB, StoreRightClicks: true, CompactCommands: true, Logger: quietLogger()}); err != nil {
+		t.Fatalf("Run (CompactCommands): %v", err)
+	}
+
+	if got := countLowValueByAction(t, compactDB, "Right Click") + countLowValueByAction(t, compactDB, "Hotkey"); got != 0 {
+		t.Errorf("CompactCommands left %d Right Click/Hotkey rows in commands_low_value", got)
+	}
+	if got := countRows(t, compactDB, "command_blobs"); got != int64(len(smallTestReplays)) {
+		t.Errorf("command_blobs has %d rows, want one per replay", got)
+	}
+	want := lowValueRows(t, rowsDB, "commands_low_value", byContent)
+	if got := lowValueRows(t, compactDB, "commands_low_value_all", byContent); !slices.Equal(got, want) {
+		t.Fatalf("commands_low_value_all of the compacted database has %d rows, want the %d the rows database stores", len(got), len(want))
+	}
+
+	// Analysis rebuilt from blobs matches analysis of the files.
+	events := replayEventRows(t, compactDB)
+	execSQL(t, compactDB, "DELETE FROM replay_events")
+	if _, err := Reanalyze(ctx, ReanalyzeConfig{SQLitePath: compactDB, ReplayIDs: []int64{1, 2}, FromDatabase: true, Logger: quietLogger()}); err != nil {
+		t.Fatalf("Reanalyze from database: %v", err)
+	}
+	if got := replayEventRows(t, compactDB); !slices.Equal(got, events) {
+		t.Fatalf("re-analysis from compacted commands stored %d events, want %d", len(got), len(events))
+	}
+
+	// Merging carries blobs over, with their player IDs remapped.
+	mergedDB := filepath.Join(t.TempDir(), "merged.db")
+	merged, err := storage.NewSQLiteStorage(mergedDB)
+	if err != nil {
+		t.Fatalf("open merged: %v", err)
+	}
+	defer merged.Close()
+	if err := merged.Initialize(ctx, false, false); err != nil {
+		t.Fatalf("Initialize merged: %v", err)
+	}
+	if _, err := merged.MergeFrom(ctx, rowsDB); err != nil {
+		t.Fatalf("MergeFrom rows: %v", err)
+	}
+	execSQL(t, mergedDB, "DELETE FROM replays")
+	stats, err := merged.MergeFrom(ctx, compactDB)
+	if err != nil {
+		t.Fatalf("MergeFrom compact: %v", err)
+	}
+	if stats.CommandBlobs != int64(len(smallTestReplays)) {
+		t.Errorf("merge copied %d command blobs, want %d", stats.CommandBlobs, len(smallTestReplays))
+	}
+	if got := lowValueRows(t, mergedDB, "commands_low_value_all", byContent); !slices.Equal(got, want) {
+		t.Fatalf("merged commands_low_value_all has %d rows, want %d", len(got), len(want))
+	}
+}
+
+func TestCompactAndExpandCommands(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "x.db")
+	if err := Run(ctx, Config{InputDir: seedReplayDir(t, smallTestReplays...), SQLitePath: dbPath, StoreRightClicks: true, Logger: quietLogger()}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	inOrder := lowValueRows(t, dbPath, "commands_low_value", "r.file_checksum, c.id")
+	byValue := lowValueRows(t, dbPath, "commands_low_value", byContent)
+
+	store, err := storage.NewSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer store.Close()
+	stats, err := store.CompactCommands(ctx)
+	if err != nil {
+		t.Fatalf("CompactCommands: %v", err)
+	}
+	if stats.Replays != int64(len(smallTestReplays)) || stats.Rows == 0 {
+		t.Fatalf("CompactCommands = %+v", stats)
+	}
+	if got := lowValueRows(t, dbPath, "commands_low_value_all", byContent); !slices.Equal(got, byValue) {
+		t.Fatalf("commands_low_value_all after compacting has %d rows, want %d", len(got), len(byValue))
+	}
+	if again, err := store.CompactCommands(ctx); err != nil || again.Replays != 0 {
+		t.Fatalf("second CompactCommands = %+v, %v; want nothing to do", again, err)
+	}
+	if _, _, err := store.Vacuum(ctx); err != nil {
+		t.Fatalf("Vacuum: %v", err)
+	}
+
+	expanded, err := store.ExpandCommands(ctx)
+	if err != nil {
+		t.Fatalf("ExpandCommands: %v", err)
+	}
+	if expanded != stats {
+		t.Errorf("ExpandCommands = %+v, want %+v", expanded, stats)
+	}
+	if got := countRows(t, dbPath, "command_blobs"); got != 0 {
+		t.Errorf("command_blobs has %d rows after expanding", got)
+	}
+	if got := lowValueRows(t, dbPath, "commands_low_value", "r.file_checksum, c.id"); !slices.Equal(got, inOrder) {
+		t.Fatalf("expanded commands_low_value differs from the original (%d rows, want %d in the same order)", len(got), len(inOrder))
+	}
+}
+
+func TestRun_CompactCommandsNeedsSQLite(t *testing.T) {
+	err := Run(context.Background(), Config{
+		InputDir:        seedReplayDir(t, smallTestReplays...),
+		DatabaseURL:     "postgres://localhost/none",
+		CompactCommands: true,
+		Logger:          quietLogger(),
+	})
+	if err == nil {
+		t.Fatal("expected CompactCommands with a DatabaseURL to fail")
+	}
+}
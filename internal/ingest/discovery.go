@@ -0,0 +1,298 @@
+package ingest
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/marianogappa/screpdb/internal/buildinfo"
+	"github.com/marianogappa/screpdb/internal/fileops"
+	"github.com/marianogappa/screpdb/internal/storage"
+)
+
+const (
+	// discoveryBatchSize caps how many files one database pre-check or
+	// hashing round handles. Smaller hashing rounds run whenever the walk has
+	// nothing else ready, so the first replays reach the parsers right away.
+	discoveryBatchSize = 100
+
+	// discoveryQueueSize bounds the files buffered between pipeline stages.
+	discoveryQueueSize = 256
+
+	// discoveryProgressInterval is how often batch mode logs a progress line.
+	discoveryProgressInterval = 5 * time.Second
+)
+
+// discoveryStats counts what the batch pipeline did with the files it found,
+// for progress lines and the final summary. Safe for concurrent use.
+type discoveryStats struct {
+	walked        atomic.Int64 // replay files the walk found
+	filtered      atomic.Int64 // left out by the date filters or StopAfterN
+	unchanged     atomic.Int64 // known paths with an unchanged stamp; never read
+	hashed        atomic.Int64
+	existing      atomic.Int64 // hashed, then found in the database
+	knownFailures atomic.Int64 // hashed, then found in the failure ledger
+	queued        atomic.Int64 // sent to the parsers
+	done          atomic.Int64 // parsed, skipped as UMS or failed
+
+	scanned     atomic.Bool  // the walk is over, so queued is final
+	firstQueued atomic.Int64 // Unix nanoseconds the first file was queued
+}
+
+// skipped returns how many files were found but not parsed because the
+// database or the failure ledger already had them.
+func (s *discoveryStats) skipped() int64 {
+	return s.unchanged.Load() + s.existing.Load() + s.knownFailures.Load()
+}
+
+func (s *discoveryStats) markQueued(now time.Time) {
+	s.firstQueued.CompareAndSwap(0, now.UnixNano())
+	s.queued.Add(1)
+}
+
+// eta estimates how long the queued replays left take to parse at the rate
+// parsing has gone since the first was queued. While the walk is still going
+// more replays may come, so the estimate is a lower bound.
+func (s *discoveryStats) eta(now time.Time) (time.Duration, bool) {
+	first, done := s.firstQueued.Load(), s.done.Load()
+	if first == 0 || done == 0 {
+		return 0, false
+	}
+	elapsed := now.Sub(time.Unix(0, first))
+	remaining := s.queued.Load() - done
+	return time.Duration(float64(elapsed) / float64(done) * float64(remaining)), true
+}
+
+// progressLine describes the run so far, e.g. "Scanned 1200 files so far: 900
+// unchanged, 300 hashed, 20 already stored; parsed 150 of 280 new replays
+// (12.5/s), ETA at least 10s".
+func (s *discoveryStats) progressLine(now time.Time) string {
+	var b strings.Builder
+	scanned := s.scanned.Load()
+	fmt.Fprintf(&b, "Scanned %d files", s.walked.Load())
+	if !scanned {
+		b.WriteString(" so far")
+	}
+	fmt.Fprintf(&b, ": %d unchanged, %d hashed, %d already stored; parsed %d of %d new replays",
+		s.unchanged.Load(), s.hashed.Load(), s.existing.Load()+s.knownFailures.Load(), s.done.Load(), s.queued.Load())
+	if first := s.firstQueued.Load(); first != 0 {
+		if elapsed := now.Sub(time.Unix(0, first)); elapsed > 0 {
+			fmt.Fprintf(&b, " (%.1f/s)", float64(s.done.Load())/elapsed.Seconds())
+		}
+	}
+	if eta, ok := s.eta(now); ok {
+		b.WriteString(", ETA ")
+		if !scanned {
+			b.WriteString("at least ")
+		}
+		b.WriteString(eta.Round(time.Second).String())
+	}
+	return b.String()
+}
+
+// reportProgress logs a progress line every interval until the returned stop
+// function is called.
+func (s *discoveryStats) reportProgress(logger *Logger, interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	quit := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		for {
+			select {
+			case now := <-ticker.C:
+				logger.Infof("%s", s.progressLine(now))
+			case <-quit:
+				return
+			}
+		}
+	}()
+	return func() {
+		ticker.Stop()
+		close(quit)
+		<-finished
+	}
+}
+
+// walkForIngest walks cfg.InputDir and sends the replays that pass include,
+// newest first, once the walk is over. Files the database already has under
+// the same path, size and modification time are dropped in rounds of
+// discoveryBatchSize as the walk goes, so only new and changed files are held
+// in memory meanwhile. With cfg.StopAfterN only the StopAfterN newest are
+// checked and sent.
+func walkForIngest(ctx context.Context, store storage.Storage, cfg Config, include func(fileops.FileInfo) bool, stats *discoveryStats, out chan<- fileops.FileInfo) error {
+	var pending, batch []fileops.FileInfo
+	flush := func() error {
+		changed, err := store.FilterOutUnchangedReplayFiles(ctx, batch)
+		if err != nil {
+			return fmt.Errorf("failed to check existing replays by path: %w", err)
+		}
+		stats.unchanged.Add(int64(len(batch) - len(changed)))
+		batch = batch[:0]
+		pending = append(pending, changed...)
+		return nil
+	}
+	check := func(file fileops.FileInfo) error {
+		batch = append(batch, file)
+		if len(batch) < discoveryBatchSize {
+			return nil
+		}
+		return flush()
+	}
+
+	var newest newestFiles
+	var included int64
+	err := fileops.StreamReplayFiles(ctx, cfg.InputDir, func(file fileops.FileInfo) error {
+		stats.walked.Add(1)
+		if !include(file) {
+			stats.filtered.Add(1)
+			return nil
+		}
+		if cfg.StopAfterN <= 0 {
+			return check(file)
+		}
+		included++
+		newest.offer(file, cfg.StopAfterN)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get replay files: %w", err)
+	}
+	stats.filtered.Add(included - int64(newest.Len()))
+	for _, file := range newest.sorted() {
+		if err := check(file); err != nil {
+			return err
+		}
+	}
+	if len(batch) > 0 {
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+
+	fileops.SortFilesByModTime(pending)
+	for _, file := range pending {
+		select {
+		case out <- file:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	stats.scanned.Store(true)
+	return nil
+}
+
+// checkDiscovered drains in, drops the files the database or the failure
+// ledger already has, and sends the rest, hashed, to out. Files are hashed
+// and checked by checksum in rounds of up to discoveryBatchSize; known paths
+// with an unchanged stamp never get here (see walkForIngest).
+func checkDiscovered(ctx context.Context, store storage.Storage, cfg Config, logger *Logger, stats *discoveryStats, in <-chan fileops.FileInfo, out chan<- fileops.FileInfo) error {
+	batch := make([]fileops.FileInfo, 0, discoveryBatchSize)
+	flush := func() error {
+		fresh, err := checkDiscoveredBatch(ctx, store, cfg, logger, stats, batch)
+		batch = batch[:0]
+		if err != nil {
+			return err
+		}
+		for _, file := range fresh {
+			select {
+			case out <- file:
+				stats.markQueued(time.Now())
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	}
+	for file := range in {
+		batch = append(batch, file)
+		// Flush full rounds, and smaller ones whenever the walk has nothing
+		// else ready yet.
+		if len(batch) == discoveryBatchSize || len(in) == 0 {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if len(batch) > 0 {
+		return flush()
+	}
+	return nil
+}
+
+// checkDiscoveredBatch returns the files of batch that are new to the
+// database and to the failure ledger, hashed.
+func checkDiscoveredBatch(ctx context.Context, store storage.Storage, cfg Config, logger *Logger, stats *discoveryStats, batch []fileops.FileInfo) ([]fileops.FileInfo, error) {
+	if len(batch) == 0 {
+		return nil, nil
+	}
+
+	hashed, err := fileops.HashFiles(ctx, batch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash candidate files: %w", err)
+	}
+	stats.hashed.Add(int64(len(hashed)))
+
+	fresh, err := store.FilterOutExistingReplays(ctx, hashed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existing replays: %w", err)
+	}
+	stats.existing.Add(int64(len(hashed) - len(fresh)))
+	if len(fresh) < len(hashed) {
+		// Some were ingested already and only touched or copied: record
+		// their new stamps so the next run skips them unread. Stamps only
+		// save work, so failing to store them mustn't fail the run.
+		if err := store.RefreshReplayFileStamps(ctx, hashed); err != nil {
+			logger.Errorf("Failed to refresh replay file stamps: %v", err)
+		}
+	}
+
+	if cfg.RetryFailed || len(fresh) == 0 {
+		return fresh, nil
+	}
+	retried, err := store.FilterOutKnownIngestFailures(ctx, fresh, buildinfo.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check ingest failures: %w", err)
+	}
+	stats.knownFailures.Add(int64(len(fresh) - len(retried)))
+	return retried, nil
+}
+
+// newestFiles keeps the n files with the latest ModTime offered to it, as a
+// min-heap by ModTime so the oldest kept is the one to evict.
+type newestFiles []fileops.FileInfo
+
+func (h newestFiles) Len() int           { return len(h) }
+func (h newestFiles) Less(i, j int) bool { return h[i].ModTime.Before(h[j].ModTime) }
+func (h newestFiles) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *newestFiles) Push(x any)        { *h = append(*h, x.(fileops.FileInfo)) }
+func (h *newestFiles) Pop() any {
+	old := *h
+	file := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return file
+}
+
+// offer keeps file if it's among the n newest seen so far, evicting the
+// oldest kept file to make room.
+func (h *newestFiles) offer(file fileops.FileInfo, n int) {
+	if h.Len() < n {
+		heap.Push(h, file)
+		return
+	}
+	if !file.ModTime.After((*h)[0].ModTime) {
+		return
+	}
+	(*h)[0] = file
+	heap.Fix(h, 0)
+}
+
+// sorted returns the kept files newest first.
+func (h newestFiles) sorted() []fileops.FileInfo {
+	files := append([]fileops.FileInfo(nil), h...)
+	fileops.SortFilesByModTime(files)
+	return files
+}
@@ -0,0 +1,136 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marianogappa/screpdb/internal/fileops"
+	"github.com/marianogappa/screpdb/internal/iofacade"
+	"github.com/marianogappa/screpdb/internal/storage"
+)
+
+func TestNewestFiles_KeepsNewestNewestFirst(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var h newestFiles
+	for _, day := range []int{3, 9, 1, 7, 5, 8, 2} {
+		h.offer(fileops.FileInfo{Name: fmt.Sprintf("day%d.rep", day), ModTime: base.AddDate(0, 0, day)}, 3)
+	}
+	var got []string
+	for _, f := range h.sorted() {
+		got = append(got, f.Name)
+	}
+	if want := "day9.rep,day8.rep,day7.rep"; strings.Join(got, ",") != want {
+		t.Fatalf("kept %v, want %s", got, want)
+	}
+}
+
+func TestDiscoveryStats_ProgressLineAndETA(t *testing.T) {
+	var s discoveryStats
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s.walked.Store(1200)
+	s.unchanged.Store(900)
+	s.hashed.Store(300)
+	s.existing.Store(20)
+	if line := s.progressLine(now); strings.Contains(line, "ETA") || !strings.Contains(line, "so far") {
+		t.Fatalf("no ETA before anything is parsed, got %q", line)
+	}
+
+	for range 280 {
+		s.markQueued(now)
+	}
+	s.done.Store(140)
+	later := now.Add(20 * time.Second)
+	eta, ok := s.eta(later)
+	if !ok || eta != 20*time.Second {
+		t.Fatalf("eta = %v, %v; want 20s: half the queue took 20s", eta, ok)
+	}
+	want := "Scanned 1200 files so far: 900 unchanged, 300 hashed, 20 already stored; parsed 140 of 280 new replays (7.0/s), ETA at least 20s"
+	if got := s.progressLine(later); got != want {
+		t.Fatalf("progressLine =\n  %q\nwant\n  %q", got, want)
+	}
+
+	s.scanned.Store(true)
+	if got := s.progressLine(later); !strings.HasPrefix(got, "Scanned 1200 files: ") || !strings.HasSuffix(got, ", ETA 20s") {
+		t.Fatalf("a finished scan gives a firm ETA, got %q", got)
+	}
+}
+
+// seedTimedReplays writes n one-byte replays g0.rep..g<n-1>.rep to a new
+// folder, each a minute newer than the last, and returns the folder.
+func seedTimedReplays(t *testing.T, n int) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := iofacade.AllowDir(dir); err != nil {
+		t.Fatalf("AllowDir: %v", err)
+	}
+	base := time.Now().Add(-time.Hour)
+	for i := range n {
+		path := filepath.Join(dir, fmt.Sprintf("g%d.rep", i))
+		if err := os.WriteFile(path, []byte{byte(i)}, 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		mtime := base.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatalf("Chtimes: %v", err)
+		}
+	}
+	return dir
+}
+
+// emptyStore returns an initialized store with no replays.
+func emptyStore(t *testing.T) storage.Storage {
+	t.Helper()
+	store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "empty.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	if err := store.Initialize(context.Background(), false, false); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	return store
+}
+
+// walkNames runs walkForIngest and returns the names it sent, in order.
+func walkNames(t *testing.T, store storage.Storage, cfg Config, include func(fileops.FileInfo) bool, stats *discoveryStats) string {
+	t.Helper()
+	out := make(chan fileops.FileInfo, 16)
+	if err := walkForIngest(context.Background(), store, cfg, include, stats, out); err != nil {
+		t.Fatalf("walkForIngest: %v", err)
+	}
+	close(out)
+	var got []string
+	for f := range out {
+		got = append(got, f.Name)
+	}
+	return strings.Join(got, ",")
+}
+
+func TestWalkForIngest_SendsNewestFirst(t *testing.T) {
+	dir := seedTimedReplays(t, 5)
+	var stats discoveryStats
+	include := func(f fileops.FileInfo) bool { return f.Name != "g4.rep" }
+	if got := walkNames(t, emptyStore(t), Config{InputDir: dir}, include, &stats); got != "g3.rep,g2.rep,g1.rep,g0.rep" {
+		t.Fatalf("sent %s, want every included file, newest first", got)
+	}
+	if stats.walked.Load() != 5 || stats.filtered.Load() != 1 || !stats.scanned.Load() {
+		t.Fatalf("walked=%d filtered=%d scanned=%v, want 5/1/true", stats.walked.Load(), stats.filtered.Load(), stats.scanned.Load())
+	}
+}
+
+func TestWalkForIngest_StopAfterNSendsNewest(t *testing.T) {
+	dir := seedTimedReplays(t, 5)
+	var stats discoveryStats
+	include := func(f fileops.FileInfo) bool { return f.Name != "g4.rep" }
+	if got := walkNames(t, emptyStore(t), Config{InputDir: dir, StopAfterN: 2}, include, &stats); got != "g3.rep,g2.rep" {
+		t.Fatalf("sent %s, want the two newest included files, newest first", got)
+	}
+	if stats.walked.Load() != 5 || stats.filtered.Load() != 3 || !stats.scanned.Load() {
+		t.Fatalf("walked=%d filtered=%d scanned=%v, want 5/3/true", stats.walked.Load(), stats.filtered.Load(), stats.scanned.Load())
+	}
+}
@@ -0,0 +1,129 @@
+package ingest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/marianogappa/screpdb/internal/buildinfo"
+	"github.com/marianogappa/screpdb/internal/fileops"
+	"github.com/marianogappa/screpdb/internal/storage"
+)
+
+// panicError is the error runGuarded turns a recovered panic into. It keeps
+// the panic value and stack apart so the failure ledger can store the value as
+// the message and group crashes by stack.
+type panicError struct {
+	value any
+	stack []byte
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("panic while processing replay (this is a bug — please report it at "+
+		"https://github.com/marianogappa/screpdb/issues): %v\n%s", e.value, e.stack)
+}
+
+// failureLedger collects the per-file failures of one run so they can be
+// written to the ingest_failures table in one go once parsing is done. Safe for
+// concurrent use by the batch workers.
+type failureLedger struct {
+	mu       sync.Mutex
+	failures []storage.IngestFailure
+}
+
+// add records that file failed with err, classifying err by what went wrong.
+func (l *failureLedger) add(file *fileops.FileInfo, err error) {
+	f := storage.IngestFailure{
+		FilePath:       file.Path,
+		FileChecksum:   file.Checksum,
+		ErrorClass:     storage.IngestFailureParseError,
+		Message:        err.Error(),
+		ScrepdbVersion: buildinfo.Version,
+	}
+	var pe *panicError
+	switch {
+	case errors.Is(err, errSkippedUMS):
+		f.ErrorClass = storage.IngestFailureUMS
+	case errors.As(err, &pe):
+		f.ErrorClass = storage.IngestFailurePanic
+		f.Message = fmt.Sprint(pe.value)
+		f.StackHash = stackHash(pe.stack)
+	}
+	l.mu.Lock()
+	l.failures = append(l.failures, f)
+	l.mu.Unlock()
+}
+
+// flush writes the collected failures to the ledger, then drops the rows of
+// files that have since been ingested. Ledger errors are logged, not returned:
+// losing a ledger row must never fail an otherwise successful ingest.
+func (l *failureLedger) flush(ctx context.Context, store storage.Storage, logger *Logger) {
+	// Failures seen before a cancellation are still worth keeping.
+	ctx = context.WithoutCancel(ctx)
+
+	l.mu.Lock()
+	failures := l.failures
+	l.failures = nil
+	l.mu.Unlock()
+
+	if err := store.RecordIngestFailures(ctx, failures); err != nil {
+		logger.Errorf("Failed to record ingest failures: %v", err)
+	} else if len(failures) > 0 {
+		logger.Warnf("Recorded %d failed or skipped replays (see `screpdb failures`)", len(failures))
+	}
+	if _, err := store.ClearResolvedIngestFailures(ctx); err != nil {
+		logger.Errorf("%v", err)
+	}
+}
+
+// filterKnownFailures drops hashed files the ledger says already failed under
+// this screpdb version, unless cfg.RetryFailed asks to re-attempt them.
+func filterKnownFailures(ctx context.Context, store storage.Storage, cfg Config, files []fileops.FileInfo, logger *Logger) ([]fileops.FileInfo, error) {
+	if cfg.RetryFailed {
+		return files, nil
+	}
+	filtered, err := store.FilterOutKnownIngestFailures(ctx, files, buildinfo.Version)
+	if err != nil {
+		return nil, err
+	}
+	if skipped := len(files) - len(filtered); skipped > 0 {
+		logger.Warnf("Skipping %d replays that already failed with this version (use --retry-failed to re-attempt)", skipped)
+	}
+	return filtered, nil
+}
+
+// stackHash fingerprints a panic stack by its function frames only. Argument
+// values, goroutine IDs and file:line suffixes are dropped so the same crash
+// site hashes the same across runs and across unrelated code edits.
+func stackHash(stack []byte) string {
+	var frames []string
+	for _, line := range strings.Split(string(stack), "\n") {
+		if line == "" || strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "goroutine ") {
+			continue
+		}
+		if i := strings.LastIndex(line, "("); i > 0 {
+			line = line[:i]
+		}
+		frames = append(frames, line)
+	}
+	sum := sha256.Sum256([]byte(strings.Join(frames, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ListFailures returns the ingest_failures ledger of the database at
+// sqlitePath, most recently failed first.
+func ListFailures(ctx context.Context, sqlitePath string) ([]storage.IngestFailure, error) {
+	store, err := storage.NewSQLiteStorage(sqlitePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SQLite storage: %w", err)
+	}
+	defer store.Close()
+	if err := store.Initialize(ctx, false, false); err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	return store.ListIngestFailures(ctx)
+}
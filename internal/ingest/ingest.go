@@ -8,7 +8,7 @@ import (
 	"runtime"
 	"runtime/debug"
 	"runtime/pprof"
-	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/marianogappa/screpdb/internal/appdata"
@@ -53,6 +53,35 @@ type Config struct {
 	// for the duration of Run. Sourced from SCREPDB_INGEST_PPROF by
 	// cmd/ingest.go. Inspect with `go tool pprof <path>`.
 	CPUProfilePath string
+
+	// Watch keeps Run going after the initial batch, ingesting replays as
+	// they appear under InputDir until ctx is cancelled. StopAfterN and the
+	// date filters only apply to the initial batch.
+	Watch bool
+
+	// WatchPollInterval and WatchSettle tune watch mode: how often the
+	// folder is re-scanned, and how long a file must stay unchanged before
+	// it's treated as fully written. Zero uses the defaults (2s / 3s).
+	WatchPollInterval time.Duration
+	WatchSettle       time.Duration
+
+	// RetryFailed re-attempts files recorded in the ingest_failures ledger
+	// even when they already failed under the running screpdb version.
+	// Without it such files are skipped until the version or file changes.
+	RetryFailed bool
+
+	// DatabaseURL, when non-empty, stores replays in the PostgreSQL database
+	// it points at instead of the SQLite file at SQLitePath.
+	DatabaseURL string
+
+	// CompactCommands stores each replay's Right Click, Hotkey and Minimap
+	// Ping commands as one compressed blob instead of commands_low_value
+	// rows (see `screpdb compact`). SQLite only.
+	CompactCommands bool
+
+	// Workers is how many replays are parsed and analyzed at once, feeding
+	// the single storage writer. Zero or less uses GOMAXPROCS.
+	Workers int
 }
 
 func Run(ctx context.Context, cfg Config) error {
@@ -75,13 +104,12 @@ func Run(ctx context.Context, cfg Config) error {
 	}
 
 	// Initialize storage
-	logger.Infof("Using SQLite storage at %s", cfg.SQLitePath)
-	store, err := storage.NewSQLiteStorage(cfg.SQLitePath)
+	store, err := openStorage(cfg, logger)
 	if err != nil {
-		return fmt.Errorf("failed to create SQLite storage: %w", err)
+		return err
 	}
 	defer store.Close()
-	store.SetCommandStorageOptions(cfg.StoreRightClicks, cfg.SkipHotkeys)
+	store.SetCommandStorageOptions(cfg.StoreRightClicks, cfg.SkipHotkeys, cfg.CompactCommands)
 
 	if err := store.Initialize(ctx, cfg.Clean, cfg.CleanDashboard); err != nil {
 		return fmt.Errorf("failed to initialize storage: %w", err)
@@ -90,7 +118,54 @@ func Run(ctx context.Context, cfg Config) error {
 	sink := profile.NewSink(cfg.ProfileMode)
 	defer sink.Aggregate()
 
-	return runBatchMode(ctx, store, cfg, logger, sink)
+	if !cfg.Watch {
+		return runBatchMode(ctx, store, cfg, logger, sink)
+	}
+
+	// Snapshot the folder before the batch pass: anything that shows up
+	// while the batch runs is new to the watcher and gets picked up (and
+	// deduped) on its first poll, so no replay falls between the two.
+	initial, err := fileops.WalkReplayFiles(cfg.InputDir)
+	if errors.Is(err, os.ErrNotExist) {
+		// StarCraft creates the folder with the first saved replay, so a
+		// fresh install starts with nothing to ingest and waits for it.
+		logger.Warnf("%s doesn't exist yet; waiting for the first replay", cfg.InputDir)
+		return runWatchMode(ctx, store, cfg, logger, sink, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get replay files: %w", err)
+	}
+	if err := runBatchMode(ctx, store, cfg, logger, sink); err != nil {
+		return err
+	}
+	return runWatchMode(ctx, store, cfg, logger, sink, initial)
+}
+
+// openStorage opens the storage cfg points at: PostgreSQL when DatabaseURL
+// is set, the SQLite file at SQLitePath otherwise. A non-nil logger is told
+// which one.
+func openStorage(cfg Config, logger *Logger) (storage.Storage, error) {
+	if cfg.DatabaseURL != "" {
+		if cfg.CompactCommands {
+			return nil, errors.New("compact command storage is only available for SQLite databases")
+		}
+		if logger != nil {
+			logger.Infof("Using PostgreSQL storage at %s", storage.RedactDatabaseURL(cfg.DatabaseURL))
+		}
+		store, err := storage.NewPostgresStorage(cfg.DatabaseURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create PostgreSQL storage: %w", err)
+		}
+		return store, nil
+	}
+	if logger != nil {
+		logger.Infof("Using SQLite storage at %s", cfg.SQLitePath)
+	}
+	store, err := storage.NewSQLiteStorage(cfg.SQLitePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SQLite storage: %w", err)
+	}
+	return store, nil
 }
 
 // parserOptions translates ingest.Config into parser.Options.
@@ -114,40 +189,21 @@ func withDefaults(cfg Config) Config {
 	if cfg.Logger == nil {
 		cfg.Logger = NewLogger(os.Stderr, cfg.UseColor, nil)
 	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = runtime.GOMAXPROCS(0)
+	}
 	return cfg
 }
 
+// runBatchMode ingests every replay under cfg.InputDir the database doesn't
+// have yet, newest first, as a pipeline: the folder walk drops files known
+// by path and stamp as it goes (see walkForIngest), then hashing and the
+// checksum checks (see checkDiscovered), cfg.Workers parsers and the storage
+// writer run concurrently on the rest. Only new and changed files are held
+// in memory, however many replays the folder holds.
 func runBatchMode(ctx context.Context, store storage.Storage, cfg Config, logger *Logger, sink *profile.Sink) error {
 	logger.Infof("Scanning directory: %s", cfg.InputDir)
-	logger.Infof("Using %d CPU cores for parsing", runtime.GOMAXPROCS(0))
-
-	// Start the ingestion process
-	dataChan, errChan := store.StartIngestion(ctx, storage.IngestionHooks{
-		OnReplayStored: logger.Progress,
-		OnDuplicateReplay: func(err error) {
-			// Silently swallow duplicate-replay errors. The pre-check at
-			// batchCheckExistingReplays already filters known duplicates;
-			// a race-condition straggler reaching the storage layer
-			// shouldn't pollute the log. Aggregate "skipped" counter still
-			// surfaces them.
-			_ = err
-		},
-		OnStoreError: func(err error) {
-			logger.Errorf("Error storing replay: %v", err)
-		},
-	})
-
-	// Walk the directory without hashing yet — checksums get computed lazily
-	// only for files that survive the cheap path-based dedup pass below.
-	files, err := fileops.WalkReplayFiles(cfg.InputDir)
-	if err != nil {
-		return fmt.Errorf("failed to get replay files: %w", err)
-	}
-
-	logger.Successf("Found %d replay files", len(files))
-
-	// Sort by modification time (newest first)
-	fileops.SortFilesByModTime(files)
+	logger.Infof("Using %d workers for parsing and pattern analysis", cfg.Workers)
 
 	// Apply date filters
 	var upToDatePtr *time.Time
@@ -163,152 +219,86 @@ func runBatchMode(ctx context.Context, store storage.Storage, cfg Config, logger
 	if cfg.UpToMonths > 0 {
 		upToMonthsPtr = &cfg.UpToMonths
 	}
+	include := fileops.DateFilter(upToDatePtr, upToMonthsPtr)
 
-	filteredFiles := fileops.FilterFilesByDate(files, upToDatePtr, upToMonthsPtr)
-	logger.Successf("After date filtering: %d files", len(filteredFiles))
-
-	// Apply count limit
-	if cfg.StopAfterN > 0 {
-		filteredFiles = fileops.LimitFiles(filteredFiles, cfg.StopAfterN)
-		logger.Successf("Limited to %d files", len(filteredFiles))
-	}
-
-	// Phase 1: cheap path-based dedup. Cuts I/O dramatically on incremental
-	// re-scans of the same replay folder by skipping the SHA256 read of
-	// every file that's already in the DB by file_path.
-	logger.Warnf("Checking existing replays by path...")
-	pathSurvivors, err := batchCheckExistingReplaysByPath(ctx, store, filteredFiles, logger)
-	if err != nil {
-		return fmt.Errorf("failed to check existing replays by path: %w", err)
-	}
-	pathSkipped := len(filteredFiles) - len(pathSurvivors)
-	logger.Warnf("Skipped %d files already known by path", pathSkipped)
-
-	// Phase 2: hash the survivors in parallel, then run checksum-based dedup
-	// to catch the rename/move case (same content, new path).
-	logger.Warnf("Hashing %d candidate files...", len(pathSurvivors))
-	hashed, err := fileops.HashFiles(ctx, pathSurvivors)
-	if err != nil {
-		return fmt.Errorf("failed to hash candidate files: %w", err)
-	}
-
-	logger.Warnf("Checking existing replays by checksum...")
-	filesToProcess, err := batchCheckExistingReplays(ctx, store, hashed, logger)
-	if err != nil {
-		return fmt.Errorf("failed to check existing replays: %w", err)
-	}
-
-	skippedCount := len(filteredFiles) - len(filesToProcess)
-	logger.Warnf("Skipping %d existing replays", skippedCount)
-	logger.Successf("Processing %d new replays", len(filesToProcess))
-
-	// Process files in batches of 100
-	const batchSize = 100
-	var processed, errCount, skippedUMS int64
-	var mu sync.Mutex
-
-	for i := 0; i < len(filesToProcess); i += batchSize {
-		end := min(i+batchSize, len(filesToProcess))
-
-		batch := filesToProcess[i:end]
-		logger.Infof("Processing batch %d-%d of %d", i+1, end, len(filesToProcess))
-
-		// Create errgroup with context for this batch
-		g, gCtx := errgroup.WithContext(ctx)
+	// Start the ingestion process
+	dataChan, errChan := store.StartIngestion(ctx, storage.IngestionHooks{
+		OnReplayStored: logger.Progress,
+		OnDuplicateReplay: func(err error) {
+			// Silently swallow duplicate-replay errors. The pre-check at
+			// checkDiscovered already filters known duplicates; a
+			// race-condition straggler reaching the storage layer
+			// shouldn't pollute the log. Aggregate "skipped" counter still
+			// surfaces them.
+			_ = err
+		},
+		OnStoreError: func(err error) {
+			logger.Errorf("Error storing replay: %v", err)
+		},
+	})
 
-		for _, fileInfo := range batch {
-			fileInfo := fileInfo // capture loop variable
+	var stats discoveryStats
+	var processed, errCount, skippedUMS atomic.Int64
+	var ledger failureLedger
 
-			g.Go(func() error {
-				if err := processFileToChannel(gCtx, dataChan, &fileInfo, parserOptions(cfg), sink); err != nil {
-					if errors.Is(err, errSkippedUMS) {
-						mu.Lock()
-						skippedUMS++
-						mu.Unlock()
-						logger.Warnf("Skipping UMS replay: %s", fileInfo.Name)
-						return nil
-					}
+	walked := make(chan fileops.FileInfo, discoveryQueueSize)
+	candidates := make(chan fileops.FileInfo, discoveryQueueSize)
+	g, gCtx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		defer close(walked)
+		return walkForIngest(gCtx, store, cfg, include, &stats, walked)
+	})
+	g.Go(func() error {
+		defer close(candidates)
+		return checkDiscovered(gCtx, store, cfg, logger, &stats, walked, candidates)
+	})
+	for range cfg.Workers {
+		g.Go(func() error {
+			for fileInfo := range candidates {
+				err := processFileToChannel(gCtx, dataChan, &fileInfo, parserOptions(cfg), sink)
+				stats.done.Add(1)
+				switch {
+				case err == nil:
+					processed.Add(1)
+				case gCtx.Err() != nil:
+					// Cancelled mid-parse; not the file's fault.
+				case errors.Is(err, errSkippedUMS):
+					ledger.add(&fileInfo, err)
+					skippedUMS.Add(1)
+					logger.Warnf("Skipping UMS replay: %s", fileInfo.Name)
+				default:
+					ledger.add(&fileInfo, err)
 					logger.Errorf("Error processing file %s: %v", fileInfo.Name, err)
-					mu.Lock()
-					errCount++
-					mu.Unlock()
-					return nil // Don't stop processing on errors
+					errCount.Add(1) // Don't stop processing on errors
 				}
-
-				mu.Lock()
-				processed++
-				mu.Unlock()
-				return nil
-			})
-		}
-
-		// Wait for this batch to complete
-		if err := g.Wait(); err != nil {
-			logger.Errorf("Error during batch processing: %v", err)
-		}
+			}
+			return nil
+		})
 	}
 
+	stopProgress := stats.reportProgress(logger, discoveryProgressInterval)
+	pipelineErr := g.Wait()
+	stopProgress()
+
 	// Close the data channel to signal completion
 	close(dataChan)
 
 	// Wait for storage to finish
-	if err := <-errChan; err != nil {
+	if err := <-errChan; err != nil && pipelineErr == nil {
 		return fmt.Errorf("storage error: %w", err)
 	}
-
-	logger.Successf("Processing complete: processed=%d skipped_existing=%d skipped_ums=%d errors=%d", processed, skippedCount, skippedUMS, errCount)
-
-	return nil
-}
-
-// batchCheckExistingReplays checks for existing replays in batches of 100
-// Returns only the FileInfo objects for replays that don't exist yet
-func batchCheckExistingReplays(ctx context.Context, store storage.Storage, files []fileops.FileInfo, logger *Logger) ([]fileops.FileInfo, error) {
-	const batchSize = 100
-	var allFiltered []fileops.FileInfo
-
-	for i := 0; i < len(files); i += batchSize {
-		end := min(i+batchSize, len(files))
-
-		batch := files[i:end]
-		batchFiltered, err := store.FilterOutExistingReplays(ctx, batch)
-		if err != nil {
-			return nil, fmt.Errorf("failed to check batch %d-%d: %w", i+1, end, err)
-		}
-
-		// Append filtered results
-		allFiltered = append(allFiltered, batchFiltered...)
-
-		skippedInBatch := len(batch) - len(batchFiltered)
-		logger.Infof("Checked batch %d-%d: %d existing replays found", i+1, end, skippedInBatch)
+	ledger.flush(ctx, store, logger)
+	if pipelineErr != nil {
+		return pipelineErr
 	}
 
-	return allFiltered, nil
-}
-
-// batchCheckExistingReplaysByPath runs the cheap path-only dedup pass against the
-// DB. Same batching shape as batchCheckExistingReplays but does not require
-// Checksum to be populated, so it can run before the SHA256 hashing step.
-func batchCheckExistingReplaysByPath(ctx context.Context, store storage.Storage, files []fileops.FileInfo, logger *Logger) ([]fileops.FileInfo, error) {
-	const batchSize = 100
-	var allFiltered []fileops.FileInfo
-
-	for i := 0; i < len(files); i += batchSize {
-		end := min(i+batchSize, len(files))
-
-		batch := files[i:end]
-		batchFiltered, err := store.FilterOutExistingReplaysByPath(ctx, batch)
-		if err != nil {
-			return nil, fmt.Errorf("failed to check batch %d-%d: %w", i+1, end, err)
-		}
-
-		allFiltered = append(allFiltered, batchFiltered...)
-
-		skippedInBatch := len(batch) - len(batchFiltered)
-		logger.Infof("Path-checked batch %d-%d: %d existing paths found", i+1, end, skippedInBatch)
+	logger.Successf("Found %d replay files, %d after date filtering and limits", stats.walked.Load(), stats.walked.Load()-stats.filtered.Load())
+	if n := stats.knownFailures.Load(); n > 0 {
+		logger.Warnf("Skipped %d replays that already failed with this version (use --retry-failed to re-attempt)", n)
 	}
+	logger.Successf("Processing complete: processed=%d skipped_existing=%d skipped_ums=%d errors=%d", processed.Load(), stats.skipped(), skippedUMS.Load(), errCount.Load())
 
-	return allFiltered, nil
+	return nil
 }
 
 // RunForFiles drives the standard ingest pipeline for an explicit list of files,
@@ -325,12 +315,12 @@ func RunForFiles(ctx context.Context, cfg Config, files []fileops.FileInfo) erro
 		return nil
 	}
 
-	store, err := storage.NewSQLiteStorage(cfg.SQLitePath)
+	store, err := openStorage(cfg, nil)
 	if err != nil {
-		return fmt.Errorf("failed to create SQLite storage: %w", err)
+		return err
 	}
 	defer store.Close()
-	store.SetCommandStorageOptions(cfg.StoreRightClicks, cfg.SkipHotkeys)
+	store.SetCommandStorageOptions(cfg.StoreRightClicks, cfg.SkipHotkeys, cfg.CompactCommands)
 
 	if err := store.Initialize(ctx, false, false); err != nil {
 		return fmt.Errorf("failed to initialize storage: %w", err)
@@ -349,50 +339,56 @@ func RunForFiles(ctx context.Context, cfg Config, files []fileops.FileInfo) erro
 		},
 	})
 
-	const batchSize = 100
-	var processed, errCount, skippedUMS int64
-	var mu sync.Mutex
-
-	for i := 0; i < len(files); i += batchSize {
-		end := min(i+batchSize, len(files))
-		batch := files[i:end]
-		logger.Infof("Re-analyzing batch %d-%d of %d", i+1, end, len(files))
-
-		g, gCtx := errgroup.WithContext(ctx)
-		for _, fileInfo := range batch {
-			fileInfo := fileInfo
-			g.Go(func() error {
-				if err := processFileToChannel(gCtx, dataChan, &fileInfo, parserOptions(cfg), sink); err != nil {
-					if errors.Is(err, errSkippedUMS) {
-						mu.Lock()
-						skippedUMS++
-						mu.Unlock()
-						logger.Warnf("Skipping UMS replay: %s", fileInfo.Name)
-						return nil
-					}
+	var processed, errCount, skippedUMS atomic.Int64
+	var ledger failureLedger
+
+	logger.Infof("Re-analyzing %d replays with %d workers", len(files), cfg.Workers)
+	queue := make(chan fileops.FileInfo)
+	g, gCtx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		defer close(queue)
+		for _, fileInfo := range files {
+			select {
+			case queue <- fileInfo:
+			case <-gCtx.Done():
+				return gCtx.Err()
+			}
+		}
+		return nil
+	})
+	for range cfg.Workers {
+		g.Go(func() error {
+			for fileInfo := range queue {
+				err := processFileToChannel(gCtx, dataChan, &fileInfo, parserOptions(cfg), sink)
+				switch {
+				case err == nil:
+					processed.Add(1)
+				case gCtx.Err() != nil:
+					// Cancelled mid-parse; not the file's fault.
+				case errors.Is(err, errSkippedUMS):
+					ledger.add(&fileInfo, err)
+					skippedUMS.Add(1)
+					logger.Warnf("Skipping UMS replay: %s", fileInfo.Name)
+				default:
+					ledger.add(&fileInfo, err)
 					logger.Errorf("Error re-analyzing file %s: %v", fileInfo.Name, err)
-					mu.Lock()
-					errCount++
-					mu.Unlock()
-					return nil
+					errCount.Add(1)
 				}
-				mu.Lock()
-				processed++
-				mu.Unlock()
-				return nil
-			})
-		}
-		if err := g.Wait(); err != nil {
-			logger.Errorf("Error during re-analyze batch: %v", err)
-		}
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		logger.Errorf("Error during re-analyze: %v", err)
 	}
 
 	close(dataChan)
 	if err := <-errChan; err != nil {
 		return fmt.Errorf("storage error: %w", err)
 	}
+	ledger.flush(ctx, store, logger)
 
-	logger.Successf("Re-analyze complete: processed=%d skipped_ums=%d errors=%d", processed, skippedUMS, errCount)
+	logger.Successf("Re-analyze complete: processed=%d skipped_ums=%d errors=%d", processed.Load(), skippedUMS.Load(), errCount.Load())
 	return nil
 }
 
@@ -400,14 +396,15 @@ func processFileToChannel(ctx context.Context, dataChan storage.ReplayDataChanne
 	return runGuarded(func() error {
 		// Create replay model from file info
 		replay := parser.CreateReplayFromFileInfo(fileInfo.Path, fileInfo.Name, fileInfo.Size, fileInfo.Checksum)
+		replay.FileModTime = fileInfo.ModTime
 
-		// Begin per-replay profile run; nil-safe when sink is disabled.
+		// Begin per-replay profile run; nil-safe when sink is disabled. The
+		// parser records its own stages into it.
 		run := sink.Replay(fileInfo.Name)
+		opts.Profile = run
 
-		// Parse the replay
-		stop := run.Phase("parse")
+		// Parse the replay and run pattern analysis
 		data, err := parser.ParseReplayWithOptions(fileInfo.Path, replay, opts)
-		stop()
 		if err != nil {
 			return fmt.Errorf("failed to parse replay: %w", err)
 		}
@@ -438,7 +435,8 @@ func processFileToChannel(ctx context.Context, dataChan storage.ReplayDataChanne
 // code would crash the whole ingest goroutine — and the entire run. Recover it,
 // turn it into a normal per-file error (the caller logs it and bumps the error
 // counter), and keep ingesting the rest. The stack is included so a tester can
-// paste it into a bug report (issue #165).
+// paste it into a bug report (issue #165); the error is a *panicError so the
+// failure ledger can tell panics apart from ordinary parse errors.
 //
 // NOTE: recover cannot catch a runtime fatal such as "concurrent map writes";
 // the parse/detect path was audited to be free of shared mutable state so that
@@ -446,8 +444,7 @@ func processFileToChannel(ctx context.Context, dataChan storage.ReplayDataChanne
 func runGuarded(fn func() error) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
-			err = fmt.Errorf("panic while processing replay (this is a bug — please report it at "+
-				"https://github.com/marianogappa/screpdb/issues): %v\n%s", r, debug.Stack())
+			err = &panicError{value: r, stack: debug.Stack()}
 		}
 	}()
 	return fn()
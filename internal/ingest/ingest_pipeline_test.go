@@ -1,6 +1,7 @@
 package ingest
 
 import (
+	"archive/zip"
 	"bytes"
 	"context"
 	"os"
@@ -10,6 +11,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/marianogappa/screpdb/internal/buildinfo"
 	"github.com/marianogappa/screpdb/internal/fileops"
 	"github.com/marianogappa/screpdb/internal/iofacade"
 	"github.com/marianogappa/screpdb/internal/storage"
@@ -200,7 +202,7 @@ func TestRunForFiles_EmptyIsNoop(t *testing.T) {
 	}
 }
 
-func TestBatchCheckExistingReplays_DedupSemantics(t *testing.T) {
+func TestDiscovery_DedupSemantics(t *testing.T) {
 	inputDir := seedReplayDir(t, smallTestReplays...)
 	dbPath := filepath.Join(t.TempDir(), "x.db")
 	cfg := Config{InputDir: inputDir, SQLitePath: dbPath, Logger: quietLogger()}
@@ -218,44 +220,81 @@ func TestBatchCheckExistingReplays_DedupSemantics(t *testing.T) {
 	}
 
 	_ = iofacade.AllowDir(inputDir)
-	known, err := fileops.GetReplayFiles(inputDir)
+	known, err := fileops.WalkReplayFiles(inputDir)
 	if err != nil {
-		t.Fatalf("GetReplayFiles: %v", err)
-	}
-	hashedKnown, err := fileops.HashFiles(context.Background(), known)
-	if err != nil {
-		t.Fatalf("HashFiles: %v", err)
+		t.Fatalf("WalkReplayFiles: %v", err)
 	}
 
 	ctx := context.Background()
 	logger := quietLogger()
-
-	// All seeded files are known by path.
-	pathSurvivors, err := batchCheckExistingReplaysByPath(ctx, store, known, logger)
-	if err != nil {
-		t.Fatalf("batchCheckExistingReplaysByPath: %v", err)
+	include := func(fileops.FileInfo) bool { return true }
+	// check walks dir and runs what the walk sends through one checking round.
+	check := func(dir string) ([]fileops.FileInfo, *discoveryStats) {
+		t.Helper()
+		var stats discoveryStats
+		out := make(chan fileops.FileInfo, 64)
+		if err := walkForIngest(ctx, store, Config{InputDir: dir}, include, &stats, out); err != nil {
+			t.Fatalf("walkForIngest: %v", err)
+		}
+		close(out)
+		var walked []fileops.FileInfo
+		for file := range out {
+			walked = append(walked, file)
+		}
+		fresh, err := checkDiscoveredBatch(ctx, store, cfg, logger, &stats, walked)
+		if err != nil {
+			t.Fatalf("checkDiscoveredBatch: %v", err)
+		}
+		return fresh, &stats
 	}
-	if len(pathSurvivors) != 0 {
-		t.Fatalf("expected all known files skipped by path, %d survived", len(pathSurvivors))
+
+	// All seeded files are known by path with their stamps, so none is read.
+	fresh, stats := check(inputDir)
+	if len(fresh) != 0 || stats.unchanged.Load() != int64(len(known)) || stats.hashed.Load() != 0 {
+		t.Fatalf("expected all known files skipped unread, got fresh=%d unchanged=%d hashed=%d", len(fresh), stats.unchanged.Load(), stats.hashed.Load())
 	}
 
-	// All seeded files are known by checksum.
-	sumSurvivors, err := batchCheckExistingReplays(ctx, store, hashedKnown, logger)
-	if err != nil {
-		t.Fatalf("batchCheckExistingReplays: %v", err)
+	// A touched file is hashed, found by checksum, and its new stamp stored
+	// so the next walk skips it unread again.
+	touched := filepath.Join(inputDir, smallTestReplays[0])
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(touched, later, later); err != nil {
+		t.Fatalf("Chtimes: %v", err)
+	}
+	fresh, stats = check(inputDir)
+	if len(fresh) != 0 || stats.hashed.Load() != 1 || stats.existing.Load() != 1 {
+		t.Fatalf("touched file: got fresh=%d hashed=%d existing=%d, want 0/1/1", len(fresh), stats.hashed.Load(), stats.existing.Load())
 	}
-	if len(sumSurvivors) != 0 {
-		t.Fatalf("expected all known files skipped by checksum, %d survived", len(sumSurvivors))
+	if _, stats = check(inputDir); stats.unchanged.Load() != int64(len(known)) {
+		t.Fatalf("touched file's stamp should be refreshed, unchanged=%d", stats.unchanged.Load())
 	}
 
-	// A novel path survives the path pass.
-	novel := []fileops.FileInfo{{Path: filepath.Join(inputDir, "unknown.rep"), Name: "unknown.rep"}}
-	novelSurvivors, err := batchCheckExistingReplaysByPath(ctx, store, novel, logger)
+	// Rows ingested before stamps existed match on path alone.
+	execSQL(t, dbPath, "UPDATE replays SET file_size = NULL, file_mod_time = NULL")
+	if _, stats = check(inputDir); stats.unchanged.Load() != int64(len(known)) {
+		t.Fatalf("unstamped rows should match on path, unchanged=%d", stats.unchanged.Load())
+	}
+
+	// The same content under a new path is hashed and dropped by checksum.
+	copied := filepath.Join(inputDir, "copy.rep")
+	data, err := os.ReadFile(touched)
 	if err != nil {
-		t.Fatalf("batchCheckExistingReplaysByPath novel: %v", err)
+		t.Fatalf("read replay: %v", err)
+	}
+	if err := os.WriteFile(copied, data, 0o644); err != nil {
+		t.Fatalf("write copy: %v", err)
 	}
-	if len(novelSurvivors) != 1 {
-		t.Fatalf("expected novel path to survive, got %d", len(novelSurvivors))
+	fresh, stats = check(inputDir)
+	if len(fresh) != 0 || stats.hashed.Load() != 1 || stats.existing.Load() != 1 {
+		t.Fatalf("copied replay should dedup by checksum, got fresh=%d hashed=%d existing=%d", len(fresh), stats.hashed.Load(), stats.existing.Load())
+	}
+
+	// A novel replay survives, hashed.
+	novelDir := seedReplayDir(t, "bo_10pool_zvz_mentalgap.rep")
+	_ = iofacade.AllowDir(novelDir)
+	fresh, _ = check(novelDir)
+	if len(fresh) != 1 || fresh[0].Checksum == "" {
+		t.Fatalf("expected the novel replay to survive hashed, got %+v", fresh)
 	}
 }
 
@@ -308,3 +347,140 @@ func TestRun_UpToDateFutureIncludesAll(t *testing.T) {
 		t.Fatalf("future UpToDate should include all files, got %d", got)
 	}
 }
+
+func TestRun_IngestsReplaysFromArchives(t *testing.T) {
+	src := testdataReplayDir(t)
+	inputDir := t.TempDir()
+	archive := filepath.Join(inputDir, "pack.zip")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatalf("create zip: %v", err)
+	}
+	zw := zip.NewWriter(f)
+	for _, name := range smallTestReplays {
+		data, err := os.ReadFile(filepath.Join(src, name))
+		if err != nil {
+			t.Fatalf("read source replay %s: %v", name, err)
+		}
+		w, err := zw.Create("Ro16/" + name)
+		if err != nil {
+			t.Fatalf("create entry: %v", err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatalf("write entry: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	f.Close()
+
+	dbPath := filepath.Join(t.TempDir(), "x.db")
+	cfg := Config{InputDir: inputDir, SQLitePath: dbPath, Logger: quietLogger()}
+	if err := Run(context.Background(), cfg); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got := countRows(t, dbPath, "replays WHERE file_path LIKE '%pack.zip!/Ro16/%'"); got != int64(len(smallTestReplays)) {
+		t.Fatalf("archive replays: got %d, want %d", got, len(smallTestReplays))
+	}
+
+	// The same replays, extracted next to the archive, dedup by checksum.
+	extracted := seedReplayDir(t, smallTestReplays...)
+	if err := Run(context.Background(), Config{InputDir: extracted, SQLitePath: dbPath, Logger: quietLogger()}); err != nil {
+		t.Fatalf("Run extracted: %v", err)
+	}
+	if got := countRows(t, dbPath, "replays"); got != int64(len(smallTestReplays)) {
+		t.Fatalf("extracted copies should dedup against archive entries: got %d replays", got)
+	}
+
+	// Re-analysis reads the replay back out of the archive.
+	execSQL(t, dbPath, "UPDATE replays SET analyzer_algorithm_version = 1 WHERE id = 1")
+	summary, err := Reanalyze(context.Background(), ReanalyzeConfig{SQLitePath: dbPath, Logger: quietLogger()})
+	if err != nil {
+		t.Fatalf("Reanalyze: %v", err)
+	}
+	if summary.Reanalyzed != 1 {
+		t.Fatalf("expected the archived replay re-analyzed, got %+v", summary)
+	}
+}
+
+func TestRun_RecordsFailuresAndRetries(t *testing.T) {
+	inputDir := seedReplayDir(t, smallTestReplays[0])
+	broken := filepath.Join(inputDir, "broken.rep")
+	if err := os.WriteFile(broken, []byte("not a replay"), 0o644); err != nil {
+		t.Fatalf("write broken.rep: %v", err)
+	}
+	dbPath := filepath.Join(t.TempDir(), "x.db")
+	cfg := Config{InputDir: inputDir, SQLitePath: dbPath, Logger: quietLogger()}
+	ctx := context.Background()
+
+	attempts := func(wantRows int) int {
+		t.Helper()
+		failures, err := ListFailures(ctx, dbPath)
+		if err != nil {
+			t.Fatalf("ListFailures: %v", err)
+		}
+		if len(failures) != wantRows {
+			t.Fatalf("ledger has %d rows, want %d: %+v", len(failures), wantRows, failures)
+		}
+		if wantRows == 0 {
+			return 0
+		}
+		f := failures[0]
+		if f.FilePath != broken || f.ErrorClass != storage.IngestFailureParseError || f.FileChecksum == "" || f.ScrepdbVersion != buildinfo.Version {
+			t.Fatalf("unexpected ledger row: %+v", f)
+		}
+		return f.Attempts
+	}
+
+	if err := Run(ctx, cfg); err != nil {
+		t.Fatalf("first Run: %v", err)
+	}
+	if got := attempts(1); got != 1 {
+		t.Fatalf("attempts after first run = %d, want 1", got)
+	}
+
+	// Same version, same content: the file is not re-attempted.
+	if err := Run(ctx, cfg); err != nil {
+		t.Fatalf("second Run: %v", err)
+	}
+	if got := attempts(1); got != 1 {
+		t.Fatalf("attempts after unchanged re-run = %d, want 1", got)
+	}
+
+	retryCfg := cfg
+	retryCfg.RetryFailed = true
+	if err := Run(ctx, retryCfg); err != nil {
+		t.Fatalf("retry Run: %v", err)
+	}
+	if got := attempts(1); got != 2 {
+		t.Fatalf("attempts after --retry-failed = %d, want 2", got)
+	}
+
+	// An upgrade re-attempts recorded failures automatically.
+	oldVersion := buildinfo.Version
+	buildinfo.Version = "v999.0.0"
+	t.Cleanup(func() { buildinfo.Version = oldVersion })
+	if err := Run(ctx, cfg); err != nil {
+		t.Fatalf("upgraded Run: %v", err)
+	}
+	if got := attempts(1); got != 3 {
+		t.Fatalf("attempts after upgrade = %d, want 3", got)
+	}
+
+	// Once the file ingests, its ledger row goes away.
+	data, err := os.ReadFile(filepath.Join(testdataReplayDir(t), smallTestReplays[1]))
+	if err != nil {
+		t.Fatalf("read replay: %v", err)
+	}
+	if err := os.WriteFile(broken, data, 0o644); err != nil {
+		t.Fatalf("fix broken.rep: %v", err)
+	}
+	if err := Run(ctx, cfg); err != nil {
+		t.Fatalf("fixed Run: %v", err)
+	}
+	attempts(0)
+	if got := countRows(t, dbPath, "replays"); got != 2 {
+		t.Fatalf("replays: got %d, want 2", got)
+	}
+}
@@ -40,3 +40,24 @@ func TestRunGuardedRecoversPanic(t *testing.T) {
 		t.Errorf("error should include a stack trace; got: %v", err)
 	}
 }
+
+func TestRunGuardedPanicErrorCarriesStackHash(t *testing.T) {
+	panicAt := func() error {
+		return runGuarded(func() error {
+			var m map[string]int
+			m["x"] = 1 // assignment to nil map
+			return nil
+		})
+	}
+	first, second := panicAt(), panicAt()
+	var pe1, pe2 *panicError
+	if !errors.As(first, &pe1) || !errors.As(second, &pe2) {
+		t.Fatalf("expected *panicError, got %T / %T", first, second)
+	}
+	if h1, h2 := stackHash(pe1.stack), stackHash(pe2.stack); h1 == "" || h1 != h2 {
+		t.Fatalf("same crash site should hash the same: %q vs %q", h1, h2)
+	}
+	if other := stackHash([]byte("goroutine 1 [running]:\nmain.elsewhere()\n\t/x.go:1 +0x1\n")); other == stackHash(pe1.stack) {
+		t.Fatal("different stacks should hash differently")
+	}
+}
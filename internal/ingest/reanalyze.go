@@ -0,0 +1,484 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/marianogappa/screpdb/internal/fileops"
+	"github.com/marianogappa/screpdb/internal/iofacade"
+	"github.com/marianogappa/screpdb/internal/models"
+	"github.com/marianogappa/screpdb/internal/parser"
+	"github.com/marianogappa/screpdb/internal/patterns"
+	"github.com/marianogappa/screpdb/internal/patterns/core"
+	"github.com/marianogappa/screpdb/internal/patterns/markers"
+	"github.com/marianogappa/screpdb/internal/storage"
+	"golang.org/x/sync/errgroup"
+)
+
+// noOpener is how a missing opener is shown in ReanalyzeSummary.OpenerChanges.
+const noOpener = "(none)"
+
+// ReanalyzeConfig selects replays to re-run pattern detection on. With no
+// ReplayIDs and no FeatureKeys every replay analyzed under an older
+// core.AlgorithmVersion is selected; otherwise the union of the listed replays
+// and the replays carrying any listed marker is selected, whatever their
+// version.
+type ReanalyzeConfig struct {
+	SQLitePath  string
+	ReplayIDs   []int64
+	FeatureKeys []string
+
+	// Concurrency caps how many replays are parsed at once. Zero uses
+	// runtime.GOMAXPROCS. Writes are serialized by the store regardless.
+	Concurrency int
+
+	// DryRun computes and reports the changes without writing them.
+	DryRun bool
+
+	// FromDatabase rebuilds every replay from the database instead of
+	// re-parsing its .rep, even when the file is still there.
+	FromDatabase bool
+
+	UseColor bool
+	Logger   *Logger
+
+	// EarlyFilterDebugDir mirrors Config.EarlyFilterDebugDir.
+	EarlyFilterDebugDir string
+}
+
+// MarkerDelta counts how a marker's stored rows differ from a fresh pass.
+// A row is keyed by (player, feature key); Changed means it exists in both
+// but its detection second or payload moved.
+type MarkerDelta struct {
+	Added   int
+	Removed int
+	Changed int
+}
+
+// ReanalyzeSummary reports what a Reanalyze run did (or, for a dry run,
+// would do).
+type ReanalyzeSummary struct {
+	Selected   int
+	Reanalyzed int
+	// FromDatabase counts the re-analyzed replays that were rebuilt from the
+	// database rather than re-parsed from their file.
+	FromDatabase int
+	// Skipped counts replays whose file is gone or no longer matches the
+	// stored checksum and that have no stored analysis input, plus UMS
+	// replays.
+	Skipped int
+	Errors  int
+
+	// Markers is keyed by marker feature key.
+	Markers map[string]*MarkerDelta
+
+	// OpenerChanges counts per-player opener reclassifications, keyed by
+	// "old -> new" feature keys (noOpener when a player had or gets none).
+	OpenerChanges map[string]int
+}
+
+// SortedMarkerKeys returns the Markers keys, most-changed first.
+func (s *ReanalyzeSummary) SortedMarkerKeys() []string {
+	keys := make([]string, 0, len(s.Markers))
+	for k := range s.Markers {
+		keys = append(keys, k)
+	}
+	total := func(k string) int {
+		d := s.Markers[k]
+		return d.Added + d.Removed + d.Changed
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if ti, tj := total(keys[i]), total(keys[j]); ti != tj {
+			return ti > tj
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// SortedOpenerChanges returns the OpenerChanges keys, most frequent first.
+func (s *ReanalyzeSummary) SortedOpenerChanges() []string {
+	keys := make([]string, 0, len(s.OpenerChanges))
+	for k := range s.OpenerChanges {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if ci, cj := s.OpenerChanges[keys[i]], s.OpenerChanges[keys[j]]; ci != cj {
+			return ci > cj
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// errReplayFileChanged marks a replay that can't be re-parsed because its
+// file is missing or was replaced since ingest.
+var errReplayFileChanged = errors.New("replay file missing or changed since ingest")
+
+// Reanalyze re-runs pattern detection for the selected replays and replaces
+// their stored markers and narrative events. Each replay is re-parsed from
+// its .rep file, which also refreshes its game fingerprint and group; when
+// the file is gone or changed the replay is rebuilt from its stored commands
+// and analysis input instead. Commands and player rows are left untouched.
+func Reanalyze(ctx context.Context, cfg ReanalyzeConfig) (*ReanalyzeSummary, error) {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = NewLogger(os.Stderr, cfg.UseColor, nil)
+	}
+	if cfg.SQLitePath == "" {
+		cfg.SQLitePath = withDefaults(Config{}).SQLitePath
+	}
+	concurrency := cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	for _, key := range cfg.FeatureKeys {
+		if markers.ByFeatureKey(key) == nil {
+			return nil, fmt.Errorf("unknown feature key %q", key)
+		}
+	}
+
+	store, err := storage.NewSQLiteStorage(cfg.SQLitePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SQLite storage: %w", err)
+	}
+	defer store.Close()
+	if err := store.Initialize(ctx, false, false); err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+
+	refs, err := store.ListReplaysForReanalysis(ctx, storage.ReanalysisSelection{
+		StaleBelow:  core.AlgorithmVersion,
+		ReplayIDs:   cfg.ReplayIDs,
+		FeatureKeys: cfg.FeatureKeys,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &ReanalyzeSummary{
+		Selected:      len(refs),
+		Markers:       map[string]*MarkerDelta{},
+		OpenerChanges: map[string]int{},
+	}
+	if len(refs) == 0 {
+		logger.Successf("Nothing to re-analyze (algorithm version %d)", core.AlgorithmVersion)
+		return summary, nil
+	}
+	verb := "Re-analyzing"
+	if cfg.DryRun {
+		verb = "Dry run: re-analyzing"
+	}
+	logger.Infof("%s %d replays with algorithm version %d (%d at a time)", verb, len(refs), core.AlgorithmVersion, concurrency)
+
+	var mu sync.Mutex
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+	for _, ref := range refs {
+		g.Go(func() error {
+			delta, err := reanalyzeReplay(gCtx, store, ref, cfg)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case errors.Is(err, errReplayFileChanged), errors.Is(err, errSkippedUMS):
+				summary.Skipped++
+				logger.Warnf("Skipping replay %d (%s): %v", ref.ID, ref.FileName, err)
+			case err != nil:
+				if gCtx.Err() != nil {
+					return gCtx.Err()
+				}
+				summary.Errors++
+				logger.Errorf("Error re-analyzing replay %d (%s): %v", ref.ID, ref.FileName, err)
+			default:
+				summary.Reanalyzed++
+				delta.mergeInto(summary)
+				logger.Progress()
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return summary, err
+	}
+
+	logger.Successf("Re-analyze complete: reanalyzed=%d (from database=%d) skipped=%d errors=%d", summary.Reanalyzed, summary.FromDatabase, summary.Skipped, summary.Errors)
+	return summary, nil
+}
+
+// replayDelta is one replay's contribution to a ReanalyzeSummary.
+type replayDelta struct {
+	markers       map[string]*MarkerDelta
+	openerChanges map[string]int
+	fromDatabase  bool
+}
+
+func (d replayDelta) mergeInto(s *ReanalyzeSummary) {
+	if d.fromDatabase {
+		s.FromDatabase++
+	}
+	for key, md := range d.markers {
+		agg, ok := s.Markers[key]
+		if !ok {
+			agg = &MarkerDelta{}
+			s.Markers[key] = agg
+		}
+		agg.Added += md.Added
+		agg.Removed += md.Removed
+		agg.Changed += md.Changed
+	}
+	for key, n := range d.openerChanges {
+		s.OpenerChanges[key] += n
+	}
+}
+
+// reanalyzeReplay re-runs detection on one replay, diffs the fresh
+// detections against the stored ones, and (unless dry-running) replaces them.
+// The replay is re-parsed from its .rep when that is still the ingested file,
+// and rebuilt from the database otherwise (or when cfg.FromDatabase is set).
+func reanalyzeReplay(ctx context.Context, store *storage.SQLiteStorage, ref storage.ReplayRef, cfg ReanalyzeConfig) (replayDelta, error) {
+	var detection replayDetection
+	err := errReplayFileChanged
+	if !cfg.FromDatabase {
+		detection, err = analyzeReplayFile(ctx, store, ref, cfg)
+	}
+	if errors.Is(err, errReplayFileChanged) {
+		detection, err = analyzeStoredReplay(ctx, store, ref)
+		if errors.Is(err, storage.ErrNoAnalysisInput) {
+			return replayDelta{}, fmt.Errorf("%w, and no analysis input is stored", errReplayFileChanged)
+		}
+	}
+	if err != nil {
+		return replayDelta{}, err
+	}
+
+	orch := detection.orchestrator
+	results := orch.GetResults()
+	events := orch.ReplayEvents()
+	orch.ConvertResultsToDatabaseIDs(detection.playerIDMap)
+	for _, r := range results {
+		r.ReplayID = ref.ID
+	}
+
+	old, err := store.ReplayMarkerRows(ctx, ref.ID)
+	if err != nil {
+		return replayDelta{}, err
+	}
+	delta := diffMarkers(old, results)
+	delta.fromDatabase = detection.fromDatabase
+
+	if cfg.DryRun {
+		return delta, nil
+	}
+	if err := store.ReplacePatternDetections(ctx, ref.ID, results, events, detection.economy, detection.production, detection.larva, detection.hotkeys, detection.playerIDMap); err != nil {
+		return replayDelta{}, err
+	}
+	// The fingerprint is computed from the raw .rep; a replay rebuilt from
+	// the database keeps the one it has.
+	if !detection.fromDatabase {
+		if err := store.UpdateGameFingerprint(ctx, ref.ID, detection.fingerprint); err != nil {
+			return replayDelta{}, err
+		}
+	}
+	return delta, nil
+}
+
+// replayDetection is a fresh detection pass over one replay, its results
+// not yet read.
+type replayDetection struct {
+	orchestrator *patterns.Orchestrator
+	economy      []models.EconomySample
+	production   []models.ProductionBusySpan
+	larva        []models.LarvaSample
+	hotkeys      []models.HotkeyUsage
+	// playerIDMap maps in-replay player IDs to database player IDs.
+	playerIDMap  map[byte]int64
+	fingerprint  string
+	fromDatabase bool
+}
+
+// analyzeReplayFile re-parses a replay from its .rep, returning
+// errReplayFileChanged when the file is gone or no longer the one ingested.
+func analyzeReplayFile(ctx context.Context, store *storage.SQLiteStorage, ref storage.ReplayRef, cfg ReanalyzeConfig) (replayDetection, error) {
+	// Replays may come from any folder they were ingested from (not only the
+	// current replays folder), so register each file's folder as a read root.
+	if err := iofacade.AllowDir(fileops.ReplayDir(ref.FilePath)); err != nil {
+		return replayDetection{}, err
+	}
+	info, err := fileops.NewFileInfoFromPath(ref.FilePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return replayDetection{}, errReplayFileChanged
+		}
+		return replayDetection{}, err
+	}
+	if info.Checksum != ref.FileChecksum {
+		return replayDetection{}, errReplayFileChanged
+	}
+
+	var detection replayDetection
+	err = runGuarded(func() error {
+		replay := parser.CreateReplayFromFileInfo(info.Path, info.Name, info.Size, info.Checksum)
+		data, err := parser.ParseReplayWithOptions(info.Path, replay, parser.Options{EarlyFilterDebugDir: cfg.EarlyFilterDebugDir})
+		if err != nil {
+			return fmt.Errorf("failed to parse replay: %w", err)
+		}
+		if data.Replay != nil && data.Replay.MapKind == "UseMapSettings" {
+			return errSkippedUMS
+		}
+		o, ok := data.PatternOrchestrator.(*patterns.Orchestrator)
+		if !ok {
+			return fmt.Errorf("parser returned no pattern orchestrator")
+		}
+		detection.orchestrator = o
+		detection.economy = data.Economy
+		detection.production = data.ProductionBusy
+		detection.larva = data.Larva
+		detection.hotkeys = data.Hotkeys
+		detection.fingerprint = data.Replay.GameFingerprint
+
+		bySlot, err := store.ReplayPlayerIDsBySlot(ctx, ref.ID)
+		if err != nil {
+			return err
+		}
+		detection.playerIDMap = make(map[byte]int64, len(data.Players))
+		for _, p := range data.Players {
+			if id, ok := bySlot[p.SlotID]; ok {
+				detection.playerIDMap[p.PlayerID] = id
+			}
+		}
+		return nil
+	})
+	return detection, err
+}
+
+// analyzeStoredReplay re-runs detection on a replay rebuilt from the
+// database. It returns storage.ErrNoAnalysisInput for replays ingested
+// before analysis inputs were stored.
+func analyzeStoredReplay(ctx context.Context, store *storage.SQLiteStorage, ref storage.ReplayRef) (replayDetection, error) {
+	data, in, err := store.LoadStoredReplayData(ctx, ref.ID)
+	if err != nil {
+		return replayDetection{}, err
+	}
+	if data.Replay.MapKind == "UseMapSettings" {
+		return replayDetection{}, errSkippedUMS
+	}
+
+	detection := replayDetection{fromDatabase: true}
+	err = runGuarded(func() error {
+		if err := parser.AnalyzeStored(data, in); err != nil {
+			return err
+		}
+		o, ok := data.PatternOrchestrator.(*patterns.Orchestrator)
+		if !ok {
+			return fmt.Errorf("stored analysis produced no pattern orchestrator")
+		}
+		detection.orchestrator = o
+		detection.economy = data.Economy
+		detection.production = data.ProductionBusy
+		detection.larva = data.Larva
+		detection.hotkeys = data.Hotkeys
+		return nil
+	})
+	if err != nil {
+		return replayDetection{}, err
+	}
+	detection.playerIDMap = make(map[byte]int64, len(data.Players))
+	for _, p := range data.Players {
+		detection.playerIDMap[p.PlayerID] = p.ID
+	}
+	return detection, nil
+}
+
+// diffMarkers compares stored marker rows with fresh results. Results whose
+// PatternName isn't a registered marker are ignored, exactly as the insert
+// path ignores them.
+func diffMarkers(old []storage.MarkerRow, fresh []*core.PatternResult) replayDelta {
+	type rowKey struct {
+		player int64 // 0 for replay-level markers, as in the unique index
+		key    string
+	}
+	type rowVal struct {
+		second  int
+		payload string
+	}
+	oldRows := map[rowKey]rowVal{}
+	oldOpener := map[int64]string{}
+	for _, r := range old {
+		var player int64
+		if r.PlayerID != nil {
+			player = *r.PlayerID
+		}
+		oldRows[rowKey{player, r.FeatureKey}] = rowVal{r.Second, r.Payload}
+		if m := markers.ByFeatureKey(r.FeatureKey); m != nil && m.Kind == markers.KindInitialBuildOrder {
+			oldOpener[player] = r.FeatureKey
+		}
+	}
+
+	newRows := map[rowKey]rowVal{}
+	newOpener := map[int64]string{}
+	for _, r := range fresh {
+		m := markers.ByPatternName(r.PatternName)
+		if m == nil {
+			continue
+		}
+		var player int64
+		if r.PlayerID != nil {
+			player = *r.PlayerID
+		}
+		newRows[rowKey{player, m.FeatureKey}] = rowVal{r.DetectedAtSecond, string(r.Payload)}
+		if m.Kind == markers.KindInitialBuildOrder {
+			newOpener[player] = m.FeatureKey
+		}
+	}
+
+	d := replayDelta{markers: map[string]*MarkerDelta{}, openerChanges: map[string]int{}}
+	bump := func(key string) *MarkerDelta {
+		md, ok := d.markers[key]
+		if !ok {
+			md = &MarkerDelta{}
+			d.markers[key] = md
+		}
+		return md
+	}
+	for k, nv := range newRows {
+		ov, ok := oldRows[k]
+		switch {
+		case !ok:
+			bump(k.key).Added++
+		case ov != nv:
+			bump(k.key).Changed++
+		}
+	}
+	for k := range oldRows {
+		if _, ok := newRows[k]; !ok {
+			bump(k.key).Removed++
+		}
+	}
+
+	players := map[int64]bool{}
+	for p := range oldOpener {
+		players[p] = true
+	}
+	for p := range newOpener {
+		players[p] = true
+	}
+	for p := range players {
+		before, after := oldOpener[p], newOpener[p]
+		if before == after {
+			continue
+		}
+		if before == "" {
+			before = noOpener
+		}
+		if after == "" {
+			after = noOpener
+		}
+		d.openerChanges[before+" -> "+after]++
+	}
+	return d
+}
@@ -0,0 +1,228 @@
+package ingest
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/marianogappa/screpdb/internal/patterns/core"
+	"github.com/marianogappa/screpdb/internal/patterns/markers"
+	"github.com/marianogappa/screpdb/internal/storage"
+)
+
+// execSQL runs a raw write against the test database, for staging stale or
+// damaged detection state.
+func execSQL(t *testing.T, dbPath, query string, args ...any) {
+	t.Helper()
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(query, args...); err != nil {
+		t.Fatalf("exec %q: %v", query, err)
+	}
+}
+
+func ingestForReanalyze(t *testing.T) (inputDir, dbPath string) {
+	t.Helper()
+	inputDir = seedReplayDir(t, smallTestReplays...)
+	dbPath = filepath.Join(t.TempDir(), "x.db")
+	if err := Run(context.Background(), Config{InputDir: inputDir, SQLitePath: dbPath, Logger: quietLogger()}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	return inputDir, dbPath
+}
+
+func TestReanalyze_DryRunReportsWithoutWriting(t *testing.T) {
+	_, dbPath := ingestForReanalyze(t)
+	markersBefore := countRows(t, dbPath, "replay_events WHERE event_kind = 'marker'")
+
+	// Simulate an older analysis that missed every marker of replay 1.
+	execSQL(t, dbPath, "DELETE FROM replay_events WHERE replay_id = 1 AND event_kind = 'marker'")
+	execSQL(t, dbPath, "UPDATE replays SET analyzer_algorithm_version = 1 WHERE id = 1")
+	damaged := countRows(t, dbPath, "replay_events WHERE event_kind = 'marker'")
+
+	summary, err := Reanalyze(context.Background(), ReanalyzeConfig{SQLitePath: dbPath, DryRun: true, Logger: quietLogger()})
+	if err != nil {
+		t.Fatalf("Reanalyze dry run: %v", err)
+	}
+	if summary.Selected != 1 || summary.Reanalyzed != 1 {
+		t.Fatalf("dry run should select only the stale replay, got %+v", summary)
+	}
+	added := 0
+	for _, d := range summary.Markers {
+		added += d.Added
+	}
+	if int64(added) != markersBefore-damaged {
+		t.Fatalf("dry run reports %d added marker rows, want %d", added, markersBefore-damaged)
+	}
+	if got := countRows(t, dbPath, "replay_events WHERE event_kind = 'marker'"); got != damaged {
+		t.Fatalf("dry run must not write: marker rows %d, want %d", got, damaged)
+	}
+
+	summary, err = Reanalyze(context.Background(), ReanalyzeConfig{SQLitePath: dbPath, Logger: quietLogger()})
+	if err != nil {
+		t.Fatalf("Reanalyze: %v", err)
+	}
+	if summary.Reanalyzed != 1 {
+		t.Fatalf("expected one replay re-analyzed, got %+v", summary)
+	}
+	if got := countRows(t, dbPath, "replay_events WHERE event_kind = 'marker'"); got != markersBefore {
+		t.Fatalf("marker rows after re-analysis = %d, want the original %d", got, markersBefore)
+	}
+	if got := countRows(t, dbPath, "replays WHERE analyzer_algorithm_version < "+strconv.Itoa(core.AlgorithmVersion)); got != 0 {
+		t.Fatalf("re-analysis should leave no stale replays, got %d", got)
+	}
+}
+
+func TestReanalyze_SelectionAndMissingFiles(t *testing.T) {
+	inputDir, dbPath := ingestForReanalyze(t)
+
+	// Nothing is stale right after ingest.
+	summary, err := Reanalyze(context.Background(), ReanalyzeConfig{SQLitePath: dbPath, Logger: quietLogger()})
+	if err != nil {
+		t.Fatalf("Reanalyze: %v", err)
+	}
+	if summary.Selected != 0 {
+		t.Fatalf("fresh ingest should have no stale replays, got %+v", summary)
+	}
+
+	if _, err := Reanalyze(context.Background(), ReanalyzeConfig{SQLitePath: dbPath, FeatureKeys: []string{"no_such_marker"}, Logger: quietLogger()}); err == nil {
+		t.Fatal("expected an unknown feature key to be rejected")
+	}
+
+	// An explicit selection ignores the version; a deleted file is rebuilt
+	// from the database.
+	if err := os.Remove(filepath.Join(inputDir, smallTestReplays[1])); err != nil {
+		t.Fatalf("remove replay: %v", err)
+	}
+	summary, err = Reanalyze(context.Background(), ReanalyzeConfig{SQLitePath: dbPath, ReplayIDs: []int64{1, 2}, Concurrency: 2, Logger: quietLogger()})
+	if err != nil {
+		t.Fatalf("Reanalyze by id: %v", err)
+	}
+	if summary.Selected != 2 || summary.Reanalyzed != 2 || summary.FromDatabase != 1 || summary.Skipped != 0 {
+		t.Fatalf("want 2 selected and re-analyzed, 1 from the database; got %+v", summary)
+	}
+	if len(summary.Markers) != 0 || len(summary.OpenerChanges) != 0 {
+		t.Fatalf("re-analyzing an up-to-date replay should change nothing, got %+v", summary)
+	}
+}
+
+func TestDiffMarkers(t *testing.T) {
+	var opener, other *markers.Marker
+	for _, m := range markers.Markers() {
+		m := m
+		if opener == nil && m.Kind == markers.KindInitialBuildOrder {
+			opener = &m
+		} else if other == nil && m.Kind == markers.KindInitialBuildOrder && opener != nil && m.FeatureKey != opener.FeatureKey {
+			other = &m
+		}
+	}
+	if opener == nil || other == nil {
+		t.Fatal("need two registered openers")
+	}
+	p1, p2 := int64(10), int64(11)
+	old := []storage.MarkerRow{
+		{PlayerID: &p1, FeatureKey: opener.FeatureKey, Second: 100},
+		{PlayerID: &p2, FeatureKey: opener.FeatureKey, Second: 120},
+		{FeatureKey: "retired_marker", Second: 5},
+	}
+	fresh := []*core.PatternResult{
+		{PatternName: other.PatternName, PlayerID: &p1, DetectedAtSecond: 100},
+		{PatternName: opener.PatternName, PlayerID: &p2, DetectedAtSecond: 130, Payload: json.RawMessage(`{}`)},
+		{PatternName: "Not a registered marker", PlayerID: &p2},
+	}
+
+	d := diffMarkers(old, fresh)
+	if got := d.markers[other.FeatureKey]; got == nil || got.Added != 1 {
+		t.Fatalf("%s: want 1 added, got %+v", other.FeatureKey, got)
+	}
+	if got := d.markers[opener.FeatureKey]; got == nil || got.Removed != 1 || got.Changed != 1 {
+		t.Fatalf("%s: want 1 removed + 1 changed, got %+v", opener.FeatureKey, got)
+	}
+	if got := d.markers["retired_marker"]; got == nil || got.Removed != 1 {
+		t.Fatalf("retired_marker: want 1 removed, got %+v", got)
+	}
+	if n := d.openerChanges[opener.FeatureKey+" -> "+other.FeatureKey]; n != 1 || len(d.openerChanges) != 1 {
+		t.Fatalf("opener changes = %v, want exactly one %s -> %s", d.openerChanges, opener.FeatureKey, other.FeatureKey)
+	}
+}
+
+// replayEventRows dumps every replay_events row in a stable order, for
+// comparing two analyses of the same replays.
+func replayEventRows(t *testing.T, dbPath string) []string {
+	t.Helper()
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+	rows, err := db.Query(`
+		SELECT json_array(replay_id, seconds_from_game_start, event_kind, event_type, location_base_type, location_base_oclock,
+			location_natural_of_oclock, location_mineral_only, source_player_id, target_player_id, attack_unit_types, payload, attack_cast_counts)
+		FROM replay_events`)
+	if err != nil {
+		t.Fatalf("query replay_events: %v", err)
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var row string
+		if err := rows.Scan(&row); err != nil {
+			t.Fatalf("scan replay_events: %v", err)
+		}
+		out = append(out, row)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestReanalyze_FromDatabaseMatchesFile(t *testing.T) {
+	inputDir, dbPath := ingestForReanalyze(t)
+	want := replayEventRows(t, dbPath)
+	if len(want) == 0 {
+		t.Fatal("ingest stored no replay events")
+	}
+
+	// Right Clicks aren't stored by default, so this also exercises the
+	// commands the analysis input carries on its own.
+	execSQL(t, dbPath, "DELETE FROM replay_events")
+	summary, err := Reanalyze(context.Background(), ReanalyzeConfig{SQLitePath: dbPath, ReplayIDs: []int64{1, 2}, FromDatabase: true, Logger: quietLogger()})
+	if err != nil {
+		t.Fatalf("Reanalyze from database: %v", err)
+	}
+	if summary.Reanalyzed != 2 || summary.FromDatabase != 2 {
+		t.Fatalf("want both replays rebuilt from the database, got %+v", summary)
+	}
+	got := replayEventRows(t, dbPath)
+	if len(got) != len(want) {
+		t.Fatalf("re-analysis from the database stored %d events, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("event %d differs:\n got  %s\n want %s", i, got[i], want[i])
+		}
+	}
+
+	// A replay whose file is gone falls back to the database; without a
+	// stored input it is skipped.
+	for _, name := range smallTestReplays {
+		if err := os.Remove(filepath.Join(inputDir, name)); err != nil {
+			t.Fatalf("remove replay: %v", err)
+		}
+	}
+	execSQL(t, dbPath, "DELETE FROM replay_analysis_inputs WHERE replay_id = 2")
+	summary, err = Reanalyze(context.Background(), ReanalyzeConfig{SQLitePath: dbPath, ReplayIDs: []int64{1, 2}, Logger: quietLogger()})
+	if err != nil {
+		t.Fatalf("Reanalyze: %v", err)
+	}
+	if summary.Reanalyzed != 1 || summary.FromDatabase != 1 || summary.Skipped != 1 {
+		t.Fatalf("want 1 replay rebuilt from the database and 1 skipped, got %+v", summary)
+	}
+}
@@ -0,0 +1,296 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/marianogappa/screpdb/internal/fileops"
+	"github.com/marianogappa/screpdb/internal/iofacade"
+	"github.com/marianogappa/screpdb/internal/profile"
+	"github.com/marianogappa/screpdb/internal/storage"
+)
+
+const (
+	// defaultWatchPollInterval is how often watch mode re-walks the replay
+	// folder. Polling (rather than OS file notifications) keeps the watcher
+	// inside iofacade and behaves the same on every platform and on network
+	// or synced folders where change notifications are unreliable.
+	defaultWatchPollInterval = 2 * time.Second
+
+	// defaultWatchSettle is how long a file's size and mtime must stay
+	// unchanged before it's considered fully written. StarCraft writes a
+	// replay in one burst at game end, so a few seconds is plenty.
+	defaultWatchSettle = 3 * time.Second
+
+	// lastReplayGrace is how long watch mode waits, after LastReplay.rep
+	// changes, for the same game to show up under a named/autosaved file
+	// before warning that it will be lost.
+	lastReplayGrace = 30 * time.Second
+
+	lastReplayFileName = "LastReplay.rep"
+)
+
+// fileStamp identifies one on-disk version of a file. A replay that's
+// overwritten in place gets a new stamp and is re-considered.
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// pendingFile is a candidate that was seen but hasn't settled yet.
+type pendingFile struct {
+	stamp     fileStamp
+	firstSeen time.Time
+}
+
+// watcher tracks the replay folder between polls. It is only touched by the
+// watch loop goroutine, so it needs no locking.
+type watcher struct {
+	inputDir string
+	settle   time.Duration
+
+	// handled holds every path that was already ingested, skipped or failed,
+	// keyed to the stamp it had at the time.
+	handled map[string]fileStamp
+	pending map[string]pendingFile
+
+	// LastReplay.rep is never ingested (it's overwritten every game, and the
+	// same game is normally autosaved under its own name), but watching it
+	// tells us a game just ended.
+	lastReplayStamp    fileStamp
+	lastReplayChecksum string
+	lastReplayDeadline time.Time
+}
+
+func newWatcher(inputDir string, settle time.Duration, initial []fileops.FileInfo) *watcher {
+	w := &watcher{
+		inputDir: inputDir,
+		settle:   settle,
+		handled:  make(map[string]fileStamp, len(initial)),
+		pending:  map[string]pendingFile{},
+	}
+	for _, f := range initial {
+		w.handled[f.Path] = fileStamp{size: f.Size, modTime: f.ModTime}
+	}
+	if stamp, ok := w.statLastReplay(); ok {
+		w.lastReplayStamp = stamp
+	}
+	return w
+}
+
+// settled returns the files that have stopped changing since the last poll
+// and are therefore safe to hash and parse. Files still being written stay
+// pending; files that disappeared are forgotten.
+func (w *watcher) settled(files []fileops.FileInfo, now time.Time) []fileops.FileInfo {
+	var ready []fileops.FileInfo
+	seen := make(map[string]bool, len(files))
+	for _, f := range files {
+		seen[f.Path] = true
+		stamp := fileStamp{size: f.Size, modTime: f.ModTime}
+		if prev, ok := w.handled[f.Path]; ok && prev == stamp {
+			continue
+		}
+		p, ok := w.pending[f.Path]
+		if !ok || p.stamp != stamp {
+			w.pending[f.Path] = pendingFile{stamp: stamp, firstSeen: now}
+			continue
+		}
+		if now.Sub(p.firstSeen) < w.settle {
+			continue
+		}
+		delete(w.pending, f.Path)
+		ready = append(ready, f)
+	}
+	for path := range w.pending {
+		if !seen[path] {
+			delete(w.pending, path)
+		}
+	}
+	return ready
+}
+
+func (w *watcher) markHandled(files []fileops.FileInfo) {
+	for _, f := range files {
+		w.handled[f.Path] = fileStamp{size: f.Size, modTime: f.ModTime}
+	}
+}
+
+func (w *watcher) lastReplayPath() string {
+	return filepath.Join(w.inputDir, lastReplayFileName)
+}
+
+func (w *watcher) statLastReplay() (fileStamp, bool) {
+	info, err := iofacade.Stat(w.lastReplayPath())
+	if err != nil || info.IsDir() {
+		return fileStamp{}, false
+	}
+	return fileStamp{size: info.Size(), modTime: info.ModTime()}, true
+}
+
+// runWatchMode keeps ingesting replays that appear under cfg.InputDir until
+// ctx is cancelled. initial is the directory listing the preceding batch pass
+// already dealt with, so watch mode only reacts to changes after it.
+func runWatchMode(ctx context.Context, store storage.Storage, cfg Config, logger *Logger, sink *profile.Sink, initial []fileops.FileInfo) error {
+	pollInterval := cfg.WatchPollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultWatchPollInterval
+	}
+	settle := cfg.WatchSettle
+	if settle <= 0 {
+		settle = defaultWatchSettle
+	}
+
+	dataChan, errChan := store.StartIngestion(ctx, storage.IngestionHooks{
+		OnReplayStored: func() {
+			logger.Successf("Replay stored")
+		},
+		OnDuplicateReplay: func(err error) {
+			logger.Warnf("Skipping a replay already in the database: %v", err)
+		},
+		OnStoreError: func(err error) {
+			logger.Errorf("Error storing replay: %v", err)
+		},
+	})
+
+	w := newWatcher(cfg.InputDir, settle, initial)
+	logger.Infof("Watching %s for new replays (Ctrl+C to stop)", cfg.InputDir)
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var ingested, skipped, errCount int64
+	for {
+		select {
+		case <-ctx.Done():
+			close(dataChan)
+			// The storage goroutine also watches ctx, so it may report the
+			// cancellation itself; that's the normal way to stop watching.
+			if err := <-errChan; err != nil && !errors.Is(err, context.Canceled) {
+				return fmt.Errorf("storage error: %w", err)
+			}
+			logger.Successf("Watch stopped: ingested=%d skipped_existing=%d errors=%d", ingested, skipped, errCount)
+			return nil
+		case err := <-errChan:
+			// The storage goroutine can see the cancellation before this
+			// loop does; that's still the normal way to stop watching.
+			if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+				close(dataChan)
+				logger.Successf("Watch stopped: ingested=%d skipped_existing=%d errors=%d", ingested, skipped, errCount)
+				return nil
+			}
+			// Otherwise it only exits early on a fatal store error.
+			if err == nil {
+				err = errors.New("storage stopped unexpectedly")
+			}
+			return fmt.Errorf("storage error: %w", err)
+		case now := <-ticker.C:
+			files, err := fileops.WalkReplayFiles(cfg.InputDir)
+			if errors.Is(err, os.ErrNotExist) {
+				continue // not created yet; the first replay will create it
+			}
+			if err != nil {
+				logger.Errorf("Failed to scan %s: %v", cfg.InputDir, err)
+				continue
+			}
+			ready := w.settled(files, now)
+			if len(ready) > 0 {
+				n, s, e := ingestWatched(ctx, store, dataChan, cfg, logger, sink, ready)
+				ingested += n
+				skipped += s
+				errCount += e
+				w.markHandled(ready)
+			}
+			w.checkLastReplay(ctx, store, logger, now)
+		}
+	}
+}
+
+// ingestWatched hashes, dedups and parses newly settled files, sending each
+// surviving replay to the long-lived storage channel. Per-file failures are
+// logged and counted; they never stop the watcher.
+func ingestWatched(ctx context.Context, store storage.Storage, dataChan storage.ReplayDataChannel, cfg Config, logger *Logger, sink *profile.Sink, ready []fileops.FileInfo) (ingested, skipped, errCount int64) {
+	hashed, err := fileops.HashFiles(ctx, ready)
+	if err != nil {
+		logger.Errorf("Failed to hash new replays: %v", err)
+		return 0, 0, int64(len(ready))
+	}
+	fresh, err := store.FilterOutExistingReplays(ctx, hashed)
+	if err != nil {
+		logger.Errorf("Failed to check existing replays: %v", err)
+		return 0, 0, int64(len(ready))
+	}
+	skipped = int64(len(hashed) - len(fresh))
+	if skipped > 0 {
+		logger.Warnf("Skipping %d replays already in the database", skipped)
+	}
+	candidates := fresh
+	if fresh, err = filterKnownFailures(ctx, store, cfg, candidates, logger); err != nil {
+		logger.Errorf("Failed to check ingest failures: %v", err)
+		return 0, skipped, int64(len(candidates))
+	}
+	skipped += int64(len(candidates) - len(fresh))
+
+	var ledger failureLedger
+	defer ledger.flush(ctx, store, logger)
+
+	for i := range fresh {
+		fileInfo := fresh[i]
+		logger.Infof("New replay: %s", fileInfo.Name)
+		if err := processFileToChannel(ctx, dataChan, &fileInfo, parserOptions(cfg), sink); err != nil {
+			if errors.Is(err, errSkippedUMS) {
+				ledger.add(&fileInfo, err)
+				logger.Warnf("Skipping UMS replay: %s", fileInfo.Name)
+				skipped++
+				continue
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			ledger.add(&fileInfo, err)
+			logger.Errorf("Error processing file %s: %v", fileInfo.Name, err)
+			errCount++
+			continue
+		}
+		ingested++
+	}
+	return ingested, skipped, errCount
+}
+
+// checkLastReplay notices LastReplay.rep being rewritten (a game just ended)
+// and, once the grace period passes, warns if that game never reached the
+// database under another file name — the usual cause is StarCraft's replay
+// autosave being off, in which case the game is lost on the next overwrite.
+func (w *watcher) checkLastReplay(ctx context.Context, store storage.Storage, logger *Logger, now time.Time) {
+	if stamp, ok := w.statLastReplay(); ok && stamp != w.lastReplayStamp {
+		if now.Sub(stamp.modTime) < w.settle {
+			return // still being written; look again next poll
+		}
+		w.lastReplayStamp = stamp
+		info, err := fileops.NewFileInfoFromPath(w.lastReplayPath())
+		if err != nil {
+			logger.Errorf("Failed to read %s: %v", lastReplayFileName, err)
+			return
+		}
+		w.lastReplayChecksum = info.Checksum
+		w.lastReplayDeadline = now.Add(lastReplayGrace)
+		logger.Infof("Game finished (%s updated); waiting for its saved copy", lastReplayFileName)
+	}
+
+	if w.lastReplayChecksum == "" || now.Before(w.lastReplayDeadline) {
+		return
+	}
+	checksum := w.lastReplayChecksum
+	w.lastReplayChecksum = ""
+	exists, err := store.ReplayExists(ctx, "", checksum)
+	if err != nil {
+		logger.Errorf("Failed to check %s: %v", lastReplayFileName, err)
+		return
+	}
+	if !exists {
+		logger.Warnf("The game in %s was not saved under its own name, so it can't be ingested (it is overwritten every game). Enable replay autosave in StarCraft to capture every game.", lastReplayFileName)
+	}
+}
@@ -0,0 +1,193 @@
+package ingest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/marianogappa/screpdb/internal/fileops"
+	"github.com/marianogappa/screpdb/internal/storage"
+)
+
+func TestWatcherSettledDebouncesGrowingFiles(t *testing.T) {
+	t0 := time.Unix(1_700_000_000, 0)
+	known := fileops.FileInfo{Path: "/r/old.rep", Size: 10, ModTime: t0}
+	w := newWatcher(t.TempDir(), 3*time.Second, []fileops.FileInfo{known})
+
+	growing := fileops.FileInfo{Path: "/r/new.rep", Size: 100, ModTime: t0}
+	if got := w.settled([]fileops.FileInfo{known, growing}, t0); len(got) != 0 {
+		t.Fatalf("first sighting must not be ready, got %v", got)
+	}
+
+	// Still being written: the stamp changes, so the settle clock restarts.
+	growing.Size, growing.ModTime = 200, t0.Add(2*time.Second)
+	if got := w.settled([]fileops.FileInfo{known, growing}, t0.Add(2*time.Second)); len(got) != 0 {
+		t.Fatalf("changed file must not be ready, got %v", got)
+	}
+	if got := w.settled([]fileops.FileInfo{known, growing}, t0.Add(4*time.Second)); len(got) != 0 {
+		t.Fatalf("file unchanged for less than the settle window must wait, got %v", got)
+	}
+
+	got := w.settled([]fileops.FileInfo{known, growing}, t0.Add(5*time.Second))
+	if len(got) != 1 || got[0].Path != growing.Path {
+		t.Fatalf("settled file should be ready exactly once, got %v", got)
+	}
+	w.markHandled(got)
+	if got := w.settled([]fileops.FileInfo{known, growing}, t0.Add(9*time.Second)); len(got) != 0 {
+		t.Fatalf("handled file must not be re-offered, got %v", got)
+	}
+
+	// Overwritten in place: a new stamp makes it a candidate again.
+	known.Size = 11
+	w.settled([]fileops.FileInfo{known}, t0.Add(10*time.Second))
+	if got := w.settled([]fileops.FileInfo{known}, t0.Add(20*time.Second)); len(got) != 1 {
+		t.Fatalf("rewritten file should be re-offered, got %v", got)
+	}
+}
+
+func TestRun_WatchIngestsNewReplays(t *testing.T) {
+	inputDir := seedReplayDir(t, smallTestReplays[0])
+	dbPath := filepath.Join(t.TempDir(), "x.db")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- Run(ctx, Config{
+			InputDir:          inputDir,
+			SQLitePath:        dbPath,
+			Logger:            quietLogger(),
+			Watch:             true,
+			WatchPollInterval: 20 * time.Millisecond,
+			WatchSettle:       50 * time.Millisecond,
+		})
+	}()
+
+	waitForReplays(t, dbPath, 1, done)
+
+	data, err := os.ReadFile(filepath.Join(testdataReplayDir(t), smallTestReplays[1]))
+	if err != nil {
+		t.Fatalf("read replay: %v", err)
+	}
+	autosave := filepath.Join(inputDir, "AutoSave")
+	if err := os.MkdirAll(autosave, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(autosave, smallTestReplays[1]), data, 0o644); err != nil {
+		t.Fatalf("write replay: %v", err)
+	}
+	// The same game saved again under another name must dedup by checksum.
+	if err := os.WriteFile(filepath.Join(inputDir, "copy.rep"), data, 0o644); err != nil {
+		t.Fatalf("write replay copy: %v", err)
+	}
+
+	waitForReplays(t, dbPath, 2, done)
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run in watch mode should stop cleanly on cancel, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+	if got := countRows(t, dbPath, "replays"); got != 2 {
+		t.Fatalf("replays: got %d, want 2 (checksum duplicate must be skipped)", got)
+	}
+}
+
+// waitForReplays polls the database until it holds want replays, failing fast
+// if the watcher returns early.
+func waitForReplays(t *testing.T, dbPath string, want int64, done <-chan error) {
+	t.Helper()
+	deadline := time.Now().Add(30 * time.Second)
+	for time.Now().Before(deadline) {
+		select {
+		case err := <-done:
+			t.Fatalf("Run returned while watching: %v", err)
+		default:
+		}
+		if _, err := os.Stat(dbPath); err == nil && countRowsIfReady(dbPath) >= want {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d replays", want)
+}
+
+// countRowsIfReady is countRows without failing the test while the schema is
+// still being created.
+func countRowsIfReady(dbPath string) int64 {
+	store, err := storage.NewSQLiteStorage(dbPath)
+	if err != nil {
+		return -1
+	}
+	defer store.Close()
+	rows, err := store.Query(context.Background(), "SELECT COUNT(*) AS c FROM replays")
+	if err != nil || len(rows) == 0 {
+		return -1
+	}
+	c, _ := rows[0]["c"].(int64)
+	return c
+}
+
+func TestRun_WatchStartsWithoutReplays(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		create bool
+	}{
+		{name: "empty folder", create: true},
+		{name: "missing folder", create: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			inputDir := filepath.Join(t.TempDir(), "Replays")
+			if tc.create {
+				if err := os.Mkdir(inputDir, 0o755); err != nil {
+					t.Fatalf("mkdir: %v", err)
+				}
+			}
+			dbPath := filepath.Join(t.TempDir(), "x.db")
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			done := make(chan error, 1)
+			go func() {
+				done <- Run(ctx, Config{
+					InputDir:          inputDir,
+					SQLitePath:        dbPath,
+					Logger:            quietLogger(),
+					Watch:             true,
+					WatchPollInterval: 20 * time.Millisecond,
+					WatchSettle:       50 * time.Millisecond,
+				})
+			}()
+
+			// The first replay lands after watching started.
+			time.Sleep(200 * time.Millisecond)
+			data, err := os.ReadFile(filepath.Join(testdataReplayDir(t), smallTestReplays[0]))
+			if err != nil {
+				t.Fatalf("read replay: %v", err)
+			}
+			if err := os.MkdirAll(inputDir, 0o755); err != nil {
+				t.Fatalf("mkdir: %v", err)
+			}
+			if err := os.WriteFile(filepath.Join(inputDir, smallTestReplays[0]), data, 0o644); err != nil {
+				t.Fatalf("write replay: %v", err)
+			}
+			waitForReplays(t, dbPath, 1, done)
+
+			cancel()
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Fatalf("Run in watch mode should stop cleanly on cancel, got %v", err)
+				}
+			case <-time.After(10 * time.Second):
+				t.Fatal("Run did not return after cancel")
+			}
+		})
+	}
+}
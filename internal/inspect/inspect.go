@@ -0,0 +1,141 @@
+// Package inspect runs the full analysis pipeline (parser, pattern
+// orchestrator, worldstate engine and alliance analysis) on a single replay
+// file entirely in memory, for the `screpdb inspect` subcommand. Nothing is
+// written anywhere, so a misdetection can be reproduced from the .rep alone.
+package inspect
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+	"sort"
+
+	"github.com/marianogappa/screpdb/internal/fileops"
+	"github.com/marianogappa/screpdb/internal/models"
+	"github.com/marianogappa/screpdb/internal/parser"
+	"github.com/marianogappa/screpdb/internal/patterns"
+	"github.com/marianogappa/screpdb/internal/patterns/core"
+	"github.com/marianogappa/screpdb/internal/patterns/markers"
+	"github.com/marianogappa/screpdb/internal/patterns/worldstate"
+)
+
+// Report is everything screpdb derives from one replay. Player references
+// use the replay's own player IDs (models.Player.PlayerID), since there is no
+// database to map them to.
+type Report struct {
+	Replay     *models.Replay              `json:"replay"`
+	Players    []*models.Player            `json:"players"`
+	Detections []Detection                 `json:"detections"`
+	Narrative  []worldstate.NarrativeEntry `json:"narrative"`
+	Alliances  *Alliances                  `json:"alliances,omitempty"`
+}
+
+// Detection is one core.PatternResult, resolved against the marker registry.
+type Detection struct {
+	FeatureKey  string          `json:"feature_key,omitempty"`
+	Name        string          `json:"name"`
+	PatternName string          `json:"pattern_name"`
+	Kind        markers.Kind    `json:"kind,omitempty"`
+	PlayerID    *byte           `json:"player_id,omitempty"` // replay player ID; nil for replay-level results
+	Second      int             `json:"second"`
+	Payload     json.RawMessage `json:"payload,omitempty"`
+}
+
+// IsOpener reports whether the detection is the player's initial build order.
+func (d Detection) IsOpener() bool { return d.Kind == markers.KindInitialBuildOrder }
+
+// Alliances is the JSON-friendly subset of parser.AllianceResult. It is only
+// present for melee games with more than two active players.
+type Alliances struct {
+	Snapshots         []parser.AllianceSnapshot `json:"snapshots"`
+	ResolvedTeams     map[byte]byte             `json:"resolved_teams,omitempty"` // replay player ID → team
+	AnyMutualResolved bool                      `json:"any_mutual_resolved"`
+	TeamStacking      bool                      `json:"team_stacking"`
+}
+
+// File analyzes the replay at path. The caller must have registered the
+// file's folder with iofacade.AllowDir.
+func File(path string, opts parser.Options) (report *Report, err error) {
+	info, err := fileops.NewFileInfoFromPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// A parser panic on a malformed replay should surface as an error, not
+	// crash the CLI: inspect is exactly what bug reports will be run through.
+	defer func() {
+		if r := recover(); r != nil {
+			report, err = nil, fmt.Errorf("panic while analyzing replay (this is a bug — please report it at "+
+				"https://github.com/marianogappa/screpdb/issues): %v\n%s", r, debug.Stack())
+		}
+	}()
+
+	replay := parser.CreateReplayFromFileInfo(info.Path, info.Name, info.Size, info.Checksum)
+	data, err := parser.ParseReplayWithOptions(info.Path, replay, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse replay: %w", err)
+	}
+	orch, ok := data.PatternOrchestrator.(*patterns.Orchestrator)
+	if !ok {
+		return nil, fmt.Errorf("parser returned no pattern orchestrator")
+	}
+
+	// GetResults finalizes the worldstate engine, so the narrative is read
+	// after it.
+	results := orch.GetResults()
+	report = &Report{
+		Replay:     data.Replay,
+		Players:    data.Players,
+		Detections: detections(results),
+		Narrative:  []worldstate.NarrativeEntry{},
+	}
+	if ws := orch.WorldStateEngine(); ws != nil {
+		report.Narrative = ws.Entries()
+	}
+	if ar, ok := data.Alliances.(*parser.AllianceResult); ok && ar != nil {
+		report.Alliances = &Alliances{
+			Snapshots:         ar.Snapshots,
+			ResolvedTeams:     ar.ResolvedTeams,
+			AnyMutualResolved: ar.AnyMutualResolved,
+			TeamStacking:      ar.TeamStackingFlag,
+		}
+	}
+	return report, nil
+}
+
+// detections converts results into Detections ordered by second, then player,
+// then feature key, so repeated runs print identically.
+func detections(results []*core.PatternResult) []Detection {
+	out := make([]Detection, 0, len(results))
+	for _, r := range results {
+		d := Detection{
+			Name:        r.PatternName,
+			PatternName: r.PatternName,
+			PlayerID:    r.ReplayPlayerID,
+			Second:      r.DetectedAtSecond,
+			Payload:     r.Payload,
+		}
+		if m := markers.ByPatternName(r.PatternName); m != nil {
+			d.FeatureKey, d.Name, d.Kind = m.FeatureKey, m.Name, m.Kind
+		}
+		out = append(out, d)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Second != b.Second {
+			return a.Second < b.Second
+		}
+		if pa, pb := playerSortKey(a.PlayerID), playerSortKey(b.PlayerID); pa != pb {
+			return pa < pb
+		}
+		return a.PatternName < b.PatternName
+	})
+	return out
+}
+
+func playerSortKey(pid *byte) int {
+	if pid == nil {
+		return -1
+	}
+	return int(*pid)
+}
@@ -0,0 +1,116 @@
+package inspect
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/marianogappa/screpdb/internal/parser"
+)
+
+func replayPath(t *testing.T, parts ...string) string {
+	t.Helper()
+	_, thisFile, _, ok := runtime.Caller(0)
+	if !ok {
+		t.Fatal("runtime.Caller failed")
+	}
+	return filepath.Join(append([]string{filepath.Dir(thisFile), ".."}, parts...)...)
+}
+
+func TestFile_OneOnOne(t *testing.T) {
+	r, err := File(replayPath(t, "patterns", "markers", "testdata", "replays", "bo_8pool_zvt_loveaddio.rep"), parser.Options{})
+	if err != nil {
+		t.Fatalf("File: %v", err)
+	}
+	if r.Alliances != nil {
+		t.Fatalf("1v1 should carry no alliance analysis, got %+v", r.Alliances)
+	}
+	if len(r.Narrative) == 0 {
+		t.Fatal("expected a narrative timeline")
+	}
+
+	var zerg byte
+	winners := 0
+	for _, p := range r.Players {
+		if p.Name == "loveaddio" {
+			zerg = p.PlayerID
+		}
+		if p.IsWinner {
+			winners++
+		}
+	}
+	if winners != 1 {
+		t.Fatalf("expected exactly one winner, got %d", winners)
+	}
+	found := false
+	for i, d := range r.Detections {
+		if i > 0 && r.Detections[i-1].Second > d.Second {
+			t.Fatalf("detections not ordered by second: %+v", r.Detections)
+		}
+		if d.IsOpener() && d.PlayerID != nil && *d.PlayerID == zerg {
+			if d.FeatureKey != "bo_8_pool" {
+				t.Fatalf("loveaddio opener = %q, want bo_8_pool", d.FeatureKey)
+			}
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("no opener detected for loveaddio: %+v", r.Detections)
+	}
+
+	var text bytes.Buffer
+	if err := Write(&text, FormatText, r); err != nil {
+		t.Fatalf("Write text: %v", err)
+	}
+	for _, want := range []string{"Players:", "8 Pool", "Detections:", "bo_8_pool", "Timeline:", "player_start"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("text output missing %q:\n%s", want, text.String())
+		}
+	}
+	if strings.Contains(text.String(), "Alliances:") {
+		t.Error("text output should omit the alliance section for a 1v1")
+	}
+}
+
+func TestFile_MeleeAlliancesAndJSON(t *testing.T) {
+	r, err := File(replayPath(t, "testdata", "replays", "bgh.rep"), parser.Options{})
+	if err != nil {
+		t.Fatalf("File: %v", err)
+	}
+	if r.Alliances == nil || len(r.Alliances.Snapshots) == 0 || !r.Alliances.AnyMutualResolved {
+		t.Fatalf("expected resolved alliances for an 8-player melee, got %+v", r.Alliances)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatJSON, r); err != nil {
+		t.Fatalf("Write json: %v", err)
+	}
+	var decoded struct {
+		Players    []json.RawMessage `json:"players"`
+		Detections []Detection       `json:"detections"`
+		Alliances  *Alliances        `json:"alliances"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if len(decoded.Players) != len(r.Players) || len(decoded.Detections) != len(r.Detections) {
+		t.Fatalf("JSON round trip lost rows: players %d/%d detections %d/%d",
+			len(decoded.Players), len(r.Players), len(decoded.Detections), len(r.Detections))
+	}
+	if decoded.Alliances == nil || len(decoded.Alliances.ResolvedTeams) != len(r.Alliances.ResolvedTeams) {
+		t.Fatalf("JSON round trip lost alliances: %+v", decoded.Alliances)
+	}
+
+	if err := Write(&buf, "yaml", r); err == nil {
+		t.Fatal("expected an unknown format to be rejected")
+	}
+}
+
+func TestFile_MissingFile(t *testing.T) {
+	if _, err := File(filepath.Join(t.TempDir(), "nope.rep"), parser.Options{}); err == nil {
+		t.Fatal("expected an error for a missing replay")
+	}
+}
@@ -0,0 +1,142 @@
+package inspect
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/marianogappa/screpdb/internal/models"
+)
+
+// Output formats accepted by Write.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Write renders the report to w as human-readable text or indented JSON.
+func Write(w io.Writer, format string, r *Report) error {
+	switch format {
+	case FormatText:
+		return writeText(w, r)
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	default:
+		return fmt.Errorf("unknown format %q (want %s or %s)", format, FormatText, FormatJSON)
+	}
+}
+
+func writeText(w io.Writer, r *Report) error {
+	rp := r.Replay
+	names := make(map[byte]string, len(r.Players))
+	for _, p := range r.Players {
+		names[p.PlayerID] = p.Name
+	}
+	openers := map[byte]string{}
+	for _, d := range r.Detections {
+		if d.IsOpener() && d.PlayerID != nil {
+			openers[*d.PlayerID] = d.Name
+		}
+	}
+
+	fmt.Fprintf(w, "Replay:   %s\n", rp.FilePath)
+	fmt.Fprintf(w, "Checksum: %s\n", rp.FileChecksum)
+	fmt.Fprintf(w, "Map:      %s (%s)\n", rp.MapName, rp.MapKind)
+	fmt.Fprintf(w, "Game:     %s %s %s, %s, %s\n", rp.GameType, rp.TeamFormat, rp.Matchup, formatClock(rp.DurationSeconds), rp.ReplayDate.Format("2006-01-02 15:04"))
+
+	fmt.Fprintln(w, "\nPlayers:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  id\tname\trace\tteam\tcolor\tapm\teapm\twinner\topener")
+	for _, p := range r.Players {
+		opener := openers[p.PlayerID]
+		if opener == "" {
+			opener = "-"
+		}
+		fmt.Fprintf(tw, "  %d\t%s\t%s\t%d\t%s\t%d\t%d\t%s\t%s\n", p.PlayerID, playerLabel(p), p.Race, p.Team, p.Color, p.APM, p.EAPM, yesNo(p.IsWinner), opener)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "\nDetections:")
+	if len(r.Detections) == 0 {
+		fmt.Fprintln(w, "  none")
+	}
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, d := range r.Detections {
+		who := "(replay)"
+		if d.PlayerID != nil {
+			who = names[*d.PlayerID]
+		}
+		key := d.FeatureKey
+		if key == "" {
+			key = d.PatternName
+		}
+		kind := "marker"
+		if d.IsOpener() {
+			kind = "opener"
+		}
+		line := fmt.Sprintf("  %s\t%s\t%s\t%s", formatClock(d.Second), who, kind, key)
+		if len(d.Payload) > 0 {
+			line += "\t" + string(d.Payload)
+		}
+		fmt.Fprintln(tw, line)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "\nTimeline:")
+	if len(r.Narrative) == 0 {
+		fmt.Fprintln(w, "  none")
+	}
+	for _, e := range r.Narrative {
+		fmt.Fprintf(w, "  %6s  %-22s %s\n", formatClock(e.Second), e.Type, e.Description)
+	}
+
+	if a := r.Alliances; a != nil {
+		fmt.Fprintln(w, "\nAlliances:")
+		for _, s := range a.Snapshots {
+			teams := make([]string, 0, len(s.Teams))
+			for _, team := range s.Teams {
+				members := make([]string, 0, len(team))
+				for _, pid := range team {
+					members = append(members, names[pid])
+				}
+				teams = append(teams, "["+strings.Join(members, ", ")+"]")
+			}
+			stacking := ""
+			if s.Stacking {
+				stacking = "  (stacking)"
+			}
+			fmt.Fprintf(w, "  %6s  %s%s\n", formatClock(s.Sec), strings.Join(teams, " vs "), stacking)
+		}
+		fmt.Fprintf(w, "  Team stacking: %s\n", yesNo(a.TeamStacking))
+	}
+	return nil
+}
+
+func playerLabel(p *models.Player) string {
+	if p.IsObserver {
+		return p.Name + " (obs)"
+	}
+	return p.Name
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func formatClock(second int) string {
+	if second < 0 {
+		second = 0
+	}
+	return fmt.Sprintf("%d:%02d", second/60, second%60)
+}
@@ -5,7 +5,9 @@ import (
 	_ "embed"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/marianogappa/screpdb/internal/chatsearch"
 	"github.com/marianogappa/screpdb/internal/storage"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -38,7 +40,7 @@ func NewServer(storage storage.Storage) *Server {
 	// natural-language questions about any ingested game or player by running
 	// read-only SQL. Call get_database_schema first to learn the real columns.
 	sqlTool := mcp.NewTool("query_database",
-		mcp.WithDescription("Run a read-only SQL query (SELECT/WITH/EXPLAIN/PRAGMA only) against the StarCraft: Remastered replay database and get the rows back. Tables: replays (one row per game: map, matchup, duration, engine), players (one row per player per replay: race, APM/eAPM, is_winner, start location), commands (the ordered action stream — builds, trains, morphs, tech, upgrades, micro), commands_low_value (high-volume noise: right-clicks, hotkeys, pings — usually excluded), replay_events (derived analysis: build-order openers, timing markers, and narrative game events like rushes/drops/proxies), player_aliases (maps battle.net tags to canonical player identities). Call get_database_schema for exact columns and get_starcraft_knowledge for domain terms before writing non-trivial queries."),
+		mcp.WithDescription("Run a read-only SQL query (SELECT/WITH/EXPLAIN/PRAGMA only) against the StarCraft: Remastered replay database and get the rows back. Tables: replays (one row per replay file: map, matchup, duration, engine; several players' files of the same game share a game_group_id, so count games with COUNT(DISTINCT replays.game_group_id) or keep only replays.id = replays.game_group_id), players (one row per player per replay: race, APM/eAPM, is_winner, start location), commands (the ordered action stream — builds, trains, morphs, tech, upgrades, micro), commands_low_value (high-volume noise: right-clicks, hotkeys, pings — usually excluded; query commands_low_value_all instead to include the ones compacted into command_blobs), replay_events (derived analysis: build-order openers, timing markers, and narrative game events like rushes/drops/proxies), player_aliases (maps battle.net tags to canonical player identities). Prefer the analyst views where they fit: analyst_player_games_v1 (one row per player per game with result and build-order opener), analyst_build_order_steps_v1, analyst_attacks_v1, analyst_attack_units_v1, analyst_attack_casts_v1, analyst_drops_v1 and analyst_opener_timings_v1 (opener milestones against progamer timings); they do the joins, unpack the JSON columns and keep their columns across screpdb versions. Call get_database_schema for exact columns and get_starcraft_knowledge for domain terms before writing non-trivial queries."),
 		mcp.WithString("sql",
 			mcp.Required(),
 			mcp.Description("A single read-only SQL statement (SELECT, WITH, EXPLAIN, or PRAGMA). Writes are rejected."),
@@ -73,7 +75,7 @@ func NewServer(storage storage.Storage) *Server {
 	// Curated discovery tools so an agent can orient itself without guessing
 	// SQL against an empty result set.
 	playersTool := mcp.NewTool("list_top_players",
-		mcp.WithDescription("List the human players with the most games in the database (name, game count, and the races they've played). Use this to discover who is in the corpus before asking player-specific questions."),
+		mcp.WithDescription("List the human players with the most games in the database (name, game count with each game counted once even when several players saved it, and the races they've played). Use this to discover who is in the corpus before asking player-specific questions."),
 		mcp.WithNumber("limit",
 			mcp.Description("Maximum number of players to return (default 25)."),
 		),
@@ -91,6 +93,38 @@ func NewServer(storage storage.Storage) *Server {
 	)
 	mcpServer.AddTool(eventsTool, s.handleListEventTypes)
 
+	chatTool := mcp.NewTool("search_chat",
+		mcp.WithDescription("Full-text search over in-game chat. Returns each message containing every word of text, with its replay (id, date, map, matchup), speaker, game time and the chat lines just before and after it, newest games first. Use it to find games where someone said gg early, typed out a build order, or trash-talked. A word ending in * matches as a prefix (\"allie*\"); punctuation is ignored."),
+		mcp.WithString("text",
+			mcp.Required(),
+			mcp.Description("Words to search for; all must appear in the message, in any order."),
+		),
+		mcp.WithArray("players",
+			mcp.WithStringItems(),
+			mcp.Description("Only messages typed by these players (names, case-insensitive)."),
+		),
+		mcp.WithArray("matchups",
+			mcp.WithStringItems(),
+			mcp.Description("Only games with these replays.matchup values, e.g. PvZ (case-insensitive)."),
+		),
+		mcp.WithString("from",
+			mcp.Description("Only games played on or after this date (YYYY-MM-DD)."),
+		),
+		mcp.WithString("to",
+			mcp.Description("Only games played on or before this date (YYYY-MM-DD)."),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description(fmt.Sprintf("Maximum number of messages to return (default %d, max %d).", chatsearch.DefaultLimit, chatsearch.MaxLimit)),
+		),
+		mcp.WithNumber("context",
+			mcp.Description(fmt.Sprintf("Chat lines to show before and after each message (default %d, max %d, 0 for none).", chatsearch.DefaultContext, chatsearch.MaxContext)),
+		),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithOpenWorldHintAnnotation(false),
+	)
+	mcpServer.AddTool(chatTool, s.handleSearchChat)
+
 	return s
 }
 
@@ -105,9 +139,11 @@ func (s *Server) Start(ctx context.Context) error {
 // (or the LLM driving it) mutate it — this tool is for questions, not edits.
 var readOnlyLeadingKeywords = []string{"SELECT", "WITH", "EXPLAIN", "PRAGMA"}
 
-// ensureReadOnly rejects anything that isn't a single read-only statement.
-func ensureReadOnly(sql string) error {
-	stmt := stripSQLComments(sql)
+// EnsureReadOnly rejects anything that isn't a single read-only statement.
+// Shared with the `screpdb query` subcommand so both surfaces enforce the same
+// guard.
+func EnsureReadOnly(sql string) error {
+	stmt := StripSQLComments(sql)
 
 	// Disallow stacked statements (e.g. "SELECT 1; DROP TABLE x"). A single
 	// trailing semicolon is fine.
@@ -128,9 +164,9 @@ func ensureReadOnly(sql string) error {
 	return fmt.Errorf("only read-only queries are allowed (must start with one of %s); got %q", strings.Join(readOnlyLeadingKeywords, ", "), leading)
 }
 
-// stripSQLComments removes -- line comments and /* */ block comments so the
+// StripSQLComments removes -- line comments and /* */ block comments so the
 // leading-keyword check can't be fooled by a comment prefix.
-func stripSQLComments(sql string) string {
+func StripSQLComments(sql string) string {
 	var b strings.Builder
 	for i := 0; i < len(sql); i++ {
 		if i+1 < len(sql) && sql[i] == '-' && sql[i+1] == '-' {
@@ -159,7 +195,7 @@ func (s *Server) handleSQLQuery(ctx context.Context, request mcp.CallToolRequest
 		return mcp.NewToolResultError(fmt.Sprintf("Invalid sql parameter: %v", err)), nil
 	}
 
-	if err := ensureReadOnly(query); err != nil {
+	if err := EnsureReadOnly(query); err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
 
@@ -184,10 +220,16 @@ func (s *Server) handleGetSchema(ctx context.Context, request mcp.CallToolReques
 
 	// Add some observations about the dataset
 	observations := `
+	- Start from the analyst views (analyst_*_v1) when one answers the question: they join replays and players, unpack replay_events' JSON columns (payload, attack_unit_types, attack_cast_counts) and keep the same columns across screpdb versions, while the tables below may change. A view's row is described under its heading above. Booleans in them are 0/1 on SQLite and true/false on PostgreSQL. Examples:
+		- Zerg win rate per opener against Terran: SELECT opener_name, AVG(is_winner) FROM analyst_player_games_v1 WHERE race = 'Zerg' AND matchup = 'TvZ' AND is_canonical GROUP BY 1
+		- How late a player's pool is against the progamer timing: SELECT opener, milestone, AVG(delta_seconds) FROM analyst_opener_timings_v1 WHERE player_name = 'x' AND found GROUP BY 1, 2
+		- Units in attacks: SELECT unit_type, COUNT(*) FROM analyst_attack_units_v1 GROUP BY 1 ORDER BY 2 DESC
 	- Replays have up to 8 players (and up to 4 observers) and a sequential list of commands/actions (like Chess). Command timing is tracked in "frames" since game start and also with a timestamp (seconds_from_game_start).
 	- The commands table has action-type-specific fields, so for a given row many fields are null.
 	- commands vs commands_low_value: high-signal actions (Build, Train, morphs, Tech, Upgrade, targeted micro) live in commands; high-volume noise (Right Click, Hotkey, Minimap Ping, Vision, Alliance) is split into commands_low_value so it can be excluded from analysis. Same schema in both. Right-clicks/hotkeys are only stored if ingestion was configured to keep them, so don't assume they exist.
+	- Databases ingested with --compact-commands (or converted with screpdb compact) keep Right Click, Hotkey and Minimap Ping rows in per-replay command_blobs instead of commands_low_value. The commands_low_value_all view reads both, with the same columns (id is NULL for compacted rows); prefer it whenever right-clicks, hotkeys or pings matter.
 	- replay_events is the DERIVED analysis layer (not raw stream). event_kind = 'marker' rows are one-per-(replay, player, event_type) summaries screpdb computed — build-order openers are stored as event_type feature keys prefixed 'bo_' (e.g. bo_9_pool, bo_12_hatch, bo_gate_expand, bo_t_111); opener_unresolved / *_fuzzy / bo_*_other are catch-alls. Other markers are timings/behaviours (e.g. used_hotkey_groups, viewport_multitasking, never_upgraded). event_kind = 'game_event' rows are narrative moments (rushes, drops, proxies, nydus, mind control, scout, expansion). source_player_id/target_player_id join to players.id; location_base_type ('starting'|'natural'|'expansion') and location_base_oclock give map position; payload is optional JSON. To discover the actual event_type values, use the list_event_types tool or: SELECT event_kind, event_type, COUNT(*) FROM replay_events GROUP BY 1,2 ORDER BY 3 DESC.
+	- A game saved by several of its players is stored once per replay file (one row per POV in replays, each with its own players/commands/replay_events). replays.game_group_id is the id of the game's canonical (longest) replay and is shared by every POV; replays.game_fingerprint is the identity hash behind the grouping ('' for replays ingested before it existed, until they are re-analyzed). To count each game once, add WHERE replays.id = replays.game_group_id, or count COUNT(DISTINCT replays.game_group_id).
 	- player_aliases maps battle.net tags to canonical player identities. players.name is the raw in-replay name; join through player_aliases (battle_tag_normalized) when you need to group a person's games across smurfs/tags.
 
 	- JOIN patterns:
@@ -200,6 +242,7 @@ func (s *Server) handleGetSchema(ctx context.Context, request mcp.CallToolReques
 	- Common WHERE clauses:
 		- players.type = 'Human' (i.e. skip 'Computer' players)
 		- players.is_observer = false (i.e. Observer players are not part of the game)
+		- replays.id = replays.game_group_id (i.e. one row per game, skipping the duplicate POVs of games several players saved)
 		- replays.matchup is a normalized string like 'TvZ' or 'PvP' for 1v1s (and e.g. 'PvPvTvZ' for larger games); replays.duration_seconds is already in seconds, frame_count is the raw frame length (≈ 23.81 frames/sec on fastest). For 1v1 analysis, filter to matchup values with a single 'v' (e.g. matchup LIKE '_v_').
 
 	action_types:
@@ -334,21 +377,27 @@ func (s *Server) handleGetStarCraftKnowledge(ctx context.Context, request mcp.Ca
 	return mcp.NewToolResultText(starcraftKnowledge), nil
 }
 
-// handleListTopPlayers lists the human players with the most games.
+// handleListTopPlayers lists the human players with the most games. A game
+// saved by several of its players counts once.
 func (s *Server) handleListTopPlayers(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	limit := request.GetInt("limit", 25)
 	if limit <= 0 || limit > 500 {
 		limit = 25
 	}
+	races := "GROUP_CONCAT(DISTINCT p.race)"
+	if s.storage.StorageName() == storage.StoragePostgres {
+		races = "STRING_AGG(DISTINCT p.race, ',')"
+	}
 	query := fmt.Sprintf(`
-		SELECT name,
-		       COUNT(*) AS games,
-		       GROUP_CONCAT(DISTINCT race) AS races
-		FROM players
-		WHERE type = 'Human' AND is_observer = 0
-		GROUP BY name
+		SELECT p.name,
+		       COUNT(DISTINCT COALESCE(r.game_group_id, r.id)) AS games,
+		       %s AS races
+		FROM players p
+		JOIN replays r ON r.id = p.replay_id
+		WHERE p.type = 'Human' AND NOT p.is_observer
+		GROUP BY p.name
 		ORDER BY games DESC
-		LIMIT %d`, limit)
+		LIMIT %d`, races, limit)
 
 	results, err := s.storage.Query(ctx, query)
 	if err != nil {
@@ -372,6 +421,66 @@ func (s *Server) handleListEventTypes(ctx context.Context, request mcp.CallToolR
 	return mcp.NewToolResultText(s.formatQueryResults(results)), nil
 }
 
+// handleSearchChat runs a chat full-text search and lists the hits with
+// their surrounding chat.
+func (s *Server) handleSearchChat(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	text, err := request.RequireString("text")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid text parameter: %v", err)), nil
+	}
+	opts := chatsearch.Options{
+		Text:     text,
+		Players:  request.GetStringSlice("players", nil),
+		Matchups: request.GetStringSlice("matchups", nil),
+		From:     strings.TrimSpace(request.GetString("from", "")),
+		To:       strings.TrimSpace(request.GetString("to", "")),
+		Limit:    request.GetInt("limit", 0),
+		Context:  request.GetInt("context", chatsearch.DefaultContext),
+	}
+	if opts.Context == 0 {
+		opts.Context = -1
+	}
+	for _, date := range []string{opts.From, opts.To} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid date %q: want YYYY-MM-DD", date)), nil
+		}
+	}
+
+	hits, err := s.storage.SearchChat(ctx, opts)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Chat search failed: %v", err)), nil
+	}
+	return mcp.NewToolResultText(formatChatHits(hits)), nil
+}
+
+// formatChatHits renders hits one block per message: the replay, then its
+// chat with the matching line marked by >.
+func formatChatHits(hits []chatsearch.Hit) string {
+	if len(hits) == 0 {
+		return "No chat messages found."
+	}
+	var output strings.Builder
+	line := func(marker string, m chatsearch.Message) {
+		output.WriteString(fmt.Sprintf("%s [%d:%02d] %s: %s\n", marker, m.Second/60, m.Second%60, m.Player, m.Text))
+	}
+	for _, h := range hits {
+		output.WriteString(fmt.Sprintf("Replay %d | %s | %s | %s\n", h.ReplayID, h.ReplayDate, h.MapName, h.Matchup))
+		for _, m := range h.Before {
+			line(" ", m)
+		}
+		line(">", h.Message)
+		for _, m := range h.After {
+			line(" ", m)
+		}
+		output.WriteString("\n")
+	}
+	output.WriteString(fmt.Sprintf("Total messages: %d", len(hits)))
+	return output.String()
+}
+
 // formatQueryResults formats query results for display
 func (s *Server) formatQueryResults(results []map[string]any) string {
 	if len(results) == 0 {
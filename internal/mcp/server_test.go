@@ -6,6 +6,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/marianogappa/screpdb/internal/chatsearch"
 	"github.com/marianogappa/screpdb/internal/storage"
 	"github.com/mark3labs/mcp-go/mcp"
 )
@@ -136,7 +137,7 @@ func TestHandleSQLQuery_RejectsWrites(t *testing.T) {
 		"EXPLAIN QUERY PLAN SELECT * FROM replays",
 		"SELECT 1;",
 	} {
-		if err := ensureReadOnly(sql); err != nil {
+		if err := EnsureReadOnly(sql); err != nil {
 			t.Fatalf("expected %q to be allowed: %v", sql, err)
 		}
 	}
@@ -161,6 +162,9 @@ func TestHandleGetSchema(t *testing.T) {
 	if !strings.Contains(out, "replay_events") || !strings.Contains(out, "player_aliases") {
 		t.Fatalf("schema missing modern tables (replay_events/player_aliases)")
 	}
+	if !strings.Contains(out, "## analyst_player_games_v1") || !strings.Contains(out, "analyst_opener_timings_v1 WHERE") {
+		t.Fatalf("schema missing the analyst views")
+	}
 }
 
 func TestHandleListTopPlayers(t *testing.T) {
@@ -176,6 +180,53 @@ func TestHandleListTopPlayers(t *testing.T) {
 	}
 }
 
+func TestHandleSearchChat(t *testing.T) {
+	store := newTestStore(t)
+	s := NewServer(store)
+	ctx := context.Background()
+
+	var req mcp.CallToolRequest
+	req.Params.Arguments = map[string]any{"text": "gg", "players": []any{"someone"}}
+	res, err := s.handleSearchChat(ctx, req)
+	if err != nil {
+		t.Fatalf("handleSearchChat: %v", err)
+	}
+	if res.IsError || textOf(t, res) != "No chat messages found." {
+		t.Fatalf("unexpected result on an empty corpus: %s", textOf(t, res))
+	}
+
+	for _, args := range []map[string]any{
+		nil,
+		{"text": "?!"},
+		{"text": "gg", "from": "last week"},
+	} {
+		req.Params.Arguments = args
+		res, err := s.handleSearchChat(ctx, req)
+		if err != nil {
+			t.Fatalf("handleSearchChat(%v): %v", args, err)
+		}
+		if !res.IsError {
+			t.Errorf("handleSearchChat(%v) should be an error result, got %s", args, textOf(t, res))
+		}
+	}
+}
+
+func TestFormatChatHits(t *testing.T) {
+	out := formatChatHits([]chatsearch.Hit{{
+		Message:    chatsearch.Message{Player: "Flash", Second: 75, Text: "gg"},
+		ReplayID:   3,
+		ReplayDate: "2024-05-01",
+		MapName:    "Polypoid",
+		Matchup:    "TvZ",
+		Before:     []chatsearch.Message{{Player: "Jaedong", Second: 70, Text: "nice"}},
+	}})
+	for _, want := range []string{"Replay 3 | 2024-05-01 | Polypoid | TvZ", "  [1:10] Jaedong: nice", "> [1:15] Flash: gg", "Total messages: 1"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
 func TestHandleListEventTypes(t *testing.T) {
 	store := newTestStore(t)
 	s := NewServer(store)
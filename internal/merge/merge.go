@@ -0,0 +1,117 @@
+// Package merge combines several screpdb databases into one for the
+// `screpdb merge` subcommand, so a team can pool the replays each member
+// ingested locally into a shared corpus.
+package merge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/marianogappa/screpdb/internal/iofacade"
+	"github.com/marianogappa/screpdb/internal/migrations"
+	"github.com/marianogappa/screpdb/internal/storage"
+)
+
+// Config controls a merge run.
+type Config struct {
+	// Sources are the databases copied from, in order. They are only read.
+	Sources []string
+	// Into is the database copied into. It is created if it does not exist.
+	Into string
+}
+
+// SourceResult is what one source contributed.
+type SourceResult struct {
+	Path  string
+	Stats storage.MergeStats
+}
+
+// Run validates every source, then merges them into cfg.Into one at a time.
+// Each source is merged in its own transaction, so an error leaves the
+// sources before it merged and the rest untouched; re-running is safe since
+// already merged replays are skipped by checksum.
+func Run(ctx context.Context, cfg Config) ([]SourceResult, error) {
+	if len(cfg.Sources) == 0 {
+		return nil, errors.New("no source databases given")
+	}
+	into, err := filepath.Abs(cfg.Into)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", cfg.Into, err)
+	}
+	if err := iofacade.AllowDir(filepath.Dir(into)); err != nil {
+		return nil, fmt.Errorf("failed to register database folder: %w", err)
+	}
+
+	sources := make([]string, 0, len(cfg.Sources))
+	seen := map[string]bool{}
+	for _, src := range cfg.Sources {
+		path, err := validateSource(src, into)
+		if err != nil {
+			return nil, err
+		}
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		sources = append(sources, path)
+	}
+
+	store, err := storage.NewSQLiteStorage(into)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+	if err := store.Initialize(ctx, false, false); err != nil {
+		return nil, err
+	}
+
+	results := make([]SourceResult, 0, len(sources))
+	for _, src := range sources {
+		stats, err := store.MergeFrom(ctx, src)
+		if err != nil {
+			return results, fmt.Errorf("failed to merge %s: %w", src, err)
+		}
+		results = append(results, SourceResult{Path: src, Stats: stats})
+	}
+	return results, nil
+}
+
+// validateSource resolves src and checks it is an existing database other
+// than into, whose schema this build understands.
+func validateSource(src, into string) (string, error) {
+	path, err := filepath.Abs(src)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", src, err)
+	}
+	if path == into {
+		return "", fmt.Errorf("source %s is the target database", src)
+	}
+	if err := iofacade.AllowDir(filepath.Dir(path)); err != nil {
+		return "", fmt.Errorf("failed to register source folder: %w", err)
+	}
+	info, err := iofacade.Stat(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("source database %s does not exist", src)
+		}
+		return "", fmt.Errorf("failed to stat %s: %w", src, err)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("source %s is a directory", src)
+	}
+	// A newer build may have changed the schema in ways a column-by-name
+	// copy cannot account for.
+	sets, err := migrations.Status(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read migrations of %s: %w", src, err)
+	}
+	for _, m := range sets {
+		if len(m.Unknown) > 0 {
+			return "", fmt.Errorf("source %s has %s migrations unknown to this build (%v); upgrade screpdb first", src, m.Set, m.Unknown)
+		}
+	}
+	return path, nil
+}
@@ -0,0 +1,192 @@
+package merge
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/marianogappa/screpdb/internal/ingest"
+)
+
+// ingestReplays ingests the named marker test replays into a fresh database
+// and returns its path.
+func ingestReplays(t *testing.T, names ...string) string {
+	t.Helper()
+	_, thisFile, _, _ := runtime.Caller(0)
+	src := filepath.Join(filepath.Dir(thisFile), "..", "patterns", "markers", "testdata", "replays")
+	inputDir := t.TempDir()
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(src, name))
+		if err != nil {
+			t.Fatalf("read replay: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(inputDir, name), data, 0o644); err != nil {
+			t.Fatalf("write replay: %v", err)
+		}
+	}
+	dbPath := filepath.Join(t.TempDir(), "x.db")
+	err := ingest.Run(context.Background(), ingest.Config{
+		InputDir:   inputDir,
+		SQLitePath: dbPath,
+		Logger:     ingest.NewLogger(&bytes.Buffer{}, false, nil),
+	})
+	if err != nil {
+		t.Fatalf("ingest: %v", err)
+	}
+	return dbPath
+}
+
+func openDB(t *testing.T, dbPath string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func exec(t *testing.T, dbPath string, queries ...string) {
+	t.Helper()
+	db := openDB(t, dbPath)
+	for _, q := range queries {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatalf("exec %q: %v", q, err)
+		}
+	}
+}
+
+func count(t *testing.T, dbPath, query string) int64 {
+	t.Helper()
+	var n int64
+	if err := openDB(t, dbPath).QueryRow(query).Scan(&n); err != nil {
+		t.Fatalf("query %q: %v", query, err)
+	}
+	return n
+}
+
+func TestRun_DeduplicatesAndRemapsIDs(t *testing.T) {
+	a := ingestReplays(t, "bo_bbs_tvp_standordie.rep", "bo_8pool_zvt_loveaddio.rep")
+	b := ingestReplays(t, "bo_8pool_zvt_loveaddio.rep", "bo_10pool_zvz_mentalgap.rep")
+	into := filepath.Join(t.TempDir(), "team.db")
+
+	results, err := Run(context.Background(), Config{Sources: []string{a, b}, Into: into})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("results = %+v", results)
+	}
+	if s := results[0].Stats; s.Replays != 2 || s.DuplicateReplays != 0 || s.Commands == 0 || s.ReplayEvents == 0 {
+		t.Fatalf("first source stats = %+v", s)
+	}
+	if s := results[1].Stats; s.Replays != 1 || s.DuplicateReplays != 1 {
+		t.Fatalf("second source stats = %+v, want 1 new and 1 duplicate", s)
+	}
+
+	if n := count(t, into, "SELECT COUNT(*) FROM replays"); n != 3 {
+		t.Fatalf("replays = %d, want 3", n)
+	}
+	// Every copied row must point at a replay and player of the same game.
+	for _, q := range []string{
+		"SELECT COUNT(*) FROM players p LEFT JOIN replays r ON r.id = p.replay_id WHERE r.id IS NULL",
+		"SELECT COUNT(*) FROM commands c JOIN players p ON p.id = c.player_id WHERE p.replay_id <> c.replay_id",
+		"SELECT COUNT(*) FROM commands_low_value c JOIN players p ON p.id = c.player_id WHERE p.replay_id <> c.replay_id",
+		"SELECT COUNT(*) FROM replay_events e JOIN players p ON p.id = e.source_player_id WHERE p.replay_id <> e.replay_id",
+	} {
+		if n := count(t, into, q); n != 0 {
+			t.Fatalf("%s: %d mismatched rows", q, n)
+		}
+	}
+	for _, table := range []string{"players", "commands", "commands_low_value", "replay_events"} {
+		want := count(t, a, "SELECT COUNT(*) FROM "+table) +
+			count(t, b, "SELECT COUNT(*) FROM "+table+" WHERE replay_id IN (SELECT id FROM replays WHERE file_path LIKE '%mentalgap%')")
+		if got := count(t, into, "SELECT COUNT(*) FROM "+table); got != want {
+			t.Fatalf("%s rows = %d, want %d", table, got, want)
+		}
+	}
+
+	// Merging again adds nothing.
+	results, err = Run(context.Background(), Config{Sources: []string{a, b}, Into: into})
+	if err != nil {
+		t.Fatalf("second Run: %v", err)
+	}
+	for _, r := range results {
+		if r.Stats.Replays != 0 || r.Stats.Players != 0 {
+			t.Fatalf("re-merge copied rows: %+v", r.Stats)
+		}
+	}
+}
+
+func TestRun_AliasConflictPolicy(t *testing.T) {
+	a := ingestReplays(t, "bo_bbs_tvp_standordie.rep")
+	b := ingestReplays(t, "bo_bbs_tvp_standordie.rep")
+	into := filepath.Join(t.TempDir(), "team.db")
+
+	exec(t, a,
+		"INSERT INTO player_aliases (canonical_alias, battle_tag_normalized, battle_tag_raw, source, updated_at) VALUES ('Flash', 'flash#1', 'Flash#1', 'imported', '2024-01-01 00:00:00')",
+		"INSERT INTO player_aliases (canonical_alias, battle_tag_normalized, battle_tag_raw, source, updated_at) VALUES ('Jaedong', 'jd#1', 'JD#1', 'manual', '2024-01-01 00:00:00')",
+		"INSERT INTO player_aliases (canonical_alias, battle_tag_normalized, battle_tag_raw, source, updated_at) VALUES ('Bisu', 'bisu#1', 'Bisu#1', 'imported', '2024-01-01 00:00:00')",
+	)
+	exec(t, b,
+		// Same mapping, newer: updates the row.
+		"INSERT INTO player_aliases (canonical_alias, battle_tag_normalized, battle_tag_raw, source, updated_at) VALUES ('Flash', 'flash#1', 'FLASH#1', 'imported', '2025-01-01 00:00:00')",
+		// Imported conflicting with manual: manual is kept.
+		"INSERT INTO player_aliases (canonical_alias, battle_tag_normalized, battle_tag_raw, source, updated_at) VALUES ('Jd', 'jd#1', 'JD#1', 'imported', '2025-01-01 00:00:00')",
+		// The other owner's "you" row arrives as manual and beats imported.
+		"INSERT INTO player_aliases (canonical_alias, battle_tag_normalized, battle_tag_raw, source, updated_at) VALUES ('Bisu [KT]', 'bisu#1', 'Bisu#1', 'you', '2023-01-01 00:00:00')",
+	)
+
+	results, err := Run(context.Background(), Config{Sources: []string{a, b}, Into: into})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if s := results[1].Stats; s.AliasesUpdated != 1 || s.AliasConflicts != 2 || s.AliasesAdded != 1 || s.DuplicateReplays != 1 {
+		t.Fatalf("second source stats = %+v", s)
+	}
+
+	rows, err := openDB(t, into).Query("SELECT battle_tag_normalized, canonical_alias, battle_tag_raw, source FROM player_aliases ORDER BY battle_tag_normalized")
+	if err != nil {
+		t.Fatalf("query aliases: %v", err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var tag, alias, raw, source string
+		if err := rows.Scan(&tag, &alias, &raw, &source); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, tag+"="+alias+"/"+raw+"/"+source)
+	}
+	want := []string{
+		"bisu#1=Bisu [KT]/Bisu#1/manual",
+		"flash#1=Flash/FLASH#1/imported",
+		"jd#1=Jaedong/JD#1/manual",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("aliases = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("aliases = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestRun_RejectsBadSources(t *testing.T) {
+	dir := t.TempDir()
+	into := filepath.Join(dir, "team.db")
+	if _, err := Run(context.Background(), Config{Sources: []string{filepath.Join(dir, "nope.db")}, Into: into}); err == nil {
+		t.Fatal("expected an error for a missing source")
+	}
+	if _, err := Run(context.Background(), Config{Sources: []string{into}, Into: into}); err == nil {
+		t.Fatal("expected an error when the source is the target")
+	}
+	if _, err := os.Stat(into); !os.IsNotExist(err) {
+		t.Fatal("a rejected merge must not create the target")
+	}
+}
@@ -0,0 +1,348 @@
+package migrations
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"maps"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/marianogappa/screpdb/internal/patterns/markers"
+)
+
+// analystViewColumns is the published shape of the analyst views. Analyst
+// queries depend on it, so a shipped view's columns never change: a failure
+// here means either restore the view or add a new _vN next to it and list it
+// here too.
+var analystViewColumns = map[string][]string{
+	"analyst_player_games_v1": {
+		"replay_id", "game_group_id", "is_canonical", "replay_date", "map_name", "map_kind", "game_type",
+		"team_format", "matchup", "duration_seconds", "player_id", "player_name", "race", "player_type",
+		"team", "is_winner", "apm", "eapm", "start_location_oclock", "opener", "opener_name", "opener_modifiers",
+	},
+	"analyst_build_order_steps_v1": {
+		"replay_id", "player_id", "player_name", "race", "step", "frame", "second", "action_type", "item",
+	},
+	"analyst_attacks_v1": {
+		"attack_id", "replay_id", "second", "attacker_player_id", "attacker_name", "attacker_race",
+		"defender_player_id", "defender_name", "defender_race", "location_base_type", "location_base_oclock",
+		"location_natural_of_oclock", "location_mineral_only", "unit_types", "cast_count",
+	},
+	"analyst_attack_units_v1": {
+		"attack_id", "replay_id", "second", "attacker_player_id", "unit_type",
+	},
+	"analyst_attack_casts_v1": {
+		"attack_id", "replay_id", "second", "attacker_player_id", "cast_name", "cast_count",
+	},
+	"analyst_drops_v1": {
+		"drop_id", "replay_id", "second", "last_unload_second", "unload_count", "is_cliff_drop", "player_id",
+		"player_name", "player_race", "target_player_id", "target_player_name", "target_player_race",
+		"location_base_type", "location_base_oclock", "location_natural_of_oclock", "source_base_type",
+		"source_base_oclock", "target_inferred_from", "unit_types",
+	},
+	"analyst_opener_timings_v1": {
+		"replay_id", "player_id", "player_name", "race", "opener", "milestone_index", "milestone", "subject",
+		"target_second", "tolerance_early_seconds", "tolerance_late_seconds", "found", "actual_second",
+		"delta_seconds", "within_tolerance",
+	},
+}
+
+func TestAnalystViews_Columns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "x.db")
+	if err := RunMigrations(path); err != nil {
+		t.Fatalf("RunMigrations: %v", err)
+	}
+	db := openDB(t, path)
+
+	rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'view' AND name LIKE 'analyst\_%' ESCAPE '\'`)
+	if err != nil {
+		t.Fatalf("list views: %v", err)
+	}
+	var views []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("scan view: %v", err)
+		}
+		views = append(views, name)
+	}
+	rows.Close()
+	slices.Sort(views)
+	if want := slices.Sorted(maps.Keys(analystViewColumns)); !slices.Equal(views, want) {
+		t.Fatalf("analyst views %v, want %v", views, want)
+	}
+
+	for view, want := range analystViewColumns {
+		cols, err := db.Query(`SELECT name FROM pragma_table_info(?)`, view)
+		if err != nil {
+			t.Fatalf("table_info(%s): %v", view, err)
+		}
+		var got []string
+		for cols.Next() {
+			var name string
+			if err := cols.Scan(&name); err != nil {
+				t.Fatalf("scan column: %v", err)
+			}
+			got = append(got, name)
+		}
+		cols.Close()
+		if !slices.Equal(got, want) {
+			t.Errorf("%s columns %v, want %v", view, got, want)
+		}
+	}
+}
+
+// TestAnalystViews_PostgresMirror checks the postgres set defines every
+// analyst view with the same columns as the replay set, by reading the
+// select lists of both migrations.
+func TestAnalystViews_PostgresMirror(t *testing.T) {
+	sqlite := viewSelectColumns(t, replayFS, "replay")
+	postgres := viewSelectColumns(t, postgresFS, "postgres")
+	for view, want := range analystViewColumns {
+		if got := sqlite[view]; !slices.Equal(got, want) {
+			t.Errorf("replay migration selects %v for %s, want %v", got, view, want)
+		}
+		if got := postgres[view]; !slices.Equal(got, want) {
+			t.Errorf("postgres migration selects %v for %s, want %v", got, view, want)
+		}
+	}
+	if len(postgres) != len(analystViewColumns) {
+		t.Errorf("postgres migration defines views %v, want %d", slices.Sorted(maps.Keys(postgres)), len(analystViewColumns))
+	}
+}
+
+var viewHeaderRegexp = regexp.MustCompile(`^CREATE (?:OR REPLACE )?VIEW (?:IF NOT EXISTS )?(analyst_\w+) AS$`)
+
+// viewSelectColumns returns, per analyst view in dir's 000009 migration, the
+// output column of each top-level (single-tab) line of its select list.
+func viewSelectColumns(t *testing.T, fsys embed.FS, dir string) map[string][]string {
+	t.Helper()
+	body, err := fsys.ReadFile(path.Join(dir, markerReferenceMigration))
+	if err != nil {
+		t.Fatalf("read %s/%s: %v", dir, markerReferenceMigration, err)
+	}
+	out := map[string][]string{}
+	view := ""
+	for _, line := range strings.Split(string(body), "\n") {
+		if m := viewHeaderRegexp.FindStringSubmatch(line); m != nil {
+			view = m[1]
+			continue
+		}
+		if view == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "FROM") {
+			view = ""
+			continue
+		}
+		if !strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "\t\t") {
+			continue
+		}
+		expr := strings.TrimSuffix(strings.TrimSpace(line), ",")
+		if i := strings.LastIndex(expr, " AS "); i >= 0 {
+			expr = expr[i+len(" AS "):]
+		} else if i := strings.LastIndex(expr, "."); i >= 0 {
+			expr = expr[i+1:]
+		}
+		out[view] = append(out[view], expr)
+	}
+	return out
+}
+
+func TestAnalystViews_UnpackData(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "x.db")
+	if err := RunMigrations(path); err != nil {
+		t.Fatalf("RunMigrations: %v", err)
+	}
+	db := openDB(t, path)
+	exec := func(query string, args ...any) {
+		t.Helper()
+		if _, err := db.Exec(query, args...); err != nil {
+			t.Fatalf("exec %q: %v", query, err)
+		}
+	}
+	for _, id := range []int{1, 2} {
+		exec(`INSERT INTO replays (id, file_path, file_checksum, file_name, created_at, replay_date, map_name,
+			map_width, map_height, duration_seconds, frame_count, engine_version, engine, game_speed, game_type,
+			home_team_size, avail_slots_count, matchup, team_format, game_group_id) VALUES (?, ?, ?, ?, '2025-01-01', '2025-01-01',
+			'Fighting Spirit', 128, 128, 600, 14400, '1.16.1', 'Brood War', 'Fastest', 'Melee', '1', 8, 'TvZ', '1v1', 1)`,
+			id, fmt.Sprintf("r%d.rep", id), fmt.Sprintf("sum%d", id), fmt.Sprintf("r%d.rep", id))
+	}
+	exec(`INSERT INTO players (id, replay_id, name, race, type, color, team, is_observer, apm, eapm, is_winner) VALUES
+		(1, 1, 'jaedong', 'Zerg', 'Human', 'Red', 1, 0, 400, 300, 1),
+		(2, 1, 'flash', 'Terran', 'Human', 'Blue', 2, 0, 350, 280, 0),
+		(3, 1, 'tasteless', 'Terran', 'Human', 'Teal', 3, 1, 10, 5, 0),
+		(4, 2, 'flash', 'Terran', 'Human', 'Blue', 2, 0, 350, 280, 0)`)
+	exec(`INSERT INTO commands (replay_id, player_id, frame, seconds_from_game_start, action_type, unit_type, upgrade_name) VALUES
+		(1, 1, 300, 12, 'Build', 'Spawning Pool', NULL),
+		(1, 1, 100, 4, 'Train', 'Drone', NULL),
+		(1, 1, 50, 2, 'Stop', NULL, NULL),
+		(1, 1, 900, 37, 'Upgrade', NULL, 'Metabolic Boost (Zergling Speed)')`)
+	exec(`INSERT INTO replay_events (id, replay_id, seconds_from_game_start, event_kind, event_type, source_player_id, target_player_id,
+		location_base_type, location_base_oclock, attack_unit_types, payload, attack_cast_counts) VALUES
+		(1, 1, 0, 'marker', 'bo_9_pool', 1, NULL, NULL, NULL, NULL, '{"expert_actuals":[{"second":100,"found":true},{"found":false}],"modifiers":["all-in","proxy"]}', NULL),
+		(2, 1, 0, 'marker', 'bo_z_fuzzy', 2, NULL, NULL, NULL, NULL, '{"label":"~9 Overpool"}', NULL),
+		(3, 1, 0, 'marker', 'never_upgraded', 2, NULL, NULL, NULL, NULL, NULL, NULL),
+		(4, 1, 300, 'game_event', 'attack', 2, 1, 'natural', 6, '["Marine","Medic"]', NULL, '{"Psionic Storm":2,"Plague":1}'),
+		(5, 1, 420, 'game_event', 'drop', 2, 1, 'starting', 6, '["Marine"]', '{"n":2,"le":440,"sb":{"k":"starting","o":12},"tp":1,"tv":"a"}', NULL)`)
+
+	var opener, openerName, modifiers sql.NullString
+	var canonical int
+	if err := db.QueryRow(`SELECT opener, opener_name, opener_modifiers, is_canonical FROM analyst_player_games_v1 WHERE player_id = 1`).
+		Scan(&opener, &openerName, &modifiers, &canonical); err != nil {
+		t.Fatalf("query player game: %v", err)
+	}
+	if opener.String != "bo_9_pool" || openerName.String != markers.ByFeatureKey("bo_9_pool").Name || modifiers.String != "all-in, proxy" || canonical != 1 {
+		t.Errorf("player 1 opener = %v %v %v canonical %d", opener, openerName, modifiers, canonical)
+	}
+	if err := db.QueryRow(`SELECT opener_name, opener_modifiers FROM analyst_player_games_v1 WHERE player_id = 2`).Scan(&openerName, &modifiers); err != nil {
+		t.Fatalf("query player game: %v", err)
+	}
+	if openerName.String != "~9 Overpool" || modifiers.Valid {
+		t.Errorf("player 2 opener_name %v modifiers %v, want the payload label and none", openerName, modifiers)
+	}
+	var games, canonicalGames int
+	if err := db.QueryRow(`SELECT COUNT(*), SUM(is_canonical) FROM analyst_player_games_v1`).Scan(&games, &canonicalGames); err != nil {
+		t.Fatalf("count player games: %v", err)
+	}
+	if games != 3 || canonicalGames != 2 {
+		t.Errorf("player games %d (canonical %d), want 3 (2): observers excluded, replay 2 a duplicate POV", games, canonicalGames)
+	}
+
+	if got := queryStrings(t, db, `SELECT step || ':' || item FROM analyst_build_order_steps_v1 WHERE player_id = 1 ORDER BY step`); !slices.Equal(got, []string{
+		"1:Drone", "2:Spawning Pool", "3:Metabolic Boost (Zergling Speed)",
+	}) {
+		t.Errorf("build order steps = %v", got)
+	}
+
+	var unitTypes string
+	var castCount int
+	if err := db.QueryRow(`SELECT unit_types, cast_count FROM analyst_attacks_v1 WHERE attack_id = 4`).Scan(&unitTypes, &castCount); err != nil {
+		t.Fatalf("query attack: %v", err)
+	}
+	if unitTypes != "Marine, Medic" || castCount != 3 {
+		t.Errorf("attack unit_types %q cast_count %d", unitTypes, castCount)
+	}
+	if got := queryStrings(t, db, `SELECT unit_type FROM analyst_attack_units_v1 ORDER BY unit_type`); !slices.Equal(got, []string{"Marine", "Medic"}) {
+		t.Errorf("attack units = %v", got)
+	}
+	if got := queryStrings(t, db, `SELECT cast_name || '=' || cast_count FROM analyst_attack_casts_v1 ORDER BY cast_name`); !slices.Equal(got, []string{"Plague=1", "Psionic Storm=2"}) {
+		t.Errorf("attack casts = %v", got)
+	}
+
+	var last, unloads, sourceOclock, cliff int
+	var sourceBase, via, dropUnits string
+	if err := db.QueryRow(`SELECT last_unload_second, unload_count, is_cliff_drop, source_base_type, source_base_oclock, target_inferred_from, unit_types
+		FROM analyst_drops_v1 WHERE drop_id = 5`).Scan(&last, &unloads, &cliff, &sourceBase, &sourceOclock, &via, &dropUnits); err != nil {
+		t.Fatalf("query drop: %v", err)
+	}
+	if last != 440 || unloads != 2 || cliff != 0 || sourceBase != "starting" || sourceOclock != 12 || via != "attack" || dropUnits != "Marine" {
+		t.Errorf("drop = last %d unloads %d cliff %d source %s@%d via %s units %s", last, unloads, cliff, sourceBase, sourceOclock, via, dropUnits)
+	}
+
+	expert := markers.ByFeatureKey("bo_9_pool").Expert
+	rows, err := db.Query(`SELECT milestone, target_second, found, actual_second, delta_seconds, within_tolerance
+		FROM analyst_opener_timings_v1 WHERE player_id = 1 ORDER BY milestone_index`)
+	if err != nil {
+		t.Fatalf("query timings: %v", err)
+	}
+	defer rows.Close()
+	i := 0
+	for ; rows.Next(); i++ {
+		var milestone string
+		var target, found int
+		var actual, delta, within sql.NullInt64
+		if err := rows.Scan(&milestone, &target, &found, &actual, &delta, &within); err != nil {
+			t.Fatalf("scan timing: %v", err)
+		}
+		if i >= len(expert) || milestone != expert[i].Key || target != expert[i].TargetSecond {
+			t.Fatalf("timing %d = %s at %d, want the marker's expert milestones %v", i, milestone, target, expert)
+		}
+		if i == 1 {
+			if found != 0 || actual.Valid || delta.Valid || within.Valid {
+				t.Errorf("missed milestone = found %d actual %v delta %v within %v, want 0 and NULLs", found, actual, delta, within)
+			}
+			continue
+		}
+		wantDelta := int64(100 - expert[0].TargetSecond)
+		wantWithin := wantDelta >= -int64(expert[0].Tolerance.EarlySeconds) && wantDelta <= int64(expert[0].Tolerance.LateSeconds)
+		if found != 1 || actual.Int64 != 100 || delta.Int64 != wantDelta || (within.Int64 == 1) != wantWithin {
+			t.Errorf("milestone = found %d actual %v delta %v within %v, want 100, %d, %v", found, actual, delta, within, wantDelta, wantWithin)
+		}
+	}
+	if i != len(expert) {
+		t.Errorf("got %d timings, want %d", i, len(expert))
+	}
+}
+
+func TestSyncMarkerReference(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "x.db")
+	if err := RunMigrationSet(path, MigrationSetReplay); err != nil {
+		t.Fatalf("RunMigrationSet(replay): %v", err)
+	}
+	db := openDB(t, path)
+	wantDefinitions, wantMilestones := len(markers.Markers()), 0
+	for _, m := range markers.Markers() {
+		wantMilestones += len(m.Expert)
+	}
+	check := func(when string) {
+		t.Helper()
+		var definitions, milestones int
+		if err := db.QueryRow(`SELECT (SELECT COUNT(*) FROM marker_definitions), (SELECT COUNT(*) FROM marker_expert_milestones)`).Scan(&definitions, &milestones); err != nil {
+			t.Fatalf("count marker reference: %v", err)
+		}
+		if definitions != wantDefinitions || milestones != wantMilestones {
+			t.Errorf("%s: %d definitions and %d milestones, want %d and %d", when, definitions, milestones, wantDefinitions, wantMilestones)
+		}
+		var target int
+		if err := db.QueryRow(`SELECT target_second FROM marker_expert_milestones WHERE event_type = 'bo_9_pool' AND milestone_index = 0`).Scan(&target); err != nil {
+			t.Fatalf("query milestone: %v", err)
+		}
+		if want := markers.ByFeatureKey("bo_9_pool").Expert[0].TargetSecond; target != want {
+			t.Errorf("%s: bo_9_pool target %d, want %d", when, target, want)
+		}
+	}
+	check("after migrating")
+
+	if _, err := db.Exec(`UPDATE marker_expert_milestones SET target_second = 1; DELETE FROM marker_definitions WHERE event_type = 'bo_4_pool'`); err != nil {
+		t.Fatalf("tamper: %v", err)
+	}
+	if err := RunMigrationSet(path, MigrationSetReplay); err != nil {
+		t.Fatalf("RunMigrationSet(replay) again: %v", err)
+	}
+	check("after a stale registry")
+
+	if _, err := MigrateTo(path, MigrationSetReplay, migrationVersion(markerReferenceMigration)-1); err != nil {
+		t.Fatalf("MigrateTo(down): %v", err)
+	}
+	if tableExists(t, db, "marker_definitions") {
+		t.Error("migrating below the analyst views should drop marker_definitions")
+	}
+	if _, err := MigrateTo(path, MigrationSetReplay, migrationVersion(markerReferenceMigration)); err != nil {
+		t.Fatalf("MigrateTo(up): %v", err)
+	}
+	check("after migrating back up")
+}
+
+func queryStrings(t *testing.T, db *sql.DB, query string) []string {
+	t.Helper()
+	rows, err := db.Query(query)
+	if err != nil {
+		t.Fatalf("query %q: %v", query, err)
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			t.Fatalf("scan %q: %v", query, err)
+		}
+		out = append(out, s)
+	}
+	return out
+}
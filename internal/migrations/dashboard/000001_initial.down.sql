@@ -0,0 +1,7 @@
+BEGIN;
+
+-- Reverts 000001_initial. The only table it creates, settings, is owned by
+-- the settings set now (see settings/000001_initial), which drops it; its
+-- data must survive the dashboard set going away.
+
+COMMIT;
@@ -0,0 +1,147 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/marianogappa/screpdb/internal/patterns/markers"
+)
+
+// markerReferenceMigration creates the tables syncMarkerReference fills.
+const markerReferenceMigration = "000009_analyst_views.up.sql"
+
+// referenceTable is the content one of the marker reference tables should
+// have: its columns and one row of values per entry.
+type referenceTable struct {
+	name    string
+	columns []string
+	rows    [][]any
+}
+
+// markerReferenceTables returns marker_definitions and
+// marker_expert_milestones as the marker registry compiled into this build
+// defines them.
+func markerReferenceTables() []referenceTable {
+	definitions := referenceTable{
+		name:    "marker_definitions",
+		columns: []string{"event_type", "name", "kind", "race", "matchups"},
+	}
+	milestones := referenceTable{
+		name:    "marker_expert_milestones",
+		columns: []string{"event_type", "milestone_index", "milestone", "subject", "target_second", "tolerance_early_seconds", "tolerance_late_seconds"},
+	}
+	for _, m := range markers.Markers() {
+		definitions.rows = append(definitions.rows, []any{m.FeatureKey, m.Name, string(m.Kind), string(m.Race), strings.Join(m.Matchup, ",")})
+		for i, expert := range m.Expert {
+			milestones.rows = append(milestones.rows, []any{m.FeatureKey, i, expert.Key, expert.Match.Subject, expert.TargetSecond, expert.Tolerance.EarlySeconds, expert.Tolerance.LateSeconds})
+		}
+	}
+	return []referenceTable{definitions, milestones}
+}
+
+// referenceDB is what syncMarkerReference needs of a *sql.DB or *sql.Conn.
+type referenceDB interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// syncMarkerReference rewrites the marker reference tables the analyst views
+// join against (see replay/000009_analyst_views) when they don't match this
+// build's marker registry, which changes between releases without a
+// migration. placeholder returns the n-th (1-based) bind parameter of the
+// database's dialect.
+func syncMarkerReference(ctx context.Context, db referenceDB, placeholder func(n int) string) error {
+	tables := markerReferenceTables()
+	current := true
+	for _, table := range tables {
+		got, err := referenceFingerprints(ctx, db, table)
+		if err != nil {
+			return err
+		}
+		want := make([]string, 0, len(table.rows))
+		for _, row := range table.rows {
+			want = append(want, referenceFingerprint(row))
+		}
+		slices.Sort(want)
+		if !slices.Equal(got, want) {
+			current = false
+			break
+		}
+	}
+	if current {
+		return nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin marker reference sync: %w", err)
+	}
+	defer tx.Rollback()
+	for _, table := range tables {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+table.name); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", table.name, err)
+		}
+		binds := make([]string, len(table.columns))
+		for i := range binds {
+			binds[i] = placeholder(i + 1)
+		}
+		insert := fmt.Sprintf(`INSERT INTO %s (%s) VALUES (%s)`, table.name, strings.Join(table.columns, ", "), strings.Join(binds, ", "))
+		for _, row := range table.rows {
+			if _, err := tx.ExecContext(ctx, insert, row...); err != nil {
+				return fmt.Errorf("failed to insert into %s: %w", table.name, err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit marker reference sync: %w", err)
+	}
+	return nil
+}
+
+// referenceFingerprints returns a fingerprint of every row of table, sorted.
+// Sorting in Go rather than SQL keeps the order independent of collation.
+func referenceFingerprints(ctx context.Context, db referenceDB, table referenceTable) ([]string, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`SELECT %s FROM %s`, strings.Join(table.columns, ", "), table.name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", table.name, err)
+	}
+	defer rows.Close()
+	var out []string
+	values := make([]string, len(table.columns))
+	dest := make([]any, len(values))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", table.name, err)
+		}
+		row := make([]any, len(values))
+		for i, v := range values {
+			row[i] = v
+		}
+		out = append(out, referenceFingerprint(row))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", table.name, err)
+	}
+	slices.Sort(out)
+	return out, nil
+}
+
+// referenceFingerprint joins a row's values, as text, into one comparable
+// string.
+func referenceFingerprint(row []any) string {
+	parts := make([]string, len(row))
+	for i, v := range row {
+		parts[i] = fmt.Sprint(v)
+	}
+	return strings.Join(parts, "\x1f")
+}
+
+func sqlitePlaceholder(int) string { return "?" }
+
+func postgresPlaceholder(n int) string { return fmt.Sprintf("$%d", n) }
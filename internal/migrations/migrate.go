@@ -1,15 +1,21 @@
 package migrations
 
 import (
+	"context"
 	"database/sql"
 	"embed"
 	"fmt"
 	"path"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 
 	_ "modernc.org/sqlite"
+
+	// Registers the module behind command_blob_rows, which replay migrations
+	// create.
+	_ "github.com/marianogappa/screpdb/internal/cmdblob"
 )
 
 //go:embed replay/*.sql
@@ -66,7 +72,8 @@ func RunMigrations(sqlitePath string) error {
 // re-invocations become no-ops — important because rebuild-style migrations
 // (e.g. 000003_replay_events_refinement) destructively copy rows and would
 // re-trip CHECK constraints against newer schemas (markers) added in later
-// migrations like 000008.
+// migrations like 000008. The replay set then brings the marker reference
+// tables in step with this build (see syncMarkerReference).
 func RunMigrationSet(sqlitePath string, set MigrationSet) error {
 	var fs embed.FS
 	var subdir string
@@ -136,9 +143,250 @@ func RunMigrationSet(sqlitePath string, set MigrationSet) error {
 			return err
 		}
 	}
+	if set == MigrationSetReplay {
+		return syncMarkerReference(context.Background(), db, sqlitePlaceholder)
+	}
 	return nil
 }
 
+// SetStatus compares the migrations embedded in this build against the ones a
+// database has recorded as applied.
+type SetStatus struct {
+	Set     MigrationSet
+	Applied []string // embedded and recorded as applied
+	Pending []string // embedded but not applied yet
+	Unknown []string // recorded as applied but not embedded (written by a newer build)
+}
+
+// Status reports the migration state of every set, without applying anything.
+// A set whose ledger table does not exist yet reports all its migrations as
+// pending.
+func Status(sqlitePath string) ([]SetStatus, error) {
+	db, err := sql.Open("sqlite", sqliteDSN(sqlitePath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	var out []SetStatus
+	for _, set := range []MigrationSet{MigrationSetReplay, MigrationSetDashboard, MigrationSetSettings} {
+		embedded, err := embeddedUpMigrations(set)
+		if err != nil {
+			return nil, err
+		}
+		applied := map[string]struct{}{}
+		var exists int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, migrationsTableName(set)).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("failed to look up migrations table %s: %w", migrationsTableName(set), err)
+		}
+		if exists > 0 {
+			if applied, err = loadAppliedMigrations(db, set); err != nil {
+				return nil, err
+			}
+		}
+
+		st := SetStatus{Set: set}
+		known := map[string]struct{}{}
+		for _, name := range embedded {
+			known[name] = struct{}{}
+			if _, ok := applied[name]; ok {
+				st.Applied = append(st.Applied, name)
+			} else {
+				st.Pending = append(st.Pending, name)
+			}
+		}
+		for name := range applied {
+			if _, ok := known[name]; !ok {
+				st.Unknown = append(st.Unknown, name)
+			}
+		}
+		sort.Strings(st.Unknown)
+		out = append(out, st)
+	}
+	return out, nil
+}
+
+// embeddedUpMigrations lists a set's .up.sql files in apply order.
+func embeddedUpMigrations(set MigrationSet) ([]string, error) {
+	var fs embed.FS
+	switch set {
+	case MigrationSetReplay:
+		fs = replayFS
+	case MigrationSetDashboard:
+		fs = dashboardFS
+	case MigrationSetSettings:
+		fs = settingsFS
+	default:
+		return nil, fmt.Errorf("unknown migration set: %s", set)
+	}
+	entries, err := fs.ReadDir(string(set))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".up.sql") {
+			files = append(files, entry.Name())
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Version is the schema version the set is at: the number prefixing its
+// newest applied migration, 0 when none is applied.
+func (s SetStatus) Version() int {
+	version := 0
+	for _, name := range s.Applied {
+		version = max(version, migrationVersion(name))
+	}
+	return version
+}
+
+// Changes reports whether migrating the set to version target has anything
+// to apply or revert.
+func (s SetStatus) Changes(target int) bool {
+	if s.Version() > target {
+		return true
+	}
+	for _, name := range s.Pending {
+		if migrationVersion(name) <= target {
+			return true
+		}
+	}
+	return false
+}
+
+// migrationVersion returns the number a migration file name starts with
+// (6 for 000006_chat_search.up.sql), or 0 when it starts with none.
+func migrationVersion(name string) int {
+	digits, _, _ := strings.Cut(name, "_")
+	version, err := strconv.Atoi(digits)
+	if err != nil {
+		return 0
+	}
+	return version
+}
+
+// LatestVersion returns the newest version this build can migrate set to.
+func LatestVersion(set MigrationSet) (int, error) {
+	files, err := embeddedUpMigrations(set)
+	if err != nil {
+		return 0, err
+	}
+	if len(files) == 0 {
+		return 0, nil
+	}
+	return migrationVersion(files[len(files)-1]), nil
+}
+
+// MigrateTo moves a set to version target: applied migrations above it are
+// reverted newest first with their .down.sql files, then pending ones up to it
+// are applied oldest first. It returns the files it ran, in order.
+//
+// Down migrations keep data wherever the older schema can hold it, so a
+// database can be taken back to what an older screpdb expects and brought
+// forward again without a re-ingest. A set carrying migrations unknown to this
+// build (written by a newer screpdb) is left alone: there is no way to revert
+// them from here.
+func MigrateTo(sqlitePath string, set MigrationSet, target int) ([]string, error) {
+	var fs embed.FS
+	switch set {
+	case MigrationSetReplay:
+		fs = replayFS
+	case MigrationSetDashboard:
+		fs = dashboardFS
+	case MigrationSetSettings:
+		fs = settingsFS
+	default:
+		return nil, fmt.Errorf("unknown migration set: %s", set)
+	}
+	files, err := embeddedUpMigrations(set)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := LatestVersion(set)
+	if err != nil {
+		return nil, err
+	}
+	if target < 0 || target > latest {
+		return nil, fmt.Errorf("%s migrations go from 0 to %d, not %d", set, latest, target)
+	}
+
+	db, err := sql.Open("sqlite", sqliteDSN(sqlitePath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`PRAGMA foreign_keys = ON;`); err != nil {
+		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
+	}
+	if err := ensureMigrationsTable(db, set); err != nil {
+		return nil, err
+	}
+	applied, err := loadAppliedMigrations(db, set)
+	if err != nil {
+		return nil, err
+	}
+	known := map[string]struct{}{}
+	for _, name := range files {
+		known[name] = struct{}{}
+	}
+	var unknown []string
+	for name := range applied {
+		if _, ok := known[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("database has %s migrations unknown to this build (%v); upgrade screpdb first", set, unknown)
+	}
+
+	var ran []string
+	for i := len(files) - 1; i >= 0; i-- {
+		name := files[i]
+		if _, ok := applied[name]; !ok || migrationVersion(name) <= target {
+			continue
+		}
+		down := strings.TrimSuffix(name, ".up.sql") + ".down.sql"
+		body, err := fs.ReadFile(path.Join(string(set), down))
+		if err != nil {
+			return ran, fmt.Errorf("failed to read down migration of %s: %w", name, err)
+		}
+		if _, err := db.Exec(string(body)); err != nil {
+			return ran, fmt.Errorf("failed to execute migration %s: %w", down, err)
+		}
+		if err := recordMigrationReverted(db, set, name); err != nil {
+			return ran, err
+		}
+		ran = append(ran, down)
+	}
+	for _, name := range files {
+		if _, ok := applied[name]; ok || migrationVersion(name) > target {
+			continue
+		}
+		body, err := fs.ReadFile(path.Join(string(set), name))
+		if err != nil {
+			return ran, fmt.Errorf("failed to read migration %s: %w", name, err)
+		}
+		if _, err := db.Exec(string(body)); err != nil {
+			return ran, fmt.Errorf("failed to execute migration %s: %w", name, err)
+		}
+		if err := recordMigrationApplied(db, set, name); err != nil {
+			return ran, err
+		}
+		ran = append(ran, name)
+	}
+	if set == MigrationSetReplay && target >= migrationVersion(markerReferenceMigration) {
+		if err := syncMarkerReference(context.Background(), db, sqlitePlaceholder); err != nil {
+			return ran, err
+		}
+	}
+	return ran, nil
+}
+
 func migrationsTableName(set MigrationSet) string {
 	return "schema_migrations_" + string(set)
 }
@@ -184,6 +432,14 @@ func recordMigrationApplied(db *sql.DB, set MigrationSet, name string) error {
 	return nil
 }
 
+func recordMigrationReverted(db *sql.DB, set MigrationSet, name string) error {
+	table := migrationsTableName(set)
+	if _, err := db.Exec(`DELETE FROM `+table+` WHERE name = ?`, name); err != nil {
+		return fmt.Errorf("failed to record reverted migration %s: %w", name, err)
+	}
+	return nil
+}
+
 // DropAllMigrations drops every migration set, including settings.
 // Used for fresh-DB nukes only (test setup, full reset). Routine
 // --clean / --clean-dashboard wipes preserve the settings set and its
@@ -201,27 +457,22 @@ func DropAllMigrations(sqlitePath string) error {
 	return nil
 }
 
-// createTableRegexp matches `CREATE TABLE [IF NOT EXISTS] "?name"?` in a SQL file.
-// Used by DropMigrationSet to rebuild the drop list when an up-only migration
-// directory has no .down.sql counterparts.
-var createTableRegexp = regexp.MustCompile(`(?i)CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?` + "`?\"?" + `(\w+)` + "`?\"?")
+// createTableRegexp matches `CREATE [VIRTUAL] TABLE [IF NOT EXISTS] "?name"?` in a SQL file.
+// Used by DropMigrationSet to build its drop list from the .up.sql files.
+var createTableRegexp = regexp.MustCompile(`(?i)CREATE\s+(?:VIRTUAL\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?` + "`?\"?" + `(\w+)` + "`?\"?")
+
+// createViewRegexp is createTableRegexp for `CREATE VIEW`.
+var createViewRegexp = regexp.MustCompile(`(?i)CREATE\s+VIEW\s+(?:IF\s+NOT\s+EXISTS\s+)?` + "`?\"?" + `(\w+)` + "`?\"?")
 
 // DropMigrationSet drops every table created by a specific migration set, then
 // clears that set's migrations-applied ledger. RunMigrationSet can re-apply the
 // migrations from scratch afterwards.
 //
-// Two paths:
-//
-//  1. If the migration directory contains .down.sql files, run them in reverse
-//     filename order (the canonical down-migration flow).
-//
-//  2. If the directory is up-only (the .down.sql siblings were dropped during
-//     a schema-consolidation), fall back to parsing every CREATE TABLE statement
-//     out of the .up.sql files and DROP TABLE-ing them in reverse order. This is
-//     the only correct behaviour for the "erase data" UI checkbox + the CLI
-//     --clean flag now that the project has consolidated to a single up-only
-//     migration per set; the previous .down.sql-only path silently dropped only
-//     the migrations ledger and left the data tables intact.
+// It does not run the set's .down.sql files: those revert one migration at a
+// time and keep data where they can (see MigrateTo), which is wasted work for
+// the "erase data" UI checkbox and the CLI --clean flag. Instead every CREATE
+// TABLE / CREATE VIEW statement is parsed out of the .up.sql files and the
+// views and tables are dropped, the tables in reverse order.
 func DropMigrationSet(sqlitePath string, set MigrationSet) error {
 	var fs embed.FS
 	var subdir string
@@ -250,73 +501,51 @@ func DropMigrationSet(sqlitePath string, set MigrationSet) error {
 		return fmt.Errorf("failed to enable foreign keys: %w", err)
 	}
 
-	entries, err := fs.ReadDir(subdir)
+	upFiles, err := embeddedUpMigrations(set)
 	if err != nil {
-		return fmt.Errorf("failed to read migrations directory: %w", err)
+		return err
 	}
 
-	var downFiles, upFiles []string
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-		switch {
-		case strings.HasSuffix(entry.Name(), ".down.sql"):
-			downFiles = append(downFiles, entry.Name())
-		case strings.HasSuffix(entry.Name(), ".up.sql"):
-			upFiles = append(upFiles, entry.Name())
+	// Indexes die with their tables; views, which don't, go before any table.
+	var tables, views []string
+	seen := map[string]struct{}{}
+	for _, name := range upFiles {
+		migrationPath := path.Join(subdir, name)
+		body, err := fs.ReadFile(migrationPath)
+		if err != nil {
+			return fmt.Errorf("failed to read migration %s: %w", name, err)
 		}
-	}
-
-	if len(downFiles) > 0 {
-		sort.Sort(sort.Reverse(sort.StringSlice(downFiles)))
-		for _, name := range downFiles {
-			migrationPath := path.Join(subdir, name)
-			body, err := fs.ReadFile(migrationPath)
-			if err != nil {
-				return fmt.Errorf("failed to read migration %s: %w", name, err)
-			}
-			if _, err := db.Exec(string(body)); err != nil {
-				return fmt.Errorf("failed to execute migration %s: %w", name, err)
+		for _, m := range createTableRegexp.FindAllStringSubmatch(string(body), -1) {
+			name := m[1]
+			if _, dup := seen[name]; dup {
+				continue
 			}
+			seen[name] = struct{}{}
+			tables = append(tables, name)
 		}
-	} else {
-		// Up-only fallback: extract table names from every .up.sql, DROP TABLE
-		// them in reverse declaration order so dependents go first. Indexes
-		// die with their tables.
-		var tables []string
-		seen := map[string]struct{}{}
-		sort.Strings(upFiles)
-		for _, name := range upFiles {
-			migrationPath := path.Join(subdir, name)
-			body, err := fs.ReadFile(migrationPath)
-			if err != nil {
-				return fmt.Errorf("failed to read migration %s: %w", name, err)
-			}
-			for _, m := range createTableRegexp.FindAllStringSubmatch(string(body), -1) {
-				name := m[1]
-				if _, dup := seen[name]; dup {
-					continue
-				}
-				seen[name] = struct{}{}
-				tables = append(tables, name)
-			}
+		for _, m := range createViewRegexp.FindAllStringSubmatch(string(body), -1) {
+			views = append(views, m[1])
 		}
-		// When dropping the replay or dashboard sets, skip tables that
-		// migrated to the settings set — they live in older migration
-		// files for legacy DB compatibility but their data is now owned
-		// by settings and must survive --clean / --clean-dashboard.
-		preserve := map[string]struct{}{}
-		if set == MigrationSetReplay || set == MigrationSetDashboard {
-			preserve = preservedTablesAcrossWipes
+	}
+	// When dropping the replay or dashboard sets, skip tables that
+	// migrated to the settings set — they live in older migration
+	// files for legacy DB compatibility but their data is now owned
+	// by settings and must survive --clean / --clean-dashboard.
+	preserve := map[string]struct{}{}
+	if set == MigrationSetReplay || set == MigrationSetDashboard {
+		preserve = preservedTablesAcrossWipes
+	}
+	for _, view := range views {
+		if _, err := db.Exec(`DROP VIEW IF EXISTS ` + view); err != nil {
+			return fmt.Errorf("failed to drop view %s: %w", view, err)
 		}
-		for i := len(tables) - 1; i >= 0; i-- {
-			if _, skip := preserve[tables[i]]; skip {
-				continue
-			}
-			if _, err := db.Exec(`DROP TABLE IF EXISTS ` + tables[i]); err != nil {
-				return fmt.Errorf("failed to drop table %s: %w", tables[i], err)
-			}
+	}
+	for i := len(tables) - 1; i >= 0; i-- {
+		if _, skip := preserve[tables[i]]; skip {
+			continue
+		}
+		if _, err := db.Exec(`DROP TABLE IF EXISTS ` + tables[i]); err != nil {
+			return fmt.Errorf("failed to drop table %s: %w", tables[i], err)
 		}
 	}
 
@@ -2,10 +2,16 @@ package migrations
 
 import (
 	"database/sql"
+	"fmt"
+	"maps"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 
 	_ "modernc.org/sqlite"
+
+	"github.com/marianogappa/screpdb/internal/cmdblob"
 )
 
 func openDB(t *testing.T, sqlitePath string) *sql.DB {
@@ -100,7 +106,7 @@ func TestRunMigrations_RecordsEveryEmbeddedUpFile(t *testing.T) {
 	db := openDB(t, path)
 
 	want := map[MigrationSet][]string{
-		MigrationSetReplay:    {"000001_initial.up.sql", "000002_add_load_action_types.up.sql"},
+		MigrationSetReplay:    {"000001_initial.up.sql", "000002_add_load_action_types.up.sql", "000003_ingest_failures.up.sql", "000004_game_groups.up.sql", "000005_analysis_inputs.up.sql", "000006_chat_search.up.sql", "000007_command_blobs.up.sql", "000008_player_aggregates.up.sql", "000009_analyst_views.up.sql", "000010_file_stamps.up.sql", "000011_replay_generation.up.sql", "000012_economy_samples.up.sql", "000013_production_busy.up.sql", "000014_larva_samples.up.sql", "000015_hotkey_usage.up.sql", "000016_stamp_generation.up.sql"},
 		MigrationSetDashboard: {"000001_initial.up.sql"},
 		MigrationSetSettings:  {"000001_initial.up.sql"},
 	}
@@ -267,7 +273,7 @@ func TestCleanAndRunMigrations_PreservesSettingsData(t *testing.T) {
 
 // replayDataTables are the tables owned by the replay migration set that a
 // --clean wipe must drop (player_aliases is preserved and tested separately).
-var replayDataTables = []string{"replays", "players", "commands", "commands_low_value", "replay_events"}
+var replayDataTables = []string{"replays", "players", "commands", "commands_low_value", "replay_events", "chat_messages_fts", "command_blobs", "command_blob_rows", "player_game_facts", "player_aggregates", "player_matchup_aggregates", "player_marker_counts", "player_aggregates_state", "marker_definitions", "marker_expert_milestones"}
 
 func TestDropAllMigrations_DropsEveryTableIncludingSettings(t *testing.T) {
 	path := filepath.Join(t.TempDir(), "x.db")
@@ -348,8 +354,8 @@ func TestCleanAndRunMigrations_DropAndReapplyCycle(t *testing.T) {
 	}
 
 	// Ledgers are fully repopulated so subsequent RunMigrations no-ops.
-	if got := appliedNames(t, db, MigrationSetReplay); len(got) != 2 {
-		t.Errorf("replay ledger should have 2 applied migrations after reapply, got %v", got)
+	if got := appliedNames(t, db, MigrationSetReplay); len(got) != 16 {
+		t.Errorf("replay ledger should have 16 applied migrations after reapply, got %v", got)
 	}
 }
 
@@ -399,7 +405,7 @@ func TestCleanAndRunMigrationSet_ReappliesSingleSet(t *testing.T) {
 		t.Errorf("player_aliases should survive CleanAndRunMigrationSet(replay), got %d rows", aliasCount)
 	}
 
-	if got := appliedNames(t, db, MigrationSetReplay); len(got) != 2 {
+	if got := appliedNames(t, db, MigrationSetReplay); len(got) != 16 {
 		t.Errorf("replay ledger should be repopulated, got %v", got)
 	}
 }
@@ -424,8 +430,8 @@ func TestDropMigrationSet_ClearsLedgerAllowingReapply(t *testing.T) {
 		t.Fatalf("RunMigrationSet(replay): %v", err)
 	}
 	db := openDB(t, path)
-	if got := appliedNames(t, db, MigrationSetReplay); len(got) != 2 {
-		t.Fatalf("precondition: replay ledger should have 2 entries, got %v", got)
+	if got := appliedNames(t, db, MigrationSetReplay); len(got) != 16 {
+		t.Fatalf("precondition: replay ledger should have 16 entries, got %v", got)
 	}
 
 	if err := DropMigrationSet(path, MigrationSetReplay); err != nil {
@@ -434,6 +440,13 @@ func TestDropMigrationSet_ClearsLedgerAllowingReapply(t *testing.T) {
 	if tableExists(t, db, migrationsTableName(MigrationSetReplay)) {
 		t.Error("replay ledger table should be dropped")
 	}
+	var views int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type='view' AND name='commands_low_value_all'`).Scan(&views); err != nil {
+		t.Fatalf("query sqlite_master for views: %v", err)
+	}
+	if views != 0 {
+		t.Error("commands_low_value_all view should be dropped")
+	}
 
 	// Reapply must recreate the tables and repopulate the ledger from scratch.
 	if err := RunMigrationSet(path, MigrationSetReplay); err != nil {
@@ -442,7 +455,7 @@ func TestDropMigrationSet_ClearsLedgerAllowingReapply(t *testing.T) {
 	if !tableExists(t, db, "replays") {
 		t.Error("replays should exist after reapply")
 	}
-	if got := appliedNames(t, db, MigrationSetReplay); len(got) != 2 {
+	if got := appliedNames(t, db, MigrationSetReplay); len(got) != 16 {
 		t.Errorf("replay ledger should be repopulated on reapply, got %v", got)
 	}
 }
@@ -518,3 +531,329 @@ func TestSqliteDSN(t *testing.T) {
 		})
 	}
 }
+
+func TestStatus_PendingAppliedAndUnknown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "x.db")
+	if err := RunMigrationSet(path, MigrationSetReplay); err != nil {
+		t.Fatalf("RunMigrationSet(replay): %v", err)
+	}
+	db := openDB(t, path)
+	if err := recordMigrationApplied(db, MigrationSetReplay, "999999_from_the_future.up.sql"); err != nil {
+		t.Fatalf("recordMigrationApplied: %v", err)
+	}
+
+	sets, err := Status(path)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if len(sets) != 3 {
+		t.Fatalf("expected 3 sets, got %d", len(sets))
+	}
+	for _, st := range sets {
+		embedded, err := embeddedUpMigrations(st.Set)
+		if err != nil {
+			t.Fatalf("embeddedUpMigrations(%s): %v", st.Set, err)
+		}
+		switch st.Set {
+		case MigrationSetReplay:
+			if len(st.Applied) != len(embedded) || len(st.Pending) != 0 {
+				t.Errorf("replay: applied=%v pending=%v, want all %d applied", st.Applied, st.Pending, len(embedded))
+			}
+			if len(st.Unknown) != 1 || st.Unknown[0] != "999999_from_the_future.up.sql" {
+				t.Errorf("replay: unknown=%v, want the future migration", st.Unknown)
+			}
+		default:
+			if len(st.Applied) != 0 || len(st.Pending) != len(embedded) || len(st.Unknown) != 0 {
+				t.Errorf("%s: applied=%v pending=%v unknown=%v, want all pending", st.Set, st.Applied, st.Pending, st.Unknown)
+			}
+		}
+	}
+}
+
+// schemaSnapshot describes every schema object but the migration ledgers:
+// tables by their columns, since ALTER TABLE rewrites their stored SQL, and
+// everything else by its SQL.
+func schemaSnapshot(t *testing.T, db *sql.DB) map[string]string {
+	t.Helper()
+	rows, err := db.Query(`SELECT type, name, COALESCE(sql, '') FROM sqlite_master
+		WHERE name NOT LIKE 'sqlite_%' AND name NOT LIKE 'schema_migrations_%'`)
+	if err != nil {
+		t.Fatalf("query sqlite_master: %v", err)
+	}
+	objects := map[string]string{}
+	for rows.Next() {
+		var typ, name, body string
+		if err := rows.Scan(&typ, &name, &body); err != nil {
+			t.Fatalf("scan sqlite_master: %v", err)
+		}
+		objects[typ+" "+name] = strings.Join(strings.Fields(body), " ")
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatalf("rows close: %v", err)
+	}
+	for key := range objects {
+		typ, name, _ := strings.Cut(key, " ")
+		if typ != "table" {
+			continue
+		}
+		cols, err := db.Query(`SELECT name, type, "notnull", COALESCE(dflt_value, ''), pk FROM pragma_table_info(?)`, name)
+		if err != nil {
+			t.Fatalf("table_info(%s): %v", name, err)
+		}
+		var desc []string
+		for cols.Next() {
+			var col, colType, dflt string
+			var notNull, pk int
+			if err := cols.Scan(&col, &colType, &notNull, &dflt, &pk); err != nil {
+				t.Fatalf("scan table_info(%s): %v", name, err)
+			}
+			desc = append(desc, fmt.Sprintf("%s %s %d %s %d", col, colType, notNull, dflt, pk))
+		}
+		if err := cols.Close(); err != nil {
+			t.Fatalf("table_info(%s) close: %v", name, err)
+		}
+		objects[key] = strings.Join(desc, ", ")
+	}
+	return objects
+}
+
+func TestMigrateTo_DownMatchesFreshUp(t *testing.T) {
+	latest, err := LatestVersion(MigrationSetReplay)
+	if err != nil {
+		t.Fatalf("LatestVersion: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "x.db")
+	if err := RunMigrationSet(path, MigrationSetReplay); err != nil {
+		t.Fatalf("RunMigrationSet(replay): %v", err)
+	}
+	db := openDB(t, path)
+
+	for version := latest - 1; version >= 1; version-- {
+		ran, err := MigrateTo(path, MigrationSetReplay, version)
+		if err != nil {
+			t.Fatalf("MigrateTo(%d): %v", version, err)
+		}
+		if len(ran) != 1 || !strings.HasSuffix(ran[0], ".down.sql") {
+			t.Errorf("MigrateTo(%d) ran %v, want one down migration", version, ran)
+		}
+		fresh := filepath.Join(t.TempDir(), "fresh.db")
+		if _, err := MigrateTo(fresh, MigrationSetReplay, version); err != nil {
+			t.Fatalf("fresh MigrateTo(%d): %v", version, err)
+		}
+		got, want := schemaSnapshot(t, db), schemaSnapshot(t, openDB(t, fresh))
+		if !maps.Equal(got, want) {
+			t.Errorf("schema after migrating down to %d differs from migrating up to it:\n got %v\nwant %v", version, got, want)
+		}
+		if got := len(appliedNames(t, db, MigrationSetReplay)); got != version {
+			t.Errorf("ledger after migrating down to %d has %d entries", version, got)
+		}
+	}
+
+	if _, err := MigrateTo(path, MigrationSetReplay, 0); err != nil {
+		t.Fatalf("MigrateTo(0): %v", err)
+	}
+	// player_aliases belongs to the settings set and outlives the replay set.
+	if !tableExists(t, db, "player_aliases") || tableExists(t, db, "replays") {
+		t.Error("migrating replay down to 0 should drop replays and keep player_aliases")
+	}
+
+	ran, err := MigrateTo(path, MigrationSetReplay, latest)
+	if err != nil {
+		t.Fatalf("MigrateTo(latest): %v", err)
+	}
+	if len(ran) != latest {
+		t.Errorf("MigrateTo(latest) ran %v, want all %d up migrations", ran, latest)
+	}
+	fresh := filepath.Join(t.TempDir(), "fresh.db")
+	if err := RunMigrationSet(fresh, MigrationSetReplay); err != nil {
+		t.Fatalf("RunMigrationSet(fresh): %v", err)
+	}
+	if got, want := schemaSnapshot(t, db), schemaSnapshot(t, openDB(t, fresh)); !maps.Equal(got, want) {
+		t.Errorf("schema after migrating back up differs from a fresh database:\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestMigrateTo_KeepsDataAcrossDownAndUp(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "x.db")
+	if err := RunMigrations(path); err != nil {
+		t.Fatalf("RunMigrations: %v", err)
+	}
+	db := openDB(t, path)
+	exec := func(query string, args ...any) {
+		t.Helper()
+		if _, err := db.Exec(query, args...); err != nil {
+			t.Fatalf("exec %q: %v", query, err)
+		}
+	}
+	exec(`INSERT INTO replays (id, file_path, file_checksum, file_name, created_at, replay_date, map_name,
+		map_width, map_height, duration_seconds, frame_count, engine_version, engine, game_speed, game_type,
+		home_team_size, avail_slots_count) VALUES (1, 'a.rep', 'abc', 'a.rep', '2025-01-01', '2025-01-01',
+		'Fighting Spirit', 128, 128, 600, 14400, '1.16.1', 'Brood War', 'Fastest', 'Melee', '1', 8)`)
+	exec(`INSERT INTO players (id, replay_id, name, race, type, color, team, is_observer, apm, eapm, is_winner)
+		VALUES (1, 1, 'flash', 'Terran', 'Human', 'Red', 1, 0, 300, 200, 1)`)
+	exec(`INSERT INTO commands (replay_id, player_id, frame, seconds_from_game_start, action_type)
+		VALUES (1, 1, 40, 1, 'Load')`)
+	for _, action := range []string{"Select", "Hotkey"} {
+		exec(`INSERT INTO commands_low_value (replay_id, player_id, frame, seconds_from_game_start, action_type)
+			VALUES (1, 1, 10, 0, ?)`, action)
+	}
+	// Stream order: Right Click, Select, Minimap Ping, Hotkey.
+	payload, err := cmdblob.Encode([]cmdblob.Row{
+		{Ordinal: 0, PlayerID: 1, Frame: 10, ActionType: "Right Click"},
+		{Ordinal: 2, PlayerID: 1, Frame: 10, ActionType: "Minimap Ping"},
+	})
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	exec(`INSERT INTO command_blobs (replay_id, format_version, row_count, payload) VALUES (1, ?, 2, ?)`, cmdblob.FormatVersion, payload)
+
+	if _, err := MigrateTo(path, MigrationSetReplay, 1); err != nil {
+		t.Fatalf("MigrateTo(1): %v", err)
+	}
+	lowValue := func() []string {
+		t.Helper()
+		rows, err := db.Query(`SELECT action_type FROM commands_low_value WHERE replay_id = 1 ORDER BY id`)
+		if err != nil {
+			t.Fatalf("query commands_low_value: %v", err)
+		}
+		defer rows.Close()
+		var actions []string
+		for rows.Next() {
+			var action string
+			if err := rows.Scan(&action); err != nil {
+				t.Fatalf("scan: %v", err)
+			}
+			actions = append(actions, action)
+		}
+		return actions
+	}
+	// The Load goes back to being the Right Click it was classified from.
+	want := []string{"Right Click", "Select", "Minimap Ping", "Hotkey", "Right Click"}
+	if got := lowValue(); !slices.Equal(got, want) {
+		t.Errorf("commands_low_value after migrating down = %v, want %v", got, want)
+	}
+
+	if _, err := MigrateTo(path, MigrationSetReplay, 16); err != nil {
+		t.Fatalf("MigrateTo(16): %v", err)
+	}
+	if got := lowValue(); !slices.Equal(got, want) {
+		t.Errorf("commands_low_value after migrating back up = %v, want %v", got, want)
+	}
+	var players int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM players WHERE replay_id = 1`).Scan(&players); err != nil {
+		t.Fatalf("count players: %v", err)
+	}
+	if players != 1 {
+		t.Errorf("players after the round trip = %d, want 1", players)
+	}
+}
+
+func TestMigrateTo_DownResetsAnalyzerVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "x.db")
+	if err := RunMigrationSet(path, MigrationSetReplay); err != nil {
+		t.Fatalf("RunMigrationSet(replay): %v", err)
+	}
+	db := openDB(t, path)
+	if _, err := db.Exec(`INSERT INTO replays (id, file_path, file_checksum, file_name, created_at, replay_date,
+		map_name, map_width, map_height, duration_seconds, frame_count, engine_version, engine, game_speed,
+		game_type, home_team_size, avail_slots_count, analyzer_algorithm_version) VALUES (1, 'a.rep', 'abc',
+		'a.rep', '2025-01-01', '2025-01-01', 'Fighting Spirit', 128, 128, 600, 14400, '1.16.1', 'Brood War',
+		'Fastest', 'Melee', '1', 8, 65)`); err != nil {
+		t.Fatalf("insert replay: %v", err)
+	}
+
+	// Each table's down migration leaves the replays it dropped data for
+	// below the version that first filled it.
+	for _, step := range []struct{ target, want int }{{14, 63}, {13, 62}, {12, 61}} {
+		if _, err := MigrateTo(path, MigrationSetReplay, step.target); err != nil {
+			t.Fatalf("MigrateTo(%d): %v", step.target, err)
+		}
+		var got int
+		if err := db.QueryRow(`SELECT analyzer_algorithm_version FROM replays WHERE id = 1`).Scan(&got); err != nil {
+			t.Fatalf("read version: %v", err)
+		}
+		if got != step.want {
+			t.Errorf("analyzer_algorithm_version after migrating down to %d = %d, want %d", step.target, got, step.want)
+		}
+	}
+}
+
+func TestMigrateTo_SettingsAndDashboardRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "x.db")
+	if err := RunMigrations(path); err != nil {
+		t.Fatalf("RunMigrations: %v", err)
+	}
+	db := openDB(t, path)
+
+	if _, err := MigrateTo(path, MigrationSetDashboard, 0); err != nil {
+		t.Fatalf("MigrateTo(dashboard, 0): %v", err)
+	}
+	if !tableExists(t, db, "settings") {
+		t.Error("migrating dashboard down should keep settings, which the settings set owns")
+	}
+	if _, err := MigrateTo(path, MigrationSetSettings, 0); err != nil {
+		t.Fatalf("MigrateTo(settings, 0): %v", err)
+	}
+	if tableExists(t, db, "settings") || tableExists(t, db, "player_aliases") {
+		t.Error("migrating settings down should drop settings and player_aliases")
+	}
+	if err := RunMigrations(path); err != nil {
+		t.Fatalf("RunMigrations after migrating down: %v", err)
+	}
+	sets, err := Status(path)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	for _, st := range sets {
+		latest, err := LatestVersion(st.Set)
+		if err != nil {
+			t.Fatalf("LatestVersion(%s): %v", st.Set, err)
+		}
+		if st.Version() != latest || len(st.Pending) != 0 {
+			t.Errorf("%s: version %d pending %v, want %d and none", st.Set, st.Version(), st.Pending, latest)
+		}
+	}
+}
+
+func TestMigrateTo_Rejects(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "x.db")
+	if err := RunMigrationSet(path, MigrationSetReplay); err != nil {
+		t.Fatalf("RunMigrationSet(replay): %v", err)
+	}
+	latest, err := LatestVersion(MigrationSetReplay)
+	if err != nil {
+		t.Fatalf("LatestVersion: %v", err)
+	}
+	for _, target := range []int{-1, latest + 1} {
+		if _, err := MigrateTo(path, MigrationSetReplay, target); err == nil {
+			t.Errorf("MigrateTo(%d) should fail", target)
+		}
+	}
+	if _, err := MigrateTo(path, MigrationSet("bogus"), 0); err == nil {
+		t.Error("MigrateTo should fail for an unknown set")
+	}
+
+	db := openDB(t, path)
+	if err := recordMigrationApplied(db, MigrationSetReplay, "999999_from_the_future.up.sql"); err != nil {
+		t.Fatalf("recordMigrationApplied: %v", err)
+	}
+	if _, err := MigrateTo(path, MigrationSetReplay, latest-1); err == nil {
+		t.Error("MigrateTo should refuse a database with migrations from a newer build")
+	}
+	if got := appliedNames(t, db, MigrationSetReplay); len(got) != latest+1 {
+		t.Errorf("a refused MigrateTo should leave the ledger alone, got %v", got)
+	}
+}
+
+func TestMigrationVersion(t *testing.T) {
+	for name, want := range map[string]int{
+		"000001_initial.up.sql":           1,
+		"000008_player_aggregates.up.sql": 8,
+		"000012.up.sql":                   0,
+		"notes.sql":                       0,
+	} {
+		if got := migrationVersion(name); got != want {
+			t.Errorf("migrationVersion(%q) = %d, want %d", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+//go:embed postgres/*.sql
+var postgresFS embed.FS
+
+// postgresMigrationsTable is the ledger of applied PostgreSQL migrations.
+const postgresMigrationsTable = "schema_migrations_postgres"
+
+// postgresMigrationLock is the advisory lock key RunPostgresMigrations and
+// DropPostgresMigrations hold, so teammates starting ingests against the same
+// fresh database don't apply a migration twice.
+const postgresMigrationLock = 7_305_246_001
+
+// RunPostgresMigrations applies the pending PostgreSQL migrations to db. The
+// postgres set mirrors the replay set file for file; there is no dashboard or
+// settings set, since the dashboard serves a local SQLite mirror. Like the
+// replay set, it ends by syncing the marker reference tables.
+func RunPostgresMigrations(ctx context.Context, db *sql.DB) error {
+	conn, unlock, err := lockPostgresMigrations(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+postgresMigrationsTable+` (
+		name TEXT PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return fmt.Errorf("failed to ensure migrations table %s: %w", postgresMigrationsTable, err)
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT name FROM `+postgresMigrationsTable)
+	if err != nil {
+		return fmt.Errorf("failed to load applied migrations from %s: %w", postgresMigrationsTable, err)
+	}
+	applied := map[string]struct{}{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan applied migration name: %w", err)
+		}
+		applied[name] = struct{}{}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	files, err := postgresUpMigrations()
+	if err != nil {
+		return err
+	}
+	for _, name := range files {
+		if _, ok := applied[name]; ok {
+			continue
+		}
+		body, err := postgresFS.ReadFile(path.Join("postgres", name))
+		if err != nil {
+			return fmt.Errorf("failed to read migration %s: %w", name, err)
+		}
+		if _, err := conn.ExecContext(ctx, string(body)); err != nil {
+			return fmt.Errorf("failed to execute migration %s: %w", name, err)
+		}
+		if _, err := conn.ExecContext(ctx, `INSERT INTO `+postgresMigrationsTable+` (name) VALUES ($1) ON CONFLICT (name) DO NOTHING`, name); err != nil {
+			return fmt.Errorf("failed to record applied migration %s: %w", name, err)
+		}
+	}
+	return syncMarkerReference(ctx, conn, postgresPlaceholder)
+}
+
+// DropPostgresMigrations drops every table the PostgreSQL migrations created,
+// except the settings-owned ones DropMigrationSet preserves, and clears the
+// ledger so RunPostgresMigrations starts over.
+func DropPostgresMigrations(ctx context.Context, db *sql.DB) error {
+	conn, unlock, err := lockPostgresMigrations(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	files, err := postgresUpMigrations()
+	if err != nil {
+		return err
+	}
+	var tables []string
+	seen := map[string]struct{}{}
+	for _, name := range files {
+		body, err := postgresFS.ReadFile(path.Join("postgres", name))
+		if err != nil {
+			return fmt.Errorf("failed to read migration %s: %w", name, err)
+		}
+		for _, m := range createTableRegexp.FindAllStringSubmatch(string(body), -1) {
+			if _, dup := seen[m[1]]; dup {
+				continue
+			}
+			seen[m[1]] = struct{}{}
+			tables = append(tables, m[1])
+		}
+	}
+	for i := len(tables) - 1; i >= 0; i-- {
+		if _, skip := preservedTablesAcrossWipes[tables[i]]; skip {
+			continue
+		}
+		if _, err := conn.ExecContext(ctx, `DROP TABLE IF EXISTS `+tables[i]+` CASCADE`); err != nil {
+			return fmt.Errorf("failed to drop table %s: %w", tables[i], err)
+		}
+	}
+	if _, err := conn.ExecContext(ctx, `DROP TABLE IF EXISTS `+postgresMigrationsTable); err != nil {
+		return fmt.Errorf("failed to drop migrations ledger %s: %w", postgresMigrationsTable, err)
+	}
+	return nil
+}
+
+// lockPostgresMigrations pins a connection and takes the migration advisory
+// lock on it. The returned func releases both.
+func lockPostgresMigrations(ctx context.Context, db *sql.DB) (*sql.Conn, func(), error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf(`SELECT pg_advisory_lock(%d)`, postgresMigrationLock)); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	return conn, func() {
+		_, _ = conn.ExecContext(context.Background(), fmt.Sprintf(`SELECT pg_advisory_unlock(%d)`, postgresMigrationLock))
+		conn.Close()
+	}, nil
+}
+
+// postgresUpMigrations lists the postgres set's .up.sql files in apply order.
+func postgresUpMigrations() ([]string, error) {
+	entries, err := postgresFS.ReadDir("postgres")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".up.sql") {
+			files = append(files, entry.Name())
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
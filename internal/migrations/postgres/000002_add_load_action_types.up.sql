@@ -0,0 +1,9 @@
+BEGIN;
+
+-- Add 'Load' and 'LoadBunker' to the commands.action_type CHECK constraint
+-- (see replay/000002_add_load_action_types). PostgreSQL swaps the constraint
+-- in place; commands_action_type_check is the name it gave the inline CHECK.
+ALTER TABLE commands DROP CONSTRAINT IF EXISTS commands_action_type_check;
+ALTER TABLE commands ADD CONSTRAINT commands_action_type_check CHECK (action_type IN ('Keep Alive', 'Save Game', 'Load Game', 'Restart Game', 'Select', 'Select Add', 'Select Remove', 'Build', 'Vision', 'Alliance', 'Game Speed', 'Pause', 'Resume', 'Cheat', 'Hotkey', 'Right Click', 'Targeted Order', 'Cancel Build', 'Cancel Morph', 'Stop', 'Carrier Stop', 'Reaver Stop', 'Order Nothing', 'Return Cargo', 'Train', 'Cancel Train', 'Cloack', 'Decloack', 'Unit Morph', 'Unsiege', 'Siege', 'Train Fighter', 'Unload All', 'Unload', 'Merge Archon', 'Hold Position', 'Burrow', 'Unburrow', 'Cancel Nuke', 'Lift Off', 'Tech', 'Cancel Tech', 'Upgrade', 'Cancel Upgrade', 'Cancel Addon', 'Building Morph', 'Stim', 'Sync', 'Voice Enable', 'Voice Disable', 'Voice Squelch', 'Voice Unsquelch', '[Lobby] Start Game', '[Lobby] Download Percentage', '[Lobby] Change Game Slot', '[Lobby] New Net Player', '[Lobby] Joined Game', '[Lobby] Change Race', '[Lobby] Team Game Team', '[Lobby] UMS Team', '[Lobby] Melee Team', '[Lobby] Swap Players', '[Lobby] Saved Data', 'Briefing Start', 'Latency', 'Replay Speed', 'Leave Game', 'Minimap Ping', 'Merge Dark Archon', 'Make Game Public', 'Chat', 'Land', 'Load', 'LoadBunker', 'UNKNOWN'));
+
+COMMIT;
@@ -0,0 +1,18 @@
+BEGIN;
+
+-- Ledger of replay files that failed to ingest or were skipped, one row per
+-- file path (see replay/000003_ingest_failures).
+CREATE TABLE IF NOT EXISTS ingest_failures (
+	id BIGSERIAL PRIMARY KEY,
+	file_path TEXT NOT NULL UNIQUE,
+	file_checksum TEXT NOT NULL DEFAULT '',
+	error_class TEXT NOT NULL CHECK (error_class IN ('parse_error', 'panic', 'ums')),
+	message TEXT NOT NULL,
+	stack_hash TEXT NOT NULL DEFAULT '',
+	screpdb_version TEXT NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 1,
+	first_failed_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	last_failed_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMIT;
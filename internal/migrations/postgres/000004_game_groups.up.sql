@@ -0,0 +1,14 @@
+BEGIN;
+
+-- Game identity (see replay/000004_game_groups): replays of the same game
+-- share a game_fingerprint and are grouped under the id of the group's
+-- canonical replay.
+ALTER TABLE replays ADD COLUMN IF NOT EXISTS game_fingerprint TEXT NOT NULL DEFAULT '';
+ALTER TABLE replays ADD COLUMN IF NOT EXISTS game_group_id BIGINT;
+
+UPDATE replays SET game_group_id = id WHERE game_group_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_replays_game_fingerprint ON replays(game_fingerprint);
+CREATE INDEX IF NOT EXISTS idx_replays_game_group_id ON replays(game_group_id);
+
+COMMIT;
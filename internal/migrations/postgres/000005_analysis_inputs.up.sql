@@ -0,0 +1,12 @@
+BEGIN;
+
+-- Detection input persisted at ingest for file-independent re-analysis (see
+-- replay/000005_analysis_inputs and internal/analysisinput).
+CREATE TABLE IF NOT EXISTS replay_analysis_inputs (
+	replay_id BIGINT PRIMARY KEY,
+	format_version INTEGER NOT NULL,
+	payload BYTEA NOT NULL,
+	FOREIGN KEY (replay_id) REFERENCES replays(id) ON DELETE CASCADE
+);
+
+COMMIT;
@@ -0,0 +1,10 @@
+BEGIN;
+
+-- Full-text index over in-game chat (see replay/000006_chat_search and
+-- internal/chatsearch). PostgreSQL indexes the expression itself, so there is
+-- no side table or trigger to maintain.
+CREATE INDEX IF NOT EXISTS idx_commands_chat_search
+	ON commands USING GIN (to_tsvector('simple', chat_message))
+	WHERE action_type = 'Chat';
+
+COMMIT;
@@ -0,0 +1,23 @@
+BEGIN;
+
+-- Compact command storage (see replay/000007_command_blobs). Only SQLite
+-- databases compact commands, since only SQLite can decode blobs in SQL, so
+-- this table stays empty; it exists to keep the two schemas in step, and the
+-- view lets analyst queries written against SQLite run unchanged.
+CREATE TABLE IF NOT EXISTS command_blobs (
+	replay_id BIGINT PRIMARY KEY,
+	format_version INTEGER NOT NULL,
+	row_count INTEGER NOT NULL,
+	payload BYTEA NOT NULL,
+	FOREIGN KEY (replay_id) REFERENCES replays(id) ON DELETE CASCADE
+);
+
+CREATE OR REPLACE VIEW commands_low_value_all AS
+SELECT
+	id, replay_id, player_id, frame, seconds_from_game_start, action_type,
+	x, y, is_queued, order_name, unit_type, unit_types, tech_name,
+	upgrade_name, hotkey_type, hotkey_group, game_speed, vision_player_ids,
+	alliance_player_ids, is_allied_victory, general_data, chat_message, leave_reason
+FROM commands_low_value;
+
+COMMIT;
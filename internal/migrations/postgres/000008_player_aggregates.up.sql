@@ -0,0 +1,117 @@
+BEGIN;
+
+-- Player aggregates (see replay/000008_player_aggregates). Only SQLite
+-- databases maintain them, since the dashboard reads its local SQLite mirror,
+-- which builds its own; these tables stay empty and exist to keep the two
+-- schemas in step.
+CREATE TABLE IF NOT EXISTS player_game_facts (
+	player_id BIGINT PRIMARY KEY REFERENCES players(id) ON DELETE CASCADE,
+	replay_id BIGINT NOT NULL REFERENCES replays(id) ON DELETE CASCADE,
+	player_key TEXT NOT NULL,
+	player_name TEXT NOT NULL,
+	race TEXT NOT NULL,
+	opp_race TEXT NOT NULL DEFAULT '',
+	team_format TEXT NOT NULL,
+	map_kind TEXT NOT NULL,
+	game_type TEXT NOT NULL,
+	is_one_on_one INTEGER NOT NULL,
+	is_short INTEGER NOT NULL,
+	has_computers INTEGER NOT NULL,
+	replay_date TEXT NOT NULL,
+	is_winner INTEGER NOT NULL,
+	apm INTEGER NOT NULL,
+	eapm INTEGER NOT NULL,
+	viewport_switch_rate DOUBLE PRECISION,
+	cadence_strict_rate DOUBLE PRECISION,
+	cadence_strict_cv DOUBLE PRECISION,
+	cadence_strict_burstiness DOUBLE PRECISION,
+	cadence_strict_idle DOUBLE PRECISION,
+	cadence_strict_score DOUBLE PRECISION,
+	cadence_broad_rate DOUBLE PRECISION,
+	cadence_broad_cv DOUBLE PRECISION,
+	cadence_broad_burstiness DOUBLE PRECISION,
+	cadence_broad_idle DOUBLE PRECISION,
+	cadence_broad_score DOUBLE PRECISION
+);
+
+CREATE INDEX IF NOT EXISTS idx_player_game_facts_replay ON player_game_facts(replay_id);
+CREATE INDEX IF NOT EXISTS idx_player_game_facts_key ON player_game_facts(player_key);
+
+CREATE TABLE IF NOT EXISTS player_aggregates (
+	player_key TEXT NOT NULL,
+	map_kind TEXT NOT NULL,
+	game_type TEXT NOT NULL,
+	is_one_on_one INTEGER NOT NULL,
+	is_canonical INTEGER NOT NULL,
+	is_short INTEGER NOT NULL,
+	has_computers INTEGER NOT NULL,
+	player_name TEXT NOT NULL,
+	games BIGINT NOT NULL,
+	wins BIGINT NOT NULL,
+	protoss_games BIGINT NOT NULL,
+	terran_games BIGINT NOT NULL,
+	zerg_games BIGINT NOT NULL,
+	apm_sum BIGINT NOT NULL,
+	apm_games BIGINT NOT NULL,
+	eapm_sum BIGINT NOT NULL,
+	eapm_games BIGINT NOT NULL,
+	last_played TEXT NOT NULL,
+	viewport_games BIGINT NOT NULL,
+	viewport_rate_sum DOUBLE PRECISION NOT NULL,
+	cadence_strict_games BIGINT NOT NULL,
+	cadence_strict_rate_sum DOUBLE PRECISION NOT NULL,
+	cadence_strict_cv_sum DOUBLE PRECISION NOT NULL,
+	cadence_strict_burstiness_sum DOUBLE PRECISION NOT NULL,
+	cadence_strict_idle_sum DOUBLE PRECISION NOT NULL,
+	cadence_strict_score_sum DOUBLE PRECISION NOT NULL,
+	cadence_broad_games BIGINT NOT NULL,
+	cadence_broad_rate_sum DOUBLE PRECISION NOT NULL,
+	cadence_broad_cv_sum DOUBLE PRECISION NOT NULL,
+	cadence_broad_burstiness_sum DOUBLE PRECISION NOT NULL,
+	cadence_broad_idle_sum DOUBLE PRECISION NOT NULL,
+	cadence_broad_score_sum DOUBLE PRECISION NOT NULL,
+	PRIMARY KEY (player_key, map_kind, game_type, is_one_on_one, is_canonical, is_short, has_computers)
+);
+
+CREATE TABLE IF NOT EXISTS player_matchup_aggregates (
+	player_key TEXT NOT NULL,
+	race TEXT NOT NULL,
+	opp_race TEXT NOT NULL,
+	team_format TEXT NOT NULL,
+	map_kind TEXT NOT NULL,
+	game_type TEXT NOT NULL,
+	is_one_on_one INTEGER NOT NULL,
+	is_canonical INTEGER NOT NULL,
+	is_short INTEGER NOT NULL,
+	has_computers INTEGER NOT NULL,
+	games BIGINT NOT NULL,
+	wins BIGINT NOT NULL,
+	apm_sum BIGINT NOT NULL,
+	apm_games BIGINT NOT NULL,
+	eapm_sum BIGINT NOT NULL,
+	eapm_games BIGINT NOT NULL,
+	PRIMARY KEY (player_key, race, opp_race, team_format, map_kind, game_type, is_one_on_one, is_canonical, is_short, has_computers)
+);
+
+CREATE TABLE IF NOT EXISTS player_marker_counts (
+	player_key TEXT NOT NULL,
+	race TEXT NOT NULL,
+	opp_race TEXT NOT NULL,
+	team_format TEXT NOT NULL,
+	map_kind TEXT NOT NULL,
+	game_type TEXT NOT NULL,
+	is_one_on_one INTEGER NOT NULL,
+	is_canonical INTEGER NOT NULL,
+	is_short INTEGER NOT NULL,
+	has_computers INTEGER NOT NULL,
+	event_type TEXT NOT NULL,
+	replays BIGINT NOT NULL,
+	PRIMARY KEY (player_key, race, opp_race, team_format, map_kind, game_type, is_one_on_one, is_canonical, is_short, has_computers, event_type)
+);
+
+CREATE TABLE IF NOT EXISTS player_aggregates_state (
+	id INTEGER PRIMARY KEY CHECK (id = 1),
+	version INTEGER NOT NULL
+);
+
+COMMIT;
@@ -0,0 +1,187 @@
+BEGIN;
+
+-- Analyst views (see replay/000009_analyst_views): the same views, with the
+-- same columns, over PostgreSQL's JSON functions. Booleans are true/false
+-- here where SQLite has 0/1.
+CREATE TABLE IF NOT EXISTS marker_definitions (
+	event_type TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	kind TEXT NOT NULL CHECK (kind IN ('initial_build_order', 'marker')),
+	race TEXT NOT NULL,
+	matchups TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS marker_expert_milestones (
+	event_type TEXT NOT NULL,
+	milestone_index INTEGER NOT NULL,
+	milestone TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	target_second INTEGER NOT NULL,
+	tolerance_early_seconds INTEGER NOT NULL,
+	tolerance_late_seconds INTEGER NOT NULL,
+	PRIMARY KEY (event_type, milestone_index)
+);
+
+CREATE OR REPLACE VIEW analyst_player_games_v1 AS
+SELECT
+	r.id AS replay_id,
+	COALESCE(r.game_group_id, r.id) AS game_group_id,
+	COALESCE(r.game_group_id, r.id) = r.id AS is_canonical,
+	r.replay_date,
+	r.map_name,
+	r.map_kind,
+	r.game_type,
+	r.team_format,
+	r.matchup,
+	r.duration_seconds,
+	p.id AS player_id,
+	p.name AS player_name,
+	p.race,
+	p.type AS player_type,
+	p.team,
+	p.is_winner,
+	p.apm,
+	p.eapm,
+	p.start_location_oclock,
+	o.event_type AS opener,
+	COALESCE(o.payload::json ->> 'label', d.name) AS opener_name,
+	(SELECT string_agg(m, ', ') FROM json_array_elements_text(o.payload::json -> 'modifiers') m) AS opener_modifiers
+FROM players p
+JOIN replays r ON r.id = p.replay_id
+LEFT JOIN replay_events o ON o.id = (
+	SELECT MIN(e.id) FROM replay_events e
+	WHERE e.replay_id = p.replay_id
+		AND e.source_player_id = p.id
+		AND e.event_kind = 'marker'
+		AND e.event_type LIKE 'bo\_%' ESCAPE '\'
+)
+LEFT JOIN marker_definitions d ON d.event_type = o.event_type
+WHERE NOT p.is_observer;
+
+CREATE OR REPLACE VIEW analyst_build_order_steps_v1 AS
+SELECT
+	c.replay_id,
+	c.player_id,
+	p.name AS player_name,
+	p.race,
+	ROW_NUMBER() OVER (PARTITION BY c.replay_id, c.player_id ORDER BY c.frame, c.id) AS step,
+	c.frame,
+	c.seconds_from_game_start AS second,
+	c.action_type,
+	COALESCE(c.unit_type, c.tech_name, c.upgrade_name) AS item
+FROM commands c
+JOIN players p ON p.id = c.player_id
+WHERE c.action_type IN ('Build', 'Train', 'Unit Morph', 'Building Morph', 'Tech', 'Upgrade')
+	AND COALESCE(c.unit_type, c.tech_name, c.upgrade_name) IS NOT NULL;
+
+CREATE OR REPLACE VIEW analyst_attacks_v1 AS
+SELECT
+	e.id AS attack_id,
+	e.replay_id,
+	e.seconds_from_game_start AS second,
+	e.source_player_id AS attacker_player_id,
+	a.name AS attacker_name,
+	a.race AS attacker_race,
+	e.target_player_id AS defender_player_id,
+	d.name AS defender_name,
+	d.race AS defender_race,
+	e.location_base_type,
+	e.location_base_oclock,
+	e.location_natural_of_oclock,
+	e.location_mineral_only,
+	(SELECT string_agg(u, ', ') FROM json_array_elements_text(e.attack_unit_types::json) u) AS unit_types,
+	(SELECT COALESCE(SUM(c.value::integer), 0) FROM json_each_text(e.attack_cast_counts::json) c) AS cast_count
+FROM replay_events e
+LEFT JOIN players a ON a.id = e.source_player_id
+LEFT JOIN players d ON d.id = e.target_player_id
+WHERE e.event_kind = 'game_event' AND e.event_type = 'attack';
+
+CREATE OR REPLACE VIEW analyst_attack_units_v1 AS
+SELECT
+	e.id AS attack_id,
+	e.replay_id,
+	e.seconds_from_game_start AS second,
+	e.source_player_id AS attacker_player_id,
+	u.unit_type
+FROM replay_events e
+CROSS JOIN LATERAL json_array_elements_text(e.attack_unit_types::json) AS u(unit_type)
+WHERE e.event_kind = 'game_event' AND e.event_type = 'attack';
+
+CREATE OR REPLACE VIEW analyst_attack_casts_v1 AS
+SELECT
+	e.id AS attack_id,
+	e.replay_id,
+	e.seconds_from_game_start AS second,
+	e.source_player_id AS attacker_player_id,
+	c.cast_name,
+	c.cast_count::integer AS cast_count
+FROM replay_events e
+CROSS JOIN LATERAL json_each_text(e.attack_cast_counts::json) AS c(cast_name, cast_count)
+WHERE e.event_kind = 'game_event' AND e.event_type = 'attack';
+
+CREATE OR REPLACE VIEW analyst_drops_v1 AS
+SELECT
+	e.id AS drop_id,
+	e.replay_id,
+	e.seconds_from_game_start AS second,
+	COALESCE((e.payload::json ->> 'le')::integer, e.seconds_from_game_start) AS last_unload_second,
+	COALESCE((e.payload::json ->> 'n')::integer, 1) AS unload_count,
+	e.event_type = 'cliff_drop' AS is_cliff_drop,
+	e.source_player_id AS player_id,
+	a.name AS player_name,
+	a.race AS player_race,
+	e.target_player_id AS target_player_id,
+	d.name AS target_player_name,
+	d.race AS target_player_race,
+	e.location_base_type,
+	e.location_base_oclock,
+	e.location_natural_of_oclock,
+	NULLIF(e.payload::json -> 'sb' ->> 'k', '') AS source_base_type,
+	CASE WHEN e.payload::json -> 'sb' IS NOT NULL THEN COALESCE((e.payload::json -> 'sb' ->> 'o')::integer, 0) END AS source_base_oclock,
+	CASE e.payload::json ->> 'tv' WHEN 'a' THEN 'attack' WHEN 'p' THEN 'activity' END AS target_inferred_from,
+	(SELECT string_agg(u, ', ') FROM json_array_elements_text(e.attack_unit_types::json) u) AS unit_types
+FROM replay_events e
+LEFT JOIN players a ON a.id = e.source_player_id
+LEFT JOIN players d ON d.id = e.target_player_id
+WHERE e.event_kind = 'game_event' AND e.event_type IN ('drop', 'cliff_drop');
+
+CREATE OR REPLACE VIEW analyst_opener_timings_v1 AS
+SELECT
+	t.replay_id,
+	t.player_id,
+	t.player_name,
+	t.race,
+	t.opener,
+	t.milestone_index,
+	t.milestone,
+	t.subject,
+	t.target_second,
+	t.tolerance_early_seconds,
+	t.tolerance_late_seconds,
+	t.actual_second IS NOT NULL AS found,
+	t.actual_second,
+	t.actual_second - t.target_second AS delta_seconds,
+	t.actual_second - t.target_second BETWEEN -t.tolerance_early_seconds AND t.tolerance_late_seconds AS within_tolerance
+FROM (
+	SELECT
+		o.replay_id,
+		o.source_player_id AS player_id,
+		p.name AS player_name,
+		p.race,
+		o.event_type AS opener,
+		m.milestone_index,
+		m.milestone,
+		m.subject,
+		m.target_second,
+		m.tolerance_early_seconds,
+		m.tolerance_late_seconds,
+		CASE WHEN (o.payload::json -> 'expert_actuals' -> m.milestone_index ->> 'found')::boolean
+			THEN COALESCE((o.payload::json -> 'expert_actuals' -> m.milestone_index ->> 'second')::integer, 0)
+		END AS actual_second
+	FROM replay_events o
+	JOIN marker_expert_milestones m ON m.event_type = o.event_type
+	LEFT JOIN players p ON p.id = o.source_player_id
+	WHERE o.event_kind = 'marker'
+) t;
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+-- File stamps (see replay/000010_file_stamps): the size and modification time
+-- (Unix nanoseconds) a replay's file had when it was ingested.
+ALTER TABLE replays ADD COLUMN IF NOT EXISTS file_size BIGINT;
+ALTER TABLE replays ADD COLUMN IF NOT EXISTS file_mod_time BIGINT;
+
+COMMIT;
@@ -0,0 +1,17 @@
+BEGIN;
+
+-- Replay generation (see replay/000011_replay_generation). The dashboard only
+-- reads it from the SQLite mirror it serves a PostgreSQL corpus through, whose
+-- own triggers count each refresh, so this table keeps the sets in step but
+-- has no triggers: a shared counter row would serialize concurrent ingests.
+CREATE TABLE IF NOT EXISTS replay_generation (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    epoch TEXT NOT NULL,
+    generation BIGINT NOT NULL DEFAULT 0
+);
+
+INSERT INTO replay_generation (id, epoch, generation)
+VALUES (1, md5(random()::text || clock_timestamp()::text), 0)
+ON CONFLICT (id) DO NOTHING;
+
+COMMIT;
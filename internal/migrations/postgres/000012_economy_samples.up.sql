@@ -0,0 +1,21 @@
+BEGIN;
+
+-- Estimated economy timeline (see replay/000012_economy_samples).
+CREATE TABLE IF NOT EXISTS player_economy_samples (
+	replay_id BIGINT NOT NULL,
+	player_id BIGINT NOT NULL,
+	second INTEGER NOT NULL,
+	minerals INTEGER NOT NULL,
+	gas INTEGER NOT NULL,
+	mineral_income INTEGER NOT NULL,
+	gas_income INTEGER NOT NULL,
+	supply_used INTEGER NOT NULL,
+	supply_max INTEGER NOT NULL,
+	workers INTEGER NOT NULL,
+	bases INTEGER NOT NULL,
+	PRIMARY KEY (replay_id, player_id, second),
+	FOREIGN KEY (replay_id) REFERENCES replays(id) ON DELETE CASCADE,
+	FOREIGN KEY (player_id) REFERENCES players(id) ON DELETE CASCADE
+);
+
+COMMIT;
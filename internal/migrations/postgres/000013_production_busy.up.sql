@@ -0,0 +1,18 @@
+BEGIN;
+
+-- Estimated production building busy time (see replay/000013_production_busy).
+CREATE TABLE IF NOT EXISTS production_busy_spans (
+	replay_id BIGINT NOT NULL,
+	player_id BIGINT NOT NULL,
+	building TEXT NOT NULL,
+	building_index INTEGER NOT NULL,
+	ready_second INTEGER NOT NULL,
+	window_end_second INTEGER NOT NULL,
+	start_second INTEGER NOT NULL,
+	end_second INTEGER NOT NULL,
+	PRIMARY KEY (replay_id, player_id, building, building_index, start_second),
+	FOREIGN KEY (replay_id) REFERENCES replays(id) ON DELETE CASCADE,
+	FOREIGN KEY (player_id) REFERENCES players(id) ON DELETE CASCADE
+);
+
+COMMIT;
@@ -0,0 +1,19 @@
+BEGIN;
+
+-- Estimated Zerg larva timelines (see replay/000014_larva_samples).
+CREATE TABLE IF NOT EXISTS larva_samples (
+	replay_id BIGINT NOT NULL,
+	player_id BIGINT NOT NULL,
+	hatchery_index INTEGER NOT NULL,
+	window_end_second INTEGER NOT NULL,
+	second INTEGER NOT NULL,
+	larvae INTEGER NOT NULL,
+	spawned INTEGER NOT NULL,
+	used INTEGER NOT NULL,
+	missed INTEGER NOT NULL,
+	PRIMARY KEY (replay_id, player_id, hatchery_index, second),
+	FOREIGN KEY (replay_id) REFERENCES replays(id) ON DELETE CASCADE,
+	FOREIGN KEY (player_id) REFERENCES players(id) ON DELETE CASCADE
+);
+
+COMMIT;
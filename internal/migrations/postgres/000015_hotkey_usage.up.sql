@@ -0,0 +1,20 @@
+BEGIN;
+
+-- Per-second hotkey group use (see replay/000015_hotkey_usage).
+CREATE TABLE IF NOT EXISTS hotkey_usage (
+	replay_id BIGINT NOT NULL,
+	player_id BIGINT NOT NULL,
+	hotkey_group INTEGER NOT NULL,
+	window_end_second INTEGER NOT NULL,
+	second INTEGER NOT NULL,
+	role TEXT NOT NULL,
+	assigns INTEGER NOT NULL,
+	selects INTEGER NOT NULL,
+	adds INTEGER NOT NULL,
+	camera_jumps INTEGER NOT NULL,
+	PRIMARY KEY (replay_id, player_id, hotkey_group, second),
+	FOREIGN KEY (replay_id) REFERENCES replays(id) ON DELETE CASCADE,
+	FOREIGN KEY (player_id) REFERENCES players(id) ON DELETE CASCADE
+);
+
+COMMIT;
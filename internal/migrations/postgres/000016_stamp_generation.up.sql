@@ -0,0 +1,6 @@
+BEGIN;
+
+-- Stamp generation (see replay/000016_stamp_generation). The postgres set has
+-- no replay generation triggers to narrow; this keeps the sets in step.
+
+COMMIT;
@@ -0,0 +1,73 @@
+package migrations
+
+import (
+	"embed"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// TestPostgresMigrationsMirrorReplaySet keeps the postgres set in step with
+// the replay set: same up migrations, ending with the same tables. Only the
+// replay set has down migrations; postgres databases migrate forward only.
+func TestPostgresMigrationsMirrorReplaySet(t *testing.T) {
+	postgres, err := postgresUpMigrations()
+	if err != nil {
+		t.Fatalf("postgresUpMigrations: %v", err)
+	}
+	entries, err := replayFS.ReadDir("replay")
+	if err != nil {
+		t.Fatalf("read replay set: %v", err)
+	}
+	var replay []string
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".up.sql") {
+			replay = append(replay, e.Name())
+		}
+	}
+	sort.Strings(replay)
+	if !slices.Equal(postgres, replay) {
+		t.Fatalf("postgres migrations %v, want the replay set's %v", postgres, replay)
+	}
+
+	if got, want := finalTables(t, postgresFS, "postgres", postgres), finalTables(t, replayFS, "replay", replay); !slices.Equal(got, want) {
+		t.Errorf("postgres set ends with tables %v, replay set with %v", got, want)
+	}
+}
+
+// tableDDLRegexp matches the statements that add, drop or rename a table.
+var tableDDLRegexp = regexp.MustCompile(`(?i)CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?(\w+)|DROP\s+TABLE\s+(?:IF\s+EXISTS\s+)?(\w+)|ALTER\s+TABLE\s+(\w+)\s+RENAME\s+TO\s+(\w+)`)
+
+// finalTables returns the tables left after applying names from fsys/dir in
+// order, sorted. Comments are not stripped; the migrations keep DDL out of
+// them.
+func finalTables(t *testing.T, fsys embed.FS, dir string, names []string) []string {
+	t.Helper()
+	tables := map[string]bool{}
+	for _, name := range names {
+		body, err := fsys.ReadFile(path.Join(dir, name))
+		if err != nil {
+			t.Fatalf("read %s/%s: %v", dir, name, err)
+		}
+		for _, m := range tableDDLRegexp.FindAllStringSubmatch(string(body), -1) {
+			switch {
+			case m[1] != "":
+				tables[m[1]] = true
+			case m[2] != "":
+				delete(tables, m[2])
+			default:
+				delete(tables, m[3])
+				tables[m[4]] = true
+			}
+		}
+	}
+	out := make([]string, 0, len(tables))
+	for name := range tables {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
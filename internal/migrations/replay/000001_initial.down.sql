@@ -0,0 +1,11 @@
+BEGIN;
+
+-- Reverts 000001_initial. player_aliases is left alone: it is owned by the
+-- settings set now (see settings/000001_initial), which drops it.
+DROP TABLE IF EXISTS replay_events;
+DROP TABLE IF EXISTS commands_low_value;
+DROP TABLE IF EXISTS commands;
+DROP TABLE IF EXISTS players;
+DROP TABLE IF EXISTS replays;
+
+COMMIT;
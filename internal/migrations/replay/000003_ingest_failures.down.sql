@@ -0,0 +1,6 @@
+BEGIN;
+
+-- Reverts 000003_ingest_failures.
+DROP TABLE IF EXISTS ingest_failures;
+
+COMMIT;
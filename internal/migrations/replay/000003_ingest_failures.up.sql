@@ -0,0 +1,20 @@
+BEGIN;
+
+-- Ledger of replay files that failed to ingest or were skipped, one row per
+-- file path. A row is re-attempted automatically once screpdb_version or
+-- file_checksum no longer match (an upgrade, or the file was replaced), and
+-- removed once the file ingests successfully.
+CREATE TABLE ingest_failures (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	file_path TEXT NOT NULL UNIQUE,
+	file_checksum TEXT NOT NULL DEFAULT '',
+	error_class TEXT NOT NULL CHECK (error_class IN ('parse_error', 'panic', 'ums')),
+	message TEXT NOT NULL,
+	stack_hash TEXT NOT NULL DEFAULT '',
+	screpdb_version TEXT NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 1,
+	first_failed_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	last_failed_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+-- Reverts 000004_game_groups. SQLite refuses to drop an indexed column, so
+-- the indexes go first.
+DROP INDEX IF EXISTS idx_replays_game_group_id;
+DROP INDEX IF EXISTS idx_replays_game_fingerprint;
+
+ALTER TABLE replays DROP COLUMN game_group_id;
+ALTER TABLE replays DROP COLUMN game_fingerprint;
+
+COMMIT;
@@ -0,0 +1,18 @@
+BEGIN;
+
+-- Game identity: replays saved by different players of the same game share a
+-- game_fingerprint (see internal/gameid) and are grouped under one
+-- game_group_id, the id of the group's canonical (longest) replay. Replays
+-- that recorded a game nobody else saved are their own group.
+--
+-- Rows ingested before this migration have no fingerprint; `screpdb
+-- reanalyze` fills it in and regroups them.
+ALTER TABLE replays ADD COLUMN game_fingerprint TEXT NOT NULL DEFAULT '';
+ALTER TABLE replays ADD COLUMN game_group_id INTEGER;
+
+UPDATE replays SET game_group_id = id;
+
+CREATE INDEX IF NOT EXISTS idx_replays_game_fingerprint ON replays(game_fingerprint);
+CREATE INDEX IF NOT EXISTS idx_replays_game_group_id ON replays(game_group_id);
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+-- Reverts 000005_analysis_inputs. Replays lose their persisted detection
+-- input and can only be re-analyzed from their .rep again.
+DROP TABLE IF EXISTS replay_analysis_inputs;
+
+COMMIT;
@@ -0,0 +1,15 @@
+BEGIN;
+
+-- Detection input persisted at ingest for file-independent re-analysis (see
+-- internal/analysisinput): map geometry, selection-state evidence, and the
+-- parts of the analyzed command stream the command tables don't keep. payload
+-- is gzip-compressed JSON in format format_version. Replays ingested before
+-- this migration have no row and can only be re-analyzed from their .rep.
+CREATE TABLE IF NOT EXISTS replay_analysis_inputs (
+	replay_id INTEGER PRIMARY KEY,
+	format_version INTEGER NOT NULL,
+	payload BLOB NOT NULL,
+	FOREIGN KEY (replay_id) REFERENCES replays(id) ON DELETE CASCADE
+);
+
+COMMIT;
@@ -0,0 +1,10 @@
+BEGIN;
+
+-- Reverts 000006_chat_search. The index holds no data of its own: the
+-- messages stay in commands.
+DROP TRIGGER IF EXISTS commands_chat_fts_update;
+DROP TRIGGER IF EXISTS commands_chat_fts_delete;
+DROP TRIGGER IF EXISTS commands_chat_fts_insert;
+DROP TABLE IF EXISTS chat_messages_fts;
+
+COMMIT;
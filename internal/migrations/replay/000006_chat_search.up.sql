@@ -0,0 +1,41 @@
+BEGIN;
+
+-- Full-text index over in-game chat (see internal/chatsearch). An external
+-- content FTS5 table: it stores only the index, keyed by commands.id, and
+-- reads message text back from commands. The triggers keep it in step with
+-- every write to commands, so ingest, mirror refreshes and deletes (including
+-- ON DELETE CASCADE from replays) need no code of their own.
+CREATE VIRTUAL TABLE IF NOT EXISTS chat_messages_fts USING fts5(
+	chat_message,
+	content = 'commands',
+	content_rowid = 'id',
+	tokenize = 'unicode61 remove_diacritics 2'
+);
+
+INSERT INTO chat_messages_fts (rowid, chat_message)
+SELECT id, chat_message FROM commands
+WHERE action_type = 'Chat' AND chat_message IS NOT NULL;
+
+CREATE TRIGGER IF NOT EXISTS commands_chat_fts_insert AFTER INSERT ON commands
+WHEN NEW.action_type = 'Chat' AND NEW.chat_message IS NOT NULL
+BEGIN
+	INSERT INTO chat_messages_fts (rowid, chat_message) VALUES (NEW.id, NEW.chat_message);
+END;
+
+CREATE TRIGGER IF NOT EXISTS commands_chat_fts_delete AFTER DELETE ON commands
+WHEN OLD.action_type = 'Chat' AND OLD.chat_message IS NOT NULL
+BEGIN
+	INSERT INTO chat_messages_fts (chat_messages_fts, rowid, chat_message) VALUES ('delete', OLD.id, OLD.chat_message);
+END;
+
+CREATE TRIGGER IF NOT EXISTS commands_chat_fts_update AFTER UPDATE OF action_type, chat_message ON commands
+BEGIN
+	INSERT INTO chat_messages_fts (chat_messages_fts, rowid, chat_message)
+	SELECT 'delete', OLD.id, OLD.chat_message
+	WHERE OLD.action_type = 'Chat' AND OLD.chat_message IS NOT NULL;
+	INSERT INTO chat_messages_fts (rowid, chat_message)
+	SELECT NEW.id, NEW.chat_message
+	WHERE NEW.action_type = 'Chat' AND NEW.chat_message IS NOT NULL;
+END;
+
+COMMIT;
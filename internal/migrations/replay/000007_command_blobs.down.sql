@@ -0,0 +1,53 @@
+BEGIN;
+
+-- Reverts 000007_command_blobs. Compacted commands are expanded back into
+-- commands_low_value rows first, as `screpdb compact --expand` does, so no
+-- command is lost. A replay's rows are rewritten in stream order: blob row j
+-- at ordinal o has o - j table rows before it, so sorting table row k at
+-- slot k and blob row j at slot o - j, blob rows first, interleaves them.
+CREATE TEMP TABLE command_blob_expansion AS
+SELECT
+	c.replay_id,
+	ROW_NUMBER() OVER (PARTITION BY c.replay_id ORDER BY c.id) - 1 AS slot,
+	1 AS from_table,
+	c.id AS seq,
+	c.player_id, c.frame, c.seconds_from_game_start, c.action_type,
+	c.x, c.y, c.is_queued, c.order_name, c.unit_type, c.unit_types, c.tech_name, c.upgrade_name,
+	c.hotkey_type, c.hotkey_group, c.game_speed, c.vision_player_ids, c.alliance_player_ids,
+	c.is_allied_victory, c.general_data, c.chat_message, c.leave_reason
+FROM commands_low_value c
+WHERE c.replay_id IN (SELECT replay_id FROM command_blobs)
+UNION ALL
+SELECT
+	b.replay_id,
+	r.ordinal - (ROW_NUMBER() OVER (PARTITION BY b.replay_id ORDER BY r.ordinal) - 1),
+	0,
+	r.ordinal,
+	r.player_id, r.frame, r.seconds_from_game_start, r.action_type,
+	r.x, r.y, r.is_queued, r.order_name, r.unit_type, r.unit_types, r.tech_name, r.upgrade_name,
+	r.hotkey_type, r.hotkey_group, r.game_speed, r.vision_player_ids, r.alliance_player_ids,
+	r.is_allied_victory, r.general_data, r.chat_message, r.leave_reason
+FROM command_blobs b, command_blob_rows(b.payload, b.format_version) r;
+
+DELETE FROM commands_low_value WHERE replay_id IN (SELECT replay_id FROM command_blobs);
+
+INSERT INTO commands_low_value (
+	replay_id, player_id, frame, seconds_from_game_start, action_type,
+	x, y, is_queued, order_name, unit_type, unit_types, tech_name, upgrade_name,
+	hotkey_type, hotkey_group, game_speed, vision_player_ids, alliance_player_ids,
+	is_allied_victory, general_data, chat_message, leave_reason
+) SELECT
+	replay_id, player_id, frame, seconds_from_game_start, action_type,
+	x, y, is_queued, order_name, unit_type, unit_types, tech_name, upgrade_name,
+	hotkey_type, hotkey_group, game_speed, vision_player_ids, alliance_player_ids,
+	is_allied_victory, general_data, chat_message, leave_reason
+FROM command_blob_expansion
+ORDER BY replay_id, slot, from_table, seq;
+
+DROP TABLE command_blob_expansion;
+
+DROP VIEW IF EXISTS commands_low_value_all;
+DROP TABLE IF EXISTS command_blob_rows;
+DROP TABLE IF EXISTS command_blobs;
+
+COMMIT;
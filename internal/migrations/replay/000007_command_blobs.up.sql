@@ -0,0 +1,36 @@
+BEGIN;
+
+-- Compact command storage (ingest --compact-commands, screpdb compact). The
+-- bulk low-value commands of a replay (Right Click, Hotkey, Minimap Ping) are
+-- stored as one compressed columnar blob instead of commands_low_value rows;
+-- see internal/cmdblob for the encoding. The rest of commands_low_value, and
+-- all of commands, stay rows.
+CREATE TABLE IF NOT EXISTS command_blobs (
+	replay_id INTEGER PRIMARY KEY REFERENCES replays(id) ON DELETE CASCADE,
+	format_version INTEGER NOT NULL,
+	row_count INTEGER NOT NULL,
+	payload BLOB NOT NULL
+);
+
+-- Decodes a blob as a table-valued function:
+--   SELECT * FROM command_blobs b, command_blob_rows(b.payload, b.format_version)
+CREATE VIRTUAL TABLE IF NOT EXISTS command_blob_rows USING screp_command_blob;
+
+-- Every low-value command, whichever way it is stored. Blob rows have a NULL
+-- id.
+CREATE VIEW IF NOT EXISTS commands_low_value_all AS
+SELECT
+	c.id, c.replay_id, c.player_id, c.frame, c.seconds_from_game_start, c.action_type,
+	c.x, c.y, c.is_queued, c.order_name, c.unit_type, c.unit_types, c.tech_name,
+	c.upgrade_name, c.hotkey_type, c.hotkey_group, c.game_speed, c.vision_player_ids,
+	c.alliance_player_ids, c.is_allied_victory, c.general_data, c.chat_message, c.leave_reason
+FROM commands_low_value c
+UNION ALL
+SELECT
+	NULL, b.replay_id, r.player_id, r.frame, r.seconds_from_game_start, r.action_type,
+	r.x, r.y, r.is_queued, r.order_name, r.unit_type, r.unit_types, r.tech_name,
+	r.upgrade_name, r.hotkey_type, r.hotkey_group, r.game_speed, r.vision_player_ids,
+	r.alliance_player_ids, r.is_allied_victory, r.general_data, r.chat_message, r.leave_reason
+FROM command_blobs b, command_blob_rows(b.payload, b.format_version) r;
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+-- Reverts 000008_player_aggregates. The tables only summarize other rows;
+-- `screpdb aggregates` rebuilds them after migrating back up.
+DROP TABLE IF EXISTS player_aggregates_state;
+DROP TABLE IF EXISTS player_marker_counts;
+DROP TABLE IF EXISTS player_matchup_aggregates;
+DROP TABLE IF EXISTS player_aggregates;
+DROP TABLE IF EXISTS player_game_facts;
+
+COMMIT;
@@ -0,0 +1,132 @@
+BEGIN;
+
+-- Materialized per-player aggregates for the dashboard's players pages (see
+-- internal/playeragg). Ingest and re-analysis keep them up to date in the
+-- same transaction as the rows they summarize; `screpdb aggregates` rebuilds
+-- them from scratch.
+--
+-- player_game_facts holds one row per human, non-observer player of a
+-- replay: the per-game numbers the aggregates add up, the most expensive of
+-- which (production cadence) would otherwise need a scan of the player's
+-- commands. opp_race is the other human's race when the replay has exactly
+-- two, '' otherwise.
+CREATE TABLE IF NOT EXISTS player_game_facts (
+	player_id INTEGER PRIMARY KEY REFERENCES players(id) ON DELETE CASCADE,
+	replay_id INTEGER NOT NULL REFERENCES replays(id) ON DELETE CASCADE,
+	player_key TEXT NOT NULL,
+	player_name TEXT NOT NULL,
+	race TEXT NOT NULL,
+	opp_race TEXT NOT NULL DEFAULT '',
+	team_format TEXT NOT NULL,
+	map_kind TEXT NOT NULL,
+	game_type TEXT NOT NULL,
+	is_one_on_one INTEGER NOT NULL,
+	is_short INTEGER NOT NULL,
+	has_computers INTEGER NOT NULL,
+	replay_date TEXT NOT NULL,
+	is_winner INTEGER NOT NULL,
+	apm INTEGER NOT NULL,
+	eapm INTEGER NOT NULL,
+	viewport_switch_rate REAL,
+	cadence_strict_rate REAL,
+	cadence_strict_cv REAL,
+	cadence_strict_burstiness REAL,
+	cadence_strict_idle REAL,
+	cadence_strict_score REAL,
+	cadence_broad_rate REAL,
+	cadence_broad_cv REAL,
+	cadence_broad_burstiness REAL,
+	cadence_broad_idle REAL,
+	cadence_broad_score REAL
+);
+
+CREATE INDEX IF NOT EXISTS idx_player_game_facts_replay ON player_game_facts(replay_id);
+CREATE INDEX IF NOT EXISTS idx_player_game_facts_key ON player_game_facts(player_key);
+
+-- The aggregate tables below are keyed by player and by replay class: the
+-- replay properties the dashboard's global replay filter selects on
+-- (map_kind, lowercased game_type, is_one_on_one, is_canonical, is_short,
+-- has_computers). Any filter setting is a predicate over the class columns,
+-- so a page sums the rows its filter admits.
+CREATE TABLE IF NOT EXISTS player_aggregates (
+	player_key TEXT NOT NULL,
+	map_kind TEXT NOT NULL,
+	game_type TEXT NOT NULL,
+	is_one_on_one INTEGER NOT NULL,
+	is_canonical INTEGER NOT NULL,
+	is_short INTEGER NOT NULL,
+	has_computers INTEGER NOT NULL,
+	player_name TEXT NOT NULL,
+	games INTEGER NOT NULL,
+	wins INTEGER NOT NULL,
+	protoss_games INTEGER NOT NULL,
+	terran_games INTEGER NOT NULL,
+	zerg_games INTEGER NOT NULL,
+	apm_sum INTEGER NOT NULL,
+	apm_games INTEGER NOT NULL,
+	eapm_sum INTEGER NOT NULL,
+	eapm_games INTEGER NOT NULL,
+	last_played TEXT NOT NULL,
+	viewport_games INTEGER NOT NULL,
+	viewport_rate_sum REAL NOT NULL,
+	cadence_strict_games INTEGER NOT NULL,
+	cadence_strict_rate_sum REAL NOT NULL,
+	cadence_strict_cv_sum REAL NOT NULL,
+	cadence_strict_burstiness_sum REAL NOT NULL,
+	cadence_strict_idle_sum REAL NOT NULL,
+	cadence_strict_score_sum REAL NOT NULL,
+	cadence_broad_games INTEGER NOT NULL,
+	cadence_broad_rate_sum REAL NOT NULL,
+	cadence_broad_cv_sum REAL NOT NULL,
+	cadence_broad_burstiness_sum REAL NOT NULL,
+	cadence_broad_idle_sum REAL NOT NULL,
+	cadence_broad_score_sum REAL NOT NULL,
+	PRIMARY KEY (player_key, map_kind, game_type, is_one_on_one, is_canonical, is_short, has_computers)
+);
+
+CREATE TABLE IF NOT EXISTS player_matchup_aggregates (
+	player_key TEXT NOT NULL,
+	race TEXT NOT NULL,
+	opp_race TEXT NOT NULL,
+	team_format TEXT NOT NULL,
+	map_kind TEXT NOT NULL,
+	game_type TEXT NOT NULL,
+	is_one_on_one INTEGER NOT NULL,
+	is_canonical INTEGER NOT NULL,
+	is_short INTEGER NOT NULL,
+	has_computers INTEGER NOT NULL,
+	games INTEGER NOT NULL,
+	wins INTEGER NOT NULL,
+	apm_sum INTEGER NOT NULL,
+	apm_games INTEGER NOT NULL,
+	eapm_sum INTEGER NOT NULL,
+	eapm_games INTEGER NOT NULL,
+	PRIMARY KEY (player_key, race, opp_race, team_format, map_kind, game_type, is_one_on_one, is_canonical, is_short, has_computers)
+);
+
+-- Replays in which the player got each marker (openers and other build
+-- orders included), per matchup and class.
+CREATE TABLE IF NOT EXISTS player_marker_counts (
+	player_key TEXT NOT NULL,
+	race TEXT NOT NULL,
+	opp_race TEXT NOT NULL,
+	team_format TEXT NOT NULL,
+	map_kind TEXT NOT NULL,
+	game_type TEXT NOT NULL,
+	is_one_on_one INTEGER NOT NULL,
+	is_canonical INTEGER NOT NULL,
+	is_short INTEGER NOT NULL,
+	has_computers INTEGER NOT NULL,
+	event_type TEXT NOT NULL,
+	replays INTEGER NOT NULL,
+	PRIMARY KEY (player_key, race, opp_race, team_format, map_kind, game_type, is_one_on_one, is_canonical, is_short, has_computers, event_type)
+);
+
+-- The playeragg.Version the tables were built with. No row means they have
+-- never been built and the dashboard computes everything live.
+CREATE TABLE IF NOT EXISTS player_aggregates_state (
+	id INTEGER PRIMARY KEY CHECK (id = 1),
+	version INTEGER NOT NULL
+);
+
+COMMIT;
@@ -0,0 +1,15 @@
+BEGIN;
+
+-- Reverts 000009_analyst_views. The marker tables are rewritten from the
+-- marker registry after migrating back up.
+DROP VIEW IF EXISTS analyst_opener_timings_v1;
+DROP VIEW IF EXISTS analyst_drops_v1;
+DROP VIEW IF EXISTS analyst_attack_casts_v1;
+DROP VIEW IF EXISTS analyst_attack_units_v1;
+DROP VIEW IF EXISTS analyst_attacks_v1;
+DROP VIEW IF EXISTS analyst_build_order_steps_v1;
+DROP VIEW IF EXISTS analyst_player_games_v1;
+DROP TABLE IF EXISTS marker_expert_milestones;
+DROP TABLE IF EXISTS marker_definitions;
+
+COMMIT;
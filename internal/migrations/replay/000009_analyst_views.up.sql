@@ -0,0 +1,219 @@
+BEGIN;
+
+-- Analyst views: a documented surface for hand-written SQL (the MCP server,
+-- scripts, notebooks) that hides how the ingester and analyzers lay out
+-- commands and replay_events, and unpacks their JSON columns.
+--
+-- A view's name ends in its version. Once shipped, a _v1 view keeps its
+-- name, columns and meaning: when internal tables change, the view is
+-- redefined on top of them, and a change that would break a query ships as a
+-- _v2 next to it instead. Every view is mirrored in postgres/ under the same
+-- name and columns; booleans are 0/1 here and true/false there.
+--
+-- marker_definitions and marker_expert_milestones describe the markers the
+-- running screpdb detects (internal/patterns/markers). They aren't data: every
+-- screpdb rewrites them from its marker registry after migrating, so they
+-- always match the analyzer that wrote replay_events. The milestones are the
+-- progamer template of each build-order opener, position-aligned with the
+-- expert_actuals its markers store in payload.
+CREATE TABLE IF NOT EXISTS marker_definitions (
+	event_type TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	kind TEXT NOT NULL CHECK (kind IN ('initial_build_order', 'marker')),
+	race TEXT NOT NULL,
+	matchups TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS marker_expert_milestones (
+	event_type TEXT NOT NULL,
+	milestone_index INTEGER NOT NULL,
+	milestone TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	target_second INTEGER NOT NULL,
+	tolerance_early_seconds INTEGER NOT NULL,
+	tolerance_late_seconds INTEGER NOT NULL,
+	PRIMARY KEY (event_type, milestone_index)
+);
+
+-- One row per player of a replay, observers excluded: the game, the player's
+-- result and build-order opener. opener is the player's bo_* marker
+-- (event_type), opener_name its display name, resolved for dynamic openers,
+-- and opener_modifiers its comma-separated modifier tags (e.g. 'proxy').
+-- Count each game once with WHERE is_canonical = 1.
+CREATE VIEW IF NOT EXISTS analyst_player_games_v1 AS
+SELECT
+	r.id AS replay_id,
+	COALESCE(r.game_group_id, r.id) AS game_group_id,
+	COALESCE(r.game_group_id, r.id) = r.id AS is_canonical,
+	r.replay_date,
+	r.map_name,
+	r.map_kind,
+	r.game_type,
+	r.team_format,
+	r.matchup,
+	r.duration_seconds,
+	p.id AS player_id,
+	p.name AS player_name,
+	p.race,
+	p.type AS player_type,
+	p.team,
+	p.is_winner,
+	p.apm,
+	p.eapm,
+	p.start_location_oclock,
+	o.event_type AS opener,
+	COALESCE(json_extract(o.payload, '$.label'), d.name) AS opener_name,
+	(SELECT group_concat(m.value, ', ') FROM json_each(o.payload, '$.modifiers') m) AS opener_modifiers
+FROM players p
+JOIN replays r ON r.id = p.replay_id
+LEFT JOIN replay_events o ON o.id = (
+	SELECT MIN(e.id) FROM replay_events e
+	WHERE e.replay_id = p.replay_id
+		AND e.source_player_id = p.id
+		AND e.event_kind = 'marker'
+		AND e.event_type LIKE 'bo\_%' ESCAPE '\'
+)
+LEFT JOIN marker_definitions d ON d.event_type = o.event_type
+WHERE p.is_observer = 0;
+
+-- One row per production or research command, numbered per player in game
+-- order: what each player built, trained, morphed, researched and upgraded.
+-- item is the unit, building, tech or upgrade name.
+CREATE VIEW IF NOT EXISTS analyst_build_order_steps_v1 AS
+SELECT
+	c.replay_id,
+	c.player_id,
+	p.name AS player_name,
+	p.race,
+	ROW_NUMBER() OVER (PARTITION BY c.replay_id, c.player_id ORDER BY c.frame, c.id) AS step,
+	c.frame,
+	c.seconds_from_game_start AS second,
+	c.action_type,
+	COALESCE(c.unit_type, c.tech_name, c.upgrade_name) AS item
+FROM commands c
+JOIN players p ON p.id = c.player_id
+WHERE c.action_type IN ('Build', 'Train', 'Unit Morph', 'Building Morph', 'Tech', 'Upgrade')
+	AND COALESCE(c.unit_type, c.tech_name, c.upgrade_name) IS NOT NULL;
+
+-- One row per attack game event: who attacked whom, where, with which unit
+-- types (comma-separated, sorted) and how many aggressive spells were cast.
+CREATE VIEW IF NOT EXISTS analyst_attacks_v1 AS
+SELECT
+	e.id AS attack_id,
+	e.replay_id,
+	e.seconds_from_game_start AS second,
+	e.source_player_id AS attacker_player_id,
+	a.name AS attacker_name,
+	a.race AS attacker_race,
+	e.target_player_id AS defender_player_id,
+	d.name AS defender_name,
+	d.race AS defender_race,
+	e.location_base_type,
+	e.location_base_oclock,
+	e.location_natural_of_oclock,
+	e.location_mineral_only,
+	(SELECT group_concat(u.value, ', ') FROM json_each(e.attack_unit_types) u) AS unit_types,
+	(SELECT COALESCE(SUM(c.value), 0) FROM json_each(e.attack_cast_counts) c) AS cast_count
+FROM replay_events e
+LEFT JOIN players a ON a.id = e.source_player_id
+LEFT JOIN players d ON d.id = e.target_player_id
+WHERE e.event_kind = 'game_event' AND e.event_type = 'attack';
+
+-- One row per unit type seen in an attack (see analyst_attacks_v1).
+CREATE VIEW IF NOT EXISTS analyst_attack_units_v1 AS
+SELECT
+	e.id AS attack_id,
+	e.replay_id,
+	e.seconds_from_game_start AS second,
+	e.source_player_id AS attacker_player_id,
+	u.value AS unit_type
+FROM replay_events e, json_each(e.attack_unit_types) u
+WHERE e.event_kind = 'game_event' AND e.event_type = 'attack';
+
+-- One row per spell cast during an attack, with how many times it was cast.
+CREATE VIEW IF NOT EXISTS analyst_attack_casts_v1 AS
+SELECT
+	e.id AS attack_id,
+	e.replay_id,
+	e.seconds_from_game_start AS second,
+	e.source_player_id AS attacker_player_id,
+	c.key AS cast_name,
+	c.value AS cast_count
+FROM replay_events e, json_each(e.attack_cast_counts) c
+WHERE e.event_kind = 'game_event' AND e.event_type = 'attack';
+
+-- One row per drop or cliff drop onto an enemy base. Repeated unloads onto
+-- the same base count as one drop of unload_count unloads, the last at
+-- last_unload_second. source_base_* is where the transports loaded, when
+-- known; target_inferred_from says how the dropped-on player was found:
+-- 'attack' (an attack at the drop) or 'activity' (commands after it).
+CREATE VIEW IF NOT EXISTS analyst_drops_v1 AS
+SELECT
+	e.id AS drop_id,
+	e.replay_id,
+	e.seconds_from_game_start AS second,
+	COALESCE(json_extract(e.payload, '$.le'), e.seconds_from_game_start) AS last_unload_second,
+	COALESCE(json_extract(e.payload, '$.n'), 1) AS unload_count,
+	e.event_type = 'cliff_drop' AS is_cliff_drop,
+	e.source_player_id AS player_id,
+	a.name AS player_name,
+	a.race AS player_race,
+	e.target_player_id AS target_player_id,
+	d.name AS target_player_name,
+	d.race AS target_player_race,
+	e.location_base_type,
+	e.location_base_oclock,
+	e.location_natural_of_oclock,
+	NULLIF(json_extract(e.payload, '$.sb.k'), '') AS source_base_type,
+	CASE WHEN json_extract(e.payload, '$.sb') IS NOT NULL THEN COALESCE(json_extract(e.payload, '$.sb.o'), 0) END AS source_base_oclock,
+	CASE json_extract(e.payload, '$.tv') WHEN 'a' THEN 'attack' WHEN 'p' THEN 'activity' END AS target_inferred_from,
+	(SELECT group_concat(u.value, ', ') FROM json_each(e.attack_unit_types) u) AS unit_types
+FROM replay_events e
+LEFT JOIN players a ON a.id = e.source_player_id
+LEFT JOIN players d ON d.id = e.target_player_id
+WHERE e.event_kind = 'game_event' AND e.event_type IN ('drop', 'cliff_drop');
+
+-- One row per milestone of each detected build-order opener: the progamer
+-- target second and tolerance (marker_expert_milestones) against when the
+-- player actually got there. actual_second, delta_seconds (negative is
+-- early) and within_tolerance are NULL when the player never did.
+CREATE VIEW IF NOT EXISTS analyst_opener_timings_v1 AS
+SELECT
+	t.replay_id,
+	t.player_id,
+	t.player_name,
+	t.race,
+	t.opener,
+	t.milestone_index,
+	t.milestone,
+	t.subject,
+	t.target_second,
+	t.tolerance_early_seconds,
+	t.tolerance_late_seconds,
+	t.actual_second IS NOT NULL AS found,
+	t.actual_second,
+	t.actual_second - t.target_second AS delta_seconds,
+	t.actual_second - t.target_second BETWEEN -t.tolerance_early_seconds AND t.tolerance_late_seconds AS within_tolerance
+FROM (
+	SELECT
+		o.replay_id,
+		o.source_player_id AS player_id,
+		p.name AS player_name,
+		p.race,
+		o.event_type AS opener,
+		m.milestone_index,
+		m.milestone,
+		m.subject,
+		m.target_second,
+		m.tolerance_early_seconds,
+		m.tolerance_late_seconds,
+		CASE WHEN json_extract(o.payload, '$.expert_actuals[' || m.milestone_index || '].found')
+			THEN COALESCE(json_extract(o.payload, '$.expert_actuals[' || m.milestone_index || '].second'), 0)
+		END AS actual_second
+	FROM replay_events o
+	JOIN marker_expert_milestones m ON m.event_type = o.event_type
+	LEFT JOIN players p ON p.id = o.source_player_id
+	WHERE o.event_kind = 'marker'
+) t;
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+-- Reverts 000010_file_stamps.
+ALTER TABLE replays DROP COLUMN file_mod_time;
+ALTER TABLE replays DROP COLUMN file_size;
+
+COMMIT;
@@ -0,0 +1,13 @@
+BEGIN;
+
+-- File stamps: the size and modification time (Unix nanoseconds) a replay's
+-- file had when it was ingested. Ingest skips a known path without reading it
+-- while its stamp is unchanged, and only hashes files that are new or whose
+-- stamp moved.
+--
+-- Rows ingested before this migration have no stamp; their paths are skipped
+-- on path alone, as before.
+ALTER TABLE replays ADD COLUMN file_size INTEGER;
+ALTER TABLE replays ADD COLUMN file_mod_time INTEGER;
+
+COMMIT;
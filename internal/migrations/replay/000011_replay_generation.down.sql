@@ -0,0 +1,9 @@
+BEGIN;
+
+-- Reverts 000011_replay_generation.
+DROP TRIGGER IF EXISTS replays_generation_delete;
+DROP TRIGGER IF EXISTS replays_generation_update;
+DROP TRIGGER IF EXISTS replays_generation_insert;
+DROP TABLE IF EXISTS replay_generation;
+
+COMMIT;
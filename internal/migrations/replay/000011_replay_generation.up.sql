@@ -0,0 +1,33 @@
+BEGIN;
+
+-- Replay generation: a counter that moves whenever the replay corpus does, so
+-- the dashboard can tell its cached results are stale (see the dashboard's
+-- resultCache). The triggers count every write to replays; ingest, re-analysis
+-- (which restamps analyzer_algorithm_version), mirror refreshes and deletes
+-- need no code of their own. epoch is random per database, so a counter value
+-- from one database never matches another's, even after --clean.
+CREATE TABLE IF NOT EXISTS replay_generation (
+	id INTEGER PRIMARY KEY CHECK (id = 1),
+	epoch TEXT NOT NULL,
+	generation INTEGER NOT NULL DEFAULT 0
+);
+
+INSERT OR IGNORE INTO replay_generation (id, epoch, generation)
+VALUES (1, lower(hex(randomblob(8))), 0);
+
+CREATE TRIGGER IF NOT EXISTS replays_generation_insert AFTER INSERT ON replays
+BEGIN
+	UPDATE replay_generation SET generation = generation + 1 WHERE id = 1;
+END;
+
+CREATE TRIGGER IF NOT EXISTS replays_generation_update AFTER UPDATE ON replays
+BEGIN
+	UPDATE replay_generation SET generation = generation + 1 WHERE id = 1;
+END;
+
+CREATE TRIGGER IF NOT EXISTS replays_generation_delete AFTER DELETE ON replays
+BEGIN
+	UPDATE replay_generation SET generation = generation + 1 WHERE id = 1;
+END;
+
+COMMIT;
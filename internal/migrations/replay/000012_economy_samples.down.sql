@@ -0,0 +1,11 @@
+BEGIN;
+
+-- Reverts 000012_economy_samples. The estimated economy timelines are
+-- dropped; the game page loses its economy chart.
+DROP TABLE IF EXISTS player_economy_samples;
+
+-- Replays analyzed with the economy samples (algorithm version 65 and later)
+-- go back to the previous version so reanalyze picks them up again.
+UPDATE replays SET analyzer_algorithm_version = 64 WHERE analyzer_algorithm_version >= 65;
+
+COMMIT;
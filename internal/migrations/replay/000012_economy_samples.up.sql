@@ -0,0 +1,25 @@
+BEGIN;
+
+-- Estimated economy timeline (see earlyfilter.SimulateEconomy): one row per
+-- player every 10 seconds of game time with the simulated bank, income per
+-- minute, supply and worker and base counts. It is a model of the command
+-- stream, not a readout of the game. Replays ingested before this migration
+-- have no rows until they are re-analyzed.
+CREATE TABLE IF NOT EXISTS player_economy_samples (
+	replay_id INTEGER NOT NULL,
+	player_id INTEGER NOT NULL,
+	second INTEGER NOT NULL,
+	minerals INTEGER NOT NULL,
+	gas INTEGER NOT NULL,
+	mineral_income INTEGER NOT NULL,
+	gas_income INTEGER NOT NULL,
+	supply_used INTEGER NOT NULL,
+	supply_max INTEGER NOT NULL,
+	workers INTEGER NOT NULL,
+	bases INTEGER NOT NULL,
+	PRIMARY KEY (replay_id, player_id, second),
+	FOREIGN KEY (replay_id) REFERENCES replays(id) ON DELETE CASCADE,
+	FOREIGN KEY (player_id) REFERENCES players(id) ON DELETE CASCADE
+);
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+-- Reverts 000013_production_busy. The estimated production building
+-- timelines are dropped; the game page loses its production busy chart.
+DROP TABLE IF EXISTS production_busy_spans;
+
+-- Replays analyzed with the production timelines (algorithm version 62 and
+-- later) go back to the previous version so reanalyze picks them up again.
+UPDATE replays SET analyzer_algorithm_version = 61 WHERE analyzer_algorithm_version >= 62;
+
+COMMIT;
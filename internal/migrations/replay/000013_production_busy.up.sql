@@ -0,0 +1,25 @@
+BEGIN;
+
+-- Estimated production building busy time (see
+-- unittags.ProducerTimelines): one row per stretch a player's Gateway,
+-- Barracks or Factory spent training, inferred from selection tags and the
+-- Train commands' build times. building_index numbers a player's buildings
+-- of one type in the order they became ready; ready_second and
+-- window_end_second bound the time it could have trained and repeat on each
+-- of its rows. Replays ingested before this migration have no rows until
+-- they are re-analyzed.
+CREATE TABLE IF NOT EXISTS production_busy_spans (
+	replay_id INTEGER NOT NULL,
+	player_id INTEGER NOT NULL,
+	building TEXT NOT NULL,
+	building_index INTEGER NOT NULL,
+	ready_second INTEGER NOT NULL,
+	window_end_second INTEGER NOT NULL,
+	start_second INTEGER NOT NULL,
+	end_second INTEGER NOT NULL,
+	PRIMARY KEY (replay_id, player_id, building, building_index, start_second),
+	FOREIGN KEY (replay_id) REFERENCES replays(id) ON DELETE CASCADE,
+	FOREIGN KEY (player_id) REFERENCES players(id) ON DELETE CASCADE
+);
+
+COMMIT;
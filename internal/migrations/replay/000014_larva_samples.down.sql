@@ -0,0 +1,11 @@
+BEGIN;
+
+-- Reverts 000014_larva_samples. The estimated Zerg larva timelines are
+-- dropped; the game page loses its larva chart.
+DROP TABLE IF EXISTS larva_samples;
+
+-- Replays analyzed with the larva samples (algorithm version 63 and later)
+-- go back to the previous version so reanalyze picks them up again.
+UPDATE replays SET analyzer_algorithm_version = 62 WHERE analyzer_algorithm_version >= 63;
+
+COMMIT;
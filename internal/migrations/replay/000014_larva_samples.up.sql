@@ -0,0 +1,26 @@
+BEGIN;
+
+-- Estimated Zerg larva timelines (see unittags.HatcheryLarvae): one row per
+-- second in which a hatchery's larvae spawned, were used by a morph, or a
+-- spawn was missed because it already held three. hatchery_index numbers a
+-- player's hatcheries in the order they became ready, and a hatchery's first
+-- row is its ready second; larvae is the count at the end of the second.
+-- window_end_second is when the player's timelines stop and repeats on each
+-- of their rows. Replays ingested before this migration have no rows until
+-- they are re-analyzed.
+CREATE TABLE IF NOT EXISTS larva_samples (
+	replay_id INTEGER NOT NULL,
+	player_id INTEGER NOT NULL,
+	hatchery_index INTEGER NOT NULL,
+	window_end_second INTEGER NOT NULL,
+	second INTEGER NOT NULL,
+	larvae INTEGER NOT NULL,
+	spawned INTEGER NOT NULL,
+	used INTEGER NOT NULL,
+	missed INTEGER NOT NULL,
+	PRIMARY KEY (replay_id, player_id, hatchery_index, second),
+	FOREIGN KEY (replay_id) REFERENCES replays(id) ON DELETE CASCADE,
+	FOREIGN KEY (player_id) REFERENCES players(id) ON DELETE CASCADE
+);
+
+COMMIT;
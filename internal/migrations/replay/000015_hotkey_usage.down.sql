@@ -0,0 +1,11 @@
+BEGIN;
+
+-- Reverts 000015_hotkey_usage. The per-second hotkey group use is dropped;
+-- the game page loses its Hotkeys tab and the player page its hotkey layout.
+DROP TABLE IF EXISTS hotkey_usage;
+
+-- Replays analyzed with the hotkey usage (algorithm version 64 and later) go
+-- back to the previous version so reanalyze picks them up again.
+UPDATE replays SET analyzer_algorithm_version = 63 WHERE analyzer_algorithm_version >= 64;
+
+COMMIT;
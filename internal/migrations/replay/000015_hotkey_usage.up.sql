@@ -0,0 +1,27 @@
+BEGIN;
+
+-- Per-second hotkey group use (see parser.BuildHotkeyUsage): one row per
+-- player, group and second with any hotkey command. camera_jumps counts the
+-- recalls that were the second tap of a double tap and is included in
+-- selects. role is what the group held at the end of the second ('building',
+-- 'army' or '' when unknown, see unittags.HotkeyBinding). window_end_second
+-- is the player's last command second and repeats on each of their rows.
+-- Replays ingested before this migration have no rows until they are
+-- re-analyzed.
+CREATE TABLE IF NOT EXISTS hotkey_usage (
+	replay_id INTEGER NOT NULL,
+	player_id INTEGER NOT NULL,
+	hotkey_group INTEGER NOT NULL,
+	window_end_second INTEGER NOT NULL,
+	second INTEGER NOT NULL,
+	role TEXT NOT NULL,
+	assigns INTEGER NOT NULL,
+	selects INTEGER NOT NULL,
+	adds INTEGER NOT NULL,
+	camera_jumps INTEGER NOT NULL,
+	PRIMARY KEY (replay_id, player_id, hotkey_group, second),
+	FOREIGN KEY (replay_id) REFERENCES replays(id) ON DELETE CASCADE,
+	FOREIGN KEY (player_id) REFERENCES players(id) ON DELETE CASCADE
+);
+
+COMMIT;
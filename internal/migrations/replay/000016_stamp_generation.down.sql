@@ -0,0 +1,11 @@
+BEGIN;
+
+-- Reverts 000016_stamp_generation: every update to replays counts again.
+DROP TRIGGER IF EXISTS replays_generation_update;
+
+CREATE TRIGGER IF NOT EXISTS replays_generation_update AFTER UPDATE ON replays
+BEGIN
+	UPDATE replay_generation SET generation = generation + 1 WHERE id = 1;
+END;
+
+COMMIT;
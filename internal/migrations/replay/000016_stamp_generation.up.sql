@@ -0,0 +1,17 @@
+BEGIN;
+
+-- File stamp refreshes leave the replay generation alone: ingest rewrites
+-- file_size and file_mod_time of replays whose file was only touched or
+-- copied, which changes nothing the dashboard shows, so it mustn't empty its
+-- result cache. The update trigger still fires on every update except those
+-- that change a stamp; RefreshReplayFileStamps is the only writer of the
+-- stamps and sets nothing else.
+DROP TRIGGER IF EXISTS replays_generation_update;
+
+CREATE TRIGGER IF NOT EXISTS replays_generation_update AFTER UPDATE ON replays
+WHEN OLD.file_size IS NEW.file_size AND OLD.file_mod_time IS NEW.file_mod_time
+BEGIN
+	UPDATE replay_generation SET generation = generation + 1 WHERE id = 1;
+END;
+
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN;
+
+-- Reverts 000001_initial, taking the aliases and the global replay filter
+-- with it. Unlike --clean and --clean-dashboard, which keep these tables,
+-- this is only reached by explicitly migrating the settings set down.
+DROP TABLE IF EXISTS settings;
+DROP TABLE IF EXISTS player_aliases;
+
+COMMIT;
@@ -5,7 +5,7 @@ import "sort"
 // Build times (seconds) at SC:BW "Fastest" game speed — the speed every
 // competitive replay is played on. These are the canonical in-game values
 // used by timing-based logic (e.g. build-order detection and expert timings)
-// AND by the early-game economy table (internal/cmdenrich/costs.go), which
+// AND by the economy tables (internal/cmdenrich/costs.go), which
 // references these consts so the two can never drift apart.
 //
 // Values are float64 because some are fractional (e.g. Zealot 25.2s). Round
@@ -24,34 +24,89 @@ const (
 	BuildTimeOverlord         float64 = 25
 	BuildTimeDrone            float64 = 12.6
 	// BuildTimeZergling is per Zergling-pair (one Egg morphs into two lings).
-	BuildTimeZergling float64 = 18
-	BuildTimeMutalisk float64 = 25
+	BuildTimeZergling        float64 = 18
+	BuildTimeMutalisk        float64 = 25
+	BuildTimeLair            float64 = 63
+	BuildTimeHive            float64 = 75.6
+	BuildTimeHydraliskDen    float64 = 25.2
+	BuildTimeGreaterSpire    float64 = 75.6
+	BuildTimeQueensNest      float64 = 37.8
+	BuildTimeUltraliskCavern float64 = 50.4
+	BuildTimeDefilerMound    float64 = 37.8
+	BuildTimeNydusCanal      float64 = 25.2
+	BuildTimeSporeColony     float64 = 12.6
+	BuildTimeHydralisk       float64 = 17.6
+	BuildTimeLurker          float64 = 25.2
+	BuildTimeUltralisk       float64 = 37.8
+	BuildTimeGuardian        float64 = 25.2
+	BuildTimeDevourer        float64 = 25.2
+	BuildTimeQueen           float64 = 31.5
+	BuildTimeDefiler         float64 = 31.5
+	BuildTimeScourge         float64 = 18.9 // per pair, like Zerglings
+	BuildTimeInfestedTerran  float64 = 25.2
 
 	// Protoss
-	BuildTimeNexus           float64 = 75
-	BuildTimePylon           float64 = 19
-	BuildTimeGateway         float64 = 38
-	BuildTimeAssimilator     float64 = 25
-	BuildTimeForge           float64 = 25
-	BuildTimePhotonCannon    float64 = 31.5
-	BuildTimeCyberneticsCore float64 = 38
-	BuildTimeProbe           float64 = 12.6
-	BuildTimeZealot          float64 = 25.2
+	BuildTimeNexus              float64 = 75
+	BuildTimePylon              float64 = 19
+	BuildTimeGateway            float64 = 38
+	BuildTimeAssimilator        float64 = 25
+	BuildTimeForge              float64 = 25
+	BuildTimePhotonCannon       float64 = 31.5
+	BuildTimeCyberneticsCore    float64 = 38
+	BuildTimeProbe              float64 = 12.6
+	BuildTimeZealot             float64 = 25.2
+	BuildTimeRoboticsFacility   float64 = 50.4
+	BuildTimeStargate           float64 = 44.1
+	BuildTimeCitadelOfAdun      float64 = 37.8
+	BuildTimeTemplarArchives    float64 = 37.8
+	BuildTimeObservatory        float64 = 18.9
+	BuildTimeRoboticsSupportBay float64 = 18.9
+	BuildTimeFleetBeacon        float64 = 37.8
+	BuildTimeArbiterTribunal    float64 = 37.8
+	BuildTimeShieldBattery      float64 = 18.9
+	BuildTimeDragoon            float64 = 31.5
+	BuildTimeHighTemplar        float64 = 31.5
+	BuildTimeDarkTemplar        float64 = 31.5
+	BuildTimeShuttle            float64 = 37.8
+	BuildTimeReaver             float64 = 44.1
+	BuildTimeObserver           float64 = 25.2
+	BuildTimeScout              float64 = 50.4
+	BuildTimeCorsair            float64 = 25.2
+	BuildTimeCarrier            float64 = 88.2
+	BuildTimeArbiter            float64 = 100.8
 
 	// Terran
-	BuildTimeCommandCenter  float64 = 75
-	BuildTimeSupplyDepot    float64 = 25
-	BuildTimeBarracks       float64 = 50
-	BuildTimeRefinery       float64 = 25
-	BuildTimeEngineeringBay float64 = 38
-	BuildTimeFactory        float64 = 50
-	BuildTimeStarport       float64 = 44
-	BuildTimeMachineShop    float64 = 25
-	BuildTimeAcademy        float64 = 50
-	BuildTimeBunker         float64 = 19
-	BuildTimeMissileTurret  float64 = 18.9
-	BuildTimeSCV            float64 = 12.6
-	BuildTimeMarine         float64 = 15
+	BuildTimeCommandCenter   float64 = 75
+	BuildTimeSupplyDepot     float64 = 25
+	BuildTimeBarracks        float64 = 50
+	BuildTimeRefinery        float64 = 25
+	BuildTimeEngineeringBay  float64 = 38
+	BuildTimeFactory         float64 = 50
+	BuildTimeStarport        float64 = 44
+	BuildTimeMachineShop     float64 = 25
+	BuildTimeAcademy         float64 = 50
+	BuildTimeBunker          float64 = 19
+	BuildTimeMissileTurret   float64 = 18.9
+	BuildTimeSCV             float64 = 12.6
+	BuildTimeMarine          float64 = 15
+	BuildTimeComSat          float64 = 25.2
+	BuildTimeNuclearSilo     float64 = 25.2
+	BuildTimeControlTower    float64 = 25.2
+	BuildTimeScienceFacility float64 = 37.8
+	BuildTimeCovertOps       float64 = 25.2
+	BuildTimePhysicsLab      float64 = 25.2
+	BuildTimeArmory          float64 = 50.4
+	BuildTimeFirebat         float64 = 15
+	BuildTimeMedic           float64 = 18.9
+	BuildTimeGhost           float64 = 31.5
+	BuildTimeVulture         float64 = 18.9
+	BuildTimeSiegeTank       float64 = 31.5
+	BuildTimeGoliath         float64 = 25.2
+	BuildTimeWraith          float64 = 37.8
+	BuildTimeDropship        float64 = 31.5
+	BuildTimeScienceVessel   float64 = 50.4
+	BuildTimeValkyrie        float64 = 31.5
+	BuildTimeBattlecruiser   float64 = 84
 )
 
 // buildTimes is the canonical name → build-time index over the BuildTime*
@@ -70,32 +125,87 @@ var buildTimes = map[string]float64{
 	GeneralUnitDrone:            BuildTimeDrone,
 	GeneralUnitZergling:         BuildTimeZergling,
 	GeneralUnitMutalisk:         BuildTimeMutalisk,
+	GeneralUnitLair:             BuildTimeLair,
+	GeneralUnitHive:             BuildTimeHive,
+	GeneralUnitHydraliskDen:     BuildTimeHydraliskDen,
+	GeneralUnitGreaterSpire:     BuildTimeGreaterSpire,
+	GeneralUnitQueensNest:       BuildTimeQueensNest,
+	GeneralUnitUltraliskCavern:  BuildTimeUltraliskCavern,
+	GeneralUnitDefilerMound:     BuildTimeDefilerMound,
+	GeneralUnitNydusCanal:       BuildTimeNydusCanal,
+	GeneralUnitSporeColony:      BuildTimeSporeColony,
+	GeneralUnitHydralisk:        BuildTimeHydralisk,
+	GeneralUnitLurker:           BuildTimeLurker,
+	GeneralUnitUltralisk:        BuildTimeUltralisk,
+	GeneralUnitGuardian:         BuildTimeGuardian,
+	GeneralUnitDevourer:         BuildTimeDevourer,
+	GeneralUnitQueen:            BuildTimeQueen,
+	GeneralUnitDefiler:          BuildTimeDefiler,
+	GeneralUnitScourge:          BuildTimeScourge,
+	GeneralUnitInfestedTerran:   BuildTimeInfestedTerran,
 
 	// Protoss
-	GeneralUnitNexus:           BuildTimeNexus,
-	GeneralUnitPylon:           BuildTimePylon,
-	GeneralUnitGateway:         BuildTimeGateway,
-	GeneralUnitAssimilator:     BuildTimeAssimilator,
-	GeneralUnitForge:           BuildTimeForge,
-	GeneralUnitPhotonCannon:    BuildTimePhotonCannon,
-	GeneralUnitCyberneticsCore: BuildTimeCyberneticsCore,
-	GeneralUnitProbe:           BuildTimeProbe,
-	GeneralUnitZealot:          BuildTimeZealot,
+	GeneralUnitNexus:              BuildTimeNexus,
+	GeneralUnitPylon:              BuildTimePylon,
+	GeneralUnitGateway:            BuildTimeGateway,
+	GeneralUnitAssimilator:        BuildTimeAssimilator,
+	GeneralUnitForge:              BuildTimeForge,
+	GeneralUnitPhotonCannon:       BuildTimePhotonCannon,
+	GeneralUnitCyberneticsCore:    BuildTimeCyberneticsCore,
+	GeneralUnitProbe:              BuildTimeProbe,
+	GeneralUnitZealot:             BuildTimeZealot,
+	GeneralUnitRoboticsFacility:   BuildTimeRoboticsFacility,
+	GeneralUnitStargate:           BuildTimeStargate,
+	GeneralUnitCitadelOfAdun:      BuildTimeCitadelOfAdun,
+	GeneralUnitTemplarArchives:    BuildTimeTemplarArchives,
+	GeneralUnitObservatory:        BuildTimeObservatory,
+	GeneralUnitRoboticsSupportBay: BuildTimeRoboticsSupportBay,
+	GeneralUnitFleetBeacon:        BuildTimeFleetBeacon,
+	GeneralUnitArbiterTribunal:    BuildTimeArbiterTribunal,
+	GeneralUnitShieldBattery:      BuildTimeShieldBattery,
+	GeneralUnitDragoon:            BuildTimeDragoon,
+	GeneralUnitHighTemplar:        BuildTimeHighTemplar,
+	GeneralUnitDarkTemplar:        BuildTimeDarkTemplar,
+	GeneralUnitShuttle:            BuildTimeShuttle,
+	GeneralUnitReaver:             BuildTimeReaver,
+	GeneralUnitObserver:           BuildTimeObserver,
+	GeneralUnitScout:              BuildTimeScout,
+	GeneralUnitCorsair:            BuildTimeCorsair,
+	GeneralUnitCarrier:            BuildTimeCarrier,
+	GeneralUnitArbiter:            BuildTimeArbiter,
 
 	// Terran
-	GeneralUnitCommandCenter:  BuildTimeCommandCenter,
-	GeneralUnitSupplyDepot:    BuildTimeSupplyDepot,
-	GeneralUnitBarracks:       BuildTimeBarracks,
-	GeneralUnitRefinery:       BuildTimeRefinery,
-	GeneralUnitEngineeringBay: BuildTimeEngineeringBay,
-	GeneralUnitFactory:        BuildTimeFactory,
-	GeneralUnitStarport:       BuildTimeStarport,
-	GeneralUnitMachineShop:    BuildTimeMachineShop,
-	GeneralUnitAcademy:        BuildTimeAcademy,
-	GeneralUnitBunker:         BuildTimeBunker,
-	GeneralUnitMissileTurret:  BuildTimeMissileTurret,
-	GeneralUnitSCV:            BuildTimeSCV,
-	GeneralUnitMarine:         BuildTimeMarine,
+	GeneralUnitCommandCenter:     BuildTimeCommandCenter,
+	GeneralUnitSupplyDepot:       BuildTimeSupplyDepot,
+	GeneralUnitBarracks:          BuildTimeBarracks,
+	GeneralUnitRefinery:          BuildTimeRefinery,
+	GeneralUnitEngineeringBay:    BuildTimeEngineeringBay,
+	GeneralUnitFactory:           BuildTimeFactory,
+	GeneralUnitStarport:          BuildTimeStarport,
+	GeneralUnitMachineShop:       BuildTimeMachineShop,
+	GeneralUnitAcademy:           BuildTimeAcademy,
+	GeneralUnitBunker:            BuildTimeBunker,
+	GeneralUnitMissileTurret:     BuildTimeMissileTurret,
+	GeneralUnitSCV:               BuildTimeSCV,
+	GeneralUnitMarine:            BuildTimeMarine,
+	GeneralUnitComSat:            BuildTimeComSat,
+	GeneralUnitNuclearSilo:       BuildTimeNuclearSilo,
+	GeneralUnitControlTower:      BuildTimeControlTower,
+	GeneralUnitScienceFacility:   BuildTimeScienceFacility,
+	GeneralUnitCovertOps:         BuildTimeCovertOps,
+	GeneralUnitPhysicsLab:        BuildTimePhysicsLab,
+	GeneralUnitArmory:            BuildTimeArmory,
+	GeneralUnitFirebat:           BuildTimeFirebat,
+	GeneralUnitMedic:             BuildTimeMedic,
+	GeneralUnitGhost:             BuildTimeGhost,
+	GeneralUnitVulture:           BuildTimeVulture,
+	GeneralUnitSiegeTankTankMode: BuildTimeSiegeTank,
+	GeneralUnitGoliath:           BuildTimeGoliath,
+	GeneralUnitWraith:            BuildTimeWraith,
+	GeneralUnitDropship:          BuildTimeDropship,
+	GeneralUnitScienceVessel:     BuildTimeScienceVessel,
+	GeneralUnitValkyrie:          BuildTimeValkyrie,
+	GeneralUnitBattlecruiser:     BuildTimeBattlecruiser,
 }
 
 // BuildTimeOf returns the canonical build time (seconds, Fastest speed) for a
@@ -1,11 +1,12 @@
 package models
 
 const (
-	ActionTypeUnitMorph   = "Unit Morph"
-	ActionTypeTrain       = "Train"
-	ActionTypeBuild       = "Build"
-	ActionTypeCancelMorph = "Cancel Morph"
-	ActionTypeCancelBuild = "Cancel Build"
+	ActionTypeUnitMorph     = "Unit Morph"
+	ActionTypeTrain         = "Train"
+	ActionTypeBuild         = "Build"
+	ActionTypeCancelMorph   = "Cancel Morph"
+	ActionTypeCancelBuild   = "Cancel Build"
+	ActionTypeBuildingMorph = "Building Morph"
 
 	UnitNameDrone         = "Drone"
 	UnitNameProbe         = "Probe"
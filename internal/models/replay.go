@@ -12,6 +12,11 @@ type Replay struct {
 	FileName     string    `json:"file_name"`
 	CreatedAt    time.Time `json:"created_at"`
 
+	// FileSize and FileModTime are the file's stamp at ingest time; ingest
+	// re-reads a known path only once its stamp changes.
+	FileSize    int64     `json:"file_size"`
+	FileModTime time.Time `json:"file_mod_time"`
+
 	// StarCraft: Brood War specific fields
 	ReplayDate      time.Time `json:"replay_date"`
 	Title           string    `json:"title"`
@@ -36,6 +41,10 @@ type Replay struct {
 	TeamStacking       bool `json:"team_stacking"`        // a stacking band (uneven non-solo team sizes) lasted >5min
 	TeamInfoIncomplete bool `json:"team_info_incomplete"` // some players still unaffiliated after our derivation
 
+	// GameFingerprint identifies the game across POVs (see internal/gameid);
+	// replays sharing it and a start time are grouped into one game.
+	GameFingerprint string `json:"game_fingerprint"`
+
 	Players []*Player `json:"-"`
 }
 
@@ -141,14 +150,89 @@ type Command struct {
 	Player *Player `json:"-"`
 }
 
+// EconomySample is one point of a player's estimated economy timeline: what
+// the resource simulation believes the player had at Second. Income is per
+// minute at that moment; supply is in the replay's whole units.
+type EconomySample struct {
+	PlayerID      byte `json:"player_id"`
+	Second        int  `json:"second"`
+	Minerals      int  `json:"minerals"`
+	Gas           int  `json:"gas"`
+	MineralIncome int  `json:"mineral_income"`
+	GasIncome     int  `json:"gas_income"`
+	SupplyUsed    int  `json:"supply_used"`
+	SupplyMax     int  `json:"supply_max"`
+	Workers       int  `json:"workers"`
+	Bases         int  `json:"bases"`
+}
+
+// ProductionBusySpan is one stretch a production building spent training,
+// from its estimated timeline (see unittags.ProducerTimelines). Building and
+// BuildingIndex name the building: the player's BuildingIndex-th Building,
+// counted from 1 in the order they became ready. ReadySecond and
+// WindowEndSecond bound the time the building could have been training; they
+// repeat on every span of the same building.
+type ProductionBusySpan struct {
+	PlayerID        byte   `json:"player_id"`
+	Building        string `json:"building"`
+	BuildingIndex   int    `json:"building_index"`
+	ReadySecond     int    `json:"ready_second"`
+	WindowEndSecond int    `json:"window_end_second"`
+	StartSecond     int    `json:"start_second"`
+	EndSecond       int    `json:"end_second"`
+}
+
+// LarvaSample is one second of a Zerg hatchery's estimated larva timeline
+// (see unittags.HatcheryLarvae) in which larvae spawned, were used, or a spawn
+// was missed because the hatchery already held three. HatcheryIndex numbers
+// the player's hatcheries from 1 in the order they became ready; the first
+// sample of a hatchery is at its ready second. Larvae is the count at the end
+// of the second. WindowEndSecond is when the timeline stops; it repeats on
+// every sample of the player.
+type LarvaSample struct {
+	PlayerID        byte `json:"player_id"`
+	HatcheryIndex   int  `json:"hatchery_index"`
+	WindowEndSecond int  `json:"window_end_second"`
+	Second          int  `json:"second"`
+	Larvae          int  `json:"larvae"`
+	Spawned         int  `json:"spawned"`
+	Used            int  `json:"used"`
+	Missed          int  `json:"missed"`
+}
+
+// HotkeyUsage is one second of a player's use of one hotkey group: how many
+// times it was assigned, recalled (Select), added to the selection (Add), and
+// recalled as the second tap of a double tap, which centers the screen on the
+// group (CameraJumps, counted among Selects). Role is what the group held at
+// the end of the second (see unittags.HotkeyBinding), empty when unknown.
+// WindowEndSecond is the player's last command second; it repeats on every
+// row of the player.
+type HotkeyUsage struct {
+	PlayerID        byte   `json:"player_id"`
+	HotkeyGroup     int    `json:"hotkey_group"`
+	WindowEndSecond int    `json:"window_end_second"`
+	Second          int    `json:"second"`
+	Role            string `json:"role"`
+	Assigns         int    `json:"assigns"`
+	Selects         int    `json:"selects"`
+	Adds            int    `json:"adds"`
+	CameraJumps     int    `json:"camera_jumps"`
+}
+
 // ReplayData represents the complete parsed replay data
 type ReplayData struct {
-	Replay              *Replay           `json:"replay"`
-	Players             []*Player         `json:"players"`
-	Commands            []*Command        `json:"commands"`
-	MapContext          *ReplayMapContext `json:"-"` // Runtime-only map context (not persisted)
-	PatternOrchestrator any               `json:"-"` // Pattern orchestrator (type *patterns.Orchestrator), not serialized
-	Profile             any               `json:"-"` // Optional *profile.Run, populated when SCREPDB_INGEST_PROFILE is set
+	Replay              *Replay              `json:"replay"`
+	Players             []*Player            `json:"players"`
+	Commands            []*Command           `json:"commands"`
+	MapContext          *ReplayMapContext    `json:"-"` // Runtime-only map context (not persisted)
+	Economy             []EconomySample      `json:"-"` // Estimated per-player economy timeline (see earlyfilter.SimulateEconomy)
+	ProductionBusy      []ProductionBusySpan `json:"-"` // Estimated production building busy time (see unittags.ProducerTimelines)
+	Larva               []LarvaSample        `json:"-"` // Estimated Zerg hatchery larva timelines (see unittags.HatcheryLarvae)
+	Hotkeys             []HotkeyUsage        `json:"-"` // Per-second hotkey group use (see parser.BuildHotkeyUsage)
+	PatternOrchestrator any                  `json:"-"` // Pattern orchestrator (type *patterns.Orchestrator), not serialized
+	Alliances           any                  `json:"-"` // Alliance analysis (type *parser.AllianceResult), nil unless multi-player melee
+	AnalysisInput       any                  `json:"-"` // Detection input persisted for file-independent re-analysis (type *analysisinput.Input)
+	Profile             any                  `json:"-"` // Optional *profile.Run, populated when SCREPDB_INGEST_PROFILE is set
 }
 
 // MapResourcePosition stores a resource position in pixels.
@@ -0,0 +1,110 @@
+package parser
+
+import (
+	"sort"
+
+	"github.com/marianogappa/screpdb/internal/models"
+	"github.com/marianogappa/screpdb/internal/unittags"
+)
+
+// hotkeyDoubleTapFrames is the longest gap, about half a second, between two
+// recalls of the same group with nothing in between that counts as a double
+// tap. Replays don't record the F2-F4 screen hotkeys, so a double tap is the
+// only camera jump a replay shows.
+const hotkeyDoubleTapFrames = 12
+
+// BuildHotkeyUsage tallies each player's hotkey commands per group and second,
+// tagging each row with the role of the group's latest binding from
+// unittags.Evidence (empty without evidence). Observers are left out. As for
+// production busy spans, a player's window ends at their last command, capped
+// at the replay's length. Rows are ordered by player, group and second.
+func BuildHotkeyUsage(ev *unittags.Evidence, replay *models.Replay, players []*models.Player, commands []*models.Command) []models.HotkeyUsage {
+	if replay == nil {
+		return nil
+	}
+	lastSecond := lastCommandSeconds(commands)
+	counted := map[byte]bool{}
+	for _, player := range players {
+		if player != nil && !player.IsObserver {
+			counted[player.PlayerID] = true
+		}
+	}
+
+	type rowKey struct {
+		playerID byte
+		group    byte
+		second   int
+	}
+	type lastRecall struct {
+		group byte
+		frame int32
+	}
+	rows := map[rowKey]*models.HotkeyUsage{}
+	recalls := map[byte]lastRecall{}
+	roles := map[byte]map[byte]string{}
+	nextBinding := map[byte]int{}
+	for _, cmd := range commands {
+		if cmd == nil || cmd.Player == nil || !counted[cmd.Player.PlayerID] {
+			continue
+		}
+		pid := cmd.Player.PlayerID
+		prev, hadRecall := recalls[pid]
+		delete(recalls, pid)
+		if cmd.ActionType != "Hotkey" || cmd.HotkeyType == nil || cmd.HotkeyGroup == nil {
+			continue
+		}
+		group := *cmd.HotkeyGroup
+
+		if ev != nil && ev.Players[pid] != nil {
+			pe := ev.Players[pid]
+			for ; nextBinding[pid] < len(pe.HotkeyBindings) && pe.HotkeyBindings[nextBinding[pid]].Frame <= cmd.Frame; nextBinding[pid]++ {
+				b := pe.HotkeyBindings[nextBinding[pid]]
+				if roles[pid] == nil {
+					roles[pid] = map[byte]string{}
+				}
+				roles[pid][b.Group] = b.Role
+			}
+		}
+
+		key := rowKey{playerID: pid, group: group, second: cmd.SecondsFromGameStart}
+		row := rows[key]
+		if row == nil {
+			row = &models.HotkeyUsage{
+				PlayerID:        pid,
+				HotkeyGroup:     int(group),
+				WindowEndSecond: min(lastSecond[pid], replay.DurationSeconds),
+				Second:          cmd.SecondsFromGameStart,
+			}
+			rows[key] = row
+		}
+		row.Role = roles[pid][group]
+		switch *cmd.HotkeyType {
+		case "Assign":
+			row.Assigns++
+		case "Select":
+			row.Selects++
+			if hadRecall && prev.group == group && cmd.Frame-prev.frame <= hotkeyDoubleTapFrames {
+				row.CameraJumps++
+			} else {
+				recalls[pid] = lastRecall{group: group, frame: cmd.Frame}
+			}
+		case "Add":
+			row.Adds++
+		}
+	}
+
+	out := make([]models.HotkeyUsage, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, *row)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].PlayerID != out[j].PlayerID {
+			return out[i].PlayerID < out[j].PlayerID
+		}
+		if out[i].HotkeyGroup != out[j].HotkeyGroup {
+			return out[i].HotkeyGroup < out[j].HotkeyGroup
+		}
+		return out[i].Second < out[j].Second
+	})
+	return out
+}
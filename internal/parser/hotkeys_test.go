@@ -0,0 +1,50 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/marianogappa/screpdb/internal/models"
+	"github.com/marianogappa/screpdb/internal/unittags"
+)
+
+func TestBuildHotkeyUsage(t *testing.T) {
+	p := &models.Player{PlayerID: 1}
+	obs := &models.Player{PlayerID: 2, IsObserver: true}
+	hk := func(player *models.Player, frame int32, htype string, group byte) *models.Command {
+		return &models.Command{Player: player, Frame: frame, SecondsFromGameStart: int(frame) * 42 / 1000,
+			ActionType: "Hotkey", HotkeyType: &htype, HotkeyGroup: &group}
+	}
+	ev := &unittags.Evidence{Players: map[byte]*unittags.PlayerEvidence{
+		1: {HotkeyBindings: []unittags.HotkeyBinding{
+			{Frame: 24, Sec: 1, Group: 1, Units: 2, Role: unittags.HotkeyRoleArmy},
+			{Frame: 100, Sec: 4, Group: 4, Units: 1, Role: unittags.HotkeyRoleBuilding},
+		}},
+	}}
+	cmds := []*models.Command{
+		hk(p, 24, "Assign", 1),
+		hk(p, 48, "Select", 1),
+		// Double tap: a camera jump.
+		hk(p, 55, "Select", 1),
+		// Another command in between: two plain recalls.
+		hk(p, 60, "Select", 1),
+		{Player: p, Frame: 62, SecondsFromGameStart: 2, ActionType: "Right Click"},
+		hk(p, 64, "Select", 1),
+		hk(p, 100, "Assign", 4),
+		hk(p, 110, "Add", 4),
+		// Never bound: no role.
+		hk(p, 120, "Select", 7),
+		hk(obs, 48, "Select", 1),
+	}
+
+	got := BuildHotkeyUsage(ev, &models.Replay{DurationSeconds: 600}, []*models.Player{p, obs}, cmds)
+	want := []models.HotkeyUsage{
+		{PlayerID: 1, HotkeyGroup: 1, WindowEndSecond: 5, Second: 1, Role: unittags.HotkeyRoleArmy, Assigns: 1},
+		{PlayerID: 1, HotkeyGroup: 1, WindowEndSecond: 5, Second: 2, Role: unittags.HotkeyRoleArmy, Selects: 4, CameraJumps: 1},
+		{PlayerID: 1, HotkeyGroup: 4, WindowEndSecond: 5, Second: 4, Role: unittags.HotkeyRoleBuilding, Assigns: 1, Adds: 1},
+		{PlayerID: 1, HotkeyGroup: 7, WindowEndSecond: 5, Second: 5, Selects: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("usage = %+v; want %+v", got, want)
+	}
+}
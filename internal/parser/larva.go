@@ -0,0 +1,43 @@
+package parser
+
+import (
+	"github.com/marianogappa/screpdb/internal/models"
+	"github.com/marianogappa/screpdb/internal/unittags"
+)
+
+// BuildLarvaSamples flattens every Zerg player's hatchery larva timelines
+// (unittags.HatcheryLarvae) into rows. As for production busy spans, a
+// player's timelines end at their last command, capped at the replay's length.
+func BuildLarvaSamples(ev *unittags.Evidence, replay *models.Replay, players []*models.Player, commands []*models.Command) []models.LarvaSample {
+	if ev == nil || replay == nil {
+		return nil
+	}
+	lastSecond := lastCommandSeconds(commands)
+
+	var out []models.LarvaSample
+	for _, player := range players {
+		if player == nil || player.IsObserver || player.Race != models.RaceZerg {
+			continue
+		}
+		pe := ev.Players[player.PlayerID]
+		if pe == nil {
+			continue
+		}
+		windowEnd := min(lastSecond[player.PlayerID], replay.DurationSeconds)
+		for i, hall := range pe.HatcheryLarvae(windowEnd) {
+			for _, sample := range hall.Samples {
+				out = append(out, models.LarvaSample{
+					PlayerID:        player.PlayerID,
+					HatcheryIndex:   i + 1,
+					WindowEndSecond: windowEnd,
+					Second:          sample.Sec,
+					Larvae:          sample.Larvae,
+					Spawned:         sample.Spawned,
+					Used:            sample.Used,
+					Missed:          sample.Missed,
+				})
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,29 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/marianogappa/screpdb/internal/models"
+	"github.com/marianogappa/screpdb/internal/unittags"
+)
+
+func TestBuildLarvaSamples(t *testing.T) {
+	zerg := &models.Player{PlayerID: 1, Race: models.RaceZerg}
+	terran := &models.Player{PlayerID: 2, Race: "Terran"}
+	ev := &unittags.Evidence{Players: map[byte]*unittags.PlayerEvidence{
+		1: {LarvaMorphs: []unittags.LarvaMorph{{Sec: 10, Larvae: 2}}},
+		2: {},
+	}}
+	cmds := []*models.Command{{Player: zerg, SecondsFromGameStart: 20}, {Player: terran, SecondsFromGameStart: 20}}
+
+	got := BuildLarvaSamples(ev, &models.Replay{DurationSeconds: 600}, []*models.Player{zerg, terran}, cmds)
+	want := []models.LarvaSample{
+		{PlayerID: 1, HatcheryIndex: 1, WindowEndSecond: 20, Second: 0, Larvae: 3, Spawned: 3},
+		{PlayerID: 1, HatcheryIndex: 1, WindowEndSecond: 20, Second: 10, Larvae: 1, Used: 2},
+		{PlayerID: 1, HatcheryIndex: 1, WindowEndSecond: 20, Second: 14, Larvae: 2, Spawned: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("samples = %+v; want %+v", got, want)
+	}
+}
@@ -29,7 +29,9 @@ func getMapAnalyzerClient() (*scmapanalyzer.Client, error) {
 // map. mapName lets scmapanalyzer.Analyze short-circuit the replay parse when
 // the map is in the embedded ladder cache (the common case). widthTiles and
 // heightTiles come from the already-parsed *rep.Replay so we do not re-parse
-// the file just to read header dimensions.
+// the file just to read header dimensions. scmapanalyzer only reads replays
+// from disk, so for a replay inside an archive (see fileops.ArchiveSeparator)
+// only maps in that cache get a layout.
 func buildMapContextLayoutFromReplay(replayPath string, mapName string, widthTiles, heightTiles int) (*models.MapContextLayout, error) {
 	client, err := getMapAnalyzerClient()
 	if err != nil {
@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"github.com/marianogappa/screpdb/internal/models"
+	"github.com/marianogappa/screpdb/internal/unittags"
+)
+
+// productionBusyBuildings are the army production buildings whose busy time
+// is stored: the ones a player keeps several of and is expected to keep
+// training from.
+var productionBusyBuildings = []string{
+	models.GeneralUnitGateway,
+	models.GeneralUnitBarracks,
+	models.GeneralUnitFactory,
+}
+
+// BuildProductionBusySpans flattens every non-observer player's Gateway,
+// Barracks and Factory timelines (unittags.ProducerTimelines) into rows. A
+// building's window ends at its owner's last command, capped at the replay's
+// length; spans are cut there, and a building ready only after it is dropped.
+func BuildProductionBusySpans(ev *unittags.Evidence, replay *models.Replay, players []*models.Player, commands []*models.Command) []models.ProductionBusySpan {
+	if ev == nil || replay == nil {
+		return nil
+	}
+	lastSecond := lastCommandSeconds(commands)
+
+	var out []models.ProductionBusySpan
+	for _, player := range players {
+		if player == nil || player.IsObserver {
+			continue
+		}
+		pe := ev.Players[player.PlayerID]
+		if pe == nil {
+			continue
+		}
+		windowEnd := min(lastSecond[player.PlayerID], replay.DurationSeconds)
+		indexByBuilding := map[string]int{}
+		for _, timeline := range pe.ProducerTimelines(productionBusyBuildings...) {
+			if timeline.ReadySec >= windowEnd {
+				continue
+			}
+			indexByBuilding[timeline.Building]++
+			for _, span := range timeline.Busy {
+				if span.StartSec >= windowEnd {
+					break
+				}
+				out = append(out, models.ProductionBusySpan{
+					PlayerID:        player.PlayerID,
+					Building:        timeline.Building,
+					BuildingIndex:   indexByBuilding[timeline.Building],
+					ReadySecond:     timeline.ReadySec,
+					WindowEndSecond: windowEnd,
+					StartSecond:     span.StartSec,
+					EndSecond:       min(span.EndSec, windowEnd),
+				})
+			}
+		}
+	}
+	return out
+}
+
+// lastCommandSeconds returns each player's last command second, keyed by
+// replay PlayerID.
+func lastCommandSeconds(commands []*models.Command) map[byte]int {
+	lastSecond := map[byte]int{}
+	for _, cmd := range commands {
+		if cmd == nil || cmd.Player == nil {
+			continue
+		}
+		lastSecond[cmd.Player.PlayerID] = max(lastSecond[cmd.Player.PlayerID], cmd.SecondsFromGameStart)
+	}
+	return lastSecond
+}
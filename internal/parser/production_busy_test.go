@@ -0,0 +1,43 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/marianogappa/screpdb/internal/models"
+	"github.com/marianogappa/screpdb/internal/unittags"
+)
+
+func TestBuildProductionBusySpans(t *testing.T) {
+	p := &models.Player{PlayerID: 1, Race: "Terran"}
+	obs := &models.Player{PlayerID: 2, IsObserver: true}
+	barracks := func(secs ...int) *unittags.Production {
+		pr := &unittags.Production{FirstSec: secs[0], Units: len(secs), Secs: secs}
+		for range secs {
+			pr.UnitNames = append(pr.UnitNames, models.GeneralUnitMarine)
+		}
+		return pr
+	}
+	ev := &unittags.Evidence{Players: map[byte]*unittags.PlayerEvidence{
+		1: {Producers: map[string]map[uint16]*unittags.Production{
+			models.GeneralUnitBarracks: {
+				0x10: barracks(60, 290),
+				// Ready after its owner's last command: dropped.
+				0x11: barracks(320),
+			},
+		}},
+		2: {Producers: map[string]map[uint16]*unittags.Production{
+			models.GeneralUnitBarracks: {0x20: barracks(60)},
+		}},
+	}}
+	cmds := []*models.Command{{Player: p, SecondsFromGameStart: 300}, {Player: obs, SecondsFromGameStart: 400}}
+
+	got := BuildProductionBusySpans(ev, &models.Replay{DurationSeconds: 600}, []*models.Player{p, obs}, cmds)
+	want := []models.ProductionBusySpan{
+		{PlayerID: 1, Building: models.GeneralUnitBarracks, BuildingIndex: 1, ReadySecond: 60, WindowEndSecond: 300, StartSecond: 60, EndSecond: 75},
+		{PlayerID: 1, Building: models.GeneralUnitBarracks, BuildingIndex: 1, ReadySecond: 60, WindowEndSecond: 300, StartSecond: 290, EndSecond: 300},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("spans = %+v; want %+v", got, want)
+	}
+}
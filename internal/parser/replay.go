@@ -11,9 +11,11 @@ import (
 	"github.com/marianogappa/screpdb/internal/builddedup"
 	"github.com/marianogappa/screpdb/internal/cmddedup"
 	"github.com/marianogappa/screpdb/internal/earlyfilter"
+	"github.com/marianogappa/screpdb/internal/gameid"
 	"github.com/marianogappa/screpdb/internal/models"
 	"github.com/marianogappa/screpdb/internal/parser/commands"
 	"github.com/marianogappa/screpdb/internal/patterns"
+	"github.com/marianogappa/screpdb/internal/profile"
 	"github.com/marianogappa/screpdb/internal/screp"
 	"github.com/marianogappa/screpdb/internal/unittags"
 	"github.com/marianogappa/screpdb/internal/utils"
@@ -27,6 +29,12 @@ type Options struct {
 	// filter dump a per-replay JSON trace into this directory. See
 	// internal/earlyfilter for the trace format.
 	EarlyFilterDebugDir string
+
+	// Profile, when non-nil, receives the time spent in each stage: parse
+	// (decoding and command extraction), unittags, earlyfilter, detect
+	// (marker detection over the filtered stream) and analyze (the
+	// worldstate and detector end-of-replay pass).
+	Profile *profile.Run
 }
 
 // ParseReplay parses a StarCraft: Brood War replay file and returns
@@ -38,10 +46,18 @@ func ParseReplay(filePath string, fileInfo *models.Replay) (*models.ReplayData,
 // ParseReplayWithOptions is the configurable entry point. The early-game
 // spam filter always runs; opts.EarlyFilterDebugDir controls only the
 // optional JSON debug trace.
+//
+// All pattern analysis, including the orchestrator's Finalize, runs here, so
+// the returned data only needs storing: callers can parse on many goroutines
+// and hand the results to a single storage writer.
 func ParseReplayWithOptions(filePath string, fileInfo *models.Replay, opts Options) (*models.ReplayData, error) {
+	run := opts.Profile
+	endPhase := run.Phase("parse")
+
 	// Parse the replay file using the real screp library
 	rep, err := screp.ParseFile(filePath)
 	if err != nil {
+		endPhase()
 		return nil, fmt.Errorf("failed to parse replay file: %w", err)
 	}
 
@@ -67,6 +83,7 @@ func ParseReplayWithOptions(filePath string, fileInfo *models.Replay, opts Optio
 	data.Replay.GameSpeed = rep.Header.Speed.String()
 	data.Replay.GameType = rep.Header.Type.String()
 	data.Replay.AvailSlotsCount = rep.Header.AvailSlotsCount
+	data.Replay.GameFingerprint = gameid.Fingerprint(rep)
 
 	// On Melee & Free for all this is always 1, and on Top vs Bottom it's what the game creator set for the home team.
 	data.Replay.HomeTeamSize = rep.Header.SubType
@@ -181,6 +198,9 @@ func ParseReplayWithOptions(filePath string, fileInfo *models.Replay, opts Optio
 	commandRegistry := commands.NewCommandRegistry()
 	startTime := rep.Header.StartTime.Unix()
 
+	endPhase()
+	endPhase = run.Phase("unittags")
+
 	// Infer pixel coordinates for production / research / cancel commands that
 	// the raw stream leaves spatially blank, by binding each to the producing
 	// building (recovered from selection state) and that building's placement.
@@ -192,6 +212,9 @@ func ParseReplayWithOptions(filePath string, fileInfo *models.Replay, opts Optio
 	// this intended count by the larva/minerals actually available.
 	morphSelectionSizes := unittags.MorphSelectionSizes(rep)
 
+	endPhase()
+	endPhase = run.Phase("parse")
+
 	if rep.Commands != nil {
 		for i, cmd := range rep.Commands.Cmds {
 			base := cmd.BaseCmd()
@@ -243,8 +266,10 @@ func ParseReplayWithOptions(filePath string, fileInfo *models.Replay, opts Optio
 	// command stream because earlyfilter / dedup don't touch Alliance commands,
 	// but consuming them here keeps the analyzer independent of those passes.
 	var allianceResult *AllianceResult
+	var allianceActivity *Activity
 	if data.Replay.GameType == "Melee" && countActiveMeleePlayers(data.Players) > 2 {
 		activity := ComputeActivity(data.Players, data.Commands, data.Replay.DurationSeconds)
+		allianceActivity = &activity
 		ar := AnalyzeAlliances(data.Players, data.Commands, data.Replay.DurationSeconds, activity)
 		allianceResult = &ar
 		data.Replay.TeamStacking = ar.TeamStackingFlag
@@ -286,6 +311,9 @@ func ParseReplayWithOptions(filePath string, fileInfo *models.Replay, opts Optio
 		DeriveWinnersFromFinalTopology(data.Players, data.Commands, ar, repSaverPID)
 	}
 
+	endPhase()
+	endPhase = run.Phase("unittags")
+
 	// Reconstruct selection state from the raw command stream (Select/Hotkey
 	// tags, which command extraction above discards) and plan the tag-based
 	// build dedup: provable worker one-at-a-time drops + never-produced
@@ -300,7 +328,11 @@ func ParseReplayWithOptions(filePath string, fileInfo *models.Replay, opts Optio
 
 	// Detect Mutalisk hit-n-run harass from selection / hotkey state + the
 	// a-move→right-click cadence, gated on Zerg + Spire + muta production (#194).
-	patternOrchestrator.SetMutaHarass(unittags.DetectMutaHarass(rep, data.Players))
+	mutaHarass := unittags.DetectMutaHarass(rep, data.Players)
+	patternOrchestrator.SetMutaHarass(mutaHarass)
+
+	endPhase()
+	endPhase = run.Phase("earlyfilter")
 
 	// Run the early-game spam filter before pattern detection so the
 	// orchestrator only sees commands the filter believes were real.
@@ -322,12 +354,24 @@ func ParseReplayWithOptions(filePath string, fileInfo *models.Replay, opts Optio
 	// Aspect, etc.
 	data.Commands = cmddedup.Dedup(data.Commands)
 
+	// Estimate each player's bank, income and supply over the whole game from
+	// the cleaned-up stream; its supply blocks become replay events.
+	economy := earlyfilter.SimulateEconomy(data.Replay, data.Players, data.Commands)
+	data.Economy = economy.Samples
+	patternOrchestrator.AppendReplayEvents(BuildSupplyBlockEvents(economy.SupplyBlocks))
+	data.ProductionBusy = BuildProductionBusySpans(unitTagEvidence, data.Replay, data.Players, data.Commands)
+	data.Larva = BuildLarvaSamples(unitTagEvidence, data.Replay, data.Players, data.Commands)
+	data.Hotkeys = BuildHotkeyUsage(unitTagEvidence, data.Replay, data.Players, data.Commands)
+
 	// Rewrite Right Click → Load / LoadBunker when the target unit is a
 	// transport, so the worldstate drop detector can pair Loads against
 	// subsequent Unload events. Must run before pattern detection so the
 	// orchestrator sees the rewritten action_type.
 	commands.ClassifyLoads(data.Commands)
 
+	endPhase()
+	endPhase = run.Phase("detect")
+
 	// Feed the filtered command stream through pattern detection.
 	for _, command := range data.Commands {
 		patternOrchestrator.ProcessCommand(command)
@@ -341,8 +385,21 @@ func ParseReplayWithOptions(filePath string, fileInfo *models.Replay, opts Optio
 		patternOrchestrator.AppendReplayEvents(extraEvents)
 	}
 
+	endPhase()
+	endPhase = run.Phase("analyze")
+	patternOrchestrator.Finalize()
+	endPhase()
+
 	// Store pattern orchestrator in data for later use
 	data.PatternOrchestrator = patternOrchestrator
+	if allianceResult != nil {
+		data.Alliances = allianceResult
+	}
+
+	// Keep what detection read from the .rep itself, so the replay can be
+	// re-analyzed from the database once the file is gone (see AnalyzeStored).
+	// Storage records the command stream into it as it stores the commands.
+	data.AnalysisInput = newAnalysisInput(data, unitTagEvidence, mutaHarass, allianceActivity)
 
 	return data, nil
 }
@@ -426,6 +483,7 @@ func CreateReplayFromFileInfo(filePath, fileName string, fileSize int64, checksu
 		FilePath:     filePath,
 		FileChecksum: checksum,
 		FileName:     fileName,
+		FileSize:     fileSize,
 		CreatedAt:    time.Now(),
 	}
 }
@@ -0,0 +1,85 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/marianogappa/screpdb/internal/analysisinput"
+	"github.com/marianogappa/screpdb/internal/earlyfilter"
+	"github.com/marianogappa/screpdb/internal/models"
+	"github.com/marianogappa/screpdb/internal/patterns"
+	"github.com/marianogappa/screpdb/internal/unittags"
+)
+
+// newAnalysisInput captures the replay-derived part of data's detection
+// input. The command stream is recorded later, by storage.
+func newAnalysisInput(data *models.ReplayData, evidence *unittags.Evidence, mutaHarass []unittags.MutaHarassEpisode, activity *Activity) *analysisinput.Input {
+	in := &analysisinput.Input{
+		PlayerIDs:  make(map[uint16]byte, len(data.Players)),
+		MapContext: analysisinput.NewMapContext(data.MapContext),
+		Evidence:   evidence,
+		MutaHarass: mutaHarass,
+		Sources:    make([]byte, 0, len(data.Commands)),
+	}
+	for _, p := range data.Players {
+		in.PlayerIDs[p.SlotID] = p.PlayerID
+	}
+	if activity != nil {
+		in.Activity = &analysisinput.Activity{
+			StoppedSecByPID: activity.StoppedSecByPID,
+			LeaveSecByPID:   activity.LeaveSecByPID,
+		}
+	}
+	return in
+}
+
+// AnalyzeStored re-runs pattern detection on a replay rebuilt from the
+// database: data holds the stored replay, players and the command stream
+// rebuilt by in.Rebuild. The early-game filter and dedup passes already ran
+// at ingest and aren't repeated. On return data.PatternOrchestrator holds the
+// results, exactly as after ParseReplay.
+func AnalyzeStored(data *models.ReplayData, in *analysisinput.Input) error {
+	if data.Replay == nil {
+		return fmt.Errorf("stored replay data has no replay")
+	}
+	data.MapContext = in.MapContext.ReplayMapContext()
+	data.Replay.Players = data.Players
+
+	patternOrchestrator := patterns.NewOrchestrator()
+	patternOrchestrator.Initialize(data.Replay, data.Players, data.MapContext)
+
+	var allianceResult *AllianceResult
+	if in.Activity != nil && data.Replay.GameType == "Melee" && countActiveMeleePlayers(data.Players) > 2 {
+		activity := Activity{
+			StoppedSecByPID: map[byte]int{},
+			LeaveSecByPID:   map[byte]int{},
+		}
+		for pid, sec := range in.Activity.StoppedSecByPID {
+			activity.StoppedSecByPID[pid] = sec
+		}
+		for pid, sec := range in.Activity.LeaveSecByPID {
+			activity.LeaveSecByPID[pid] = sec
+		}
+		ar := AnalyzeAlliances(data.Players, data.Commands, data.Replay.DurationSeconds, activity)
+		allianceResult = &ar
+	}
+
+	economy := earlyfilter.SimulateEconomy(data.Replay, data.Players, data.Commands)
+	data.Economy = economy.Samples
+	data.ProductionBusy = BuildProductionBusySpans(in.Evidence, data.Replay, data.Players, data.Commands)
+	data.Larva = BuildLarvaSamples(in.Evidence, data.Replay, data.Players, data.Commands)
+	data.Hotkeys = BuildHotkeyUsage(in.Evidence, data.Replay, data.Players, data.Commands)
+
+	patternOrchestrator.SetProductionSignals(in.Evidence)
+	patternOrchestrator.SetMutaHarass(in.MutaHarass)
+	for _, command := range data.Commands {
+		patternOrchestrator.ProcessCommand(command)
+	}
+	patternOrchestrator.AppendReplayEvents(BuildSupplyBlockEvents(economy.SupplyBlocks))
+	if allianceResult != nil {
+		patternOrchestrator.AppendReplayEvents(BuildAllianceDerivedEvents(data.Players, *allianceResult))
+		data.Alliances = allianceResult
+	}
+
+	data.PatternOrchestrator = patternOrchestrator
+	return nil
+}
@@ -0,0 +1,27 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/marianogappa/screpdb/internal/earlyfilter"
+	"github.com/marianogappa/screpdb/internal/patterns/worldstate"
+)
+
+// BuildSupplyBlockEvents converts the economy simulation's supply blocks into
+// supply_block replay events: one per block, at the second it began, sourced
+// by the blocked player, with the block's length and the supply cap it hit as
+// the {"duration_seconds": N, "supply": M} payload.
+func BuildSupplyBlockEvents(blocks []earlyfilter.SupplyBlock) []worldstate.ReplayEvent {
+	events := make([]worldstate.ReplayEvent, 0, len(blocks))
+	for _, block := range blocks {
+		pid := block.PlayerID
+		payload := fmt.Sprintf(`{"duration_seconds":%d,"supply":%d}`, block.DurationSeconds(), block.Supply)
+		events = append(events, worldstate.ReplayEvent{
+			EventType:            "supply_block",
+			Second:               block.StartSecond,
+			SourceReplayPlayerID: &pid,
+			Payload:              &payload,
+		})
+	}
+	return events
+}
@@ -0,0 +1,26 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/marianogappa/screpdb/internal/earlyfilter"
+)
+
+func TestBuildSupplyBlockEvents(t *testing.T) {
+	events := BuildSupplyBlockEvents([]earlyfilter.SupplyBlock{
+		{PlayerID: 2, StartSecond: 41, EndSecond: 54, Supply: 9},
+	})
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	ev := events[0]
+	if ev.EventType != "supply_block" || ev.Second != 41 {
+		t.Errorf("event = %s @%d; want supply_block @41", ev.EventType, ev.Second)
+	}
+	if ev.SourceReplayPlayerID == nil || *ev.SourceReplayPlayerID != 2 {
+		t.Errorf("source = %v; want player 2", ev.SourceReplayPlayerID)
+	}
+	if ev.Payload == nil || *ev.Payload != `{"duration_seconds":13,"supply":9}` {
+		t.Errorf("payload = %v", ev.Payload)
+	}
+}
@@ -264,7 +264,32 @@ import (
 // openers gained a "proxy" modifier (WorldstateEvent proxy_factory). Fixes
 // gas-trick Zerg openers read several supply too low (a 10 Hatch read as 4
 // Hatch). Re-ingest so Zerg openers + Terran mech proxies re-evaluate.
-const AlgorithmVersion = 60
+// 61: supply_block game events from the full-game economy simulation (periods
+// at the supply cap with no supply in progress). Re-analyze so stored replays
+// gain them and the supply-block skill proxy covers them.
+// 62: Siege Tank trains bind to their Factory's tag (unittags keyed the
+// producer as "Siege Tank", but the command names it "Siege Tank (Tank
+// Mode)"), so tank production refreshes base ownership and build dedup no
+// longer drops tank-only Factories as never-produced. Tank trains now carry
+// their Factory's coordinates, which Viewport Multitasking counts as screen
+// positions: switches_per_minute changed for 36 Terran players in
+// markers_golden.json and no other marker moved.
+// Production busy spans are stored for Gateways, Barracks and Factories.
+// Re-analyze so stored replays gain the spans and the production-idle skill
+// proxy covers them.
+// 63: larva morphs are kept in the selection evidence and each Zerg
+// hatchery's larva timeline is stored. Re-analyze so stored replays gain the
+// samples and the missed-larvae skill proxy covers them.
+// 64: hotkey binds are classified as building or army from the selection
+// evidence and each player's control-group use is stored. Re-analyze so
+// stored replays gain the usage and the control-group views cover them.
+// 65: the full-game economy estimate (bank, income, supply, workers and bases
+// every 10 seconds) is stored as player_economy_samples. Re-analyze so replays
+// analyzed before the samples existed gain them.
+// 66: replays ingested before duplicate POV grouping have no game fingerprint.
+// Re-analyze so those whose .rep is still in place get one and join their
+// game; replays rebuilt from the database stay ungrouped.
+const AlgorithmVersion = 66
 
 // DetectorLevel indicates at which level a pattern detector operates
 type DetectorLevel string
@@ -53,6 +53,7 @@ type Orchestrator struct {
 	replay     *models.Replay
 	players    []*models.Player
 	worldState *worldstate.Engine
+	finalized  bool
 }
 
 // NewOrchestrator creates a new pattern detection orchestrator
@@ -113,8 +114,23 @@ func (o *Orchestrator) ProcessCommand(command *models.Command) {
 	}
 }
 
-// GetResults returns all pattern results that should be saved
+// GetResults returns all pattern results that should be saved, running
+// Finalize first if nobody has yet.
 func (o *Orchestrator) GetResults() []*core.PatternResult {
+	o.Finalize()
+	return o.results
+}
+
+// Finalize runs the end-of-replay analysis once every command has been
+// processed: worldstate's batch pipeline, each detector's Finalize and the
+// one-opener-per-player selection. GetResults runs it on first use; calling
+// it up front does that work on the caller's goroutine instead, e.g. an
+// ingest worker rather than the storage writer. Later calls are no-ops.
+func (o *Orchestrator) Finalize() {
+	if o.finalized {
+		return
+	}
+	o.finalized = true
 	// Run worldstate's batch pipeline before detector Finalize — markers'
 	// worldstateFirstEventEvaluator reads worldstate at Finalize time.
 	if o.worldState != nil {
@@ -153,7 +169,6 @@ func (o *Orchestrator) GetResults() []*core.PatternResult {
 		}
 	}
 	o.results = selectBestTierOpeners(o.results)
-	return o.results
 }
 
 // selectBestTierOpeners enforces "one opener per player, best tier wins".
@@ -331,3 +331,20 @@ func TestGetResultsRunsSelectBestTier(t *testing.T) {
 		t.Errorf("kept %q, want the preferred opener %q", got[0].PatternName, tier1)
 	}
 }
+
+func TestFinalizeRunsOnce(t *testing.T) {
+	o := NewOrchestrator()
+	tier1 := openerName(markers.TierPreferred)
+	tier3 := openerName(markers.TierResidual)
+	o.results = []*core.PatternResult{
+		{PatternName: tier1, Level: core.LevelPlayer, ReplayID: 1, ReplayPlayerID: bytePtr(0)},
+		{PatternName: tier3, Level: core.LevelPlayer, ReplayID: 1, ReplayPlayerID: bytePtr(0)},
+	}
+	o.Finalize()
+	// A result added after Finalize must survive GetResults: tier selection
+	// already ran and doesn't run again.
+	o.results = append(o.results, &core.PatternResult{PatternName: tier3, Level: core.LevelPlayer, ReplayID: 1, ReplayPlayerID: bytePtr(1)})
+	if got := o.GetResults(); len(got) != 2 {
+		t.Fatalf("GetResults after Finalize: len = %d, want 2", len(got))
+	}
+}
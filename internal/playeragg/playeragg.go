@@ -0,0 +1,531 @@
+// Package playeragg maintains the player aggregate tables (see the replay
+// migration 000008_player_aggregates), which the dashboard's players pages
+// read instead of aggregating players, commands and replay_events on every
+// request.
+//
+// player_game_facts has one row per human, non-observer player of a replay,
+// computed from that replay's rows alone, so keeping it current costs a
+// fraction of the replay's own ingest. The aggregate tables are per-player
+// sums of the facts, keyed by replay class, and are recomputed a player at a
+// time from the facts. All SQL here is SQLite's.
+package playeragg
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version identifies how the tables are computed. Bump it when a change here
+// makes existing rows wrong: databases built under another version read as
+// unbuilt until Rebuild runs.
+const Version = 1
+
+// ShortGameSeconds is the duration under which a replay is a short game
+// (is_short), which the dashboard's global replay filter can exclude.
+const ShortGameSeconds = 120
+
+// Production cadence: the spacing of a player's Train and Unit Morph
+// commands between CadenceStartSeconds and CadenceEndFraction of the game. A
+// player's replay counts when it has CadenceMinUnitsPerReplay such commands
+// and CadenceMinGapsPerReplay gaps between them; gaps of
+// CadenceIdleGapSeconds or more are idle.
+const (
+	CadenceStartSeconds      int64   = 7 * 60
+	CadenceEndFraction       float64 = 0.8
+	CadenceIdleGapSeconds    int64   = 20
+	CadenceMinUnitsPerReplay int64   = 12
+	CadenceMinGapsPerReplay  int64   = 8
+)
+
+// CadenceExcludedUnits returns the units production cadence ignores. The
+// broad variant only drops workers and supply; the strict one also drops
+// support and transport units, whose production follows the army's needs
+// rather than the player's macro rhythm.
+func CadenceExcludedUnits(broad bool) []string {
+	if broad {
+		return []string{"SCV", "Probe", "Drone", "Overlord"}
+	}
+	return []string{
+		"SCV",
+		"Probe",
+		"Drone",
+		"Overlord",
+		"Observer",
+		"Shuttle",
+		"Science Vessel",
+		"Medic",
+		"Dropship",
+		"Defiler",
+		"Queen",
+		"Nuclear Missile",
+	}
+}
+
+// viewportMarker is the marker whose payload carries a player's viewport
+// switches per minute.
+const viewportMarker = "viewport_multitasking"
+
+// summaryEventPredicate selects the replay_events counted in
+// player_marker_counts: every marker but the meta ones that aren't
+// per-matchup features, plus the drop subtypes surfaced next to made_drops.
+const summaryEventPredicate = `(
+	(re.event_kind = 'marker' AND re.event_type NOT IN (
+		'used_hotkey_groups',
+		'viewport_multitasking',
+		'mid_game_starts',
+		'late_game_starts',
+		'never_used_hotkeys'
+	))
+	OR (re.event_kind = 'game_event' AND re.event_type IN (
+		'cliff_drop'
+	))
+)`
+
+// chunkSize bounds the bind parameters of one statement.
+const chunkSize = 500
+
+// DB is what the package needs of a *sql.DB or *sql.Tx.
+type DB interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// Stats summarizes one Rebuild.
+type Stats struct {
+	Players int64 // distinct players aggregated
+	Games   int64 // player_game_facts rows
+}
+
+// Current reports whether db's aggregates have been built under Version.
+func Current(ctx context.Context, db DB) (bool, error) {
+	rows, err := db.QueryContext(ctx, "SELECT version FROM player_aggregates_state WHERE id = 1")
+	if err != nil {
+		return false, fmt.Errorf("failed to read player aggregates state: %w", err)
+	}
+	defer rows.Close()
+	var version int
+	if rows.Next() {
+		if err := rows.Scan(&version); err != nil {
+			return false, fmt.Errorf("failed to scan player aggregates state: %w", err)
+		}
+	}
+	return version == Version, rows.Err()
+}
+
+// MarkBuiltIfEmpty records the aggregates of a database without replays as
+// built, since there is nothing to aggregate; maintenance then keeps them
+// current from the first ingest on.
+func MarkBuiltIfEmpty(ctx context.Context, db DB) error {
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO player_aggregates_state (id, version)
+		SELECT 1, ? WHERE NOT EXISTS (SELECT 1 FROM replays)
+		ON CONFLICT (id) DO UPDATE SET version = excluded.version`, Version); err != nil {
+		return fmt.Errorf("failed to mark player aggregates built: %w", err)
+	}
+	return nil
+}
+
+// Rebuild recomputes every fact and aggregate and marks the tables built.
+// Run it in a transaction: it empties the tables first.
+func Rebuild(ctx context.Context, db DB) (Stats, error) {
+	var stats Stats
+	if _, err := db.ExecContext(ctx, "DELETE FROM player_game_facts"); err != nil {
+		return stats, fmt.Errorf("failed to clear player game facts: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, factsSQL("1 = 1")); err != nil {
+		return stats, fmt.Errorf("failed to compute player game facts: %w", err)
+	}
+	if err := RefreshAll(ctx, db); err != nil {
+		return stats, err
+	}
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO player_aggregates_state (id, version) VALUES (1, ?)
+		ON CONFLICT (id) DO UPDATE SET version = excluded.version`, Version); err != nil {
+		return stats, fmt.Errorf("failed to mark player aggregates built: %w", err)
+	}
+	rows, err := db.QueryContext(ctx, "SELECT COUNT(DISTINCT player_key), COUNT(*) FROM player_game_facts")
+	if err != nil {
+		return stats, fmt.Errorf("failed to count player game facts: %w", err)
+	}
+	defer rows.Close()
+	if rows.Next() {
+		if err := rows.Scan(&stats.Players, &stats.Games); err != nil {
+			return stats, fmt.Errorf("failed to count player game facts: %w", err)
+		}
+	}
+	return stats, rows.Err()
+}
+
+// Update recomputes the facts of replayIDs and the aggregates of every
+// player in them, or in a replay sharing one of fingerprints: regrouping a
+// fingerprint can change which of its replays is canonical.
+func Update(ctx context.Context, db DB, replayIDs []int64, fingerprints []string) error {
+	if err := UpdateFacts(ctx, db, replayIDs); err != nil {
+		return err
+	}
+
+	keys := map[string]bool{}
+	for start := 0; start < len(replayIDs); start += chunkSize {
+		chunk := replayIDs[start:min(start+chunkSize, len(replayIDs))]
+		scope, args := inList("replay_id", int64Args(chunk))
+		if err := collectKeys(ctx, db, keys, "SELECT DISTINCT player_key FROM player_game_facts WHERE "+scope, args); err != nil {
+			return err
+		}
+	}
+	for _, fp := range fingerprints {
+		if fp == "" {
+			continue
+		}
+		if err := collectKeys(ctx, db, keys, `
+			SELECT DISTINCT f.player_key
+			FROM player_game_facts f
+			JOIN replays r ON r.id = f.replay_id
+			WHERE r.game_fingerprint = ?`, []any{fp}); err != nil {
+			return err
+		}
+	}
+	playerKeys := make([]string, 0, len(keys))
+	for key := range keys {
+		playerKeys = append(playerKeys, key)
+	}
+	return RefreshPlayers(ctx, db, playerKeys)
+}
+
+// UpdateFacts recomputes the player_game_facts rows of replayIDs, leaving
+// the aggregates to RefreshPlayers or RefreshAll.
+func UpdateFacts(ctx context.Context, db DB, replayIDs []int64) error {
+	for start := 0; start < len(replayIDs); start += chunkSize {
+		chunk := replayIDs[start:min(start+chunkSize, len(replayIDs))]
+		factScope, args := inList("replay_id", int64Args(chunk))
+		if _, err := db.ExecContext(ctx, "DELETE FROM player_game_facts WHERE "+factScope, args...); err != nil {
+			return fmt.Errorf("failed to clear player game facts: %w", err)
+		}
+		replayScope, _ := inList("r.id", args)
+		if _, err := db.ExecContext(ctx, factsSQL(replayScope), args...); err != nil {
+			return fmt.Errorf("failed to compute player game facts: %w", err)
+		}
+	}
+	return nil
+}
+
+// RefreshPlayers recomputes the aggregates of playerKeys from their facts.
+func RefreshPlayers(ctx context.Context, db DB, playerKeys []string) error {
+	for start := 0; start < len(playerKeys); start += chunkSize {
+		chunk := playerKeys[start:min(start+chunkSize, len(playerKeys))]
+		args := make([]any, len(chunk))
+		for i, key := range chunk {
+			args[i] = key
+		}
+		scope, args := inList("player_key", args)
+		if err := refresh(ctx, db, scope, args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RefreshAll recomputes every aggregate from the facts, for when too much
+// changed to track the affected players.
+func RefreshAll(ctx context.Context, db DB) error {
+	return refresh(ctx, db, "1 = 1", nil)
+}
+
+func refresh(ctx context.Context, db DB, keyScope string, args []any) error {
+	for _, table := range []string{"player_aggregates", "player_matchup_aggregates", "player_marker_counts"} {
+		if _, err := db.ExecContext(ctx, "DELETE FROM "+table+" WHERE "+keyScope, args...); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", table, err)
+		}
+	}
+	factScope := "f." + keyScope
+	if keyScope == "1 = 1" {
+		factScope = keyScope
+	}
+	for _, q := range []struct{ table, sql string }{
+		{"player_aggregates", aggregatesSQL(factScope)},
+		{"player_matchup_aggregates", matchupAggregatesSQL(factScope)},
+		{"player_marker_counts", markerCountsSQL(factScope)},
+	} {
+		if _, err := db.ExecContext(ctx, q.sql, args...); err != nil {
+			return fmt.Errorf("failed to compute %s: %w", q.table, err)
+		}
+	}
+	return nil
+}
+
+func collectKeys(ctx context.Context, db DB, keys map[string]bool, query string, args []any) error {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to list aggregated players: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return fmt.Errorf("failed to scan aggregated player: %w", err)
+		}
+		keys[key] = true
+	}
+	return rows.Err()
+}
+
+func int64Args(values []int64) []any {
+	out := make([]any, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
+
+// inList returns "column IN (?, ...)" over args; an empty list matches
+// nothing.
+func inList(column string, args []any) (string, []any) {
+	if len(args) == 0 {
+		return "0 = 1", nil
+	}
+	return column + " IN (" + strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ") + ")", args
+}
+
+// classColumns are the replay class columns every aggregate table is keyed
+// by; classSelect computes them from a fact f and its replay r.
+const (
+	classColumns = "map_kind, game_type, is_one_on_one, is_canonical, is_short, has_computers"
+	classSelect  = "f.map_kind, f.game_type, f.is_one_on_one, CASE WHEN COALESCE(r.game_group_id, r.id) = r.id THEN 1 ELSE 0 END AS is_canonical, f.is_short, f.has_computers"
+	classGroupBy = "f.map_kind, f.game_type, f.is_one_on_one, is_canonical, f.is_short, f.has_computers"
+)
+
+// factsSQL computes the player_game_facts rows of the replays r matching
+// replayScope.
+func factsSQL(replayScope string) string {
+	return `
+		WITH scoped AS (
+			SELECT
+				r.id,
+				r.team_format,
+				r.map_kind,
+				lower(trim(coalesce(r.game_type, ''))) AS game_type,
+				r.duration_seconds,
+				r.replay_date
+			FROM replays r
+			WHERE ` + replayScope + `
+		),
+		lineup AS (
+			SELECT
+				p.replay_id,
+				COUNT(*) AS players,
+				COUNT(DISTINCT p.team) AS teams,
+				SUM(CASE WHEN lower(trim(coalesce(p.type, ''))) IN ('computer', 'computer controlled') THEN 1 ELSE 0 END) AS computers
+			FROM players p
+			JOIN scoped s ON s.id = p.replay_id
+			WHERE p.is_observer = 0
+			GROUP BY p.replay_id
+		),
+		humans AS (
+			SELECT p.id, p.replay_id, p.name, p.race, p.is_winner, p.apm, p.eapm
+			FROM players p
+			JOIN scoped s ON s.id = p.replay_id
+			WHERE p.is_observer = 0
+				AND lower(trim(coalesce(p.type, ''))) = 'human'
+		),
+		duels AS (
+			SELECT replay_id, MIN(id) AS first_id, MAX(id) AS second_id
+			FROM humans
+			GROUP BY replay_id
+			HAVING COUNT(*) = 2
+		),
+		viewport AS (
+			SELECT
+				re.source_player_id AS player_id,
+				MAX(CASE
+					WHEN NOT json_valid(re.payload) THEN NULL
+					WHEN json_type(re.payload) = 'object' THEN COALESCE(json_extract(re.payload, '$.switches_per_minute'), 0)
+					WHEN json_type(re.payload) IN ('integer', 'real') THEN CAST(re.payload AS REAL)
+				END) AS rate
+			FROM replay_events re
+			JOIN scoped s ON s.id = re.replay_id
+			WHERE re.event_kind = 'marker'
+				AND re.event_type = '` + viewportMarker + `'
+				AND re.payload IS NOT NULL
+				AND trim(re.payload) <> ''
+			GROUP BY re.source_player_id
+		),
+		` + cadenceCTEs("cadence_strict", CadenceExcludedUnits(false)) + `,
+		` + cadenceCTEs("cadence_broad", CadenceExcludedUnits(true)) + `
+		INSERT INTO player_game_facts (
+			player_id, replay_id, player_key, player_name, race, opp_race, team_format,
+			map_kind, game_type, is_one_on_one, is_short, has_computers, replay_date,
+			is_winner, apm, eapm, viewport_switch_rate,
+			cadence_strict_rate, cadence_strict_cv, cadence_strict_burstiness, cadence_strict_idle, cadence_strict_score,
+			cadence_broad_rate, cadence_broad_cv, cadence_broad_burstiness, cadence_broad_idle, cadence_broad_score
+		)
+		SELECT
+			h.id,
+			h.replay_id,
+			lower(trim(h.name)),
+			h.name,
+			h.race,
+			COALESCE(opp.race, ''),
+			s.team_format,
+			s.map_kind,
+			s.game_type,
+			CASE WHEN l.players = 2 AND l.teams = 2 THEN 1 ELSE 0 END,
+			CASE WHEN s.duration_seconds < ` + strconv.Itoa(ShortGameSeconds) + ` THEN 1 ELSE 0 END,
+			CASE WHEN l.computers > 0 THEN 1 ELSE 0 END,
+			s.replay_date,
+			CASE WHEN h.is_winner THEN 1 ELSE 0 END,
+			h.apm,
+			h.eapm,
+			v.rate,
+			cs.rate, cs.cv, cs.burstiness, cs.idle, cs.score,
+			cb.rate, cb.cv, cb.burstiness, cb.idle, cb.score
+		FROM humans h
+		JOIN scoped s ON s.id = h.replay_id
+		JOIN lineup l ON l.replay_id = h.replay_id
+		LEFT JOIN duels d ON d.replay_id = h.replay_id
+		LEFT JOIN players opp ON opp.id = CASE WHEN h.id = d.first_id THEN d.second_id ELSE d.first_id END
+		LEFT JOIN viewport v ON v.player_id = h.id
+		LEFT JOIN cadence_strict cs ON cs.player_id = h.id
+		LEFT JOIN cadence_broad cb ON cb.player_id = h.id
+	`
+}
+
+// cadenceCTEs defines name, the production cadence of each player in scope
+// (player_id, rate, cv, burstiness, idle, score), through helper CTEs
+// prefixed with name.
+func cadenceCTEs(name string, excludedUnits []string) string {
+	quoted := make([]string, len(excludedUnits))
+	for i, unit := range excludedUnits {
+		quoted[i] = "'" + strings.ReplaceAll(unit, "'", "''") + "'"
+	}
+	start := strconv.FormatInt(CadenceStartSeconds, 10)
+	windowEnd := "CAST(" + strconv.FormatFloat(CadenceEndFraction, 'f', 4, 64) + " * s.duration_seconds AS INTEGER)"
+	return name + `_base AS (
+			SELECT c.player_id, c.seconds_from_game_start AS t, c.id AS cmd_id, ` + windowEnd + ` - ` + start + ` AS window_s
+			FROM commands c
+			JOIN players p ON p.id = c.player_id
+			JOIN scoped s ON s.id = c.replay_id
+			WHERE p.is_observer = 0
+				AND lower(trim(coalesce(p.type, ''))) = 'human'
+				AND c.action_type IN ('Train', 'Unit Morph')
+				AND c.unit_type IS NOT NULL
+				AND trim(c.unit_type) <> ''
+				AND c.unit_type NOT IN (` + strings.Join(quoted, ", ") + `)
+				AND c.seconds_from_game_start >= ` + start + `
+				AND c.seconds_from_game_start <= ` + windowEnd + `
+				AND ` + windowEnd + ` > ` + start + `
+		),
+		` + name + `_gaps AS (
+			SELECT
+				player_id,
+				window_s,
+				t - LAG(t) OVER (PARTITION BY player_id ORDER BY t, cmd_id) AS gap_s
+			FROM ` + name + `_base
+		),
+		` + name + `_metrics AS (
+			SELECT
+				player_id,
+				window_s,
+				COUNT(*) AS n_units,
+				AVG(gap_s * 1.0) AS mean_gap_s,
+				sqrt(AVG(gap_s * gap_s * 1.0) - AVG(gap_s * 1.0) * AVG(gap_s * 1.0)) AS std_gap_s,
+				SUM(CASE WHEN gap_s >= ` + strconv.FormatInt(CadenceIdleGapSeconds, 10) + ` THEN 1 ELSE 0 END) * 1.0 / NULLIF(COUNT(gap_s), 0) AS idle_ratio
+			FROM ` + name + `_gaps
+			GROUP BY player_id, window_s
+			HAVING COUNT(*) >= ` + strconv.FormatInt(CadenceMinUnitsPerReplay, 10) + `
+				AND COUNT(gap_s) >= ` + strconv.FormatInt(CadenceMinGapsPerReplay, 10) + `
+				AND window_s > 0
+		),
+		` + name + ` AS (
+			SELECT
+				player_id,
+				(n_units * 60.0) / window_s AS rate,
+				std_gap_s / NULLIF(mean_gap_s, 0) AS cv,
+				((std_gap_s / NULLIF(mean_gap_s, 0)) - 1.0) / ((std_gap_s / NULLIF(mean_gap_s, 0)) + 1.0) AS burstiness,
+				idle_ratio AS idle,
+				((n_units * 60.0) / window_s) / (1.0 + COALESCE(std_gap_s / NULLIF(mean_gap_s, 0), 9999.0)) AS score
+			FROM ` + name + `_metrics
+		)`
+}
+
+// apmSums are the APM and EAPM columns of the aggregate tables: sums and
+// counts over the games where the value is known (> 0), so an average over
+// any set of rows is SUM(apm_sum) / SUM(apm_games).
+const apmSums = `
+	SUM(CASE WHEN f.apm > 0 THEN f.apm ELSE 0 END),
+	SUM(CASE WHEN f.apm > 0 THEN 1 ELSE 0 END),
+	SUM(CASE WHEN f.eapm > 0 THEN f.eapm ELSE 0 END),
+	SUM(CASE WHEN f.eapm > 0 THEN 1 ELSE 0 END)`
+
+func aggregatesSQL(factScope string) string {
+	cadence := func(mode string) string {
+		return `
+			COUNT(f.cadence_` + mode + `_rate),
+			COALESCE(SUM(f.cadence_` + mode + `_rate), 0),
+			COALESCE(SUM(f.cadence_` + mode + `_cv), 0),
+			COALESCE(SUM(f.cadence_` + mode + `_burstiness), 0),
+			COALESCE(SUM(f.cadence_` + mode + `_idle), 0),
+			COALESCE(SUM(f.cadence_` + mode + `_score), 0)`
+	}
+	return `
+		INSERT INTO player_aggregates (
+			player_key, ` + classColumns + `,
+			player_name, games, wins, protoss_games, terran_games, zerg_games,
+			apm_sum, apm_games, eapm_sum, eapm_games, last_played,
+			viewport_games, viewport_rate_sum,
+			cadence_strict_games, cadence_strict_rate_sum, cadence_strict_cv_sum,
+			cadence_strict_burstiness_sum, cadence_strict_idle_sum, cadence_strict_score_sum,
+			cadence_broad_games, cadence_broad_rate_sum, cadence_broad_cv_sum,
+			cadence_broad_burstiness_sum, cadence_broad_idle_sum, cadence_broad_score_sum
+		)
+		SELECT
+			f.player_key, ` + classSelect + `,
+			MIN(f.player_name),
+			COUNT(*),
+			SUM(f.is_winner),
+			SUM(CASE WHEN lower(trim(f.race)) = 'protoss' THEN 1 ELSE 0 END),
+			SUM(CASE WHEN lower(trim(f.race)) = 'terran' THEN 1 ELSE 0 END),
+			SUM(CASE WHEN lower(trim(f.race)) = 'zerg' THEN 1 ELSE 0 END),` + apmSums + `,
+			MAX(f.replay_date),
+			COUNT(f.viewport_switch_rate),
+			COALESCE(SUM(f.viewport_switch_rate), 0),` + cadence("strict") + `,` + cadence("broad") + `
+		FROM player_game_facts f
+		JOIN replays r ON r.id = f.replay_id
+		WHERE ` + factScope + `
+		GROUP BY f.player_key, ` + classGroupBy
+}
+
+func matchupAggregatesSQL(factScope string) string {
+	return `
+		INSERT INTO player_matchup_aggregates (
+			player_key, race, opp_race, team_format, ` + classColumns + `,
+			games, wins, apm_sum, apm_games, eapm_sum, eapm_games
+		)
+		SELECT
+			f.player_key, f.race, f.opp_race, f.team_format, ` + classSelect + `,
+			COUNT(DISTINCT f.replay_id),
+			SUM(f.is_winner),` + apmSums + `
+		FROM player_game_facts f
+		JOIN replays r ON r.id = f.replay_id
+		WHERE ` + factScope + `
+		GROUP BY f.player_key, f.race, f.opp_race, f.team_format, ` + classGroupBy
+}
+
+func markerCountsSQL(factScope string) string {
+	return `
+		INSERT INTO player_marker_counts (
+			player_key, race, opp_race, team_format, ` + classColumns + `,
+			event_type, replays
+		)
+		SELECT
+			f.player_key, f.race, f.opp_race, f.team_format, ` + classSelect + `,
+			re.event_type,
+			COUNT(DISTINCT f.replay_id)
+		FROM player_game_facts f
+		JOIN replays r ON r.id = f.replay_id
+		JOIN replay_events re ON re.source_player_id = f.player_id AND re.replay_id = f.replay_id
+		WHERE ` + factScope + `
+			AND ` + summaryEventPredicate + `
+		GROUP BY f.player_key, f.race, f.opp_race, f.team_format, ` + classGroupBy + `, re.event_type`
+}
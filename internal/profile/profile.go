@@ -42,11 +42,16 @@ func ModeFromEnv(v string) Mode {
 // not in this list still get accumulated but won't appear in the headline.
 var phaseOrder = []string{
 	"parse",
+	"unittags",
+	"earlyfilter",
+	"detect",
+	"analyze",
 	"replay_ins",
 	"players",
 	"cmds",
 	"events",
 	"patterns",
+	"aggregates",
 	"commit",
 }
 
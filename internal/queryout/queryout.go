@@ -0,0 +1,199 @@
+// Package queryout renders ad-hoc SQL results for the `screpdb query`
+// subcommand. Columns are always emitted in select order so scripted consumers
+// (shell pipelines, weekly report jobs) get stable output across runs.
+package queryout
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Output formats accepted by Write.
+const (
+	FormatTable  = "table"
+	FormatCSV    = "csv"
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+)
+
+// Formats lists every supported format, in the order shown in help text.
+var Formats = []string{FormatTable, FormatCSV, FormatJSON, FormatNDJSON}
+
+// Write renders columns/rows to w in the given format. rows[i] must hold one
+// value per column, in column order.
+func Write(w io.Writer, format string, columns []string, rows [][]any) error {
+	switch format {
+	case FormatTable:
+		return writeTable(w, columns, rows)
+	case FormatCSV:
+		return writeCSV(w, columns, rows)
+	case FormatJSON:
+		return writeJSON(w, columns, rows)
+	case FormatNDJSON:
+		return writeNDJSON(w, columns, rows)
+	default:
+		return fmt.Errorf("unknown output format %q (want one of %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+// writeTable prints a psql-style aligned table followed by a row count.
+func writeTable(w io.Writer, columns []string, rows [][]any) error {
+	cells := make([][]string, len(rows))
+	widths := make([]int, len(columns))
+	for i, col := range columns {
+		widths[i] = utf8.RuneCountInString(col)
+	}
+	for r, row := range rows {
+		cells[r] = make([]string, len(columns))
+		for i := range columns {
+			s := "NULL"
+			if row[i] != nil {
+				s = strings.ReplaceAll(textValue(row[i]), "\n", `\n`)
+			}
+			cells[r][i] = s
+			if n := utf8.RuneCountInString(s); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	var b strings.Builder
+	writeLine := func(values []string) {
+		for i, v := range values {
+			if i > 0 {
+				b.WriteString(" | ")
+			}
+			b.WriteString(v)
+			if i < len(values)-1 {
+				b.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(v)))
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	writeLine(columns)
+	for i, width := range widths {
+		if i > 0 {
+			b.WriteString("-+-")
+		}
+		b.WriteString(strings.Repeat("-", width))
+	}
+	b.WriteString("\n")
+	for _, row := range cells {
+		writeLine(row)
+	}
+	if len(rows) == 1 {
+		b.WriteString("(1 row)\n")
+	} else {
+		fmt.Fprintf(&b, "(%d rows)\n", len(rows))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeCSV prints an RFC 4180 header row plus one record per row. NULL is
+// rendered as an empty field.
+func writeCSV(w io.Writer, columns []string, rows [][]any) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	record := make([]string, len(columns))
+	for _, row := range rows {
+		for i := range columns {
+			record[i] = ""
+			if row[i] != nil {
+				record[i] = textValue(row[i])
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeJSON prints a single JSON array of objects. An empty result is "[]".
+func writeJSON(w io.Writer, columns []string, rows [][]any) error {
+	var b bytes.Buffer
+	b.WriteString("[")
+	for r, row := range rows {
+		if r > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n  ")
+		if err := appendObject(&b, columns, row); err != nil {
+			return err
+		}
+	}
+	if len(rows) > 0 {
+		b.WriteString("\n")
+	}
+	b.WriteString("]\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// writeNDJSON prints one JSON object per line.
+func writeNDJSON(w io.Writer, columns []string, rows [][]any) error {
+	var b bytes.Buffer
+	for _, row := range rows {
+		if err := appendObject(&b, columns, row); err != nil {
+			return err
+		}
+		b.WriteString("\n")
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// appendObject writes row as a JSON object whose keys follow column order
+// (encoding/json would sort map keys alphabetically).
+func appendObject(b *bytes.Buffer, columns []string, row []any) error {
+	b.WriteString("{")
+	for i, col := range columns {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		key, err := json.Marshal(col)
+		if err != nil {
+			return err
+		}
+		value, err := json.Marshal(row[i])
+		if err != nil {
+			return fmt.Errorf("encode column %q: %w", col, err)
+		}
+		b.Write(key)
+		b.WriteString(":")
+		b.Write(value)
+	}
+	b.WriteString("}")
+	return nil
+}
+
+// textValue renders a non-NULL scanned SQLite value for the text formats.
+func textValue(v any) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	case time.Time:
+		return x.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(x)
+	}
+}
@@ -0,0 +1,85 @@
+package queryout
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+var (
+	testColumns = []string{"name", "games", "win_rate"}
+	testRows    = [][]any{
+		{"Soma", int64(12), 0.5},
+		{"Jy, \"the\" Zerg", int64(3), nil},
+	}
+)
+
+func render(t *testing.T, format string) string {
+	t.Helper()
+	var b bytes.Buffer
+	if err := Write(&b, format, testColumns, testRows); err != nil {
+		t.Fatalf("Write(%s): %v", format, err)
+	}
+	return b.String()
+}
+
+func TestWriteTable(t *testing.T) {
+	want := "" +
+		"name           | games | win_rate\n" +
+		"---------------+-------+---------\n" +
+		"Soma           | 12    | 0.5\n" +
+		"Jy, \"the\" Zerg | 3     | NULL\n" +
+		"(2 rows)\n"
+	if got := render(t, FormatTable); got != want {
+		t.Fatalf("table output mismatch:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	want := "name,games,win_rate\nSoma,12,0.5\n\"Jy, \"\"the\"\" Zerg\",3,\n"
+	if got := render(t, FormatCSV); got != want {
+		t.Fatalf("csv output = %q, want %q", got, want)
+	}
+}
+
+func TestWriteJSONPreservesColumnOrder(t *testing.T) {
+	got := render(t, FormatJSON)
+	if !strings.Contains(got, `{"name":"Soma","games":12,"win_rate":0.5}`) {
+		t.Fatalf("json output lost column order: %s", got)
+	}
+	var decoded []map[string]any
+	if err := json.Unmarshal([]byte(got), &decoded); err != nil {
+		t.Fatalf("json output is not valid JSON: %v\n%s", err, got)
+	}
+	if len(decoded) != 2 || decoded[1]["win_rate"] != nil {
+		t.Fatalf("unexpected decoded rows: %v", decoded)
+	}
+
+	var empty bytes.Buffer
+	if err := Write(&empty, FormatJSON, testColumns, nil); err != nil {
+		t.Fatalf("Write empty: %v", err)
+	}
+	if empty.String() != "[]\n" {
+		t.Fatalf("empty json = %q, want []", empty.String())
+	}
+}
+
+func TestWriteNDJSON(t *testing.T) {
+	lines := strings.Split(strings.TrimSuffix(render(t, FormatNDJSON), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d ndjson lines, want 2", len(lines))
+	}
+	for _, line := range lines {
+		var obj map[string]any
+		if err := json.Unmarshal([]byte(line), &obj); err != nil {
+			t.Fatalf("line %q is not a JSON object: %v", line, err)
+		}
+	}
+}
+
+func TestWriteUnknownFormat(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, "xml", testColumns, testRows); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
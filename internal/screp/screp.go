@@ -5,12 +5,25 @@ import (
 
 	"github.com/icza/screp/rep"
 	"github.com/icza/screp/repparser"
+	"github.com/marianogappa/screpdb/internal/fileops"
 )
 
-// ParseFile parses a StarCraft: Brood War replay file using the real screp library
+// ParseFile parses a StarCraft: Brood War replay file using the real screp library.
+// filePath may be an archive entry path (see fileops.ArchiveSeparator), in which
+// case the entry is read into memory and parsed from there.
 func ParseFile(filePath string) (*rep.Replay, error) {
-	// Parse the replay file using the real screp library
-	replay, err := repparser.ParseFile(filePath)
+	var replay *rep.Replay
+	var err error
+	if _, _, ok := fileops.SplitArchivePath(filePath); ok {
+		var data []byte
+		if data, err = fileops.ReadReplayFile(filePath); err != nil {
+			return nil, fmt.Errorf("failed to read replay file: %w", err)
+		}
+		replay, err = repparser.Parse(data)
+	} else {
+		// Parse the replay file using the real screp library
+		replay, err = repparser.ParseFile(filePath)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse replay file: %w", err)
 	}
@@ -235,11 +235,12 @@ func registerDetectionScalars() {
 func registerUnitEconomics() {
 	Register(Section{
 		Key:   "24-unit-economics",
-		Title: "Early-game unit economics",
-		Intro: "What producing each early-game unit/building costs and does to supply. " +
+		Title: "Unit economics",
+		Intro: "What producing each unit/building costs and does to supply. The early " +
+			"filter uses the Tier-1 rows; the full-game economy estimate uses them all. " +
 			"Supply Δ is the cap increase from supply structures (Pylon/Depot/Overlord " +
-			"= +8); supply cost is what a unit consumes. Build times match the Build " +
-			"times section.",
+			"= +8; the economy estimate also adds town-hall supply); supply cost is what " +
+			"a unit consumes. Build times match the Build times section.",
 		Columns: []string{"Subject", "Minerals", "Gas", "Build time (s)", "Supply Δ", "Supply cost"},
 		Rows: func() [][]string {
 			var rows [][]string
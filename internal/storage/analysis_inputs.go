@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/marianogappa/screpdb/internal/analysisinput"
+	"github.com/marianogappa/screpdb/internal/models"
+)
+
+// ErrNoAnalysisInput is returned by LoadStoredReplayData for replays without
+// a usable replay_analysis_inputs row: ingested before it existed, or stored
+// in a format this version can't read.
+var ErrNoAnalysisInput = errors.New("replay has no stored analysis input")
+
+// insertAnalysisInputTx stores a replay's detection input.
+func insertAnalysisInputTx(ctx context.Context, db dbtx, replayID int64, in *analysisinput.Input) error {
+	payload, err := analysisinput.Encode(in)
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx,
+		"INSERT INTO replay_analysis_inputs (replay_id, format_version, payload) VALUES (?, ?, ?)",
+		replayID, analysisinput.FormatVersion, payload); err != nil {
+		return fmt.Errorf("failed to insert analysis input: %w", err)
+	}
+	return nil
+}
+
+// LoadStoredReplayData rebuilds a replay's detection input from the
+// database: the replay and player rows, and the analyzed command stream put
+// back together from both command tables (and the command blob, if any) and
+// the stored analysis input, which is returned alongside. Players and
+// commands carry their database IDs.
+func (s *SQLiteStorage) LoadStoredReplayData(ctx context.Context, replayID int64) (*models.ReplayData, *analysisinput.Input, error) {
+	var formatVersion int
+	var payload []byte
+	err := s.db.QueryRowContext(ctx,
+		"SELECT format_version, payload FROM replay_analysis_inputs WHERE replay_id = ?", replayID).Scan(&formatVersion, &payload)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrNoAnalysisInput
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read analysis input: %w", err)
+	}
+	in, err := analysisinput.Decode(formatVersion, payload)
+	if errors.Is(err, analysisinput.ErrUnsupportedFormat) {
+		return nil, nil, fmt.Errorf("%w: %v", ErrNoAnalysisInput, err)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	replay, err := s.loadStoredReplay(ctx, replayID)
+	if err != nil {
+		return nil, nil, err
+	}
+	players, err := s.loadStoredPlayers(ctx, replay, in.PlayerIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	replay.Players = players
+
+	playersByDBID := make(map[int64]*models.Player, len(players))
+	playersByID := make(map[byte]*models.Player, len(players))
+	for _, p := range players {
+		playersByDBID[p.ID] = p
+		playersByID[p.PlayerID] = p
+	}
+	commands, err := s.loadStoredCommands(ctx, "commands", replay, playersByDBID)
+	if err != nil {
+		return nil, nil, err
+	}
+	lowValue, err := s.loadStoredLowValueCommands(ctx, replay, playersByDBID)
+	if err != nil {
+		return nil, nil, err
+	}
+	stream, err := in.Rebuild(commands, lowValue, playersByID, replay)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to rebuild command stream of replay %d: %w", replayID, err)
+	}
+
+	return &models.ReplayData{
+		Replay:   replay,
+		Players:  players,
+		Commands: stream,
+	}, in, nil
+}
+
+// loadStoredReplay reads a replays row back into a models.Replay.
+func (s *SQLiteStorage) loadStoredReplay(ctx context.Context, replayID int64) (*models.Replay, error) {
+	r := &models.Replay{ID: replayID}
+	var createdAt, replayDate, homeTeamSize string
+	var title, host sql.NullString
+	var mapWidth, mapHeight int64
+	var availSlots int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT file_path, file_checksum, file_name, created_at, replay_date, title, host, map_name, map_width, map_height,
+			duration_seconds, frame_count, engine_version, engine, game_speed, game_type, map_kind, team_format, matchup,
+			home_team_size, avail_slots_count, team_stacking, team_info_incomplete, game_fingerprint
+		FROM replays WHERE id = ?`, replayID).Scan(
+		&r.FilePath, &r.FileChecksum, &r.FileName, &createdAt, &replayDate, &title, &host, &r.MapName, &mapWidth, &mapHeight,
+		&r.DurationSeconds, &r.FrameCount, &r.EngineVersion, &r.Engine, &r.GameSpeed, &r.GameType, &r.MapKind, &r.TeamFormat, &r.Matchup,
+		&homeTeamSize, &availSlots, &r.TeamStacking, &r.TeamInfoIncomplete, &r.GameFingerprint,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read replay %d: %w", replayID, err)
+	}
+	r.CreatedAt, _ = parseReplayDate(createdAt)
+	r.ReplayDate, _ = parseReplayDate(replayDate)
+	r.Title = title.String
+	r.Host = host.String
+	r.MapWidth = uint16(mapWidth)
+	r.MapHeight = uint16(mapHeight)
+	r.AvailSlotsCount = byte(availSlots)
+	if n, err := strconv.ParseUint(homeTeamSize, 10, 16); err == nil {
+		r.HomeTeamSize = uint16(n)
+	}
+	return r, nil
+}
+
+// loadStoredPlayers reads a replay's players rows, restoring their in-replay
+// player IDs from playerIDs (keyed by slot).
+func (s *SQLiteStorage) loadStoredPlayers(ctx context.Context, replay *models.Replay, playerIDs map[uint16]byte) ([]*models.Player, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, slot_id, name, race, type, color, team, is_observer, apm, eapm, is_winner,
+			start_location_x, start_location_y, start_location_oclock
+		FROM players WHERE replay_id = ? ORDER BY id`, replay.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query players: %w", err)
+	}
+	defer rows.Close()
+
+	var players []*models.Player
+	for rows.Next() {
+		p := &models.Player{ReplayID: replay.ID, Replay: replay}
+		var slot, team int64
+		if err := rows.Scan(&p.ID, &slot, &p.Name, &p.Race, &p.Type, &p.Color, &team, &p.IsObserver, &p.APM, &p.EAPM, &p.IsWinner,
+			&p.StartLocationX, &p.StartLocationY, &p.StartLocationOclock); err != nil {
+			return nil, fmt.Errorf("failed to scan player: %w", err)
+		}
+		p.SlotID = uint16(slot)
+		p.Team = byte(team)
+		pid, ok := playerIDs[p.SlotID]
+		if !ok {
+			return nil, fmt.Errorf("analysis input has no player ID for slot %d", p.SlotID)
+		}
+		p.PlayerID = pid
+		players = append(players, p)
+	}
+	return players, rows.Err()
+}
+
+// loadStoredCommands reads a replay's rows of a command table, in insertion
+// order.
+func (s *SQLiteStorage) loadStoredCommands(ctx context.Context, table string, replay *models.Replay, playersByDBID map[int64]*models.Player) ([]*models.Command, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT id, player_id, frame, seconds_from_game_start, action_type,
+			x, y, is_queued, order_name, unit_type, unit_types, tech_name, upgrade_name,
+			hotkey_type, hotkey_group, game_speed, vision_player_ids, alliance_player_ids,
+			is_allied_victory, general_data, chat_message, leave_reason
+		FROM %s WHERE replay_id = ? ORDER BY id`, table), replay.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var commands []*models.Command
+	for rows.Next() {
+		c := &models.Command{ReplayID: replay.ID, Replay: replay}
+		var hotkeyGroup *int64
+		var visionIDs, allianceIDs *string
+		if err := rows.Scan(&c.ID, &c.PlayerID, &c.Frame, &c.SecondsFromGameStart, &c.ActionType,
+			&c.X, &c.Y, &c.IsQueued, &c.OrderName, &c.UnitType, &c.UnitTypes, &c.TechName, &c.UpgradeName,
+			&c.HotkeyType, &hotkeyGroup, &c.GameSpeed, &visionIDs, &allianceIDs,
+			&c.IsAlliedVictory, &c.GeneralData, &c.ChatMessage, &c.LeaveReason); err != nil {
+			return nil, fmt.Errorf("failed to scan %s row: %w", table, err)
+		}
+		c.Player = playersByDBID[c.PlayerID]
+		if c.Player == nil {
+			return nil, fmt.Errorf("%s row %d references unknown player %d", table, c.ID, c.PlayerID)
+		}
+		if hotkeyGroup != nil {
+			g := byte(*hotkeyGroup)
+			c.HotkeyGroup = &g
+		}
+		if c.VisionPlayerIDs, err = decodeInt64ArrayJSON(visionIDs); err != nil {
+			return nil, fmt.Errorf("failed to decode vision_player_ids of %s row %d: %w", table, c.ID, err)
+		}
+		if c.AlliancePlayerIDs, err = decodeInt64ArrayJSON(allianceIDs); err != nil {
+			return nil, fmt.Errorf("failed to decode alliance_player_ids of %s row %d: %w", table, c.ID, err)
+		}
+		commands = append(commands, c)
+	}
+	return commands, rows.Err()
+}
+
+// decodeInt64ArrayJSON reverses encodeInt64ArrayJSON; NULL stays nil.
+func decodeInt64ArrayJSON(s *string) (*[]int64, error) {
+	if s == nil {
+		return nil, nil
+	}
+	var ids []int64
+	if err := json.Unmarshal([]byte(*s), &ids); err != nil {
+		return nil, err
+	}
+	return &ids, nil
+}
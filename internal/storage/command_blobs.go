@@ -0,0 +1,426 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/marianogappa/screpdb/internal/cmdblob"
+	"github.com/marianogappa/screpdb/internal/models"
+)
+
+// compactActionTypes are the commands_low_value action types that compact
+// storage moves into command_blobs: the bulk of the table, and none of what
+// the dashboard queries. The rest of commands_low_value stays rows.
+var compactActionTypes = enumSetFromNames([]string{
+	"Right Click",
+	"Hotkey",
+	"Minimap Ping",
+})
+
+// compactActionTypesSQL is compactActionTypes as a SQL list.
+const compactActionTypesSQL = `('Right Click', 'Hotkey', 'Minimap Ping')`
+
+// CompactStats summarizes one CompactCommands or ExpandCommands call.
+type CompactStats struct {
+	Replays int64 // replays converted
+	Rows    int64 // commands_low_value rows moved into or out of blobs
+}
+
+func widenInt32(v *int32) *int64 {
+	if v == nil {
+		return nil
+	}
+	w := int64(*v)
+	return &w
+}
+
+// insertCommandBlobTx stores a replay's compacted commands.
+func insertCommandBlobTx(ctx context.Context, db dbtx, replayID int64, rows []cmdblob.Row) error {
+	payload, err := cmdblob.Encode(rows)
+	if err != nil {
+		return fmt.Errorf("failed to encode command blob: %w", err)
+	}
+	if _, err := db.ExecContext(ctx,
+		"INSERT INTO command_blobs (replay_id, format_version, row_count, payload) VALUES (?, ?, ?, ?)",
+		replayID, cmdblob.FormatVersion, len(rows), payload); err != nil {
+		return fmt.Errorf("failed to insert command blob: %w", err)
+	}
+	return nil
+}
+
+// loadCommandBlob returns a replay's compacted commands, or none if it has no
+// blob.
+func loadCommandBlob(ctx context.Context, db dbtx, replayID int64) ([]cmdblob.Row, error) {
+	rows, err := db.QueryContext(ctx, "SELECT format_version, payload FROM command_blobs WHERE replay_id = ?", replayID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read command blob: %w", err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	var formatVersion int
+	var payload []byte
+	if err := rows.Scan(&formatVersion, &payload); err != nil {
+		return nil, fmt.Errorf("failed to read command blob: %w", err)
+	}
+	blob, err := cmdblob.Decode(formatVersion, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode command blob of replay %d: %w", replayID, err)
+	}
+	return blob, nil
+}
+
+// interleaveBlobRows puts blob rows back among a replay's commands_low_value
+// rows (in insertion order) at their ordinals.
+func interleaveBlobRows[T any](tableRows []T, blob []cmdblob.Row, fromBlob func(cmdblob.Row) T) ([]T, error) {
+	out := make([]T, 0, len(tableRows)+len(blob))
+	for _, b := range blob {
+		for int64(len(out)) < b.Ordinal {
+			if len(tableRows) == 0 {
+				return nil, fmt.Errorf("command blob row at ordinal %d follows only %d commands_low_value rows", b.Ordinal, len(out))
+			}
+			out = append(out, tableRows[0])
+			tableRows = tableRows[1:]
+		}
+		out = append(out, fromBlob(b))
+	}
+	return append(out, tableRows...), nil
+}
+
+// commandFromBlobRow is loadStoredCommands' models.Command for a blob row.
+func commandFromBlobRow(r cmdblob.Row, replay *models.Replay, playersByDBID map[int64]*models.Player) (*models.Command, error) {
+	c := &models.Command{
+		ReplayID:             replay.ID,
+		Replay:               replay,
+		PlayerID:             r.PlayerID,
+		Player:               playersByDBID[r.PlayerID],
+		Frame:                int32(r.Frame),
+		SecondsFromGameStart: int(r.Second),
+		ActionType:           r.ActionType,
+		IsQueued:             r.IsQueued,
+		OrderName:            r.OrderName,
+		UnitType:             r.UnitType,
+		UnitTypes:            r.UnitTypes,
+		TechName:             r.TechName,
+		UpgradeName:          r.UpgradeName,
+		HotkeyType:           r.HotkeyType,
+		GameSpeed:            r.GameSpeed,
+		IsAlliedVictory:      r.IsAlliedVictory,
+		GeneralData:          r.GeneralData,
+		ChatMessage:          r.ChatMessage,
+		LeaveReason:          r.LeaveReason,
+	}
+	if c.Player == nil {
+		return nil, fmt.Errorf("command blob row %d references unknown player %d", r.Ordinal, r.PlayerID)
+	}
+	if r.X != nil {
+		x := int(*r.X)
+		c.X = &x
+	}
+	if r.Y != nil {
+		y := int(*r.Y)
+		c.Y = &y
+	}
+	if r.HotkeyGroup != nil {
+		g := byte(*r.HotkeyGroup)
+		c.HotkeyGroup = &g
+	}
+	var err error
+	if c.VisionPlayerIDs, err = decodeInt64ArrayJSON(r.VisionPlayerIDs); err != nil {
+		return nil, fmt.Errorf("failed to decode vision_player_ids of command blob row %d: %w", r.Ordinal, err)
+	}
+	if c.AlliancePlayerIDs, err = decodeInt64ArrayJSON(r.AlliancePlayerIDs); err != nil {
+		return nil, fmt.Errorf("failed to decode alliance_player_ids of command blob row %d: %w", r.Ordinal, err)
+	}
+	return c, nil
+}
+
+// loadStoredLowValueCommands reads a replay's low-value commands in insertion
+// order, from commands_low_value and its command blob alike.
+func (s *SQLiteStorage) loadStoredLowValueCommands(ctx context.Context, replay *models.Replay, playersByDBID map[int64]*models.Player) ([]*models.Command, error) {
+	commands, err := s.loadStoredCommands(ctx, "commands_low_value", replay, playersByDBID)
+	if err != nil {
+		return nil, err
+	}
+	blob, err := loadCommandBlob(ctx, s.db, replay.ID)
+	if err != nil || len(blob) == 0 {
+		return commands, err
+	}
+	var convErr error
+	commands, err = interleaveBlobRows(commands, blob, func(r cmdblob.Row) *models.Command {
+		c, err := commandFromBlobRow(r, replay, playersByDBID)
+		if err != nil && convErr == nil {
+			convErr = err
+		}
+		return c
+	})
+	if err != nil {
+		return nil, err
+	}
+	return commands, convErr
+}
+
+// loadLowValueRowsTx reads a replay's commands_low_value rows in insertion
+// order, numbering them from 0.
+func loadLowValueRowsTx(ctx context.Context, tx *sql.Tx, replayID int64) ([]cmdblob.Row, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT player_id, frame, seconds_from_game_start, action_type,
+			x, y, is_queued, order_name, unit_type, unit_types, tech_name, upgrade_name,
+			hotkey_type, hotkey_group, game_speed, vision_player_ids, alliance_player_ids,
+			is_allied_victory, general_data, chat_message, leave_reason
+		FROM commands_low_value WHERE replay_id = ? ORDER BY id`, replayID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query commands_low_value: %w", err)
+	}
+	defer rows.Close()
+	var out []cmdblob.Row
+	for rows.Next() {
+		r := cmdblob.Row{Ordinal: int64(len(out))}
+		if err := rows.Scan(&r.PlayerID, &r.Frame, &r.Second, &r.ActionType,
+			&r.X, &r.Y, &r.IsQueued, &r.OrderName, &r.UnitType, &r.UnitTypes, &r.TechName, &r.UpgradeName,
+			&r.HotkeyType, &r.HotkeyGroup, &r.GameSpeed, &r.VisionPlayerIDs, &r.AlliancePlayerIDs,
+			&r.IsAlliedVictory, &r.GeneralData, &r.ChatMessage, &r.LeaveReason); err != nil {
+			return nil, fmt.Errorf("failed to scan commands_low_value row: %w", err)
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// replayIDsTx runs query, which selects replay IDs, to completion.
+func replayIDsTx(ctx context.Context, db dbtx, query string) ([]int64, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// CompactCommands converts stored replays to compact command storage: the
+// compactActionTypes rows of each replay without a command blob move from
+// commands_low_value into one. Each replay converts in its own transaction,
+// so an interrupted run keeps the replays it finished. The file doesn't
+// shrink until it is vacuumed.
+func (s *SQLiteStorage) CompactCommands(ctx context.Context) (CompactStats, error) {
+	var stats CompactStats
+	ids, err := replayIDsTx(ctx, s.db, `
+		SELECT DISTINCT replay_id FROM commands_low_value
+		WHERE action_type IN `+compactActionTypesSQL+`
+			AND replay_id NOT IN (SELECT replay_id FROM command_blobs)
+		ORDER BY replay_id`)
+	if err != nil {
+		return stats, fmt.Errorf("failed to list replays to compact: %w", err)
+	}
+	for _, id := range ids {
+		n, err := s.compactReplay(ctx, id)
+		if err != nil {
+			return stats, fmt.Errorf("failed to compact replay %d: %w", id, err)
+		}
+		stats.Replays++
+		stats.Rows += n
+	}
+	return stats, nil
+}
+
+func (s *SQLiteStorage) compactReplay(ctx context.Context, replayID int64) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	lowValue, err := loadLowValueRowsTx(ctx, tx, replayID)
+	if err != nil {
+		return 0, err
+	}
+	var blob []cmdblob.Row
+	for _, r := range lowValue {
+		if _, ok := compactActionTypes[r.ActionType]; ok {
+			blob = append(blob, r)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM commands_low_value WHERE replay_id = ? AND action_type IN "+compactActionTypesSQL, replayID); err != nil {
+		return 0, fmt.Errorf("failed to delete compacted commands_low_value rows: %w", err)
+	}
+	if err := insertCommandBlobTx(ctx, tx, replayID, blob); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit: %w", err)
+	}
+	return int64(len(blob)), nil
+}
+
+// ExpandCommands reverses CompactCommands: every command blob goes back to
+// commands_low_value rows, in their original order, and is deleted.
+func (s *SQLiteStorage) ExpandCommands(ctx context.Context) (CompactStats, error) {
+	var stats CompactStats
+	ids, err := replayIDsTx(ctx, s.db, "SELECT replay_id FROM command_blobs ORDER BY replay_id")
+	if err != nil {
+		return stats, fmt.Errorf("failed to list compacted replays: %w", err)
+	}
+	for _, id := range ids {
+		n, err := s.expandReplay(ctx, id)
+		if err != nil {
+			return stats, fmt.Errorf("failed to expand replay %d: %w", id, err)
+		}
+		stats.Replays++
+		stats.Rows += n
+	}
+	return stats, nil
+}
+
+// expandReplay rewrites a replay's commands_low_value rows with its blob rows
+// among them. The rows kept in the table are rewritten too, since new rows
+// only sort after them by id.
+func (s *SQLiteStorage) expandReplay(ctx context.Context, replayID int64) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	blob, err := loadCommandBlob(ctx, tx, replayID)
+	if err != nil {
+		return 0, err
+	}
+	rows, err := loadLowValueRowsTx(ctx, tx, replayID)
+	if err != nil {
+		return 0, err
+	}
+	rows, err = interleaveBlobRows(rows, blob, func(r cmdblob.Row) cmdblob.Row { return r })
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM commands_low_value WHERE replay_id = ?", replayID); err != nil {
+		return 0, fmt.Errorf("failed to delete commands_low_value rows: %w", err)
+	}
+	w, err := newCommandWriter(ctx, tx, "commands_low_value", s.commandBatchRows)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare low-value command insert statement: %w", err)
+	}
+	defer w.Close()
+	for _, r := range rows {
+		if err := w.Add(ctx, replayID, r.PlayerID, r.Frame, r.Second, r.ActionType,
+			r.X, r.Y, r.IsQueued, r.OrderName, r.UnitType, r.UnitTypes, r.TechName, r.UpgradeName,
+			r.HotkeyType, r.HotkeyGroup, r.GameSpeed, r.VisionPlayerIDs, r.AlliancePlayerIDs,
+			r.IsAlliedVictory, r.GeneralData, r.ChatMessage, r.LeaveReason); err != nil {
+			return 0, fmt.Errorf("failed to insert commands_low_value row: %w", err)
+		}
+	}
+	if err := w.Flush(ctx); err != nil {
+		return 0, fmt.Errorf("failed to insert commands_low_value row: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM command_blobs WHERE replay_id = ?", replayID); err != nil {
+		return 0, fmt.Errorf("failed to delete command blob: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit: %w", err)
+	}
+	return int64(len(blob)), nil
+}
+
+// mergeCommandBlobsTx copies the command blobs of the replays MergeFrom
+// copied, remapping the player IDs inside them. Ordinals need no remapping:
+// commands_low_value rows are copied in order.
+func mergeCommandBlobsTx(ctx context.Context, tx *sql.Tx, stats *MergeStats) error {
+	var hasBlobs int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+mergeAttachName+".sqlite_master WHERE type = 'table' AND name = 'command_blobs'").Scan(&hasBlobs); err != nil {
+		return fmt.Errorf("failed to check source command blobs: %w", err)
+	}
+	if hasBlobs == 0 {
+		return nil
+	}
+	playerMap := map[int64]int64{}
+	rows, err := tx.QueryContext(ctx, "SELECT old_id, new_id FROM temp.merge_player_map")
+	if err != nil {
+		return fmt.Errorf("failed to read merged players: %w", err)
+	}
+	for rows.Next() {
+		var oldID, newID int64
+		if err := rows.Scan(&oldID, &newID); err != nil {
+			rows.Close()
+			return err
+		}
+		playerMap[oldID] = newID
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	type replayPair struct{ oldID, newID int64 }
+	rows, err = tx.QueryContext(ctx, "SELECT b.replay_id, rm.new_id FROM "+mergeAttachName+".command_blobs b JOIN temp.merge_replay_map rm ON rm.old_id = b.replay_id ORDER BY b.replay_id")
+	if err != nil {
+		return fmt.Errorf("failed to list source command blobs: %w", err)
+	}
+	var pairs []replayPair
+	for rows.Next() {
+		var p replayPair
+		if err := rows.Scan(&p.oldID, &p.newID); err != nil {
+			rows.Close()
+			return err
+		}
+		pairs = append(pairs, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, p := range pairs {
+		var formatVersion int
+		var payload []byte
+		if err := tx.QueryRowContext(ctx, "SELECT format_version, payload FROM "+mergeAttachName+".command_blobs WHERE replay_id = ?", p.oldID).Scan(&formatVersion, &payload); err != nil {
+			return fmt.Errorf("failed to read command blob of replay %d: %w", p.oldID, err)
+		}
+		blob, err := cmdblob.Decode(formatVersion, payload)
+		if err != nil {
+			return fmt.Errorf("failed to decode command blob of replay %d: %w", p.oldID, err)
+		}
+		for i := range blob {
+			newID, ok := playerMap[blob[i].PlayerID]
+			if !ok {
+				return fmt.Errorf("command blob of replay %d references unknown player %d", p.oldID, blob[i].PlayerID)
+			}
+			blob[i].PlayerID = newID
+		}
+		if err := insertCommandBlobTx(ctx, tx, p.newID, blob); err != nil {
+			return err
+		}
+		stats.CommandBlobs++
+	}
+	return nil
+}
+
+// Vacuum rebuilds the database file so the space freed by CompactCommands is
+// returned to the filesystem, and reports the database size before and after.
+func (s *SQLiteStorage) Vacuum(ctx context.Context) (before, after int64, err error) {
+	size := func() (int64, error) {
+		var n int64
+		err := s.db.QueryRowContext(ctx, "SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()").Scan(&n)
+		return n, err
+	}
+	if before, err = size(); err != nil {
+		return 0, 0, fmt.Errorf("failed to read database size: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, "VACUUM"); err != nil {
+		return 0, 0, fmt.Errorf("failed to vacuum: %w", err)
+	}
+	if after, err = size(); err != nil {
+		return 0, 0, fmt.Errorf("failed to read database size: %w", err)
+	}
+	return before, after, nil
+}
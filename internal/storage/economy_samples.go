@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/marianogappa/screpdb/internal/models"
+)
+
+// insertEconomySamplesTx stores a replay's estimated economy timeline.
+// playerIDMap maps replay-local player IDs to database IDs; samples of
+// players it lacks are dropped.
+func insertEconomySamplesTx(ctx context.Context, db dbtx, replayID int64, samples []models.EconomySample, playerIDMap map[byte]int64) error {
+	const batchSize = 500
+	for i := 0; i < len(samples); i += batchSize {
+		batch := samples[i:min(i+batchSize, len(samples))]
+		valueStrings := make([]string, 0, len(batch))
+		valueArgs := make([]any, 0, len(batch)*11)
+		for _, sample := range batch {
+			playerID, ok := playerIDMap[sample.PlayerID]
+			if !ok {
+				continue
+			}
+			valueStrings = append(valueStrings, "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+			valueArgs = append(valueArgs,
+				replayID,
+				playerID,
+				sample.Second,
+				sample.Minerals,
+				sample.Gas,
+				sample.MineralIncome,
+				sample.GasIncome,
+				sample.SupplyUsed,
+				sample.SupplyMax,
+				sample.Workers,
+				sample.Bases,
+			)
+		}
+		if len(valueStrings) == 0 {
+			continue
+		}
+		query := `
+			INSERT INTO player_economy_samples (
+				replay_id, player_id, second, minerals, gas, mineral_income,
+				gas_income, supply_used, supply_max, workers, bases
+			) VALUES ` + strings.Join(valueStrings, ", ")
+		if _, err := db.ExecContext(ctx, query, valueArgs...); err != nil {
+			return fmt.Errorf("failed to insert economy samples: %w", err)
+		}
+	}
+	return nil
+}
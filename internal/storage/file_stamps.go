@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/marianogappa/screpdb/internal/fileops"
+)
+
+// fileStampArgs returns a replay file's stamp as replays.file_size and
+// replays.file_mod_time values; unknown parts are NULL.
+func fileStampArgs(size int64, modTime time.Time) (any, any) {
+	var sizeArg, modTimeArg any
+	if size > 0 {
+		sizeArg = size
+	}
+	if !modTime.IsZero() {
+		modTimeArg = modTime.UnixNano()
+	}
+	return sizeArg, modTimeArg
+}
+
+// FilterOutUnchangedReplayFiles filters out files whose path is already known
+// to the database with the same size and modification time, which are taken
+// to be the file that was ingested without reading it. Rows ingested before
+// stamps were recorded match on path alone. Files that survive need hashing
+// and FilterOutExistingReplays, which also catches content that's known under
+// another path.
+func (s *SQLiteStorage) FilterOutUnchangedReplayFiles(ctx context.Context, files []fileops.FileInfo) ([]fileops.FileInfo, error) {
+	if len(files) == 0 {
+		return []fileops.FileInfo{}, nil
+	}
+
+	placeholders := make([]string, len(files))
+	args := make([]any, len(files))
+	for i, file := range files {
+		placeholders[i] = "?"
+		args[i] = file.Path
+	}
+
+	query := fmt.Sprintf(`SELECT file_path, file_size, file_mod_time FROM replays WHERE file_path IN (%s)`, strings.Join(placeholders, ", "))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query replay file stamps: %w", err)
+	}
+	defer rows.Close()
+
+	type stamp struct {
+		size, modTime sql.NullInt64
+	}
+	known := make(map[string]stamp, len(files))
+	for rows.Next() {
+		var path string
+		var st stamp
+		if err := rows.Scan(&path, &st.size, &st.modTime); err != nil {
+			return nil, fmt.Errorf("failed to scan replay file stamp: %w", err)
+		}
+		known[path] = st
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	filtered := make([]fileops.FileInfo, 0, len(files))
+	for _, file := range files {
+		st, ok := known[file.Path]
+		if ok && (!st.size.Valid || st.size.Int64 == file.Size) && (!st.modTime.Valid || st.modTime.Int64 == file.ModTime.UnixNano()) {
+			continue
+		}
+		filtered = append(filtered, file)
+	}
+	return filtered, nil
+}
+
+// RefreshReplayFileStamps records the current size and modification time of
+// hashed files whose path and checksum match an ingested replay: the file was
+// touched or copied but not changed, and the next run can skip it unread
+// again. Other files are ignored.
+func (s *SQLiteStorage) RefreshReplayFileStamps(ctx context.Context, files []fileops.FileInfo) error {
+	if len(files) == 0 {
+		return nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Only the stamps: the replay generation trigger skips updates that change
+	// them, so anything else set here would never reach the dashboard cache.
+	stmt, err := tx.PrepareContext(ctx, `UPDATE replays SET file_size = ?, file_mod_time = ? WHERE file_path = ? AND file_checksum = ?`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare file stamp update: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, file := range files {
+		if file.Checksum == "" {
+			continue
+		}
+		size, modTime := fileStampArgs(file.Size, file.ModTime)
+		if _, err := stmt.ExecContext(ctx, size, modTime, file.Path, file.Checksum); err != nil {
+			return fmt.Errorf("failed to refresh file stamp for %s: %w", file.Path, err)
+		}
+	}
+	return tx.Commit()
+}
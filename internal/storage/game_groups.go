@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GameGroupStartTolerance is how far apart the start times of two replays
+// with the same game fingerprint may be and still count as the same game.
+// POVs of one game agree on the start time up to the recorders' clock skew;
+// a rematch with identical lobby and opening starts minutes later at best.
+const GameGroupStartTolerance = 10 * time.Minute
+
+// replayDateLayouts are the formats replay_date has been stored in: the
+// driver writes time.Time values as time.String(), older rows and merged
+// databases may carry RFC 3339.
+var replayDateLayouts = []string{
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+}
+
+// parseReplayDate parses a stored replay_date, reporting whether it could.
+func parseReplayDate(s string) (time.Time, bool) {
+	// time.String() appends a monotonic clock reading ("m=+0.01") to
+	// times that carry one.
+	if i := strings.Index(s, " m="); i >= 0 {
+		s = s[:i]
+	}
+	for _, layout := range replayDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// groupMember is one replay considered by regroupFingerprintTx.
+type groupMember struct {
+	id         int64
+	start      time.Time
+	hasStart   bool
+	frameCount int64
+}
+
+// assignGameGroupTx puts a freshly stored replay into its game group. A
+// replay without a fingerprint is its own group; otherwise every replay
+// sharing its fingerprint is regrouped, since the newcomer may bridge or
+// outlast the existing members.
+func (s *SQLiteStorage) assignGameGroupTx(ctx context.Context, db dbtx, replayID int64, fingerprint string) error {
+	if fingerprint == "" {
+		if _, err := db.ExecContext(ctx, "UPDATE replays SET game_group_id = id WHERE id = ?", replayID); err != nil {
+			return fmt.Errorf("failed to set game group: %w", err)
+		}
+		return nil
+	}
+	return regroupFingerprintTx(ctx, db, fingerprint)
+}
+
+// regroupFingerprintTx recomputes the game groups of all replays sharing
+// fingerprint. Replays are clustered by start time (a member joins the
+// cluster when it starts within GameGroupStartTolerance of the previous
+// one); each cluster's canonical replay is its longest recording, lowest id
+// on ties, and every member's game_group_id is set to that id.
+func regroupFingerprintTx(ctx context.Context, db dbtx, fingerprint string) error {
+	rows, err := db.QueryContext(ctx,
+		"SELECT id, replay_date, frame_count FROM replays WHERE game_fingerprint = ? ORDER BY id", fingerprint)
+	if err != nil {
+		return fmt.Errorf("failed to query game group candidates: %w", err)
+	}
+	var members []groupMember
+	for rows.Next() {
+		var m groupMember
+		var date string
+		if err := rows.Scan(&m.id, &date, &m.frameCount); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan game group candidate: %w", err)
+		}
+		m.start, m.hasStart = parseReplayDate(date)
+		members = append(members, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, cluster := range clusterByStart(members) {
+		canonical := cluster[0]
+		for _, m := range cluster {
+			if m.frameCount > canonical.frameCount || (m.frameCount == canonical.frameCount && m.id < canonical.id) {
+				canonical = m
+			}
+		}
+		ids := make([]any, 0, len(cluster)+1)
+		ids = append(ids, canonical.id)
+		for _, m := range cluster {
+			ids = append(ids, m.id)
+		}
+		if _, err := db.ExecContext(ctx,
+			fmt.Sprintf("UPDATE replays SET game_group_id = ? WHERE id IN (%s)", strings.TrimSuffix(strings.Repeat("?, ", len(cluster)), ", ")),
+			ids...); err != nil {
+			return fmt.Errorf("failed to set game group: %w", err)
+		}
+	}
+	return nil
+}
+
+// clusterByStart splits replays sharing a fingerprint into games. Replays
+// whose start time can't be parsed can't be matched and stay alone.
+func clusterByStart(members []groupMember) [][]groupMember {
+	var clusters [][]groupMember
+	var dated []groupMember
+	for _, m := range members {
+		if m.hasStart {
+			dated = append(dated, m)
+		} else {
+			clusters = append(clusters, []groupMember{m})
+		}
+	}
+	sort.SliceStable(dated, func(i, j int) bool { return dated[i].start.Before(dated[j].start) })
+	for i, m := range dated {
+		if i > 0 && m.start.Sub(dated[i-1].start) <= GameGroupStartTolerance {
+			clusters[len(clusters)-1] = append(clusters[len(clusters)-1], m)
+			continue
+		}
+		clusters = append(clusters, []groupMember{m})
+	}
+	return clusters
+}
+
+// RegroupGames recomputes every replay's game_group_id from the stored
+// fingerprints and returns how many replays now share a group with another.
+func (s *SQLiteStorage) RegroupGames(ctx context.Context) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := regroupAllTx(ctx, tx); err != nil {
+		return 0, err
+	}
+	if err := s.refreshPlayerAggregatesTx(ctx, tx, nil); err != nil {
+		return 0, err
+	}
+	var grouped int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM replays
+		WHERE game_group_id IN (SELECT game_group_id FROM replays GROUP BY game_group_id HAVING COUNT(*) > 1)
+	`).Scan(&grouped); err != nil {
+		return 0, fmt.Errorf("failed to count grouped replays: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return grouped, nil
+}
+
+// regroupAllTx makes every replay without a fingerprint its own group and
+// regroups every fingerprint.
+func regroupAllTx(ctx context.Context, db dbtx) error {
+	if _, err := db.ExecContext(ctx, "UPDATE replays SET game_group_id = id WHERE game_fingerprint = ''"); err != nil {
+		return fmt.Errorf("failed to reset game groups: %w", err)
+	}
+	rows, err := db.QueryContext(ctx, "SELECT DISTINCT game_fingerprint FROM replays WHERE game_fingerprint <> ''")
+	if err != nil {
+		return fmt.Errorf("failed to list game fingerprints: %w", err)
+	}
+	var fingerprints []string
+	for rows.Next() {
+		var fp string
+		if err := rows.Scan(&fp); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan game fingerprint: %w", err)
+		}
+		fingerprints = append(fingerprints, fp)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, fp := range fingerprints {
+		if err := regroupFingerprintTx(ctx, db, fp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateGameFingerprint stores a re-computed fingerprint for a replay and
+// regroups both the fingerprint it leaves and the one it joins.
+func (s *SQLiteStorage) UpdateGameFingerprint(ctx context.Context, replayID int64, fingerprint string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var previous string
+	if err := tx.QueryRowContext(ctx, "SELECT game_fingerprint FROM replays WHERE id = ?", replayID).Scan(&previous); err != nil {
+		return fmt.Errorf("failed to read game fingerprint: %w", err)
+	}
+	if previous == fingerprint {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE replays SET game_fingerprint = ? WHERE id = ?", fingerprint, replayID); err != nil {
+		return fmt.Errorf("failed to update game fingerprint: %w", err)
+	}
+	if previous != "" {
+		if err := regroupFingerprintTx(ctx, tx, previous); err != nil {
+			return err
+		}
+	}
+	if err := s.assignGameGroupTx(ctx, tx, replayID, fingerprint); err != nil {
+		return err
+	}
+	if err := s.updatePlayerAggregatesTx(ctx, tx, nil, previous, fingerprint); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
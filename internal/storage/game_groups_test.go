@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/marianogappa/screpdb/internal/models"
+)
+
+func TestParseReplayDate(t *testing.T) {
+	want := time.Date(2026, 4, 17, 11, 52, 20, 0, time.UTC)
+	for _, in := range []string{
+		"2026-04-17 11:52:20 +0000 UTC",
+		"2026-04-17 11:52:20.000000001 +0000 UTC m=+0.012",
+		"2026-04-17T11:52:20Z",
+		"2026-04-17 11:52:20",
+	} {
+		got, ok := parseReplayDate(in)
+		if !ok {
+			t.Errorf("parseReplayDate(%q) failed", in)
+			continue
+		}
+		if got.Truncate(time.Second) != want {
+			t.Errorf("parseReplayDate(%q) = %v, want %v", in, got, want)
+		}
+	}
+	if _, ok := parseReplayDate("yesterday"); ok {
+		t.Error("parseReplayDate should reject garbage")
+	}
+}
+
+func TestGameGroups(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewSQLiteStorage(filepath.Join(t.TempDir(), "groups.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	if err := store.Initialize(ctx, true, true); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+
+	start := time.Date(2026, 4, 17, 11, 52, 20, 0, time.UTC)
+	insert := func(name, fingerprint string, startOffset time.Duration, frames int32) int64 {
+		t.Helper()
+		replay := &models.Replay{
+			FilePath:        "/replays/" + name,
+			FileChecksum:    name,
+			FileName:        name,
+			ReplayDate:      start.Add(startOffset),
+			FrameCount:      frames,
+			MapKind:         "Regular",
+			GameFingerprint: fingerprint,
+		}
+		if err := store.storeReplayWithBatching(ctx, &models.ReplayData{Replay: replay}); err != nil {
+			t.Fatalf("store %s: %v", name, err)
+		}
+		var id int64
+		if err := store.db.QueryRowContext(ctx, "SELECT id FROM replays WHERE file_name = ?", name).Scan(&id); err != nil {
+			t.Fatalf("look up %s: %v", name, err)
+		}
+		return id
+	}
+	groupOf := func(id int64) int64 {
+		t.Helper()
+		var group int64
+		if err := store.db.QueryRowContext(ctx, "SELECT game_group_id FROM replays WHERE id = ?", id).Scan(&group); err != nil {
+			t.Fatalf("game_group_id of %d: %v", id, err)
+		}
+		return group
+	}
+	expectGroups := func(step string, want map[int64]int64) {
+		t.Helper()
+		for id, group := range want {
+			if got := groupOf(id); got != group {
+				t.Errorf("%s: replay %d in group %d, want %d", step, id, got, group)
+			}
+		}
+	}
+
+	// The first POV recorded the shorter game; its opponent's longer POV,
+	// a few seconds of clock skew later, takes over as canonical.
+	first := insert("first.rep", "g1", 0, 1000)
+	expectGroups("alone", map[int64]int64{first: first})
+	longer := insert("longer.rep", "g1", 30*time.Second, 2000)
+	rematch := insert("rematch.rep", "g1", 2*time.Hour, 3000)
+	legacy := insert("legacy.rep", "", 0, 1000)
+	expectGroups("after ingest", map[int64]int64{first: longer, longer: longer, rematch: rematch, legacy: legacy})
+
+	if err := store.UpdateGameFingerprint(ctx, longer, "g2"); err != nil {
+		t.Fatalf("UpdateGameFingerprint: %v", err)
+	}
+	expectGroups("after refingerprint", map[int64]int64{first: first, longer: longer, rematch: rematch})
+
+	if _, err := store.db.ExecContext(ctx, "UPDATE replays SET game_group_id = NULL"); err != nil {
+		t.Fatalf("reset groups: %v", err)
+	}
+	if err := store.UpdateGameFingerprint(ctx, legacy, "g1"); err != nil {
+		t.Fatalf("UpdateGameFingerprint: %v", err)
+	}
+	grouped, err := store.RegroupGames(ctx)
+	if err != nil {
+		t.Fatalf("RegroupGames: %v", err)
+	}
+	if grouped != 2 {
+		t.Errorf("RegroupGames grouped %d replays, want 2", grouped)
+	}
+	expectGroups("after regroup", map[int64]int64{first: first, legacy: first, longer: longer, rematch: rematch})
+}
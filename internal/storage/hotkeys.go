@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/marianogappa/screpdb/internal/models"
+)
+
+// insertHotkeyUsageTx stores a replay's per-second hotkey group use.
+// playerIDMap maps replay-local player IDs to database IDs; rows of players it
+// lacks are dropped.
+func insertHotkeyUsageTx(ctx context.Context, db dbtx, replayID int64, usage []models.HotkeyUsage, playerIDMap map[byte]int64) error {
+	const batchSize = 500
+	for i := 0; i < len(usage); i += batchSize {
+		batch := usage[i:min(i+batchSize, len(usage))]
+		valueStrings := make([]string, 0, len(batch))
+		valueArgs := make([]any, 0, len(batch)*10)
+		for _, row := range batch {
+			playerID, ok := playerIDMap[row.PlayerID]
+			if !ok {
+				continue
+			}
+			valueStrings = append(valueStrings, "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+			valueArgs = append(valueArgs,
+				replayID,
+				playerID,
+				row.HotkeyGroup,
+				row.WindowEndSecond,
+				row.Second,
+				row.Role,
+				row.Assigns,
+				row.Selects,
+				row.Adds,
+				row.CameraJumps,
+			)
+		}
+		if len(valueStrings) == 0 {
+			continue
+		}
+		query := `
+			INSERT INTO hotkey_usage (
+				replay_id, player_id, hotkey_group, window_end_second, second,
+				role, assigns, selects, adds, camera_jumps
+			) VALUES ` + strings.Join(valueStrings, ", ")
+		if _, err := db.ExecContext(ctx, query, valueArgs...); err != nil {
+			return fmt.Errorf("failed to insert hotkey usage: %w", err)
+		}
+	}
+	return nil
+}
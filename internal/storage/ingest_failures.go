@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/marianogappa/screpdb/internal/fileops"
+)
+
+// Error classes recorded in the ingest_failures ledger.
+const (
+	IngestFailureParseError = "parse_error"
+	IngestFailurePanic      = "panic"
+	IngestFailureUMS        = "ums"
+)
+
+// IngestFailure is one ingest_failures row: a replay file that failed to
+// parse, panicked, or was skipped as UMS, as of its most recent attempt.
+type IngestFailure struct {
+	FilePath       string
+	FileChecksum   string
+	ErrorClass     string
+	Message        string
+	StackHash      string // panics only: groups failures that crashed in the same place
+	ScrepdbVersion string
+	Attempts       int
+	FirstFailedAt  string
+	LastFailedAt   string
+}
+
+// RecordIngestFailures upserts failures by file path. A file that already has
+// a row gets its attempt count bumped and every other column overwritten with
+// the latest attempt's values.
+func (s *SQLiteStorage) RecordIngestFailures(ctx context.Context, failures []IngestFailure) error {
+	if len(failures) == 0 {
+		return nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO ingest_failures (file_path, file_checksum, error_class, message, stack_hash, screpdb_version)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (file_path) DO UPDATE SET
+			file_checksum = excluded.file_checksum,
+			error_class = excluded.error_class,
+			message = excluded.message,
+			stack_hash = excluded.stack_hash,
+			screpdb_version = excluded.screpdb_version,
+			attempts = ingest_failures.attempts + 1,
+			last_failed_at = CURRENT_TIMESTAMP
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare ingest failure insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, f := range failures {
+		if _, err := stmt.ExecContext(ctx, f.FilePath, f.FileChecksum, f.ErrorClass, f.Message, f.StackHash, f.ScrepdbVersion); err != nil {
+			return fmt.Errorf("failed to record ingest failure for %s: %w", f.FilePath, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ListIngestFailures returns every ledger row, most recently failed first.
+func (s *SQLiteStorage) ListIngestFailures(ctx context.Context) ([]IngestFailure, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT file_path, file_checksum, error_class, message, stack_hash, screpdb_version,
+			attempts, first_failed_at, last_failed_at
+		FROM ingest_failures
+		ORDER BY last_failed_at DESC, id DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ingest failures: %w", err)
+	}
+	defer rows.Close()
+
+	failures := []IngestFailure{}
+	for rows.Next() {
+		var f IngestFailure
+		if err := rows.Scan(&f.FilePath, &f.FileChecksum, &f.ErrorClass, &f.Message, &f.StackHash, &f.ScrepdbVersion,
+			&f.Attempts, &f.FirstFailedAt, &f.LastFailedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan ingest failure: %w", err)
+		}
+		failures = append(failures, f)
+	}
+	return failures, rows.Err()
+}
+
+// FilterOutKnownIngestFailures drops files that already failed under version
+// with the same content. Those would only fail the same way again; a new
+// screpdb version or a replaced file makes them eligible once more.
+func (s *SQLiteStorage) FilterOutKnownIngestFailures(ctx context.Context, files []fileops.FileInfo, version string) ([]fileops.FileInfo, error) {
+	if len(files) == 0 {
+		return files, nil
+	}
+
+	placeholders := make([]string, len(files))
+	args := make([]any, 0, len(files)+1)
+	args = append(args, version)
+	for i, file := range files {
+		placeholders[i] = "?"
+		args = append(args, file.Path)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT file_path, file_checksum FROM ingest_failures
+		WHERE screpdb_version = ? AND file_path IN (%s)
+	`, strings.Join(placeholders, ", "))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ingest failures: %w", err)
+	}
+	defer rows.Close()
+
+	known := make(map[string]string, len(files))
+	for rows.Next() {
+		var path, checksum string
+		if err := rows.Scan(&path, &checksum); err != nil {
+			return nil, fmt.Errorf("failed to scan ingest failure: %w", err)
+		}
+		known[path] = checksum
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	filtered := make([]fileops.FileInfo, 0, len(files))
+	for _, file := range files {
+		if checksum, ok := known[file.Path]; !ok || checksum != file.Checksum {
+			filtered = append(filtered, file)
+		}
+	}
+	return filtered, nil
+}
+
+// ClearResolvedIngestFailures deletes ledger rows for files that are now in
+// the replays table, by path or by content, and returns how many it removed.
+func (s *SQLiteStorage) ClearResolvedIngestFailures(ctx context.Context) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM ingest_failures
+		WHERE file_path IN (SELECT file_path FROM replays)
+			OR (file_checksum <> '' AND file_checksum IN (SELECT file_checksum FROM replays))
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear resolved ingest failures: %w", err)
+	}
+	return res.RowsAffected()
+}
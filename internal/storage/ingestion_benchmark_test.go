@@ -6,11 +6,15 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"runtime/pprof"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/marianogappa/screpdb/internal/fileops"
+	"github.com/marianogappa/screpdb/internal/iofacade"
 	"github.com/marianogappa/screpdb/internal/parser"
 	"github.com/marianogappa/screpdb/internal/profile"
 )
@@ -24,6 +28,127 @@ import (
 // totals so results stay comparable as the corpus grows.
 func BenchmarkSQLiteIngestionCorpus(b *testing.B) {
 	ctx := context.Background()
+	files := benchCorpus(b)
+	corpusSize := len(files)
+
+	b.ResetTimer()
+	sink := profile.NewSink(profile.ModeSummary)
+	sink.SetWriter(io.Discard) // silence per-replay log spam during bench
+	var totalDur time.Duration
+	for i := 0; i < b.N; i++ {
+		store := newBenchStore(b, i)
+
+		iterStart := time.Now()
+		for idx := range files {
+			fileInfo := files[idx]
+
+			run := sink.Replay(fileInfo.Name)
+			replay := parser.CreateReplayFromFileInfo(fileInfo.Path, fileInfo.Name, fileInfo.Size, fileInfo.Checksum)
+			data, err := parser.ParseReplayWithOptions(fileInfo.Path, replay, parser.Options{Profile: run})
+			if err != nil {
+				_ = store.Close()
+				b.Fatalf("ParseReplay(%s): %v", fileInfo.Name, err)
+			}
+			data.Profile = run
+
+			if err := store.storeReplayWithBatching(ctx, data); err != nil {
+				_ = store.Close()
+				b.Fatalf("storeReplayWithBatching(%s): %v", fileInfo.Name, err)
+			}
+		}
+		totalDur += time.Since(iterStart)
+
+		if err := store.Close(); err != nil {
+			b.Fatalf("Close: %v", err)
+		}
+	}
+
+	ops := float64(b.N)
+	b.ReportMetric(totalDur.Seconds()/ops, fmt.Sprintf("s/ingest_%dreplays", corpusSize))
+	b.ReportMetric(totalDur.Seconds()/ops/float64(corpusSize), "s/replay")
+	reportPhaseMetrics(b, sink, ops)
+}
+
+// BenchmarkSQLiteIngestionPipeline compares the ingest pipeline shapes over
+// the same corpus as BenchmarkSQLiteIngestionCorpus. "sequential" parses and
+// analyzes one replay at a time and commits each in its own transaction, as
+// ingestion used to; "parallel" runs one parse worker per CPU core in front
+// of StartIngestion's batched writer. Compare their replays/s.
+func BenchmarkSQLiteIngestionPipeline(b *testing.B) {
+	files := benchCorpus(b)
+	for _, bc := range []struct {
+		name         string
+		workers      int
+		batchReplays int
+	}{
+		{name: "sequential", workers: 1, batchReplays: 1},
+		{name: "parallel", workers: runtime.GOMAXPROCS(0)},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			ctx := context.Background()
+			sink := profile.NewSink(profile.ModeSummary)
+			sink.SetWriter(io.Discard)
+			var totalDur time.Duration
+			for i := 0; i < b.N; i++ {
+				store := newBenchStore(b, i)
+				store.ingestBatchReplays = bc.batchReplays
+
+				iterStart := time.Now()
+				var stored atomic.Int64
+				dataChan, errChan := store.StartIngestion(ctx, IngestionHooks{
+					OnReplayStored: func() { stored.Add(1) },
+					OnStoreError:   func(err error) { b.Errorf("store: %v", err) },
+				})
+				queue := make(chan fileops.FileInfo)
+				var wg sync.WaitGroup
+				for range bc.workers {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						for fileInfo := range queue {
+							run := sink.Replay(fileInfo.Name)
+							replay := parser.CreateReplayFromFileInfo(fileInfo.Path, fileInfo.Name, fileInfo.Size, fileInfo.Checksum)
+							data, err := parser.ParseReplayWithOptions(fileInfo.Path, replay, parser.Options{Profile: run})
+							if err != nil {
+								b.Errorf("ParseReplay(%s): %v", fileInfo.Name, err)
+								continue
+							}
+							data.Profile = run
+							dataChan <- data
+						}
+					}()
+				}
+				for _, fileInfo := range files {
+					queue <- fileInfo
+				}
+				close(queue)
+				wg.Wait()
+				close(dataChan)
+				if err := <-errChan; err != nil {
+					b.Fatalf("StartIngestion: %v", err)
+				}
+				totalDur += time.Since(iterStart)
+				if got := stored.Load(); got != int64(len(files)) {
+					b.Fatalf("stored %d replays, want %d", got, len(files))
+				}
+
+				if err := store.Close(); err != nil {
+					b.Fatalf("Close: %v", err)
+				}
+			}
+
+			ops := float64(b.N)
+			b.ReportMetric(float64(len(files))*ops/totalDur.Seconds(), "replays/s")
+			reportPhaseMetrics(b, sink, ops)
+		})
+	}
+}
+
+// benchCorpus returns the benchmark corpus: SCREPDB_BENCH_CORPUS or the
+// testdata replays, without duplicate checksums, oldest first and capped at
+// SCREPDB_BENCH_CORPUS_CAP.
+func benchCorpus(b *testing.B) []fileops.FileInfo {
+	b.Helper()
 	replaysDir := os.Getenv("SCREPDB_BENCH_CORPUS")
 	if replaysDir == "" {
 		var err error
@@ -45,6 +170,7 @@ func BenchmarkSQLiteIngestionCorpus(b *testing.B) {
 			f.Close()
 		})
 	}
+	_ = iofacade.AllowDir(replaysDir)
 	files, err := fileops.GetReplayFiles(replaysDir)
 	if err != nil {
 		b.Fatalf("GetReplayFiles: %v", err)
@@ -73,55 +199,31 @@ func BenchmarkSQLiteIngestionCorpus(b *testing.B) {
 			files = files[:capN]
 		}
 	}
-	corpusSize := len(files)
-
-	b.ResetTimer()
-	sink := profile.NewSink(profile.ModeSummary)
-	sink.SetWriter(io.Discard) // silence per-replay log spam during bench
-	var totalDur time.Duration
-	for i := 0; i < b.N; i++ {
-		dbPath := filepath.Join(b.TempDir(), fmt.Sprintf("bench_%d.db", i))
-		store, err := NewSQLiteStorage(dbPath)
-		if err != nil {
-			b.Fatalf("NewSQLiteStorage: %v", err)
-		}
-		if err := store.Initialize(ctx, true, true); err != nil {
-			_ = store.Close()
-			b.Fatalf("Initialize: %v", err)
-		}
-
-		iterStart := time.Now()
-		for idx := range files {
-			fileInfo := files[idx]
-
-			run := sink.Replay(fileInfo.Name)
-			stop := run.Phase("parse")
-			replay := parser.CreateReplayFromFileInfo(fileInfo.Path, fileInfo.Name, fileInfo.Size, fileInfo.Checksum)
-			data, err := parser.ParseReplay(fileInfo.Path, replay)
-			stop()
-			if err != nil {
-				_ = store.Close()
-				b.Fatalf("ParseReplay(%s): %v", fileInfo.Name, err)
-			}
-			data.Profile = run
-
-			if err := store.storeReplayWithBatching(ctx, data); err != nil {
-				_ = store.Close()
-				b.Fatalf("storeReplayWithBatching(%s): %v", fileInfo.Name, err)
-			}
-		}
-		totalDur += time.Since(iterStart)
+	return files
+}
 
-		if err := store.Close(); err != nil {
-			b.Fatalf("Close: %v", err)
-		}
+// newBenchStore returns a freshly initialized SQLite database for benchmark
+// iteration i.
+func newBenchStore(b *testing.B, i int) *SQLiteStorage {
+	b.Helper()
+	dbPath := filepath.Join(b.TempDir(), fmt.Sprintf("bench_%d.db", i))
+	store, err := NewSQLiteStorage(dbPath)
+	if err != nil {
+		b.Fatalf("NewSQLiteStorage: %v", err)
 	}
+	if err := store.Initialize(context.Background(), true, true); err != nil {
+		_ = store.Close()
+		b.Fatalf("Initialize: %v", err)
+	}
+	return store
+}
 
-	ops := float64(b.N)
+// reportPhaseMetrics reports the total time per iteration of each profile
+// phase. With parallel workers the parser phases add up across goroutines,
+// so they can exceed the wall time.
+func reportPhaseMetrics(b *testing.B, sink *profile.Sink, ops float64) {
 	totals := sink.PhaseTotals()
-	b.ReportMetric(totalDur.Seconds()/ops, fmt.Sprintf("s/ingest_%dreplays", corpusSize))
-	b.ReportMetric(totalDur.Seconds()/ops/float64(corpusSize), "s/replay")
-	for _, phase := range []string{"parse", "replay_ins", "players", "cmds", "events", "patterns", "commit"} {
+	for _, phase := range []string{"parse", "unittags", "earlyfilter", "detect", "analyze", "replay_ins", "players", "cmds", "events", "patterns", "aggregates", "commit"} {
 		if d, ok := totals[phase]; ok {
 			b.ReportMetric(d.Seconds()/ops, fmt.Sprintf("s/%s", phase))
 		}
@@ -0,0 +1,449 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Integrity checks used by `screpdb doctor`. Foreign keys are enforced on every
+// connection this package opens, so orphans only appear in databases written
+// by older builds, external tools or with foreign_keys disabled; the checks
+// still look for them explicitly because PRAGMA foreign_key_check reports rows,
+// not what is safe to do about them.
+
+// orphanCheck describes rows whose reference no longer resolves. fix is the
+// statement that repairs them: a DELETE for required references, an UPDATE
+// ... SET NULL where the schema declares ON DELETE SET NULL, or a rebuild
+// when the fix can't report the rows it repaired (counted then by count).
+// table is the table whose replay_id the check covers, if any.
+type orphanCheck struct {
+	name    string
+	table   string
+	count   string
+	fix     string
+	rebuild bool
+}
+
+var orphanChecks = []orphanCheck{
+	{
+		name:  "players without replay",
+		table: "players",
+		count: "SELECT COUNT(*) FROM players WHERE replay_id NOT IN (SELECT id FROM replays)",
+		fix:   "DELETE FROM players WHERE replay_id NOT IN (SELECT id FROM replays)",
+	},
+	{
+		name:  "commands without replay or player",
+		table: "commands",
+		count: "SELECT COUNT(*) FROM commands WHERE replay_id NOT IN (SELECT id FROM replays) OR player_id NOT IN (SELECT id FROM players)",
+		fix:   "DELETE FROM commands WHERE replay_id NOT IN (SELECT id FROM replays) OR player_id NOT IN (SELECT id FROM players)",
+	},
+	{
+		name:  "commands_low_value without replay or player",
+		table: "commands_low_value",
+		count: "SELECT COUNT(*) FROM commands_low_value WHERE replay_id NOT IN (SELECT id FROM replays) OR player_id NOT IN (SELECT id FROM players)",
+		fix:   "DELETE FROM commands_low_value WHERE replay_id NOT IN (SELECT id FROM replays) OR player_id NOT IN (SELECT id FROM players)",
+	},
+	{
+		name:  "replay_events without replay or source player",
+		table: "replay_events",
+		count: "SELECT COUNT(*) FROM replay_events WHERE replay_id NOT IN (SELECT id FROM replays) OR (source_player_id IS NOT NULL AND source_player_id NOT IN (SELECT id FROM players))",
+		fix:   "DELETE FROM replay_events WHERE replay_id NOT IN (SELECT id FROM replays) OR (source_player_id IS NOT NULL AND source_player_id NOT IN (SELECT id FROM players))",
+	},
+	{
+		name:  "replay_events with dangling target player",
+		count: "SELECT COUNT(*) FROM replay_events WHERE target_player_id IS NOT NULL AND target_player_id NOT IN (SELECT id FROM players)",
+		fix:   "UPDATE replay_events SET target_player_id = NULL WHERE target_player_id IS NOT NULL AND target_player_id NOT IN (SELECT id FROM players)",
+	},
+	{
+		name:  "replay_analysis_inputs without replay",
+		table: "replay_analysis_inputs",
+		count: "SELECT COUNT(*) FROM replay_analysis_inputs WHERE replay_id NOT IN (SELECT id FROM replays)",
+		fix:   "DELETE FROM replay_analysis_inputs WHERE replay_id NOT IN (SELECT id FROM replays)",
+	},
+	{
+		name:  "player_economy_samples without replay or player",
+		table: "player_economy_samples",
+		count: "SELECT COUNT(*) FROM player_economy_samples WHERE replay_id NOT IN (SELECT id FROM replays) OR player_id NOT IN (SELECT id FROM players)",
+		fix:   "DELETE FROM player_economy_samples WHERE replay_id NOT IN (SELECT id FROM replays) OR player_id NOT IN (SELECT id FROM players)",
+	},
+	{
+		name:  "production_busy_spans without replay or player",
+		table: "production_busy_spans",
+		count: "SELECT COUNT(*) FROM production_busy_spans WHERE replay_id NOT IN (SELECT id FROM replays) OR player_id NOT IN (SELECT id FROM players)",
+		fix:   "DELETE FROM production_busy_spans WHERE replay_id NOT IN (SELECT id FROM replays) OR player_id NOT IN (SELECT id FROM players)",
+	},
+	{
+		name:  "larva_samples without replay or player",
+		table: "larva_samples",
+		count: "SELECT COUNT(*) FROM larva_samples WHERE replay_id NOT IN (SELECT id FROM replays) OR player_id NOT IN (SELECT id FROM players)",
+		fix:   "DELETE FROM larva_samples WHERE replay_id NOT IN (SELECT id FROM replays) OR player_id NOT IN (SELECT id FROM players)",
+	},
+	{
+		name:  "hotkey_usage without replay or player",
+		table: "hotkey_usage",
+		count: "SELECT COUNT(*) FROM hotkey_usage WHERE replay_id NOT IN (SELECT id FROM replays) OR player_id NOT IN (SELECT id FROM players)",
+		fix:   "DELETE FROM hotkey_usage WHERE replay_id NOT IN (SELECT id FROM replays) OR player_id NOT IN (SELECT id FROM players)",
+	},
+	{
+		name:  "command_blobs without replay",
+		table: "command_blobs",
+		count: "SELECT COUNT(*) FROM command_blobs WHERE replay_id NOT IN (SELECT id FROM replays)",
+		fix:   "DELETE FROM command_blobs WHERE replay_id NOT IN (SELECT id FROM replays)",
+	},
+	{
+		name:  "player_game_facts without replay or player",
+		table: "player_game_facts",
+		count: "SELECT COUNT(*) FROM player_game_facts WHERE replay_id NOT IN (SELECT id FROM replays) OR player_id NOT IN (SELECT id FROM players)",
+		fix:   "DELETE FROM player_game_facts WHERE replay_id NOT IN (SELECT id FROM replays) OR player_id NOT IN (SELECT id FROM players)",
+	},
+	{
+		// The chat index is external content over commands: entries whose
+		// command is gone, and chat commands missing from it, are repaired by
+		// refilling it from commands as 000006_chat_search does. It goes last,
+		// after commands are pruned.
+		name: "chat_messages_fts out of step with commands",
+		count: `SELECT
+			(SELECT COUNT(*) FROM chat_messages_fts_docsize WHERE id NOT IN (` + chatCommandIDs + `))
+			+ (SELECT COUNT(*) FROM (` + chatCommandIDs + `) WHERE id NOT IN (SELECT id FROM chat_messages_fts_docsize))`,
+		fix: `INSERT INTO chat_messages_fts (chat_messages_fts) VALUES ('delete-all');
+			INSERT INTO chat_messages_fts (rowid, chat_message) SELECT id, chat_message FROM commands WHERE action_type = 'Chat' AND chat_message IS NOT NULL`,
+		rebuild: true,
+	},
+}
+
+// chatCommandIDs selects the commands chat_messages_fts indexes.
+const chatCommandIDs = "SELECT id FROM commands WHERE action_type = 'Chat' AND chat_message IS NOT NULL"
+
+// replayTableOrphanChecks covers every table with a replay_id column that
+// orphanChecks doesn't, so a table added by a later migration is checked for
+// rows of deleted replays without being listed here.
+func (s *SQLiteStorage) replayTableOrphanChecks(ctx context.Context) ([]orphanCheck, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND sql NOT LIKE 'CREATE VIRTUAL TABLE%' ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan table name: %w", err)
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	covered := make(map[string]bool, len(orphanChecks))
+	for _, c := range orphanChecks {
+		covered[c.table] = true
+	}
+	var out []orphanCheck
+	for _, table := range tables {
+		if covered[table] {
+			continue
+		}
+		var hasReplayID bool
+		if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) > 0 FROM pragma_table_info(?) WHERE name = 'replay_id'", table).Scan(&hasReplayID); err != nil {
+			return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+		}
+		if !hasReplayID {
+			continue
+		}
+		where := fmt.Sprintf("FROM %q WHERE replay_id NOT IN (SELECT id FROM replays)", table)
+		out = append(out, orphanCheck{
+			name:  table + " without replay",
+			table: table,
+			count: "SELECT COUNT(*) " + where,
+			fix:   "DELETE " + where,
+		})
+	}
+	return out, nil
+}
+
+// allOrphanChecks returns orphanChecks followed by replayTableOrphanChecks.
+func (s *SQLiteStorage) allOrphanChecks(ctx context.Context) ([]orphanCheck, error) {
+	extra, err := s.replayTableOrphanChecks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return append(slices.Clip(orphanChecks), extra...), nil
+}
+
+// OrphanCount is the number of rows found by one orphan check.
+type OrphanCount struct {
+	Check string
+	Rows  int64
+}
+
+// CountOrphans runs every orphan check, in a fixed order.
+func (s *SQLiteStorage) CountOrphans(ctx context.Context) ([]OrphanCount, error) {
+	checks, err := s.allOrphanChecks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]OrphanCount, 0, len(checks))
+	for _, c := range checks {
+		var n int64
+		if err := s.db.QueryRowContext(ctx, c.count).Scan(&n); err != nil {
+			return nil, fmt.Errorf("failed to count %s: %w", c.name, err)
+		}
+		out = append(out, OrphanCount{Check: c.name, Rows: n})
+	}
+	return out, nil
+}
+
+// PruneOrphans repairs every orphan check in one transaction and returns the
+// number of rows deleted or detached. Players go first so the commands and
+// events that hung off a pruned player are caught by the later checks.
+func (s *SQLiteStorage) PruneOrphans(ctx context.Context) (int64, error) {
+	checks, err := s.allOrphanChecks(ctx)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var total int64
+	for _, c := range checks {
+		if c.rebuild {
+			var n int64
+			if err := tx.QueryRowContext(ctx, c.count).Scan(&n); err != nil {
+				return 0, fmt.Errorf("failed to count %s: %w", c.name, err)
+			}
+			if n == 0 {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, c.fix); err != nil {
+				return 0, fmt.Errorf("failed to prune %s: %w", c.name, err)
+			}
+			total += n
+			continue
+		}
+		res, err := tx.ExecContext(ctx, c.fix)
+		if err != nil {
+			return 0, fmt.Errorf("failed to prune %s: %w", c.name, err)
+		}
+		n, _ := res.RowsAffected()
+		total += n
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit orphan prune: %w", err)
+	}
+	return total, nil
+}
+
+// VersionCount is the number of replays stamped with one analyzer algorithm
+// version.
+type VersionCount struct {
+	Version int
+	Replays int64
+}
+
+// AlgorithmVersionCounts returns the analyzer_algorithm_version distribution,
+// oldest version first.
+func (s *SQLiteStorage) AlgorithmVersionCounts(ctx context.Context) ([]VersionCount, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT analyzer_algorithm_version, COUNT(*) FROM replays GROUP BY analyzer_algorithm_version ORDER BY analyzer_algorithm_version")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query algorithm versions: %w", err)
+	}
+	defer rows.Close()
+
+	var out []VersionCount
+	for rows.Next() {
+		var vc VersionCount
+		if err := rows.Scan(&vc.Version, &vc.Replays); err != nil {
+			return nil, fmt.Errorf("failed to scan algorithm version: %w", err)
+		}
+		out = append(out, vc)
+	}
+	return out, rows.Err()
+}
+
+// enumColumns lists every column whose writes fall back to unknownEnumValue
+// when screp reports a value outside the allowed set.
+var enumColumns = []struct{ table, column string }{
+	{"players", "race"},
+	{"players", "type"},
+	{"players", "color"},
+	{"commands", "action_type"},
+	{"commands", "order_name"},
+	{"commands", "unit_type"},
+	{"commands", "tech_name"},
+	{"commands", "upgrade_name"},
+	{"commands", "hotkey_type"},
+	{"commands", "game_speed"},
+	{"commands", "leave_reason"},
+	{"commands_low_value", "action_type"},
+	{"commands_low_value", "order_name"},
+	{"commands_low_value", "unit_type"},
+	{"commands_low_value", "tech_name"},
+	{"commands_low_value", "upgrade_name"},
+	{"commands_low_value", "hotkey_type"},
+	{"commands_low_value", "game_speed"},
+	{"commands_low_value", "leave_reason"},
+}
+
+// EnumFallbackCount is the number of rows whose column holds the UNKNOWN
+// fallback, with how many distinct replays they come from.
+type EnumFallbackCount struct {
+	Table   string
+	Column  string
+	Rows    int64
+	Replays int64
+}
+
+// UnknownEnumCounts reports every enum column holding UNKNOWN values. Columns
+// without any are omitted.
+func (s *SQLiteStorage) UnknownEnumCounts(ctx context.Context) ([]EnumFallbackCount, error) {
+	var out []EnumFallbackCount
+	for _, c := range enumColumns {
+		q := fmt.Sprintf("SELECT COUNT(*), COUNT(DISTINCT replay_id) FROM %s WHERE %s = ?", c.table, c.column)
+		ec := EnumFallbackCount{Table: c.table, Column: c.column}
+		if err := s.db.QueryRowContext(ctx, q, unknownEnumValue).Scan(&ec.Rows, &ec.Replays); err != nil {
+			return nil, fmt.Errorf("failed to count UNKNOWN %s.%s: %w", c.table, c.column, err)
+		}
+		if ec.Rows > 0 {
+			out = append(out, ec)
+		}
+	}
+	return out, nil
+}
+
+// IntegrityCheck runs PRAGMA integrity_check and returns its messages. A
+// healthy database returns exactly []string{"ok"}.
+func (s *SQLiteStorage) IntegrityCheck(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return nil, fmt.Errorf("failed to run integrity_check: %w", err)
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			return nil, fmt.Errorf("failed to scan integrity_check: %w", err)
+		}
+		out = append(out, msg)
+	}
+	return out, rows.Err()
+}
+
+// ForeignKeyViolation is one row reported by PRAGMA foreign_key_check,
+// aggregated per (table, parent).
+type ForeignKeyViolation struct {
+	Table  string
+	Parent string
+	Rows   int64
+}
+
+// ForeignKeyCheck runs PRAGMA foreign_key_check and aggregates the violations
+// per child/parent table pair.
+func (s *SQLiteStorage) ForeignKeyCheck(ctx context.Context) ([]ForeignKeyViolation, error) {
+	rows, err := s.db.QueryContext(ctx, "PRAGMA foreign_key_check")
+	if err != nil {
+		return nil, fmt.Errorf("failed to run foreign_key_check: %w", err)
+	}
+	defer rows.Close()
+
+	index := map[[2]string]int{}
+	var out []ForeignKeyViolation
+	for rows.Next() {
+		var table, parent string
+		var rowid, fkid any
+		if err := rows.Scan(&table, &rowid, &parent, &fkid); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign_key_check: %w", err)
+		}
+		key := [2]string{table, parent}
+		i, ok := index[key]
+		if !ok {
+			i = len(out)
+			index[key] = i
+			out = append(out, ForeignKeyViolation{Table: table, Parent: parent})
+		}
+		out[i].Rows++
+	}
+	return out, rows.Err()
+}
+
+// ListAllReplayRefs returns every replay with the file it was ingested from.
+func (s *SQLiteStorage) ListAllReplayRefs(ctx context.Context) ([]ReplayRef, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id, file_path, file_checksum, file_name, analyzer_algorithm_version FROM replays ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list replays: %w", err)
+	}
+	defer rows.Close()
+
+	var out []ReplayRef
+	for rows.Next() {
+		var r ReplayRef
+		if err := rows.Scan(&r.ID, &r.FilePath, &r.FileChecksum, &r.FileName, &r.AlgorithmVersion); err != nil {
+			return nil, fmt.Errorf("failed to scan replay: %w", err)
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// ReplaysMissingAnalysis returns the IDs of replays stamped as analyzed (a
+// non-zero analyzer_algorithm_version) that have no replay_events rows at all.
+// Every analyzed melee replay carries at least its player_start events, so
+// these are detections lost to a crash or a manual DELETE.
+func (s *SQLiteStorage) ReplaysMissingAnalysis(ctx context.Context) ([]int64, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT r.id FROM replays r
+		WHERE r.analyzer_algorithm_version > 0
+		  AND NOT EXISTS (SELECT 1 FROM replay_events e WHERE e.replay_id = r.id)
+		ORDER BY r.id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query replays missing analysis: %w", err)
+	}
+	defer rows.Close()
+
+	var out []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan replay id: %w", err)
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
+
+// RequeueReplayAnalysis resets analyzer_algorithm_version to 0 for the given
+// replays, so they count as stale for `screpdb reanalyze` and the dashboard's
+// re-analyze banner.
+func (s *SQLiteStorage) RequeueReplayAnalysis(ctx context.Context, replayIDs []int64) error {
+	if len(replayIDs) == 0 {
+		return nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(replayIDs)), ",")
+	args := make([]any, len(replayIDs))
+	for i, id := range replayIDs {
+		args[i] = id
+	}
+	if _, err := s.db.ExecContext(ctx, "UPDATE replays SET analyzer_algorithm_version = 0 WHERE id IN ("+placeholders+")", args...); err != nil {
+		return fmt.Errorf("failed to re-queue analysis: %w", err)
+	}
+	return nil
+}
+
+// UpdateReplayFilePath re-points a replay row at a file that moved, keeping
+// file_name in sync with the new path.
+func (s *SQLiteStorage) UpdateReplayFilePath(ctx context.Context, replayID int64, path string) error {
+	if _, err := s.db.ExecContext(ctx, "UPDATE replays SET file_path = ?, file_name = ? WHERE id = ?", path, filepath.Base(path), replayID); err != nil {
+		return fmt.Errorf("failed to update replay file path: %w", err)
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 
+	"github.com/marianogappa/screpdb/internal/chatsearch"
 	"github.com/marianogappa/screpdb/internal/fileops"
 	"github.com/marianogappa/screpdb/internal/models"
 	"github.com/marianogappa/screpdb/internal/patterns/core"
@@ -10,7 +11,8 @@ import (
 
 // Storage backend constants
 const (
-	StorageSQLite = "sqlite"
+	StorageSQLite   = "sqlite"
+	StoragePostgres = "postgres"
 )
 
 // ReplayDataChannel represents a channel for sending replay data to storage
@@ -29,6 +31,11 @@ type Storage interface {
 	// If cleanDashboard is true, drops all dashboard tables
 	Initialize(ctx context.Context, clean bool, cleanDashboard bool) error
 
+	// SetCommandStorageOptions controls whether Right Click commands are
+	// stored, Hotkey commands skipped, and the bulk low-value commands
+	// compacted into per-replay blobs
+	SetCommandStorageOptions(storeRightClicks bool, skipHotkeys bool, compactCommands bool)
+
 	// StartIngestion starts the ingestion process with batching
 	// Returns a channel for sending replay data and a done channel
 	StartIngestion(ctx context.Context, hooks IngestionHooks) (ReplayDataChannel, <-chan error)
@@ -47,15 +54,45 @@ type Storage interface {
 	// file-renamed case where the same content lives at a new path.
 	FilterOutExistingReplaysByPath(ctx context.Context, files []fileops.FileInfo) ([]fileops.FileInfo, error)
 
+	// FilterOutUnchangedReplayFiles filters out files whose path is already
+	// known with the same size and modification time. Like
+	// FilterOutExistingReplaysByPath it runs before hashing; unlike it, a known
+	// path whose file changed survives to be hashed.
+	FilterOutUnchangedReplayFiles(ctx context.Context, files []fileops.FileInfo) ([]fileops.FileInfo, error)
+
+	// RefreshReplayFileStamps updates the recorded size and modification time
+	// of hashed files whose path and checksum match an ingested replay
+	RefreshReplayFileStamps(ctx context.Context, files []fileops.FileInfo) error
+
+	// FilterOutKnownIngestFailures filters out files the ingest_failures ledger
+	// already holds for the same content under the given screpdb version.
+	// Requires Checksum to be populated.
+	FilterOutKnownIngestFailures(ctx context.Context, files []fileops.FileInfo, version string) ([]fileops.FileInfo, error)
+
+	// RecordIngestFailures upserts per-file failures into the ingest_failures ledger
+	RecordIngestFailures(ctx context.Context, failures []IngestFailure) error
+
+	// ClearResolvedIngestFailures removes ledger rows for files that have since been ingested
+	ClearResolvedIngestFailures(ctx context.Context) (int64, error)
+
 	// Query executes a SQL query and returns results
 	Query(ctx context.Context, query string, args ...any) ([]map[string]any, error)
 
+	// QueryColumns executes a SQL query and returns ordered column names and
+	// row values, stopping after maxRows rows when maxRows > 0. The bool
+	// reports whether rows were left unread because of the cap.
+	QueryColumns(ctx context.Context, maxRows int, query string, args ...any) ([]string, [][]any, bool, error)
+
 	// StorageName returns the name of the storage backend
 	StorageName() string
 
 	// GetDatabaseSchema returns the database schema information
 	GetDatabaseSchema(ctx context.Context) (string, error)
 
+	// SearchChat returns the in-game chat messages matching opts, with the
+	// messages around each; see internal/chatsearch
+	SearchChat(ctx context.Context, opts chatsearch.Options) ([]chatsearch.Hit, error)
+
 	// Pattern detection methods
 	// FilterOutExistingPatternDetections filters out replays that already have pattern detection run
 	// with the current algorithm version. Returns only FileInfo objects for replays that need detection.
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/marianogappa/screpdb/internal/models"
+)
+
+// insertLarvaSamplesTx stores a replay's estimated Zerg larva timelines.
+// playerIDMap maps replay-local player IDs to database IDs; samples of players
+// it lacks are dropped.
+func insertLarvaSamplesTx(ctx context.Context, db dbtx, replayID int64, samples []models.LarvaSample, playerIDMap map[byte]int64) error {
+	const batchSize = 500
+	for i := 0; i < len(samples); i += batchSize {
+		batch := samples[i:min(i+batchSize, len(samples))]
+		valueStrings := make([]string, 0, len(batch))
+		valueArgs := make([]any, 0, len(batch)*9)
+		for _, sample := range batch {
+			playerID, ok := playerIDMap[sample.PlayerID]
+			if !ok {
+				continue
+			}
+			valueStrings = append(valueStrings, "(?, ?, ?, ?, ?, ?, ?, ?, ?)")
+			valueArgs = append(valueArgs,
+				replayID,
+				playerID,
+				sample.HatcheryIndex,
+				sample.WindowEndSecond,
+				sample.Second,
+				sample.Larvae,
+				sample.Spawned,
+				sample.Used,
+				sample.Missed,
+			)
+		}
+		if len(valueStrings) == 0 {
+			continue
+		}
+		query := `
+			INSERT INTO larva_samples (
+				replay_id, player_id, hatchery_index, window_end_second, second,
+				larvae, spawned, used, missed
+			) VALUES ` + strings.Join(valueStrings, ", ")
+		if _, err := db.ExecContext(ctx, query, valueArgs...); err != nil {
+			return fmt.Errorf("failed to insert larva samples: %w", err)
+		}
+	}
+	return nil
+}
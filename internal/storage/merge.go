@@ -0,0 +1,551 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// MergeStats summarizes one MergeFrom call.
+type MergeStats struct {
+	Replays             int64 // replays copied
+	DuplicateReplays    int64 // skipped: file_checksum already present
+	PathConflicts       int64 // skipped: another replay already uses the file_path
+	Players             int64
+	Commands            int64
+	CommandsLowValue    int64
+	CommandBlobs        int64
+	ReplayEvents        int64
+	AnalysisInputs      int64
+	EconomySamples      int64
+	ProductionBusySpans int64
+	LarvaSamples        int64
+	HotkeyUsage         int64
+	AliasesAdded        int64
+	AliasesUpdated      int64 // same mapping, newer row won
+	AliasConflicts      int64 // tag mapped to a different alias; resolved by the alias policy
+}
+
+// mergeAttachName is the schema name the source database is attached under.
+const mergeAttachName = "merge_src"
+
+// MergeFrom copies every replay of the database at srcPath (with its players,
+// commands, commands_low_value, command_blobs, replay_events,
+// replay_analysis_inputs, player_economy_samples, production_busy_spans,
+// larva_samples and hotkey_usage) into this database, then merges
+// player_aliases.
+// Autoincrement IDs are remapped; replays whose file_checksum is already
+// present are skipped, as are replays whose file_path another replay already
+// uses (file_path is UNIQUE).
+//
+// Columns are copied by name, so a source written by an older schema merges
+// with defaults for the columns it lacks. Everything from one source lands in
+// a single transaction: a failed merge leaves this database untouched. Built
+// player aggregates are brought up to date in the same transaction.
+func (s *SQLiteStorage) MergeFrom(ctx context.Context, srcPath string) (MergeStats, error) {
+	var stats MergeStats
+
+	// ATTACH is per connection and cannot run inside a transaction, so pin
+	// one connection for attach, copy and detach.
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return stats, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS "+mergeAttachName, srcPath); err != nil {
+		return stats, fmt.Errorf("failed to attach %s: %w", srcPath, err)
+	}
+	defer conn.ExecContext(context.Background(), "DETACH DATABASE "+mergeAttachName)
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return stats, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := mergeReplaysTx(ctx, tx, &stats); err != nil {
+		return stats, err
+	}
+	merged, err := replayIDsTx(ctx, tx, "SELECT new_id FROM temp.merge_replay_map ORDER BY new_id")
+	if err != nil {
+		return stats, fmt.Errorf("failed to list merged replays: %w", err)
+	}
+	if err := s.refreshPlayerAggregatesTx(ctx, tx, merged); err != nil {
+		return stats, err
+	}
+	if err := mergeAliasesTx(ctx, tx, &stats); err != nil {
+		return stats, err
+	}
+	if err := tx.Commit(); err != nil {
+		return stats, fmt.Errorf("failed to commit merge: %w", err)
+	}
+	return stats, nil
+}
+
+// sharedColumns returns the columns table has in both databases, in the
+// target's order, minus skip.
+func sharedColumns(ctx context.Context, tx *sql.Tx, table string, skip ...string) ([]string, error) {
+	names := func(schema string) ([]string, error) {
+		rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT name FROM pragma_table_info('%s', '%s')", table, schema))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s.%s columns: %w", schema, table, err)
+		}
+		defer rows.Close()
+		var out []string
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				return nil, err
+			}
+			out = append(out, name)
+		}
+		return out, rows.Err()
+	}
+	target, err := names("main")
+	if err != nil {
+		return nil, err
+	}
+	source, err := names(mergeAttachName)
+	if err != nil {
+		return nil, err
+	}
+	if len(source) == 0 {
+		return nil, fmt.Errorf("source database has no %s table", table)
+	}
+	inSource := make(map[string]bool, len(source))
+	for _, c := range source {
+		inSource[c] = true
+	}
+	skipped := map[string]bool{"id": true}
+	for _, c := range skip {
+		skipped[c] = true
+	}
+	var out []string
+	for _, c := range target {
+		if inSource[c] && !skipped[c] {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func prefixed(alias string, cols []string) string {
+	out := make([]string, len(cols))
+	for i, c := range cols {
+		out[i] = alias + "." + c
+	}
+	return strings.Join(out, ", ")
+}
+
+func mergeReplaysTx(ctx context.Context, tx *sql.Tx, stats *MergeStats) error {
+	for _, q := range []string{
+		"CREATE TEMP TABLE IF NOT EXISTS merge_replay_map (old_id INTEGER PRIMARY KEY, new_id INTEGER NOT NULL)",
+		"CREATE TEMP TABLE IF NOT EXISTS merge_player_map (old_id INTEGER PRIMARY KEY, new_id INTEGER NOT NULL)",
+		"DELETE FROM temp.merge_replay_map",
+		"DELETE FROM temp.merge_player_map",
+	} {
+		if _, err := tx.ExecContext(ctx, q); err != nil {
+			return fmt.Errorf("failed to prepare id maps: %w", err)
+		}
+	}
+
+	// game_group_id holds source IDs; merged replays are regrouped below.
+	replayCols, err := sharedColumns(ctx, tx, "replays", "game_group_id")
+	if err != nil {
+		return err
+	}
+	playerCols, err := sharedColumns(ctx, tx, "players", "replay_id")
+	if err != nil {
+		return err
+	}
+
+	// Replays and players are copied row by row to learn their new IDs;
+	// the high-volume tables below are then copied set-wise through the maps.
+	type srcReplay struct {
+		id             int64
+		path, checksum string
+	}
+	rows, err := tx.QueryContext(ctx, "SELECT id, file_path, file_checksum FROM "+mergeAttachName+".replays ORDER BY id")
+	if err != nil {
+		return fmt.Errorf("failed to list source replays: %w", err)
+	}
+	var replays []srcReplay
+	for rows.Next() {
+		var r srcReplay
+		if err := rows.Scan(&r.id, &r.path, &r.checksum); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan source replay: %w", err)
+		}
+		replays = append(replays, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	insertReplay := fmt.Sprintf("INSERT INTO main.replays (%s) SELECT %s FROM %s.replays r WHERE r.id = ?",
+		strings.Join(replayCols, ", "), prefixed("r", replayCols), mergeAttachName)
+	for _, r := range replays {
+		var checksumTaken, pathTaken int
+		if err := tx.QueryRowContext(ctx,
+			"SELECT EXISTS(SELECT 1 FROM main.replays WHERE file_checksum = ?), EXISTS(SELECT 1 FROM main.replays WHERE file_path = ?)",
+			r.checksum, r.path).Scan(&checksumTaken, &pathTaken); err != nil {
+			return fmt.Errorf("failed to check replay %d: %w", r.id, err)
+		}
+		switch {
+		case checksumTaken == 1:
+			stats.DuplicateReplays++
+			continue
+		case pathTaken == 1:
+			stats.PathConflicts++
+			continue
+		}
+		res, err := tx.ExecContext(ctx, insertReplay, r.id)
+		if err != nil {
+			return fmt.Errorf("failed to copy replay %d: %w", r.id, err)
+		}
+		newID, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO temp.merge_replay_map (old_id, new_id) VALUES (?, ?)", r.id, newID); err != nil {
+			return err
+		}
+		stats.Replays++
+	}
+	if stats.Replays == 0 {
+		return nil
+	}
+	if err := regroupMergedReplaysTx(ctx, tx); err != nil {
+		return err
+	}
+
+	type srcPlayer struct{ id, newReplayID int64 }
+	rows, err = tx.QueryContext(ctx, "SELECT p.id, m.new_id FROM "+mergeAttachName+".players p JOIN temp.merge_replay_map m ON m.old_id = p.replay_id ORDER BY p.id")
+	if err != nil {
+		return fmt.Errorf("failed to list source players: %w", err)
+	}
+	var players []srcPlayer
+	for rows.Next() {
+		var p srcPlayer
+		if err := rows.Scan(&p.id, &p.newReplayID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan source player: %w", err)
+		}
+		players = append(players, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	insertPlayer := fmt.Sprintf("INSERT INTO main.players (replay_id, %s) SELECT ?, %s FROM %s.players p WHERE p.id = ?",
+		strings.Join(playerCols, ", "), prefixed("p", playerCols), mergeAttachName)
+	for _, p := range players {
+		res, err := tx.ExecContext(ctx, insertPlayer, p.newReplayID, p.id)
+		if err != nil {
+			return fmt.Errorf("failed to copy player %d: %w", p.id, err)
+		}
+		newID, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO temp.merge_player_map (old_id, new_id) VALUES (?, ?)", p.id, newID); err != nil {
+			return err
+		}
+		stats.Players++
+	}
+
+	for _, t := range []struct {
+		table string
+		count *int64
+	}{
+		{"commands", &stats.Commands},
+		{"commands_low_value", &stats.CommandsLowValue},
+	} {
+		cols, err := sharedColumns(ctx, tx, t.table, "replay_id", "player_id")
+		if err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, fmt.Sprintf(`
+			INSERT INTO main.%[1]s (replay_id, player_id, %[2]s)
+			SELECT rm.new_id, pm.new_id, %[3]s
+			FROM %[4]s.%[1]s c
+			JOIN temp.merge_replay_map rm ON rm.old_id = c.replay_id
+			JOIN temp.merge_player_map pm ON pm.old_id = c.player_id
+			ORDER BY c.id`, t.table, strings.Join(cols, ", "), prefixed("c", cols), mergeAttachName))
+		if err != nil {
+			return fmt.Errorf("failed to copy %s: %w", t.table, err)
+		}
+		*t.count, _ = res.RowsAffected()
+	}
+
+	eventCols, err := sharedColumns(ctx, tx, "replay_events", "replay_id", "source_player_id", "target_player_id")
+	if err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO main.replay_events (replay_id, source_player_id, target_player_id, %[1]s)
+		SELECT rm.new_id, sp.new_id, tp.new_id, %[2]s
+		FROM %[3]s.replay_events e
+		JOIN temp.merge_replay_map rm ON rm.old_id = e.replay_id
+		LEFT JOIN temp.merge_player_map sp ON sp.old_id = e.source_player_id
+		LEFT JOIN temp.merge_player_map tp ON tp.old_id = e.target_player_id
+		ORDER BY e.id`, strings.Join(eventCols, ", "), prefixed("e", eventCols), mergeAttachName))
+	if err != nil {
+		return fmt.Errorf("failed to copy replay_events: %w", err)
+	}
+	stats.ReplayEvents, _ = res.RowsAffected()
+
+	if err := mergeCommandBlobsTx(ctx, tx, stats); err != nil {
+		return err
+	}
+
+	// Analysis inputs key players by in-replay ID and commands by stream
+	// position, neither of which the copy changes. Sources from before they
+	// were stored have none.
+	hasInputs, err := mergeSourceHasTable(ctx, tx, "replay_analysis_inputs")
+	if err != nil {
+		return err
+	}
+	if hasInputs {
+		res, err = tx.ExecContext(ctx, fmt.Sprintf(`
+			INSERT INTO main.replay_analysis_inputs (replay_id, format_version, payload)
+			SELECT rm.new_id, a.format_version, a.payload
+			FROM %s.replay_analysis_inputs a
+			JOIN temp.merge_replay_map rm ON rm.old_id = a.replay_id`, mergeAttachName))
+		if err != nil {
+			return fmt.Errorf("failed to copy replay_analysis_inputs: %w", err)
+		}
+		stats.AnalysisInputs, _ = res.RowsAffected()
+	}
+
+	// Likewise economy samples, production busy spans, larva samples and
+	// hotkey usage, which sources from before they were stored lack until
+	// re-analyzed.
+	if stats.EconomySamples, err = mergePlayerRowsTx(ctx, tx, "player_economy_samples"); err != nil {
+		return err
+	}
+	if stats.ProductionBusySpans, err = mergePlayerRowsTx(ctx, tx, "production_busy_spans"); err != nil {
+		return err
+	}
+	if stats.LarvaSamples, err = mergePlayerRowsTx(ctx, tx, "larva_samples"); err != nil {
+		return err
+	}
+	if stats.HotkeyUsage, err = mergePlayerRowsTx(ctx, tx, "hotkey_usage"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// mergePlayerRowsTx copies a table keyed by replay_id and player_id from the
+// source, remapping both, and returns the rows copied. A source without the
+// table copies nothing.
+func mergePlayerRowsTx(ctx context.Context, tx *sql.Tx, table string) (int64, error) {
+	hasTable, err := mergeSourceHasTable(ctx, tx, table)
+	if err != nil || !hasTable {
+		return 0, err
+	}
+	cols, err := sharedColumns(ctx, tx, table, "replay_id", "player_id")
+	if err != nil {
+		return 0, err
+	}
+	res, err := tx.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO main.%[1]s (replay_id, player_id, %[2]s)
+		SELECT rm.new_id, pm.new_id, %[3]s
+		FROM %[4]s.%[1]s src
+		JOIN temp.merge_replay_map rm ON rm.old_id = src.replay_id
+		JOIN temp.merge_player_map pm ON pm.old_id = src.player_id`, table, strings.Join(cols, ", "), prefixed("src", cols), mergeAttachName))
+	if err != nil {
+		return 0, fmt.Errorf("failed to copy %s: %w", table, err)
+	}
+	n, _ := res.RowsAffected()
+	return n, nil
+}
+
+// mergeSourceHasTable reports whether the attached source database has table.
+func mergeSourceHasTable(ctx context.Context, tx *sql.Tx, table string) (bool, error) {
+	var n int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+mergeAttachName+".sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&n); err != nil {
+		return false, fmt.Errorf("failed to check source %s: %w", table, err)
+	}
+	return n > 0, nil
+}
+
+// regroupMergedReplaysTx assigns game groups to the replays just copied in,
+// which may join games another POV of which was already here.
+func regroupMergedReplaysTx(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE main.replays SET game_group_id = id
+		WHERE game_fingerprint = '' AND id IN (SELECT new_id FROM temp.merge_replay_map)`); err != nil {
+		return fmt.Errorf("failed to group merged replays: %w", err)
+	}
+	rows, err := tx.QueryContext(ctx, `
+		SELECT DISTINCT game_fingerprint FROM main.replays
+		WHERE game_fingerprint <> '' AND id IN (SELECT new_id FROM temp.merge_replay_map)`)
+	if err != nil {
+		return fmt.Errorf("failed to list merged game fingerprints: %w", err)
+	}
+	var fingerprints []string
+	for rows.Next() {
+		var fp string
+		if err := rows.Scan(&fp); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan game fingerprint: %w", err)
+		}
+		fingerprints = append(fingerprints, fp)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, fp := range fingerprints {
+		if err := regroupFingerprintTx(ctx, tx, fp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// aliasRow is one player_aliases row as seen by the merge.
+type aliasRow struct {
+	id             int64
+	canonicalAlias string
+	tagNormalized  string
+	tagRaw         string
+	auroraID       sql.NullInt64
+	source         string
+	updatedAt      string
+}
+
+// aliasPriority ranks alias sources: the local user's own "you" rows, then
+// hand-curated "manual" ones, then bulk "imported" lists.
+func aliasPriority(source string) int {
+	switch source {
+	case "you":
+		return 3
+	case "manual":
+		return 2
+	case "imported":
+		return 1
+	default:
+		return 0
+	}
+}
+
+// aliasBeats reports whether a should win over b when both map the same
+// battle tag: higher source priority, then the newer updated_at.
+func aliasBeats(a, b aliasRow) bool {
+	if pa, pb := aliasPriority(a.source), aliasPriority(b.source); pa != pb {
+		return pa > pb
+	}
+	return a.updatedAt > b.updatedAt
+}
+
+// mergeAliasesTx folds the source's player_aliases into this database.
+//
+// A source's "you" rows describe whoever owned that database; they are kept
+// as "manual" since "you" is recomputed locally and would be wiped here. When
+// an incoming row maps a battle tag to a different canonical alias than this
+// database does, the better row (aliasBeats) wins and the losing mappings for
+// that tag are dropped, so each tag resolves to one identity.
+func mergeAliasesTx(ctx context.Context, tx *sql.Tx, stats *MergeStats) error {
+	var exists int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+mergeAttachName+".sqlite_master WHERE type = 'table' AND name = 'player_aliases'").Scan(&exists); err != nil {
+		return fmt.Errorf("failed to look up source aliases: %w", err)
+	}
+	if exists == 0 {
+		return nil
+	}
+
+	incoming, err := queryAliases(ctx, tx, mergeAttachName+".player_aliases", "")
+	if err != nil {
+		return err
+	}
+	for _, in := range incoming {
+		if in.source == "you" {
+			in.source = "manual"
+		}
+		current, err := queryAliases(ctx, tx, "main.player_aliases", in.tagNormalized)
+		if err != nil {
+			return err
+		}
+
+		var same *aliasRow
+		var best *aliasRow
+		conflict := false
+		for i := range current {
+			c := &current[i]
+			if strings.EqualFold(strings.TrimSpace(c.canonicalAlias), strings.TrimSpace(in.canonicalAlias)) {
+				if c.source == in.source {
+					same = c
+				}
+				continue
+			}
+			conflict = true
+			if best == nil || aliasBeats(*c, *best) {
+				best = c
+			}
+		}
+
+		if conflict {
+			stats.AliasConflicts++
+			if !aliasBeats(in, *best) {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx,
+				"DELETE FROM main.player_aliases WHERE battle_tag_normalized = ? AND lower(trim(canonical_alias)) <> lower(trim(?))",
+				in.tagNormalized, in.canonicalAlias); err != nil {
+				return fmt.Errorf("failed to resolve alias conflict: %w", err)
+			}
+		}
+
+		if same != nil {
+			if in.updatedAt <= same.updatedAt {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx,
+				"UPDATE main.player_aliases SET battle_tag_raw = ?, aurora_id = ?, updated_at = ? WHERE id = ?",
+				in.tagRaw, in.auroraID, in.updatedAt, same.id); err != nil {
+				return fmt.Errorf("failed to update alias: %w", err)
+			}
+			stats.AliasesUpdated++
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO main.player_aliases (canonical_alias, battle_tag_normalized, battle_tag_raw, aurora_id, source, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(source, battle_tag_normalized, canonical_alias) DO NOTHING`,
+			in.canonicalAlias, in.tagNormalized, in.tagRaw, in.auroraID, in.source, in.updatedAt); err != nil {
+			return fmt.Errorf("failed to insert alias: %w", err)
+		}
+		stats.AliasesAdded++
+	}
+	return nil
+}
+
+// queryAliases reads alias rows from table, optionally only those for one
+// normalized battle tag.
+func queryAliases(ctx context.Context, tx *sql.Tx, table, tag string) ([]aliasRow, error) {
+	query := "SELECT id, canonical_alias, battle_tag_normalized, battle_tag_raw, aurora_id, source, updated_at FROM " + table
+	var args []any
+	if tag != "" {
+		query += " WHERE battle_tag_normalized = ?"
+		args = append(args, tag)
+	}
+	rows, err := tx.QueryContext(ctx, query+" ORDER BY id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read aliases: %w", err)
+	}
+	defer rows.Close()
+	var out []aliasRow
+	for rows.Next() {
+		var r aliasRow
+		if err := rows.Scan(&r.id, &r.canonicalAlias, &r.tagNormalized, &r.tagRaw, &r.auroraID, &r.source, &r.updatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan alias: %w", err)
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
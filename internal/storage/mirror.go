@@ -0,0 +1,324 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// MirrorStats summarizes one MirrorFrom call.
+type MirrorStats struct {
+	Replays        int64 // replays copied
+	RemovedReplays int64 // local replays gone from, or changed in, the source
+	Regrouped      int64 // replays whose game_group_id was updated in place
+	IngestFailures int64 // ingest_failures rows after the refresh
+}
+
+// mirrorReplayChunk is how many replays MirrorFrom copies per source query.
+const mirrorReplayChunk = 50
+
+// mirrorMaxParams bounds the bind parameters of one mirror INSERT.
+const mirrorMaxParams = 30000
+
+// mirrorTables are the per-replay tables MirrorFrom copies, parents first,
+// with the column that ties their rows to a replay.
+var mirrorTables = []struct {
+	name, replayColumn string
+}{
+	{"replays", "id"},
+	{"players", "replay_id"},
+	{"commands", "replay_id"},
+	{"commands_low_value", "replay_id"},
+	{"replay_events", "replay_id"},
+	{"replay_analysis_inputs", "replay_id"},
+	{"player_economy_samples", "replay_id"},
+	{"production_busy_spans", "replay_id"},
+	{"larva_samples", "replay_id"},
+	{"hotkey_usage", "replay_id"},
+}
+
+func mirrorTableNames() []string {
+	names := make([]string, len(mirrorTables))
+	for i, t := range mirrorTables {
+		names[i] = t.name
+	}
+	return names
+}
+
+// mirrorSource is a database MirrorFrom can read a corpus from.
+type mirrorSource interface {
+	mirrorDB() *sql.DB
+	tableColumns(ctx context.Context, table string) ([]string, error)
+}
+
+func (s *PostgresStorage) mirrorDB() *sql.DB { return s.db }
+
+func (s *SQLiteStorage) mirrorDB() *sql.DB { return s.db }
+
+// tableColumns returns table's column names in declaration order.
+func (s *SQLiteStorage) tableColumns(ctx context.Context, table string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s columns: %w", table, err)
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		out = append(out, name)
+	}
+	return out, rows.Err()
+}
+
+// RefreshMirror brings the SQLite database at sqlitePath up to date with the
+// PostgreSQL corpus at databaseURL; see MirrorFrom.
+func RefreshMirror(ctx context.Context, sqlitePath, databaseURL string) (MirrorStats, error) {
+	src, err := NewPostgresStorage(databaseURL)
+	if err != nil {
+		return MirrorStats{}, err
+	}
+	defer src.Close()
+	if err := src.Initialize(ctx, false, false); err != nil {
+		return MirrorStats{}, err
+	}
+
+	local, err := NewSQLiteStorage(sqlitePath)
+	if err != nil {
+		return MirrorStats{}, err
+	}
+	defer local.Close()
+	if err := local.Initialize(ctx, false, false); err != nil {
+		return MirrorStats{}, err
+	}
+	return local.MirrorFrom(ctx, src)
+}
+
+// MirrorFrom makes this database's replays a copy of src's, so the dashboard
+// can serve a corpus that lives in PostgreSQL. Replays keep their source IDs.
+// A local replay is kept while src still has it under the same ID, checksum,
+// analyzer version and player IDs; otherwise it is deleted (its rows go with
+// it) and copied afresh. game_group_id is updated in place, and
+// ingest_failures is replaced wholesale. Dashboard tables are untouched;
+// built player aggregates are brought up to date.
+//
+// Columns are copied by name, and source tables that don't exist yet are
+// skipped. The refresh runs in a single transaction: a failed mirror leaves
+// this database as it was.
+func (s *SQLiteStorage) MirrorFrom(ctx context.Context, src *PostgresStorage) (MirrorStats, error) {
+	return s.mirrorFrom(ctx, src)
+}
+
+// mirrorReplay is a replay's identity as far as MirrorFrom is concerned.
+type mirrorReplay struct {
+	checksum      string
+	version       int64
+	firstPlayerID int64
+	players       int64
+	gameGroupID   sql.NullInt64
+}
+
+func (m mirrorReplay) sameRows(o mirrorReplay) bool {
+	return m.checksum == o.checksum && m.version == o.version &&
+		m.firstPlayerID == o.firstPlayerID && m.players == o.players
+}
+
+func (s *SQLiteStorage) mirrorFrom(ctx context.Context, src mirrorSource) (MirrorStats, error) {
+	var stats MirrorStats
+
+	remote, err := loadMirrorReplays(ctx, src.mirrorDB())
+	if err != nil {
+		return stats, fmt.Errorf("failed to read source replays: %w", err)
+	}
+
+	// Resolved up front: this store's single connection is the transaction's
+	// once it begins.
+	columns := map[string][]string{}
+	for _, table := range append(mirrorTableNames(), "ingest_failures") {
+		if columns[table], err = mirrorColumns(ctx, src, s, table); err != nil {
+			return stats, err
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return stats, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	local, err := loadMirrorReplays(ctx, tx)
+	if err != nil {
+		return stats, fmt.Errorf("failed to read local replays: %w", err)
+	}
+
+	var missing []int64
+	for id, r := range remote {
+		l, ok := local[id]
+		if ok && l.sameRows(r) {
+			if l.gameGroupID != r.gameGroupID {
+				if _, err := tx.ExecContext(ctx, "UPDATE replays SET game_group_id = ? WHERE id = ?", r.gameGroupID, id); err != nil {
+					return stats, fmt.Errorf("failed to regroup replay %d: %w", id, err)
+				}
+				stats.Regrouped++
+			}
+			continue
+		}
+		missing = append(missing, id)
+	}
+	for id, l := range local {
+		if r, ok := remote[id]; ok && l.sameRows(r) {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM replays WHERE id = ?", id); err != nil {
+			return stats, fmt.Errorf("failed to remove replay %d: %w", id, err)
+		}
+		stats.RemovedReplays++
+	}
+
+	for _, table := range mirrorTables {
+		cols := columns[table.name]
+		if len(cols) == 0 {
+			continue
+		}
+		for start := 0; start < len(missing); start += mirrorReplayChunk {
+			end := min(start+mirrorReplayChunk, len(missing))
+			n, err := mirrorRowsTx(ctx, tx, src.mirrorDB(), table.name, cols, table.replayColumn, missing[start:end])
+			if err != nil {
+				return stats, err
+			}
+			if table.name == "replays" {
+				stats.Replays += n
+			}
+		}
+	}
+
+	if len(missing) > 0 || stats.RemovedReplays > 0 || stats.Regrouped > 0 {
+		if err := s.refreshPlayerAggregatesTx(ctx, tx, missing); err != nil {
+			return stats, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM ingest_failures"); err != nil {
+		return stats, fmt.Errorf("failed to clear ingest failures: %w", err)
+	}
+	if cols := columns["ingest_failures"]; len(cols) > 0 {
+		if stats.IngestFailures, err = mirrorRowsTx(ctx, tx, src.mirrorDB(), "ingest_failures", cols, "", nil); err != nil {
+			return stats, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return stats, fmt.Errorf("failed to commit mirror: %w", err)
+	}
+	return stats, nil
+}
+
+// loadMirrorReplays reads the identity of every replay in db.
+func loadMirrorReplays(ctx context.Context, db dbtx) (map[int64]mirrorReplay, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT r.id, r.file_checksum, r.analyzer_algorithm_version, r.game_group_id,
+			COALESCE(MIN(p.id), 0), COUNT(p.id)
+		FROM replays r
+		LEFT JOIN players p ON p.replay_id = r.id
+		GROUP BY r.id, r.file_checksum, r.analyzer_algorithm_version, r.game_group_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[int64]mirrorReplay{}
+	for rows.Next() {
+		var id int64
+		var r mirrorReplay
+		if err := rows.Scan(&id, &r.checksum, &r.version, &r.gameGroupID, &r.firstPlayerID, &r.players); err != nil {
+			return nil, err
+		}
+		out[id] = r
+	}
+	return out, rows.Err()
+}
+
+// mirrorColumns returns the columns table has in both databases, in the
+// source's order. It is empty when the source has no such table.
+func mirrorColumns(ctx context.Context, src mirrorSource, dst *SQLiteStorage, table string) ([]string, error) {
+	source, err := src.tableColumns(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	target, err := dst.tableColumns(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	inTarget := make(map[string]bool, len(target))
+	for _, c := range target {
+		inTarget[c] = true
+	}
+	var out []string
+	for _, c := range source {
+		if inTarget[c] {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+// mirrorRowsTx copies table's rows belonging to replayIDs (every row when
+// replayColumn is empty) from src into tx, streaming them in multi-row
+// INSERTs. It returns how many rows it copied.
+func mirrorRowsTx(ctx context.Context, tx *sql.Tx, src *sql.DB, table string, cols []string, replayColumn string, replayIDs []int64) (int64, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(cols, ", "), table)
+	args := make([]any, len(replayIDs))
+	if replayColumn != "" {
+		for i, id := range replayIDs {
+			args[i] = id
+		}
+		query += fmt.Sprintf(" WHERE %s IN (%s)", replayColumn, strings.TrimSuffix(strings.Repeat("?, ", len(replayIDs)), ", "))
+	}
+	rows, err := src.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read source %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	batchRows := max(1, min(500, mirrorMaxParams/len(cols)))
+	rowPlaceholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(cols)), ", ") + ")"
+	insertPrefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES ", table, strings.Join(cols, ", "))
+	batch := make([]any, 0, batchRows*len(cols))
+	flush := func() error {
+		n := len(batch) / len(cols)
+		if n == 0 {
+			return nil
+		}
+		q := insertPrefix + strings.TrimSuffix(strings.Repeat(rowPlaceholders+", ", n), ", ")
+		if _, err := tx.ExecContext(ctx, q, batch...); err != nil {
+			return fmt.Errorf("failed to copy %s rows: %w", table, err)
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	var copied int64
+	values := make([]any, len(cols))
+	ptrs := make([]any, len(cols))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return copied, fmt.Errorf("failed to scan source %s row: %w", table, err)
+		}
+		batch = append(batch, values...)
+		copied++
+		if len(batch) == batchRows*len(cols) {
+			if err := flush(); err != nil {
+				return copied, err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return copied, fmt.Errorf("failed to read source %s: %w", table, err)
+	}
+	return copied, flush()
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestMirrorFrom(t *testing.T) {
+	ctx := context.Background()
+	src := newIngestedStore(t)
+	if err := src.RecordIngestFailures(ctx, []IngestFailure{{
+		FilePath: "/replays/broken.rep", ErrorClass: IngestFailureParseError, Message: "boom", ScrepdbVersion: "test",
+	}}); err != nil {
+		t.Fatalf("RecordIngestFailures: %v", err)
+	}
+
+	dst, err := NewSQLiteStorage(filepath.Join(t.TempDir(), "mirror.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage: %v", err)
+	}
+	t.Cleanup(func() { _ = dst.Close() })
+	if err := dst.Initialize(ctx, true, true); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+
+	tables := append(mirrorTableNames(), "ingest_failures")
+	expectSameCounts := func(step string) {
+		t.Helper()
+		want, err := collectCounts(src, tables)
+		if err != nil {
+			t.Fatalf("%s: source counts: %v", step, err)
+		}
+		got, err := collectCounts(dst, tables)
+		if err != nil {
+			t.Fatalf("%s: mirror counts: %v", step, err)
+		}
+		if table := compareCounts(want, got); table != "" {
+			t.Fatalf("%s: mirror has %d %s rows, source %d", step, got[table], table, want[table])
+		}
+	}
+
+	stats, err := dst.mirrorFrom(ctx, src)
+	if err != nil {
+		t.Fatalf("mirrorFrom: %v", err)
+	}
+	replays, err := countTable(ctx, src, "replays")
+	if err != nil {
+		t.Fatalf("countTable: %v", err)
+	}
+	if stats.Replays != replays || stats.RemovedReplays != 0 || stats.IngestFailures != 1 {
+		t.Fatalf("first mirror stats = %+v, want %d replays and 1 failure", stats, replays)
+	}
+	expectSameCounts("first mirror")
+
+	if stats, err = dst.mirrorFrom(ctx, src); err != nil {
+		t.Fatalf("mirrorFrom again: %v", err)
+	}
+	if stats.Replays != 0 || stats.RemovedReplays != 0 || stats.Regrouped != 0 {
+		t.Fatalf("unchanged source re-copied rows: %+v", stats)
+	}
+
+	// One replay deleted, one re-analyzed, one regrouped.
+	var ids []int64
+	rows, err := src.db.QueryContext(ctx, "SELECT id FROM replays ORDER BY id LIMIT 3")
+	if err != nil {
+		t.Fatalf("list replays: %v", err)
+	}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if len(ids) < 3 {
+		t.Skipf("need 3 replays, have %d", len(ids))
+	}
+	for _, q := range []struct {
+		sql string
+		id  int64
+	}{
+		{"DELETE FROM replays WHERE id = ?", ids[0]},
+		{"UPDATE replays SET analyzer_algorithm_version = analyzer_algorithm_version + 1 WHERE id = ?", ids[1]},
+		{"UPDATE replays SET game_group_id = -1 WHERE id = ?", ids[2]},
+	} {
+		if _, err := src.db.ExecContext(ctx, q.sql, q.id); err != nil {
+			t.Fatalf("%s: %v", q.sql, err)
+		}
+	}
+
+	if stats, err = dst.mirrorFrom(ctx, src); err != nil {
+		t.Fatalf("mirrorFrom after changes: %v", err)
+	}
+	if stats.Replays != 1 || stats.RemovedReplays != 2 || stats.Regrouped != 1 {
+		t.Fatalf("mirror stats after changes = %+v, want 1 copied, 2 removed, 1 regrouped", stats)
+	}
+	expectSameCounts("after changes")
+	var group int64
+	if err := dst.db.QueryRowContext(ctx, "SELECT game_group_id FROM replays WHERE id = ?", ids[2]).Scan(&group); err != nil || group != -1 {
+		t.Fatalf("regrouped replay has game_group_id %d (%v), want -1", group, err)
+	}
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/marianogappa/screpdb/internal/playeragg"
+)
+
+// updatePlayerAggregatesTx brings the player aggregate tables up to date
+// with replayIDs, whose rows were just written, and with the replays sharing
+// fingerprints, which were just regrouped. It does nothing until the tables
+// have been built (see playeragg.Current): an unbuilt database keeps
+// computing its players pages live, and RebuildPlayerAggregates starts from
+// scratch anyway.
+func (s *SQLiteStorage) updatePlayerAggregatesTx(ctx context.Context, db dbtx, replayIDs []int64, fingerprints ...string) error {
+	if !s.playerAggregates {
+		return nil
+	}
+	current, err := playeragg.Current(ctx, db)
+	if err != nil || !current {
+		return err
+	}
+	if err := playeragg.Update(ctx, db, replayIDs, fingerprints); err != nil {
+		return fmt.Errorf("failed to update player aggregates: %w", err)
+	}
+	return nil
+}
+
+// refreshPlayerAggregatesTx is updatePlayerAggregatesTx for bulk changes
+// (merges, mirror refreshes, regrouping everything): it recomputes the facts
+// of replayIDs and then every player's aggregates.
+func (s *SQLiteStorage) refreshPlayerAggregatesTx(ctx context.Context, db dbtx, replayIDs []int64) error {
+	if !s.playerAggregates {
+		return nil
+	}
+	current, err := playeragg.Current(ctx, db)
+	if err != nil || !current {
+		return err
+	}
+	if err := playeragg.UpdateFacts(ctx, db, replayIDs); err != nil {
+		return fmt.Errorf("failed to update player aggregates: %w", err)
+	}
+	if err := playeragg.RefreshAll(ctx, db); err != nil {
+		return fmt.Errorf("failed to update player aggregates: %w", err)
+	}
+	return nil
+}
+
+// RebuildPlayerAggregates recomputes the player aggregate tables from every
+// stored replay and marks them built, after which ingest, re-analysis,
+// merges and mirror refreshes keep them current.
+func (s *SQLiteStorage) RebuildPlayerAggregates(ctx context.Context) (playeragg.Stats, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return playeragg.Stats{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stats, err := playeragg.Rebuild(ctx, tx)
+	if err != nil {
+		return stats, fmt.Errorf("failed to rebuild player aggregates: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return stats, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return stats, nil
+}
+
+// PlayerAggregatesCurrent reports whether the player aggregate tables are
+// built under the current playeragg.Version.
+func (s *SQLiteStorage) PlayerAggregatesCurrent(ctx context.Context) (bool, error) {
+	return playeragg.Current(ctx, s.db)
+}
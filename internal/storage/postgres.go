@@ -0,0 +1,361 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/marianogappa/screpdb/internal/chatsearch"
+	"github.com/marianogappa/screpdb/internal/fileops"
+	"github.com/marianogappa/screpdb/internal/migrations"
+	"github.com/marianogappa/screpdb/internal/patterns/core"
+)
+
+// postgresCommandBatchRows is how many command rows PostgresStorage sends per
+// INSERT. Unlike in-process SQLite, every statement is a network round trip;
+// 22 columns × 500 rows stays well under PostgreSQL's 65535 bind parameters.
+const postgresCommandBatchRows = 500
+
+// PostgresStorage implements Storage on a PostgreSQL server, so a team can
+// share one corpus. It runs SQLiteStorage's ingestion and lookup queries,
+// which stick to SQL both engines accept, over a connection that rewrites
+// their ? placeholders to PostgreSQL's $n. Only schema management (the
+// internal/migrations/postgres set) and schema introspection are its own.
+type PostgresStorage struct {
+	core *SQLiteStorage
+	db   *sql.DB
+}
+
+// NewPostgresStorage connects to the PostgreSQL database at databaseURL, a
+// postgres:// URL or key=value connection string.
+func NewPostgresStorage(databaseURL string) (*PostgresStorage, error) {
+	cfg, err := pgx.ParseConfig(databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database URL: %w", err)
+	}
+	db := sql.OpenDB(rebindConnector{stdlib.GetConnector(*cfg)})
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+	return &PostgresStorage{
+		core: &SQLiteStorage{db: db, commandBatchRows: postgresCommandBatchRows},
+		db:   db,
+	}, nil
+}
+
+// Open returns the PostgreSQL storage at databaseURL when it is set, and the
+// SQLite storage at sqlitePath otherwise.
+func Open(sqlitePath, databaseURL string) (Storage, error) {
+	if strings.TrimSpace(databaseURL) != "" {
+		return NewPostgresStorage(databaseURL)
+	}
+	return NewSQLiteStorage(sqlitePath)
+}
+
+// RedactDatabaseURL returns databaseURL fit for logs: the password of a URL
+// is masked, and a key=value connection string is reduced to its host and
+// database name.
+func RedactDatabaseURL(databaseURL string) string {
+	if u, err := url.Parse(databaseURL); err == nil && u.Scheme != "" {
+		return u.Redacted()
+	}
+	cfg, err := pgx.ParseConfig(databaseURL)
+	if err != nil {
+		return "(unparseable database URL)"
+	}
+	return fmt.Sprintf("host=%s port=%d dbname=%s", cfg.Host, cfg.Port, cfg.Database)
+}
+
+// SetCommandStorageOptions controls low-value command persistence behavior.
+// compactCommands is ignored: only SQLite can decode command blobs in SQL,
+// so PostgreSQL always stores rows.
+func (s *PostgresStorage) SetCommandStorageOptions(storeRightClicks bool, skipHotkeys bool, compactCommands bool) {
+	s.core.SetCommandStorageOptions(storeRightClicks, skipHotkeys, false)
+}
+
+// Initialize creates the database schema using the PostgreSQL migrations. If
+// clean is true, drops the replay tables first. cleanDashboard is accepted
+// for interface parity: dashboard tables live in the dashboard's local
+// SQLite mirror, never in PostgreSQL.
+func (s *PostgresStorage) Initialize(ctx context.Context, clean bool, cleanDashboard bool) error {
+	_ = cleanDashboard
+	if clean {
+		if err := migrations.DropPostgresMigrations(ctx, s.db); err != nil {
+			return fmt.Errorf("failed to drop postgres migrations: %w", err)
+		}
+	}
+	if err := migrations.RunPostgresMigrations(ctx, s.db); err != nil {
+		return fmt.Errorf("failed to run postgres migrations: %w", err)
+	}
+	return nil
+}
+
+// StartIngestion starts the ingestion process with batching
+func (s *PostgresStorage) StartIngestion(ctx context.Context, hooks IngestionHooks) (ReplayDataChannel, <-chan error) {
+	return s.core.StartIngestion(ctx, hooks)
+}
+
+// ReplayExists checks if a replay already exists by file path or checksum
+func (s *PostgresStorage) ReplayExists(ctx context.Context, filePath, checksum string) (bool, error) {
+	return s.core.ReplayExists(ctx, filePath, checksum)
+}
+
+// FilterOutExistingReplays filters out replays that already exist in the database
+func (s *PostgresStorage) FilterOutExistingReplays(ctx context.Context, files []fileops.FileInfo) ([]fileops.FileInfo, error) {
+	return s.core.FilterOutExistingReplays(ctx, files)
+}
+
+// FilterOutExistingReplaysByPath filters out replays whose file_path is already known
+func (s *PostgresStorage) FilterOutExistingReplaysByPath(ctx context.Context, files []fileops.FileInfo) ([]fileops.FileInfo, error) {
+	return s.core.FilterOutExistingReplaysByPath(ctx, files)
+}
+
+// FilterOutUnchangedReplayFiles filters out known paths whose size and modification time are unchanged
+func (s *PostgresStorage) FilterOutUnchangedReplayFiles(ctx context.Context, files []fileops.FileInfo) ([]fileops.FileInfo, error) {
+	return s.core.FilterOutUnchangedReplayFiles(ctx, files)
+}
+
+// RefreshReplayFileStamps updates the recorded stamps of unchanged ingested files
+func (s *PostgresStorage) RefreshReplayFileStamps(ctx context.Context, files []fileops.FileInfo) error {
+	return s.core.RefreshReplayFileStamps(ctx, files)
+}
+
+// FilterOutKnownIngestFailures drops files that already failed under version with the same content
+func (s *PostgresStorage) FilterOutKnownIngestFailures(ctx context.Context, files []fileops.FileInfo, version string) ([]fileops.FileInfo, error) {
+	return s.core.FilterOutKnownIngestFailures(ctx, files, version)
+}
+
+// RecordIngestFailures upserts failures by file path
+func (s *PostgresStorage) RecordIngestFailures(ctx context.Context, failures []IngestFailure) error {
+	return s.core.RecordIngestFailures(ctx, failures)
+}
+
+// ListIngestFailures returns every ledger row, most recently failed first.
+func (s *PostgresStorage) ListIngestFailures(ctx context.Context) ([]IngestFailure, error) {
+	return s.core.ListIngestFailures(ctx)
+}
+
+// ClearResolvedIngestFailures deletes ledger rows for files that are now in the replays table
+func (s *PostgresStorage) ClearResolvedIngestFailures(ctx context.Context) (int64, error) {
+	return s.core.ClearResolvedIngestFailures(ctx)
+}
+
+// Query executes a SQL query and returns results
+func (s *PostgresStorage) Query(ctx context.Context, query string, args ...any) ([]map[string]any, error) {
+	return s.core.Query(ctx, query, args...)
+}
+
+// QueryColumns executes a SQL query and returns ordered column names and row values
+func (s *PostgresStorage) QueryColumns(ctx context.Context, maxRows int, query string, args ...any) ([]string, [][]any, bool, error) {
+	return s.core.QueryColumns(ctx, maxRows, query, args...)
+}
+
+// StorageName returns the storage backend name
+func (s *PostgresStorage) StorageName() string {
+	return StoragePostgres
+}
+
+// GetDatabaseSchema returns the database schema information, introspected
+// from information_schema in the same format as SQLiteStorage's.
+func (s *PostgresStorage) GetDatabaseSchema(ctx context.Context) (string, error) {
+	var schema strings.Builder
+	schema.WriteString("# Database Schema\n\n")
+
+	for _, relation := range schemaRelations {
+		tableName := relation.name
+		rows, err := s.db.QueryContext(ctx, `
+			SELECT column_name, data_type, is_nullable
+			FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = ?
+			ORDER BY ordinal_position`, tableName)
+		if err != nil {
+			return "", fmt.Errorf("failed to query schema for %s: %w", tableName, err)
+		}
+
+		schema.WriteString(fmt.Sprintf("## %s\n\n", tableName))
+		if relation.note != "" {
+			schema.WriteString(relation.note + "\n\n")
+		}
+		schema.WriteString("| Column | Type | Nullable |\n")
+		schema.WriteString("|--------|------|----------|\n")
+		for rows.Next() {
+			var name, dataType, nullable string
+			if err := rows.Scan(&name, &dataType, &nullable); err != nil {
+				rows.Close()
+				return "", fmt.Errorf("failed to scan schema info for %s: %w", tableName, err)
+			}
+			schema.WriteString(fmt.Sprintf("| %s | %s | %s |\n", name, dataType, nullable))
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return "", fmt.Errorf("failed to read schema info for %s: %w", tableName, err)
+		}
+		schema.WriteString("\n")
+	}
+
+	return schema.String(), nil
+}
+
+// SearchChat returns the in-game chat messages matching opts, using the
+// idx_commands_chat_search text index.
+func (s *PostgresStorage) SearchChat(ctx context.Context, opts chatsearch.Options) ([]chatsearch.Hit, error) {
+	return chatsearch.Search(ctx, s.db, chatsearch.DialectPostgres, opts)
+}
+
+// FilterOutExistingPatternDetections filters out replays that already have pattern detection run
+func (s *PostgresStorage) FilterOutExistingPatternDetections(ctx context.Context, files []fileops.FileInfo, algorithmVersion int) ([]fileops.FileInfo, error) {
+	return s.core.FilterOutExistingPatternDetections(ctx, files, algorithmVersion)
+}
+
+// DeletePatternDetectionsForReplay clears a replay's replay_events rows and algorithm version
+func (s *PostgresStorage) DeletePatternDetectionsForReplay(ctx context.Context, replayID int64) error {
+	return s.core.DeletePatternDetectionsForReplay(ctx, replayID)
+}
+
+// BatchInsertPatternResults inserts pattern detection results in batch
+func (s *PostgresStorage) BatchInsertPatternResults(ctx context.Context, results []*core.PatternResult) error {
+	return s.core.BatchInsertPatternResults(ctx, results)
+}
+
+// Close closes the database connection
+func (s *PostgresStorage) Close() error {
+	return s.db.Close()
+}
+
+// tableColumns returns table's column names in declaration order.
+func (s *PostgresStorage) tableColumns(ctx context.Context, table string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = ?
+		ORDER BY ordinal_position`, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s columns: %w", table, err)
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		out = append(out, name)
+	}
+	return out, rows.Err()
+}
+
+// rebindPlaceholders rewrites the ? placeholders of query to PostgreSQL's
+// $1, $2, ... Question marks inside string literals, quoted identifiers and
+// comments are left alone.
+func rebindPlaceholders(query string) string {
+	if !strings.Contains(query, "?") {
+		return query
+	}
+	var b strings.Builder
+	b.Grow(len(query) + 16)
+	n := 0
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(query[i+1:], c)
+			if end < 0 {
+				b.WriteString(query[i:])
+				return b.String()
+			}
+			// A doubled quote escapes itself and reads as two literals.
+			b.WriteString(query[i : i+end+2])
+			i += end + 1
+		case c == '-' && i+1 < len(query) && query[i+1] == '-':
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				b.WriteString(query[i:])
+				return b.String()
+			}
+			b.WriteString(query[i : i+end])
+			i += end - 1
+		case c == '/' && i+1 < len(query) && query[i+1] == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				b.WriteString(query[i:])
+				return b.String()
+			}
+			b.WriteString(query[i : i+end+4])
+			i += end + 3
+		case c == '?':
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// pgxConn is the set of database/sql driver interfaces pgx's stdlib
+// connections implement.
+type pgxConn interface {
+	driver.Conn
+	driver.ConnPrepareContext
+	driver.ConnBeginTx
+	driver.ExecerContext
+	driver.QueryerContext
+	driver.Pinger
+	driver.NamedValueChecker
+	driver.SessionResetter
+}
+
+// rebindConnector hands out pgx connections that accept ? placeholders.
+type rebindConnector struct {
+	driver.Connector
+}
+
+func (c rebindConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pc, ok := conn.(pgxConn)
+	if !ok {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected pgx connection type %T", conn)
+	}
+	return rebindConn{pc}, nil
+}
+
+// rebindConn rebinds the placeholders of every statement it runs. Queries
+// without arguments run verbatim: they may be multi-statement migrations or
+// user SQL, where a ? is PostgreSQL's own operator.
+type rebindConn struct {
+	pgxConn
+}
+
+func (c rebindConn) Prepare(query string) (driver.Stmt, error) {
+	return c.pgxConn.Prepare(rebindPlaceholders(query))
+}
+
+func (c rebindConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return c.pgxConn.PrepareContext(ctx, rebindPlaceholders(query))
+}
+
+func (c rebindConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if len(args) > 0 {
+		query = rebindPlaceholders(query)
+	}
+	return c.pgxConn.ExecContext(ctx, query, args)
+}
+
+func (c rebindConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if len(args) > 0 {
+		query = rebindPlaceholders(query)
+	}
+	return c.pgxConn.QueryContext(ctx, query, args)
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/marianogappa/screpdb/internal/models"
+)
+
+// insertProductionBusySpansTx stores a replay's estimated production building
+// busy spans. playerIDMap maps replay-local player IDs to database IDs; spans
+// of players it lacks are dropped.
+func insertProductionBusySpansTx(ctx context.Context, db dbtx, replayID int64, spans []models.ProductionBusySpan, playerIDMap map[byte]int64) error {
+	const batchSize = 500
+	for i := 0; i < len(spans); i += batchSize {
+		batch := spans[i:min(i+batchSize, len(spans))]
+		valueStrings := make([]string, 0, len(batch))
+		valueArgs := make([]any, 0, len(batch)*8)
+		for _, span := range batch {
+			playerID, ok := playerIDMap[span.PlayerID]
+			if !ok {
+				continue
+			}
+			valueStrings = append(valueStrings, "(?, ?, ?, ?, ?, ?, ?, ?)")
+			valueArgs = append(valueArgs,
+				replayID,
+				playerID,
+				span.Building,
+				span.BuildingIndex,
+				span.ReadySecond,
+				span.WindowEndSecond,
+				span.StartSecond,
+				span.EndSecond,
+			)
+		}
+		if len(valueStrings) == 0 {
+			continue
+		}
+		query := `
+			INSERT INTO production_busy_spans (
+				replay_id, player_id, building, building_index, ready_second,
+				window_end_second, start_second, end_second
+			) VALUES ` + strings.Join(valueStrings, ", ")
+		if _, err := db.ExecContext(ctx, query, valueArgs...); err != nil {
+			return fmt.Errorf("failed to insert production busy spans: %w", err)
+		}
+	}
+	return nil
+}
@@ -8,11 +8,16 @@ import (
 	"runtime/debug"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/icza/screp/rep/repcmd"
 	"github.com/icza/screp/rep/repcore"
 	_ "modernc.org/sqlite"
 
+	"github.com/marianogappa/screpdb/internal/analysisinput"
+	"github.com/marianogappa/screpdb/internal/backup"
+	"github.com/marianogappa/screpdb/internal/chatsearch"
+	"github.com/marianogappa/screpdb/internal/cmdblob"
 	"github.com/marianogappa/screpdb/internal/crashreport"
 	"github.com/marianogappa/screpdb/internal/fileops"
 	"github.com/marianogappa/screpdb/internal/migrations"
@@ -21,6 +26,7 @@ import (
 	"github.com/marianogappa/screpdb/internal/patterns/core"
 	"github.com/marianogappa/screpdb/internal/patterns/markers"
 	"github.com/marianogappa/screpdb/internal/patterns/worldstate"
+	"github.com/marianogappa/screpdb/internal/playeragg"
 	"github.com/marianogappa/screpdb/internal/profile"
 )
 
@@ -30,8 +36,27 @@ type SQLiteStorage struct {
 	dbPath           string
 	storeRightClicks bool
 	skipHotkeys      bool
+	// compactCommands stores compactActionTypes commands in command_blobs
+	// rather than commands_low_value (see command_blobs.go).
+	compactCommands bool
+	// commandBatchRows is how many command rows go in one INSERT; 0 or 1
+	// means one prepared-statement Exec per row (see insertCommandsBatchTx).
+	commandBatchRows int
+	// playerAggregates keeps the player aggregate tables current as replays
+	// are written (see player_aggregates.go). Only SQLite databases have
+	// them maintained; PostgresStorage's core leaves it off.
+	playerAggregates bool
+	// ingestBatchReplays caps how many replays StartIngestion commits per
+	// transaction; 0 means defaultIngestBatchReplays.
+	ingestBatchReplays int
 }
 
+// defaultIngestBatchReplays is how many replays StartIngestion's writer
+// commits in one transaction at most. The writer only batches replays that
+// are already waiting, so a lone replay (e.g. in watch mode) is committed
+// right away.
+const defaultIngestBatchReplays = 64
+
 type dbtx interface {
 	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
 	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
@@ -83,6 +108,7 @@ var (
 		"player_stopped_playing",
 		"late_alliance",
 		"team_stacking_detected",
+		"supply_block",
 	})
 	allowedReplayEventLocationTypes = enumSetFromNames([]string{
 		"starting",
@@ -132,20 +158,25 @@ func NewSQLiteStorage(dbPath string) (*SQLiteStorage, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return &SQLiteStorage{db: db, dbPath: dbPath}, nil
+	return &SQLiteStorage{db: db, dbPath: dbPath, playerAggregates: true}, nil
 }
 
 // SetCommandStorageOptions controls low-value command persistence behavior.
-func (s *SQLiteStorage) SetCommandStorageOptions(storeRightClicks bool, skipHotkeys bool) {
+func (s *SQLiteStorage) SetCommandStorageOptions(storeRightClicks bool, skipHotkeys bool, compactCommands bool) {
 	s.storeRightClicks = storeRightClicks
 	s.skipHotkeys = skipHotkeys
+	s.compactCommands = compactCommands
 }
 
 // Initialize creates the database schema using migrations
 // If clean is true, drops all non-dashboard tables before creating new ones
 // If cleanDashboard is true, drops all dashboard tables
+// An existing database with pending migrations is backed up first (see
+// backup.BeforePendingMigrations).
 func (s *SQLiteStorage) Initialize(ctx context.Context, clean bool, cleanDashboard bool) error {
-	_ = ctx
+	if _, _, err := backup.BeforePendingMigrations(ctx, s.dbPath, migrations.MigrationSetReplay, migrations.MigrationSetDashboard); err != nil {
+		return err
+	}
 
 	// Drop dashboard migrations if requested
 	if cleanDashboard {
@@ -168,7 +199,7 @@ func (s *SQLiteStorage) Initialize(ctx context.Context, clean bool, cleanDashboa
 	if err := migrations.RunMigrationSet(s.dbPath, migrations.MigrationSetDashboard); err != nil {
 		return fmt.Errorf("failed to run dashboard migrations: %w", err)
 	}
-	return nil
+	return playeragg.MarkBuiltIfEmpty(ctx, s.db)
 }
 
 // StartIngestion starts the ingestion process with batching
@@ -193,7 +224,15 @@ func (s *SQLiteStorage) StartIngestion(ctx context.Context, hooks IngestionHooks
 			close(errChan)
 		}()
 
-		// Process replays sequentially to handle dependencies
+		// A single writer stores replays in arrival order. Parsing and pattern
+		// analysis already happened upstream, so the writer only inserts rows:
+		// it takes whatever replays are waiting, up to batchSize, and commits
+		// them in one transaction.
+		batchSize := s.ingestBatchReplays
+		if batchSize <= 0 {
+			batchSize = defaultIngestBatchReplays
+		}
+		batch := make([]*models.ReplayData, 0, batchSize)
 		for {
 			select {
 			case data, ok := <-dataChan:
@@ -203,38 +242,29 @@ func (s *SQLiteStorage) StartIngestion(ctx context.Context, hooks IngestionHooks
 					return
 				}
 
-				// Process this replay completely before moving to the next.
-				// A panic in here is recovered into a per-replay error so one
-				// malformed replay is skipped instead of crashing the whole
-				// ingest (and, on the GUI build, the dashboard) — #234.
-				err, panicked := s.storeReplayRecovered(ctx, data)
-				if panicked {
-					if hooks.OnStoreError != nil {
-						hooks.OnStoreError(err)
-					}
-					continue
-				}
-				if err != nil {
-					if isDuplicateReplayError(err) {
-						if hooks.OnDuplicateReplay != nil {
-							hooks.OnDuplicateReplay(err)
+				batch = append(batch[:0], data)
+				closed := false
+			fill:
+				for len(batch) < batchSize {
+					select {
+					case next, ok := <-dataChan:
+						if !ok {
+							closed = true
+							break fill
 						}
-						// No fmt.Printf fallback: duplicate-replay errors
-						// are expected (watch-mode re-add, race with the
-						// pre-check) and the caller's hook decides how
-						// to surface them. Silent default.
-						continue
-					}
-					if hooks.OnStoreError != nil {
-						hooks.OnStoreError(err)
-					} else {
-						fmt.Printf("Error storing replay: %v\n", err)
+						batch = append(batch, next)
+					default:
+						break fill
 					}
+				}
+
+				if err := s.storeReplayBatch(ctx, batch, hooks); err != nil {
 					errChan <- err
 					return
 				}
-				if hooks.OnReplayStored != nil {
-					hooks.OnReplayStored()
+				if closed {
+					errChan <- nil
+					return
 				}
 
 			case <-ctx.Done():
@@ -256,20 +286,147 @@ func (s *SQLiteStorage) StartIngestion(ctx context.Context, hooks IngestionHooks
 func (s *SQLiteStorage) storeReplayRecovered(ctx context.Context, data *models.ReplayData) (err error, panicked bool) {
 	defer func() {
 		if r := recover(); r != nil {
-			crashreport.ReportPanic(r, debug.Stack())
-			err = fmt.Errorf("panic while storing replay (this is a bug — please report it at "+
-				"https://github.com/marianogappa/screpdb/issues): %v", r)
-			panicked = true
+			err, panicked = storePanicError(r), true
 		}
 	}()
 	return s.storeReplayWithBatching(ctx, data), false
 }
 
+// storePanicError writes a crash report for a panic recovered while storing a
+// replay and returns the error the replay is skipped with.
+func storePanicError(r any) error {
+	crashreport.ReportPanic(r, debug.Stack())
+	return fmt.Errorf("panic while storing replay (this is a bug — please report it at "+
+		"https://github.com/marianogappa/screpdb/issues): %v", r)
+}
+
+// storeReplayBatch stores batch in one transaction, each replay under its own
+// savepoint so that a duplicate or a panic only discards that replay (#234).
+// Every replay is reported to hooks. It returns the first error that should
+// end ingestion; the replays stored before it are still committed, and each
+// replay after it is reported to OnStoreError as not stored.
+func (s *SQLiteStorage) storeReplayBatch(ctx context.Context, batch []*models.ReplayData, hooks IngestionHooks) error {
+	fail := func(err error) error {
+		if hooks.OnStoreError != nil {
+			hooks.OnStoreError(err)
+		} else {
+			fmt.Printf("Error storing replay: %v\n", err)
+		}
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fail(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer tx.Rollback()
+
+	var stored []*profile.Run
+	var fatal error
+	for i, data := range batch {
+		var run *profile.Run
+		if data != nil {
+			run, _ = data.Profile.(*profile.Run)
+		}
+		err, panicked := s.storeReplaySavepoint(ctx, tx, data, run)
+		if err == nil {
+			stored = append(stored, run)
+			continue
+		}
+		run.Done()
+		if panicked {
+			if hooks.OnStoreError != nil {
+				hooks.OnStoreError(err)
+			}
+			continue
+		}
+		if isDuplicateReplayError(err) {
+			if hooks.OnDuplicateReplay != nil {
+				hooks.OnDuplicateReplay(err)
+			}
+			// No fmt.Printf fallback: duplicate-replay errors are expected
+			// (watch-mode re-add, race with the pre-check) and the caller's
+			// hook decides how to surface them. Silent default.
+			continue
+		}
+		fatal = fail(err)
+		for _, rest := range batch[i+1:] {
+			var path string
+			if rest != nil {
+				run, _ := rest.Profile.(*profile.Run)
+				run.Done()
+				if rest.Replay != nil {
+					path = rest.Replay.FilePath
+				}
+			}
+			fail(fmt.Errorf("replay %s not stored: an earlier replay in its batch failed: %w", path, err))
+		}
+		break
+	}
+	if len(stored) == 0 {
+		return fatal
+	}
+
+	// The commit is shared by the whole batch, so each stored replay's
+	// profile gets an even share of it.
+	start := time.Now()
+	err = tx.Commit()
+	share := time.Since(start) / time.Duration(len(stored))
+	for _, run := range stored {
+		run.Add("commit", share)
+		run.Done()
+	}
+	if err != nil {
+		return fail(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+	if hooks.OnReplayStored != nil {
+		for range stored {
+			hooks.OnReplayStored()
+		}
+	}
+	return fatal
+}
+
+// storeReplaySavepoint runs storeReplayTx inside a savepoint of tx, rolling
+// back to it when the replay fails or panics so the rest of the transaction
+// is untouched. A panic is reported like in storeReplayRecovered.
+func (s *SQLiteStorage) storeReplaySavepoint(ctx context.Context, tx *sql.Tx, data *models.ReplayData, run *profile.Run) (err error, panicked bool) {
+	if _, err := tx.ExecContext(ctx, `SAVEPOINT replay`); err != nil {
+		return fmt.Errorf("failed to create savepoint: %w", err), false
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err, panicked = storePanicError(r), true
+		}
+		if err == nil {
+			if _, releaseErr := tx.ExecContext(ctx, `RELEASE SAVEPOINT replay`); releaseErr != nil {
+				err = fmt.Errorf("failed to release savepoint: %w", releaseErr)
+			}
+			return
+		}
+		if _, rollbackErr := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT replay`); rollbackErr != nil {
+			err, panicked = fmt.Errorf("failed to roll back replay after %v: %w", err, rollbackErr), false
+			return
+		}
+		if _, releaseErr := tx.ExecContext(ctx, `RELEASE SAVEPOINT replay`); releaseErr != nil {
+			err, panicked = fmt.Errorf("failed to release savepoint after %v: %w", err, releaseErr), false
+		}
+	}()
+	return s.storeReplayTx(ctx, tx, data, run), false
+}
+
+// isDuplicateReplayError reports whether err is the file_checksum uniqueness
+// violation, as worded by SQLite or PostgreSQL.
 func isDuplicateReplayError(err error) bool {
-	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed: replays.file_checksum")
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "UNIQUE constraint failed: replays.file_checksum") ||
+		strings.Contains(msg, `violates unique constraint "replays_file_checksum_key"`)
 }
 
-// storeReplayWithBatching stores a replay data structure using sequential processing
+// storeReplayWithBatching stores a replay data structure in its own transaction
 func (s *SQLiteStorage) storeReplayWithBatching(ctx context.Context, data *models.ReplayData) error {
 	// Optional profile run, attached upstream when SCREPDB_INGEST_PROFILE is set.
 	// nil-safe: every Phase()/Done() call below is a no-op when run is nil.
@@ -283,6 +440,24 @@ func (s *SQLiteStorage) storeReplayWithBatching(ctx context.Context, data *model
 	}
 	defer tx.Rollback()
 
+	if err := s.storeReplayTx(ctx, tx, data, run); err != nil {
+		return err
+	}
+
+	// Commit the transaction
+	stop := run.Phase("commit")
+	err = tx.Commit()
+	stop()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// storeReplayTx writes every row of one replay using tx, leaving the commit
+// to the caller.
+func (s *SQLiteStorage) storeReplayTx(ctx context.Context, tx *sql.Tx, data *models.ReplayData, run *profile.Run) error {
 	// Step 1: Insert replay sequentially
 	stop := run.Phase("replay_ins")
 	replayID, err := s.insertReplaySequentialTx(ctx, tx, data.Replay)
@@ -293,6 +468,9 @@ func (s *SQLiteStorage) storeReplayWithBatching(ctx context.Context, data *model
 	if replayID == 0 {
 		return fmt.Errorf("replay insert returned invalid ID: 0")
 	}
+	if err := s.assignGameGroupTx(ctx, tx, replayID, data.Replay.GameFingerprint); err != nil {
+		return fmt.Errorf("failed to group replay: %w", err)
+	}
 
 	// Step 2: Insert players and map IDs
 	stop = run.Phase("players")
@@ -305,15 +483,34 @@ func (s *SQLiteStorage) storeReplayWithBatching(ctx context.Context, data *model
 	// Step 3: Update commands with correct IDs and insert them
 	s.updateEntityIDs(data, replayID, playerIDs)
 
-	// Step 4: Insert commands in batch
+	// Step 4: Insert commands in batch, recording where each one went into
+	// the analysis input
+	analysisInput, _ := data.AnalysisInput.(*analysisinput.Input)
 	if len(data.Commands) > 0 {
 		stop = run.Phase("cmds")
-		err := s.insertCommandsBatchTx(ctx, tx, data.Commands)
+		err := s.insertCommandsBatchTx(ctx, tx, data.Commands, analysisInput)
 		stop()
 		if err != nil {
 			return fmt.Errorf("failed to insert commands: %w", err)
 		}
 	}
+	if analysisInput != nil {
+		if err := insertAnalysisInputTx(ctx, tx, replayID, analysisInput); err != nil {
+			return err
+		}
+	}
+	if err := insertEconomySamplesTx(ctx, tx, replayID, data.Economy, playerIDs); err != nil {
+		return err
+	}
+	if err := insertProductionBusySpansTx(ctx, tx, replayID, data.ProductionBusy, playerIDs); err != nil {
+		return err
+	}
+	if err := insertLarvaSamplesTx(ctx, tx, replayID, data.Larva, playerIDs); err != nil {
+		return err
+	}
+	if err := insertHotkeyUsageTx(ctx, tx, replayID, data.Hotkeys, playerIDs); err != nil {
+		return err
+	}
 
 	// Step 5: Process pattern detection results if orchestrator is present
 	if data.PatternOrchestrator != nil {
@@ -322,15 +519,11 @@ func (s *SQLiteStorage) storeReplayWithBatching(ctx context.Context, data *model
 		}
 	}
 
-	// Commit the transaction
-	stop = run.Phase("commit")
-	err = tx.Commit()
+	// Step 6: Fold the replay into the player aggregates
+	stop = run.Phase("aggregates")
+	err = s.updatePlayerAggregatesTx(ctx, tx, []int64{replayID}, data.Replay.GameFingerprint)
 	stop()
-	if err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	return nil
+	return err
 }
 
 // processPatternResultsTx processes pattern detection results (uses provided connection/transaction)
@@ -393,11 +586,13 @@ func (s *SQLiteStorage) insertReplaySequentialTx(ctx context.Context, db dbtx, r
 		INSERT INTO replays (
 			file_path, file_checksum, file_name, created_at, replay_date, title, host, map_name, map_width, map_height,
 			duration_seconds, frame_count, engine_version, engine, game_speed, game_type, map_kind, team_format, matchup, home_team_size, avail_slots_count,
-			team_stacking, team_info_incomplete
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			team_stacking, team_info_incomplete, game_fingerprint, file_size, file_mod_time
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
 	`
+	fileSize, fileModTime := fileStampArgs(replay.FileSize, replay.FileModTime)
 
-	res, err := db.ExecContext(ctx, query,
+	rows, err := db.QueryContext(ctx, query,
 		replay.FilePath,
 		replay.FileChecksum,
 		replay.FileName,
@@ -421,14 +616,23 @@ func (s *SQLiteStorage) insertReplaySequentialTx(ctx context.Context, db dbtx, r
 		int32(replay.AvailSlotsCount),
 		replay.TeamStacking,
 		replay.TeamInfoIncomplete,
+		replay.GameFingerprint,
+		fileSize,
+		fileModTime,
 	)
 	if err != nil {
 		return 0, fmt.Errorf("failed to insert replay: %w", err)
 	}
+	defer rows.Close()
 
-	id, err := res.LastInsertId()
-	if err != nil {
-		return 0, fmt.Errorf("failed to get replay ID: %w", err)
+	var id int64
+	if rows.Next() {
+		if err := rows.Scan(&id); err != nil {
+			return 0, fmt.Errorf("failed to get replay ID: %w", err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to insert replay: %w", err)
 	}
 	if id == 0 {
 		return 0, fmt.Errorf("replay insert returned invalid ID: 0")
@@ -543,44 +747,45 @@ func (s *SQLiteStorage) insertPlayersBatchTx(ctx context.Context, db dbtx, repla
 // prepared-statement Exec on the driver is already a tight bind+step loop with
 // no marshaling overhead, and bigger statements just add SQL parse and
 // per-row evaluation cost without removing any round-trips (everything is
-// in-process). See git history for the experiment.
-func (s *SQLiteStorage) insertCommandsBatchTx(ctx context.Context, db dbtx, commands []*models.Command) error {
+// in-process). See git history for the experiment. Over a network
+// connection the opposite holds, so PostgresStorage sets commandBatchRows.
+func (s *SQLiteStorage) insertCommandsBatchTx(ctx context.Context, db dbtx, commands []*models.Command, analysisInput *analysisinput.Input) error {
 	if len(commands) == 0 {
 		return nil
 	}
 
-	insertCommandSQL := `
-		INSERT INTO %s (
-			replay_id, player_id, frame, seconds_from_game_start, action_type,
-			x, y, is_queued, order_name, unit_type, unit_types, tech_name, upgrade_name,
-			hotkey_type, hotkey_group, game_speed, vision_player_ids, alliance_player_ids,
-			is_allied_victory, general_data, chat_message, leave_reason
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`
-	highValueStmt, err := db.PrepareContext(ctx, fmt.Sprintf(insertCommandSQL, "commands"))
+	highValueWriter, err := newCommandWriter(ctx, db, "commands", s.commandBatchRows)
 	if err != nil {
 		return fmt.Errorf("failed to prepare command insert statement: %w", err)
 	}
-	defer highValueStmt.Close()
+	defer highValueWriter.Close()
 
-	lowValueStmt, err := db.PrepareContext(ctx, fmt.Sprintf(insertCommandSQL, "commands_low_value"))
+	lowValueWriter, err := newCommandWriter(ctx, db, "commands_low_value", s.commandBatchRows)
 	if err != nil {
 		return fmt.Errorf("failed to prepare low-value command insert statement: %w", err)
 	}
-	defer lowValueStmt.Close()
+	defer lowValueWriter.Close()
+
+	// With compactCommands, compactActionTypes rows go to a blob instead of
+	// lowValueWriter, keeping their position among the low-value rows.
+	var blobRows []cmdblob.Row
+	var lowValueOrdinal int64
 
 	for _, command := range commands {
 		actionType := normalizeEnumValue(command.ActionType, allowedCommandActionTypes)
-		if actionType == "Right Click" && !s.storeRightClicks {
-			continue
-		}
-		if actionType == "Hotkey" && s.skipHotkeys {
+		if (actionType == "Right Click" && !s.storeRightClicks) || (actionType == "Hotkey" && s.skipHotkeys) {
+			if analysisInput != nil {
+				analysisInput.Record(command, analysisinput.SourceOmitted)
+			}
 			continue
 		}
 
-		targetStmt := highValueStmt
+		targetWriter, source := highValueWriter, analysisinput.SourceCommands
 		if _, ok := lowValueActionTypes[actionType]; ok {
-			targetStmt = lowValueStmt
+			targetWriter, source = lowValueWriter, analysisinput.SourceLowValue
+		}
+		if analysisInput != nil {
+			analysisInput.Record(command, source)
 		}
 
 		orderName := normalizeNullableEnum(command.OrderName, allowedCommandOrders)
@@ -629,7 +834,39 @@ func (s *SQLiteStorage) insertCommandsBatchTx(ctx context.Context, db dbtx, comm
 			hotkeyGroup = &val
 		}
 
-		if _, err := targetStmt.ExecContext(ctx,
+		if source == analysisinput.SourceLowValue {
+			ordinal := lowValueOrdinal
+			lowValueOrdinal++
+			if _, ok := compactActionTypes[actionType]; ok && s.compactCommands {
+				blobRows = append(blobRows, cmdblob.Row{
+					Ordinal:           ordinal,
+					PlayerID:          command.PlayerID,
+					Frame:             int64(command.Frame),
+					Second:            int64(command.SecondsFromGameStart),
+					ActionType:        actionType,
+					X:                 widenInt32(x),
+					Y:                 widenInt32(y),
+					IsQueued:          command.IsQueued,
+					OrderName:         orderName,
+					UnitType:          unitType,
+					UnitTypes:         command.UnitTypes,
+					TechName:          techName,
+					UpgradeName:       upgradeName,
+					HotkeyType:        hotkeyType,
+					HotkeyGroup:       widenInt32(hotkeyGroup),
+					GameSpeed:         commandSpeed,
+					VisionPlayerIDs:   visionPlayerIdsJSON,
+					AlliancePlayerIDs: alliancePlayerIdsJSON,
+					IsAlliedVictory:   command.IsAlliedVictory,
+					GeneralData:       command.GeneralData,
+					ChatMessage:       command.ChatMessage,
+					LeaveReason:       leaveReason,
+				})
+				continue
+			}
+		}
+
+		if err := targetWriter.Add(ctx,
 			int32(command.ReplayID),
 			int32(command.PlayerID),
 			command.Frame,
@@ -657,9 +894,89 @@ func (s *SQLiteStorage) insertCommandsBatchTx(ctx context.Context, db dbtx, comm
 		}
 	}
 
+	if err := highValueWriter.Flush(ctx); err != nil {
+		return fmt.Errorf("failed to execute command insert: %w", err)
+	}
+	if err := lowValueWriter.Flush(ctx); err != nil {
+		return fmt.Errorf("failed to execute command insert: %w", err)
+	}
+	if len(blobRows) > 0 {
+		if err := insertCommandBlobTx(ctx, db, commands[0].ReplayID, blobRows); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// commandColumns are the columns insertCommandsBatchTx writes, in argument
+// order.
+const commandColumns = `replay_id, player_id, frame, seconds_from_game_start, action_type,
+	x, y, is_queued, order_name, unit_type, unit_types, tech_name, upgrade_name,
+	hotkey_type, hotkey_group, game_speed, vision_player_ids, alliance_player_ids,
+	is_allied_victory, general_data, chat_message, leave_reason`
+
+const commandColumnCount = 22
+
+// commandWriter inserts rows into one command table: with batchRows <= 1 one
+// prepared-statement Exec per row, otherwise multi-row INSERTs of up to
+// batchRows rows, flushed in insertion order.
+type commandWriter struct {
+	db        dbtx
+	table     string
+	stmt      *sql.Stmt
+	batchRows int
+	pending   []any
+}
+
+func newCommandWriter(ctx context.Context, db dbtx, table string, batchRows int) (*commandWriter, error) {
+	w := &commandWriter{db: db, table: table, batchRows: batchRows}
+	if batchRows > 1 {
+		w.pending = make([]any, 0, batchRows*commandColumnCount)
+		return w, nil
+	}
+	stmt, err := db.PrepareContext(ctx, w.insertSQL(1))
+	if err != nil {
+		return nil, err
+	}
+	w.stmt = stmt
+	return w, nil
+}
+
+func (w *commandWriter) insertSQL(rows int) string {
+	row := "(" + strings.TrimSuffix(strings.Repeat("?, ", commandColumnCount), ", ") + ")"
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", w.table, commandColumns,
+		strings.TrimSuffix(strings.Repeat(row+", ", rows), ", "))
+}
+
+// Add inserts one row of commandColumnCount values, or queues it.
+func (w *commandWriter) Add(ctx context.Context, args ...any) error {
+	if w.stmt != nil {
+		_, err := w.stmt.ExecContext(ctx, args...)
+		return err
+	}
+	w.pending = append(w.pending, args...)
+	if len(w.pending) >= w.batchRows*commandColumnCount {
+		return w.Flush(ctx)
+	}
+	return nil
+}
+
+// Flush inserts the queued rows.
+func (w *commandWriter) Flush(ctx context.Context) error {
+	if len(w.pending) == 0 {
+		return nil
+	}
+	_, err := w.db.ExecContext(ctx, w.insertSQL(len(w.pending)/commandColumnCount), w.pending...)
+	w.pending = w.pending[:0]
+	return err
+}
+
+func (w *commandWriter) Close() {
+	if w.stmt != nil {
+		w.stmt.Close()
+	}
+}
+
 // updateEntityIDs updates all entities with the correct replay ID and player IDs
 func (s *SQLiteStorage) updateEntityIDs(data *models.ReplayData, replayID int64, playerIDs map[byte]int64) {
 	data.Replay.ID = replayID
@@ -812,19 +1129,46 @@ func (s *SQLiteStorage) FilterOutExistingReplays(ctx context.Context, files []fi
 
 // Query executes a SQL query and returns results
 func (s *SQLiteStorage) Query(ctx context.Context, query string, args ...any) ([]map[string]any, error) {
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	columns, rows, _, err := s.QueryColumns(ctx, 0, query, args...)
 	if err != nil {
 		return nil, err
 	}
+
+	var results []map[string]any
+	for _, values := range rows {
+		row := make(map[string]any)
+		for i, col := range columns {
+			row[col] = values[i]
+		}
+		results = append(results, row)
+	}
+	return results, nil
+}
+
+// QueryColumns executes a SQL query and returns the column names in select
+// order alongside each row's values in the same order. Unlike Query it keeps
+// duplicate column names and ordering intact, which matters for CSV/table
+// output. When maxRows > 0 scanning stops after maxRows rows and truncated
+// reports whether more rows were available.
+func (s *SQLiteStorage) QueryColumns(ctx context.Context, maxRows int, query string, args ...any) ([]string, [][]any, bool, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, false, err
+	}
 	defer rows.Close()
 
 	columns, err := rows.Columns()
 	if err != nil {
-		return nil, err
+		return nil, nil, false, err
 	}
 
-	var results []map[string]any
+	var results [][]any
+	truncated := false
 	for rows.Next() {
+		if maxRows > 0 && len(results) >= maxRows {
+			truncated = true
+			break
+		}
 		values := make([]any, len(columns))
 		valuePtrs := make([]any, len(columns))
 		for i := range values {
@@ -832,22 +1176,18 @@ func (s *SQLiteStorage) Query(ctx context.Context, query string, args ...any) ([
 		}
 
 		if err := rows.Scan(valuePtrs...); err != nil {
-			return nil, err
+			return nil, nil, false, err
 		}
 
-		row := make(map[string]any)
-		for i, col := range columns {
-			val := values[i]
+		for i, val := range values {
 			if b, ok := val.([]byte); ok {
-				row[col] = string(b)
-			} else {
-				row[col] = val
+				values[i] = string(b)
 			}
 		}
-		results = append(results, row)
+		results = append(results, values)
 	}
 
-	return results, rows.Err()
+	return columns, results, truncated, rows.Err()
 }
 
 // StorageName returns the storage backend name
@@ -855,14 +1195,52 @@ func (s *SQLiteStorage) StorageName() string {
 	return StorageSQLite
 }
 
+// SearchChat returns the in-game chat messages matching opts, using the
+// chat_messages_fts index.
+func (s *SQLiteStorage) SearchChat(ctx context.Context, opts chatsearch.Options) ([]chatsearch.Hit, error) {
+	return chatsearch.Search(ctx, s.db, chatsearch.DialectSQLite, opts)
+}
+
+// schemaRelation is a table or view GetDatabaseSchema describes, with a note
+// printed under its heading.
+type schemaRelation struct {
+	name string
+	note string
+}
+
+// schemaRelations are the tables and views GetDatabaseSchema describes, in
+// order: the raw tables, then the analyst views (see
+// migrations/replay/000009_analyst_views), whose _v1 columns don't change
+// when the tables do, and the marker reference tables they join against.
+var schemaRelations = []schemaRelation{
+	{name: "replays"},
+	{name: "players"},
+	{name: "commands"},
+	{name: "commands_low_value"},
+	{name: "replay_events"},
+	{name: "player_economy_samples", note: "Estimated, not measured: a per-player economy simulation over the command stream, one row every 10 seconds of game time with bank (minerals, gas), income per minute, supply used and max, workers and bases."},
+	{name: "production_busy_spans", note: "Estimated, not measured: each stretch a player's Gateway, Barracks or Factory spent training, from selection tags and build times. building_index numbers the player's buildings of that type by when they became ready; ready_second to window_end_second is when the building could have trained, so idle time is that window minus the spans."},
+	{name: "larva_samples", note: "Estimated, not measured: each Zerg player's hatchery larva timeline from selection tags and the larva timer (one spawn every 14.4 seconds while a hatchery holds fewer than three). One row per second in which larvae spawned, were used or a spawn was missed at three; larvae is the count after that second, and a hatchery's first row is when it became ready."},
+	{name: "hotkey_usage", note: "Each player's hotkey commands per group (0-9) and second: assigns, selects (recalls), adds (group added to the selection) and camera_jumps (selects that were the second tap of a double tap, included in selects). role is what the group held ('building', 'army' or '' when unknown), estimated from selection tags. window_end_second is the player's last command second."},
+	{name: "player_aliases"},
+	{name: "analyst_player_games_v1", note: "View. One row per player per replay, observers excluded: the game, the player's result and APM, and their build-order opener (opener is the bo_* event_type, opener_name its display name, opener_modifiers its comma-separated tags). Add WHERE is_canonical to count each game once."},
+	{name: "analyst_build_order_steps_v1", note: "View. One row per Build, Train, Unit Morph, Building Morph, Tech or Upgrade command, numbered per player (step) in game order; item is the unit, building, tech or upgrade."},
+	{name: "analyst_attacks_v1", note: "View. One row per attack game event: attacker, defender, target base, the attacking unit types (comma-separated) and the number of aggressive spells cast."},
+	{name: "analyst_attack_units_v1", note: "View. One row per unit type seen in an attack."},
+	{name: "analyst_attack_casts_v1", note: "View. One row per spell cast during an attack, with its count."},
+	{name: "analyst_drops_v1", note: "View. One row per drop or cliff drop onto an enemy base: dropping and dropped-on players, target and source bases, unload count and the dropped unit types."},
+	{name: "analyst_opener_timings_v1", note: "View. One row per milestone of each detected build-order opener: the progamer target second and tolerance against the player's actual second. delta_seconds is negative when early; actual_second, delta_seconds and within_tolerance are NULL when the milestone was never reached."},
+	{name: "marker_definitions", note: "Reference. Every marker event_type this screpdb detects, with its display name, kind ('initial_build_order' for openers, 'marker') and race and matchup gates."},
+	{name: "marker_expert_milestones", note: "Reference. The progamer milestones of each build-order opener, in order (milestone_index), with target second and tolerance."},
+}
+
 // GetDatabaseSchema returns the database schema information
 func (s *SQLiteStorage) GetDatabaseSchema(ctx context.Context) (string, error) {
-	tables := []string{"replays", "players", "commands", "commands_low_value", "replay_events", "player_aliases"}
-
 	var schema strings.Builder
 	schema.WriteString("# Database Schema\n\n")
 
-	for _, tableName := range tables {
+	for _, relation := range schemaRelations {
+		tableName := relation.name
 		query := fmt.Sprintf("PRAGMA table_info(%s);", tableName)
 		rows, err := s.db.QueryContext(ctx, query)
 		if err != nil {
@@ -898,6 +1276,9 @@ func (s *SQLiteStorage) GetDatabaseSchema(ctx context.Context) (string, error) {
 		rows.Close()
 
 		schema.WriteString(fmt.Sprintf("## %s\n\n", tableName))
+		if relation.note != "" {
+			schema.WriteString(relation.note + "\n\n")
+		}
 		schema.WriteString("| Column | Type | Nullable |\n")
 		schema.WriteString("|--------|------|----------|\n")
 		for _, col := range columns {
@@ -1026,6 +1407,190 @@ func (s *SQLiteStorage) CountStaleReplays(ctx context.Context, currentVersion in
 	return count, nil
 }
 
+// ReplayRef identifies a stored replay and the file it was ingested from.
+type ReplayRef struct {
+	ID               int64
+	FilePath         string
+	FileChecksum     string
+	FileName         string
+	AlgorithmVersion int
+}
+
+// ReanalysisSelection picks the replays ListReplaysForReanalysis returns. With
+// no ReplayIDs and no FeatureKeys it selects every replay analyzed below
+// StaleBelow; otherwise it selects the union of the given replay IDs and the
+// replays carrying any of the given marker feature keys, regardless of version.
+type ReanalysisSelection struct {
+	StaleBelow  int
+	ReplayIDs   []int64
+	FeatureKeys []string
+}
+
+// ListReplaysForReanalysis returns the replays matching sel, ordered by ID.
+func (s *SQLiteStorage) ListReplaysForReanalysis(ctx context.Context, sel ReanalysisSelection) ([]ReplayRef, error) {
+	var where string
+	var args []any
+	if len(sel.ReplayIDs) == 0 && len(sel.FeatureKeys) == 0 {
+		where = "analyzer_algorithm_version < ?"
+		args = append(args, sel.StaleBelow)
+	} else {
+		var clauses []string
+		if len(sel.ReplayIDs) > 0 {
+			clauses = append(clauses, fmt.Sprintf("id IN (%s)", strings.TrimSuffix(strings.Repeat("?, ", len(sel.ReplayIDs)), ", ")))
+			for _, id := range sel.ReplayIDs {
+				args = append(args, id)
+			}
+		}
+		if len(sel.FeatureKeys) > 0 {
+			clauses = append(clauses, fmt.Sprintf(
+				"id IN (SELECT replay_id FROM replay_events WHERE event_kind = 'marker' AND event_type IN (%s))",
+				strings.TrimSuffix(strings.Repeat("?, ", len(sel.FeatureKeys)), ", ")))
+			for _, key := range sel.FeatureKeys {
+				args = append(args, key)
+			}
+		}
+		where = strings.Join(clauses, " OR ")
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id, file_path, file_checksum, file_name, analyzer_algorithm_version FROM replays WHERE "+where+" ORDER BY id",
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list replays for re-analysis: %w", err)
+	}
+	defer rows.Close()
+
+	var refs []ReplayRef
+	for rows.Next() {
+		var ref ReplayRef
+		if err := rows.Scan(&ref.ID, &ref.FilePath, &ref.FileChecksum, &ref.FileName, &ref.AlgorithmVersion); err != nil {
+			return nil, fmt.Errorf("failed to scan replay: %w", err)
+		}
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
+}
+
+// ReplayPlayerIDsBySlot maps each stored player's slot_id to its database ID.
+// The players table doesn't keep the replay-local player ID, so re-analysis
+// joins freshly parsed players back to their rows by slot.
+func (s *SQLiteStorage) ReplayPlayerIDsBySlot(ctx context.Context, replayID int64) (map[uint16]int64, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, slot_id FROM players WHERE replay_id = ?", replayID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query players: %w", err)
+	}
+	defer rows.Close()
+
+	bySlot := make(map[uint16]int64)
+	for rows.Next() {
+		var id int64
+		var slot int64
+		if err := rows.Scan(&id, &slot); err != nil {
+			return nil, fmt.Errorf("failed to scan player: %w", err)
+		}
+		bySlot[uint16(slot)] = id
+	}
+	return bySlot, rows.Err()
+}
+
+// MarkerRow is one stored marker detection (a replay_events row with
+// event_kind='marker').
+type MarkerRow struct {
+	PlayerID   *int64
+	FeatureKey string
+	Second     int
+	Payload    string
+}
+
+// ReplayMarkerRows returns the marker detections currently stored for a replay.
+func (s *SQLiteStorage) ReplayMarkerRows(ctx context.Context, replayID int64) ([]MarkerRow, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT source_player_id, event_type, seconds_from_game_start, COALESCE(payload, '')
+		FROM replay_events
+		WHERE replay_id = ? AND event_kind = 'marker'
+	`, replayID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query marker rows: %w", err)
+	}
+	defer rows.Close()
+
+	var out []MarkerRow
+	for rows.Next() {
+		var row MarkerRow
+		var playerID sql.NullInt64
+		if err := rows.Scan(&playerID, &row.FeatureKey, &row.Second, &row.Payload); err != nil {
+			return nil, fmt.Errorf("failed to scan marker row: %w", err)
+		}
+		if playerID.Valid {
+			id := playerID.Int64
+			row.PlayerID = &id
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+// ReplacePatternDetections atomically swaps a replay's narrative events,
+// marker rows, economy timeline, production busy spans, larva samples and
+// hotkey usage for a fresh detection pass and stamps the current
+// AlgorithmVersion. results must already carry database player IDs and the
+// replay ID; playerIDMap maps replay-local player IDs to database IDs for the
+// events, economy samples, busy spans, larva samples and hotkey usage.
+func (s *SQLiteStorage) ReplacePatternDetections(ctx context.Context, replayID int64, results []*core.PatternResult, events []worldstate.ReplayEvent, economy []models.EconomySample, production []models.ProductionBusySpan, larva []models.LarvaSample, hotkeys []models.HotkeyUsage, playerIDMap map[byte]int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM replay_events WHERE replay_id = ?", replayID); err != nil {
+		return fmt.Errorf("failed to delete pattern detections: %w", err)
+	}
+	if len(events) > 0 {
+		if err := s.insertReplayEventsTx(ctx, tx, replayID, events, playerIDMap); err != nil {
+			return fmt.Errorf("failed to insert replay events: %w", err)
+		}
+	}
+	if err := s.BatchInsertPatternResultsTx(ctx, tx, results); err != nil {
+		return fmt.Errorf("failed to insert pattern results: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM player_economy_samples WHERE replay_id = ?", replayID); err != nil {
+		return fmt.Errorf("failed to delete economy samples: %w", err)
+	}
+	if err := insertEconomySamplesTx(ctx, tx, replayID, economy, playerIDMap); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM production_busy_spans WHERE replay_id = ?", replayID); err != nil {
+		return fmt.Errorf("failed to delete production busy spans: %w", err)
+	}
+	if err := insertProductionBusySpansTx(ctx, tx, replayID, production, playerIDMap); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM larva_samples WHERE replay_id = ?", replayID); err != nil {
+		return fmt.Errorf("failed to delete larva samples: %w", err)
+	}
+	if err := insertLarvaSamplesTx(ctx, tx, replayID, larva, playerIDMap); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM hotkey_usage WHERE replay_id = ?", replayID); err != nil {
+		return fmt.Errorf("failed to delete hotkey usage: %w", err)
+	}
+	if err := insertHotkeyUsageTx(ctx, tx, replayID, hotkeys, playerIDMap); err != nil {
+		return err
+	}
+	if err := s.updateAnalyzerAlgorithmVersionTx(ctx, tx, replayID, core.AlgorithmVersion); err != nil {
+		return fmt.Errorf("failed to stamp analyzer_algorithm_version: %w", err)
+	}
+	if err := s.updatePlayerAggregatesTx(ctx, tx, []int64{replayID}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
 // BatchInsertPatternResults inserts pattern detection results in batch (uses default connection)
 func (s *SQLiteStorage) BatchInsertPatternResults(ctx context.Context, results []*core.PatternResult) error {
 	return s.BatchInsertPatternResultsTx(ctx, s.db, results)
@@ -1072,6 +1637,10 @@ func (s *SQLiteStorage) insertMarkerEventsTx(ctx context.Context, db dbtx, resul
 
 		valueStrings := make([]string, 0, len(batch))
 		valueArgs := make([]any, 0, len(batch)*6)
+		// PostgreSQL rejects an upsert that touches the same row twice, so a
+		// repeated marker within the batch overwrites its earlier row's values,
+		// which is what SQLite's row-by-row upsert ends up storing too.
+		rowByKey := make(map[markerEventKey]int, len(batch))
 
 		for _, result := range batch {
 			marker := markers.ByPatternName(result.PatternName)
@@ -1094,15 +1663,25 @@ func (s *SQLiteStorage) insertMarkerEventsTx(ctx context.Context, db dbtx, resul
 				payload = nil
 			}
 
-			valueStrings = append(valueStrings, "(?, ?, ?, ?, ?, ?)")
-			valueArgs = append(valueArgs,
+			row := []any{
 				result.ReplayID,
 				result.DetectedAtSecond,
 				"marker",
 				marker.FeatureKey,
 				sourcePlayerID,
 				payload,
-			)
+			}
+			key := markerEventKey{replayID: result.ReplayID, featureKey: marker.FeatureKey}
+			if result.PlayerID != nil {
+				key.playerID = *result.PlayerID
+			}
+			if i, ok := rowByKey[key]; ok {
+				copy(valueArgs[i*6:], row)
+				continue
+			}
+			rowByKey[key] = len(valueStrings)
+			valueStrings = append(valueStrings, "(?, ?, ?, ?, ?, ?)")
+			valueArgs = append(valueArgs, row...)
 		}
 
 		if len(valueStrings) == 0 {
@@ -1133,6 +1712,13 @@ func (s *SQLiteStorage) insertMarkerEventsTx(ctx context.Context, db dbtx, resul
 	return nil
 }
 
+// markerEventKey is the identity the marker unique index enforces.
+type markerEventKey struct {
+	replayID   int64
+	playerID   int64
+	featureKey string
+}
+
 func (s *SQLiteStorage) updateAnalyzerAlgorithmVersionTx(ctx context.Context, db dbtx, replayID int64, algorithmVersion int) error {
 	_, err := db.ExecContext(ctx, "UPDATE replays SET analyzer_algorithm_version = ? WHERE id = ?", algorithmVersion, replayID)
 	return err
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/marianogappa/screpdb/internal/chatsearch"
 	"github.com/marianogappa/screpdb/internal/fileops"
 	"github.com/marianogappa/screpdb/internal/iofacade"
 	"github.com/marianogappa/screpdb/internal/models"
@@ -213,6 +214,7 @@ func TestIsDuplicateReplayError(t *testing.T) {
 		{"unrelated", errors.New("something else went wrong"), false},
 		{"other unique", errors.New("UNIQUE constraint failed: players.name"), false},
 		{"duplicate checksum", errors.New("UNIQUE constraint failed: replays.file_checksum"), true},
+		{"postgres duplicate checksum", errors.New(`ERROR: duplicate key value violates unique constraint "replays_file_checksum_key" (SQLSTATE 23505)`), true},
 		{"wrapped duplicate", fmt.Errorf("insert: %w", errors.New("UNIQUE constraint failed: replays.file_checksum")), true},
 	}
 	for _, tc := range cases {
@@ -329,6 +331,33 @@ func TestQuery_BadSQLReturnsError(t *testing.T) {
 	}
 }
 
+func TestQueryColumns_OrderAndMaxRows(t *testing.T) {
+	ctx := context.Background()
+	store := newIngestedStore(t)
+
+	columns, rows, truncated, err := store.QueryColumns(ctx, 2, "SELECT id, file_name, id AS id FROM replays ORDER BY id")
+	if err != nil {
+		t.Fatalf("QueryColumns: %v", err)
+	}
+	if strings.Join(columns, ",") != "id,file_name,id" {
+		t.Fatalf("columns = %v, want select order with duplicates kept", columns)
+	}
+	if len(rows) != 2 || !truncated {
+		t.Fatalf("got %d rows (truncated=%v), want 2 rows truncated", len(rows), truncated)
+	}
+	if _, ok := rows[0][1].(string); !ok {
+		t.Fatalf("file_name scanned as %T, want string", rows[0][1])
+	}
+
+	_, rows, truncated, err = store.QueryColumns(ctx, 0, "SELECT id FROM replays WHERE id = ?", rows[1][0])
+	if err != nil {
+		t.Fatalf("QueryColumns with bind arg: %v", err)
+	}
+	if len(rows) != 1 || truncated {
+		t.Fatalf("got %d rows (truncated=%v), want exactly 1", len(rows), truncated)
+	}
+}
+
 func TestGetDatabaseSchema_ContainsTables(t *testing.T) {
 	ctx := context.Background()
 	store := newIngestedStore(t)
@@ -337,11 +366,116 @@ func TestGetDatabaseSchema_ContainsTables(t *testing.T) {
 	if err != nil {
 		t.Fatalf("GetDatabaseSchema: %v", err)
 	}
-	for _, want := range []string{"## replays", "## players", "## commands"} {
+	for _, want := range []string{"## replays", "## players", "## commands", "## marker_expert_milestones", "| delta_seconds |"} {
 		if !strings.Contains(schema, want) {
 			t.Fatalf("expected schema to contain %q", want)
 		}
 	}
+
+	// Every analyst view the migrations create is documented.
+	rows, err := store.db.QueryContext(ctx, `SELECT name FROM sqlite_master WHERE type = 'view' AND name LIKE 'analyst\_%' ESCAPE '\'`)
+	if err != nil {
+		t.Fatalf("list analyst views: %v", err)
+	}
+	defer rows.Close()
+	views := 0
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("scan view: %v", err)
+		}
+		views++
+		if !strings.Contains(schema, "## "+name+"\n\nView. ") {
+			t.Errorf("schema does not describe analyst view %s", name)
+		}
+	}
+	if views == 0 {
+		t.Fatal("no analyst views in a migrated database")
+	}
+}
+
+// TestAnalystViews_ReadIngestedReplays reads every analyst view in full over
+// real ingested replays, so payloads the analyzers write parse as the views
+// expect them to.
+func TestAnalystViews_ReadIngestedReplays(t *testing.T) {
+	ctx := context.Background()
+	store := newIngestedStore(t)
+
+	counts := map[string]int{}
+	for _, relation := range schemaRelations {
+		if !strings.HasPrefix(relation.name, "analyst_") {
+			continue
+		}
+		rows, err := store.db.QueryContext(ctx, "SELECT * FROM "+relation.name)
+		if err != nil {
+			t.Fatalf("query %s: %v", relation.name, err)
+		}
+		for rows.Next() {
+			counts[relation.name]++
+		}
+		if err := rows.Err(); err != nil {
+			t.Fatalf("read %s: %v", relation.name, err)
+		}
+		rows.Close()
+		if counts[relation.name] == 0 {
+			t.Errorf("%s is empty over the ingested replays", relation.name)
+		}
+	}
+}
+
+func TestSearchChat(t *testing.T) {
+	ctx := context.Background()
+	store := newIngestedStore(t)
+
+	hits, err := store.SearchChat(ctx, chatsearch.Options{Text: "GG"})
+	if err != nil {
+		t.Fatalf("SearchChat: %v", err)
+	}
+	if len(hits) < 2 {
+		t.Fatalf("expected several gg hits across the testdata replays, got %d", len(hits))
+	}
+	for _, h := range hits {
+		if !strings.EqualFold(h.Text, "gg") {
+			t.Errorf("hit %q does not match gg", h.Text)
+		}
+	}
+
+	hits, err = store.SearchChat(ctx, chatsearch.Options{Text: "alli*", Players: []string{" howard_the_duck "}})
+	if err != nil {
+		t.Fatalf("SearchChat prefix: %v", err)
+	}
+	if len(hits) != 1 || hits[0].Text != "6 and 9 are allied" {
+		t.Fatalf("prefix search = %+v, want the one allied message", hits)
+	}
+	hit := hits[0]
+	if len(hit.Before) != 2 || hit.Before[1].Text != "6 will win if not attacked" {
+		t.Errorf("Before = %+v, want the two preceding messages", hit.Before)
+	}
+	if len(hit.After) == 0 || hit.After[0].Second < hit.Second {
+		t.Errorf("After = %+v, want the messages that follow", hit.After)
+	}
+
+	hits, err = store.SearchChat(ctx, chatsearch.Options{Text: "gg", Players: []string{"nobody"}})
+	if err != nil || len(hits) != 0 {
+		t.Errorf("search for an unknown speaker = %d hits, %v; want none", len(hits), err)
+	}
+	hits, err = store.SearchChat(ctx, chatsearch.Options{Text: "gg", To: "1999-01-01"})
+	if err != nil || len(hits) != 0 {
+		t.Errorf("search before any replay = %d hits, %v; want none", len(hits), err)
+	}
+
+	// The index follows deletes through the commands triggers.
+	if _, err := store.db.ExecContext(ctx, "DELETE FROM replays WHERE id = ?", hit.ReplayID); err != nil {
+		t.Fatalf("delete replay: %v", err)
+	}
+	hits, err = store.SearchChat(ctx, chatsearch.Options{Text: "allied"})
+	if err != nil || len(hits) != 0 {
+		t.Errorf("search after deleting the replay = %d hits, %v; want none", len(hits), err)
+	}
+
+	if _, err := store.SearchChat(ctx, chatsearch.Options{Text: "^^"}); !errors.Is(err, chatsearch.ErrNoWords) {
+		t.Errorf("search without words = %v, want ErrNoWords", err)
+	}
 }
 
 func TestStartIngestion_PanicRoutedToStoreErrorHook(t *testing.T) {
@@ -461,3 +595,115 @@ func TestStartIngestion_DuplicateReplayHooked(t *testing.T) {
 		t.Fatalf("expected 1 duplicate hook call, got %d", dupes)
 	}
 }
+
+func TestStoreReplayBatch_FailuresOnlyDropTheirReplay(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("SCREPDB_APPDATA_DIR", t.TempDir())
+	dbPath := filepath.Join(t.TempDir(), "batch_ingest.db")
+	store, err := NewSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	if err := store.Initialize(ctx, true, true); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+
+	replaysDir, err := resolveReplayDir()
+	if err != nil {
+		t.Fatalf("resolveReplayDir: %v", err)
+	}
+	_ = iofacade.AllowDir(replaysDir)
+	files, err := fileops.GetReplayFiles(replaysDir)
+	if err != nil {
+		t.Fatalf("GetReplayFiles: %v", err)
+	}
+	if len(files) < 2 {
+		t.Fatalf("need 2 replays, found %d", len(files))
+	}
+	parse := func(fi fileops.FileInfo) *models.ReplayData {
+		replay := parser.CreateReplayFromFileInfo(fi.Path, fi.Name, fi.Size, fi.Checksum)
+		data, err := parser.ParseReplay(fi.Path, replay)
+		if err != nil {
+			t.Fatalf("ParseReplay: %v", err)
+		}
+		return data
+	}
+
+	var stored, dupes, storeErrs int
+	hooks := IngestionHooks{
+		OnReplayStored:    func() { stored++ },
+		OnDuplicateReplay: func(error) { dupes++ },
+		OnStoreError:      func(error) { storeErrs++ },
+	}
+	// nil data panics; the second copy of files[0] is a duplicate. Neither
+	// may take the other replays of the batch down with it.
+	batch := []*models.ReplayData{parse(files[0]), nil, parse(files[0]), parse(files[1])}
+	if err := store.storeReplayBatch(ctx, batch, hooks); err != nil {
+		t.Fatalf("storeReplayBatch: %v", err)
+	}
+	if stored != 2 || dupes != 1 || storeErrs != 1 {
+		t.Fatalf("stored=%d dupes=%d storeErrs=%d, want 2/1/1", stored, dupes, storeErrs)
+	}
+	var replays int
+	if err := store.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM replays`).Scan(&replays); err != nil {
+		t.Fatalf("count replays: %v", err)
+	}
+	if replays != 2 {
+		t.Fatalf("replays = %d, want 2", replays)
+	}
+}
+
+func TestStoreReplayBatch_FatalErrorReportsTheRestOfTheBatch(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("SCREPDB_APPDATA_DIR", t.TempDir())
+	store, err := NewSQLiteStorage(filepath.Join(t.TempDir(), "batch_abort.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	if err := store.Initialize(ctx, true, true); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+
+	replaysDir, err := resolveReplayDir()
+	if err != nil {
+		t.Fatalf("resolveReplayDir: %v", err)
+	}
+	_ = iofacade.AllowDir(replaysDir)
+	files, err := fileops.GetReplayFiles(replaysDir)
+	if err != nil {
+		t.Fatalf("GetReplayFiles: %v", err)
+	}
+	if len(files) < 3 {
+		t.Fatalf("need 3 replays, found %d", len(files))
+	}
+	var batch []*models.ReplayData
+	for _, fi := range files[:3] {
+		replay := parser.CreateReplayFromFileInfo(fi.Path, fi.Name, fi.Size, fi.Checksum)
+		data, err := parser.ParseReplay(fi.Path, replay)
+		if err != nil {
+			t.Fatalf("ParseReplay: %v", err)
+		}
+		batch = append(batch, data)
+	}
+	// A map kind the replays table rejects is neither a duplicate nor a
+	// panic, so it ends ingestion: the replay after it must be reported.
+	batch[1].Replay.MapKind = "Bogus"
+
+	var stored int
+	var storeErrs []error
+	hooks := IngestionHooks{
+		OnReplayStored: func() { stored++ },
+		OnStoreError:   func(err error) { storeErrs = append(storeErrs, err) },
+	}
+	if err := store.storeReplayBatch(ctx, batch, hooks); err == nil {
+		t.Fatalf("expected storeReplayBatch to return the map kind error")
+	}
+	if stored != 1 || len(storeErrs) != 2 {
+		t.Fatalf("stored=%d storeErrs=%v, want 1 stored and 2 errors", stored, storeErrs)
+	}
+	if !strings.Contains(storeErrs[1].Error(), batch[2].Replay.FilePath) {
+		t.Fatalf("expected the unstored replay's path in %q", storeErrs[1])
+	}
+}
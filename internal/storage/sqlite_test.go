@@ -11,6 +11,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/marianogappa/screpdb/internal/fileops"
 	"github.com/marianogappa/screpdb/internal/iofacade"
@@ -177,7 +178,12 @@ func TestSQLiteStorage_IngestionAndQueries(t *testing.T) {
 		// moved from build-order opener to composition marker layered on the
 		// supply opener, so each ingested Zerg tech game now carries both an
 		// opener row and a marker row (two such games in this corpus).
-		"replay_events": 217,
+		// Then +46: the full-game economy simulation's supply blocks land as
+		// supply_block game events (10, 7, 4 and 25 across the four replays).
+		// Then -1: Siege Tank trains now bind to their Factory's tag, so the
+		// BGH replay's tank-producing Factory keeps its base owned and one
+		// location_inactive timeout no longer fires.
+		"replay_events": 262,
 	}
 	actualCounts, err := collectCounts(store, keys(expectedCounts))
 	if err != nil {
@@ -210,6 +216,77 @@ func TestSQLiteStorage_IngestionAndQueries(t *testing.T) {
 		t.Fatalf("expected low-value commands to be present")
 	}
 
+	// Every player who played has an economy timeline spanning the game.
+	economyRows, err := store.Query(ctx, `
+		SELECT COUNT(*) AS c FROM players p
+		JOIN replays r ON r.id = p.replay_id
+		WHERE p.is_observer = 0 AND NOT EXISTS (
+			SELECT 1 FROM player_economy_samples es
+			WHERE es.player_id = p.id AND es.second = 0
+		) OR EXISTS (
+			SELECT 1 FROM player_economy_samples es
+			WHERE es.replay_id = r.id AND es.second > r.duration_seconds
+		)`)
+	if err != nil {
+		t.Fatalf("query economy samples: %v", err)
+	}
+	if n, _ := asInt64(economyRows[0]["c"]); n != 0 {
+		t.Fatalf("expected every player's economy timeline to start at 0 and stop by the game's end, %d players don't", n)
+	}
+
+	// Production busy spans stay inside their building's window, and the
+	// Gateway/Barracks/Factory games in this corpus have some.
+	busyRows, err := store.Query(ctx, `
+		SELECT COUNT(*) AS c,
+			COALESCE(SUM(start_second < ready_second OR end_second > window_end_second OR end_second <= start_second), 0) AS bad
+		FROM production_busy_spans`)
+	if err != nil {
+		t.Fatalf("query production busy spans: %v", err)
+	}
+	if n, _ := asInt64(busyRows[0]["c"]); n == 0 {
+		t.Fatalf("expected production busy spans to be stored")
+	}
+	if bad, _ := asInt64(busyRows[0]["bad"]); bad != 0 {
+		t.Fatalf("expected every production busy span inside its building's window, %d aren't", bad)
+	}
+
+	// Larva samples hold 0-3 larvae inside the player's window, and the Zerg
+	// games in this corpus have some.
+	larvaRows, err := store.Query(ctx, `
+		SELECT COUNT(*) AS c,
+			COALESCE(SUM(larvae < 0 OR larvae > 3 OR second >= window_end_second), 0) AS bad
+		FROM larva_samples`)
+	if err != nil {
+		t.Fatalf("query larva samples: %v", err)
+	}
+	if n, _ := asInt64(larvaRows[0]["c"]); n == 0 {
+		t.Fatalf("expected larva samples to be stored")
+	}
+	if bad, _ := asInt64(larvaRows[0]["bad"]); bad != 0 {
+		t.Fatalf("expected every larva sample to hold 0-3 larvae inside its window, %d don't", bad)
+	}
+
+	// Hotkey usage rows hold a 0-9 group, a known role and no more camera
+	// jumps than selects, and these replays bind both buildings and army.
+	usageRows, err := store.Query(ctx, `
+		SELECT COUNT(*) AS c,
+			COALESCE(SUM(hotkey_group < 0 OR hotkey_group > 9 OR camera_jumps > selects
+				OR role NOT IN ('', 'building', 'army')), 0) AS bad,
+			COUNT(DISTINCT role) AS roles
+		FROM hotkey_usage`)
+	if err != nil {
+		t.Fatalf("query hotkey usage: %v", err)
+	}
+	if n, _ := asInt64(usageRows[0]["c"]); n == 0 {
+		t.Fatalf("expected hotkey usage to be stored")
+	}
+	if bad, _ := asInt64(usageRows[0]["bad"]); bad != 0 {
+		t.Fatalf("expected every hotkey usage row to be well-formed, %d aren't", bad)
+	}
+	if roles, _ := asInt64(usageRows[0]["roles"]); roles < 2 {
+		t.Fatalf("expected building and army hotkey groups, got %d distinct roles", roles)
+	}
+
 	rightClickRows, err := countAcrossCommandTables(ctx, store, "Right Click")
 	if err != nil {
 		t.Fatalf("countAcrossCommandTables right click: %v", err)
@@ -372,7 +449,7 @@ func TestSQLiteStorage_CommandStorageFlags(t *testing.T) {
 	}
 	defer store.Close()
 
-	store.SetCommandStorageOptions(true, true)
+	store.SetCommandStorageOptions(true, true, false)
 	if err := store.Initialize(ctx, true, true); err != nil {
 		t.Fatalf("Initialize: %v", err)
 	}
@@ -412,7 +489,86 @@ func TestSQLiteStorage_CommandStorageFlags(t *testing.T) {
 	}
 }
 
-func ingestFiles(ctx context.Context, store *SQLiteStorage, files []fileops.FileInfo) error {
+// TestBatchCheckExistingReplays_DedupSemantics covers the two checks ingest
+// runs on discovered files: the path pass (FilterOutUnchangedReplayFiles)
+// and the checksum pass (FilterOutExistingReplays). Refreshing a touched
+// file's stamp must not move the replay generation.
+func TestBatchCheckExistingReplays_DedupSemantics(t *testing.T) {
+	ctx := context.Background()
+	store := newIngestedStore(t)
+
+	replaysDir, err := resolveReplayDir()
+	if err != nil {
+		t.Fatalf("resolveReplayDir: %v", err)
+	}
+	known, err := fileops.WalkReplayFiles(replaysDir)
+	if err != nil {
+		t.Fatalf("WalkReplayFiles: %v", err)
+	}
+	hashedKnown, err := fileops.HashFiles(ctx, known)
+	if err != nil {
+		t.Fatalf("HashFiles: %v", err)
+	}
+
+	// All ingested files are known by path with their stamps.
+	pathSurvivors, err := store.FilterOutUnchangedReplayFiles(ctx, known)
+	if err != nil {
+		t.Fatalf("FilterOutUnchangedReplayFiles: %v", err)
+	}
+	if len(pathSurvivors) != 0 {
+		t.Fatalf("expected all known files skipped by path, %d survived", len(pathSurvivors))
+	}
+
+	// All ingested files are known by checksum.
+	sumSurvivors, err := store.FilterOutExistingReplays(ctx, hashedKnown)
+	if err != nil {
+		t.Fatalf("FilterOutExistingReplays: %v", err)
+	}
+	if len(sumSurvivors) != 0 {
+		t.Fatalf("expected all known files skipped by checksum, %d survived", len(sumSurvivors))
+	}
+
+	// Recording the files' stamps, then a touched file's new one, makes the
+	// path pass compare them, and neither refresh moves the replay generation.
+	generation := func() int64 {
+		t.Helper()
+		var g int64
+		if err := store.db.QueryRowContext(ctx, "SELECT generation FROM replay_generation WHERE id = 1").Scan(&g); err != nil {
+			t.Fatalf("read replay generation: %v", err)
+		}
+		return g
+	}
+	before := generation()
+	if err := store.RefreshReplayFileStamps(ctx, hashedKnown); err != nil {
+		t.Fatalf("RefreshReplayFileStamps: %v", err)
+	}
+	touched := hashedKnown[0]
+	touched.ModTime = touched.ModTime.Add(time.Hour)
+	if got, err := store.FilterOutUnchangedReplayFiles(ctx, []fileops.FileInfo{touched}); err != nil || len(got) != 1 {
+		t.Fatalf("expected the touched file to survive the path pass, got %v, %v", got, err)
+	}
+	if err := store.RefreshReplayFileStamps(ctx, []fileops.FileInfo{touched}); err != nil {
+		t.Fatalf("RefreshReplayFileStamps touched: %v", err)
+	}
+	if got, err := store.FilterOutUnchangedReplayFiles(ctx, []fileops.FileInfo{touched}); err != nil || len(got) != 0 {
+		t.Fatalf("expected the refreshed stamp to skip the file, got %v, %v", got, err)
+	}
+	if after := generation(); after != before {
+		t.Fatalf("replay generation moved from %d to %d on stamp refreshes", before, after)
+	}
+
+	// A novel path survives the path pass.
+	novel := []fileops.FileInfo{{Path: filepath.Join(replaysDir, "unknown.rep"), Name: "unknown.rep"}}
+	novelSurvivors, err := store.FilterOutUnchangedReplayFiles(ctx, novel)
+	if err != nil {
+		t.Fatalf("FilterOutUnchangedReplayFiles novel: %v", err)
+	}
+	if len(novelSurvivors) != 1 {
+		t.Fatalf("expected novel path to survive, got %d", len(novelSurvivors))
+	}
+}
+
+func ingestFiles(ctx context.Context, store Storage, files []fileops.FileInfo) error {
 	dataChan, errChan := store.StartIngestion(ctx, IngestionHooks{})
 	for i := range files {
 		fileInfo := files[i]
@@ -449,7 +605,7 @@ func collectCounts(store *SQLiteStorage, tables []string) (map[string]int64, err
 	return counts, nil
 }
 
-func countTable(ctx context.Context, store *SQLiteStorage, table string) (int64, error) {
+func countTable(ctx context.Context, store Storage, table string) (int64, error) {
 	rows, err := store.Query(ctx, "SELECT COUNT(*) AS c FROM "+table)
 	if err != nil {
 		return 0, err
@@ -0,0 +1,78 @@
+package unittags
+
+// Roles a hotkey group can be bound to. Brood War can't select more than one
+// building at a time, so a group of two or more units is army (workers and
+// larvae included), and a single-unit group is a building when that tag did
+// something only a building does: trained a unit, researched, morphed into
+// another building, lifted off, was tapped before a larva morph, or built an
+// add-on's parent. A single unit seen in a multi-unit selection or sent to
+// build is army. Anything else is left unclassified.
+const (
+	HotkeyRoleBuilding = "building"
+	HotkeyRoleArmy     = "army"
+)
+
+// HotkeyBinding is one hotkey Assign: the group was set to the Units units
+// selected at Frame. Role is HotkeyRoleBuilding, HotkeyRoleArmy or empty when
+// the selection gave no evidence either way.
+type HotkeyBinding struct {
+	Frame int32
+	Sec   int
+	Group byte
+	Units int
+	Role  string `json:",omitempty"`
+}
+
+// hotkeyTags is the per-player selection evidence Analyze gathers to classify
+// HotkeyBindings once the whole stream has been seen.
+type hotkeyTags struct {
+	// bound is each binding's tags, index-aligned with HotkeyBindings.
+	bound     [][]uint16
+	buildings map[uint16]bool
+	units     map[uint16]bool
+}
+
+func newHotkeyTags() *hotkeyTags {
+	return &hotkeyTags{buildings: map[uint16]bool{}, units: map[uint16]bool{}}
+}
+
+// selected records a selection: two or more selected units prove every one
+// of them is not a building.
+func (h *hotkeyTags) selected(cur []uint16) {
+	if len(cur) < 2 {
+		return
+	}
+	for _, tag := range cur {
+		h.units[tag] = true
+	}
+}
+
+// classifyHotkeyBindings sets the Role of each of pe's HotkeyBindings from
+// h and the production evidence in pe.
+func classifyHotkeyBindings(pe *PlayerEvidence, h *hotkeyTags) {
+	for _, tags := range pe.Producers {
+		for tag := range tags {
+			h.buildings[tag] = true
+		}
+	}
+	for _, tags := range pe.Addons {
+		for tag := range tags {
+			h.buildings[tag] = true
+		}
+	}
+	for _, wb := range pe.WorkerBuilds {
+		h.units[wb.Worker] = true
+	}
+	for i := range pe.HotkeyBindings {
+		b := &pe.HotkeyBindings[i]
+		switch tags := h.bound[i]; {
+		case len(tags) >= 2:
+			b.Role = HotkeyRoleArmy
+		case len(tags) == 0:
+		case h.buildings[tags[0]]:
+			b.Role = HotkeyRoleBuilding
+		case h.units[tags[0]]:
+			b.Role = HotkeyRoleArmy
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package unittags
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/icza/screp/rep/repcmd"
+)
+
+// TestAnalyze_HotkeyBindingRoles — a Gateway that later trains and a Forge
+// that later researches are buildings even when bound before the proof; a
+// two-unit group, a lone worker sent to build, and a lone unit once part of a
+// box-select are army; a lone tag with no evidence and an empty selection are
+// unclassified.
+func TestAnalyze_HotkeyBindingRoles(t *testing.T) {
+	ev := Analyze(replayOf(
+		sel(1, 5, 0x10),
+		hotkey(1, 5, "Assign", 4),
+		sel(1, 6, 0x20),
+		hotkey(1, 6, "Assign", 5),
+		sel(1, 7, 0x30, 0x31),
+		hotkey(1, 7, "Assign", 1),
+		sel(1, 8, 0x40),
+		hotkey(1, 8, "Assign", 9),
+		build(1, 8, "Pylon", 10, 10),
+		sel(1, 9, 0x31),
+		hotkey(1, 9, "Assign", 2),
+		sel(1, 10, 0x50),
+		hotkey(1, 10, "Assign", 3),
+		selRemove(1, 11, 0x50),
+		hotkey(1, 11, "Assign", 3),
+		hotkey(1, 20, "Select", 4),
+		train(1, 20, "Zealot"),
+		hotkey(1, 40, "Select", 5),
+		&repcmd.TechCmd{Base: base(1, 40, repcmd.TypeIDTech)},
+	))
+	var got []HotkeyBinding
+	for _, b := range ev.Players[1].HotkeyBindings {
+		got = append(got, HotkeyBinding{Group: b.Group, Units: b.Units, Role: b.Role})
+	}
+	want := []HotkeyBinding{
+		{Group: 4, Units: 1, Role: HotkeyRoleBuilding},
+		{Group: 5, Units: 1, Role: HotkeyRoleBuilding},
+		{Group: 1, Units: 2, Role: HotkeyRoleArmy},
+		{Group: 9, Units: 1, Role: HotkeyRoleArmy},
+		{Group: 2, Units: 1, Role: HotkeyRoleArmy},
+		{Group: 3, Units: 1},
+		{Group: 3, Units: 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bindings = %+v; want %+v", got, want)
+	}
+}
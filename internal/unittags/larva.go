@@ -0,0 +1,201 @@
+package unittags
+
+import (
+	"math"
+	"sort"
+
+	"github.com/marianogappa/screpdb/internal/models"
+)
+
+// The larva model matches earlyfilter's: a Hatchery (and the Lair or Hive it
+// becomes) holds at most maxLarvae larvae, and from the moment it finishes its
+// timer spawns one every larvaSpawnSeconds whether or not there is room. A
+// spawn while the hatchery already holds maxLarvae is lost.
+const (
+	maxLarvae         = 3
+	larvaSpawnSeconds = 14.4
+)
+
+// LarvaSample is one second of a hatchery's larva timeline in which larvae
+// spawned, were used by a morph, or a spawn was lost at the cap. Larvae is the
+// count at the end of that second.
+type LarvaSample struct {
+	Sec     int
+	Larvae  int
+	Spawned int
+	Used    int
+	Missed  int
+}
+
+// HatcheryLarva is one Zerg town hall's estimated larva timeline.
+type HatcheryLarva struct {
+	// Tag is the hall's unit tag when a larva morph was attributed to it;
+	// Attributed is false (and Tag zero) for a hall nothing was attributed to.
+	Tag        uint16
+	Attributed bool
+	// ReadySec is 0 for the starting Hatchery, else its Build command plus the
+	// Hatchery build time.
+	ReadySec int
+	// Samples starts at ReadySec with the maxLarvae larvae a hatchery comes
+	// with, counted as spawned, and is in time order.
+	Samples []LarvaSample
+}
+
+// HatcheryLarvae estimates, up to endSec, the larva timeline of the player's
+// starting Hatchery and of each distinct Hatchery Build (footprint-overlapping
+// re-placements collapsed, as for TownHallBuildSeconds). Town-hall tags come
+// from the zergTownHall producers: each is matched to a finished Hatchery the
+// way production locations are matched to Builds, and the earliest unmatched
+// one is the starting Hatchery. A morph (LarvaMorphs) uses as many larvae as
+// were selected, taken from its tapped hall first and then from whichever
+// ready halls hold the most, since larvae clicked directly or box-selected
+// across hatcheries name no hall. It is an estimate: a morph the game refused
+// for minerals, gas or supply still uses larvae, and a cancelled or destroyed
+// Hatchery keeps spawning. Halls are ordered by ReadySec; halls ready at or
+// after endSec are left out.
+func (pe *PlayerEvidence) HatcheryLarvae(endSec int) []HatcheryLarva {
+	tags := pe.Producers[zergTownHall]
+	// Match tags against when each Hatchery finished, not when it was placed:
+	// a hall cannot hand out larvae before it stands.
+	buildSeconds, _ := models.BuildTimeOf(zergTownHall)
+	finished := collapsedBuilds(pe.Builds[zergTownHall])
+	for i := range finished {
+		finished[i].Sec += int(math.Round(buildSeconds))
+	}
+	loc := matchProducerTagsToBuilds(tags, finished)
+	tagByBuild := make(map[Build]uint16, len(loc))
+	for tag, b := range loc {
+		tagByBuild[b] = tag
+	}
+
+	start := HatcheryLarva{}
+	for tag, p := range tags {
+		if _, matched := loc[tag]; matched {
+			continue
+		}
+		if !start.Attributed || p.FirstSec < tags[start.Tag].FirstSec ||
+			(p.FirstSec == tags[start.Tag].FirstSec && tag < start.Tag) {
+			start.Tag, start.Attributed = tag, true
+		}
+	}
+	var halls []*larvaHall
+	addHall := func(h HatcheryLarva) {
+		if h.ReadySec < endSec {
+			halls = append(halls, newLarvaHall(h))
+		}
+	}
+	addHall(start)
+	for _, b := range finished {
+		hall := HatcheryLarva{ReadySec: b.Sec}
+		if tag, ok := tagByBuild[b]; ok {
+			hall.Tag, hall.Attributed = tag, true
+		}
+		addHall(hall)
+	}
+	sort.SliceStable(halls, func(i, j int) bool { return halls[i].ReadySec < halls[j].ReadySec })
+
+	for _, morph := range pe.larvaMorphs() {
+		if morph.Sec >= endSec {
+			break
+		}
+		var ready []*larvaHall
+		for _, h := range halls {
+			if h.ReadySec <= morph.Sec {
+				h.tick(morph.Sec)
+				ready = append(ready, h)
+			}
+		}
+		// The tapped hall first, then the fullest.
+		sort.SliceStable(ready, func(i, j int) bool {
+			ti := morph.HallKnown && ready[i].Attributed && ready[i].Tag == morph.Hall
+			tj := morph.HallKnown && ready[j].Attributed && ready[j].Tag == morph.Hall
+			if ti != tj {
+				return ti
+			}
+			return ready[i].larvae > ready[j].larvae
+		})
+		want := max(1, morph.Larvae)
+		for _, h := range ready {
+			if want == 0 {
+				break
+			}
+			want -= h.use(morph.Sec, want)
+		}
+	}
+
+	out := make([]HatcheryLarva, 0, len(halls))
+	for _, h := range halls {
+		h.tick(endSec - 1)
+		out = append(out, h.HatcheryLarva)
+	}
+	return out
+}
+
+// larvaMorphs returns LarvaMorphs, or for evidence recorded before they were
+// kept, one single-larva morph per attributed town-hall production.
+func (pe *PlayerEvidence) larvaMorphs() []LarvaMorph {
+	if len(pe.LarvaMorphs) > 0 {
+		return pe.LarvaMorphs
+	}
+	var out []LarvaMorph
+	for tag, p := range pe.Producers[zergTownHall] {
+		for _, sec := range p.Secs {
+			out = append(out, LarvaMorph{Sec: sec, Larvae: 1, Hall: tag, HallKnown: true})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Sec != out[j].Sec {
+			return out[i].Sec < out[j].Sec
+		}
+		return out[i].Hall < out[j].Hall
+	})
+	return out
+}
+
+// larvaHall runs one hall's larva timer from its ReadySec. A spawn and a morph
+// in the same second are applied spawn first.
+type larvaHall struct {
+	HatcheryLarva
+	larvae int
+	next   float64
+}
+
+func newLarvaHall(h HatcheryLarva) *larvaHall {
+	h.Samples = []LarvaSample{{Sec: h.ReadySec, Larvae: maxLarvae, Spawned: maxLarvae}}
+	return &larvaHall{HatcheryLarva: h, larvae: maxLarvae, next: float64(h.ReadySec) + larvaSpawnSeconds}
+}
+
+// at returns the sample for sec, appending one when sec is past the last.
+func (h *larvaHall) at(sec int) *LarvaSample {
+	if h.Samples[len(h.Samples)-1].Sec != sec {
+		h.Samples = append(h.Samples, LarvaSample{Sec: sec})
+	}
+	return &h.Samples[len(h.Samples)-1]
+}
+
+// tick fires the spawn timer up to and including second until.
+func (h *larvaHall) tick(until int) {
+	for ; int(math.Round(h.next)) <= until; h.next += larvaSpawnSeconds {
+		s := h.at(int(math.Round(h.next)))
+		if h.larvae < maxLarvae {
+			h.larvae++
+			s.Spawned++
+		} else {
+			s.Missed++
+		}
+		s.Larvae = h.larvae
+	}
+}
+
+// use takes up to n larvae at sec and returns how many it took.
+func (h *larvaHall) use(sec, n int) int {
+	used := min(n, h.larvae)
+	if used == 0 {
+		return 0
+	}
+	h.larvae -= used
+	s := h.at(sec)
+	s.Used += used
+	s.Larvae = h.larvae
+	return used
+}
@@ -0,0 +1,66 @@
+package unittags
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestHatcheryLarvae_SpawnsUsesAndMisses — three selected larvae morph at
+// once; the timer refills the starting Hatchery every 14.4s and loses spawns
+// at the cap; a morph with no tapped hall takes from the fullest one; four
+// larvae box-selected after tapping the start Hatchery take its three first and
+// one from the expansion, which finished 75s after its Build.
+func TestHatcheryLarvae_SpawnsUsesAndMisses(t *testing.T) {
+	ev := Analyze(replayOf(
+		sel(1, 10, 0x100),
+		sel(1, 10, 0x01, 0x02, 0x03),
+		morph(1, 10, "Drone"),
+		build(1, 20, "Hatchery", 40, 40),
+		sel(1, 50, 0x04),
+		morph(1, 50, "Zergling"),
+		sel(1, 100, 0x100),
+		sel(1, 100, 0x05, 0x06, 0x07, 0x08),
+		morph(1, 100, "Drone"),
+	))
+	got := ev.Players[1].HatcheryLarvae(110)
+	want := []HatcheryLarva{
+		{Tag: 0x100, Attributed: true, ReadySec: 0, Samples: []LarvaSample{
+			{Sec: 0, Larvae: 3, Spawned: 3},
+			{Sec: 10, Larvae: 0, Used: 3},
+			{Sec: 14, Larvae: 1, Spawned: 1},
+			{Sec: 29, Larvae: 2, Spawned: 1},
+			{Sec: 43, Larvae: 3, Spawned: 1},
+			{Sec: 50, Larvae: 2, Used: 1},
+			{Sec: 58, Larvae: 3, Spawned: 1},
+			{Sec: 72, Larvae: 3, Missed: 1},
+			{Sec: 86, Larvae: 3, Missed: 1},
+			{Sec: 100, Larvae: 0, Used: 3},
+			{Sec: 101, Larvae: 1, Spawned: 1},
+		}},
+		{ReadySec: 95, Samples: []LarvaSample{
+			{Sec: 95, Larvae: 3, Spawned: 3},
+			{Sec: 100, Larvae: 2, Used: 1},
+			{Sec: 109, Larvae: 3, Spawned: 1},
+		}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("halls = %+v; want %+v", got, want)
+	}
+	if halls := ev.Players[1].HatcheryLarvae(95); len(halls) != 1 {
+		t.Errorf("halls ready at endSec should be left out, got %d", len(halls))
+	}
+}
+
+// TestHatcheryLarvae_FallsBackToProducers — evidence stored before
+// LarvaMorphs were kept still uses the attributed town-hall productions, one
+// larva each.
+func TestHatcheryLarvae_FallsBackToProducers(t *testing.T) {
+	pe := &PlayerEvidence{Producers: map[string]map[uint16]*Production{
+		zergTownHall: {0x100: {FirstSec: 5, Units: 2, Secs: []int{5, 5}}},
+	}}
+	got := pe.HatcheryLarvae(10)
+	want := []LarvaSample{{Sec: 0, Larvae: 3, Spawned: 3}, {Sec: 5, Larvae: 1, Used: 2}}
+	if len(got) != 1 || !reflect.DeepEqual(got[0].Samples, want) {
+		t.Fatalf("halls = %+v; want one hall with samples %+v", got, want)
+	}
+}
@@ -0,0 +1,112 @@
+package unittags
+
+import (
+	"math"
+	"sort"
+
+	"github.com/marianogappa/screpdb/internal/models"
+)
+
+// maxTrainQueue is a production building's queue size. A Train issued while
+// the queue is full is refused by the game, so it adds no busy time.
+const maxTrainQueue = 5
+
+// BusySpan is a stretch of game seconds a production building spent training,
+// [StartSec, EndSec).
+type BusySpan struct {
+	StartSec int
+	EndSec   int
+}
+
+// ProducerTimeline is one production building's estimated training timeline.
+type ProducerTimeline struct {
+	Building string
+	Tag      uint16
+	// ReadySec is when the building finished: its matched Build command plus
+	// the building's build time, or its first Train when no Build matched.
+	ReadySec int
+	// Busy is in time order and never overlaps.
+	Busy []BusySpan
+}
+
+// ProducerTimelines replays each producing tag's Train commands of the given
+// building types through a queue: a unit starts when the command arrives or
+// when the unit ahead of it finishes, and takes its build time
+// (models.BuildTimeOf). It is an estimate: a Train the game refused for
+// minerals, gas or supply, or a cancelled unit, still counts as busy time,
+// and the building's death is not seen. Producers recorded without unit names
+// (analysis inputs stored before they were kept) are skipped. Timelines are
+// ordered by ReadySec, then tag.
+func (pe *PlayerEvidence) ProducerTimelines(buildings ...string) []ProducerTimeline {
+	var out []ProducerTimeline
+	for _, bldg := range buildings {
+		tags := pe.Producers[bldg]
+		if len(tags) == 0 {
+			continue
+		}
+		loc := matchProducerTagsToBuilds(tags, pe.Builds[bldg])
+		buildSeconds, _ := models.BuildTimeOf(bldg)
+		for tag, p := range tags {
+			if len(p.UnitNames) != len(p.Secs) {
+				continue
+			}
+			busy := busySpans(p)
+			if len(busy) == 0 {
+				continue
+			}
+			ready := busy[0].StartSec
+			if b, ok := loc[tag]; ok {
+				ready = min(ready, b.Sec+int(math.Round(buildSeconds)))
+			}
+			out = append(out, ProducerTimeline{Building: bldg, Tag: tag, ReadySec: ready, Busy: busy})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].ReadySec != out[j].ReadySec {
+			return out[i].ReadySec < out[j].ReadySec
+		}
+		if out[i].Building != out[j].Building {
+			return out[i].Building < out[j].Building
+		}
+		return out[i].Tag < out[j].Tag
+	})
+	return out
+}
+
+// busySpans queues one producer's trains and merges back-to-back units into
+// spans. Units without a known build time are ignored.
+func busySpans(p *Production) []BusySpan {
+	var (
+		queue []float64 // finish second of each queued unit, in order
+		spans [][2]float64
+	)
+	for i, sec := range p.Secs {
+		seconds, ok := models.BuildTimeOf(p.UnitNames[i])
+		if !ok {
+			continue
+		}
+		at := float64(sec)
+		for len(queue) > 0 && queue[0] <= at {
+			queue = queue[1:]
+		}
+		if len(queue) >= maxTrainQueue {
+			continue
+		}
+		start := at
+		if len(queue) > 0 {
+			start = queue[len(queue)-1]
+		}
+		end := start + seconds
+		queue = append(queue, end)
+		if n := len(spans); n > 0 && start <= spans[n-1][1] {
+			spans[n-1][1] = end
+		} else {
+			spans = append(spans, [2]float64{start, end})
+		}
+	}
+	out := make([]BusySpan, 0, len(spans))
+	for _, s := range spans {
+		out = append(out, BusySpan{StartSec: int(math.Round(s[0])), EndSec: int(math.Round(s[1]))})
+	}
+	return out
+}
@@ -0,0 +1,48 @@
+package unittags
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/icza/screp/rep/repcmd"
+)
+
+// TestProducerTimelines_QueuesTrains — two queued Zealots run back to back
+// into one span, a later Zealot opens a second, and the Gateway is ready its
+// build time after the Build command matched to it.
+func TestProducerTimelines_QueuesTrains(t *testing.T) {
+	ev := Analyze(replayOf(
+		sel(1, 10, 0x01),
+		build(1, 20, "Gateway", 30, 40),
+		sel(1, 80, 0xAA),
+		train(1, 80, "Zealot"),
+		train(1, 81, "Zealot"),
+		train(1, 200, "Zealot"),
+	))
+	got := ev.Players[1].ProducerTimelines("Gateway", "Barracks")
+	want := []ProducerTimeline{{
+		Building: "Gateway",
+		Tag:      0xAA,
+		ReadySec: 58,
+		Busy:     []BusySpan{{StartSec: 80, EndSec: 130}, {StartSec: 200, EndSec: 225}},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("timelines = %+v; want %+v", got, want)
+	}
+}
+
+// TestProducerTimelines_RefusesFullQueue — a sixth Train while five units are
+// queued adds nothing.
+func TestProducerTimelines_RefusesFullQueue(t *testing.T) {
+	cmds := []repcmd.Cmd{sel(1, 10, 0xBB)}
+	for i := 0; i < 6; i++ {
+		cmds = append(cmds, train(1, 10, "Marine"))
+	}
+	got := Analyze(replayOf(cmds...)).Players[1].ProducerTimelines("Barracks")
+	if len(got) != 1 || !reflect.DeepEqual(got[0].Busy, []BusySpan{{StartSec: 10, EndSec: 85}}) {
+		t.Fatalf("timelines = %+v; want one span 10-85", got)
+	}
+	if got[0].ReadySec != 10 {
+		t.Errorf("ReadySec = %d; want the first Train with no matched Build", got[0].ReadySec)
+	}
+}
@@ -6,7 +6,9 @@
 // the unit, but Select / Hotkey commands carry the selected units' tags. By
 // replaying selection state we can bind, with high confidence, each single-unit
 // selection to the building tag that produced from it — the evidence the
-// internal/builddedup strategies consume.
+// internal/builddedup strategies consume, the per-building training
+// timelines ProducerTimelines and larva timelines HatcheryLarvae estimate, and
+// whether each hotkey group held a building or army (HotkeyBindings).
 //
 // This operates on the raw screp stream (rep.Commands.Cmds) because screpdb's
 // normal parser discards Select commands and their tags.
@@ -31,7 +33,7 @@ var producerBuilding = map[string]string{
 	"Scout": "Stargate", "Carrier": "Stargate", "Arbiter": "Stargate", "Corsair": "Stargate",
 	"SCV":    "Command Center",
 	"Marine": "Barracks", "Firebat": "Barracks", "Ghost": "Barracks", "Medic": "Barracks",
-	"Vulture": "Factory", "Siege Tank": "Factory", "Goliath": "Factory",
+	"Vulture": "Factory", "Siege Tank (Tank Mode)": "Factory", "Goliath": "Factory",
 	"Wraith": "Starport", "Dropship": "Starport", "Science Vessel": "Starport",
 	"Valkyrie": "Starport", "Battlecruiser": "Starport",
 }
@@ -88,6 +90,19 @@ type Production struct {
 	// stream order. Used by the ownership pass to refresh base ownership at each
 	// production moment (a producing building proves the base is still alive).
 	Secs []int
+	// UnitNames is the unit each Secs entry trained or morphed, index-aligned
+	// with Secs. Used by ProducerTimelines to look up train durations.
+	UnitNames []string `json:",omitempty"`
+}
+
+// LarvaMorph is one Zerg larva-morph command. Larvae is how many units were
+// selected: one command morphs every selected larva. Hall is the town-hall tag
+// tapped just before the larvae select, when HallKnown.
+type LarvaMorph struct {
+	Sec       int
+	Larvae    int
+	Hall      uint16
+	HallKnown bool
 }
 
 // ProductionSignal is one "the producing building is alive here" datapoint:
@@ -114,6 +129,13 @@ type PlayerEvidence struct {
 	// datapoints (see ProductionSignal). Populated by attributeProductionLocations
 	// at the end of Analyze.
 	ProductionSignals []ProductionSignal
+	// LarvaMorphs is every Zerg larva-morph command, in stream order,
+	// including those no town hall could be attributed to. Used by
+	// HatcheryLarvae.
+	LarvaMorphs []LarvaMorph `json:",omitempty"`
+	// HotkeyBindings is every hotkey Assign, in stream order, with the role
+	// of what was bound (see HotkeyRoleBuilding).
+	HotkeyBindings []HotkeyBinding `json:",omitempty"`
 }
 
 // Evidence holds per-player evidence keyed by replay PlayerID.
@@ -137,11 +159,12 @@ func Analyze(r *rep.Replay) *Evidence {
 		// recovered from this prior single-select (the macro-cycle hatch tap).
 		prevSingle      uint16
 		prevSingleValid bool
+		hotkeys         *hotkeyTags
 	}
 	states := map[byte]*selState{}
 	get := func(pid byte) (*selState, *PlayerEvidence) {
 		if states[pid] == nil {
-			states[pid] = &selState{groups: map[byte][]uint16{}}
+			states[pid] = &selState{groups: map[byte][]uint16{}, hotkeys: newHotkeyTags()}
 			ev.Players[pid] = &PlayerEvidence{
 				Builds:    map[string][]Build{},
 				Producers: map[string]map[uint16]*Production{},
@@ -161,7 +184,7 @@ func Analyze(r *rep.Replay) *Evidence {
 		}
 	}
 
-	recordProduction := func(pe *PlayerEvidence, bldg string, tag uint16, sec int) {
+	recordProduction := func(pe *PlayerEvidence, bldg string, tag uint16, sec int, unit string) {
 		if pe.Producers[bldg] == nil {
 			pe.Producers[bldg] = map[uint16]*Production{}
 		}
@@ -172,6 +195,7 @@ func Analyze(r *rep.Replay) *Evidence {
 		}
 		p.Units++
 		p.Secs = append(p.Secs, sec)
+		p.UnitNames = append(p.UnitNames, unit)
 	}
 
 	for _, c := range r.Commands.Cmds {
@@ -187,11 +211,13 @@ func Analyze(r *rep.Replay) *Evidence {
 			if sc, ok := c.(*repcmd.SelectCmd); ok {
 				snap(s)
 				s.cur = tagsOf(sc.UnitTags)
+				s.hotkeys.selected(s.cur)
 			}
 		case repcmd.TypeIDSelectAdd, repcmd.TypeIDSelectAdd121:
 			if sc, ok := c.(*repcmd.SelectCmd); ok {
 				snap(s)
 				s.cur = unionTags(s.cur, tagsOf(sc.UnitTags))
+				s.hotkeys.selected(s.cur)
 			}
 		case repcmd.TypeIDSelectRemove, repcmd.TypeIDSelectRemove121:
 			if sc, ok := c.(*repcmd.SelectCmd); ok {
@@ -203,14 +229,25 @@ func Analyze(r *rep.Replay) *Evidence {
 				switch hc.HotkeyType.Name {
 				case "Assign":
 					s.groups[hc.Group] = append([]uint16(nil), s.cur...)
+					pe.HotkeyBindings = append(pe.HotkeyBindings, HotkeyBinding{
+						Frame: int32(b.Frame), Sec: sec, Group: hc.Group, Units: len(s.cur),
+					})
+					s.hotkeys.bound = append(s.hotkeys.bound, s.groups[hc.Group])
 				case "Select":
 					snap(s)
 					s.cur = append([]uint16(nil), s.groups[hc.Group]...)
 				case "Add":
 					snap(s)
 					s.cur = unionTags(s.cur, s.groups[hc.Group])
+					s.hotkeys.selected(s.cur)
 				}
 			}
+		case repcmd.TypeIDTech, repcmd.TypeIDUpgrade, repcmd.TypeIDBuildingMorph, repcmd.TypeIDLiftOff:
+			// Only a building researches, morphs into another building or
+			// lifts off.
+			if len(s.cur) == 1 {
+				s.hotkeys.buildings[s.cur[0]] = true
+			}
 		case repcmd.TypeIDBuild:
 			bc, ok := c.(*repcmd.BuildCmd)
 			if !ok || bc.Unit == nil {
@@ -242,20 +279,26 @@ func Analyze(r *rep.Replay) *Evidence {
 				// Terran/Protoss: the producing building IS the single-selected
 				// unit (Train operates on the selected production structure).
 				if len(s.cur) == 1 {
-					recordProduction(pe, bldg, s.cur[0], sec)
+					recordProduction(pe, bldg, s.cur[0], sec, name)
 				}
 				continue
 			}
-			if larvaMorphUnits[name] && s.prevSingleValid {
-				// Zerg larva morph: attribute to the town-hall tag tapped just
-				// before the larvae select (see prevSingle).
-				recordProduction(pe, zergTownHall, s.prevSingle, sec)
+			if larvaMorphUnits[name] {
+				morph := LarvaMorph{Sec: sec, Larvae: len(s.cur)}
+				if s.prevSingleValid {
+					// Zerg larva morph: attribute to the town-hall tag tapped
+					// just before the larvae select (see prevSingle).
+					recordProduction(pe, zergTownHall, s.prevSingle, sec, name)
+					morph.Hall, morph.HallKnown = s.prevSingle, true
+				}
+				pe.LarvaMorphs = append(pe.LarvaMorphs, morph)
 			}
 		}
 	}
 
-	for _, pe := range ev.Players {
+	for pid, pe := range ev.Players {
 		attributeProductionLocations(pe)
+		classifyHotkeyBindings(pe, states[pid].hotkeys)
 	}
 	return ev
 }
@@ -303,11 +346,24 @@ func TownHallBuildSeconds(ev *Evidence) map[byte][]int {
 	return out
 }
 
-// collapsedBuildSeconds returns the sorted seconds of the given Builds, dropping
-// any whose footprint overlaps an earlier kept build (a re-placement of the same
-// intended structure). Builds are processed in time order so the first placement
-// at a spot is the one kept.
+// collapsedBuildSeconds returns the sorted seconds of collapsedBuilds.
 func collapsedBuildSeconds(builds []Build) []int {
+	kept := collapsedBuilds(builds)
+	if len(kept) == 0 {
+		return nil
+	}
+	secs := make([]int, len(kept))
+	for i, b := range kept {
+		secs[i] = b.Sec
+	}
+	return secs
+}
+
+// collapsedBuilds returns the given Builds in time order, dropping any whose
+// footprint overlaps an earlier kept build (a re-placement of the same intended
+// structure). Builds are processed in time order so the first placement at a
+// spot is the one kept.
+func collapsedBuilds(builds []Build) []Build {
 	if len(builds) == 0 {
 		return nil
 	}
@@ -326,12 +382,7 @@ func collapsedBuildSeconds(builds []Build) []int {
 			kept = append(kept, b)
 		}
 	}
-	secs := make([]int, len(kept))
-	for i, b := range kept {
-		secs[i] = b.Sec
-	}
-	sort.Ints(secs)
-	return secs
+	return kept
 }
 
 func abs(x int) int {